@@ -0,0 +1,138 @@
+package ais
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIndexCache bounds the number of Headers whose field indices are cached for
+// the typed accessors of Record.
+const maxIndexCache = 256
+
+// indexCache maps the Fields of a Headers value, identified by the address of
+// their first element and their length, to the index of each field name.  Every
+// hit is checked against the Fields so a Headers modified in place is never
+// answered from stale data.
+var indexCache struct {
+	sync.Mutex
+	m map[indexKey]map[string]int
+}
+
+type indexKey struct {
+	first *string
+	n     int
+}
+
+// index returns the index of field in h from the cache, or an ErrMissingHeader.
+func (h Headers) index(field string) (int, error) {
+	if len(h.Fields) == 0 {
+		return 0, ErrMissingHeader{Field: field}
+	}
+	key := indexKey{&h.Fields[0], len(h.Fields)}
+	indexCache.Lock()
+	defer indexCache.Unlock()
+	m, ok := indexCache.m[key]
+	if !ok {
+		if indexCache.m == nil || len(indexCache.m) >= maxIndexCache {
+			indexCache.m = make(map[indexKey]map[string]int)
+		}
+		m = make(map[string]int, len(h.Fields))
+		indexCache.m[key] = m
+	}
+	if i, ok := m[field]; ok && h.names(i, field) {
+		return i, nil
+	}
+	i, ok := h.Contains(field)
+	if !ok {
+		return 0, ErrMissingHeader{Field: field}
+	}
+	m[field] = i
+	return i, nil
+}
+
+// float parses the field of r named by field in h.
+func (r Record) float(h Headers, field string) (float64, error) {
+	i, err := h.index(field)
+	if err != nil {
+		return 0, err
+	}
+	if i >= len(r) {
+		return 0, ErrParse{Field: field, Err: strconv.ErrSyntax}
+	}
+	f, err := strconv.ParseFloat(r[i], 64)
+	if err != nil {
+		return 0, ErrParse{Field: field, Err: err}
+	}
+	return f, nil
+}
+
+// LatLon returns the LAT and LON fields of the Record described by h.  Errors are
+// an ErrMissingHeader when h lacks either field and an ErrParse when a value is
+// not a number.  The index of each field in h is cached, so the accessors of
+// Record are cheap to call in a loop over a RecordSet.
+func (r Record) LatLon(h Headers) (lat, lon float64, err error) {
+	if lat, err = r.float(h, "LAT"); err != nil {
+		return 0, 0, err
+	}
+	if lon, err = r.float(h, "LON"); err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+// Time returns the BaseDateTime of the Record described by h parsed with the
+// TimeParser of h, or with TimeLayout when h has none.
+func (r Record) Time(h Headers) (time.Time, error) {
+	i, err := h.index("BaseDateTime")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if i >= len(r) {
+		return time.Time{}, ErrParse{Field: "BaseDateTime", Err: strconv.ErrSyntax}
+	}
+	t, err := h.parseTime(r[i])
+	if err != nil {
+		return time.Time{}, ErrParse{Field: "BaseDateTime", Err: err}
+	}
+	return t, nil
+}
+
+// SOG returns the speed over ground in knots of the Record described by h.  The
+// value 102.3 that AIS uses for speed not available is returned unchanged.
+func (r Record) SOG(h Headers) (float64, error) { return r.float(h, "SOG") }
+
+// COG returns the course over ground in degrees of the Record described by h.
+// The value 360 that AIS uses for course not available is returned unchanged.
+func (r Record) COG(h Headers) (float64, error) { return r.float(h, "COG") }
+
+// Heading returns the true heading in degrees of the Record described by h.  The
+// value 511 that AIS uses for heading not available is returned unchanged.
+func (r Record) Heading(h Headers) (float64, error) { return r.float(h, "Heading") }
+
+// MMSI returns the MMSI field of the Record described by h.
+func (r Record) MMSI(h Headers) (MMSI, error) {
+	i, err := h.index("MMSI")
+	if err != nil {
+		return "", err
+	}
+	if i >= len(r) {
+		return "", ErrParse{Field: "MMSI", Err: strconv.ErrSyntax}
+	}
+	return MMSI(r[i]), nil
+}
+
+// names reports whether the field at index i of h is field or one of its
+// aliases in the Mapping.
+func (h Headers) names(i int, field string) bool {
+	if h.Fields[i] == field {
+		return true
+	}
+	for _, alias := range h.Mapping[field] {
+		if strings.EqualFold(h.Fields[i], alias) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package ais
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecord_Accessors(t *testing.T) {
+	rec := Record{"477307901", "2017-12-01T00:00:01", "31.90512", "-76.32652", "0.5", "131.0", "352.0", "FIRST", "", "", "", "", "", "", "", ""}
+	lat, lon, err := rec.LatLon(goodHeaders)
+	if err != nil || lat != 31.90512 || lon != -76.32652 {
+		t.Errorf("Record.LatLon() = %v, %v, %v, want 31.90512, -76.32652", lat, lon, err)
+	}
+	tm, err := rec.Time(goodHeaders)
+	if want := time.Date(2017, 12, 1, 0, 0, 1, 0, time.UTC); err != nil || !tm.Equal(want) {
+		t.Errorf("Record.Time() = %v, %v, want %v", tm, err, want)
+	}
+	if sog, err := rec.SOG(goodHeaders); err != nil || sog != 0.5 {
+		t.Errorf("Record.SOG() = %v, %v, want 0.5", sog, err)
+	}
+	if cog, err := rec.COG(goodHeaders); err != nil || cog != 131 {
+		t.Errorf("Record.COG() = %v, %v, want 131", cog, err)
+	}
+	if hdg, err := rec.Heading(goodHeaders); err != nil || hdg != 352 {
+		t.Errorf("Record.Heading() = %v, %v, want 352", hdg, err)
+	}
+	if m, err := rec.MMSI(goodHeaders); err != nil || m != "477307901" {
+		t.Errorf("Record.MMSI() = %v, %v, want 477307901", m, err)
+	}
+
+	var perr ErrParse
+	bad := Record{"1", "not a time", "north"}
+	if _, _, err := bad.LatLon(goodHeaders); !errors.As(err, &perr) || perr.Field != "LAT" {
+		t.Errorf("Record.LatLon() error = %v, want ErrParse for LAT", err)
+	}
+	if _, err := bad.SOG(goodHeaders); !errors.As(err, &perr) || perr.Field != "SOG" {
+		t.Errorf("Record.SOG() error = %v, want ErrParse for SOG", err)
+	}
+	var missing ErrMissingHeader
+	if _, err := rec.Time(Headers{Fields: []string{"MMSI"}}); !errors.As(err, &missing) || missing.Field != "BaseDateTime" {
+		t.Errorf("Record.Time() error = %v, want ErrMissingHeader for BaseDateTime", err)
+	}
+}
+
+func TestHeaders_IndexModifiedInPlace(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "LAT", "LON"}}
+	if i, err := h.index("LON"); err != nil || i != 2 {
+		t.Fatalf("Headers.index(LON) = %d, %v, want 2", i, err)
+	}
+	h.Fields[1], h.Fields[2] = "LON", "LAT"
+	if i, err := h.index("LON"); err != nil || i != 1 {
+		t.Errorf("Headers.index(LON) after reorder = %d, %v, want 1", i, err)
+	}
+}
@@ -8,6 +8,7 @@ package ais
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
@@ -22,7 +23,6 @@ import (
 	"text/tabwriter"
 	"time"
 
-	"github.com/FATHOM5/haversine"
 	"github.com/mmcloughlin/geohash"
 )
 
@@ -37,6 +37,20 @@ const TimeLayout = `2006-01-02T15:04:05`
 // will write to memory before being flushed.
 const flushThreshold = 250000
 
+// cancelCheckInterval is the number of Records read between checks of the
+// Context passed to the Context variants of long-running methods.
+const cancelCheckInterval = 1024
+
+// canceled returns ctx.Err() on every cancelCheckInterval'th value of the loop
+// counter n and nil otherwise, which keeps the cost of checking for cancellation
+// negligible in loops over millions of Records.
+func canceled(ctx context.Context, n int) error {
+	if n%cancelCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
 // ErrEmptySet is the error returned by Subset variants when there are no records
 // in the returned *RecordSet because nothing matched the selection criteria.
 // Functions should only return ErrEmptySet when all processing occurred successfully,
@@ -115,11 +129,11 @@ func (g *Geohasher) Generate(rec Record, index ...int) (Field, error) {
 	// From these values create a geohash and return it
 	lat, err := rec.ParseFloat(indexLat)
 	if err != nil {
-		return "", fmt.Errorf("geohash: unable to parse lat")
+		return "", fmt.Errorf("geohash: %w", ErrParse{Field: "LAT", Err: err})
 	}
 	lon, err := rec.ParseFloat(indexLon)
 	if err != nil {
-		return "", fmt.Errorf("geohash: unable to parse lon")
+		return "", fmt.Errorf("geohash: %w", ErrParse{Field: "LON", Err: err})
 	}
 	hash := geohash.EncodeIntWithPrecision(lat, lon, uint(22))
 	return Field(fmt.Sprintf("%#x", hash)), nil
@@ -131,11 +145,21 @@ func (g *Geohasher) Generate(rec Record, index ...int) (Field, error) {
 // empty set, or OpenRecordSet(filename) to read a file on disk.
 type RecordSet struct {
 	r     *csv.Reader   // internally held csv pointer
-	w     *csv.Writer   // internally held csv pointer
+	w     *recordWriter // internally held csv writer
 	h     Headers       // Headers used to parse each Record
 	data  io.ReadWriter // client provided io interface
 	first *Record       // accessible only by package functions
 	stash *Record       // stashed Record from a client Read() but not yet used
+	cur   *Record       // current Record of a Next() iteration
+	err   error         // first non-EOF error encountered by Next()
+	index *recordIndex  // random access index set by OpenIndexedRecordSet
+	src   *os.File      // file opened by OpenRecordSet, used to report progress
+	proj  []int         // index in the file of each field kept by ReadColumns, nil for every field
+	reuse bool          // Next reuses buf for every Record, set by SetReuseRecord
+	buf   Record        // Record reused by Next and internal scans
+	meter *Metrics      // counts the Records read, set by ReadMetrics
+	pool  *internPool   // shares the values of repeated fields, set by ReadInterned
+	mmsis *mmsiFilter   // keeps the Records of chosen MMSIs, set by IncludeMMSI and ExcludeMMSI
 }
 
 // NewRecordSet returns a *Recordset that has an in-memory data buffer for
@@ -148,7 +172,7 @@ func NewRecordSet() *RecordSet {
 	buf := bytes.Buffer{}
 	rs.data = &buf
 	rs.r = csv.NewReader(&buf)
-	rs.w = csv.NewWriter(&buf)
+	rs.w = newRecordWriter(&buf)
 
 	rs.r.LazyQuotes = true
 	rs.r.Comment = '#'
@@ -156,23 +180,199 @@ func NewRecordSet() *RecordSet {
 	return rs
 }
 
+// OpenOption configures OpenRecordSet and NewRecordSetFromReader.
+type OpenOption func(*openConfig) error
+
+type openConfig struct {
+	columns []string
+	bufSize int
+	comma   rune
+	strict  bool
+	charset Charset
+	meter   *Metrics
+	intern  []string // fields shared by ReadInterned, nil for none
+	mmsis   *mmsiFilter
+	time    TimeParser    // Time of the Headers, set by ReadTimeParser
+	mapping HeaderMapping // Mapping of the Headers, set by ReadMapping
+}
+
+// headers sets the Time and Mapping chosen by ReadTimeParser and ReadMapping on
+// h.
+func (c *openConfig) headers(h Headers) Headers {
+	if c.time != nil {
+		h.Time = c.time
+	}
+	if c.mapping != nil {
+		h.Mapping = c.mapping
+	}
+	return h
+}
+
+// apply sets the delimiter and quoting of the Reader and Writer of rs and the
+// Metrics it counts its Records in.
+func (c *openConfig) apply(rs *RecordSet) {
+	if c.comma != 0 {
+		rs.r.Comma, rs.w.Comma = c.comma, c.comma
+	}
+	rs.r.LazyQuotes = !c.strict
+	rs.meter = c.meter
+}
+
+// Delimiter sets the rune that separates the fields of the file, in place of a
+// comma, such as ';' for the exports of agencies that use a decimal comma or
+// '\t' for tab separated files.  The delimiter may not be '"', '#', \r, or \n.
+func Delimiter(r rune) OpenOption {
+	return func(c *openConfig) error {
+		if err := validDelimiter(r); err != nil {
+			return fmt.Errorf("delimiter: %w", err)
+		}
+		c.comma = r
+		return nil
+	}
+}
+
+// ReadCharset sets the character encoding of the file, which is converted to
+// UTF-8 as it is read, in place of UTF8.  Records written to a RecordSet opened
+// with OpenRecordSet are converted back to the Charset of the file.
+func ReadCharset(c Charset) OpenOption {
+	return func(cfg *openConfig) error {
+		if err := c.valid(); err != nil {
+			return fmt.Errorf("read charset: %w", err)
+		}
+		cfg.charset = c
+		return nil
+	}
+}
+
+// LazyQuotes sets whether a quote may appear in an unquoted field and a
+// non-doubled quote in a quoted field, as with the LazyQuotes of an
+// encoding/csv Reader.  Quotes are lazy by default, which reads the malformed
+// vessel names of some feeds, and LazyQuotes(false) reports them as errors
+// instead.
+func LazyQuotes(lazy bool) OpenOption {
+	return func(c *openConfig) error {
+		c.strict = !lazy
+		return nil
+	}
+}
+
+// DefaultReadBufferSize is the size in bytes of the buffer through which
+// OpenRecordSet reads a file.  It is much larger than the 4 KB buffer of an
+// encoding/csv Reader, which keeps the number of system calls for a multi-GB
+// file low on every platform.
+const DefaultReadBufferSize = 1 << 20
+
+// ReadBufferSize sets the size in bytes of the buffer through which
+// OpenRecordSet reads a file, in place of DefaultReadBufferSize.
+func ReadBufferSize(n int) OpenOption {
+	return func(c *openConfig) error {
+		if n <= 0 {
+			return fmt.Errorf("read buffer size: size must be greater than zero, got %d", n)
+		}
+		c.bufSize = n
+		return nil
+	}
+}
+
+// byteOrderMark is the UTF-8 encoding of U+FEFF, which Excel and other Windows
+// programs write at the start of a csv file saved as UTF-8.
+const byteOrderMark = "\ufeff"
+
+// trimBOM removes a byte order mark from the first of the header fields, where
+// encoding/csv leaves it, so that a file exported on Windows has the same
+// Headers as any other.  Line endings need no such care since encoding/csv
+// reads \r\n as \n.
+func trimBOM(fields []string) []string {
+	if len(fields) > 0 {
+		fields[0] = strings.TrimPrefix(fields[0], byteOrderMark)
+	}
+	return fields
+}
+
+// ReadColumns limits the RecordSet returned by OpenRecordSet to the named
+// columns, in the order given.  The other fields of each line are dropped as it
+// is parsed, so a RecordSet opened with
+//
+//	ais.ReadColumns("MMSI", "BaseDateTime", "LAT", "LON", "SOG", "COG")
+//
+// for an interaction analysis holds less than half the data of one with every
+// field of a MarineCadastre.gov file.  Columns must be named as they are in the
+// header line of the file.  A RecordSet opened with ReadColumns is read only.
+func ReadColumns(columns ...string) OpenOption {
+	return func(c *openConfig) error {
+		if len(columns) == 0 {
+			return fmt.Errorf("read columns: no columns")
+		}
+		c.columns = append([]string(nil), columns...)
+		return nil
+	}
+}
+
+// project keeps only columns of the Records read from rs from now on.
+func (rs *RecordSet) project(columns []string) error {
+	idx, err := rs.h.require(columns...)
+	if err != nil {
+		return fmt.Errorf("read columns: %w", err)
+	}
+	fields := make([]string, len(columns))
+	rs.proj = make([]int, len(columns))
+	for k, c := range columns {
+		rs.proj[k] = idx[c].Idx
+		fields[k] = rs.h.Fields[idx[c].Idx]
+	}
+	rs.h.Fields = fields
+	rs.r.ReuseRecord = true
+	if _, ok := rs.data.(readOnly); !ok {
+		ro := readOnly{rs.data}
+		rs.data = ro
+		comma := rs.w.Comma
+		rs.w = newRecordWriter(ro)
+		rs.w.Comma = comma
+	}
+	return nil
+}
+
 // OpenRecordSet takes the filename of an ais data file as its input.
 // It returns a pointer to the RecordSet and a nil error upon successfully
 // validating that the file can be read by an encoding/csv Reader. It returns
-// a nil Recordset on any non-nil error.
-func OpenRecordSet(filename string) (*RecordSet, error) {
+// a nil Recordset on any non-nil error.  Files compressed with gzip and zip
+// archives, such as those distributed by MarineCadastre.gov, are detected by their
+// contents and decompressed transparently.  A RecordSet opened from a compressed
+// file is read only.  Files larger than 4 GB are read on every platform, a
+// byte order mark before the header line is dropped, and lines may end with
+// \r\n as well as \n.  Options such as ReadColumns change how the file is
+// read.
+func OpenRecordSet(filename string, opts ...OpenOption) (*RecordSet, error) {
+	cfg := &openConfig{bufSize: DefaultReadBufferSize}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, fmt.Errorf("open recordset: %w", err)
+		}
+	}
 	rs := NewRecordSet()
 
 	f, err := os.OpenFile(filename, os.O_RDWR, 0666) // 0666 - Read Write
 	if err != nil {
-		return nil, fmt.Errorf("open recordset: %v", err)
+		return nil, fmt.Errorf("open recordset: %w", err)
+	}
+	rc, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open recordset: %w", err)
+	}
+	rs.src = f
+	if rc != nil {
+		ro := readOnly{rc}
+		rs.data = ro
+		rs.r = csv.NewReader(bufio.NewReaderSize(cfg.charset.decoder(ro), cfg.bufSize))
+		rs.w = newRecordWriter(ro)
+	} else {
+		rs.data = f
+		rs.r = csv.NewReader(bufio.NewReaderSize(cfg.charset.decoder(f), cfg.bufSize))
+		rs.w = newRecordWriter(cfg.charset.encoder(f))
 	}
-	rs.data = f
-	rs.r = csv.NewReader(f)
-	rs.r.LazyQuotes = true
 	rs.r.Comment = '#'
-
-	rs.w = csv.NewWriter(f)
+	cfg.apply(rs)
 
 	// The first non-comment line of a valid ais datafile should contain the headers.
 	// The following Read() command also advances the file pointer so that
@@ -180,13 +380,112 @@ func OpenRecordSet(filename string) (*RecordSet, error) {
 	var h Headers
 	h.Fields, err = rs.r.Read()
 	if err != nil {
-		return nil, fmt.Errorf("open recordset: %v", err)
+		return nil, fmt.Errorf("open recordset: %w", err)
 	}
-	rs.h = h
+	h.Fields = trimBOM(h.Fields)
+	rs.h = cfg.headers(h)
+
+	if cfg.mmsis != nil {
+		if err := rs.filterMMSI(cfg.mmsis); err != nil {
+			rs.Close()
+			return nil, fmt.Errorf("open recordset: %w", err)
+		}
+	}
+	if cfg.columns != nil {
+		if err := rs.project(cfg.columns); err != nil {
+			rs.Close()
+			return nil, fmt.Errorf("open recordset: %w", err)
+		}
+	}
+	if cfg.intern != nil {
+		if err := rs.internFields(cfg.intern); err != nil {
+			rs.Close()
+			return nil, fmt.Errorf("open recordset: %w", err)
+		}
+	}
+	return rs, nil
+}
+
+// NewRecordSetFromReader returns a *RecordSet that streams Records from r
+// without reading the entire input into memory.  This allows a RecordSet to be
+// built from pipes, HTTP response bodies, or a gzip.Reader.  If h has no Fields
+// then the first non-comment line of r is read as the Headers, which is the same
+// behavior as OpenRecordSet, including dropping a byte order mark.  The returned RecordSet is read only and any
+// Records written to it return an error on Flush.  If r implements io.Closer it is closed by rs.Close().
+// The options of OpenRecordSet apply, except for ReadBufferSize.
+func NewRecordSetFromReader(r io.Reader, h Headers, opts ...OpenOption) (*RecordSet, error) {
+	cfg := new(openConfig)
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, fmt.Errorf("new recordset from reader: %w", err)
+		}
+	}
+	rs := new(RecordSet)
+	ro := readOnly{r}
+	rs.data = ro
+	rs.r = csv.NewReader(cfg.charset.decoder(ro))
+	rs.r.Comment = '#'
+	rs.w = newRecordWriter(ro)
+	cfg.apply(rs)
+
+	if len(h.Fields) == 0 {
+		var err error
+		h.Fields, err = rs.r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("new recordset from reader: %w", err)
+		}
+		h.Fields = trimBOM(h.Fields)
+	}
+	rs.h = cfg.headers(h)
 
+	if cfg.mmsis != nil {
+		if err := rs.filterMMSI(cfg.mmsis); err != nil {
+			return nil, fmt.Errorf("new recordset from reader: %w", err)
+		}
+	}
+	if cfg.columns != nil {
+		if err := rs.project(cfg.columns); err != nil {
+			return nil, fmt.Errorf("new recordset from reader: %w", err)
+		}
+	}
+	if cfg.intern != nil {
+		if err := rs.internFields(cfg.intern); err != nil {
+			return nil, fmt.Errorf("new recordset from reader: %w", err)
+		}
+	}
 	return rs, nil
 }
 
+// rewind replaces the Reader of rs with one that reads r, a copy of the Records
+// of rs written by a recordWriter with the same delimiter, keeping the settings
+// of the old Reader.  The Records of the copy are already projected.
+func (rs *RecordSet) rewind(r io.Reader) {
+	old := rs.r
+	rs.r = csv.NewReader(r)
+	rs.r.Comma, rs.r.Comment, rs.r.LazyQuotes = old.Comma, old.Comment, old.LazyQuotes
+	rs.proj = nil
+	rs.mmsis = nil
+}
+
+// readOnly wraps an io.Reader so that it satisfies the io.ReadWriter held by
+// a RecordSet.
+type readOnly struct {
+	io.Reader
+}
+
+// Write implements io.Writer and always returns an error.
+func (readOnly) Write(p []byte) (int, error) {
+	return 0, errors.New("recordset is read only")
+}
+
+// Close closes the underlying io.Reader if it implements io.Closer.
+func (ro readOnly) Close() error {
+	if c, ok := ro.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // SetHeaders provides the expected interface to a RecordSet
 func (rs *RecordSet) SetHeaders(h Headers) {
 	rs.h = h
@@ -194,8 +493,17 @@ func (rs *RecordSet) SetHeaders(h Headers) {
 
 // Read calls Read() on the csv.Reader held by the RecordSet and returns a
 // Record.  The idiomatic way to iterate over a recordset comes from the
-// same idiom to read a file using encoding/csv.
+// same idiom to read a file using encoding/csv.  Every Record returned by Read
+// is newly allocated and owned by the caller, whether or not SetReuseRecord is
+// on.
 func (rs *RecordSet) Read() (*Record, error) {
+	return rs.read(false)
+}
+
+// read returns the next Record.  With reuse the Record is held in rs.buf and is
+// overwritten by the next call to read with reuse, so the caller must not keep
+// it.
+func (rs *RecordSet) read(reuse bool) (*Record, error) {
 	// When Read is called by clients they want the first Record. If that
 	// Record has already been read by internal packages return the one that
 	// was already read internally.
@@ -212,29 +520,135 @@ func (rs *RecordSet) Read() (*Record, error) {
 		return rec, nil
 	}
 
-	r, err := rs.r.Read()
-	if err == io.EOF {
-		return nil, err
+	rs.r.ReuseRecord = reuse || rs.proj != nil || rs.mmsis != nil
+	var r []string
+	for {
+		var err error
+		r, err = rs.r.Read()
+		if err == io.EOF {
+			return nil, err
+		}
+		if pe, ok := err.(*csv.ParseError); ok {
+			rs.meter.addParseError()
+			return nil, fmt.Errorf("recordset read: %w", ErrParse{Line: pe.Line, Err: pe.Err})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("recordset read: %w", err)
+		}
+		if rs.mmsis.keep(r) {
+			break
+		}
 	}
-	if err != nil {
-		return nil, fmt.Errorf("recordset read: %v", err)
+	if rs.mmsis != nil && !reuse && rs.proj == nil {
+		r = append([]string(nil), r...) // the Reader reused r while filtering
+	}
+	rs.meter.addRecord()
+	if rs.proj != nil {
+		if reuse {
+			// The Record is not kept, so the fields need not be copied out of
+			// the line.
+			if cap(rs.buf) < len(rs.proj) {
+				rs.buf = make(Record, len(rs.proj))
+			}
+			rs.buf = rs.buf[:len(rs.proj)]
+			for k, i := range rs.proj {
+				rs.buf[k] = r[i]
+			}
+			return &rs.buf, nil
+		}
+		rec := make(Record, len(rs.proj))
+		if rs.pool != nil {
+			for k, i := range rs.proj {
+				rec[k] = r[i]
+			}
+			rs.pool.record(rec)
+			return &rec, nil
+		}
+		for k, i := range rs.proj {
+			rec[k] = string(append([]byte(nil), r[i]...)) // release the rest of the line
+		}
+		return &rec, nil
+	}
+	if reuse {
+		rs.buf = r
+		return &rs.buf, nil
+	}
+	if rs.pool != nil {
+		// Interned values would not save memory while the other fields still
+		// hold the line, so the other fields are copied out of it.
+		rs.pool.record(r)
 	}
 	rec := Record(r)
 	return &rec, nil
 }
 
+// SetReuseRecord turns record reuse on or off for the Next iteration of the
+// RecordSet.  Loading tens of millions of Records allocates a new slice for
+// each, and most of the time of a large scan goes to the garbage collector.
+// With reuse on, Next reads every line into the same Record, so a scan that
+// only inspects each Record, counts it, or writes it elsewhere allocates little
+// more than the text of each line.
+//
+// The ownership rules are those of encoding/csv's Reader.ReuseRecord: the
+// Record returned by Record, and the pointer itself, are valid only until the
+// next call to Next.  A caller that keeps a Record, for example by appending it
+// to a slice or adding it to a Window, must keep a copy made with
+// append(ais.Record(nil), *rec...).  The strings of the fields are not reused
+// and may be kept.  Read is not affected and always returns a Record owned by
+// the caller, so the methods of RecordSet that keep the Records they read, such
+// as SortByTime and SlideWindow, may be used with reuse on.  Reuse is off by
+// default.
+func (rs *RecordSet) SetReuseRecord(reuse bool) {
+	rs.reuse = reuse
+}
+
+// Next advances the RecordSet to the next Record, which will then be available
+// through the Record method.  It returns false when the iteration stops, either by
+// reaching the end of the data or an error.  After Next returns false, the Err
+// method will return any error that occurred during iteration.  The idiomatic
+// streaming loop is
+//
+//	for rs.Next() {
+//		rec := rs.Record()
+//		// use rec...
+//	}
+//	if err := rs.Err(); err != nil {
+//		// handle err
+//	}
+func (rs *RecordSet) Next() bool {
+	if rs.err != nil {
+		return false
+	}
+	rec, err := rs.read(rs.reuse)
+	if err != nil {
+		if err != io.EOF {
+			rs.err = err
+		}
+		rs.cur = nil
+		return false
+	}
+	rs.cur = rec
+	return true
+}
+
+// Record returns the most recent Record read by a call to Next.  With
+// SetReuseRecord on, the Record is valid only until the next call to Next.
+func (rs *RecordSet) Record() *Record { return rs.cur }
+
+// Err returns the first non-EOF error encountered by Next.
+func (rs *RecordSet) Err() error { return rs.err }
+
 // ReadFirst is an unexported method used by various internal packages
 // to get the first line of the RecordSet.
 func (rs *RecordSet) readFirst() (*Record, error) {
 	if rs.first != nil {
 		return rs.first, nil
 	}
-	r, err := rs.r.Read()
+	rec, err := rs.read(false)
 	if err != nil {
 		return nil, err
 	}
-	rec := Record(r)
-	rs.first = &rec
+	rs.first = rec
 	return rs.first, nil
 }
 
@@ -280,7 +694,7 @@ func (rs *RecordSet) AppendField(newField string, requiredHeaders []string, gen
 	for _, target := range requiredHeaders {
 		index, ok := rs.Headers().Contains(target)
 		if !ok {
-			return nil, fmt.Errorf("append: headers does not contain %s", target)
+			return nil, fmt.Errorf("append: %w", ErrMissingHeader{Field: target})
 		}
 		indices = append(indices, index)
 	}
@@ -288,36 +702,36 @@ func (rs *RecordSet) AppendField(newField string, requiredHeaders []string, gen
 	// Iterate over the records
 	written := 0
 	for {
-		var rec Record
-		rec, err := rs.r.Read()
+		r, err := rs.read(false)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("append: read error on csv file: %v", err)
+			return nil, fmt.Errorf("append: read error on csv file: %w", err)
 		}
 
+		rec := *r
 		field, err := gen.Generate(rec, indices...)
 		if err != nil {
-			return nil, fmt.Errorf("appendfield: generate: %v", err)
+			return nil, fmt.Errorf("appendfield: generate: %w", err)
 		}
 		rec = append(rec, string(field))
 		err = rs2.Write(rec)
 		if err != nil {
-			return nil, fmt.Errorf("appendfield: csv write error: %v", err)
+			return nil, fmt.Errorf("appendfield: csv write error: %w", err)
 		}
 		written++
 		if written%flushThreshold == 0 {
 			err := rs2.Flush()
 			if err != nil {
-				return nil, fmt.Errorf("appendfield: csv flush error: %v", err)
+				return nil, fmt.Errorf("appendfield: csv flush error: %w", err)
 			}
 		}
 
 	}
 	err := rs2.Flush()
 	if err != nil {
-		return nil, fmt.Errorf("appendfield: csv flush error: %v", err)
+		return nil, fmt.Errorf("appendfield: csv flush error: %w", err)
 	}
 	return rs2, nil
 }
@@ -328,6 +742,12 @@ func (rs *RecordSet) AppendField(newField string, requiredHeaders []string, gen
 //      defer rs.Close()
 // immediately after creating a NewRecordSet.
 func (rs *RecordSet) Close() error {
+	if rs.index != nil {
+		if err := rs.index.close(); err != nil {
+			return fmt.Errorf("recordset close: %w", err)
+		}
+		rs.index = nil
+	}
 	if rs.data == nil {
 		return nil
 	}
@@ -341,7 +761,7 @@ func (rs *RecordSet) Close() error {
 			return nil
 		}
 		err := v[0].Interface().(error)
-		return fmt.Errorf("recordset close: %v", err)
+		return fmt.Errorf("recordset close: %w", err)
 	}
 
 	// no-op for types that do not implement close
@@ -351,30 +771,112 @@ func (rs *RecordSet) Close() error {
 // Headers returns the encapsulated headers data of the Recordset
 func (rs *RecordSet) Headers() Headers { return rs.h }
 
-// Save writes the RecordSet to disk in the filename provided
-func (rs *RecordSet) Save(name string) error {
-	var err error
-	rs.data, err = os.Create(name)
+// SaveOption configures Save.
+type SaveOption func(*saveConfig) error
+
+type saveConfig struct {
+	comma   rune
+	quote   QuoteStyle
+	charset Charset
+}
+
+// SaveDelimiter sets the rune that separates the fields of the saved file in
+// place of a comma, with the same limits as Delimiter.
+func SaveDelimiter(r rune) SaveOption {
+	return func(c *saveConfig) error {
+		if err := validDelimiter(r); err != nil {
+			return fmt.Errorf("save delimiter: %w", err)
+		}
+		c.comma = r
+		return nil
+	}
+}
+
+// SaveQuoting sets the fields that are quoted in the saved file, QuoteMinimal
+// by default.
+func SaveQuoting(q QuoteStyle) SaveOption {
+	return func(c *saveConfig) error {
+		if q < QuoteMinimal || q > QuoteAll {
+			return fmt.Errorf("save quoting: unknown quote style %v", q)
+		}
+		c.quote = q
+		return nil
+	}
+}
+
+// SaveCharset sets the character encoding of the saved file, UTF8 by default.
+// A character the Charset cannot represent is written as '?'.
+func SaveCharset(cs Charset) SaveOption {
+	return func(c *saveConfig) error {
+		if err := cs.valid(); err != nil {
+			return fmt.Errorf("save charset: %w", err)
+		}
+		c.charset = cs
+		return nil
+	}
+}
+
+// Save writes the RecordSet to disk in the filename provided.  When the filename
+// ends in .gz the file is compressed with gzip, and when it ends in .zip the data
+// is written as the only file in a zip archive.  Options such as SaveDelimiter
+// change how the file is written, so that it can be read by tools that expect
+// an agency's own export format.
+func (rs *RecordSet) Save(name string, opts ...SaveOption) error {
+	return rs.SaveContext(context.Background(), name, opts...)
+}
+
+// SaveContext is Save with a Context that stops the write when it is canceled.
+// The partially written file is left on disk.
+func (rs *RecordSet) SaveContext(ctx context.Context, name string, opts ...SaveOption) error {
+	cfg := &saveConfig{comma: ','}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return fmt.Errorf("recordset save: %w", err)
+		}
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("recordset save: %w", err)
+	}
+	rs.data = f
+	cw, err := compressor(f, name)
 	if err != nil {
-		return fmt.Errorf("recordset save: %v", err)
+		return fmt.Errorf("recordset save: %w", err)
+	}
+	if cw != nil {
+		rs.w = newRecordWriter(cfg.charset.encoder(cw))
+	} else {
+		rs.w = newRecordWriter(cfg.charset.encoder(rs.data)) // buffered with a bufio.Writer internally
 	}
-	rs.w = csv.NewWriter(rs.data) // FYI - csv uses bufio.NewWriter internally
+	rs.w.Comma, rs.w.Quote = cfg.comma, cfg.quote
 	rs.Write(rs.h.Fields)
 
-	for {
-		rec, err := rs.r.Read()
+	pt := startProgress(ctx, "recordset save", rs)
+	n := 0
+	for ; ; n++ {
+		if err := canceled(ctx, n); err != nil {
+			return fmt.Errorf("recordset save: %w", err)
+		}
+		pt.update(n)
+		rec, err := rs.read(false)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("recordset save: read error on csv file: %v", err)
+			return fmt.Errorf("recordset save: read error on csv file: %w", err)
 		}
-		rs.Write(rec)
+		rs.Write(*rec)
 	}
 	err = rs.Flush()
 	if err != nil {
-		return fmt.Errorf("recordset save: flush error: %v", err)
+		return fmt.Errorf("recordset save: flush error: %w", err)
+	}
+	if cw != nil {
+		if err := cw.Close(); err != nil {
+			return fmt.Errorf("recordset save: %w", err)
+		}
 	}
+	pt.done(n)
 
 	return nil
 }
@@ -395,6 +897,12 @@ func (rs *RecordSet) Save(name string) error {
 // situations where it is causing an issue use rs.Close() and then OpenRecordSet(filename)
 // to get a fresh copy of the data.
 func (rs *RecordSet) SubsetLimit(m Matching, n int, multipass bool) (*RecordSet, error) {
+	return rs.SubsetLimitContext(context.Background(), m, n, multipass)
+}
+
+// SubsetLimitContext is SubsetLimit with a Context that stops the scan when it is
+// canceled.
+func (rs *RecordSet) SubsetLimitContext(ctx context.Context, m Matching, n int, multipass bool) (*RecordSet, error) {
 	rs2 := NewRecordSet()
 	rs2.SetHeaders(rs.Headers())
 
@@ -404,23 +912,30 @@ func (rs *RecordSet) SubsetLimit(m Matching, n int, multipass bool) (*RecordSet,
 	// efficient solution would probably be controlling the Seek() value of the underlying
 	// decriptor, but csv.Reader does not expose this pointer.
 	copyBuf := &bytes.Buffer{}
-	copyWriter := bufio.NewWriter(copyBuf)
+	copyWriter := newRecordWriter(copyBuf)
+	copyWriter.Comma = rs.r.Comma
 
+	pt := startProgress(ctx, "subset", rs)
 	recordsLeftToWrite := n
-	for recordsLeftToWrite != 0 {
+	read := 0
+	for ; recordsLeftToWrite != 0; read++ {
+		if err := canceled(ctx, read); err != nil {
+			return nil, fmt.Errorf("subset: %w", err)
+		}
+		pt.update(read)
 		var rec *Record
 		rec, err := rs.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("subset: read error on csv file: %v", err)
+			return nil, fmt.Errorf("subset: read error on csv file: %w", err)
 		}
 
 		// This step is a SIGNFICANT performance penalty, but helpful in scenarios when
 		// the underlying file cannot be reopened.
 		if multipass {
-			copyWriter.Write(rec.Data())
+			copyWriter.Write(*rec)
 		}
 
 		match, err := m.Match(rec)
@@ -431,28 +946,29 @@ func (rs *RecordSet) SubsetLimit(m Matching, n int, multipass bool) (*RecordSet,
 		if match {
 			err := rs2.Write(*rec)
 			if err != nil {
-				return nil, fmt.Errorf("subset: csv write error: %v", err)
+				return nil, fmt.Errorf("subset: csv write error: %w", err)
 			}
 			recordsLeftToWrite--
 			if recordsLeftToWrite%flushThreshold == 0 {
 				err := rs2.Flush()
 				if err != nil {
-					return nil, fmt.Errorf("subset: csv flush error: %v", err)
+					return nil, fmt.Errorf("subset: csv flush error: %w", err)
 				}
 			}
 		}
 	}
 	err := rs2.Flush()
 	if err != nil {
-		return nil, fmt.Errorf("subset: csv flush error: %v", err)
+		return nil, fmt.Errorf("subset: csv flush error: %w", err)
 	}
+	pt.done(read)
 
 	if recordsLeftToWrite == n { // no change, therefore no records written
 		return rs2, ErrEmptySet
 	}
 	if multipass {
 		copyWriter.Flush()
-		rs.r = csv.NewReader(copyBuf)
+		rs.rewind(copyBuf)
 	}
 	return rs2, nil
 }
@@ -465,6 +981,11 @@ func (rs *RecordSet) Subset(m Matching) (*RecordSet, error) {
 	return rs.SubsetLimit(m, -1, false)
 }
 
+// SubsetContext is Subset with a Context that stops the scan when it is canceled.
+func (rs *RecordSet) SubsetContext(ctx context.Context, m Matching) (*RecordSet, error) {
+	return rs.SubsetLimitContext(ctx, m, -1, false)
+}
+
 // UniqueVessels returns a VesselMap, map[Vessel]int, that includes a unique key for
 // each Vessel in the RecordSet.  The value of each key is the number of Records for
 // that Vessel in the data.
@@ -480,12 +1001,18 @@ func (rs *RecordSet) UniqueVessels() (VesselSet, error) {
 // the returned VesselSet to create a Subset of data for each ship requires reusing the
 // rs reciver in most cases.
 func (rs *RecordSet) UniqueVesselsMulti(multipass bool) (VesselSet, error) {
+	return rs.UniqueVesselsContext(context.Background(), multipass)
+}
+
+// UniqueVesselsContext is UniqueVesselsMulti with a Context that stops the scan
+// when it is canceled.
+func (rs *RecordSet) UniqueVesselsContext(ctx context.Context, multipass bool) (VesselSet, error) {
 	vs := make(VesselSet)
 	var defaultVesselName = "no VesselName header"
 
 	mmsiIndex, ok := rs.Headers().Contains("MMSI")
 	if !ok {
-		return nil, fmt.Errorf("unique vessels: recordset does not contain MMSI header")
+		return nil, fmt.Errorf("unique vessels: %w", ErrMissingHeader{Field: "MMSI"})
 	}
 	vesselNameIndex, okVesselName := rs.Headers().Contains("VesselName")
 
@@ -498,18 +1025,25 @@ func (rs *RecordSet) UniqueVesselsMulti(multipass bool) (VesselSet, error) {
 	// efficient solution would probably be controlling the Seek() value of the underlying
 	// decriptor, but csv.Reader does not expose this pointer.
 	copyBuf := &bytes.Buffer{}
-	copyWriter := bufio.NewWriter(copyBuf)
-
-	for {
-		rec, err = rs.Read()
+	copyWriter := newRecordWriter(copyBuf)
+	copyWriter.Comma = rs.r.Comma
+
+	pt := startProgress(ctx, "unique vessels", rs)
+	n := 0
+	for ; ; n++ {
+		if err := canceled(ctx, n); err != nil {
+			return nil, fmt.Errorf("unique vessel: %w", err)
+		}
+		pt.update(n)
+		rec, err = rs.read(true) // only the strings of each Record are kept
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("unique vessel: read error on csv file: %v", err)
+			return nil, fmt.Errorf("unique vessel: read error on csv file: %w", err)
 		}
 		if multipass {
-			copyWriter.Write(rec.Data())
+			copyWriter.Write(*rec)
 		}
 
 		if okVesselName {
@@ -518,9 +1052,10 @@ func (rs *RecordSet) UniqueVesselsMulti(multipass bool) (VesselSet, error) {
 			vs[Vessel{MMSI: (*rec)[mmsiIndex], VesselName: defaultVesselName}]++
 		}
 	}
+	pt.done(n)
 	if multipass {
 		copyWriter.Flush()
-		rs.r = csv.NewReader(copyBuf)
+		rs.rewind(copyBuf)
 	}
 	return vs, nil
 }
@@ -528,33 +1063,46 @@ func (rs *RecordSet) UniqueVesselsMulti(multipass bool) (VesselSet, error) {
 // SortByTime returns a pointer to a new RecordSet sorted in ascending order
 // by BaseDateTime.
 func (rs *RecordSet) SortByTime() (*RecordSet, error) {
+	return rs.SortByTimeContext(context.Background())
+}
+
+// SortByTimeContext is SortByTime with a Context that stops loading or writing the
+// Records when it is canceled.
+func (rs *RecordSet) SortByTimeContext(ctx context.Context) (*RecordSet, error) {
 	rs2 := NewRecordSet()
 	rs2.SetHeaders(rs.Headers())
 
-	bt, err := NewByTimestamp(rs)
+	data, err := rs.loadRecords(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("sortbytime: %v", err)
+		return nil, fmt.Errorf("sortbytime: new bytimestamp: unable to load data: %w", err)
 	}
+	bt := &ByTimestamp{h: rs.Headers(), data: data}
 
 	sort.Sort(bt)
+	pt := startProgress(ctx, "sortbytime", nil)
 
 	// Write the reports to the new RecordSet
 	// NOTE: Headers are written only when the RecordSet is saved to disk
 	written := 0
 	for _, rec := range *bt.data {
+		if err := canceled(ctx, written); err != nil {
+			return nil, fmt.Errorf("sortbytime: %w", err)
+		}
+		pt.update(written)
 		rs2.Write(rec)
 		written++
 		if written%flushThreshold == 0 {
 			err := rs2.Flush()
 			if err != nil {
-				return nil, fmt.Errorf("sortbytime: flush error writing to new recordset: %v", err)
+				return nil, fmt.Errorf("sortbytime: flush error writing to new recordset: %w", err)
 			}
 		}
 	}
 	err = rs2.Flush()
 	if err != nil {
-		return nil, fmt.Errorf("sortbytime: flush error writing to new recordset: %v", err)
+		return nil, fmt.Errorf("sortbytime: flush error writing to new recordset: %w", err)
 	}
+	pt.done(written)
 
 	return rs2, nil
 }
@@ -578,14 +1126,14 @@ type Box struct {
 func (b *Box) Match(rec *Record) (bool, error) {
 	lat, err := rec.ParseFloat(b.LatIndex)
 	if err != nil {
-		return false, fmt.Errorf("unable to parse %v", (*rec)[b.LatIndex])
+		return false, ErrParse{Field: "LAT", Err: err}
 	}
 	lon, err := rec.ParseFloat(b.LonIndex)
 	if err != nil {
-		return false, fmt.Errorf("unable to parse %v", (*rec)[b.LonIndex])
+		return false, ErrParse{Field: "LON", Err: err}
 	}
 
-	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon, nil
+	return b.Contains(lat, lon), nil
 }
 
 // ByTimestamp implements the sort.Interface for creating a RecordSet
@@ -607,9 +1155,9 @@ func NewByTimestamp(rs *RecordSet) (*ByTimestamp, error) {
 
 	// Read the data from the underlying Recordset into a slice
 	var err error
-	bt.data, err = rs.loadRecords()
+	bt.data, err = rs.loadRecords(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("new bytimestamp: unable to load data: %v", err)
+		return nil, fmt.Errorf("new bytimestamp: unable to load data: %w", err)
 	}
 
 	return bt, nil
@@ -629,11 +1177,11 @@ func (bt ByTimestamp) Less(i, j int) bool {
 	if !ok {
 		panic("bytimestamp: less: headers does not contain BaseDateTime")
 	}
-	t1, err := time.Parse(TimeLayout, (*bt.data)[i][timeIndex])
+	t1, err := bt.h.parseTime((*bt.data)[i][timeIndex])
 	if err != nil {
 		panic(err)
 	}
-	t2, err := time.Parse(TimeLayout, (*bt.data)[j][timeIndex])
+	t2, err := bt.h.parseTime((*bt.data)[j][timeIndex])
 	if err != nil {
 		panic(err)
 	}
@@ -642,12 +1190,18 @@ func (bt ByTimestamp) Less(i, j int) bool {
 
 // Unexported loadRecords reads the RecordSet into memory and returns a
 // *[]Record and any error that occurred.  If err is non-nil then loadRecords
-// returns nil for the *[]Record
-func (rs *RecordSet) loadRecords() (*[]Record, error) {
+// returns nil for the *[]Record.  Loading stops with ctx.Err() when ctx is canceled.
+func (rs *RecordSet) loadRecords(ctx context.Context) (*[]Record, error) {
 	recs := new([]Record)
 
+	pt := startProgress(ctx, "load records", rs)
 	record := new(Record)
-	for {
+	n := 0
+	for ; ; n++ {
+		if err := canceled(ctx, n); err != nil {
+			return nil, err
+		}
+		pt.update(n)
 		var err error
 		record, err = rs.Read()
 		if err == io.EOF {
@@ -659,9 +1213,20 @@ func (rs *RecordSet) loadRecords() (*[]Record, error) {
 
 		*recs = append(*recs, *record)
 	}
+	pt.done(n)
 	return recs, nil
 }
 
+// DefaultFields are the column headers used by the MarineCadastre.gov AIS data
+// files.  Records created by the package from sources other than a csv file, for
+// example by a Decoder, use these Headers.
+const DefaultFields = "MMSI,BaseDateTime,LAT,LON,SOG,COG,Heading,VesselName,IMO,CallSign,VesselType,Status,Length,Width,Draft,Cargo"
+
+// DefaultHeaders returns a new set of Headers built from DefaultFields.
+func DefaultHeaders() Headers {
+	return Headers{Fields: strings.Split(DefaultFields, ",")}
+}
+
 // Headers are the field names for AIS data elements in a Record.
 type Headers struct {
 	// Fields is an encapsulated []string . It is initialized from the first
@@ -669,23 +1234,44 @@ type Headers struct {
 	// is called.
 	Fields []string
 
-	// DEPRECATED
-	// dictionary is a map[fieldname]description composed of string values
-	// usually created from a JSON file that contains
-	// Definition structs for each of the fields in the set of ais.Headers.
-	// dictionary map[string]string
+	// Mapping holds alternative names for the fields, so that Contains("MMSI")
+	// finds a column named "mmsi" in an archive that does not use the
+	// MarineCadastre.gov names.  See HeaderMapping.
+	Mapping HeaderMapping
+
+	// Time parses the BaseDateTime field.  When it is nil BaseDateTime must be
+	// written in TimeLayout.  See TimeParser.
+	Time TimeParser
+
+	// Sources classifies Records as Class B or satellite reports and holds the
+	// tolerances applied to them.  When it is nil every Record is a terrestrial
+	// Class A report.  See SourceRules.
+	Sources *SourceRules
+
+	// Dictionary labels the coded values of fields such as Status and
+	// VesselType for RecordSet.Expand.  When it is nil Expand uses
+	// DefaultDictionary.  See Dictionary.
+	Dictionary Dictionary
 }
 
 // Contains returns the index of a specific header.  This provides
 // a nice syntax ais.Headers().Contains("LAT") to ensure
 // an ais.Record contains a specific field.  If the Headers do not
-// contain the requested field ok is false.
+// contain the requested field ok is false.  When no field has exactly the
+// requested name, the aliases given for it by the Mapping are tried.
 func (h Headers) Contains(field string) (i int, ok bool) {
 	for i, s := range h.Fields {
 		if s == field {
 			return i, true
 		}
 	}
+	for _, alias := range h.Mapping[field] {
+		for i, s := range h.Fields {
+			if strings.EqualFold(s, alias) {
+				return i, true
+			}
+		}
+	}
 	return 0, false
 }
 
@@ -768,26 +1354,47 @@ func (r Record) Hash() uint64 {
 	return h64.Sum64()
 }
 
-// Data returns the underlying []string in a Record as a []byte
+// Data returns the underlying []string in a Record as a []byte, a line of csv
+// with the fields quoted as RFC 4180 requires.
 func (r Record) Data() []byte {
 	var b bytes.Buffer
-	b.WriteString(strings.Join([]string(r), ","))
-	b.WriteString("\n")
+	w := newRecordWriter(&b)
+	w.Write(r)
+	w.Flush()
 	return b.Bytes()
 }
 
-// Distance calculates the haversine distance between two AIS records that
-// contain a latitude and longitude measurement identified by their index
-// number in the Record slice.
+// Distance returns the haversine distance in nautical miles between two AIS
+// records that contain a latitude and longitude measurement identified by their
+// index number in the Record slice.  It returns an ErrParse when a value is not
+// a number or is the placeholder for a position that is not available, as
+// DistanceTo does.
+//
+// Deprecated: use DistanceTo, which finds the fields by Headers and returns the
+// distance in a chosen DistanceUnit.
 func (r Record) Distance(r2 Record, latIndex, lonIndex int) (nm float64, err error) {
-	latP, _ := r.ParseFloat(latIndex)
-	lonP, _ := r.ParseFloat(lonIndex)
-	latQ, _ := r2.ParseFloat(latIndex)
-	lonQ, _ := r2.ParseFloat(lonIndex)
-	p := haversine.Coord{Lat: latP, Lon: lonP}
-	q := haversine.Coord{Lat: latQ, Lon: lonQ}
-	nm = haversine.Distance(p, q)
-	return nm, nil
+	var pos [4]float64
+	for i, v := range []struct {
+		rec   Record
+		index int
+		field string
+	}{
+		{r, latIndex, "LAT"}, {r, lonIndex, "LON"},
+		{r2, latIndex, "LAT"}, {r2, lonIndex, "LON"},
+	} {
+		f, err := v.rec.ParseFloat(v.index)
+		if err != nil {
+			return 0, ErrParse{Field: v.field, Err: err}
+		}
+		pos[i] = f
+	}
+	if err := checkPosition(pos[0], pos[1]); err != nil {
+		return 0, err
+	}
+	if err := checkPosition(pos[2], pos[3]); err != nil {
+		return 0, err
+	}
+	return Haversine(pos[0], pos[1], pos[2], pos[3]), nil
 }
 
 // ParseFloat wraps strconv.ParseFloat with a method to return a
@@ -8,6 +8,9 @@ package ais
 import (
 	"encoding/csv"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -572,10 +575,116 @@ func TestOpenRecordSet(t *testing.T) {
 	}
 }
 
+func TestOpenRecordSet_ReadColumns(t *testing.T) {
+	rs, err := OpenRecordSet("testdata/ten.csv", ReadColumns("LON", "LAT", "MMSI"))
+	if err != nil {
+		t.Fatalf("OpenRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+	if got := strings.Join(rs.Headers().Fields, ","); got != "LON,LAT,MMSI" {
+		t.Errorf("OpenRecordSet() headers = %s, want LON,LAT,MMSI", got)
+	}
+	rec, err := rs.Read()
+	if err != nil {
+		t.Fatalf("RecordSet.Read() error = %v", err)
+	}
+	if want := (Record{"-76.32652", "31.90512", "477307901"}); !reflect.DeepEqual(*rec, want) {
+		t.Errorf("RecordSet.Read() = %v, want %v", *rec, want)
+	}
+	second, _ := rs.Read()
+	if (*rec)[2] != "477307901" || (*second)[2] != "338029922" {
+		t.Errorf("RecordSet.Read() reused the fields of an earlier Record")
+	}
+	if err := rs.Write(Record{"0", "0", "0"}); err == nil {
+		if err := rs.Flush(); err == nil {
+			t.Error("RecordSet opened with ReadColumns is not read only")
+		}
+	}
+
+	if _, err := OpenRecordSet("testdata/ten.csv", ReadColumns("MMSI", "Nope")); err == nil {
+		t.Error("OpenRecordSet() expected error for unknown column")
+	}
+	if _, err := OpenRecordSet("testdata/ten.csv", ReadColumns()); err == nil {
+		t.Error("OpenRecordSet() expected error for no columns")
+	}
+}
+
+func TestRecordSet_SaveReadColumns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "projected.csv")
+
+	rs, err := OpenRecordSet("testdata/ten.csv", ReadColumns("MMSI", "LAT"))
+	if err != nil {
+		t.Fatalf("OpenRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+	if err := rs.Save(filename); err != nil {
+		t.Fatalf("RecordSet.Save() error = %v", err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("RecordSet.Save() wrote rows of different lengths: %v", err)
+	}
+	if len(rows) != 11 {
+		t.Fatalf("RecordSet.Save() wrote %d rows, want a header and 10 Records", len(rows))
+	}
+	if want := []string{"MMSI", "LAT"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("RecordSet.Save() header = %v, want %v", rows[0], want)
+	}
+	if want := []string{"477307901", "31.90512"}; !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("RecordSet.Save() first row = %v, want %v", rows[1], want)
+	}
+}
+
+func TestRecordSet_AppendFieldReadColumns(t *testing.T) {
+	geohashes := func(opts ...OpenOption) []string {
+		rs, err := OpenRecordSet("testdata/ten.csv", opts...)
+		if err != nil {
+			t.Fatalf("OpenRecordSet() error = %v", err)
+		}
+		defer rs.Close()
+		rs2, err := rs.AppendField("Geohash", []string{"LAT", "LON"}, NewGeohasher(rs))
+		if err != nil {
+			t.Fatalf("RecordSet.AppendField() error = %v", err)
+		}
+		defer rs2.Close()
+		n := len(rs2.Headers().Fields)
+		var got []string
+		for {
+			rec, err := rs2.Read()
+			if err == io.EOF {
+				return got
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*rec) != n {
+				t.Fatalf("RecordSet.AppendField() record %v has %d fields, want %d", *rec, len(*rec), n)
+			}
+			got = append(got, (*rec)[n-1])
+		}
+	}
+
+	want := geohashes()
+	got := geohashes(ReadColumns("MMSI", "LON", "LAT"))
+	if len(want) != 10 || !reflect.DeepEqual(got, want) {
+		t.Errorf("AppendField with ReadColumns generated %v, want %v", got, want)
+	}
+}
+
 func TestRecordSet_readFirst(t *testing.T) {
 	type fields struct {
 		r     *csv.Reader
-		w     *csv.Writer
+		w     *recordWriter
 		h     Headers
 		data  io.ReadWriter
 		first *Record
@@ -658,7 +767,7 @@ func TestRecordSet_Stash(t *testing.T) {
 func TestRecordSet_Read(t *testing.T) {
 	type fields struct {
 		r     *csv.Reader
-		w     *csv.Writer
+		w     *recordWriter
 		h     Headers
 		data  io.ReadWriter
 		first *Record
@@ -722,7 +831,7 @@ func TestRecordSet_Read(t *testing.T) {
 func TestRecordSet_SubsetLimit(t *testing.T) {
 	type fields struct {
 		r     *csv.Reader
-		w     *csv.Writer
+		w     *recordWriter
 		h     Headers
 		data  io.ReadWriter
 		first *Record
@@ -820,7 +929,7 @@ func TestRecordSet_SubsetLimit(t *testing.T) {
 func TestRecordSet_UniqueVessels(t *testing.T) {
 	type fields struct {
 		r     *csv.Reader
-		w     *csv.Writer
+		w     *recordWriter
 		h     Headers
 		data  io.ReadWriter
 		first *Record
@@ -901,7 +1010,7 @@ func TestRecordSet_UniqueVessels(t *testing.T) {
 				rs.h = tt.fields.h
 			} else {
 				rec, _ := rs.Read()
-				rs.SetHeaders(Headers{[]string(*rec)})
+				rs.SetHeaders(Headers{Fields: []string(*rec)})
 			}
 			got, err := rs.UniqueVessels()
 			if (err != nil) != tt.wantErr {
@@ -922,7 +1031,7 @@ func TestRecordSet_UniqueVessels(t *testing.T) {
 func TestRecordSet_UniqueVesselsMulti(t *testing.T) {
 	type fields struct {
 		r     *csv.Reader
-		w     *csv.Writer
+		w     *recordWriter
 		h     Headers
 		data  io.ReadWriter
 		first *Record
@@ -960,7 +1069,7 @@ func TestRecordSet_UniqueVesselsMulti(t *testing.T) {
 				rs.h = tt.fields.h
 			} else {
 				rec, _ := rs.Read()
-				rs.SetHeaders(Headers{[]string(*rec)})
+				rs.SetHeaders(Headers{Fields: []string(*rec)})
 			}
 			got, _ := rs.UniqueVesselsMulti(true)
 			// got, _ = rs.UniqueVessels()
@@ -976,3 +1085,128 @@ func TestRecordSet_UniqueVesselsMulti(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRecordSetFromReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		h         Headers
+		wantH     Headers
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name:      "headers read from stream",
+			data:      testString,
+			h:         Headers{},
+			wantH:     goodHeaders,
+			wantCount: 3,
+		},
+		{
+			name:      "headers provided by client",
+			data:      strings.Join(strings.Split(testString, "\n")[2:], "\n"),
+			h:         goodHeaders,
+			wantH:     goodHeaders,
+			wantCount: 3,
+		},
+		{
+			name:    "empty stream without headers",
+			data:    "",
+			h:       Headers{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := NewRecordSetFromReader(strings.NewReader(tt.data), tt.h)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRecordSetFromReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer rs.Close()
+			if !rs.Headers().Equals(tt.wantH) {
+				t.Errorf("NewRecordSetFromReader() headers = %v, want %v", rs.Headers(), tt.wantH)
+			}
+			count := 0
+			for rs.Next() {
+				count++
+			}
+			if err := rs.Err(); err != nil {
+				t.Errorf("RecordSet.Err() = %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("RecordSet.Next() iterated %d records, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRecordSet_Next(t *testing.T) {
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+
+	if !rs.Next() {
+		t.Fatalf("RecordSet.Next() = false on first call, want true")
+	}
+	if got := []string(*rs.Record()); !reflect.DeepEqual(got, firstRec) {
+		t.Errorf("RecordSet.Record() = %v, want %v", got, firstRec)
+	}
+
+	errSet := &RecordSet{
+		h: goodHeaders,
+		r: csv.NewReader(&errorReader{}),
+	}
+	if errSet.Next() {
+		t.Errorf("RecordSet.Next() = true on errorReader, want false")
+	}
+	if errSet.Err() == nil {
+		t.Errorf("RecordSet.Err() = nil on errorReader, want error")
+	}
+	if errSet.Record() != nil {
+		t.Errorf("RecordSet.Record() = %v after failed Next, want nil", errSet.Record())
+	}
+}
+
+func TestRecordSet_SetReuseRecord(t *testing.T) {
+	for _, opts := range [][]OpenOption{nil, {ReadColumns("MMSI", "LAT", "LON")}} {
+		want, _ := OpenRecordSet("testdata/ten.csv", opts...)
+		rs, _ := OpenRecordSet("testdata/ten.csv", opts...)
+		rs.SetReuseRecord(true)
+
+		var first *Record
+		n := 0
+		for rs.Next() {
+			rec := rs.Record()
+			if first == nil {
+				first = rec
+			} else if rec != first {
+				t.Errorf("RecordSet.Next() with reuse returned a new Record")
+			}
+			w, _ := want.Read()
+			if !reflect.DeepEqual(*rec, *w) {
+				t.Errorf("RecordSet.Record() = %v, want %v", *rec, *w)
+			}
+			n++
+		}
+		if err := rs.Err(); err != nil {
+			t.Errorf("RecordSet.Err() = %v", err)
+		}
+		if n != 10 {
+			t.Errorf("RecordSet.Next() read %d Records, want 10", n)
+		}
+		rs.Close()
+		want.Close()
+	}
+
+	// Read returns Records owned by the caller with reuse on.
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	rs.SetReuseRecord(true)
+	rec1, _ := rs.Read()
+	rec2, _ := rs.Read()
+	if reflect.DeepEqual(*rec1, *rec2) {
+		t.Errorf("RecordSet.Read() with reuse overwrote an earlier Record")
+	}
+}
@@ -0,0 +1,224 @@
+package ais
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnomalyFields are the columns written by AnomalyReports.Save, one row per
+// Anomaly.
+const AnomalyFields = "MMSI,Anomaly,BaseDateTime,LAT,LON,Detail"
+
+// AnomalyKind identifies a suspicious behavior found by RecordSet.DetectAnomalies.
+type AnomalyKind int
+
+const (
+	// TransmissionGap is a vessel that stops reporting for longer than
+	// AnomalyRules.MaxGap where coverage is good, which may mean its AIS was
+	// switched off.
+	TransmissionGap AnomalyKind = iota
+
+	// SpeedJump is a position that could only be reached from the previous fix of
+	// the same vessel at a speed above AnomalyRules.MaxImpliedSpeed.
+	SpeedJump
+
+	// MMSIConflict is a position more than AnomalyRules.ConflictDistance from
+	// another report of the same MMSI within AnomalyRules.ConflictWindow, so two
+	// transmitters are using the MMSI at once.
+	MMSIConflict
+
+	// OnLand is a position inside AnomalyRules.Land.
+	OnLand
+
+	numAnomalyKinds
+)
+
+var anomalyKindNames = [...]string{
+	TransmissionGap: "gap",
+	SpeedJump:       "speed jump",
+	MMSIConflict:    "mmsi conflict",
+	OnLand:          "on land",
+}
+
+// String implements the Stringer interface for AnomalyKind.
+func (k AnomalyKind) String() string {
+	if k < 0 || k >= numAnomalyKinds {
+		return fmt.Sprintf("AnomalyKind(%d)", int(k))
+	}
+	return anomalyKindNames[k]
+}
+
+// AnomalyRules configures RecordSet.DetectAnomalies.  A limit of zero, or a nil
+// Geofence for Land, disables the check.
+type AnomalyRules struct {
+	MaxGap           time.Duration // longest silence between reports of one vessel
+	Coverage         *Geofence     // gaps are reported only when both ends are inside it, nil for everywhere
+	MaxImpliedSpeed  float64       // knots, between consecutive fixes of one vessel
+	ConflictWindow   time.Duration // reports of one MMSI this close in time are simultaneous
+	ConflictDistance float64       // nm, farthest apart simultaneous reports of one MMSI may be
+	Land             *Geofence     // positions inside it are on land
+}
+
+// DefaultAnomalyRules returns AnomalyRules that report silences of more than
+// six hours, fixes that imply more than 100 knots, and reports of one MMSI more
+// than 10 nm apart within a minute.  Land and Coverage are not set.
+func DefaultAnomalyRules() AnomalyRules {
+	return AnomalyRules{
+		MaxGap:           6 * time.Hour,
+		MaxImpliedSpeed:  100,
+		ConflictWindow:   time.Minute,
+		ConflictDistance: 10,
+	}
+}
+
+// Anomaly is one suspicious report of a vessel.
+type Anomaly struct {
+	Kind     AnomalyKind
+	Time     time.Time // BaseDateTime of the Record that raised the anomaly
+	LAT, LON float64
+	Detail   string // the length of a gap, an implied speed, or the distance of a conflict
+}
+
+// AnomalyReport lists the anomalies of one MMSI in the order they were found.
+type AnomalyReport struct {
+	MMSI      string
+	Records   int // number of Records of the MMSI that were checked
+	Counts    [numAnomalyKinds]int
+	Anomalies []Anomaly
+}
+
+// AnomalyReports are the reports of the vessels with at least one anomaly,
+// sorted by MMSI.
+type AnomalyReports []*AnomalyReport
+
+// DetectAnomalies reads the RecordSet and returns a report for every MMSI with
+// an anomaly: a TransmissionGap, SpeedJump, MMSIConflict, or OnLand position as
+// configured by rules.  Each Record is compared with the last fix of the same
+// MMSI, so the RecordSet should be sorted by time, and one fix per vessel is
+// kept in memory.  A Record that raises a SpeedJump or MMSIConflict does not
+// replace the last fix, so a single spoofed position is reported once rather
+// than twice, and a second transmitter using the MMSI is reported at each of its
+// fixes.  Records with a position that is not available or cannot be parsed are
+// skipped; CleanKinematics reports those.  The SourceRules of the Headers
+// lengthen MaxGap for Class B and satellite reports and widen the time between
+// fixes by their TimeSkew.  The Headers must contain MMSI, BaseDateTime, LAT,
+// and LON.  DetectAnomalies consumes the receiver.
+func (rs *RecordSet) DetectAnomalies(rules AnomalyRules) (AnomalyReports, error) {
+	h := rs.Headers()
+	idx, err := h.require("MMSI", "BaseDateTime", "LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("detect anomalies: %w", err)
+	}
+
+	reports := make(map[string]*AnomalyReport)
+	last := make(map[string]cleanFix)
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("detect anomalies: read error on csv file: %w", err)
+		}
+		lat, errLat := rec.ParseFloat(idx["LAT"].Idx)
+		lon, errLon := rec.ParseFloat(idx["LON"].Idx)
+		if errLat != nil || errLon != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			continue
+		}
+		t, err := h.parseTime((*rec)[idx["BaseDateTime"].Idx])
+		if err != nil {
+			return nil, fmt.Errorf("detect anomalies: %w", err)
+		}
+
+		mmsi := (*rec)[idx["MMSI"].Idx]
+		r, ok := reports[mmsi]
+		if !ok {
+			r = &AnomalyReport{MMSI: mmsi}
+			reports[mmsi] = r
+		}
+		r.Records++
+		add := func(kind AnomalyKind, detail string) {
+			r.Counts[kind]++
+			r.Anomalies = append(r.Anomalies, Anomaly{Kind: kind, Time: t, LAT: lat, LON: lon, Detail: detail})
+		}
+
+		if rules.Land != nil && rules.Land.Contains(lat, lon) {
+			add(OnLand, "")
+		}
+		tol := rec.tolerance(h)
+		prev, seen := last[mmsi]
+		if !seen {
+			last[mmsi] = cleanFix{lat, lon, t, tol.TimeSkew}
+			continue
+		}
+		dt := t.Sub(prev.t)
+		if dt < 0 {
+			dt = -dt
+		}
+		skew := tol.TimeSkew
+		if prev.skew > skew {
+			skew = prev.skew
+		}
+		maxGap := rules.MaxGap
+		if maxGap > 0 && tol.MaxGap > maxGap {
+			maxGap = tol.MaxGap
+		}
+		d := Haversine(prev.lat, prev.lon, lat, lon)
+		if maxGap > 0 && dt > maxGap &&
+			(rules.Coverage == nil || rules.Coverage.Contains(prev.lat, prev.lon) && rules.Coverage.Contains(lat, lon)) {
+			add(TransmissionGap, dt.String())
+		}
+		speed := prev.impliedSpeed(lat, lon, t, tol.TimeSkew)
+		switch {
+		case rules.ConflictDistance > 0 && dt <= rules.ConflictWindow+skew && d > rules.ConflictDistance:
+			add(MMSIConflict, fmt.Sprintf("%.1f nm in %v", d, dt))
+		case rules.MaxImpliedSpeed > 0 && (dt > 0 || skew > 0) && speed > rules.MaxImpliedSpeed:
+			add(SpeedJump, fmt.Sprintf("%.1f knots", speed))
+		default:
+			last[mmsi] = cleanFix{lat, lon, t, tol.TimeSkew}
+		}
+	}
+
+	var out AnomalyReports
+	for _, r := range reports {
+		if len(r.Anomalies) > 0 {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].MMSI < out[j].MMSI })
+	return out, nil
+}
+
+// Save writes every Anomaly of the reports to a csv file with AnomalyFields.
+func (ar AnomalyReports) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("anomalies save: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(strings.Split(AnomalyFields, ","))
+	for _, r := range ar {
+		for _, a := range r.Anomalies {
+			w.Write([]string{
+				r.MMSI,
+				a.Kind.String(),
+				a.Time.Format(TimeLayout),
+				strconv.FormatFloat(a.LAT, 'f', -1, 64),
+				strconv.FormatFloat(a.LON, 'f', -1, 64),
+				a.Detail,
+			})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("anomalies save: %w", err)
+	}
+	return f.Close()
+}
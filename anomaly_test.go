@@ -0,0 +1,87 @@
+package ais
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const anomalyData = `MMSI,BaseDateTime,LAT,LON
+100000000,2017-12-01T00:00:00,30.00000,-76.00000
+200000000,2017-12-01T00:00:00,35.00000,-75.00000
+300000000,2017-12-01T00:00:00,32.00000,-76.00000
+100000000,2017-12-01T00:01:00,30.00000,-76.01000
+200000000,2017-12-01T01:00:00,35.00000,-75.20000
+300000000,2017-12-01T00:05:00,32.01000,-76.00000
+100000000,2017-12-01T08:00:00,30.00000,-76.00000
+100000000,2017-12-01T08:01:00,30.50000,-76.00000
+100000000,2017-12-01T08:30:00,31.50000,-76.00000
+100000000,2017-12-01T08:40:00,91.00000,-76.00000
+`
+
+func TestRecordSet_DetectAnomalies(t *testing.T) {
+	land, err := NewGeofenceWKT("POLYGON ((-75.1 34.9, -74.9 34.9, -74.9 35.1, -75.1 35.1, -75.1 34.9))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := DefaultAnomalyRules()
+	rules.Land = land
+	rs, _ := NewRecordSetFromReader(strings.NewReader(anomalyData), Headers{})
+	reports, err := rs.DetectAnomalies(rules)
+	if err != nil {
+		t.Fatalf("RecordSet.DetectAnomalies() error = %v", err)
+	}
+	if len(reports) != 2 || reports[0].MMSI != "100000000" || reports[1].MMSI != "200000000" {
+		t.Fatalf("RecordSet.DetectAnomalies() = %v, want reports for 100000000 and 200000000", reports)
+	}
+
+	r := reports[0]
+	if r.Records != 5 || r.Counts != [numAnomalyKinds]int{1, 1, 1, 0} {
+		t.Errorf("RecordSet.DetectAnomalies() report = %+v", r)
+	}
+	var kinds []string
+	for _, a := range r.Anomalies {
+		kinds = append(kinds, a.Kind.String())
+	}
+	if got, want := strings.Join(kinds, ","), "gap,mmsi conflict,speed jump"; got != want {
+		t.Errorf("RecordSet.DetectAnomalies() anomalies = %s, want %s", got, want)
+	}
+	if a := r.Anomalies[0]; a.Detail != "7h59m0s" || a.Time.Hour() != 8 {
+		t.Errorf("RecordSet.DetectAnomalies() gap = %+v", a)
+	}
+	if r := reports[1]; r.Counts[OnLand] != 1 || len(r.Anomalies) != 1 {
+		t.Errorf("RecordSet.DetectAnomalies() on land report = %+v", r)
+	}
+
+	// A coverage area that excludes the gap suppresses it.
+	rules.Coverage, _ = NewGeofenceWKT("POLYGON ((-80 40, -70 40, -70 45, -80 45, -80 40))")
+	rs, _ = NewRecordSetFromReader(strings.NewReader(anomalyData), Headers{})
+	reports, _ = rs.DetectAnomalies(rules)
+	if reports[0].Counts[TransmissionGap] != 0 {
+		t.Errorf("RecordSet.DetectAnomalies() reported a gap outside of Coverage")
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "anomalies.csv")
+	if err := reports.Save(filename); err != nil {
+		t.Fatalf("AnomalyReports.Save() error = %v", err)
+	}
+	f, _ := os.Open(filename)
+	defer f.Close()
+	rows, _ := csv.NewReader(f).ReadAll()
+	if len(rows) != 4 || rows[3][1] != "on land" {
+		t.Errorf("AnomalyReports.Save() wrote %v", rows)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,LAT,LON\n"), Headers{})
+	if _, err := rs.DetectAnomalies(rules); err == nil {
+		t.Error("RecordSet.DetectAnomalies() expected error for missing BaseDateTime")
+	}
+}
@@ -0,0 +1,100 @@
+package ais
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// appendState records the pairs of an Interactions set already written by
+// AppendCSV.
+type appendState struct {
+	sync.Mutex
+	m       map[Hash128]*RecordPair // nil before the first AppendCSV
+	spilled map[Hash128]int64       // spill file entry of the pairs written and then spilled
+}
+
+// AppendSave appends the interactions added to the set since the last
+// AppendSave or AppendCSV to filename, which is created with the OutputHeaders
+// when it does not exist or is empty.  A long running pipeline, such as one fed
+// by a live Feed, can call AppendSave periodically to flush its new
+// interactions without rewriting the whole file.  The header line of an
+// existing file must match the OutputHeaders.  It returns the number of
+// interactions appended.
+func (inter *Interactions) AppendSave(filename string) (int, error) {
+	return inter.AppendSaveContext(context.Background(), filename)
+}
+
+// AppendSaveContext is AppendSave with a Context that stops the write when it
+// is canceled.
+func (inter *Interactions) AppendSaveContext(ctx context.Context, filename string) (int, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return 0, fmt.Errorf("interactions append save: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("interactions append save: %w", err)
+	}
+	header := fi.Size() == 0
+	if !header {
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = -1
+		fields, err := r.Read()
+		if err != nil {
+			return 0, fmt.Errorf("interactions append save: %s: %w", filename, err)
+		}
+		if !inter.OutputHeaders.Equals(Headers{Fields: fields}) {
+			return 0, fmt.Errorf("interactions append save: %s: headers differ from the output headers", filename)
+		}
+	}
+	n, err := inter.AppendCSV(ctx, f, header)
+	if err != nil {
+		return n, err
+	}
+	return n, f.Close()
+}
+
+// AppendCSV writes the interactions added to the set since the last AppendCSV
+// or AppendSave to w as csv, preceded by the OutputHeaders when header is set,
+// and returns the number of interactions written.  An interaction is new when
+// its pair was not written before under the same InteractionHash, so a closer
+// pair that replaces one already written, with WithTimeBucket or
+// WithClosestApproach, is written again and the last row for an
+// InteractionHash is the current one.  The pairs written are only recorded
+// when the whole write succeeds, so after an error they are written again by
+// the next call.  Save and WriteCSV write every interaction and do not change
+// what is new.
+func (inter *Interactions) AppendCSV(ctx context.Context, w io.Writer, header bool) (int, error) {
+	inter.appended.Lock()
+	defer inter.appended.Unlock()
+	if inter.appended.m == nil {
+		inter.appended.m = make(map[Hash128]*RecordPair)
+		inter.appended.spilled = make(map[Hash128]int64)
+	}
+
+	var hashes []Hash128
+	var pairs []*RecordPair
+	n, err := inter.writeRows(ctx, w, header, func(hash Hash128, pair *RecordPair) bool {
+		if pair.spill != 0 && inter.appended.spilled[hash] == pair.spill || inter.appended.m[hash] == pair {
+			return false
+		}
+		hashes, pairs = append(hashes, hash), append(pairs, pair)
+		return true
+	})
+	if err != nil {
+		return n, fmt.Errorf("append: %w", err)
+	}
+	for i, hash := range hashes {
+		if pairs[i].spill != 0 {
+			inter.appended.spilled[hash] = pairs[i].spill
+			continue
+		}
+		inter.appended.m[hash] = pairs[i]
+	}
+	return n, nil
+}
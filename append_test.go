@@ -0,0 +1,98 @@
+package ais
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInteractions_AppendSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "live.csv")
+
+	clusters := testClusters(4, 3) // three pairs in each cluster
+	inter, _ := NewInteractionsWithOptions(goodHeaders, WithRecordColumns())
+	inter.AddCluster(clusters[0])
+	n, err := inter.AppendSave(filename)
+	if err != nil || n != 3 {
+		t.Fatalf("AppendSave() = %d, %v, want 3", n, err)
+	}
+	// Nothing is new.
+	if n, err := inter.AppendSave(filename); err != nil || n != 0 {
+		t.Errorf("AppendSave() = %d, %v, want 0", n, err)
+	}
+	inter.AddCluster(clusters[1])
+	inter.AddCluster(clusters[2])
+	if n, err := inter.AppendSave(filename); err != nil || n != 6 {
+		t.Errorf("AppendSave() = %d, %v, want 6", n, err)
+	}
+
+	rs, err := OpenRecordSet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if !rs.Headers().Equals(inter.OutputHeaders) {
+		t.Errorf("AppendSave() headers = %v", rs.Headers())
+	}
+	rows := 0
+	seen := make(map[string]bool)
+	for rs.Next() {
+		hash := (*rs.Record())[0]
+		if seen[hash] {
+			t.Errorf("AppendSave() wrote %s twice", hash)
+		}
+		seen[hash] = true
+		rows++
+	}
+	if rows != 9 || inter.Len() != 9 {
+		t.Errorf("AppendSave() wrote %d rows of %d interactions, want 9", rows, inter.Len())
+	}
+
+	// A file written with other OutputHeaders is not appended to.
+	other, _ := NewInteractions(goodHeaders)
+	other.SetColumns("InteractionHash", "MMSI_1", "MMSI_2")
+	other.AddCluster(clusters[3])
+	if _, err := other.AppendSave(filename); err == nil {
+		t.Error("AppendSave() expected error for different headers")
+	}
+}
+
+func TestInteractions_AppendCSV(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	clusters := testClusters(2, 2)
+	inter.AddCluster(clusters[0])
+
+	var buf bytes.Buffer
+	if n, err := inter.AppendCSV(context.Background(), &buf, false); err != nil || n != 1 {
+		t.Fatalf("AppendCSV() = %d, %v, want 1", n, err)
+	}
+	if strings.HasPrefix(buf.String(), "InteractionHash") {
+		t.Error("AppendCSV() wrote the headers")
+	}
+	inter.AddCluster(clusters[1])
+	buf.Reset()
+	if n, err := inter.AppendCSV(context.Background(), &buf, true); err != nil || n != 1 {
+		t.Fatalf("AppendCSV() = %d, %v, want 1", n, err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("AppendCSV() wrote %d lines, want the headers and 1 row", lines)
+	}
+
+	// Save writes everything and leaves the new interactions alone.
+	var all bytes.Buffer
+	if err := inter.WriteCSV(&all); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := inter.AppendCSV(context.Background(), ioutil.Discard, false); n != 0 {
+		t.Errorf("AppendCSV() after WriteCSV = %d, want 0", n)
+	}
+}
@@ -0,0 +1,582 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Arrow type ids, message header ids and enum values used by SaveArrow and
+// OpenArrow.  The names follow Schema.fbs and Message.fbs.
+const (
+	arrowInt           = 2
+	arrowFloatingPoint = 3
+	arrowUtf8          = 5
+	arrowTimestamp     = 10
+
+	arrowSchemaMessage = 1
+	arrowRecordBatch   = 3
+
+	arrowV5 = 4
+
+	arrowSingle = 1
+	arrowDouble = 2
+
+	arrowSecond      = 0
+	arrowMillisecond = 1
+	arrowMicrosecond = 2
+	arrowNanosecond  = 3
+)
+
+// arrowMagic begins and ends every Arrow IPC file.
+const arrowMagic = "ARROW1"
+
+// arrowColumn describes one column of a flat Arrow schema.
+type arrowColumn struct {
+	name      string
+	typ       uint8 // Type union id
+	bitWidth  int32 // Int
+	signed    bool  // Int
+	precision int16 // FloatingPoint
+	unit      int16 // Timestamp
+}
+
+// arrowBlock locates a record batch in the file for the footer.
+type arrowBlock struct {
+	offset  int64
+	metaLen int32
+	bodyLen int64
+}
+
+// arrowWriter buffers a batch of Records and writes each batch as an
+// uncompressed Arrow record batch message.
+type arrowWriter struct {
+	w          *bufio.Writer
+	offset     int64
+	cols       []arrowColumn
+	schema     *fbTable
+	buf        [][]string // buffered values by column for the current batch
+	rows       int
+	batches    []arrowBlock
+	timeParser TimeParser // from the Headers, nil for TimeLayout
+}
+
+// newArrowWriter writes the file header and schema for the columns of h.  The
+// columns with a Parquet type in SaveParquet have the same type in Arrow: LAT,
+// LON, SOG, and COG are float64 and BaseDateTime is a millisecond timestamp.
+func newArrowWriter(w io.Writer, h Headers) (*arrowWriter, error) {
+	aw := &arrowWriter{w: bufio.NewWriter(w), timeParser: h.Time}
+	for _, name := range h.Fields {
+		col := arrowColumn{name: name, typ: arrowUtf8}
+		switch parquetTypes[name] {
+		case parquetDouble:
+			col.typ, col.precision = arrowFloatingPoint, arrowDouble
+		case parquetInt64:
+			col.typ, col.unit = arrowTimestamp, arrowMillisecond
+		}
+		aw.cols = append(aw.cols, col)
+	}
+	aw.buf = make([][]string, len(aw.cols))
+
+	fields := make([]*fbTable, len(aw.cols))
+	for i, col := range aw.cols {
+		typ := new(fbTable)
+		switch col.typ {
+		case arrowFloatingPoint:
+			typ.scalar(0, col.precision)
+		case arrowTimestamp:
+			typ.scalar(0, col.unit)
+		}
+		f := new(fbTable)
+		f.ref(0, col.name)
+		f.scalar(1, true) // nullable
+		f.scalar(2, col.typ)
+		f.ref(3, typ)
+		f.ref(5, []*fbTable{})
+		fields[i] = f
+	}
+	aw.schema = new(fbTable)
+	aw.schema.scalar(0, int16(0)) // little endian
+	aw.schema.ref(1, fields)
+
+	if err := aw.write([]byte(arrowMagic + "\x00\x00")); err != nil {
+		return nil, err
+	}
+	if _, err := aw.message(arrowSchemaMessage, aw.schema, nil); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+func (aw *arrowWriter) write(b []byte) error {
+	n, err := aw.w.Write(b)
+	aw.offset += int64(n)
+	return err
+}
+
+// message writes an encapsulated IPC message with the given header and body.
+func (aw *arrowWriter) message(typ uint8, header *fbTable, body []byte) (arrowBlock, error) {
+	msg := new(fbTable)
+	msg.scalar(0, int16(arrowV5))
+	msg.scalar(1, typ)
+	msg.ref(2, header)
+	msg.scalar(3, int64(len(body)))
+	meta := new(fbBuilder).finish(msg)
+
+	block := arrowBlock{offset: aw.offset, metaLen: int32(8 + len(meta)), bodyLen: int64(len(body))}
+	var prefix [8]byte
+	binary.LittleEndian.PutUint32(prefix[:4], 0xffffffff)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(meta)))
+	for _, b := range [][]byte{prefix[:], meta, body} {
+		if err := aw.write(b); err != nil {
+			return block, err
+		}
+	}
+	return block, nil
+}
+
+// append adds a Record to the current batch.  Records with fewer fields than the
+// Headers are padded with empty values.
+func (aw *arrowWriter) append(rec Record) error {
+	for i := range aw.cols {
+		v := ""
+		if i < len(rec) {
+			v = rec[i]
+		}
+		aw.buf[i] = append(aw.buf[i], v)
+	}
+	aw.rows++
+	if aw.rows == flushThreshold {
+		return aw.flushBatch()
+	}
+	return nil
+}
+
+func (aw *arrowWriter) flushBatch() error {
+	if aw.rows == 0 {
+		return nil
+	}
+	var body, nodes, buffers []byte
+	addBuffer := func(b []byte) {
+		buffers = appendInt64(buffers, int64(len(body)))
+		buffers = appendInt64(buffers, int64(len(b)))
+		body = append(body, b...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+	for i, col := range aw.cols {
+		validity, values, offsets, nulls, err := encodeArrowColumn(col, aw.buf[i], aw.timeParser)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.name, err)
+		}
+		nodes = appendInt64(nodes, int64(aw.rows))
+		nodes = appendInt64(nodes, int64(nulls))
+		if nulls == 0 {
+			validity = nil
+		}
+		addBuffer(validity)
+		if col.typ == arrowUtf8 {
+			addBuffer(offsets)
+		}
+		addBuffer(values)
+		aw.buf[i] = aw.buf[i][:0]
+	}
+
+	batch := new(fbTable)
+	batch.scalar(0, int64(aw.rows))
+	batch.ref(1, fbStructs{8, len(aw.cols), nodes})
+	batch.ref(2, fbStructs{8, len(buffers) / 16, buffers})
+	block, err := aw.message(arrowRecordBatch, batch, body)
+	if err != nil {
+		return err
+	}
+	aw.batches = append(aw.batches, block)
+	aw.rows = 0
+	return nil
+}
+
+// close writes the final batch, the end of stream marker and the file footer.
+func (aw *arrowWriter) close() error {
+	if err := aw.flushBatch(); err != nil {
+		return err
+	}
+	if err := aw.write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}); err != nil {
+		return err
+	}
+
+	var blocks []byte
+	for _, b := range aw.batches {
+		blocks = appendInt64(blocks, b.offset)
+		blocks = appendInt64(blocks, int64(uint32(b.metaLen))) // int32 and padding
+		blocks = appendInt64(blocks, b.bodyLen)
+	}
+	footer := new(fbTable)
+	footer.scalar(0, int16(arrowV5))
+	footer.ref(1, aw.schema)
+	footer.ref(3, fbStructs{8, len(aw.batches), blocks})
+	meta := new(fbBuilder).finish(footer)
+
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(meta)))
+	for _, b := range [][]byte{meta, size[:], []byte(arrowMagic)} {
+		if err := aw.write(b); err != nil {
+			return err
+		}
+	}
+	return aw.w.Flush()
+}
+
+func appendInt64(b []byte, v int64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], uint64(v))
+	return append(b, tmp[:]...)
+}
+
+// encodeArrowColumn returns the validity bitmap, value buffer and, for strings,
+// the offsets buffer of a column.  Empty values in typed columns are nulls.
+func encodeArrowColumn(col arrowColumn, values []string, p TimeParser) (validity, data, offsets []byte, nulls int, err error) {
+	validity = make([]byte, (len(values)+7)/8)
+	var b [8]byte
+	if col.typ == arrowUtf8 {
+		offsets = make([]byte, 4, 4*(len(values)+1))
+	}
+	for i, v := range values {
+		switch col.typ {
+		case arrowUtf8:
+			data = append(data, v...)
+			binary.LittleEndian.PutUint32(b[:4], uint32(len(data)))
+			offsets = append(offsets, b[:4]...)
+			validity[i/8] |= 1 << uint(i%8)
+			continue
+		}
+		if v == "" {
+			nulls++
+			data = append(data, make([]byte, 8)...)
+			continue
+		}
+		validity[i/8] |= 1 << uint(i%8)
+		switch col.typ {
+		case arrowFloatingPoint:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+		case arrowTimestamp:
+			t, err := Headers{Time: p}.parseTime(strings.TrimSpace(v))
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+			binary.LittleEndian.PutUint64(b[:], uint64(t.UnixNano()/int64(time.Millisecond)))
+		}
+		data = append(data, b[:]...)
+	}
+	return validity, data, offsets, nulls, nil
+}
+
+// SaveArrow writes the RecordSet to filename in the Apache Arrow IPC file format,
+// also known as Feather version 2, which pandas, polars and other Arrow based
+// tools can memory map without parsing.  The schema matches SaveParquet: LAT,
+// LON, SOG, and COG are float64, BaseDateTime is a millisecond timestamp, and
+// every other field is a UTF8 string.  Empty values in typed columns are stored
+// as nulls.  Records are written in uncompressed record batches of up to 250,000
+// records.  Like Save, SaveArrow reads the RecordSet to the end of its data.
+func (rs *RecordSet) SaveArrow(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("recordset save arrow: %w", err)
+	}
+	defer out.Close()
+
+	aw, err := newArrowWriter(out, rs.Headers())
+	if err != nil {
+		return fmt.Errorf("recordset save arrow: %w", err)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("recordset save arrow: %w", err)
+		}
+		if err := aw.append(*rec); err != nil {
+			return fmt.Errorf("recordset save arrow: %w", err)
+		}
+	}
+	if err := aw.close(); err != nil {
+		return fmt.Errorf("recordset save arrow: %w", err)
+	}
+	return nil
+}
+
+// OpenArrow reads an Arrow IPC file with a flat schema, such as one written by
+// SaveArrow or by pandas.DataFrame.to_feather, into a new in-memory *RecordSet.
+// The Headers of the RecordSet are the field names of the schema.  Floating point
+// values are formatted with at least one decimal place and timestamps with
+// TimeLayout in UTC so that the Records match the MarineCadastre csv
+// representation.  Only uncompressed utf8, integer, floating point and timestamp
+// columns without dictionary encoding are supported.
+func OpenArrow(filename string) (*RecordSet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open arrow: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("open arrow: %w", err)
+	}
+
+	var head [6]byte
+	var tail [10]byte
+	if fi.Size() < int64(8+len(tail)) {
+		return nil, fmt.Errorf("open arrow: %s is too small to be an arrow file", filename)
+	}
+	if _, err := f.ReadAt(head[:], 0); err != nil {
+		return nil, fmt.Errorf("open arrow: %w", err)
+	}
+	if _, err := f.ReadAt(tail[:], fi.Size()-int64(len(tail))); err != nil {
+		return nil, fmt.Errorf("open arrow: %w", err)
+	}
+	if string(head[:]) != arrowMagic || string(tail[4:]) != arrowMagic {
+		return nil, fmt.Errorf("open arrow: %s is not an arrow file", filename)
+	}
+	footerLen := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if footerLen > fi.Size()-int64(len(tail))-8 {
+		return nil, fmt.Errorf("open arrow: footer length %d is out of range", footerLen)
+	}
+	footerBuf := make([]byte, footerLen)
+	if _, err := f.ReadAt(footerBuf, fi.Size()-int64(len(tail))-footerLen); err != nil {
+		return nil, fmt.Errorf("open arrow: %w", err)
+	}
+
+	fb := &fbBuf{b: footerBuf}
+	footer := fb.root()
+	schema, ok := footer.table(1)
+	if !ok {
+		return nil, fmt.Errorf("open arrow: footer has no schema")
+	}
+	var cols []arrowColumn
+	start, n := schema.vector(1)
+	for i := 0; i < n; i++ {
+		field := schema.elem(start, i)
+		col := arrowColumn{name: field.str(0), typ: field.u8(2)}
+		if _, ok := field.table(4); ok {
+			return nil, fmt.Errorf("open arrow: dictionary encoded column %s is not supported", col.name)
+		}
+		if _, nc := field.vector(5); nc > 0 {
+			return nil, fmt.Errorf("open arrow: nested column %s is not supported", col.name)
+		}
+		typ, _ := field.table(3)
+		switch col.typ {
+		case arrowInt:
+			col.bitWidth, col.signed = typ.i32(0), typ.u8(1) != 0
+		case arrowFloatingPoint:
+			col.precision = typ.i16(0)
+		case arrowTimestamp:
+			col.unit = typ.i16(0)
+		case arrowUtf8:
+		default:
+			return nil, fmt.Errorf("open arrow: column %s has unsupported type %d", col.name, col.typ)
+		}
+		cols = append(cols, col)
+	}
+	type block struct {
+		offset, metaLen, bodyLen int64
+	}
+	var blocks []block
+	start, n = footer.vector(3)
+	for i := 0; i < n; i++ {
+		p := start + 24*i
+		blocks = append(blocks, block{int64(fb.u64(p)), int64(int32(fb.u32(p + 8))), int64(fb.u64(p + 16))})
+	}
+	if fb.err != nil {
+		return nil, fmt.Errorf("open arrow: unable to read footer: %w", fb.err)
+	}
+
+	rs := NewRecordSet()
+	h := Headers{}
+	for _, col := range cols {
+		h.Fields = append(h.Fields, col.name)
+	}
+	rs.SetHeaders(h)
+
+	for _, b := range blocks {
+		if b.offset < 0 || b.metaLen < 8 || b.bodyLen < 0 || b.offset > fi.Size() || b.metaLen > fi.Size()-b.offset || b.bodyLen > fi.Size()-b.offset-b.metaLen {
+			return nil, fmt.Errorf("open arrow: record batch block is out of range")
+		}
+		buf := make([]byte, b.metaLen+b.bodyLen)
+		if _, err := f.ReadAt(buf, b.offset); err != nil {
+			return nil, fmt.Errorf("open arrow: %w", err)
+		}
+		meta := buf[4:b.metaLen]
+		if binary.LittleEndian.Uint32(buf) == 0xffffffff {
+			meta = buf[8:b.metaLen]
+		}
+		values, rows, err := decodeArrowBatch(cols, meta, buf[b.metaLen:])
+		if err != nil {
+			return nil, fmt.Errorf("open arrow: %w", err)
+		}
+		for r := 0; r < rows; r++ {
+			rec := make(Record, len(cols))
+			for i := range cols {
+				rec[i] = values[i][r]
+			}
+			if err := rs.Write(rec); err != nil {
+				return nil, fmt.Errorf("open arrow: %w", err)
+			}
+		}
+	}
+	if err := rs.Flush(); err != nil {
+		return nil, fmt.Errorf("open arrow: %w", err)
+	}
+	return rs, nil
+}
+
+// decodeArrowBatch returns the string values by column of the record batch
+// message in meta with its body.
+func decodeArrowBatch(cols []arrowColumn, meta, body []byte) ([][]string, int, error) {
+	fb := &fbBuf{b: meta}
+	msg := fb.root()
+	if typ := msg.u8(1); typ != arrowRecordBatch {
+		return nil, 0, fmt.Errorf("message type %d is not a record batch", typ)
+	}
+	batch, _ := msg.table(2)
+	if _, ok := batch.table(3); ok {
+		return nil, 0, fmt.Errorf("compressed record batches are not supported")
+	}
+	rows := int(batch.i64(0))
+	nodeStart, nNodes := batch.vector(1)
+	bufStart, nBufs := batch.vector(2)
+	if fb.err != nil {
+		return nil, 0, fmt.Errorf("unable to read record batch: %w", fb.err)
+	}
+	// Every row needs at least one bit of some buffer, which bounds the allocation
+	// for a corrupt length.
+	if nNodes != len(cols) || rows < 0 || rows > len(body)*8+8 {
+		return nil, 0, fmt.Errorf("record batch does not match the schema")
+	}
+
+	next := 0
+	buffer := func() ([]byte, error) {
+		if next >= nBufs {
+			return nil, fmt.Errorf("record batch has too few buffers")
+		}
+		p := bufStart + 16*next
+		next++
+		off, n := int64(fb.u64(p)), int64(fb.u64(p+8))
+		if fb.err != nil || off < 0 || n < 0 || off > int64(len(body)) || n > int64(len(body))-off {
+			return nil, fmt.Errorf("record batch buffer is out of range")
+		}
+		return body[off : off+n], nil
+	}
+
+	values := make([][]string, len(cols))
+	for i, col := range cols {
+		validity, err := buffer()
+		if err != nil {
+			return nil, 0, err
+		}
+		// A bitmap may be left out when there are no nulls, but one that is
+		// present must cover every row whatever the null count says.
+		if nulls := fb.u64(nodeStart + 16*i + 8); (nulls > 0 || len(validity) != 0) && len(validity) < (rows+7)/8 {
+			return nil, 0, fmt.Errorf("column %s: validity bitmap is too short", col.name)
+		}
+		valid := func(r int) bool { return len(validity) == 0 || validity[r/8]&(1<<uint(r%8)) != 0 }
+
+		var offsets []byte
+		if col.typ == arrowUtf8 {
+			if offsets, err = buffer(); err != nil {
+				return nil, 0, err
+			}
+		}
+		data, err := buffer()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		width := 8
+		switch {
+		case col.typ == arrowUtf8:
+			width = 0
+			if len(offsets) < 4*(rows+1) {
+				return nil, 0, fmt.Errorf("column %s: offsets buffer is too short", col.name)
+			}
+		case col.typ == arrowInt:
+			width = int(col.bitWidth / 8)
+		case col.typ == arrowFloatingPoint && col.precision == arrowSingle:
+			width = 4
+		case col.typ == arrowFloatingPoint && col.precision != arrowDouble:
+			return nil, 0, fmt.Errorf("column %s: half precision floats are not supported", col.name)
+		}
+		if width != 0 && (width != 1 && width != 2 && width != 4 && width != 8 || len(data) < width*rows) {
+			return nil, 0, fmt.Errorf("column %s: data buffer is too short", col.name)
+		}
+
+		values[i] = make([]string, rows)
+		for r := 0; r < rows; r++ {
+			if !valid(r) {
+				continue
+			}
+			if col.typ == arrowUtf8 {
+				lo, hi := binary.LittleEndian.Uint32(offsets[4*r:]), binary.LittleEndian.Uint32(offsets[4*r+4:])
+				if lo > hi || int(hi) > len(data) {
+					return nil, 0, fmt.Errorf("column %s: string offset is out of range", col.name)
+				}
+				values[i][r] = string(data[lo:hi])
+				continue
+			}
+			var u uint64
+			switch width {
+			case 1:
+				u = uint64(data[r])
+			case 2:
+				u = uint64(binary.LittleEndian.Uint16(data[2*r:]))
+			case 4:
+				u = uint64(binary.LittleEndian.Uint32(data[4*r:]))
+			default:
+				u = binary.LittleEndian.Uint64(data[8*r:])
+			}
+			values[i][r] = formatArrowValue(col, u)
+		}
+	}
+	return values, rows, nil
+}
+
+// formatArrowValue formats the raw bits u of a fixed width value.
+func formatArrowValue(col arrowColumn, u uint64) string {
+	switch col.typ {
+	case arrowFloatingPoint:
+		if col.precision == arrowSingle {
+			return formatDouble(float64(math.Float32frombits(uint32(u))))
+		}
+		return formatDouble(math.Float64frombits(u))
+	case arrowTimestamp:
+		v := int64(u)
+		var t time.Time
+		switch col.unit {
+		case arrowSecond:
+			t = time.Unix(v, 0)
+		case arrowMillisecond:
+			t = time.Unix(0, v*int64(time.Millisecond))
+		case arrowMicrosecond:
+			t = time.Unix(0, v*int64(time.Microsecond))
+		default:
+			t = time.Unix(0, v)
+		}
+		return t.UTC().Format(TimeLayout)
+	}
+	if !col.signed {
+		return strconv.FormatUint(u, 10)
+	}
+	shift := uint(64 - col.bitWidth)
+	return strconv.FormatInt(int64(u<<shift)>>shift, 10)
+}
@@ -0,0 +1,205 @@
+package ais
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordSet_SaveArrow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ten.arrow")
+
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	if err := rs.SaveArrow(filename); err != nil {
+		t.Fatalf("RecordSet.SaveArrow() error = %v", err)
+	}
+
+	got, err := OpenArrow(filename)
+	if err != nil {
+		t.Fatalf("OpenArrow() error = %v", err)
+	}
+	if !got.Headers().Equals(goodHeaders) {
+		t.Errorf("OpenArrow() headers = %v, want %v", got.Headers(), goodHeaders)
+	}
+
+	want, _ := OpenRecordSet("testdata/ten.csv")
+	defer want.Close()
+	n := 0
+	for {
+		wantRec, err := want.Read()
+		if err == io.EOF {
+			break
+		}
+		gotRec, err := got.Read()
+		if err != nil {
+			t.Fatalf("record %d: Read() error = %v", n, err)
+		}
+		for _, field := range []string{"MMSI", "BaseDateTime", "VesselName", "Status", "Length", "Cargo"} {
+			i, _ := goodHeaders.Contains(field)
+			if (*gotRec)[i] != (*wantRec)[i] {
+				t.Errorf("record %d: %s = %q, want %q", n, field, (*gotRec)[i], (*wantRec)[i])
+			}
+		}
+		for _, field := range []string{"LAT", "LON", "SOG", "COG"} {
+			i, _ := goodHeaders.Contains(field)
+			g, _ := gotRec.ParseFloat(i)
+			w, _ := wantRec.ParseFloat(i)
+			if g != w {
+				t.Errorf("record %d: %s = %v, want %v", n, field, g, w)
+			}
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("OpenArrow() read %d records, want 10", n)
+	}
+}
+
+func TestOpenArrow_NotArrow(t *testing.T) {
+	if _, err := OpenArrow("testdata/ten.csv"); err == nil {
+		t.Errorf("OpenArrow() expected error for a csv file")
+	}
+	if _, err := OpenArrow("doesNotExist.arrow"); err == nil {
+		t.Errorf("OpenArrow() expected error for missing file")
+	}
+}
+
+func TestFlatbuffer(t *testing.T) {
+	child := new(fbTable)
+	child.scalar(0, int16(-2))
+	root := new(fbTable)
+	root.scalar(0, uint8(7))
+	root.scalar(1, int64(1)<<40)
+	root.ref(2, "name")
+	root.ref(4, []*fbTable{child, child})
+	root.scalar(5, int32(-3))
+
+	fb := &fbBuf{b: new(fbBuilder).finish(root)}
+	r := fb.root()
+	if r.u8(0) != 7 || r.i64(1) != 1<<40 || r.str(2) != "name" || r.i32(5) != -3 {
+		t.Errorf("flatbuffer scalars = %d, %d, %q, %d", r.u8(0), r.i64(1), r.str(2), r.i32(5))
+	}
+	if _, ok := r.table(3); ok {
+		t.Error("flatbuffer absent field is present")
+	}
+	start, n := r.vector(4)
+	if n != 2 || r.elem(start, 1).i16(0) != -2 {
+		t.Errorf("flatbuffer vector = %d elements", n)
+	}
+	if fb.err != nil {
+		t.Errorf("flatbuffer error = %v", fb.err)
+	}
+
+	fb = &fbBuf{b: []byte{0xff, 0, 0, 0}}
+	fb.root().str(0)
+	if fb.err == nil {
+		t.Error("flatbuffer expected error for an out of range root")
+	}
+}
+
+func TestFormatArrowValue(t *testing.T) {
+	tests := []struct {
+		col  arrowColumn
+		u    uint64
+		want string
+	}{
+		{arrowColumn{typ: arrowInt, bitWidth: 32, signed: true}, 0xffffffff, "-1"},
+		{arrowColumn{typ: arrowInt, bitWidth: 16}, 0xffff, "65535"},
+		{arrowColumn{typ: arrowFloatingPoint, precision: arrowSingle}, 0x40490000, "3.140625"},
+		{arrowColumn{typ: arrowTimestamp, unit: arrowSecond}, 1512086400, "2017-12-01T00:00:00"},
+		{arrowColumn{typ: arrowTimestamp, unit: arrowNanosecond}, 1512086400 * 1e9, "2017-12-01T00:00:00"},
+	}
+	for _, tt := range tests {
+		if got := formatArrowValue(tt.col, tt.u); got != tt.want {
+			t.Errorf("formatArrowValue(%+v, %#x) = %q, want %q", tt.col, tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestRecordSet_SaveArrow_Nulls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "nulls.arrow")
+
+	rs, _ := NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT\n1,,\n2,2017-12-01T00:00:00,30.5\n"), Headers{})
+	if err := rs.SaveArrow(filename); err != nil {
+		t.Fatalf("RecordSet.SaveArrow() error = %v", err)
+	}
+	got, err := OpenArrow(filename)
+	if err != nil {
+		t.Fatalf("OpenArrow() error = %v", err)
+	}
+	want := []string{"1,,", "2,2017-12-01T00:00:00,30.5"}
+	for i, w := range want {
+		rec, err := got.Read()
+		if err != nil {
+			t.Fatalf("record %d: Read() error = %v", i, err)
+		}
+		if s := strings.Join(*rec, ","); s != w {
+			t.Errorf("record %d = %q, want %q", i, s, w)
+		}
+	}
+}
+
+// arrowBatchMeta returns a record batch message of rows rows in one column with
+// the given null count and buffers of offset and length pairs.
+func arrowBatchMeta(rows, nulls int64, buffers ...int64) []byte {
+	var nodes, bufs []byte
+	nodes = appendInt64(nodes, rows)
+	nodes = appendInt64(nodes, nulls)
+	for _, v := range buffers {
+		bufs = appendInt64(bufs, v)
+	}
+	batch := new(fbTable)
+	batch.scalar(0, rows)
+	batch.ref(1, fbStructs{8, 1, nodes})
+	batch.ref(2, fbStructs{8, len(buffers) / 2, bufs})
+	msg := new(fbTable)
+	msg.scalar(0, int16(arrowV5))
+	msg.scalar(1, uint8(arrowRecordBatch))
+	msg.ref(2, batch)
+	return new(fbBuilder).finish(msg)
+}
+
+func TestDecodeArrowBatch_Corrupt(t *testing.T) {
+	cols := []arrowColumn{{name: "MMSI", typ: arrowInt, bitWidth: 32, signed: true}}
+	body := make([]byte, 72) // one byte of bitmap and 16 int32 values at 8
+
+	tests := []struct {
+		name string
+		meta []byte
+		want string
+	}{
+		{"truncated bitmap without nulls", arrowBatchMeta(16, 0, 0, 1, 8, 64), "validity bitmap"},
+		{"truncated bitmap with nulls", arrowBatchMeta(16, 3, 0, 1, 8, 64), "validity bitmap"},
+		{"buffer past body", arrowBatchMeta(16, 0, 0, 0, 8, 128), "out of range"},
+		{"buffer offset past body", arrowBatchMeta(16, 0, 0, 0, 1<<40, 0), "out of range"},
+		{"buffer end overflows", arrowBatchMeta(16, 0, 0, 0, 1<<62, 1<<62), "out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeArrowBatch(cols, tt.meta, body); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("decodeArrowBatch() error = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// The same batch with a bitmap of two bytes decodes.
+	body[0], body[1] = 0xff, 0x7f
+	values, rows, err := decodeArrowBatch(cols, arrowBatchMeta(16, 1, 0, 2, 8, 64), body)
+	if err != nil || rows != 16 || values[0][0] != "0" || values[0][15] != "" {
+		t.Errorf("decodeArrowBatch() = %v, %d, %v, want 16 rows with the last null", values, rows, err)
+	}
+}
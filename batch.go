@@ -0,0 +1,251 @@
+package ais
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Batch finds the interactions in a sequence of daily files, such as the
+// MarineCadastre.gov files of a month, without losing the encounters that
+// span midnight.  Running FindInteractions on each file alone compares the
+// last reports of one day with nothing, so two vessels passing at 23:58 and
+// 00:03 are never paired.  Batch carries the reports of the last Window of each
+// day into the next, where they are compared with the reports of the new day.
+// Create a Batch with NewBatch and call Run.
+type Batch struct {
+	// Files are the names of the daily files in time order.
+	Files []string
+
+	// Params configures the FindInteractions run on each day.  The carry over
+	// from one day to the next is Params.Window, or DefaultInteractionWindow
+	// when it is zero.
+	Params InteractionParams
+
+	// Options are passed to FindInteractions on each day and used to create the
+	// aggregate Interactions.
+	Options []InteractionOption
+
+	// Open opens each of Files.  When it is nil Files are opened with
+	// OpenRecordSet.
+	Open func(filename string) (*RecordSet, error)
+}
+
+// DayFunc is called by Batch.Run with the interactions found in each file.
+// The Interactions of a day may be configured and saved, and are not used by
+// the Batch once DayFunc returns.
+type DayFunc func(filename string, inter *Interactions) error
+
+// NewBatch returns a Batch for the daily files in time order.
+func NewBatch(files []string, p InteractionParams, opts ...InteractionOption) (*Batch, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("new batch: no files")
+	}
+	return &Batch{Files: files, Params: p, Options: opts}, nil
+}
+
+// Run finds the interactions of each file in turn, with the Records of the
+// last window of the previous file added before the Records of the file, and
+// calls fn, which may be nil, with the interactions of each file.  A pair
+// found between a carried Record and a Record of the new day belongs to the
+// new day, and pairs of two carried Records, which were already found the day
+// before, are removed from it.  A Record of the new day no later than the last
+// Record of the day before is taken to be carried.  Run returns the aggregate
+// Interactions of every day, where each interaction appears once and the time
+// bucket and closest approach options apply across days.  The aggregate holds
+// the Records of every pair in memory.  The files must have the same Headers.
+func (b *Batch) Run(ctx context.Context, fn DayFunc) (*Interactions, error) {
+	window := b.Params.Window
+	if window == 0 {
+		window = DefaultInteractionWindow
+	}
+	open := b.Open
+	if open == nil {
+		open = func(filename string) (*RecordSet, error) { return OpenRecordSet(filename) }
+	}
+
+	var agg *Interactions
+	var h Headers
+	var carry []Record
+	var carryEnd time.Time // latest BaseDateTime of the carried Records
+	for i, filename := range b.Files {
+		rs, err := open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("batch: %w", err)
+		}
+		if i == 0 {
+			h = rs.Headers()
+		} else if !h.Equals(rs.Headers()) {
+			rs.Close()
+			return nil, fmt.Errorf("batch: %s: headers differ from %s", filename, b.Files[0])
+		}
+		day, next, latest, err := b.withCarry(rs, carry, window)
+		rs.Close()
+		if err != nil {
+			return nil, fmt.Errorf("batch: %s: %w", filename, err)
+		}
+
+		inter, err := day.FindInteractions(ctx, b.Params, b.Options...)
+		day.Close()
+		if err != nil {
+			return nil, fmt.Errorf("batch: %s: %w", filename, err)
+		}
+		if len(carry) > 0 {
+			if err := inter.dropCarried(carryEnd); err != nil {
+				return nil, fmt.Errorf("batch: %s: %w", filename, err)
+			}
+		}
+		if agg == nil {
+			if agg, err = NewInteractionsWithOptions(inter.RecordHeaders, b.Options...); err != nil {
+				return nil, fmt.Errorf("batch: %w", err)
+			}
+		}
+		if err := agg.addAll(inter); err != nil {
+			return nil, fmt.Errorf("batch: %s: %w", filename, err)
+		}
+		if fn != nil {
+			if err := fn(filename, inter); err != nil {
+				return nil, err
+			}
+		}
+
+		carry, carryEnd = next, latest
+	}
+	return agg, nil
+}
+
+// withCarry returns a RecordSet of the carried Records followed by the Records
+// of rs, the Records of rs within window of its latest BaseDateTime, which are
+// carried into the next day, and that latest BaseDateTime.
+func (b *Batch) withCarry(rs *RecordSet, carry []Record, window time.Duration) (*RecordSet, []Record, time.Time, error) {
+	var latest time.Time
+	h := rs.Headers()
+	timeIndex, err := h.index("BaseDateTime")
+	if err != nil {
+		return nil, nil, latest, err
+	}
+	day := NewRecordSet()
+	day.SetHeaders(h)
+	for _, rec := range carry {
+		if err := day.Write(rec); err != nil {
+			return nil, nil, latest, fmt.Errorf("csv write error: %w", err)
+		}
+	}
+
+	// tail holds every Record within window of the latest time seen so far,
+	// which is pruned as the latest time advances.
+	var tail []Record
+	var tailTimes []time.Time
+	written := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, latest, fmt.Errorf("read error on csv file: %w", err)
+		}
+		if err := day.Write(*rec); err != nil {
+			return nil, nil, latest, fmt.Errorf("csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := day.Flush(); err != nil {
+				return nil, nil, latest, fmt.Errorf("csv flush error: %w", err)
+			}
+		}
+
+		t, err := h.parseTime((*rec)[timeIndex])
+		if err != nil {
+			return nil, nil, latest, err
+		}
+		if t.After(latest) {
+			latest = t
+		}
+		if latest.Sub(t) >= window {
+			continue
+		}
+		tail, tailTimes = append(tail, *rec), append(tailTimes, t)
+		if len(tail) > 2*flushThreshold {
+			tail, tailTimes = pruneTail(tail, tailTimes, latest, window)
+		}
+	}
+	if err := day.Flush(); err != nil {
+		return nil, nil, latest, fmt.Errorf("csv flush error: %w", err)
+	}
+	tail, _ = pruneTail(tail, tailTimes, latest, window)
+	return day, tail, latest, nil
+}
+
+// pruneTail removes the Records earlier than window before latest.
+func pruneTail(tail []Record, times []time.Time, latest time.Time, window time.Duration) ([]Record, []time.Time) {
+	n := 0
+	for i, t := range times {
+		if latest.Sub(t) < window {
+			tail[n], times[n] = tail[i], t
+			n++
+		}
+	}
+	return tail[:n], times[:n]
+}
+
+// dropCarried removes the pairs whose Records are both no later than
+// carryEnd, which are the pairs of two Records carried from the day before.
+func (inter *Interactions) dropCarried(carryEnd time.Time) error {
+	timeIndex := inter.hashIndices[1]
+	carried := func(pair *RecordPair) (bool, error) {
+		t1, err := inter.RecordHeaders.parseTime((*pair.rec1)[timeIndex])
+		if err != nil {
+			return false, ErrParse{Field: "BaseDateTime", Err: err}
+		}
+		t2, err := inter.RecordHeaders.parseTime((*pair.rec2)[timeIndex])
+		if err != nil {
+			return false, ErrParse{Field: "BaseDateTime", Err: err}
+		}
+		return !t1.After(carryEnd) && !t2.After(carryEnd), nil
+	}
+	if err := inter.forgetSpilled(carried); err != nil {
+		return err
+	}
+	for i := range inter.data {
+		shard := &inter.data[i]
+		shard.Lock()
+		for hash, pair := range shard.m {
+			drop, err := carried(pair)
+			if err != nil {
+				shard.Unlock()
+				return err
+			}
+			if drop {
+				delete(shard.m, hash)
+				if inter.spill != nil {
+					inter.spill.grow(-1, -pairSize(pair))
+				}
+			}
+		}
+		shard.Unlock()
+	}
+	return nil
+}
+
+// addAll adds every pair of inter2, which has the same RecordHeaders, to the
+// set.  With a time bucket or closest approach a pair replaces a farther pair
+// stored under the same hash.
+func (inter *Interactions) addAll(inter2 *Interactions) error {
+	nearest := inter.timeBucket > 0 || inter.closest
+	return inter2.each(func(hash Hash128, pair *RecordPair) error {
+		if !nearest {
+			inter.insert(hash, pair)
+			return nil
+		}
+		if !pair.hasDist {
+			d, err := inter2.distanceOf(pair)
+			if err != nil {
+				return err
+			}
+			pair.dist, pair.hasDist = d, true
+		}
+		return inter.insertNearest(hash, pair)
+	})
+}
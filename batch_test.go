@@ -0,0 +1,79 @@
+package ais
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBatch_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const header = "MMSI,BaseDateTime,LAT,LON\n"
+	days := map[string]string{
+		"day1.csv": header +
+			"111111111,2017-12-01T12:00:00,30.00000,-76.00000\n" +
+			"222222222,2017-12-01T23:57:00,36.90000,-76.10000\n" +
+			"333333333,2017-12-01T23:58:00,36.90100,-76.10100\n",
+		"day2.csv": header +
+			"444444444,2017-12-02T00:02:00,36.90200,-76.10200\n" +
+			"111111111,2017-12-02T12:00:00,30.00000,-76.00000\n",
+	}
+	var files []string
+	for _, name := range []string{"day1.csv", "day2.csv"} {
+		f := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(f, []byte(days[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	b, err := NewBatch(files, InteractionParams{Window: 10 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	perDay := make(map[string]int)
+	agg, err := b.Run(context.Background(), func(filename string, inter *Interactions) error {
+		perDay[filepath.Base(filename)] = inter.Len()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// day1 pairs 2 and 3; day2 pairs the carried 2 and 3 with 4 across midnight
+	// and does not repeat the pair of 2 and 3.
+	if perDay["day1.csv"] != 1 || perDay["day2.csv"] != 2 {
+		t.Errorf("Run() per day = %v, want day1.csv:1 day2.csv:2", perDay)
+	}
+	if agg.Len() != 3 {
+		t.Errorf("Run() aggregate Len() = %d, want 3", agg.Len())
+	}
+
+	if _, err := NewBatch(nil, InteractionParams{}); err == nil {
+		t.Error("NewBatch() without files should fail")
+	}
+}
+
+func TestBatch_RunHeadersDiffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f1, f2 := filepath.Join(dir, "a.csv"), filepath.Join(dir, "b.csv")
+	ioutil.WriteFile(f1, []byte("MMSI,BaseDateTime,LAT,LON\n"), 0644)
+	ioutil.WriteFile(f2, []byte("MMSI,BaseDateTime,LON,LAT\n"), 0644)
+	b, _ := NewBatch([]string{f1, f2}, InteractionParams{})
+	if _, err := b.Run(context.Background(), nil); err == nil {
+		t.Error("Run() with different headers should fail")
+	}
+}
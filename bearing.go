@@ -0,0 +1,125 @@
+package ais
+
+import (
+	"errors"
+	"math"
+)
+
+// HeadingNotAvailable is the Heading AIS reports when a vessel has no heading
+// sensor, and COGNotAvailable the COG it reports when the course is unknown.
+const (
+	HeadingNotAvailable = 511
+	COGNotAvailable     = 360
+)
+
+// ErrNotAvailable is returned by the bearing helpers when a Record reports
+// neither a Heading nor a COG.
+var ErrNotAvailable = errors.New("heading and course not available")
+
+// Bearing returns the initial great circle bearing in degrees true, from 0 up to
+// 360, from the position of r to the position of r2.  The positions are the
+// fields at latIndex and lonIndex, as for Distance.
+func (r Record) Bearing(r2 Record, latIndex, lonIndex int) (float64, error) {
+	var pos [4]float64
+	for i, v := range []struct {
+		rec   Record
+		index int
+		field string
+	}{
+		{r, latIndex, "LAT"}, {r, lonIndex, "LON"},
+		{r2, latIndex, "LAT"}, {r2, lonIndex, "LON"},
+	} {
+		f, err := v.rec.ParseFloat(v.index)
+		if err != nil {
+			return 0, ErrParse{Field: v.field, Err: err}
+		}
+		pos[i] = f
+	}
+	return initialBearing(pos[0], pos[1], pos[2], pos[3]), nil
+}
+
+// Orientation returns the direction the bow of the vessel of r points in
+// degrees true.  It is the Heading when one is reported and otherwise the COG,
+// which is the usual substitute for vessels without a heading sensor.  It
+// returns ErrNotAvailable when r reports neither.  The Headers must contain
+// Heading and COG.
+func (r Record) Orientation(h Headers) (float64, error) {
+	hdg, err := r.Heading(h)
+	if err != nil {
+		return 0, err
+	}
+	if hdg >= 0 && hdg < 360 {
+		return hdg, nil
+	}
+	cog, err := r.COG(h)
+	if err != nil {
+		return 0, err
+	}
+	if cog >= 0 && cog < COGNotAvailable {
+		return cog, nil
+	}
+	return 0, ErrNotAvailable
+}
+
+// RelativeBearing returns the bearing of the vessel of r2 seen from the vessel
+// of r, measured clockwise from the bow of r from 0 up to 360 degrees.  The bow
+// is given by Orientation, so a Heading of HeadingNotAvailable falls back to the
+// COG.  The Headers must contain LAT, LON, Heading, and COG.
+func (r Record) RelativeBearing(r2 Record, h Headers) (float64, error) {
+	b, err := r.bearingTo(r2, h)
+	if err != nil {
+		return 0, err
+	}
+	bow, err := r.Orientation(h)
+	if err != nil {
+		return 0, err
+	}
+	return RelativeBearing(b, bow), nil
+}
+
+// Aspect returns the aspect of the vessel of r2 seen from the vessel of r: the
+// angle at r2 between its bow and the line of sight to r, from -180 to 180
+// degrees.  Positive angles are on the starboard side of r2 and negative angles
+// on its port side, so an aspect near 0 means r2 is heading toward r and one
+// near ±180 means r is looking at its stern.  The Headers have the same
+// requirements as RelativeBearing.
+func (r Record) Aspect(r2 Record, h Headers) (float64, error) {
+	rel, err := r2.RelativeBearing(r, h)
+	if err != nil {
+		return 0, err
+	}
+	if rel > 180 {
+		rel -= 360
+	}
+	return rel, nil
+}
+
+// bearingTo is Bearing with the LAT and LON indices looked up in h.
+func (r Record) bearingTo(r2 Record, h Headers) (float64, error) {
+	latIndex, err := h.index("LAT")
+	if err != nil {
+		return 0, err
+	}
+	lonIndex, err := h.index("LON")
+	if err != nil {
+		return 0, err
+	}
+	return r.Bearing(r2, latIndex, lonIndex)
+}
+
+// RelativeBearing returns bearing measured clockwise from heading, both in
+// degrees true, as an angle from 0 up to 360.
+func RelativeBearing(bearing, heading float64) float64 {
+	return math.Mod(math.Mod(bearing-heading, 360)+360, 360)
+}
+
+// initialBearing returns the great circle bearing in degrees true, from 0 up to
+// 360, at which a vessel at lat1, lon1 departs toward lat2, lon2.
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	phi1, phi2 := lat1*rad, lat2*rad
+	dLon := (lon2 - lon1) * rad
+	y := math.Sin(dLon) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)/rad+360, 360)
+}
@@ -0,0 +1,97 @@
+package ais
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRecord_Bearing(t *testing.T) {
+	rec := func(lat, cog, heading string) Record {
+		return Record{"100000001", "2017-12-01T00:00:00", lat, "-76.0", "10.0", cog, heading, "", "", "", "", "", "", "", "", ""}
+	}
+	own := rec("30.00", "90.0", "90.0")
+	north := rec("30.01", "170.0", "180.0")
+
+	if b, err := own.Bearing(north, 2, 3); err != nil || math.Abs(b) > 1e-9 {
+		t.Errorf("Record.Bearing() = %v, %v, want 0", b, err)
+	}
+	if _, err := own.Bearing(rec("xx", "", ""), 2, 3); err == nil {
+		t.Error("Record.Bearing() expected error for unparsable LAT")
+	}
+
+	tests := []struct {
+		name            string
+		own, other      Record
+		relative        float64
+		aspect          float64
+		wantOrientErr   error
+		wantOrientation float64
+	}{
+		{"heading", own, north, 270, 0, nil, 90},
+		{"heading not available uses cog", rec("30.00", "45.0", "511"), north, 315, 0, nil, 45},
+		{"target stern on", own, rec("30.01", "0.0", "0.0"), 270, 180, nil, 90},
+		{"target starboard side", own, rec("30.01", "90.0", "90.0"), 270, 90, nil, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if o, err := tt.own.Orientation(goodHeaders); err != nil || o != tt.wantOrientation {
+				t.Errorf("Record.Orientation() = %v, %v, want %v", o, err, tt.wantOrientation)
+			}
+			rel, err := tt.own.RelativeBearing(tt.other, goodHeaders)
+			if err != nil || math.Abs(rel-tt.relative) > 1e-6 {
+				t.Errorf("Record.RelativeBearing() = %v, %v, want %v", rel, err, tt.relative)
+			}
+			aspect, err := tt.own.Aspect(tt.other, goodHeaders)
+			if err != nil || math.Abs(math.Abs(aspect)-tt.aspect) > 1e-6 {
+				t.Errorf("Record.Aspect() = %v, %v, want %v", aspect, err, tt.aspect)
+			}
+		})
+	}
+
+	na := rec("30.00", "360.0", "511")
+	if _, err := na.Orientation(goodHeaders); !errors.Is(err, ErrNotAvailable) {
+		t.Errorf("Record.Orientation() error = %v, want ErrNotAvailable", err)
+	}
+	if _, err := na.RelativeBearing(north, goodHeaders); !errors.Is(err, ErrNotAvailable) {
+		t.Errorf("Record.RelativeBearing() error = %v, want ErrNotAvailable", err)
+	}
+	if _, err := own.Orientation(Headers{Fields: []string{"LAT", "LON"}}); err == nil {
+		t.Error("Record.Orientation() expected error for missing Heading header")
+	}
+}
+
+func TestRelativeBearing(t *testing.T) {
+	for _, tt := range []struct{ bearing, heading, want float64 }{
+		{10, 350, 20},
+		{350, 10, 340},
+		{90, 90, 0},
+		{-90, 0, 270},
+		{720, 0, 0},
+	} {
+		if got := RelativeBearing(tt.bearing, tt.heading); got != tt.want {
+			t.Errorf("RelativeBearing(%v, %v) = %v, want %v", tt.bearing, tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestInitialBearing(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"north", 30, -76, 31, -76, 0},
+		{"south", 31, -76, 30, -76, 180},
+		{"east on equator", 0, 0, 0, 1, 90},
+		{"west on equator", 0, 1, 0, 0, 270},
+		{"across antimeridian", 0, 179.5, 0, -179.5, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := initialBearing(tt.lat1, tt.lon1, tt.lat2, tt.lon2); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("initialBearing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package ais
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// BoundingBox is another name for Box, the rectangular study area bounded by
+// minimum and maximum latitude and longitude.
+type BoundingBox = Box
+
+// Contains reports whether the position is inside the Box or on its border.
+// The LatIndex and LonIndex of the Box are not used.
+func (b *Box) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// CropBox returns a pointer to a new RecordSet holding the Records inside the
+// Box b, using the LAT and LON Headers of rs in place of the LatIndex and
+// LonIndex of b.  It is the cheapest way to cut a dataset down to a study area
+// before any geohashing or geofencing: LAT is checked before LON is parsed, and
+// coordinates written as plain decimals are parsed without strconv.  Records
+// with a position that cannot be parsed are dropped rather than returned as an
+// error, as they are by Subset with a Box.  Like Subset, CropBox returns
+// ErrEmptySet when no Records are inside the Box.  CropBox consumes the
+// receiver.
+func (rs *RecordSet) CropBox(b Box) (*RecordSet, error) {
+	return rs.CropBoxContext(context.Background(), b)
+}
+
+// CropBoxContext is CropBox with a Context that stops the scan when it is
+// canceled.
+func (rs *RecordSet) CropBoxContext(ctx context.Context, b Box) (*RecordSet, error) {
+	idx, err := rs.Headers().require("LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("crop box: %w", err)
+	}
+	latIndex, lonIndex := idx["LAT"].Idx, idx["LON"].Idx
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(rs.Headers())
+	pt := startProgress(ctx, "crop box", rs)
+	written, n := 0, 0
+	for ; ; n++ {
+		if err := canceled(ctx, n); err != nil {
+			return nil, fmt.Errorf("crop box: %w", err)
+		}
+		pt.update(n)
+		rec, err := rs.read(true) // each Record is written before the next is read
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("crop box: read error on csv file: %w", err)
+		}
+		if latIndex >= len(*rec) || lonIndex >= len(*rec) {
+			warn("crop box: dropped record without a position", "record", n, "fields", len(*rec))
+			continue
+		}
+		lat, okLat := parseCoord((*rec)[latIndex])
+		lon, okLon := parseCoord((*rec)[lonIndex])
+		if !okLat || !okLon {
+			warn("crop box: dropped record with unparsable position", "record", n, "LAT", (*rec)[latIndex], "LON", (*rec)[lonIndex])
+			continue
+		}
+		if lat < b.MinLat || lat > b.MaxLat || lon < b.MinLon || lon > b.MaxLon {
+			continue
+		}
+		if err := rs2.Write(*rec); err != nil {
+			return nil, fmt.Errorf("crop box: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, fmt.Errorf("crop box: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("crop box: csv flush error: %w", err)
+	}
+	pt.done(n)
+	if written == 0 {
+		return rs2, ErrEmptySet
+	}
+	return rs2, nil
+}
+
+// parseCoord parses a latitude or longitude.  Plain decimals of up to 15 digits
+// such as -76.32652, which is how every AIS archive writes positions, are
+// parsed directly with the same result as strconv.ParseFloat, since both the
+// digits and the power of ten are exact in a float64.  Anything else, such as
+// an exponent, falls back to strconv.ParseFloat.
+func parseCoord(s string) (float64, bool) {
+	i, neg := 0, false
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		neg = s[i] == '-'
+		i++
+	}
+	var mant uint64
+	digits, scale := 0, 0
+	dot := false
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9' && digits < 15: // exact in a float64
+			mant = mant*10 + uint64(c-'0')
+			digits++
+			if dot {
+				scale++
+			}
+		case c == '.' && !dot:
+			dot = true
+		default:
+			f, err := strconv.ParseFloat(s, 64)
+			return f, err == nil
+		}
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	f := float64(mant) / pow10[scale]
+	if neg {
+		f = -f
+	}
+	return f, true
+}
+
+// pow10 holds the powers of ten used by parseCoord.
+var pow10 = [...]float64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15}
@@ -0,0 +1,71 @@
+package ais
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestParseCoord(t *testing.T) {
+	for _, s := range []string{"31.90512", "-76.32652", "+45.5", "0", "-0.00001", "180", ".5", "5.", "1e2", "-7.6e1", "12345678901234567.5"} {
+		want, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := parseCoord(s)
+		if !ok || got != want {
+			t.Errorf("parseCoord(%q) = %v, %v; want %v, true", s, got, ok, want)
+		}
+	}
+	for _, s := range []string{"", "-", ".", "abc", "1.2.3", " 1"} {
+		if _, ok := parseCoord(s); ok {
+			t.Errorf("parseCoord(%q) ok; want failure", s)
+		}
+	}
+}
+
+func TestRecordSet_CropBox(t *testing.T) {
+	b := BoundingBox{MinLat: 35, MaxLat: 45, MinLon: -76.2, MaxLon: -74}
+	if !b.Contains(40, -75) {
+		t.Errorf("Contains: position inside the box reported outside")
+	}
+	if b.Contains(31.90512, -76.32652) {
+		t.Errorf("Contains: position outside the box reported inside")
+	}
+
+	rs, err := OpenRecordSet("testdata/ten.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	rs2, err := rs.CropBox(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 43.60792,-74.20417  36.93276,-75.13876  38.00223,-76.26308 (out on LON)
+	var got []string
+	for {
+		rec, err := rs2.Read()
+		if err != nil {
+			break
+		}
+		got = append(got, (*rec)[2])
+	}
+	want := []string{"43.60792", "36.93276"}
+	if len(got) != len(want) {
+		t.Fatalf("CropBox kept %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("CropBox record %d LAT %s; want %s", i, got[i], want[i])
+		}
+	}
+
+	rs, err = OpenRecordSet("testdata/ten.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if _, err := rs.CropBox(Box{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1}); err != ErrEmptySet {
+		t.Errorf("CropBox of empty area: got error %v; want ErrEmptySet", err)
+	}
+}
@@ -0,0 +1,171 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Charset is the character encoding of a csv file.  A RecordSet holds its
+// Records in UTF-8, and files in another Charset are converted as they are
+// read and written.
+type Charset int
+
+const (
+	// UTF8 is the encoding of MarineCadastre.gov files and of every file read
+	// and written by default.
+	UTF8 Charset = iota
+
+	// Latin1 is ISO 8859-1, in which the vessel names of some European feeds are
+	// written.
+	Latin1
+
+	// Windows1252 is the Windows code page 1252, the superset of Latin1 that
+	// Excel and other Windows programs write as "ANSI".
+	Windows1252
+)
+
+var charsetNames = [...]string{
+	UTF8:        "utf-8",
+	Latin1:      "iso-8859-1",
+	Windows1252: "windows-1252",
+}
+
+// String implements the Stringer interface for Charset.
+func (c Charset) String() string {
+	if c < 0 || int(c) >= len(charsetNames) {
+		return fmt.Sprintf("Charset(%d)", int(c))
+	}
+	return charsetNames[c]
+}
+
+// valid returns an error for an unknown Charset.
+func (c Charset) valid() error {
+	if c < UTF8 || c > Windows1252 {
+		return fmt.Errorf("unknown charset %v", c)
+	}
+	return nil
+}
+
+// windows1252 holds the runes of the bytes 0x80 through 0x9f of Windows1252,
+// where it differs from Latin1.  The five bytes that the code page leaves
+// undefined map to the C1 controls, as they do in Latin1.
+var windows1252 = [32]rune{
+	0x20ac, 0x0081, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008d, 0x017d, 0x008f,
+	0x0090, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0x009d, 0x017e, 0x0178,
+}
+
+// decode returns the rune of byte b in c, which is Latin1 or Windows1252.
+func (c Charset) decode(b byte) rune {
+	if c == Windows1252 && b >= 0x80 && b < 0xa0 {
+		return windows1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// encode returns the byte of r in c, which is Latin1 or Windows1252, and false
+// when c has no such character.
+func (c Charset) encode(r rune) (byte, bool) {
+	switch {
+	case r < 0x80:
+		return byte(r), true
+	case r < 0xa0 && c == Windows1252:
+		// Only the undefined bytes decode to a C1 control.
+		b := byte(r)
+		return b, windows1252[b-0x80] == r
+	case r < 0x100:
+		return byte(r), true
+	case c == Windows1252:
+		for i, w := range windows1252 {
+			if w == r {
+				return byte(0x80 + i), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// decoder returns r converted from c to UTF-8.
+func (c Charset) decoder(r io.Reader) io.Reader {
+	if c == UTF8 {
+		return r
+	}
+	return &charsetReader{c: c, r: r}
+}
+
+// encoder returns w, which is written in UTF-8 and converted to c.  A
+// character that c cannot represent is written as '?'.
+func (c Charset) encoder(w io.Writer) io.Writer {
+	if c == UTF8 {
+		return w
+	}
+	return &charsetWriter{c: c, w: w}
+}
+
+// charsetReader converts a single byte Charset to UTF-8.
+type charsetReader struct {
+	c   Charset
+	r   io.Reader
+	in  []byte // bytes read from r
+	out []byte // converted bytes not yet returned
+}
+
+func (cr *charsetReader) Read(p []byte) (int, error) {
+	if len(cr.out) == 0 {
+		// Each byte is at most utf8.UTFMax bytes of UTF-8.
+		n := len(p) / utf8.UTFMax
+		if n == 0 {
+			n = 1
+		}
+		if cap(cr.in) < n {
+			cr.in = make([]byte, n)
+		}
+		n, err := cr.r.Read(cr.in[:n])
+		var rb [utf8.UTFMax]byte
+		for _, b := range cr.in[:n] {
+			cr.out = append(cr.out, rb[:utf8.EncodeRune(rb[:], cr.c.decode(b))]...)
+		}
+		if n == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, cr.out)
+	cr.out = cr.out[:copy(cr.out, cr.out[n:])]
+	return n, nil
+}
+
+// charsetWriter converts UTF-8 to a single byte Charset.
+type charsetWriter struct {
+	c       Charset
+	w       io.Writer
+	pending []byte // the start of a rune split between two writes
+	buf     []byte
+}
+
+func (cw *charsetWriter) Write(p []byte) (int, error) {
+	in := p
+	if len(cw.pending) > 0 {
+		in = append(cw.pending, p...)
+		cw.pending = nil
+	}
+	cw.buf = cw.buf[:0]
+	for len(in) > 0 {
+		if !utf8.FullRune(in) {
+			cw.pending = append([]byte(nil), in...)
+			break
+		}
+		r, size := utf8.DecodeRune(in)
+		b, ok := cw.c.encode(r)
+		if !ok {
+			b = '?'
+		}
+		cw.buf = append(cw.buf, b)
+		in = in[size:]
+	}
+	if _, err := cw.w.Write(cw.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,103 @@
+package ais
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCharset_Decoder(t *testing.T) {
+	tests := []struct {
+		c    Charset
+		in   string
+		want string
+	}{
+		{UTF8, "S\xc3\x98REN", "SØREN"},
+		{Latin1, "S\xd8REN \xe9\x80", "SØREN é\u0080"},
+		{Windows1252, "S\xd8REN \x80\x8a\x9f\x81", "SØREN €ŠŸ\u0081"},
+	}
+	for _, tt := range tests {
+		got, err := ioutil.ReadAll(tt.c.decoder(iotest.OneByteReader(strings.NewReader(tt.in))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%v decoder = %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestCharset_Encoder(t *testing.T) {
+	tests := []struct {
+		c    Charset
+		in   string
+		want string
+	}{
+		{Latin1, "SØREN €", "S\xd8REN ?"},
+		{Windows1252, "SØREN €Š\u0081\u0080", "S\xd8REN \x80\x8a\x81?"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := tt.c.encoder(&buf)
+		// Write one byte at a time, which splits every rune of two or more bytes.
+		for i := 0; i < len(tt.in); i++ {
+			if _, err := w.Write([]byte{tt.in[i]}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%v encoder = %q, want %q", tt.c, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestRecordSet_SaveOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "export.csv")
+
+	rs := NewRecordSet()
+	rs.SetHeaders(Headers{Fields: []string{"MMSI", "VesselName", "Draft"}})
+	rs.Write(Record{"219000001", "SØREN; LARSEN", ""})
+	rs.Flush()
+	if err := rs.Save(filename, SaveDelimiter(';'), SaveQuoting(QuoteAll), SaveCharset(Latin1)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\"MMSI\";\"VesselName\";\"Draft\"\n\"219000001\";\"S\xd8REN; LARSEN\";\"\"\n"
+	if string(b) != want {
+		t.Errorf("Save() wrote %q, want %q", b, want)
+	}
+
+	rs2, err := OpenRecordSet(filename, Delimiter(';'), ReadCharset(Latin1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs2.Close()
+	rec, err := rs2.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*rec)[1] != "SØREN; LARSEN" {
+		t.Errorf("OpenRecordSet() VesselName = %q", (*rec)[1])
+	}
+
+	for _, opt := range []SaveOption{SaveDelimiter('"'), SaveQuoting(QuoteStyle(9)), SaveCharset(Charset(9))} {
+		if err := NewRecordSet().Save(filename, opt); err == nil {
+			t.Error("Save() expected error for invalid option")
+		}
+	}
+	if _, err := OpenRecordSet(filename, ReadCharset(Charset(-1))); err == nil {
+		t.Error("OpenRecordSet() expected error for unknown charset")
+	}
+}
@@ -0,0 +1,173 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpointVersion is incremented whenever the layout of checkpointFile
+// changes so that an incompatible checkpoint is rejected rather than misread.
+const checkpointVersion = 2
+
+// checkpointFile is the gob encoded content of an Interactions checkpoint.  A
+// Record shared by several pairs is stored once in Records and the pairs refer
+// to it by position.
+type checkpointFile struct {
+	Version       int
+	Fields        []string
+	Mapping       HeaderMapping
+	CPA           bool
+	Risk          bool
+	Encounter     bool
+	MaxDist       float64
+	MaxGap        time.Duration
+	TimeBucket    time.Duration
+	Closest       bool
+	RecordColumns bool
+	Columns       []string
+	Order         OutputOrder
+	Records       [][]string
+	Pairs         []checkpointPair
+}
+
+// checkpointPair is one interaction of a checkpointFile.
+type checkpointPair struct {
+	Hash       Hash128
+	Rec1, Rec2 int // positions in Records
+}
+
+// Checkpoint writes the interactions in the set, its RecordHeaders, and its
+// settings to the file path so that a long job can be resumed with
+// ResumeInteractions after it is interrupted.  A multi-day analysis that calls
+// Checkpoint after each window or hour of data loses at most the work since the
+// last call, and the caller is responsible for recording how far through its
+// input that was.  The file is written to a temporary file in the same directory
+// and renamed over path, so an interruption during Checkpoint leaves the previous
+// checkpoint intact.  Checkpoint must not be called concurrently with methods
+// that add interactions.
+func (inter *Interactions) Checkpoint(path string) error {
+	cf := &checkpointFile{
+		Version:       checkpointVersion,
+		Fields:        inter.RecordHeaders.Fields,
+		Mapping:       inter.RecordHeaders.Mapping,
+		CPA:           inter.cpa,
+		Risk:          inter.risk,
+		Encounter:     inter.encounter,
+		MaxDist:       inter.maxDistance,
+		MaxGap:        inter.maxTimeGap,
+		TimeBucket:    inter.timeBucket,
+		Closest:       inter.closest,
+		RecordColumns: inter.recordColumns,
+		Columns:       inter.columns,
+		Order:         inter.order,
+	}
+	pos := make(map[*Record]int)
+	record := func(rec *Record) int {
+		i, ok := pos[rec]
+		if !ok {
+			i = len(cf.Records)
+			pos[rec] = i
+			cf.Records = append(cf.Records, *rec)
+		}
+		return i
+	}
+	err := inter.eachUnordered(func(hash Hash128, pair *RecordPair) error {
+		cf.Pairs = append(cf.Pairs, checkpointPair{Hash: hash, Rec1: record(pair.rec1), Rec2: record(pair.rec2)})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	if err := gob.NewEncoder(w).Encode(cf); err != nil {
+		return fail(err)
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ResumeInteractions returns the Interactions saved by Checkpoint to the file
+// path, ready for more clusters to be added.  The limits set by WithMaxDistance,
+// WithMaxTimeGap, WithTimeBucket, and WithClosestApproach, and the output set by
+// WithRecordColumns, SetCPA, SetRisk, SetEncounter, SetColumns, and SetOrder, are restored from the
+// checkpoint.  Settings that hold functions or filters, namely
+// WithDistanceFunc, WithGeofence, WithStationClasses, WithVesselCategories, and a
+// TimeParser on the RecordHeaders, cannot be saved and must be passed again as
+// opts, just as they were to NewInteractionsWithOptions.
+func ResumeInteractions(path string, opts ...InteractionOption) (*Interactions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("resume interactions: %w", err)
+	}
+	defer f.Close()
+	cf := new(checkpointFile)
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(cf); err != nil {
+		return nil, fmt.Errorf("resume interactions: %w", err)
+	}
+	if cf.Version != checkpointVersion {
+		return nil, fmt.Errorf("resume interactions: checkpoint version %d, want %d", cf.Version, checkpointVersion)
+	}
+
+	saved := func(inter *Interactions) error {
+		inter.maxDistance = cf.MaxDist
+		inter.maxTimeGap = cf.MaxGap
+		inter.timeBucket = cf.TimeBucket
+		inter.closest = cf.Closest
+		inter.recordColumns = cf.RecordColumns
+		inter.resetOutputHeaders()
+		return nil
+	}
+	h := Headers{Fields: cf.Fields, Mapping: cf.Mapping}
+	inter, err := NewInteractionsWithOptions(h, append([]InteractionOption{saved}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("resume interactions: %w", err)
+	}
+	inter.cpa, inter.risk, inter.encounter = cf.CPA, cf.Risk, cf.Encounter
+	if err := inter.SetColumns(cf.Columns...); err != nil {
+		return nil, fmt.Errorf("resume interactions: %w", err)
+	}
+	if err := inter.SetOrder(cf.Order); err != nil {
+		return nil, fmt.Errorf("resume interactions: %w", err)
+	}
+
+	recs := make([]*Record, len(cf.Records))
+	for i, r := range cf.Records {
+		rec := Record(r)
+		recs[i] = &rec
+	}
+	for _, p := range cf.Pairs {
+		if p.Rec1 < 0 || p.Rec1 >= len(recs) || p.Rec2 < 0 || p.Rec2 >= len(recs) {
+			return nil, fmt.Errorf("resume interactions: corrupt checkpoint %s", path)
+		}
+		inter.insert(p.Hash, &RecordPair{rec1: recs[p.Rec1], rec2: recs[p.Rec2]})
+	}
+	return inter, nil
+}
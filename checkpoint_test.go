@@ -0,0 +1,130 @@
+package ais
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInteractions_Checkpoint(t *testing.T) {
+	inter, _ := NewInteractionsWithOptions(goodHeaders, WithMaxDistance(5))
+	c := new(Cluster)
+	for _, v := range [][2]string{{"100000001", "30.00000"}, {"100000002", "30.01000"}, {"100000003", "30.02000"}} {
+		rec := Record{v[0], "2017-12-01T00:00:00", v[1], "-76.00000", "", "", "", "", "", "", "", "", "", "", "", ""}
+		c.Append(&rec)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	inter.SetCPA(true)
+	if err := inter.SetOrder(OrderByHash); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inter.ckpt")
+	if err := inter.Checkpoint(path); err != nil {
+		t.Fatalf("Interactions.Checkpoint() error = %v", err)
+	}
+
+	resumed, err := ResumeInteractions(path)
+	if err != nil {
+		t.Fatalf("ResumeInteractions() error = %v", err)
+	}
+	if resumed.Len() != 3 {
+		t.Errorf("ResumeInteractions() Len = %d, want 3", resumed.Len())
+	}
+	if resumed.maxDistance != 5 || !resumed.cpa || resumed.order != OrderByHash {
+		t.Errorf("ResumeInteractions() did not restore the settings of the set")
+	}
+	if !reflect.DeepEqual(resumed.OutputHeaders, inter.OutputHeaders) {
+		t.Errorf("ResumeInteractions() OutputHeaders = %v, want %v", resumed.OutputHeaders, inter.OutputHeaders)
+	}
+	var want, got bytes.Buffer
+	inter.WriteCSV(&want)
+	resumed.WriteCSV(&got)
+	if got.String() != want.String() {
+		t.Errorf("ResumeInteractions() wrote\n%s\nwant\n%s", got.String(), want.String())
+	}
+
+	// Adding the same cluster to the resumed set stores no new interactions.
+	if err := resumed.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	if resumed.Len() != 3 {
+		t.Errorf("Interactions.AddCluster() after resume Len = %d, want 3", resumed.Len())
+	}
+
+	if _, err := ResumeInteractions(filepath.Join(dir, "missing")); err == nil {
+		t.Error("ResumeInteractions() expected error for a missing file")
+	}
+	bad := filepath.Join(dir, "bad.ckpt")
+	ioutil.WriteFile(bad, []byte("not a checkpoint"), 0644)
+	if _, err := ResumeInteractions(bad); err == nil {
+		t.Error("ResumeInteractions() expected error for a corrupt file")
+	}
+}
+
+func TestInteractions_CheckpointRecordColumns(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON"}}
+	inter, _ := NewInteractionsWithOptions(h, WithRecordColumns())
+	c := new(Cluster)
+	for _, v := range [][2]string{{"100000001", "30.00000"}, {"100000002", "30.01000"}} {
+		rec := Record{v[0], "2017-12-01T00:00:00", v[1], "-76.00000"}
+		c.Append(&rec)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inter.ckpt")
+	if err := inter.Checkpoint(path); err != nil {
+		t.Fatalf("Interactions.Checkpoint() error = %v", err)
+	}
+	resumed, err := ResumeInteractions(path)
+	if err != nil {
+		t.Fatalf("ResumeInteractions() error = %v", err)
+	}
+
+	// widths returns the number of fields in each line written by inter.
+	widths := func(inter *Interactions) []int {
+		var b bytes.Buffer
+		if err := inter.WriteCSV(&b); err != nil {
+			t.Fatalf("Interactions.WriteCSV() error = %v", err)
+		}
+		var w []int
+		for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+			w = append(w, len(strings.Split(line, ",")))
+		}
+		return w
+	}
+	if want := []int{10, 10}; !reflect.DeepEqual(widths(inter), want) {
+		t.Fatalf("Interactions.WriteCSV() widths = %v, want %v", widths(inter), want)
+	}
+	if got, want := widths(resumed), widths(inter); !reflect.DeepEqual(got, want) {
+		t.Errorf("ResumeInteractions() WriteCSV widths = %v, want %v", got, want)
+	}
+
+	// Columns of the Records can be selected after resume as before.
+	for _, set := range []*Interactions{inter, resumed} {
+		if err := set.SetColumns("MMSI_1", "LAT_2"); err != nil {
+			t.Errorf("Interactions.SetColumns() error = %v", err)
+		}
+	}
+	if got, want := widths(resumed), widths(inter); !reflect.DeepEqual(got, want) || got[0] != 2 {
+		t.Errorf("ResumeInteractions() SetColumns widths = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,267 @@
+package ais
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// KinematicFields is the column header appended by RecordSet.CleanKinematics
+// when CleanRules.Flag is set.
+const KinematicFields = "KinematicFlags"
+
+// CleanRule identifies a check applied by RecordSet.CleanKinematics.
+type CleanRule int
+
+const (
+	// PositionNotAvailable is a LAT of 91 or a LON of 181, the AIS placeholders
+	// for a position that is not available.
+	PositionNotAvailable CleanRule = iota
+
+	// PositionOutOfRange is a LAT outside [-90, 90], a LON outside [-180, 180],
+	// or a position that cannot be parsed.
+	PositionOutOfRange
+
+	// ExcessiveSOG is a SOG above CleanRules.MaxSOG, or above MaxCargoSOG for a
+	// cargo ship or tanker.
+	ExcessiveSOG
+
+	// ImpliedSpeed is a position that could only be reached from the previous
+	// fix of the same vessel at a speed above CleanRules.MaxImpliedSpeed.
+	ImpliedSpeed
+
+	numCleanRules
+)
+
+// String implements the Stringer interface for CleanRule.
+func (r CleanRule) String() string {
+	switch r {
+	case PositionNotAvailable:
+		return "position not available"
+	case PositionOutOfRange:
+		return "position out of range"
+	case ExcessiveSOG:
+		return "excessive SOG"
+	case ImpliedSpeed:
+		return "implied speed"
+	}
+	return fmt.Sprintf("CleanRule(%d)", int(r))
+}
+
+// CleanRules configures RecordSet.CleanKinematics.  A limit of zero disables the
+// check.  The position checks are always applied.
+type CleanRules struct {
+	MaxSOG          float64 // knots, for any vessel
+	MaxCargoSOG     float64 // knots, for VesselType 70 through 89
+	MaxImpliedSpeed float64 // knots, between consecutive fixes of one vessel
+
+	// Flag keeps every Record and appends a KinematicFields column listing the
+	// failed rules instead of dropping the Records that fail.
+	Flag bool
+}
+
+// DefaultCleanRules returns the CleanRules used by most analyses: cargo ships
+// and tankers faster than 60 knots and fixes that imply more than 100 knots are
+// rejected.
+func DefaultCleanRules() CleanRules {
+	return CleanRules{MaxCargoSOG: 60, MaxImpliedSpeed: 100}
+}
+
+// CleanReport summarizes the Records rejected by RecordSet.CleanKinematics.  A
+// Record that fails several rules is counted once in Rejected and once for each
+// rule in Counts.
+type CleanReport struct {
+	Records  int
+	Rejected int
+	Counts   [numCleanRules]int
+}
+
+// String implements the Stringer interface for CleanReport.
+func (r *CleanReport) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d of %d records rejected\n", r.Rejected, r.Records)
+	for rule, n := range r.Counts {
+		fmt.Fprintf(&buf, "\t%s: %d\n", CleanRule(rule), n)
+	}
+	return buf.String()
+}
+
+// cleanFix is the last accepted position of a vessel.
+type cleanFix struct {
+	lat, lon float64
+	t        time.Time
+	skew     time.Duration // TimeSkew of the Source of the fix
+}
+
+// impliedSpeed returns the speed in knots needed to move from the fix to lat, lon
+// at t.  The time between the two is widened by the larger of skew and the
+// TimeSkew of the fix.
+func (f cleanFix) impliedSpeed(lat, lon float64, t time.Time, skew time.Duration) float64 {
+	if f.skew > skew {
+		skew = f.skew
+	}
+	dt := t.Sub(f.t)
+	if dt < 0 {
+		dt = -dt
+	}
+	dt += skew
+	if dt <= 0 {
+		return math.Inf(1)
+	}
+	return Haversine(f.lat, f.lon, lat, lon) / dt.Hours()
+}
+
+// CleanKinematics returns a pointer to a new RecordSet without the Records that
+// report impossible positions or speeds, and a CleanReport of what was removed.
+// When rules.Flag is set every Record is kept and the failures are listed in a
+// KinematicFields column instead.  The Headers must contain MMSI, BaseDateTime,
+// LAT, and LON.  The SOG checks are skipped when the Headers do not contain SOG,
+// and MaxCargoSOG also needs VesselType.  The implied speed check compares each
+// Record with the last accepted fix of the same MMSI, so the RecordSet should be
+// sorted by time, and it keeps one fix per vessel in memory.  The time between
+// fixes is widened by the TimeSkew of the SourceRules of the Headers, so the
+// late timestamps of satellite reports do not imply impossible speeds.
+// CleanKinematics consumes the receiver.
+func (rs *RecordSet) CleanKinematics(rules CleanRules) (*RecordSet, *CleanReport, error) {
+	kc, err := newKinematicCleaner(rs.Headers(), rules)
+	if err != nil {
+		return nil, nil, fmt.Errorf("clean kinematics: %w", err)
+	}
+
+	rs2 := NewRecordSet()
+	h := rs.Headers()
+	if rules.Flag {
+		h.Fields = append(append([]string{}, h.Fields...), KinematicFields)
+	}
+	rs2.SetHeaders(h)
+
+	written := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("clean kinematics: read error on csv file: %w", err)
+		}
+		keep, err := kc.clean(rec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("clean kinematics: %w", err)
+		}
+		if !keep {
+			continue
+		}
+
+		if err := rs2.Write(*rec); err != nil {
+			return nil, nil, fmt.Errorf("clean kinematics: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, nil, fmt.Errorf("clean kinematics: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, nil, fmt.Errorf("clean kinematics: csv flush error: %w", err)
+	}
+	return rs2, kc.report, nil
+}
+
+// kinematicCleaner applies CleanRules to the Records of one RecordSet in turn.
+type kinematicCleaner struct {
+	rules             CleanRules
+	h                 Headers
+	idx               map[string]HeaderMap
+	sogIndex          int
+	typeIndex         int
+	haveSOG, haveType bool
+	last              map[string]cleanFix
+	report            *CleanReport
+}
+
+// newKinematicCleaner returns a kinematicCleaner for Records described by h,
+// which must contain MMSI, BaseDateTime, LAT, and LON.
+func newKinematicCleaner(h Headers, rules CleanRules) (*kinematicCleaner, error) {
+	idx, err := h.require("MMSI", "BaseDateTime", "LAT", "LON")
+	if err != nil {
+		return nil, err
+	}
+	kc := &kinematicCleaner{rules: rules, h: h, idx: idx, last: make(map[string]cleanFix), report: new(CleanReport)}
+	kc.sogIndex, kc.haveSOG = h.Contains("SOG")
+	kc.typeIndex, kc.haveType = h.Contains("VesselType")
+	if !kc.haveSOG {
+		warn("clean kinematics: SOG checks skipped", "missing", "SOG")
+	} else if rules.MaxCargoSOG > 0 && !kc.haveType {
+		warn("clean kinematics: MaxCargoSOG check skipped", "missing", "VesselType")
+	}
+	return kc, nil
+}
+
+// clean checks rec, counts it in the report, and reports whether it is kept.
+// With Flag set every Record is kept and the failed rules are appended to rec.
+func (kc *kinematicCleaner) clean(rec *Record) (bool, error) {
+	kc.report.Records++
+	idx, h := kc.idx, kc.h
+
+	var failed []CleanRule
+	lat, errLat := rec.ParseFloat(idx["LAT"].Idx)
+	lon, errLon := rec.ParseFloat(idx["LON"].Idx)
+	switch {
+	case errLat == nil && errLon == nil && (lat == 91 || lon == 181):
+		failed = append(failed, PositionNotAvailable)
+	case errLat != nil || errLon != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180:
+		failed = append(failed, PositionOutOfRange)
+	}
+
+	if kc.haveSOG {
+		if sog, err := rec.ParseFloat(kc.sogIndex); err == nil && sog < 102.3 {
+			limit := kc.rules.MaxSOG
+			if kc.rules.MaxCargoSOG > 0 && kc.haveType {
+				if vt, err := rec.ParseInt(kc.typeIndex); err == nil && vt >= 70 && vt <= 89 {
+					if limit == 0 || kc.rules.MaxCargoSOG < limit {
+						limit = kc.rules.MaxCargoSOG
+					}
+				}
+			}
+			if limit > 0 && sog > limit {
+				failed = append(failed, ExcessiveSOG)
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		t, err := h.parseTime((*rec)[idx["BaseDateTime"].Idx])
+		if err != nil {
+			return false, err
+		}
+		mmsi := (*rec)[idx["MMSI"].Idx]
+		prev, seen := kc.last[mmsi]
+		skew := rec.tolerance(h).TimeSkew
+		if kc.rules.MaxImpliedSpeed > 0 && seen && (t.After(prev.t) || skew > 0 || prev.skew > 0) &&
+			prev.impliedSpeed(lat, lon, t, skew) > kc.rules.MaxImpliedSpeed {
+			failed = append(failed, ImpliedSpeed)
+		} else {
+			kc.last[mmsi] = cleanFix{lat, lon, t, skew}
+		}
+	}
+
+	for _, rule := range failed {
+		kc.report.Counts[rule]++
+	}
+	if len(failed) > 0 {
+		kc.report.Rejected++
+	}
+	if kc.rules.Flag {
+		names := make([]string, len(failed))
+		for i, rule := range failed {
+			names[i] = rule.String()
+		}
+		*rec = append(*rec, strings.Join(names, ";"))
+		return true, nil
+	}
+	return len(failed) == 0, nil
+}
@@ -0,0 +1,72 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+)
+
+const cleanData = `MMSI,BaseDateTime,LAT,LON,SOG,VesselType
+100000000,2017-12-01T00:00:00,30.00000,-76.00000,10.0,70
+100000000,2017-12-01T00:01:00,91.00000,-76.00000,10.0,70
+100000000,2017-12-01T00:02:00,30.00000,-190.00000,10.0,70
+100000000,2017-12-01T00:03:00,30.00000,-76.00000,65.0,70
+200000000,2017-12-01T00:00:00,30.00000,-76.00000,65.0,30
+100000000,2017-12-01T00:10:00,31.00000,-76.00000,10.0,70
+100000000,2017-12-01T00:20:00,30.01000,-76.00000,10.0,70
+100000000,2017-12-01T00:21:00,30.01000,-76.00000,102.3,70
+`
+
+func TestRecordSet_CleanKinematics(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(cleanData), Headers{})
+	clean, report, err := rs.CleanKinematics(DefaultCleanRules())
+	if err != nil {
+		t.Fatalf("RecordSet.CleanKinematics() error = %v", err)
+	}
+	var times []string
+	for {
+		rec, err := clean.Read()
+		if err != nil {
+			break
+		}
+		times = append(times, (*rec)[0][:1]+(*rec)[1][14:16]) // first MMSI digit and minute
+	}
+	want := "100,200,120,121"
+	if strings.Join(times, ",") != want {
+		t.Errorf("RecordSet.CleanKinematics() kept %v, want %s", times, want)
+	}
+	if report.Records != 8 || report.Rejected != 4 || report.Counts != [numCleanRules]int{1, 1, 1, 1} {
+		t.Errorf("RecordSet.CleanKinematics() report = %+v", report)
+	}
+	if !strings.Contains(report.String(), "4 of 8 records rejected") {
+		t.Errorf("CleanReport.String() = %q", report.String())
+	}
+
+	rules := DefaultCleanRules()
+	rules.Flag = true
+	rs, _ = NewRecordSetFromReader(strings.NewReader(cleanData), Headers{})
+	flagged, _, err := rs.CleanKinematics(rules)
+	if err != nil {
+		t.Fatalf("RecordSet.CleanKinematics() error = %v", err)
+	}
+	i, ok := flagged.Headers().Contains(KinematicFields)
+	if !ok {
+		t.Fatalf("RecordSet.CleanKinematics() headers = %v, want %s", flagged.Headers(), KinematicFields)
+	}
+	var flags []string
+	for {
+		rec, err := flagged.Read()
+		if err != nil {
+			break
+		}
+		flags = append(flags, (*rec)[i])
+	}
+	wantFlags := []string{"", "position not available", "position out of range", "excessive SOG", "", "implied speed", "", ""}
+	if strings.Join(flags, "|") != strings.Join(wantFlags, "|") {
+		t.Errorf("RecordSet.CleanKinematics() flags = %q, want %q", flags, wantFlags)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,LAT,LON\n"), Headers{})
+	if _, _, err := rs.CleanKinematics(rules); err == nil {
+		t.Error("RecordSet.CleanKinematics() expected error for headers without BaseDateTime")
+	}
+}
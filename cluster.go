@@ -2,14 +2,23 @@ package ais
 
 import (
 	"bytes"
+	"encoding/csv"
 	"fmt"
+	"math"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcloughlin/geohash"
 )
 
 // Cluster is an abstraction for a []*Record. The intent is that a Cluster of
 // Records are vessels that share the same geohash
 type Cluster struct {
 	data []*Record
+	home int // number of leading Records from the home cell, all of them when zero
 }
 
 // Append adds a *Record to the underlying slice managed by the Cluster
@@ -40,9 +49,10 @@ type ClusterMap map[uint64]*Cluster
 // into common Clusters that share the same geohash.  It requires that
 // the RecordSet Window it is operating on has a 'Geohash' field stored as
 // a Uint64 with the proper prefix for the hash (i.e. 0x for hex representation).
+// The Records of each Cluster are in the order of Window.Records.
 func (win *Window) FindClusters(geohashIndex int) ClusterMap {
 	cm := make(ClusterMap)
-	for _, rec := range win.Data {
+	for _, rec := range win.Records() {
 		geoString := (*rec)[geohashIndex]
 		geohash, err := strconv.ParseUint(geoString, 0, 64)
 		if err != nil {
@@ -60,3 +70,228 @@ func (win *Window) FindClusters(geohashIndex int) ClusterMap {
 	}
 	return cm
 }
+
+// FindClustersNeighbors is FindClusters for vessels that straddle the border of a
+// geohash cell.  The Cluster of each occupied cell also holds the Records of its
+// north, northeast, east, and southeast neighbors, which with the clusters of
+// those neighbors covers all eight neighbors of every cell.  When the Cluster is
+// added to Interactions only pairs with at least one Record from the home cell
+// are compared, so no pair of Records is compared twice.  Every pair of vessels
+// closer than the size of a cell is found, and TunePrecision with EightNeighbors
+// gives the precision for an interaction radius.  The Geohash field must hold
+// integer geohashes with bits of precision, as appended by FindInteractions, and
+// clusters are keyed by the geohash of their home cell.  Combine the neighbor
+// clusters with WithMaxDistance, because they pair vessels up to two cells apart.
+func (win *Window) FindClustersNeighbors(geohashIndex int, bits uint) ClusterMap {
+	cells := make(map[uint64][]*Record)
+	var order []uint64
+	for _, rec := range win.Records() {
+		hash, err := strconv.ParseUint((*rec)[geohashIndex], 0, 64)
+		if err != nil {
+			panic(err)
+		}
+		if _, ok := cells[hash]; !ok {
+			order = append(order, hash)
+		}
+		cells[hash] = append(cells[hash], rec)
+	}
+
+	cm := make(ClusterMap, len(cells))
+	for _, hash := range order {
+		recs := cells[hash]
+		c := &Cluster{data: append([]*Record(nil), recs...), home: len(recs)}
+		seen := map[uint64]bool{hash: true}
+		for _, n := range geohash.NeighborsIntWithPrecision(hash, bits)[:4] { // N, NE, E, SE
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			c.data = append(c.data, cells[n]...)
+		}
+		cm[hash] = c
+	}
+	return cm
+}
+
+// Clusters returns the Clusters of the ClusterMap sorted by geohash, an order
+// that is fixed for a given Window where ranging over the map is not.
+func (cm ClusterMap) Clusters() []*Cluster {
+	hashes := make([]uint64, 0, len(cm))
+	for h := range cm {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	clusters := make([]*Cluster, len(hashes))
+	for i, h := range hashes {
+		clusters[i] = cm[h]
+	}
+	return clusters
+}
+
+// Centroid returns the mean position of the Records of the Cluster described by
+// h, averaged on the sphere so that a Cluster across the antimeridian has its
+// centroid there rather than on the far side of the earth.  The Headers must
+// contain LAT and LON, and the position of every Record must be available.
+func (c *Cluster) Centroid(h Headers) (lat, lon float64, err error) {
+	if len(c.data) == 0 {
+		return 0, 0, fmt.Errorf("cluster centroid: %w", ErrEmptySet)
+	}
+	const rad = math.Pi / 180
+	var x, y, z float64
+	for _, rec := range c.data {
+		lat, lon, err := rec.position(h)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cluster centroid: %w", err)
+		}
+		sinLat, cosLat := math.Sincos(lat * rad)
+		sinLon, cosLon := math.Sincos(lon * rad)
+		x += cosLat * cosLon
+		y += cosLat * sinLon
+		z += sinLat
+	}
+	return math.Atan2(z, math.Hypot(x, y)) / rad, math.Atan2(y, x) / rad, nil
+}
+
+// Bounds returns the smallest Box that contains the positions of the Records of
+// the Cluster described by h.  Like Track.BoundingBox it does not support a
+// Cluster across the antimeridian.  The Headers must contain LAT and LON.
+func (c *Cluster) Bounds(h Headers) (Box, error) {
+	if len(c.data) == 0 {
+		return Box{}, fmt.Errorf("cluster bounds: %w", ErrEmptySet)
+	}
+	b := Box{MinLat: math.Inf(1), MaxLat: math.Inf(-1), MinLon: math.Inf(1), MaxLon: math.Inf(-1)}
+	for _, rec := range c.data {
+		lat, lon, err := rec.position(h)
+		if err != nil {
+			return Box{}, fmt.Errorf("cluster bounds: %w", err)
+		}
+		b.MinLat, b.MaxLat = math.Min(b.MinLat, lat), math.Max(b.MaxLat, lat)
+		b.MinLon, b.MaxLon = math.Min(b.MinLon, lon), math.Max(b.MaxLon, lon)
+	}
+	return b, nil
+}
+
+// TimeExtent returns the earliest and latest BaseDateTime of the Records of the
+// Cluster described by h.
+func (c *Cluster) TimeExtent(h Headers) (start, end time.Time, err error) {
+	if len(c.data) == 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("cluster time extent: %w", ErrEmptySet)
+	}
+	for i, rec := range c.data {
+		t, err := rec.Time(h)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("cluster time extent: %w", err)
+		}
+		if i == 0 || t.Before(start) {
+			start = t
+		}
+		if i == 0 || t.After(end) {
+			end = t
+		}
+	}
+	return start, end, nil
+}
+
+// MMSIs returns the distinct MMSIs of the Records of the Cluster described by h
+// in ascending order.  The Headers must contain MMSI.
+func (c *Cluster) MMSIs(h Headers) ([]string, error) {
+	seen := make(map[string]bool)
+	var mmsis []string
+	for _, rec := range c.data {
+		m, err := rec.MMSI(h)
+		if err != nil {
+			return nil, fmt.Errorf("cluster mmsis: %w", err)
+		}
+		if !seen[string(m)] {
+			seen[string(m)] = true
+			mmsis = append(mmsis, string(m))
+		}
+	}
+	sort.Strings(mmsis)
+	return mmsis, nil
+}
+
+// Density returns the number of distinct vessels of the Cluster described by h
+// per square nautical mile of its Bounds.  It returns an error for a Cluster
+// whose positions enclose no area, such as one of a single Record.  The Headers
+// must contain MMSI, LAT, and LON.
+func (c *Cluster) Density(h Headers) (float64, error) {
+	mmsis, err := c.MMSIs(h)
+	if err != nil {
+		return 0, fmt.Errorf("cluster density: %w", err)
+	}
+	b, err := c.Bounds(h)
+	if err != nil {
+		return 0, fmt.Errorf("cluster density: %w", err)
+	}
+	area := (b.MaxLat - b.MinLat) * 60 * (b.MaxLon - b.MinLon) * 60 * math.Cos((b.MinLat+b.MaxLat)/2*math.Pi/180)
+	if area <= 0 {
+		return 0, fmt.Errorf("cluster density: positions enclose no area")
+	}
+	return float64(len(mmsis)) / area, nil
+}
+
+// ClusterFields are the columns written by SaveClusters, one row per Cluster.
+// Cluster is the position of the Cluster in the slice, LAT and LON are its
+// Centroid, and Density is in vessels per square nautical mile, empty when the
+// Cluster encloses no area.
+const ClusterFields = "Cluster,Records,Vessels,Start,End,LAT,LON,MinLat,MaxLat,MinLon,MaxLon,Density"
+
+// SaveClusters writes the metadata of each of the clusters, such as those of
+// ClusterMap.Clusters or DBSCAN, to a csv file with ClusterFields, so clusters
+// can be mapped and analyzed on their own rather than only added to
+// Interactions.  The Headers h describe the Records of every Cluster and must
+// contain MMSI, BaseDateTime, LAT, and LON.
+func SaveClusters(filename string, h Headers, clusters []*Cluster) error {
+	if _, err := h.require("MMSI", "BaseDateTime", "LAT", "LON"); err != nil {
+		return fmt.Errorf("save clusters: %w", err)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("save clusters: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(strings.Split(ClusterFields, ","))
+	for i, c := range clusters {
+		mmsis, err := c.MMSIs(h)
+		if err != nil {
+			return fmt.Errorf("save clusters: cluster %d: %w", i, err)
+		}
+		start, end, err := c.TimeExtent(h)
+		if err != nil {
+			return fmt.Errorf("save clusters: cluster %d: %w", i, err)
+		}
+		lat, lon, err := c.Centroid(h)
+		if err != nil {
+			return fmt.Errorf("save clusters: cluster %d: %w", i, err)
+		}
+		b, err := c.Bounds(h)
+		if err != nil {
+			return fmt.Errorf("save clusters: cluster %d: %w", i, err)
+		}
+		density := ""
+		if d, err := c.Density(h); err == nil {
+			density = strconv.FormatFloat(d, 'f', 4, 64)
+		}
+		w.Write([]string{
+			strconv.Itoa(i),
+			strconv.Itoa(c.Size()),
+			strconv.Itoa(len(mmsis)),
+			start.Format(TimeLayout),
+			end.Format(TimeLayout),
+			strconv.FormatFloat(lat, 'f', 5, 64),
+			strconv.FormatFloat(lon, 'f', 5, 64),
+			strconv.FormatFloat(b.MinLat, 'f', -1, 64),
+			strconv.FormatFloat(b.MaxLat, 'f', -1, 64),
+			strconv.FormatFloat(b.MinLon, 'f', -1, 64),
+			strconv.FormatFloat(b.MaxLon, 'f', -1, 64),
+			density,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("save clusters: %w", err)
+	}
+	return f.Close()
+}
@@ -1,9 +1,15 @@
 package ais
 
 import (
+	"encoding/csv"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCluster_Append(t *testing.T) {
@@ -231,3 +237,140 @@ func TestCluster_Data(t *testing.T) {
 		})
 	}
 }
+
+func TestWindow_FindClustersNeighbors(t *testing.T) {
+	// Vessels a few hundred yards apart on either side of a cell border at 22
+	// bits of precision, and a third vessel far away.
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON", "Geohash"}}
+	win := &Window{}
+	var cells []string
+	for _, r := range []Record{
+		{"1", "2017-12-01T00:00:00", "37.0", "-76.642", ""},
+		{"2", "2017-12-01T00:00:00", "37.0", "-76.639", ""},
+		{"3", "2017-12-01T00:00:00", "38.0", "-75.0", ""},
+	} {
+		g, _ := geohashGenerator{22}.Generate(r, 2, 3)
+		r[4] = string(g)
+		cells = append(cells, r[4])
+		win.AddRecord(r)
+	}
+	if cells[0] == cells[1] {
+		t.Fatal("test vessels share a geohash cell")
+	}
+
+	pairs := func(cm ClusterMap) int {
+		inter, _ := NewInteractions(h)
+		for _, c := range cm {
+			if err := inter.AddCluster(c); err != nil {
+				t.Fatalf("Interactions.AddCluster() error = %v", err)
+			}
+		}
+		return inter.Len()
+	}
+	if n := pairs(win.FindClusters(4)); n != 0 {
+		t.Errorf("FindClusters() found %d interactions, want 0 across a cell border", n)
+	}
+	if n := pairs(win.FindClustersNeighbors(4, 22)); n != 1 {
+		t.Errorf("FindClustersNeighbors() found %d interactions, want 1", n)
+	}
+}
+
+func TestCluster_Metadata(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON"}}
+	c := new(Cluster)
+	for _, r := range []Record{
+		{"366000002", "2017-12-01T00:02:00", "36.0", "-76.0"},
+		{"366000001", "2017-12-01T00:00:00", "36.1", "-76.1"},
+		{"366000002", "2017-12-01T00:01:00", "36.1", "-76.0"},
+		{"366000001", "2017-12-01T00:03:00", "36.0", "-76.1"},
+	} {
+		rec := r
+		c.Append(&rec)
+	}
+
+	lat, lon, err := c.Centroid(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(lat-36.05) > 1e-3 || math.Abs(lon+76.05) > 1e-9 {
+		t.Errorf("Centroid() = %v, %v, want about 36.05, -76.05", lat, lon)
+	}
+	b, err := c.Bounds(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Box{MinLat: 36, MaxLat: 36.1, MinLon: -76.1, MaxLon: -76}); b != want {
+		t.Errorf("Bounds() = %+v, want %+v", b, want)
+	}
+	start, end, err := c.TimeExtent(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC); !start.Equal(s) || !end.Equal(s.Add(3*time.Minute)) {
+		t.Errorf("TimeExtent() = %v, %v", start, end)
+	}
+	mmsis, err := c.MMSIs(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"366000001", "366000002"}; !reflect.DeepEqual(mmsis, want) {
+		t.Errorf("MMSIs() = %v, want %v", mmsis, want)
+	}
+	d, err := c.Density(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	area := 6 * 6 * math.Cos(36.05*math.Pi/180)
+	if math.Abs(d-2/area) > 1e-6 {
+		t.Errorf("Density() = %v, want %v", d, 2/area)
+	}
+
+	// A cluster across the antimeridian has its centroid on it.
+	across := &Cluster{data: []*Record{{"1", "2017-12-01T00:00:00", "0", "179.9"}, {"2", "2017-12-01T00:00:00", "0", "-179.9"}}}
+	if _, lon, err := across.Centroid(h); err != nil || math.Abs(math.Abs(lon)-180) > 1e-9 {
+		t.Errorf("Centroid() across the antimeridian = %v, %v", lon, err)
+	}
+	single := &Cluster{data: c.data[:1]}
+	if _, err := single.Density(h); err == nil {
+		t.Error("Density() of a single Record returned no error")
+	}
+	if _, _, err := new(Cluster).Centroid(h); err == nil {
+		t.Error("Centroid() of an empty Cluster returned no error")
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "clusters.csv")
+	if err := SaveClusters(filename, h, []*Cluster{c, single}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != ClusterFields {
+		t.Fatalf("SaveClusters wrote %v", rows)
+	}
+	if rows[1][1] != "4" || rows[1][2] != "2" || rows[1][11] == "" || rows[2][11] != "" {
+		t.Errorf("SaveClusters rows = %v", rows[1:])
+	}
+	if err := SaveClusters(filename, Headers{Fields: []string{"LAT", "LON"}}, nil); err == nil {
+		t.Error("SaveClusters without MMSI returned no error")
+	}
+}
+
+func TestClusterMap_Clusters(t *testing.T) {
+	a, b, c := new(Cluster), new(Cluster), new(Cluster)
+	cm := ClusterMap{0x30: c, 0x10: a, 0x20: b}
+	if got := cm.Clusters(); len(got) != 3 || got[0] != a || got[1] != b || got[2] != c {
+		t.Errorf("Clusters() = %v, want them in geohash order", got)
+	}
+}
@@ -0,0 +1,183 @@
+// Command ais-interactions finds the two vessel interactions in a file of AIS
+// data and saves them, running the same pipeline as RecordSet.FindInteractions
+// without writing any Go.
+//
+// Usage:
+//
+//	ais-interactions -in day.csv -out inter.csv [flags]
+//
+// The input is read with ais.OpenRecordSet unless its extension is .jsonl,
+// .arrow, or .parquet.  The output format is chosen with -format or, when it is
+// empty, from the extension of -out and may be csv, geojson, kml, or jsonl.  An
+// -out of - writes csv to standard output.  For
+// example, to find vessels within half a mile of each other in five minute
+// windows and view them in a GIS:
+//
+//	ais-interactions -in day.csv.gz -out inter.geojson -window 5m -distance 0.5
+//
+// Distances are Haversine great circle distances unless -metric chooses another,
+// such as geodesic for the WGS-84 distance of ais.Vincenty.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/FATHOM5/ais"
+)
+
+func main() {
+	in := flag.String("in", "", "input file of AIS records (required)")
+	out := flag.String("out", "", "output file of interactions, - for csv on standard output (required)")
+	format := flag.String("format", "", "output format: csv, geojson, kml, or jsonl (default from -out extension)")
+	precision := flag.Uint("precision", 0, "bits of geohash precision used to group vessels (0 for the ais default, or to use a Geohash column in the input)")
+	distance := flag.Float64("distance", 0, "largest distance in nautical miles between interacting vessels (0 keeps every pair)")
+	metric := flag.String("metric", "haversine", "distance metric: haversine, equirectangular, or geodesic (WGS-84 Vincenty)")
+	window := flag.Duration("window", ais.DefaultInteractionWindow, "width of the time window")
+	slide := flag.Duration("slide", 0, "step between windows (default half the window)")
+	timeGap := flag.Duration("timegap", 0, "largest time between the two records of a pair (0 for no limit)")
+	neighbors := flag.Bool("neighbors", false, "also compare vessels in the eight neighboring geohash cells")
+	closest := flag.Bool("closest", false, "keep only the closest approach of each vessel pair")
+	sorted := flag.Bool("sorted", false, "input is already sorted by BaseDateTime")
+	cpa := flag.Bool("cpa", false, "add closest point of approach columns")
+	risk := flag.Bool("risk", false, "add collision risk columns")
+	encounter := flag.Bool("encounter", false, "add the COLREGS encounter column")
+	columns := flag.String("columns", "", "comma separated output columns, such as MMSI_1,MMSI_2,Distance(nm),Bearing (default all)")
+	order := flag.String("order", "", "order of the output rows: time or hash (default unordered)")
+	summary := flag.String("summary", "", "also write a csv summary of the interactions to this file")
+	graph := flag.String("graph", "", "also write the vessel encounter network to this .graphml, .dot, or .csv file")
+	flag.Parse()
+
+	if *in == "" || *out == "" {
+		fmt.Fprintln(os.Stderr, "ais-interactions: -in and -out are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = outputFormat(*out)
+	}
+
+	rs, err := open(*in)
+	if err != nil {
+		log.Fatalf("ais-interactions: %v", err)
+	}
+	defer rs.Close()
+
+	fn, err := ais.ParseDistanceFunc(*metric)
+	if err != nil {
+		log.Fatalf("ais-interactions: %v", err)
+	}
+	opts := []ais.InteractionOption{ais.WithDistanceFunc(fn)}
+	if *distance > 0 {
+		opts = append(opts, ais.WithMaxDistance(*distance))
+	}
+	if *timeGap > 0 {
+		opts = append(opts, ais.WithMaxTimeGap(*timeGap))
+	}
+	if *closest {
+		opts = append(opts, ais.WithClosestApproach())
+	}
+	p := ais.InteractionParams{
+		Window:    *window,
+		Slide:     *slide,
+		Precision: *precision,
+		Sorted:    *sorted,
+	}
+	if *neighbors {
+		p.Strategy = ais.EightNeighbors
+	}
+	inter, err := rs.FindInteractions(context.Background(), p, opts...)
+	if err != nil {
+		log.Fatalf("ais-interactions: %v", err)
+	}
+	inter.SetCPA(*cpa)
+	inter.SetRisk(*risk)
+	inter.SetEncounter(*encounter)
+	if *columns != "" {
+		if err := inter.SetColumns(strings.Split(*columns, ",")...); err != nil {
+			log.Fatalf("ais-interactions: %v", err)
+		}
+	}
+	switch *order {
+	case "":
+	case "time":
+		err = inter.SetOrder(ais.OrderByTime)
+	case "hash":
+		err = inter.SetOrder(ais.OrderByHash)
+	default:
+		err = fmt.Errorf("unknown order %q", *order)
+	}
+	if err != nil {
+		log.Fatalf("ais-interactions: %v", err)
+	}
+
+	if err := save(inter, *out, *format); err != nil {
+		log.Fatalf("ais-interactions: %v", err)
+	}
+	log.Printf("ais-interactions: wrote %d interactions to %s", inter.Len(), *out)
+
+	if *summary != "" {
+		s, err := inter.Summary()
+		if err != nil {
+			log.Fatalf("ais-interactions: %v", err)
+		}
+		if err := s.Save(*summary); err != nil {
+			log.Fatalf("ais-interactions: %v", err)
+		}
+	}
+	if *graph != "" {
+		if err := inter.SaveGraph(*graph); err != nil {
+			log.Fatalf("ais-interactions: %v", err)
+		}
+	}
+}
+
+// open reads filename with the ais function that matches its extension.
+func open(filename string) (*ais.RecordSet, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jsonl":
+		return ais.OpenJSONL(filename)
+	case ".arrow":
+		return ais.OpenArrow(filename)
+	case ".parquet":
+		return ais.OpenParquet(filename)
+	}
+	return ais.OpenRecordSet(filename)
+}
+
+// outputFormat returns the format implied by the extension of filename.
+func outputFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".geojson", ".json":
+		return "geojson"
+	case ".kml":
+		return "kml"
+	case ".jsonl":
+		return "jsonl"
+	}
+	return "csv"
+}
+
+// save writes the interactions to filename in format, or as csv to standard
+// output when filename is -.
+func save(inter *ais.Interactions, filename, format string) error {
+	if filename == "-" {
+		return inter.WriteCSV(os.Stdout)
+	}
+	switch format {
+	case "csv":
+		return inter.Save(filename)
+	case "geojson":
+		return inter.SaveGeoJSON(filename)
+	case "kml":
+		return inter.SaveKML(filename)
+	case "jsonl":
+		return inter.SaveJSONL(filename)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
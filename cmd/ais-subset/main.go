@@ -0,0 +1,290 @@
+// Command ais-subset filters a large AIS csv file by area, time, vessel, and
+// vessel type and selects or reorders its columns.  Records are streamed from the
+// input to the output one at a time so memory use does not grow with the size of
+// the file.
+//
+// Usage:
+//
+//	ais-subset -in day.csv.gz [-out subset.csv] [flags]
+//
+// The output is written to stdout when -out is empty.  Every filter that is set
+// must match for a Record to be kept.  For example, to keep the tankers in
+// Chesapeake Bay during one morning with only their positions:
+//
+//	ais-subset -in day.csv -bbox 36.8,-76.6,39.6,-75.9 \
+//		-start 2017-12-01T06:00:00 -end 2017-12-01T12:00:00 \
+//		-vesseltype 80-89 -columns MMSI,BaseDateTime,LAT,LON
+//
+// A list for -mmsi may be given inline or, prefixed with @, as the name of a file
+// with one MMSI per line.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FATHOM5/ais"
+)
+
+func main() {
+	in := flag.String("in", "", "input csv file of AIS records (required)")
+	out := flag.String("out", "", "output csv file (default stdout)")
+	bbox := flag.String("bbox", "", "bounding box as minLat,minLon,maxLat,maxLon")
+	start := flag.String("start", "", "earliest BaseDateTime kept, as "+ais.TimeLayout)
+	end := flag.String("end", "", "latest BaseDateTime kept, as "+ais.TimeLayout)
+	mmsi := flag.String("mmsi", "", "comma separated MMSIs to keep, or @file with one per line")
+	vesselType := flag.String("vesseltype", "", "comma separated VesselType codes or ranges such as 70-79")
+	columns := flag.String("columns", "", "comma separated columns to write in order (default all)")
+	flag.Parse()
+
+	if *in == "" {
+		fmt.Fprintln(os.Stderr, "ais-subset: -in is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	rs, err := ais.OpenRecordSet(*in)
+	if err != nil {
+		log.Fatalf("ais-subset: %v", err)
+	}
+	defer rs.Close()
+	h := rs.Headers()
+
+	var matchers []ais.Matching
+	add := func(m ais.Matching, err error) {
+		if err != nil {
+			log.Fatalf("ais-subset: %v", err)
+		}
+		if m != nil {
+			matchers = append(matchers, m)
+		}
+	}
+	add(boxMatcher(*bbox, h))
+	add(timeMatcher(*start, *end, h))
+	add(mmsiMatcher(*mmsi, h))
+	add(typeMatcher(*vesselType, h))
+
+	project, outFields, err := projection(*columns, h)
+	if err != nil {
+		log.Fatalf("ais-subset: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("ais-subset: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	cw := csv.NewWriter(bw)
+	n, err := subset(rs, matchers, project, outFields, cw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		log.Fatalf("ais-subset: %v", err)
+	}
+	log.Printf("ais-subset: wrote %d records", n)
+}
+
+// subset writes the projection of every Record of rs that matches all of the
+// matchers to cw and returns the number written.
+func subset(rs *ais.RecordSet, matchers []ais.Matching, project []int, fields []string, cw *csv.Writer) (int, error) {
+	if err := cw.Write(fields); err != nil {
+		return 0, err
+	}
+	row := make([]string, len(project))
+	n := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		keep := true
+		for _, m := range matchers {
+			if keep, err = m.Match(rec); err != nil || !keep {
+				break
+			}
+		}
+		if err != nil || !keep {
+			continue // records that cannot be parsed are dropped
+		}
+		for i, j := range project {
+			row[i] = (*rec)[j]
+		}
+		if err := cw.Write(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// boxMatcher returns an *ais.Box for a minLat,minLon,maxLat,maxLon flag value.
+func boxMatcher(s string, h ais.Headers) (ais.Matching, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox: want minLat,minLon,maxLat,maxLon, got %q", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		var err error
+		if v[i], err = strconv.ParseFloat(strings.TrimSpace(p), 64); err != nil {
+			return nil, fmt.Errorf("bbox: %v", err)
+		}
+	}
+	idx, ok := h.ContainsMulti("LAT", "LON")
+	if !ok {
+		return nil, fmt.Errorf("bbox: headers must contain LAT and LON")
+	}
+	return &ais.Box{MinLat: v[0], MinLon: v[1], MaxLat: v[2], MaxLon: v[3], LatIndex: idx["LAT"].Idx, LonIndex: idx["LON"].Idx}, nil
+}
+
+// timeRange matches Records with a BaseDateTime in [start, end].  A zero start or
+// end leaves that side open.
+type timeRange struct {
+	start, end time.Time
+	index      int
+}
+
+func (tr timeRange) Match(rec *ais.Record) (bool, error) {
+	t, err := rec.ParseTime(tr.index)
+	if err != nil {
+		return false, err
+	}
+	if !tr.start.IsZero() && t.Before(tr.start) {
+		return false, nil
+	}
+	if !tr.end.IsZero() && t.After(tr.end) {
+		return false, nil
+	}
+	return true, nil
+}
+
+func timeMatcher(start, end string, h ais.Headers) (ais.Matching, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	var tr timeRange
+	var err error
+	if start != "" {
+		if tr.start, err = time.Parse(ais.TimeLayout, start); err != nil {
+			return nil, fmt.Errorf("start: %v", err)
+		}
+	}
+	if end != "" {
+		if tr.end, err = time.Parse(ais.TimeLayout, end); err != nil {
+			return nil, fmt.Errorf("end: %v", err)
+		}
+	}
+	var ok bool
+	if tr.index, ok = h.Contains("BaseDateTime"); !ok {
+		return nil, fmt.Errorf("start, end: headers must contain BaseDateTime")
+	}
+	return tr, nil
+}
+
+// fieldSet matches Records whose field at index is one of the set.
+type fieldSet struct {
+	set   map[string]bool
+	index int
+}
+
+func (fs fieldSet) Match(rec *ais.Record) (bool, error) {
+	return fs.set[strings.TrimSpace((*rec)[fs.index])], nil
+}
+
+func mmsiMatcher(s string, h ais.Headers) (ais.Matching, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var list []string
+	if strings.HasPrefix(s, "@") {
+		var err error
+		if list, err = ais.LoadMMSIs(s[1:]); err != nil {
+			return nil, fmt.Errorf("mmsi: %v", err)
+		}
+	} else {
+		list = strings.Split(s, ",")
+	}
+	fs := fieldSet{set: make(map[string]bool)}
+	for _, m := range list {
+		if m = strings.TrimSpace(m); m != "" {
+			fs.set[m] = true
+		}
+	}
+	var ok bool
+	if fs.index, ok = h.Contains("MMSI"); !ok {
+		return nil, fmt.Errorf("mmsi: headers must contain MMSI")
+	}
+	return fs, nil
+}
+
+func typeMatcher(s string, h ais.Headers) (ais.Matching, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fs := fieldSet{set: make(map[string]bool)}
+	for _, p := range strings.Split(s, ",") {
+		lo, hi := strings.TrimSpace(p), strings.TrimSpace(p)
+		if i := strings.Index(p, "-"); i > 0 {
+			lo, hi = strings.TrimSpace(p[:i]), strings.TrimSpace(p[i+1:])
+		}
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("vesseltype: %v", err)
+		}
+		to, err := strconv.Atoi(hi)
+		if err != nil {
+			return nil, fmt.Errorf("vesseltype: %v", err)
+		}
+		for c := from; c <= to; c++ {
+			fs.set[strconv.Itoa(c)] = true
+		}
+	}
+	var ok bool
+	if fs.index, ok = h.Contains("VesselType"); !ok {
+		return nil, fmt.Errorf("vesseltype: headers must contain VesselType")
+	}
+	return fs, nil
+}
+
+// projection returns the indices into h of the named columns and their names,
+// or every column when columns is empty.
+func projection(columns string, h ais.Headers) ([]int, []string, error) {
+	if columns == "" {
+		idx := make([]int, len(h.Fields))
+		for i := range idx {
+			idx[i] = i
+		}
+		return idx, h.Fields, nil
+	}
+	fields := strings.Split(columns, ",")
+	idx := make([]int, len(fields))
+	for i, f := range fields {
+		fields[i] = strings.TrimSpace(f)
+		j, ok := h.Contains(fields[i])
+		if !ok {
+			return nil, nil, fmt.Errorf("columns: headers do not contain %s", fields[i])
+		}
+		idx[i] = j
+	}
+	return idx, fields, nil
+}
@@ -0,0 +1,37 @@
+// Command aisserver serves the interaction detection of package ais over HTTP so
+// that programs written in other languages can upload AIS data and fetch the
+// two vessel interactions found in it.
+//
+// Usage:
+//
+//	aisserver [-addr :8080] [-max-upload bytes] [-max-store bytes] [-max-results n]
+//
+// See ais.Server for the endpoints.  A typical session with curl is
+//
+//	curl --data-binary @day.csv localhost:8080/recordsets
+//	curl -X POST 'localhost:8080/recordsets/{id}/interactions?window=10m&distance=2&cpa=true'
+//	curl 'localhost:8080/interactions/{id}?format=geojson'
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/FATHOM5/ais"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	maxUpload := flag.Int64("max-upload", ais.DefaultMaxUpload, "largest recordset accepted in bytes")
+	maxStore := flag.Int64("max-store", ais.DefaultMaxStore, "bytes of recordsets held before the least recently used are evicted")
+	maxResults := flag.Int("max-results", ais.DefaultMaxResults, "interactions held before the least recently used are evicted")
+	flag.Parse()
+
+	srv := ais.NewServer()
+	srv.MaxUpload = *maxUpload
+	srv.MaxStore = *maxStore
+	srv.MaxResults = *maxResults
+	log.Printf("aisserver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
@@ -0,0 +1,88 @@
+package ais
+
+import "fmt"
+
+// BearingField is a derived column that may be passed to
+// Interactions.SetColumns.  It holds the initial great circle bearing in degrees
+// true from the first vessel of a pair to the second.
+const BearingField = "Bearing"
+
+// bearingColumn marks BearingField in the projection of an Interactions.
+const bearingColumn = -1
+
+// SetColumns chooses the columns, and their order, written by Save and the other
+// methods that write an Interactions set, instead of every one of the default
+// OutputHeaders.  Each column must be one of the OutputHeaders, such as
+// InteractionHash, Distance(nm), LAT_1, or the CPAFields once SetCPA is on, or
+// BearingField.  For example
+//
+//	inter.SetColumns("MMSI_1", "MMSI_2", "BaseDateTime_1", "Distance(nm)", ais.BearingField)
+//
+// writes five columns rather than one for every field of both Records.  Calling
+// SetColumns with no columns restores the default.  A column removed by a later
+// call to SetCPA, SetRisk, or SetEncounter is dropped from the output, and rows
+// are left empty when every chosen column is dropped.
+func (inter *Interactions) SetColumns(columns ...string) error {
+	inter.columns = nil
+	inter.resetOutputHeaders()
+	if len(columns) == 0 {
+		return nil
+	}
+	for _, c := range columns {
+		if c == BearingField {
+			continue
+		}
+		if _, ok := inter.OutputHeaders.Contains(c); !ok {
+			return fmt.Errorf("set columns: %w", ErrMissingHeader{Field: c})
+		}
+	}
+	inter.columns = append([]string(nil), columns...)
+	inter.project()
+	return nil
+}
+
+// project sets the projection from the columns chosen by SetColumns and narrows
+// OutputHeaders, which must hold every column, to them.  The projection is
+// non-nil, even when none of the columns remain, whenever columns are chosen.
+func (inter *Interactions) project() {
+	inter.projection = nil
+	if inter.columns == nil {
+		return
+	}
+	inter.projection = []int{}
+	fields := []string{}
+	for _, c := range inter.columns {
+		j := bearingColumn
+		if c != BearingField {
+			var ok bool
+			if j, ok = inter.OutputHeaders.Contains(c); !ok {
+				continue
+			}
+		}
+		fields = append(fields, c)
+		inter.projection = append(inter.projection, j)
+	}
+	inter.OutputHeaders = Headers{Fields: fields, Time: inter.OutputHeaders.Time}
+}
+
+// projectRow returns the columns of the projection from the full row of pair.
+// An unavailable Bearing is written as an empty field.
+func (inter *Interactions) projectRow(pair *RecordPair, full []string) []string {
+	row := make([]string, len(inter.projection))
+	for i, j := range inter.projection {
+		if j != bearingColumn {
+			row[i] = full[j]
+			continue
+		}
+		if b, err := inter.pairBearing(pair); err == nil {
+			row[i] = fmt.Sprintf("%.1f", b)
+		}
+	}
+	return row
+}
+
+// pairBearing returns the initial bearing from the first Record of pair to the
+// second.
+func (inter *Interactions) pairBearing(pair *RecordPair) (float64, error) {
+	return pair.rec1.Bearing(*pair.rec2, inter.hashIndices[2], inter.hashIndices[3])
+}
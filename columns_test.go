@@ -0,0 +1,106 @@
+package ais
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInteractions_SetColumns(t *testing.T) {
+	inter, _ := NewInteractionsWithOptions(goodHeaders, WithRecordColumns())
+	c := new(Cluster)
+	for _, v := range [][2]string{{"100000001", "30.00000"}, {"100000002", "30.01000"}} {
+		rec := Record{v[0], "2017-12-01T00:00:00", v[1], "-76.00000", "", "", "", "", "", "", "", "", "", "", "", ""}
+		c.Append(&rec)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+
+	cols := []string{"MMSI_2", "MMSI_1", "Distance(nm)", BearingField}
+	if err := inter.SetColumns(cols...); err != nil {
+		t.Fatalf("Interactions.SetColumns() error = %v", err)
+	}
+	if !reflect.DeepEqual(inter.OutputHeaders.Fields, cols) {
+		t.Errorf("OutputHeaders = %v, want %v", inter.OutputHeaders.Fields, cols)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "inter.csv")
+	if err := inter.Save(filename); err != nil {
+		t.Fatalf("Interactions.Save() error = %v", err)
+	}
+	f, _ := os.Open(filename)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(rows[1]) != 4 {
+		t.Fatalf("Interactions.Save() wrote %v, want a header and one row of 4 columns", rows)
+	}
+	row := rows[1]
+	want := map[string]string{"100000001": "0.0", "100000002": "180.0"}[row[1]]
+	if row[2] != "0.6" || row[3] != want {
+		t.Errorf("Interactions.Save() row = %v, want distance 0.6 and bearing %s", row, want)
+	}
+
+	// SetEncounter drops nothing from the chosen columns, and an unknown column is
+	// an error that leaves the default columns.
+	inter.SetEncounter(true)
+	if !reflect.DeepEqual(inter.OutputHeaders.Fields, cols) {
+		t.Errorf("OutputHeaders after SetEncounter = %v, want %v", inter.OutputHeaders.Fields, cols)
+	}
+	if err := inter.SetColumns("MMSI_1", "Nope"); err == nil {
+		t.Error("Interactions.SetColumns() expected error for unknown column")
+	}
+	if err := inter.SetColumns(); err != nil || len(inter.OutputHeaders.Fields) != 2+1+2*len(goodHeaders.Fields) {
+		t.Errorf("Interactions.SetColumns() did not restore the default columns, got %v", inter.OutputHeaders.Fields)
+	}
+}
+
+func TestInteractions_SetColumnsDropped(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	c := new(Cluster)
+	for _, v := range [][2]string{{"100000001", "30.00000"}, {"100000002", "30.01000"}} {
+		rec := Record{v[0], "2017-12-01T00:00:00", v[1], "-76.00000", "", "", "", "", "", "", "", "", "", "", "", ""}
+		c.Append(&rec)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+
+	// Turning CPA off drops the only chosen column, which leaves rows as empty
+	// as the header rather than writing the full row.
+	inter.SetCPA(true)
+	if err := inter.SetColumns("CPA(nm)"); err != nil {
+		t.Fatalf("Interactions.SetColumns() error = %v", err)
+	}
+	inter.SetCPA(false)
+	if n := len(inter.OutputHeaders.Fields); n != 0 {
+		t.Errorf("OutputHeaders = %v, want no columns", inter.OutputHeaders.Fields)
+	}
+	var buf bytes.Buffer
+	if err := inter.WriteCSV(&buf); err != nil {
+		t.Fatalf("Interactions.WriteCSV() error = %v", err)
+	}
+	r := csv.NewReader(&buf)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if len(row) > 1 || len(row) == 1 && row[0] != "" {
+			t.Errorf("Interactions.WriteCSV() wrote %q, want empty rows", row)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ColumnFloatFields are the fields a ColumnSet holds as float64 columns.  Every
+// other field except BaseDateTime is held as an interned string column.
+var ColumnFloatFields = []string{"LAT", "LON", "SOG", "COG", "Heading", "Length", "Width", "Draft"}
+
+// ColumnSet is a columnar, in-memory alternative to a RecordSet.  Each field is
+// a column typed by its name: the ColumnFloatFields are float64 slices,
+// BaseDateTime is an int64 slice of Unix seconds, and the other fields, such as
+// MMSI and VesselName, hold a code into a table of their distinct values, so a
+// value repeated in every report of a vessel is stored once.  A ColumnSet holds
+// several times less memory than the same Records, which keep every field as a
+// string, and whole columns can be filtered and measured at once with Mask,
+// Select, and DistancesTo.
+//
+// Float values that are empty or cannot be parsed are NaN, and all values are
+// formatted again when a Record is rebuilt, so 36.50000 becomes 36.5 and an
+// unparsable float becomes empty.
+type ColumnSet struct {
+	h         Headers
+	n         int
+	floats    [][]float64     // by field index, nil for other fields
+	strs      []*stringColumn // by field index, nil for other fields
+	timeIndex int             // index of BaseDateTime
+	times     []int64
+}
+
+// stringColumn is a column of interned strings.  Each row holds an index into
+// values, the distinct strings of the column in the order they first appear.
+type stringColumn struct {
+	codes  []uint32
+	values []string
+	lookup map[string]uint32 // nil once the column is built
+}
+
+func (c *stringColumn) add(s string) {
+	code, ok := c.lookup[s]
+	if !ok {
+		code = uint32(len(c.values))
+		c.values = append(c.values, s)
+		c.lookup[s] = code
+	}
+	c.codes = append(c.codes, code)
+}
+
+// Columns reads the RecordSet into a new ColumnSet with the same Headers.  The
+// Headers must contain BaseDateTime, which is parsed with their TimeParser.
+// Columns consumes the RecordSet.
+func (rs *RecordSet) Columns() (*ColumnSet, error) {
+	h := rs.Headers()
+	idx, err := h.require("BaseDateTime")
+	if err != nil {
+		return nil, fmt.Errorf("columns: %w", err)
+	}
+	cs := &ColumnSet{
+		h:         h,
+		floats:    make([][]float64, len(h.Fields)),
+		strs:      make([]*stringColumn, len(h.Fields)),
+		timeIndex: idx["BaseDateTime"].Idx,
+	}
+	isFloat := make([]bool, len(h.Fields))
+	for _, field := range ColumnFloatFields {
+		if i, ok := h.Contains(field); ok && i != cs.timeIndex {
+			isFloat[i] = true
+			cs.floats[i] = []float64{}
+		}
+	}
+	for i := range h.Fields {
+		if !isFloat[i] && i != cs.timeIndex {
+			cs.strs[i] = &stringColumn{lookup: make(map[string]uint32)}
+		}
+	}
+
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("columns: read error on csv file: %w", err)
+		}
+		if len(*rec) < len(h.Fields) {
+			return nil, fmt.Errorf("columns: record %d has %d fields, want %d", cs.n+1, len(*rec), len(h.Fields))
+		}
+		t, err := h.parseTime((*rec)[cs.timeIndex])
+		if err != nil {
+			return nil, fmt.Errorf("columns: %w", ErrParse{Line: cs.n + 2, Field: "BaseDateTime", Err: err})
+		}
+		cs.times = append(cs.times, t.Unix())
+		for i := range h.Fields {
+			switch {
+			case isFloat[i]:
+				v, ok := parseCoord((*rec)[i])
+				if !ok {
+					if v, err = strconv.ParseFloat((*rec)[i], 64); err != nil {
+						v = math.NaN()
+					}
+				}
+				cs.floats[i] = append(cs.floats[i], v)
+			case cs.strs[i] != nil:
+				cs.strs[i].add((*rec)[i])
+			}
+		}
+		cs.n++
+	}
+	for _, c := range cs.strs {
+		if c != nil {
+			c.lookup = nil
+		}
+	}
+	return cs, nil
+}
+
+// Headers returns the Headers of the ColumnSet.
+func (cs *ColumnSet) Headers() Headers { return cs.h }
+
+// Len returns the number of rows in the ColumnSet.
+func (cs *ColumnSet) Len() int { return cs.n }
+
+// Floats returns the column of a field in ColumnFloatFields.  The slice is the
+// column itself and must not be modified.
+func (cs *ColumnSet) Floats(field string) ([]float64, error) {
+	i, ok := cs.h.Contains(field)
+	if !ok {
+		return nil, ErrMissingHeader{Field: field}
+	}
+	if cs.floats[i] == nil {
+		return nil, fmt.Errorf("columns: %s is not a float column", field)
+	}
+	return cs.floats[i], nil
+}
+
+// Times returns the BaseDateTime column as Unix seconds.  The slice is the
+// column itself and must not be modified.
+func (cs *ColumnSet) Times() []int64 { return cs.times }
+
+// Strings returns the values of a string column, one per row.
+func (cs *ColumnSet) Strings(field string) ([]string, error) {
+	i, ok := cs.h.Contains(field)
+	if !ok {
+		return nil, ErrMissingHeader{Field: field}
+	}
+	c := cs.strs[i]
+	if c == nil {
+		return nil, fmt.Errorf("columns: %s is not a string column", field)
+	}
+	out := make([]string, cs.n)
+	for row, code := range c.codes {
+		out[row] = c.values[code]
+	}
+	return out, nil
+}
+
+// Record returns the i-th row as a new Record.
+func (cs *ColumnSet) Record(i int) Record {
+	rec := make(Record, len(cs.h.Fields))
+	for j := range rec {
+		switch {
+		case j == cs.timeIndex:
+			rec[j] = time.Unix(cs.times[i], 0).UTC().Format(TimeLayout)
+		case cs.floats[j] != nil:
+			if v := cs.floats[j][i]; !math.IsNaN(v) {
+				rec[j] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		default:
+			c := cs.strs[j]
+			rec[j] = c.values[c.codes[i]]
+		}
+	}
+	return rec
+}
+
+// RecordSet returns a new RecordSet holding a Record for every row of the
+// ColumnSet.  BaseDateTime is written in TimeLayout, so the Headers of the
+// RecordSet have no TimeParser.
+func (cs *ColumnSet) RecordSet() (*RecordSet, error) {
+	h := cs.h
+	h.Time = nil
+	rs := NewRecordSet()
+	rs.SetHeaders(h)
+	for i := 0; i < cs.n; i++ {
+		if err := rs.Write(cs.Record(i)); err != nil {
+			return nil, fmt.Errorf("columns: csv write error: %w", err)
+		}
+		if (i+1)%flushThreshold == 0 {
+			if err := rs.Flush(); err != nil {
+				return nil, fmt.Errorf("columns: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs.Flush(); err != nil {
+		return nil, fmt.Errorf("columns: csv flush error: %w", err)
+	}
+	return rs, nil
+}
+
+// Mask returns true for each row whose value of the float column field
+// satisfies keep, for use with Select.
+func (cs *ColumnSet) Mask(field string, keep func(float64) bool) ([]bool, error) {
+	col, err := cs.Floats(field)
+	if err != nil {
+		return nil, fmt.Errorf("columns mask: %w", err)
+	}
+	mask := make([]bool, len(col))
+	for i, v := range col {
+		mask[i] = keep(v)
+	}
+	return mask, nil
+}
+
+// Select returns a new ColumnSet with the rows whose mask value is true.  The
+// tables of distinct strings are shared with cs.
+func (cs *ColumnSet) Select(mask []bool) (*ColumnSet, error) {
+	if len(mask) != cs.n {
+		return nil, fmt.Errorf("columns select: mask of %d rows for %d rows", len(mask), cs.n)
+	}
+	n := 0
+	for _, keep := range mask {
+		if keep {
+			n++
+		}
+	}
+	out := &ColumnSet{
+		h:         cs.h,
+		n:         n,
+		floats:    make([][]float64, len(cs.floats)),
+		strs:      make([]*stringColumn, len(cs.strs)),
+		timeIndex: cs.timeIndex,
+		times:     make([]int64, 0, n),
+	}
+	for j := range cs.floats {
+		if cs.floats[j] != nil {
+			out.floats[j] = make([]float64, 0, n)
+		}
+		if cs.strs[j] != nil {
+			out.strs[j] = &stringColumn{codes: make([]uint32, 0, n), values: cs.strs[j].values}
+		}
+	}
+	for i, keep := range mask {
+		if !keep {
+			continue
+		}
+		out.times = append(out.times, cs.times[i])
+		for j := range cs.floats {
+			if cs.floats[j] != nil {
+				out.floats[j] = append(out.floats[j], cs.floats[j][i])
+			}
+			if cs.strs[j] != nil {
+				out.strs[j].codes = append(out.strs[j].codes, cs.strs[j].codes[i])
+			}
+		}
+	}
+	return out, nil
+}
+
+// DistancesTo appends to dst the Haversine distance in nautical miles from the
+// position lat, lon to the LAT and LON of every row, NaN for a row without a
+// position, and returns the extended slice.
+func (cs *ColumnSet) DistancesTo(lat, lon float64, dst []float64) ([]float64, error) {
+	lats, err := cs.Floats("LAT")
+	if err != nil {
+		return nil, fmt.Errorf("columns distances: %w", err)
+	}
+	lons, err := cs.Floats("LON")
+	if err != nil {
+		return nil, fmt.Errorf("columns distances: %w", err)
+	}
+	const rad = math.Pi / 180
+	cos0 := math.Cos(lat * rad)
+	lons = lons[:len(lats)] // hoist the bounds checks
+	for i := range lats {
+		dst = append(dst, haversineCos(lat, lon, cos0, lats[i], lons[i], math.Cos(lats[i]*rad)))
+	}
+	return dst, nil
+}
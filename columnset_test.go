@@ -0,0 +1,89 @@
+package ais
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const columnData = `MMSI,BaseDateTime,LAT,LON,SOG,VesselName
+111111111,2017-12-01T00:00:00,36.50000,-76.00000,10.0,ALPHA
+222222222,2017-12-01T00:00:10,37.00000,-76.00000,,BRAVO
+111111111,2017-12-01T00:01:00,36.51000,-76.00000,12.5,ALPHA
+`
+
+func TestRecordSet_Columns(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(columnData), Headers{})
+	cs, err := rs.Columns()
+	if err != nil {
+		t.Fatalf("RecordSet.Columns() error = %v", err)
+	}
+	if cs.Len() != 3 {
+		t.Fatalf("ColumnSet.Len() = %d, want 3", cs.Len())
+	}
+	sog, err := cs.Floats("SOG")
+	if err != nil || sog[0] != 10 || !math.IsNaN(sog[1]) || sog[2] != 12.5 {
+		t.Errorf("ColumnSet.Floats(SOG) = %v, %v", sog, err)
+	}
+	if times := cs.Times(); times[1]-times[0] != 10 {
+		t.Errorf("ColumnSet.Times() = %v", times)
+	}
+	names, err := cs.Strings("VesselName")
+	if err != nil || strings.Join(names, ",") != "ALPHA,BRAVO,ALPHA" {
+		t.Errorf("ColumnSet.Strings(VesselName) = %v, %v", names, err)
+	}
+	if c := cs.strs[0]; len(c.values) != 2 {
+		t.Errorf("MMSI column holds %d distinct values, want 2", len(c.values))
+	}
+	if _, err := cs.Floats("VesselName"); err == nil {
+		t.Errorf("ColumnSet.Floats(VesselName) error = nil, want an error")
+	}
+
+	rs2, err := cs.RecordSet()
+	if err != nil {
+		t.Fatalf("ColumnSet.RecordSet() error = %v", err)
+	}
+	defer rs2.Close()
+	var lines []string
+	for {
+		rec, err := rs2.Read()
+		if err != nil {
+			break
+		}
+		lines = append(lines, strings.Join(*rec, ","))
+	}
+	want := "111111111,2017-12-01T00:00:00,36.5,-76,10,ALPHA|" +
+		"222222222,2017-12-01T00:00:10,37,-76,,BRAVO|" +
+		"111111111,2017-12-01T00:01:00,36.51,-76,12.5,ALPHA"
+	if got := strings.Join(lines, "|"); got != want {
+		t.Errorf("ColumnSet.RecordSet() = %s, want %s", got, want)
+	}
+}
+
+func TestColumnSet_Select(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(columnData), Headers{})
+	cs, _ := rs.Columns()
+	d, err := cs.DistancesTo(36.5, -76, nil)
+	if err != nil {
+		t.Fatalf("ColumnSet.DistancesTo() error = %v", err)
+	}
+	for i, want := range []float64{0, 30.02, 0.6} {
+		if math.Abs(d[i]-want) > 0.01 {
+			t.Errorf("ColumnSet.DistancesTo()[%d] = %v, want %v", i, d[i], want)
+		}
+	}
+	mask, err := cs.Mask("SOG", func(sog float64) bool { return sog > 11 })
+	if err != nil {
+		t.Fatalf("ColumnSet.Mask() error = %v", err)
+	}
+	fast, err := cs.Select(mask)
+	if err != nil {
+		t.Fatalf("ColumnSet.Select() error = %v", err)
+	}
+	if fast.Len() != 1 || strings.Join(fast.Record(0), ",") != "111111111,2017-12-01T00:01:00,36.51,-76,12.5,ALPHA" {
+		t.Errorf("ColumnSet.Select() = %d rows, %v", fast.Len(), fast.Record(0))
+	}
+	if _, err := cs.Select(mask[:1]); err == nil {
+		t.Errorf("ColumnSet.Select() of a short mask error = nil, want an error")
+	}
+}
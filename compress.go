@@ -0,0 +1,125 @@
+package ais
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Magic numbers that identify the compressed formats read by OpenRecordSet.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// decompress returns a reader of the uncompressed contents of f when f is a gzip
+// file or a zip archive, identified by its magic number rather than its name.  For
+// a zip archive the first file ending in .csv is read, or the first file if none
+// does.  Closing the returned reader closes f.  For an uncompressed file
+// decompress returns nil and a nil error with f positioned at its start.
+func decompress(f *os.File) (io.ReadCloser, error) {
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	magic = magic[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		return &stackedReader{Reader: gz, closers: []io.Closer{gz, f}}, nil
+	case bytes.HasPrefix(magic, zipMagic):
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		zr, err := zip.NewReader(f, fi.Size())
+		if err != nil {
+			return nil, err
+		}
+		var entry *zip.File
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			if entry == nil {
+				entry = zf
+			}
+			if strings.EqualFold(filepath.Ext(zf.Name), ".csv") {
+				entry = zf
+				break
+			}
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("zip archive contains no files")
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		return &stackedReader{Reader: rc, closers: []io.Closer{rc, f}}, nil
+	}
+	return nil, nil
+}
+
+// stackedReader reads from the top of a stack of readers and closes all of them
+// in order.
+type stackedReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes each of the stacked readers and returns the first error.
+func (s *stackedReader) Close() error {
+	var first error
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// compressor returns a writer that compresses data written to f when name ends in
+// .gz or .zip.  A zip archive holds a single file named for name without the .zip
+// extension.  The returned writer must be closed to complete the compressed data,
+// which does not close f.  For any other name compressor returns nil.
+func compressor(f io.Writer, name string) (io.WriteCloser, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz":
+		return gzip.NewWriter(f), nil
+	case ".zip":
+		zw := zip.NewWriter(f)
+		entry := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		if filepath.Ext(entry) == "" {
+			entry += ".csv"
+		}
+		w, err := zw.Create(entry)
+		if err != nil {
+			return nil, err
+		}
+		return zipEntry{w, zw}, nil
+	}
+	return nil, nil
+}
+
+// zipEntry is the single file of a zip archive being written by Save.
+type zipEntry struct {
+	io.Writer
+	zw *zip.Writer
+}
+
+// Close completes the zip archive.
+func (z zipEntry) Close() error { return z.zw.Close() }
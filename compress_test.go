@@ -0,0 +1,130 @@
+package ais
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecordSet_SaveCompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		magic []byte
+	}{
+		{"ten.csv.gz", gzipMagic},
+		{"ten.zip", zipMagic},
+		{"ten.csv", []byte("MMSI")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.name)
+			rs, _ := OpenRecordSet("testdata/ten.csv")
+			if err := rs.Save(filename); err != nil {
+				t.Fatalf("RecordSet.Save() error = %v", err)
+			}
+			rs.Close()
+
+			b, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(b, tt.magic) {
+				t.Errorf("RecordSet.Save() wrote %q..., want prefix %q", b[:4], tt.magic)
+			}
+
+			got, err := OpenRecordSet(filename)
+			if err != nil {
+				t.Fatalf("OpenRecordSet() error = %v", err)
+			}
+			defer got.Close()
+			compareRecordSets(t, got, "testdata/ten.csv")
+		})
+	}
+}
+
+func TestOpenRecordSet_Zip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A MarineCadastre archive holds metadata next to the csv file.
+	csvData, err := ioutil.ReadFile("testdata/ten.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"AIS_ASCII_by_UTM_Month/", nil},
+		{"AIS_ASCII_by_UTM_Month/README.txt", []byte("metadata")},
+		{"AIS_ASCII_by_UTM_Month/AIS_2017_12_Zone18.csv", csvData},
+	} {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(entry.data)
+	}
+	zw.Close()
+	filename := filepath.Join(dir, "AIS_2017_12_Zone18.zip")
+	if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := OpenRecordSet(filename)
+	if err != nil {
+		t.Fatalf("OpenRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+	compareRecordSets(t, rs, "testdata/ten.csv")
+	rs.Write(Record(firstRec))
+	if err := rs.Flush(); err == nil {
+		t.Error("RecordSet.Flush() expected error for compressed RecordSet")
+	}
+}
+
+// compareRecordSets reports an error if rs does not hold the same Headers and
+// Records as the csv file filename.
+func compareRecordSets(t *testing.T, rs *RecordSet, filename string) {
+	t.Helper()
+	want, err := OpenRecordSet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Close()
+	if !rs.Headers().Equals(want.Headers()) {
+		t.Errorf("Headers = %v, want %v", rs.Headers(), want.Headers())
+	}
+	for n := 0; ; n++ {
+		wantRec, err := want.Read()
+		gotRec, gotErr := rs.Read()
+		if err == io.EOF {
+			if gotErr != io.EOF {
+				t.Errorf("record %d: Read() error = %v, want EOF", n, gotErr)
+			}
+			return
+		}
+		if gotErr != nil {
+			t.Fatalf("record %d: Read() error = %v", n, gotErr)
+		}
+		if !reflect.DeepEqual(gotRec, wantRec) {
+			t.Errorf("record %d = %v, want %v", n, *gotRec, *wantRec)
+		}
+	}
+}
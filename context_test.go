@@ -0,0 +1,80 @@
+package ais
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordSet_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		fn   func(rs *RecordSet) error
+	}{
+		{"SubsetContext", func(rs *RecordSet) error {
+			_, err := rs.SubsetContext(ctx, &Box{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180, LatIndex: 2, LonIndex: 3})
+			return err
+		}},
+		{"SortByTimeContext", func(rs *RecordSet) error {
+			_, err := rs.SortByTimeContext(ctx)
+			return err
+		}},
+		{"UniqueVesselsContext", func(rs *RecordSet) error {
+			_, err := rs.UniqueVesselsContext(ctx, false)
+			return err
+		}},
+		{"SaveContext", func(rs *RecordSet) error {
+			return rs.SaveContext(ctx, filepath.Join(dir, "canceled.csv"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := OpenRecordSet("testdata/ten.csv")
+			if err != nil {
+				t.Fatalf("OpenRecordSet() error = %v", err)
+			}
+			defer rs.Close()
+			if err := tt.fn(rs); !errors.Is(err, context.Canceled) {
+				t.Errorf("RecordSet.%s() error = %v, want context.Canceled", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestInteractions_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	clusters := testClusters(20, 5)
+
+	inter, _ := NewInteractions(goodHeaders)
+	if err := inter.AddClusterContext(ctx, clusters[0]); !errors.Is(err, context.Canceled) {
+		t.Errorf("Interactions.AddClusterContext() error = %v, want context.Canceled", err)
+	}
+	if err := inter.AddClustersParallelContext(ctx, clusters, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Interactions.AddClustersParallelContext() error = %v, want context.Canceled", err)
+	}
+
+	if err := inter.AddClustersParallel(clusters, 2); err != nil {
+		t.Fatalf("Interactions.AddClustersParallel() error = %v", err)
+	}
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := inter.SaveContext(ctx, filepath.Join(dir, "canceled.csv")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Interactions.SaveContext() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,128 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CPAFields are the optional column headers written by Interactions.Save when
+// closest point of approach computation is enabled with SetCPA.  CPA(nm) is the
+// distance between the two vessels at their closest point of approach and TCPA(min)
+// is the time in minutes until that closest approach occurs.
+const CPAFields = "CPA(nm),TCPA(min)"
+
+// CPA computes the closest point of approach distance in nautical miles and the
+// time to the closest point of approach between the two Records of the pair.  The
+// Headers must contain "BaseDateTime", "LAT", "LON", "SOG", and "COG".
+//
+// Both vessels are assumed to hold their reported SOG and COG.  When the two Records
+// were reported at different times the earlier Record is dead reckoned forward so
+// that tcpa is measured from the BaseDateTime of the later Record.  A negative tcpa
+// indicates that the vessels are opening and their closest approach has already
+// passed.  Positions are projected onto a local flat-earth plane, so results are
+// intended for the short ranges of two-vessel interactions.
+func (p *RecordPair) CPA(h Headers) (cpa float64, tcpa time.Duration, err error) {
+	_, _, rx, ry, vx, vy, err := p.relativeMotion(h)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cpa: %w", err)
+	}
+	cpa, hours := closestApproach(rx, ry, vx, vy)
+	return cpa, time.Duration(hours * float64(time.Hour)), nil
+}
+
+// relativeMotion returns the kinematics of both vessels along with the
+// position in nautical miles and velocity in knots of the second vessel relative
+// to the first.  The relative position is taken at the later of the two report
+// times after dead reckoning the earlier Record forward.
+func (p *RecordPair) relativeMotion(h Headers) (k1, k2 kinematics, rx, ry, vx, vy float64, err error) {
+	idx, err := h.require("BaseDateTime", "LAT", "LON", "SOG", "COG")
+	if err != nil {
+		return k1, k2, 0, 0, 0, 0, err
+	}
+	k1, err = newKinematics(p.rec1, h, idx)
+	if err != nil {
+		return k1, k2, 0, 0, 0, 0, err
+	}
+	k2, err = newKinematics(p.rec2, h, idx)
+	if err != nil {
+		return k1, k2, 0, 0, 0, 0, err
+	}
+
+	// Bring both vessels to the same instant.
+	t0 := k1.t
+	if k2.t.After(t0) {
+		t0 = k2.t
+	}
+	meanLat := (k1.lat + k2.lat) / 2
+	x1, y1 := k1.positionAt(t0, meanLat, k1.lat, k1.lon)
+	x2, y2 := k2.positionAt(t0, meanLat, k1.lat, k1.lon)
+
+	// Relative position and velocity of vessel 2 with respect to vessel 1.
+	return k1, k2, x2 - x1, y2 - y1, k2.vx - k1.vx, k2.vy - k1.vy, nil
+}
+
+// closestApproach returns the range at the closest point of approach and the
+// time in hours until it occurs for a relative position and velocity.
+func closestApproach(rx, ry, vx, vy float64) (cpa, hours float64) {
+	v2 := vx*vx + vy*vy
+	if v2 < 1e-9 { // no relative motion so the range never changes
+		return math.Hypot(rx, ry), 0
+	}
+	hours = -(rx*vx + ry*vy) / v2
+	return math.Hypot(rx+vx*hours, ry+vy*hours), hours
+}
+
+// kinematics holds the parsed position and velocity of a single Record.  The
+// velocity components are in knots with vx positive to the east and vy positive
+// to the north.
+type kinematics struct {
+	t        time.Time
+	lat, lon float64
+	cog      float64
+	vx, vy   float64
+}
+
+// newKinematics parses the fields required for relative motion computations.
+// SOG of 102.3 and COG of 360 are the AIS "not available" values and cause an
+// error.
+func newKinematics(rec *Record, h Headers, idx map[string]HeaderMap) (kinematics, error) {
+	var k kinematics
+	var err error
+	if k.t, err = h.parseTime((*rec)[idx["BaseDateTime"].Idx]); err != nil {
+		return k, ErrParse{Field: "BaseDateTime", Err: err}
+	}
+	if k.lat, err = rec.ParseFloat(idx["LAT"].Idx); err != nil {
+		return k, ErrParse{Field: "LAT", Err: err}
+	}
+	if k.lon, err = rec.ParseFloat(idx["LON"].Idx); err != nil {
+		return k, ErrParse{Field: "LON", Err: err}
+	}
+	sog, err := rec.ParseFloat(idx["SOG"].Idx)
+	if err != nil {
+		return k, ErrParse{Field: "SOG", Err: err}
+	}
+	cog, err := rec.ParseFloat(idx["COG"].Idx)
+	if err != nil {
+		return k, ErrParse{Field: "COG", Err: err}
+	}
+	if sog < 0 || sog >= 102.3 {
+		return k, fmt.Errorf("SOG not available")
+	}
+	if cog < 0 || cog >= 360 {
+		return k, fmt.Errorf("COG not available")
+	}
+	k.cog = cog
+	rad := cog * math.Pi / 180
+	k.vx, k.vy = sog*math.Sin(rad), sog*math.Cos(rad)
+	return k, nil
+}
+
+// positionAt returns the dead reckoned position of the vessel at time t in
+// nautical miles east and north of the origin (lat0, lon0).
+func (k kinematics) positionAt(t time.Time, meanLat, lat0, lon0 float64) (x, y float64) {
+	x = (k.lon - lon0) * 60 * math.Cos(meanLat*math.Pi/180)
+	y = (k.lat - lat0) * 60
+	hours := t.Sub(k.t).Hours()
+	return x + k.vx*hours, y + k.vy*hours
+}
@@ -0,0 +1,106 @@
+package ais
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var kinematicHeaders = Headers{Fields: strings.Split("MMSI,BaseDateTime,LAT,LON,SOG,COG", ",")}
+
+func TestRecordPair_CPA(t *testing.T) {
+	tests := []struct {
+		name     string
+		rec1     Record
+		rec2     Record
+		h        Headers
+		wantCPA  float64
+		wantTCPA time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "head on",
+			rec1:     Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2:     Record{"2", "2017-12-01T00:00:00", "0.1", "0.0", "10.0", "180.0"},
+			h:        kinematicHeaders,
+			wantCPA:  0,
+			wantTCPA: 18 * time.Minute,
+		},
+		{
+			name:     "crossing",
+			rec1:     Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "90.0"},
+			rec2:     Record{"2", "2017-12-01T00:00:00", "0.1", "0.0", "10.0", "180.0"},
+			h:        kinematicHeaders,
+			wantCPA:  3 * math.Sqrt2,
+			wantTCPA: 18 * time.Minute,
+		},
+		{
+			name:     "no relative motion",
+			rec1:     Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "45.0"},
+			rec2:     Record{"2", "2017-12-01T00:00:00", "0.1", "0.0", "10.0", "45.0"},
+			h:        kinematicHeaders,
+			wantCPA:  6,
+			wantTCPA: 0,
+		},
+		{
+			name:     "different report times",
+			rec1:     Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2:     Record{"2", "2017-12-01T00:06:00", "0.1", "0.0", "10.0", "180.0"},
+			h:        kinematicHeaders,
+			wantCPA:  0,
+			wantTCPA: 15 * time.Minute,
+		},
+		{
+			name:    "COG not available",
+			rec1:    Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "360.0"},
+			rec2:    Record{"2", "2017-12-01T00:00:00", "0.1", "0.0", "10.0", "180.0"},
+			h:       kinematicHeaders,
+			wantErr: true,
+		},
+		{
+			name:    "headers missing SOG",
+			rec1:    testRec0,
+			rec2:    testRec1,
+			h:       basicHeaders(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &RecordPair{rec1: &tt.rec1, rec2: &tt.rec2}
+			cpa, tcpa, err := p.CPA(tt.h)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecordPair.CPA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(cpa-tt.wantCPA) > 1e-3 {
+				t.Errorf("RecordPair.CPA() cpa = %v, want %v", cpa, tt.wantCPA)
+			}
+			if (tcpa - tt.wantTCPA).Round(time.Second) != 0 {
+				t.Errorf("RecordPair.CPA() tcpa = %v, want %v", tcpa, tt.wantTCPA)
+			}
+		})
+	}
+}
+
+func basicHeaders() Headers {
+	return Headers{Fields: strings.Split(basicHeadersString, ",")}
+}
+
+func TestInteractions_SetCPA(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	n := len(inter.OutputHeaders.Fields)
+
+	inter.SetCPA(true)
+	if got := len(inter.OutputHeaders.Fields); got != n+2 {
+		t.Errorf("Interactions.SetCPA(true) output fields = %d, want %d", got, n+2)
+	}
+	if i, ok := inter.OutputHeaders.Contains("CPA(nm)"); !ok || i != 2 {
+		t.Errorf("Interactions.SetCPA(true) CPA(nm) index = %d, %v, want 2, true", i, ok)
+	}
+
+	inter.SetCPA(false)
+	if got := len(inter.OutputHeaders.Fields); got != n {
+		t.Errorf("Interactions.SetCPA(false) output fields = %d, want %d", got, n)
+	}
+}
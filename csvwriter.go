@@ -0,0 +1,123 @@
+package ais
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// recordWriter writes Records as RFC 4180 csv, like an encoding/csv Writer,
+// with quoting that lets a RecordSet read back every field it writes.  An
+// encoding/csv Writer does not quote a first field that begins with the
+// comment character or a Record of one empty field, both of which a Reader
+// with Comment set skips.  Otherwise the output is the same, so files written
+// before are unchanged.  A \r\n inside a field is read back as \n, as it is by
+// every encoding/csv Reader.
+type recordWriter struct {
+	Comma   rune       // field delimiter, ',' by default
+	Comment rune       // comment character of the Reader of the output, '#' by default
+	Quote   QuoteStyle // which fields are quoted
+	w       *bufio.Writer
+}
+
+// QuoteStyle selects the fields of a Record that are quoted when it is
+// written.
+type QuoteStyle int
+
+const (
+	// QuoteMinimal quotes only the fields that must be quoted to be read back,
+	// as encoding/csv does.
+	QuoteMinimal QuoteStyle = iota
+
+	// QuoteAll quotes every field, as some agency import tools require.
+	QuoteAll
+)
+
+var quoteStyleNames = [...]string{
+	QuoteMinimal: "minimal",
+	QuoteAll:     "all",
+}
+
+// String implements the Stringer interface for QuoteStyle.
+func (q QuoteStyle) String() string {
+	if q < 0 || int(q) >= len(quoteStyleNames) {
+		return fmt.Sprintf("QuoteStyle(%d)", int(q))
+	}
+	return quoteStyleNames[q]
+}
+
+// newRecordWriter returns a recordWriter that writes to w.
+func newRecordWriter(w io.Writer) *recordWriter {
+	return &recordWriter{Comma: ',', Comment: '#', w: bufio.NewWriter(w)}
+}
+
+// Write writes a single Record to the buffer of w.
+func (w *recordWriter) Write(rec []string) error {
+	if len(rec) == 1 && rec[0] == "" && w.Quote == QuoteMinimal {
+		_, err := w.w.WriteString("\"\"\n")
+		return err
+	}
+	for i, field := range rec {
+		if i > 0 {
+			if _, err := w.w.WriteRune(w.Comma); err != nil {
+				return err
+			}
+		}
+		if !w.needsQuotes(field, i == 0) {
+			if _, err := w.w.WriteString(field); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.w.WriteByte('"'); err != nil {
+			return err
+		}
+		if _, err := w.w.WriteString(strings.Replace(field, `"`, `""`, -1)); err != nil {
+			return err
+		}
+		if err := w.w.WriteByte('"'); err != nil {
+			return err
+		}
+	}
+	return w.w.WriteByte('\n')
+}
+
+// needsQuotes reports whether field must be quoted, which is when an
+// encoding/csv Writer quotes it or when it is the first field of the Record and
+// begins with the comment character.
+func (w *recordWriter) needsQuotes(field string, first bool) bool {
+	if w.Quote == QuoteAll {
+		return true
+	}
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsRune(field, w.Comma) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r) || first && w.Comment != 0 && r == w.Comment
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *recordWriter) Flush() {
+	w.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write or Flush.
+func (w *recordWriter) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// validDelimiter returns an error if r cannot separate the fields of a csv
+// file read with '#' comments.
+func validDelimiter(r rune) error {
+	if r == 0 || r == '"' || r == '\r' || r == '\n' || r == '#' || !utf8.ValidRune(r) || r == utf8.RuneError {
+		return fmt.Errorf("invalid delimiter %q", r)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package ais
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTrip writes recs to a RecordSet with Headers h and the delimiter comma,
+// and returns the Records read back from its csv.
+func roundTrip(t *testing.T, h Headers, recs []Record, comma rune) []Record {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newRecordWriter(&buf)
+	w.Comma = comma
+	w.Write(h.Fields)
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := NewRecordSetFromReader(&buf, Headers{}, Delimiter(comma), LazyQuotes(false))
+	if err != nil {
+		t.Fatalf("NewRecordSetFromReader() error = %v for %q", err, buf.String())
+	}
+	if !reflect.DeepEqual(rs.Headers().Fields, h.Fields) {
+		t.Errorf("headers = %q, want %q", rs.Headers().Fields, h.Fields)
+	}
+	var got []Record
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, *rec)
+	}
+	return got
+}
+
+func TestRecordWriter_RoundTrip(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "VesselName", "CallSign"}}
+	recs := []Record{
+		{"477307901", "MAERSK, KENSINGTON", "VRPJ6"},
+		{"477307902", `THE "BIG" ONE`, `"`},
+		{"477307903", "TWO\nLINES", "A\rB"},
+		{"#477307904", "COMMENT", "#"},
+		{"477307905", " LEADING SPACE", "\t"},
+		{"477307906", "S\xf8REN \xff\xfe", "LATIN-1"},
+		{"477307907", "SEMI;COLON", "TAB\tBED"},
+		{"", "", ""},
+		{`\.`, "", "END"},
+	}
+	for _, comma := range []rune{',', ';', '\t', '|'} {
+		got := roundTrip(t, h, recs, comma)
+		if !reflect.DeepEqual(got, recs) {
+			t.Errorf("round trip with %q = %q, want %q", comma, got, recs)
+		}
+	}
+
+	// A Record of a single empty field is not an empty line.
+	one := Headers{Fields: []string{"VesselName"}}
+	recs = []Record{{""}, {"#"}, {"A"}}
+	if got := roundTrip(t, one, recs, ','); !reflect.DeepEqual(got, recs) {
+		t.Errorf("round trip of single fields = %q, want %q", got, recs)
+	}
+}
+
+// mergeSortRoundTrip writes recs to a file with Headers h, passes it through
+// MergeRecordSets, MergeRecordSets with MergeSortByTime, and SortExternal when h
+// contains BaseDateTime, and returns the Records read back from each.  The
+// merged files are written with SaveDelimiter(comma) and quote.
+func mergeSortRoundTrip(t *testing.T, h Headers, recs []Record, comma rune, quote QuoteStyle) map[string][]Record {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	w := newRecordWriter(&buf)
+	w.Write(h.Fields)
+	for _, rec := range recs {
+		w.Write(rec)
+	}
+	w.Flush()
+	in := filepath.Join(dir, "in.csv")
+	if err := ioutil.WriteFile(in, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	readAll := func(rs *RecordSet) []Record {
+		defer rs.Close()
+		var got []Record
+		for {
+			rec, err := rs.Read()
+			if err == io.EOF {
+				return got
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, *rec)
+		}
+	}
+
+	got := make(map[string][]Record)
+	merges := map[string][]MergeOption{"merge": nil}
+	if _, ok := h.Contains("BaseDateTime"); ok {
+		merges["merge sorted"] = []MergeOption{MergeSortByTime(dir)}
+	}
+	for name, opts := range merges {
+		out := filepath.Join(dir, "out.csv")
+		opts = append(opts, MergeSaveOptions(SaveDelimiter(comma), SaveQuoting(quote)))
+		if err := MergeRecordSets([]string{in}, out, opts...); err != nil {
+			t.Fatalf("MergeRecordSets() %s error = %v", name, err)
+		}
+		rs, err := OpenRecordSet(out, Delimiter(comma), LazyQuotes(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[name] = readAll(rs)
+	}
+	if _, ok := h.Contains("BaseDateTime"); ok {
+		rs, err := OpenRecordSet(in, LazyQuotes(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted, err := rs.SortExternal("BaseDateTime", dir)
+		rs.Close()
+		if err != nil {
+			t.Fatalf("RecordSet.SortExternal() error = %v", err)
+		}
+		got["sort external"] = readAll(sorted)
+	}
+	return got
+}
+
+func TestMergeSort_RoundTrip(t *testing.T) {
+	h := Headers{Fields: []string{"CallSign", "BaseDateTime", "VesselName"}}
+	recs := []Record{
+		{"#VRPJ6", "2017-12-01T00:00:01", "MAERSK, KENSINGTON"},
+		{"", "2017-12-01T00:00:02", `THE "BIG" ONE`},
+		{" A", "2017-12-01T00:00:03", "TWO\nLINES"},
+		{`\.`, "2017-12-01T00:00:04", "SEMI;COLON"},
+	}
+	for _, quote := range []QuoteStyle{QuoteMinimal, QuoteAll} {
+		for name, got := range mergeSortRoundTrip(t, h, recs, ';', quote) {
+			if !reflect.DeepEqual(got, recs) {
+				t.Errorf("%s round trip with %v = %q, want %q", name, quote, got, recs)
+			}
+		}
+	}
+
+	// A Record of a single empty field is not an empty line.
+	one := Headers{Fields: []string{"VesselName"}}
+	recs = []Record{{""}, {"#"}, {"A"}}
+	if got := mergeSortRoundTrip(t, one, recs, ',', QuoteMinimal)["merge"]; !reflect.DeepEqual(got, recs) {
+		t.Errorf("merge round trip of single fields = %q, want %q", got, recs)
+	}
+}
+
+func TestRecordWriter_Unchanged(t *testing.T) {
+	// Records that need no extra quoting are written as by encoding/csv.
+	var buf bytes.Buffer
+	w := newRecordWriter(&buf)
+	w.Write([]string{"477307901", "2017-12-01T00:00:01", "FIRST", "", `A"B`, "C,D"})
+	w.Flush()
+	if want := "477307901,2017-12-01T00:00:01,FIRST,,\"A\"\"B\",\"C,D\"\n"; buf.String() != want {
+		t.Errorf("recordWriter.Write() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRecord_DataQuoted(t *testing.T) {
+	if got, want := string(Record{"A,B", "C"}.Data()), "\"A,B\",C\n"; got != want {
+		t.Errorf("Record.Data() = %q, want %q", got, want)
+	}
+	if (Record{"A,B", "C"}).Hash() == (Record{"A", "B,C"}).Hash() {
+		t.Error("Record.Hash() is the same for different fields")
+	}
+}
+
+func TestDelimiter(t *testing.T) {
+	for _, r := range []rune{0, '"', '#', '\n', '\r', 0xFFFD} {
+		if _, err := NewRecordSetFromReader(strings.NewReader("MMSI\n"), Headers{}, Delimiter(r)); err == nil {
+			t.Errorf("Delimiter(%q) expected error", r)
+		}
+	}
+	rs, err := NewRecordSetFromReader(strings.NewReader("MMSI;LAT;LON\n477307901;31,90512;-76,32652\n"), Headers{}, Delimiter(';'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rs.Headers().Fields; len(got) != 3 {
+		t.Errorf("Delimiter(';') headers = %q", got)
+	}
+}
+
+func TestLazyQuotes(t *testing.T) {
+	data := "MMSI,VesselName\n477307901,THE \"BIG\" ONE\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if rec, err := rs.Read(); err != nil || (*rec)[1] != `THE "BIG" ONE` {
+		t.Errorf("lazy Read() = %q, %v", rec, err)
+	}
+	rs, _ = NewRecordSetFromReader(strings.NewReader(data), Headers{}, LazyQuotes(false))
+	if _, err := rs.Read(); err == nil {
+		t.Error("LazyQuotes(false) Read() expected error for a bare quote")
+	}
+}
+
+func TestNewRecordSetFromReader_ReadColumns(t *testing.T) {
+	rs, err := NewRecordSetFromReader(strings.NewReader(testString), Headers{}, ReadColumns("LAT", "MMSI"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := rs.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Record{"31.90512", "477307901"}); !reflect.DeepEqual(*rec, want) {
+		t.Errorf("ReadColumns Read() = %q, want %q", *rec, want)
+	}
+}
@@ -0,0 +1,112 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+)
+
+// dbscan labels of a spatialNode that is not a member of a cluster.
+const (
+	dbscanUnvisited = 0
+	dbscanNoise     = -1
+)
+
+// DBSCAN groups the Records of the SpatialIndex into Clusters by density.  A
+// Record with at least minPts Records, itself included, within eps nautical miles
+// is a core point, and each Cluster holds the core points reachable from one
+// another through chains of such neighborhoods along with the Records within eps
+// of them.  Records that belong to no Cluster are returned as noise.
+//
+// Unlike the geohash Clusters of FindClusters, a DBSCAN Cluster can have any
+// shape and is never split by a cell border, which suits hot spot and near miss
+// analysis of traffic concentrations.  The Clusters may be passed to
+// Interactions.AddCluster.  Clusters are returned in the order they are found,
+// which is fixed for a given SpatialIndex.
+func (idx *SpatialIndex) DBSCAN(eps float64, minPts int) (clusters []*Cluster, noise []*Record, err error) {
+	if eps <= 0 || math.IsNaN(eps) || math.IsInf(eps, 0) {
+		return nil, nil, fmt.Errorf("dbscan: eps must be positive, got %v", eps)
+	}
+	if minPts < 1 {
+		return nil, nil, fmt.Errorf("dbscan: minPts must be at least 1, got %d", minPts)
+	}
+
+	labels := make(map[*spatialNode]int, len(idx.nodes))
+	for i := range idx.nodes {
+		n := &idx.nodes[i]
+		if labels[n] != dbscanUnvisited {
+			continue
+		}
+		seeds := idx.neighbors(n, eps)
+		if len(seeds) < minPts {
+			labels[n] = dbscanNoise
+			continue
+		}
+
+		c := new(Cluster)
+		clusters = append(clusters, c)
+		id := len(clusters)
+		labels[n] = id
+		c.Append(n.rec)
+		for len(seeds) > 0 {
+			s := seeds[len(seeds)-1]
+			seeds = seeds[:len(seeds)-1]
+			switch labels[s] {
+			case dbscanNoise:
+				// a border point of this cluster
+				labels[s] = id
+				c.Append(s.rec)
+				continue
+			case dbscanUnvisited:
+				labels[s] = id
+				c.Append(s.rec)
+			default:
+				continue
+			}
+			if more := idx.neighbors(s, eps); len(more) >= minPts {
+				seeds = append(seeds, more...)
+			}
+		}
+	}
+
+	for i := range idx.nodes {
+		if labels[&idx.nodes[i]] == dbscanNoise {
+			noise = append(noise, idx.nodes[i].rec)
+		}
+	}
+	return clusters, noise, nil
+}
+
+// neighbors returns the nodes within nm nautical miles of n, including n.
+func (idx *SpatialIndex) neighbors(n *spatialNode, nm float64) []*spatialNode {
+	var nodes []*spatialNode
+	r := chordLength(nm) * 1.001
+	idx.search(idx.nodes, 0, func(m *spatialNode) float64 {
+		if Haversine(n.lat, n.lon, m.lat, m.lon) <= nm {
+			nodes = append(nodes, m)
+		}
+		return r
+	}, n.p)
+	return nodes
+}
+
+// DBSCAN reads the remaining Records in the RecordSet and groups them into
+// Clusters by density as described for SpatialIndex.DBSCAN.  The RecordSet
+// Headers must contain LAT and LON.  DBSCAN consumes the receiver.
+func (rs *RecordSet) DBSCAN(eps float64, minPts int) (clusters []*Cluster, noise []*Record, err error) {
+	idx, err := rs.SpatialIndex()
+	if err != nil {
+		return nil, nil, fmt.Errorf("dbscan: %w", err)
+	}
+	return idx.DBSCAN(eps, minPts)
+}
+
+// DBSCAN groups the Records currently in the Window into Clusters by density as
+// described for SpatialIndex.DBSCAN.  It is an alternative to FindClusters for
+// the WindowFunc passed to SlideWindow.
+func (win *Window) DBSCAN(latIndex, lonIndex int, eps float64, minPts int) (clusters []*Cluster, noise []*Record, err error) {
+	idx, err := win.SpatialIndex(latIndex, lonIndex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dbscan: %w", err)
+	}
+	return idx.DBSCAN(eps, minPts)
+}
@@ -0,0 +1,43 @@
+package ais
+
+import "testing"
+
+func TestSpatialIndex_DBSCAN(t *testing.T) {
+	// Two dense groups of vessels a degree apart and three isolated vessels.
+	recs := append(testPositions(50, 36.9, -76.1, 0.01), testPositions(30, 37.9, -76.1, 0.01)...)
+	for _, pos := range [][2]string{{"36.5", "-75.5"}, {"37.4", "-75.0"}, {"38.5", "-74.0"}} {
+		rec := Record{"200000000", pos[0], pos[1]}
+		recs = append(recs, &rec)
+	}
+	idx, err := NewSpatialIndex(recs, 1, 2)
+	if err != nil {
+		t.Fatalf("NewSpatialIndex() error = %v", err)
+	}
+
+	clusters, noise, err := idx.DBSCAN(0.5, 4)
+	if err != nil {
+		t.Fatalf("SpatialIndex.DBSCAN() error = %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("SpatialIndex.DBSCAN() returned %d clusters, want 2", len(clusters))
+	}
+	sizes := map[int]bool{clusters[0].Size(): true, clusters[1].Size(): true}
+	if !sizes[50] || !sizes[30] {
+		t.Errorf("SpatialIndex.DBSCAN() cluster sizes = %d and %d, want 50 and 30", clusters[0].Size(), clusters[1].Size())
+	}
+	if len(noise) != 3 {
+		t.Errorf("SpatialIndex.DBSCAN() returned %d noise records, want 3", len(noise))
+	}
+
+	// Every Record is noise when no neighborhood is dense enough.
+	if clusters, noise, _ := idx.DBSCAN(0.5, 100); len(clusters) != 0 || len(noise) != len(recs) {
+		t.Errorf("SpatialIndex.DBSCAN() = %d clusters and %d noise, want 0 and %d", len(clusters), len(noise), len(recs))
+	}
+
+	if _, _, err := idx.DBSCAN(0, 4); err == nil {
+		t.Error("SpatialIndex.DBSCAN() expected error for zero eps")
+	}
+	if _, _, err := idx.DBSCAN(0.5, 0); err == nil {
+		t.Error("SpatialIndex.DBSCAN() expected error for zero minPts")
+	}
+}
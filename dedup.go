@@ -0,0 +1,110 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Deduplicate returns a pointer to a new RecordSet without the duplicate position
+// reports that are common in archives merged from overlapping receivers.  Records
+// are duplicates when they have the same MMSI and BaseDateTime, and only the
+// first of each set of duplicates is kept.  The number of Records dropped is
+// returned along with the new RecordSet.  The Headers must contain MMSI and
+// BaseDateTime.  Deduplicate keeps a key for every unique report in memory and
+// consumes the receiver.
+func (rs *RecordSet) Deduplicate() (*RecordSet, int, error) {
+	return rs.deduplicate(math.NaN())
+}
+
+// DeduplicateWithin is Deduplicate with the additional requirement that the
+// positions of two Records with the same MMSI and BaseDateTime are no more than
+// nm nautical miles apart for them to be duplicates.  Records that share a
+// timestamp but report different positions, as happens with spoofed or
+// conflicting MMSIs, are kept.  Passing zero for nm keeps only exact positions.
+// The Headers must also contain LAT and LON.
+func (rs *RecordSet) DeduplicateWithin(nm float64) (*RecordSet, int, error) {
+	if nm < 0 || math.IsNaN(nm) {
+		return nil, 0, fmt.Errorf("deduplicate: distance must not be negative, got %v", nm)
+	}
+	return rs.deduplicate(nm)
+}
+
+// dedupKey identifies reports from the same vessel at the same time.
+type dedupKey struct {
+	mmsi, t string
+}
+
+// deduplicate implements Deduplicate when nm is NaN and DeduplicateWithin
+// otherwise.
+func (rs *RecordSet) deduplicate(nm float64) (*RecordSet, int, error) {
+	fields := []string{"MMSI", "BaseDateTime"}
+	usePosition := !math.IsNaN(nm)
+	if usePosition {
+		fields = append(fields, "LAT", "LON")
+	}
+	idx, err := rs.Headers().require(fields...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("deduplicate: %w", err)
+	}
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(rs.Headers())
+
+	// seen holds the positions already kept for each key.  Without position
+	// matching only the presence of the key matters.
+	seen := make(map[dedupKey][][2]float64)
+	dropped, written := 0, 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("deduplicate: read error on csv file: %w", err)
+		}
+		key := dedupKey{(*rec)[idx["MMSI"].Idx], (*rec)[idx["BaseDateTime"].Idx]}
+		kept, found := seen[key]
+
+		if !usePosition {
+			if found {
+				dropped++
+				continue
+			}
+			seen[key] = nil
+		} else {
+			lat, err := rec.ParseFloat(idx["LAT"].Idx)
+			if err != nil {
+				return nil, 0, fmt.Errorf("deduplicate: %w", ErrParse{Field: "LAT", Err: err})
+			}
+			lon, err := rec.ParseFloat(idx["LON"].Idx)
+			if err != nil {
+				return nil, 0, fmt.Errorf("deduplicate: %w", ErrParse{Field: "LON", Err: err})
+			}
+			dup := false
+			for _, p := range kept {
+				if (p[0] == lat && p[1] == lon) || Haversine(p[0], p[1], lat, lon) <= nm {
+					dup = true
+					break
+				}
+			}
+			if dup {
+				dropped++
+				continue
+			}
+			seen[key] = append(kept, [2]float64{lat, lon})
+		}
+
+		rs2.Write(*rec)
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, 0, fmt.Errorf("deduplicate: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, 0, fmt.Errorf("deduplicate: csv flush error: %w", err)
+	}
+	return rs2, dropped, nil
+}
@@ -0,0 +1,76 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+)
+
+const dedupData = `MMSI,BaseDateTime,LAT,LON
+100000000,2017-12-01T00:00:00,30.00000,-76.00000
+100000000,2017-12-01T00:00:00,30.00000,-76.00000
+100000000,2017-12-01T00:00:00,30.00010,-76.00000
+100000000,2017-12-01T00:00:00,31.00000,-76.00000
+100000000,2017-12-01T00:00:01,30.00000,-76.00000
+200000000,2017-12-01T00:00:00,30.00000,-76.00000
+`
+
+func TestRecordSet_Deduplicate(t *testing.T) {
+	tests := []struct {
+		name        string
+		dedup       func(rs *RecordSet) (*RecordSet, int, error)
+		wantLen     int
+		wantDropped int
+		wantErr     bool
+	}{
+		{
+			name:        "mmsi and time",
+			dedup:       (*RecordSet).Deduplicate,
+			wantLen:     3,
+			wantDropped: 3,
+		},
+		{
+			name:        "exact position",
+			dedup:       func(rs *RecordSet) (*RecordSet, int, error) { return rs.DeduplicateWithin(0) },
+			wantLen:     5,
+			wantDropped: 1,
+		},
+		{
+			name:        "within 0.01nm",
+			dedup:       func(rs *RecordSet) (*RecordSet, int, error) { return rs.DeduplicateWithin(0.01) },
+			wantLen:     4, // 30.0001 is 0.006nm from 30.0 but 31.0 is a conflicting report
+			wantDropped: 2,
+		},
+		{
+			name:    "negative distance",
+			dedup:   func(rs *RecordSet) (*RecordSet, int, error) { return rs.DeduplicateWithin(-1) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, _ := NewRecordSetFromReader(strings.NewReader(dedupData), Headers{})
+			got, dropped, err := tt.dedup(rs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecordSet.Deduplicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("RecordSet.Deduplicate() dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+			n := 0
+			for got.Next() {
+				n++
+			}
+			if n != tt.wantLen {
+				t.Errorf("RecordSet.Deduplicate() kept %d records, want %d", n, tt.wantLen)
+			}
+		})
+	}
+
+	rs, _ := NewRecordSetFromReader(strings.NewReader("MMSI,LAT\n1,2\n"), Headers{})
+	if _, _, err := rs.Deduplicate(); err == nil {
+		t.Error("RecordSet.Deduplicate() expected error for headers without BaseDateTime")
+	}
+}
@@ -0,0 +1,349 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/mmcloughlin/geohash"
+)
+
+// Density counts position reports in the cells of a regular latitude and
+// longitude grid or of a geohash grid in order to produce traffic density maps.
+// Create a Density with NewDensity or NewGeohashDensity, add positions with Add
+// or AddRecordSet, and export the counts with SaveCSV, SaveGeoJSON, or, for a
+// latitude and longitude grid, SaveGeoTIFF.
+type Density struct {
+	// latitude and longitude grid, row 0 is the southernmost row
+	minLat, minLon float64
+	cell           float64
+	rows, cols     int
+	grid           []uint64
+
+	// geohash grid
+	bits  uint
+	cells map[uint64]uint64
+
+	outside int // positions outside of a latitude and longitude grid
+}
+
+// NewDensity returns a Density with square cells of cellSize degrees that cover
+// the area of box.  The grid starts at the south west corner of box and is
+// extended to a whole number of cells to the north and east if necessary.  The
+// LatIndex and LonIndex of box are not used.
+func NewDensity(box Box, cellSize float64) (*Density, error) {
+	if cellSize <= 0 || math.IsNaN(cellSize) {
+		return nil, fmt.Errorf("new density: cell size must be greater than zero, got %v", cellSize)
+	}
+	if box.MaxLat <= box.MinLat || box.MaxLon <= box.MinLon {
+		return nil, fmt.Errorf("new density: box must have a positive area")
+	}
+	d := &Density{
+		minLat: box.MinLat,
+		minLon: box.MinLon,
+		cell:   cellSize,
+		rows:   int(math.Ceil((box.MaxLat - box.MinLat) / cellSize)),
+		cols:   int(math.Ceil((box.MaxLon - box.MinLon) / cellSize)),
+	}
+	if d.rows*d.cols > 1<<28 {
+		return nil, fmt.Errorf("new density: grid of %d by %d cells is too large", d.rows, d.cols)
+	}
+	d.grid = make([]uint64, d.rows*d.cols)
+	return d, nil
+}
+
+// NewGeohashDensity returns a Density whose cells are the integer geohashes of
+// the given number of bits, the same representation produced by Geohasher at 22
+// bits.  A geohash grid covers the whole globe and only stores occupied cells.
+func NewGeohashDensity(bits uint) (*Density, error) {
+	if bits < 1 || bits > 64 {
+		return nil, fmt.Errorf("new density: geohash bits must be between 1 and 64, got %d", bits)
+	}
+	return &Density{bits: bits, cells: make(map[uint64]uint64)}, nil
+}
+
+// Add counts a single position.  Positions outside of a latitude and longitude
+// grid are counted by Outside instead.
+func (d *Density) Add(lat, lon float64) {
+	if d.cells != nil {
+		d.cells[geohash.EncodeIntWithPrecision(lat, lon, d.bits)]++
+		return
+	}
+	r, c, ok := d.index(lat, lon)
+	if !ok {
+		d.outside++
+		return
+	}
+	d.grid[r*d.cols+c]++
+}
+
+// index returns the row and column of the cell holding the position.
+func (d *Density) index(lat, lon float64) (r, c int, ok bool) {
+	if lat < d.minLat || lon < d.minLon {
+		return 0, 0, false
+	}
+	r = int((lat - d.minLat) / d.cell)
+	c = int((lon - d.minLon) / d.cell)
+	if r >= d.rows || c >= d.cols {
+		return 0, 0, false
+	}
+	return r, c, true
+}
+
+// AddRecordSet counts the position of every Record in rs.  The Headers must
+// contain LAT and LON.  AddRecordSet consumes rs.
+func (d *Density) AddRecordSet(rs *RecordSet) error {
+	idx, err := rs.Headers().require("LAT", "LON")
+	if err != nil {
+		return fmt.Errorf("density: %w", err)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("density: read error on csv file: %w", err)
+		}
+		lat, err := rec.ParseFloat(idx["LAT"].Idx)
+		if err != nil {
+			return fmt.Errorf("density: %w", ErrParse{Field: "LAT", Err: err})
+		}
+		lon, err := rec.ParseFloat(idx["LON"].Idx)
+		if err != nil {
+			return fmt.Errorf("density: %w", ErrParse{Field: "LON", Err: err})
+		}
+		d.Add(lat, lon)
+	}
+	return nil
+}
+
+// Count returns the number of positions counted in the cell holding the
+// position.
+func (d *Density) Count(lat, lon float64) uint64 {
+	if d.cells != nil {
+		return d.cells[geohash.EncodeIntWithPrecision(lat, lon, d.bits)]
+	}
+	r, c, ok := d.index(lat, lon)
+	if !ok {
+		return 0
+	}
+	return d.grid[r*d.cols+c]
+}
+
+// Outside returns the number of positions added to a latitude and longitude
+// grid that fell outside of it.
+func (d *Density) Outside() int { return d.outside }
+
+// densityCell is an occupied cell of a Density.
+type densityCell struct {
+	hash                           uint64 // geohash grids only
+	minLat, maxLat, minLon, maxLon float64
+	count                          uint64
+}
+
+// occupied returns the cells with a non-zero count ordered from south west to
+// north east, or by geohash for a geohash grid.
+func (d *Density) occupied() []densityCell {
+	var cells []densityCell
+	if d.cells != nil {
+		for h, n := range d.cells {
+			b := geohash.BoundingBoxIntWithPrecision(h, d.bits)
+			cells = append(cells, densityCell{h, b.MinLat, b.MaxLat, b.MinLng, b.MaxLng, n})
+		}
+		sort.Slice(cells, func(i, j int) bool { return cells[i].hash < cells[j].hash })
+		return cells
+	}
+	for r := 0; r < d.rows; r++ {
+		for c := 0; c < d.cols; c++ {
+			if n := d.grid[r*d.cols+c]; n > 0 {
+				lat, lon := d.minLat+float64(r)*d.cell, d.minLon+float64(c)*d.cell
+				cells = append(cells, densityCell{0, lat, lat + d.cell, lon, lon + d.cell, n})
+			}
+		}
+	}
+	return cells
+}
+
+// SaveCSV writes the occupied cells to filename with the latitude and longitude
+// of the cell center and the count.  A geohash grid also writes the geohash of
+// each cell in the form produced by Geohasher.
+func (d *Density) SaveCSV(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("density save csv: %w", err)
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	if d.cells != nil {
+		w.Write([]string{"Geohash", "LAT", "LON", "Count"})
+	} else {
+		w.Write([]string{"LAT", "LON", "Count"})
+	}
+	for _, c := range d.occupied() {
+		row := []string{
+			strconv.FormatFloat((c.minLat+c.maxLat)/2, 'f', -1, 64),
+			strconv.FormatFloat((c.minLon+c.maxLon)/2, 'f', -1, 64),
+			strconv.FormatUint(c.count, 10),
+		}
+		if d.cells != nil {
+			row = append([]string{fmt.Sprintf("%#x", c.hash)}, row...)
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("density save csv: %w", err)
+	}
+	return nil
+}
+
+// SaveGeoJSON writes the occupied cells to filename as a GeoJSON
+// FeatureCollection of Polygon Features with a Count property.
+func (d *Density) SaveGeoJSON(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("density save geojson: %w", err)
+	}
+	defer out.Close()
+
+	gw, err := newGeoJSONWriter(out)
+	if err != nil {
+		return fmt.Errorf("density save geojson: %w", err)
+	}
+	for _, c := range d.occupied() {
+		ring := [][]float64{
+			{c.minLon, c.minLat}, {c.maxLon, c.minLat}, {c.maxLon, c.maxLat}, {c.minLon, c.maxLat}, {c.minLon, c.minLat},
+		}
+		props := map[string]string{"Count": strconv.FormatUint(c.count, 10)}
+		if d.cells != nil {
+			props["Geohash"] = fmt.Sprintf("%#x", c.hash)
+		}
+		err := gw.write(geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Polygon", Coordinates: [][][]float64{ring}},
+			Properties: props,
+		})
+		if err != nil {
+			return fmt.Errorf("density save geojson: %w", err)
+		}
+	}
+	if err := gw.close(); err != nil {
+		return fmt.Errorf("density save geojson: %w", err)
+	}
+	return nil
+}
+
+// TIFF tag and field type values used by SaveGeoTIFF.
+const (
+	tiffShort  = 3
+	tiffLong   = 4
+	tiffDouble = 12
+)
+
+// tiffEntry is a single IFD entry.  Values that do not fit in four bytes are
+// written after the IFD and referenced by offset.
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	value    uint32 // inline value or offset
+}
+
+// SaveGeoTIFF writes a latitude and longitude grid to filename as a single band
+// GeoTIFF of unsigned 32 bit counts in WGS 84 (EPSG:4326) that can be opened
+// directly in GIS tools.  Counts larger than the maximum uint32 are clipped.
+// SaveGeoTIFF returns an error for a geohash grid, whose cells are not a raster.
+func (d *Density) SaveGeoTIFF(filename string) error {
+	if d.cells != nil {
+		return fmt.Errorf("density save geotiff: geohash grids cannot be written as a raster")
+	}
+	const nEntries = 14
+	const ifdOffset = 8
+	scaleOffset := uint32(ifdOffset + 2 + nEntries*12 + 4)
+	tieOffset := scaleOffset + 3*8
+	keysOffset := tieOffset + 6*8
+	geoKeys := []uint16{
+		1, 1, 0, 3, // GeoKeyDirectory version 1.1.0 with 3 keys
+		1024, 0, 1, 2, // GTModelTypeGeoKey = ModelTypeGeographic
+		1025, 0, 1, 1, // GTRasterTypeGeoKey = RasterPixelIsArea
+		2048, 0, 1, 4326, // GeographicTypeGeoKey = WGS 84
+	}
+	dataOffset := keysOffset + uint32(2*len(geoKeys))
+	dataBytes := uint32(4 * d.rows * d.cols)
+
+	entries := []tiffEntry{
+		{256, tiffLong, 1, uint32(d.cols)},                   // ImageWidth
+		{257, tiffLong, 1, uint32(d.rows)},                   // ImageLength
+		{258, tiffShort, 1, 32},                              // BitsPerSample
+		{259, tiffShort, 1, 1},                               // Compression = none
+		{262, tiffShort, 1, 1},                               // PhotometricInterpretation = BlackIsZero
+		{273, tiffLong, 1, dataOffset},                       // StripOffsets
+		{277, tiffShort, 1, 1},                               // SamplesPerPixel
+		{278, tiffLong, 1, uint32(d.rows)},                   // RowsPerStrip
+		{279, tiffLong, 1, dataBytes},                        // StripByteCounts
+		{284, tiffShort, 1, 1},                               // PlanarConfiguration = chunky
+		{339, tiffShort, 1, 1},                               // SampleFormat = unsigned integer
+		{33550, tiffDouble, 3, scaleOffset},                  // ModelPixelScaleTag
+		{33922, tiffDouble, 6, tieOffset},                    // ModelTiepointTag
+		{34735, tiffShort, uint32(len(geoKeys)), keysOffset}, // GeoKeyDirectoryTag
+	}
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("density save geotiff: %w", err)
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	le := binary.LittleEndian
+	put := func(v interface{}) {
+		if err == nil {
+			err = binary.Write(w, le, v)
+		}
+	}
+
+	put([]byte("II"))
+	put(uint16(42))
+	put(uint32(ifdOffset))
+	put(uint16(len(entries)))
+	for _, e := range entries {
+		put(e.tag)
+		put(e.typ)
+		put(e.count)
+		if e.typ == tiffShort && e.count == 1 {
+			put([2]uint16{uint16(e.value), 0}) // left justified in the value field
+		} else {
+			put(e.value)
+		}
+	}
+	put(uint32(0)) // no further IFDs
+	put([3]float64{d.cell, d.cell, 0})
+	north := d.minLat + float64(d.rows)*d.cell
+	put([6]float64{0, 0, 0, d.minLon, north, 0}) // raster origin is the north west corner
+	put(geoKeys)
+
+	row := make([]uint32, d.cols)
+	for r := d.rows - 1; r >= 0; r-- { // north to south
+		for c := range row {
+			n := d.grid[r*d.cols+c]
+			if n > math.MaxUint32 {
+				n = math.MaxUint32
+			}
+			row[c] = uint32(n)
+		}
+		put(row)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("density save geotiff: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package ais
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const densityData = `MMSI,BaseDateTime,LAT,LON
+100000000,2017-12-01T00:00:00,30.02,-75.95
+100000000,2017-12-01T00:01:00,30.03,-75.96
+100000000,2017-12-01T00:02:00,30.15,-75.95
+200000000,2017-12-01T00:00:00,30.95,-75.05
+200000000,2017-12-01T00:00:00,35.00,-75.05
+`
+
+func TestDensity(t *testing.T) {
+	d, err := NewDensity(Box{MinLat: 30, MaxLat: 31, MinLon: -76, MaxLon: -75}, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Add(29.0, -76.0) // outside
+	rs, _ := NewRecordSetFromReader(strings.NewReader(densityData), Headers{})
+	if err := d.AddRecordSet(rs); err != nil {
+		t.Fatalf("Density.AddRecordSet() error = %v", err)
+	}
+	counts := []struct {
+		lat, lon float64
+		want     uint64
+	}{
+		{30.01, -75.99, 2},
+		{30.11, -75.99, 1},
+		{30.99, -75.01, 1},
+		{30.50, -75.50, 0},
+	}
+	for _, c := range counts {
+		if got := d.Count(c.lat, c.lon); got != c.want {
+			t.Errorf("Density.Count(%v, %v) = %d, want %d", c.lat, c.lon, got, c.want)
+		}
+	}
+	if d.Outside() != 2 {
+		t.Errorf("Density.Outside() = %d, want 2", d.Outside())
+	}
+
+	dir, err := ioutil.TempDir("", "ais-density")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	csvFile := filepath.Join(dir, "density.csv")
+	if err := d.SaveCSV(csvFile); err != nil {
+		t.Fatalf("Density.SaveCSV() error = %v", err)
+	}
+	b, _ := ioutil.ReadFile(csvFile)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 4 || lines[0] != "LAT,LON,Count" || lines[1] != "30.05,-75.95,2" {
+		t.Errorf("Density.SaveCSV() wrote\n%s", b)
+	}
+
+	jsonFile := filepath.Join(dir, "density.geojson")
+	if err := d.SaveGeoJSON(jsonFile); err != nil {
+		t.Fatalf("Density.SaveGeoJSON() error = %v", err)
+	}
+	var fc struct {
+		Features []geoJSONFeature `json:"features"`
+	}
+	b, _ = ioutil.ReadFile(jsonFile)
+	if err := json.Unmarshal(b, &fc); err != nil {
+		t.Fatalf("Density.SaveGeoJSON() wrote invalid json: %v", err)
+	}
+	if len(fc.Features) != 3 || fc.Features[0].Properties["Count"] != "2" {
+		t.Errorf("Density.SaveGeoJSON() features = %+v", fc.Features)
+	}
+
+	tiffFile := filepath.Join(dir, "density.tif")
+	if err := d.SaveGeoTIFF(tiffFile); err != nil {
+		t.Fatalf("Density.SaveGeoTIFF() error = %v", err)
+	}
+	b, _ = ioutil.ReadFile(tiffFile)
+	if string(b[:2]) != "II" || binary.LittleEndian.Uint16(b[2:]) != 42 {
+		t.Fatalf("Density.SaveGeoTIFF() wrote bad header % x", b[:4])
+	}
+	// The first row of the raster is the north edge, so the last row holds the
+	// south west cell.
+	last := b[len(b)-4*10:]
+	if binary.LittleEndian.Uint32(last) != 2 {
+		t.Errorf("Density.SaveGeoTIFF() south west cell = %d, want 2", binary.LittleEndian.Uint32(last))
+	}
+	first := b[len(b)-4*100:]
+	if binary.LittleEndian.Uint32(first[4*9:]) != 1 {
+		t.Errorf("Density.SaveGeoTIFF() north east cell = %d, want 1", binary.LittleEndian.Uint32(first[4*9:]))
+	}
+}
+
+func TestGeohashDensity(t *testing.T) {
+	d, err := NewGeohashDensity(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, _ := NewRecordSetFromReader(strings.NewReader(densityData), Headers{})
+	if err := d.AddRecordSet(rs); err != nil {
+		t.Fatalf("Density.AddRecordSet() error = %v", err)
+	}
+	if got := d.Count(30.02, -75.95); got != 2 {
+		t.Errorf("Density.Count() = %d, want 2", got)
+	}
+	if len(d.occupied()) != 4 {
+		t.Errorf("Density.occupied() = %d cells, want 4", len(d.occupied()))
+	}
+	if err := d.SaveGeoTIFF(filepath.Join(os.TempDir(), "never.tif")); err == nil {
+		t.Error("Density.SaveGeoTIFF() expected error for a geohash grid")
+	}
+	if _, err := NewGeohashDensity(0); err == nil {
+		t.Error("NewGeohashDensity(0) expected error")
+	}
+	if _, err := NewDensity(Box{MinLat: 1, MaxLat: 0}, 1); err == nil {
+		t.Error("NewDensity() expected error for an empty box")
+	}
+}
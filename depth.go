@@ -0,0 +1,242 @@
+package ais
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DepthField and UKCField are the fields appended by JoinDepth, the water depth
+// in meters at the position of a Record and its under-keel clearance, the depth
+// less the Draft of the vessel.
+const (
+	DepthField = "Depth"
+	UKCField   = "UKC"
+)
+
+// Bathymetry supplies the water depth in meters, positive below sea level, at a
+// position, or NaN where it is not known.  A Grid with a Depth field, such as
+// one read by OpenRaster, is a Bathymetry.
+type Bathymetry interface {
+	Depth(lat, lon float64) float64
+}
+
+// BathymetryFunc is a function that implements the Bathymetry interface.
+type BathymetryFunc func(lat, lon float64) float64
+
+// Depth calls f(lat, lon).
+func (f BathymetryFunc) Depth(lat, lon float64) float64 { return f(lat, lon) }
+
+// Depth implements the Bathymetry interface for a Grid whose Fields include
+// Depth, or that has a single field.  Depths are interpolated as Values are, at
+// the first time of a Grid that has times.  It returns NaN for a Grid without a
+// depth field.
+func (g *Grid) Depth(lat, lon float64) float64 {
+	field := -1
+	for i, f := range g.fields {
+		if strings.EqualFold(f, DepthField) {
+			field = i
+			break
+		}
+	}
+	if field < 0 && len(g.fields) == 1 {
+		field = 0
+	}
+	if field < 0 {
+		return math.NaN()
+	}
+	vals, _ := g.Values(g.start, lat, lon)
+	return vals[field]
+}
+
+// OpenRaster reads a Grid with a single Depth field from an ESRI ASCII raster,
+// the .asc format in which GEBCO, NOAA, and most GIS tools export gridded
+// bathymetry.  Each value is placed at the center of its cell and cells with
+// the NODATA_value are not known.  When elevation is set the raster holds
+// elevations, negative below sea level as in GEBCO, which are negated into
+// depths.
+func OpenRaster(filename string, elevation bool) (*Grid, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open raster: %w", err)
+	}
+	defer f.Close()
+	g, err := readRaster(f, elevation)
+	if err != nil {
+		return nil, fmt.Errorf("open raster: %s: %w", filename, err)
+	}
+	return g, nil
+}
+
+func readRaster(in io.Reader, elevation bool) (*Grid, error) {
+	s := bufio.NewScanner(in)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	s.Split(bufio.ScanWords)
+	next := func() (string, bool) {
+		if !s.Scan() {
+			return "", false
+		}
+		return s.Text(), true
+	}
+
+	// The header is a keyword and value per line, ncols through cellsize in
+	// any order and an optional NODATA_value.
+	header := make(map[string]float64)
+	var first string
+	for {
+		key, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("header: %v", io.ErrUnexpectedEOF)
+		}
+		if _, err := strconv.ParseFloat(key, 64); err == nil {
+			first = key // the first value of the data
+			break
+		}
+		val, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("header: %v", io.ErrUnexpectedEOF)
+		}
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, ErrParse{Field: key, Err: err}
+		}
+		header[strings.ToLower(key)] = v
+	}
+	for _, key := range []string{"ncols", "nrows", "cellsize"} {
+		if _, ok := header[key]; !ok {
+			return nil, ErrMissingHeader{Field: key}
+		}
+	}
+	ncols, nrows, cell := int(header["ncols"]), int(header["nrows"]), header["cellsize"]
+	if ncols < 1 || nrows < 1 || cell <= 0 {
+		return nil, fmt.Errorf("invalid size %d by %d with cell size %v", ncols, nrows, cell)
+	}
+	x0, xok := header["xllcenter"]
+	if !xok {
+		x, ok := header["xllcorner"]
+		if !ok {
+			return nil, ErrMissingHeader{Field: "xllcorner"}
+		}
+		x0 = x + cell/2
+	}
+	y0, yok := header["yllcenter"]
+	if !yok {
+		y, ok := header["yllcorner"]
+		if !ok {
+			return nil, ErrMissingHeader{Field: "yllcorner"}
+		}
+		y0 = y + cell/2
+	}
+	nodata, hasNoData := header["nodata_value"]
+
+	g := &Grid{fields: []string{DepthField}, times: []float64{0}, nlat: nrows, nlon: ncols, nvar: 1}
+	g.lats, g.lons = make([]float64, nrows), make([]float64, ncols)
+	for i := range g.lats {
+		g.lats[i] = y0 + float64(i)*cell
+	}
+	for j := range g.lons {
+		g.lons[j] = x0 + float64(j)*cell
+	}
+	g.wrap = g.lons[ncols-1] > 180
+	g.data = make([]float64, nrows*ncols)
+	for n := range g.data {
+		val := first
+		if n > 0 {
+			var ok bool
+			if val, ok = next(); !ok {
+				return nil, fmt.Errorf("%d values for %d cells", n, len(g.data))
+			}
+		}
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, ErrParse{Line: n/ncols + 1, Field: "value", Err: err}
+		}
+		if hasNoData && v == nodata {
+			v = math.NaN()
+		} else if elevation {
+			v = -v
+		}
+		// Rows run from north to south and the grid from south to north.
+		row, col := nrows-1-n/ncols, n%ncols
+		g.data[g.offset(0, row, col)] = v
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// JoinDepth returns a pointer to a new RecordSet with the DepthField appended
+// to the Headers of rs and the Depth of b at the LAT and LON of each Record
+// appended to it, in meters to one decimal place.  When the Headers contain
+// Draft the UKCField is appended as well, the under-keel clearance of the
+// Depth less the Draft, for grounding risk studies.  A value that is not known,
+// including the UKC of a Draft of zero, which AIS uses for not available, is
+// left empty.  JoinDepth consumes rs.
+func (rs *RecordSet) JoinDepth(b Bathymetry) (*RecordSet, error) {
+	h := rs.Headers()
+	if _, err := h.require("LAT", "LON"); err != nil {
+		return nil, fmt.Errorf("join depth: %w", err)
+	}
+	draftIndex, haveDraft := h.Contains("Draft")
+	fields := []string{DepthField}
+	if haveDraft {
+		fields = append(fields, UKCField)
+	}
+	for _, field := range fields {
+		if _, dup := h.Contains(field); dup {
+			return nil, fmt.Errorf("join depth: headers already contain %s", field)
+		}
+	}
+	h2 := h
+	h2.Fields = append(append([]string{}, h.Fields...), fields...)
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(h2)
+
+	format := func(v float64) string {
+		if math.IsNaN(v) {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	written := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("join depth: read error on csv file: %w", err)
+		}
+		lat, lon, err := rec.LatLon(h)
+		if err != nil {
+			return nil, fmt.Errorf("join depth: %w", err)
+		}
+		depth := b.Depth(lat, lon)
+		*rec = append(*rec, format(depth))
+		if haveDraft {
+			ukc := math.NaN()
+			if draft, err := rec.ParseFloat(draftIndex); err == nil && draft > 0 {
+				ukc = depth - draft
+			}
+			*rec = append(*rec, format(ukc))
+		}
+		if err := rs2.Write(*rec); err != nil {
+			return nil, fmt.Errorf("join depth: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, fmt.Errorf("join depth: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("join depth: csv flush error: %w", err)
+	}
+	return rs2, nil
+}
@@ -0,0 +1,120 @@
+package ais
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRaster is a GEBCO style elevation raster of one degree cells, north row
+// first, with a land cell in the northeast corner.
+const testRaster = `ncols 3
+nrows 2
+xllcorner -78
+yllcorner 36
+cellsize 1
+NODATA_value -9999
+-10 -20 -9999
+-30 -40 -50
+`
+
+func TestOpenRaster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "depth.asc")
+	if err := ioutil.WriteFile(filename, []byte(testRaster), 0666); err != nil {
+		t.Fatal(err)
+	}
+	g, err := OpenRaster(filename, true)
+	if err != nil {
+		t.Fatalf("OpenRaster() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     float64
+	}{
+		{"southwest center", 36.5, -77.5, 30},
+		{"northwest center", 37.5, -77.5, 10},
+		{"between the south cells", 36.5, -77, 35},
+		{"nodata cell", 37.5, -75.5, math.NaN()},
+		{"beside nodata", 37.5, -76.0, 20},
+		{"outside", 45, -77.5, math.NaN()},
+	}
+	for _, tt := range tests {
+		got := g.Depth(tt.lat, tt.lon)
+		if math.IsNaN(got) != math.IsNaN(tt.want) || !math.IsNaN(tt.want) && math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Depth(%v, %v) = %v, want %v", tt.name, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"no cellsize": "ncols 1\nnrows 1\nxllcorner 0\nyllcorner 0\n5\n",
+		"short":       "ncols 2\nnrows 2\nxllcorner 0\nyllcorner 0\ncellsize 1\n1 2 3\n",
+		"bad value":   "ncols 1\nnrows 1\nxllcorner 0\nyllcorner 0\ncellsize 1\ndeep\n",
+	} {
+		if _, err := readRaster(strings.NewReader(data), false); err == nil {
+			t.Errorf("%s: readRaster() error = nil, want an error", name)
+		}
+	}
+}
+
+func TestGrid_Depth(t *testing.T) {
+	g, err := readGrid(strings.NewReader("lat,lon,depth\n36,-76,10\n36,-75,20\n"))
+	if err != nil {
+		t.Fatalf("readGrid() error = %v", err)
+	}
+	if got := g.Depth(36, -75.5); got != 15 {
+		t.Errorf("Grid.Depth() = %v, want 15", got)
+	}
+	g, _ = readGrid(strings.NewReader("lat,lon,WindSpeed,WaveHeight\n36,-76,10,1\n"))
+	if got := g.Depth(36, -76); !math.IsNaN(got) {
+		t.Errorf("Grid.Depth() without a depth field = %v, want NaN", got)
+	}
+}
+
+func TestRecordSet_JoinDepth(t *testing.T) {
+	flat := BathymetryFunc(func(lat, lon float64) float64 {
+		if lat > 40 {
+			return math.NaN()
+		}
+		return 12
+	})
+	data := "MMSI,LAT,LON,Draft\n" +
+		"111111111,36.9,-76.1,10.5\n" +
+		"222222222,36.9,-76.1,0\n" +
+		"333333333,45.0,-76.1,5\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs2, err := rs.JoinDepth(flat)
+	if err != nil {
+		t.Fatalf("RecordSet.JoinDepth() error = %v", err)
+	}
+	defer rs2.Close()
+	if got := strings.Join(rs2.Headers().Fields, ","); got != "MMSI,LAT,LON,Draft,Depth,UKC" {
+		t.Errorf("Headers = %s", got)
+	}
+	for _, want := range []string{"12.0,1.5", "12.0,", ","} {
+		rec, err := rs2.Read()
+		if err != nil {
+			t.Fatalf("RecordSet.Read() error = %v", err)
+		}
+		if got := strings.Join((*rec)[4:], ","); got != want {
+			t.Errorf("Depth,UKC = %q, want %q", got, want)
+		}
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,LAT,LON\n1,36,-76\n"), Headers{})
+	rs2, err = rs.JoinDepth(flat)
+	if err != nil {
+		t.Fatalf("RecordSet.JoinDepth() error = %v", err)
+	}
+	if got := strings.Join(rs2.Headers().Fields, ","); got != "MMSI,LAT,LON,Depth" {
+		t.Errorf("Headers without Draft = %s, want no UKC", got)
+	}
+}
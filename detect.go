@@ -0,0 +1,135 @@
+package ais
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mmcloughlin/geohash"
+)
+
+// Defaults for the zero values of InteractionParams.
+const (
+	DefaultInteractionWindow    = 10 * time.Minute
+	DefaultInteractionPrecision = 22
+)
+
+// InteractionParams configures RecordSet.FindInteractions.
+type InteractionParams struct {
+	// Window is the width of the time window in which vessels are compared,
+	// DefaultInteractionWindow when zero.
+	Window time.Duration
+
+	// Slide is the step between windows, half of Window when zero.
+	Slide time.Duration
+
+	// Precision is the number of bits of the geohash that groups vessels in a
+	// window, DefaultInteractionPrecision when zero, which gives cells of about
+	// .1 degree as Geohasher does.
+	Precision uint
+
+	// Sorted skips sorting the RecordSet by BaseDateTime when it is already
+	// sorted, which avoids loading it into memory.
+	Sorted bool
+
+	// Workers is the number of goroutines used to add the clusters of each
+	// window, as for AddClustersParallel.
+	Workers int
+
+	// Strategy selects the geohash cells compared in each window.  SingleCell
+	// uses Window.FindClusters and EightNeighbors uses FindClustersNeighbors,
+	// which also pairs vessels on either side of the border of a cell.  When the
+	// Headers already contain Geohash its values are taken to have
+	// DefaultInteractionPrecision bits, the precision of a Geohasher.
+	Strategy NeighborStrategy
+}
+
+// geohashGenerator implements Generator with a geohash of a chosen precision.
+type geohashGenerator struct {
+	bits uint
+}
+
+func (g geohashGenerator) Generate(rec Record, index ...int) (Field, error) {
+	lat, err := rec.ParseFloat(index[0])
+	if err != nil {
+		return "", fmt.Errorf("geohash: %w", ErrParse{Field: "LAT", Err: err})
+	}
+	lon, err := rec.ParseFloat(index[1])
+	if err != nil {
+		return "", fmt.Errorf("geohash: %w", ErrParse{Field: "LON", Err: err})
+	}
+	return Field(fmt.Sprintf("%#x", geohash.EncodeIntWithPrecision(lat, lon, g.bits))), nil
+}
+
+// FindInteractions runs the complete two vessel interaction pipeline described
+// in the package documentation: a Geohash field is appended to every Record, the
+// Records are sorted by time unless p.Sorted is set, a Window is slid through the
+// set, and the vessels that share a geohash in each window are added to a new
+// set of Interactions created with WithRecordColumns and opts.  When the Headers
+// already contain Geohash, for example after AppendField with a Geohasher, the
+// existing values are used and p.Precision must be zero.  FindInteractions
+// consumes the receiver.
+func (rs *RecordSet) FindInteractions(ctx context.Context, p InteractionParams, opts ...InteractionOption) (*Interactions, error) {
+	if p.Window < 0 || p.Slide < 0 || p.Precision > 64 || (p.Strategy != SingleCell && p.Strategy != EightNeighbors) {
+		return nil, fmt.Errorf("find interactions: invalid parameters %+v", p)
+	}
+	if p.Window == 0 {
+		p.Window = DefaultInteractionWindow
+	}
+	if p.Slide == 0 {
+		p.Slide = p.Window / 2
+	}
+
+	bits := uint(DefaultInteractionPrecision)
+	if _, ok := rs.Headers().Contains("Geohash"); ok {
+		if p.Precision != 0 {
+			return nil, fmt.Errorf("find interactions: headers already contain Geohash, precision must be zero")
+		}
+	} else {
+		if p.Precision == 0 {
+			p.Precision = DefaultInteractionPrecision
+		}
+		bits = p.Precision
+		var err error
+		rs, err = rs.AppendField("Geohash", []string{"LAT", "LON"}, geohashGenerator{p.Precision})
+		if err != nil {
+			return nil, fmt.Errorf("find interactions: %w", err)
+		}
+	}
+	if !p.Sorted {
+		var err error
+		if rs, err = rs.SortByTimeContext(ctx); err != nil {
+			return nil, fmt.Errorf("find interactions: %w", err)
+		}
+	}
+
+	opts = append([]InteractionOption{WithRecordColumns()}, opts...)
+	inter, err := NewInteractionsWithOptions(rs.Headers(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("find interactions: %w", err)
+	}
+	if _, err := rs.readFirst(); err == io.EOF {
+		return inter, nil
+	}
+	geoIndex, _ := rs.Headers().Contains("Geohash")
+	err = rs.SlideWindow(p.Window, p.Slide, func(win *Window) error {
+		var cm ClusterMap
+		if p.Strategy == EightNeighbors {
+			cm = win.FindClustersNeighbors(geoIndex, bits)
+		} else {
+			cm = win.FindClusters(geoIndex)
+		}
+		var clusters []*Cluster
+		for _, c := range cm {
+			if c.Size() > 1 {
+				clusters = append(clusters, c)
+			}
+		}
+		return inter.AddClustersParallelContext(ctx, clusters, p.Workers)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find interactions: %w", err)
+	}
+	return inter, nil
+}
@@ -0,0 +1,52 @@
+package ais
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// detectData has two vessels within a few hundred yards of each other for two
+// minutes, a third vessel far away, and is not sorted by time.
+const detectData = `MMSI,BaseDateTime,LAT,LON,SOG,COG
+100000000,2017-12-01T00:01:00,30.00100,-76.00000,10.0,0.0
+200000000,2017-12-01T00:00:00,30.00000,-76.00100,10.0,90.0
+100000000,2017-12-01T00:00:00,30.00000,-76.00000,10.0,0.0
+300000000,2017-12-01T00:00:30,35.00000,-70.00000,10.0,90.0
+200000000,2017-12-01T00:01:00,30.00100,-76.00100,10.0,90.0
+`
+
+func TestRecordSet_FindInteractions(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(detectData), Headers{})
+	inter, err := rs.FindInteractions(context.Background(), InteractionParams{Window: 2 * time.Minute, Slide: time.Minute})
+	if err != nil {
+		t.Fatalf("RecordSet.FindInteractions() error = %v", err)
+	}
+	// Both vessel pairs at 00:00 and 00:01 and the pairs across the minute.
+	if inter.Len() != 4 {
+		t.Errorf("RecordSet.FindInteractions() Len() = %d, want 4", inter.Len())
+	}
+	if _, ok := inter.RecordHeaders.Contains("Geohash"); !ok {
+		t.Errorf("RecordSet.FindInteractions() record headers = %v, want Geohash", inter.RecordHeaders)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(detectData), Headers{})
+	inter, err = rs.FindInteractions(context.Background(), InteractionParams{Window: 2 * time.Minute}, WithMaxTimeGap(time.Second))
+	if err != nil {
+		t.Fatalf("RecordSet.FindInteractions() error = %v", err)
+	}
+	if inter.Len() != 2 {
+		t.Errorf("RecordSet.FindInteractions(WithMaxTimeGap(time.Second)) Len() = %d, want 2", inter.Len())
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT,LON\n"), Headers{})
+	if inter, err := rs.FindInteractions(context.Background(), InteractionParams{}); err != nil || inter.Len() != 0 {
+		t.Errorf("RecordSet.FindInteractions() on an empty set = %v, %v", inter, err)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT,LON,Geohash\n"), Headers{})
+	if _, err := rs.FindInteractions(context.Background(), InteractionParams{Precision: 30}); err == nil {
+		t.Error("RecordSet.FindInteractions() expected error for precision with an existing Geohash")
+	}
+}
@@ -0,0 +1,307 @@
+package ais
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DictionaryFields are the columns of a csv dictionary read by
+// NewDictionaryCSV, one row per code.
+const DictionaryFields = "Field,Code,Label"
+
+// LabelSuffix is appended to the name of a field to name the column of labels
+// added for it by RecordSet.Expand, so the labels of Status are StatusLabel.
+const LabelSuffix = "Label"
+
+// Dictionary maps the coded values of fields to human readable labels.  The
+// outer map is keyed by field name and the inner map by code, so that
+//
+//	d["Status"]["5"]
+//
+// is "moored".  Attach a Dictionary to the Headers of a RecordSet with
+// Headers.WithDictionary or RecordSet.SetDictionary and append the labels to
+// the Records with RecordSet.Expand.
+type Dictionary map[string]map[string]string
+
+// Label returns the label of code in field.  A numeric code written with a
+// decimal point, such as 70.0, finds the label of the integer code.
+func (d Dictionary) Label(field, code string) (string, bool) {
+	codes, ok := d[field]
+	if !ok {
+		return "", false
+	}
+	code = strings.TrimSpace(code)
+	if label, ok := codes[code]; ok {
+		return label, true
+	}
+	if f, err := strconv.ParseFloat(code, 64); err == nil && f == float64(int64(f)) {
+		label, ok := codes[strconv.FormatInt(int64(f), 10)]
+		return label, ok
+	}
+	return "", false
+}
+
+// Merge returns a new Dictionary with the codes of d and d2, where a code in
+// both takes its label from d2.  Merge a loaded Dictionary into
+// DefaultDictionary to relabel or add codes without listing every code.
+func (d Dictionary) Merge(d2 Dictionary) Dictionary {
+	out := make(Dictionary, len(d)+len(d2))
+	for _, src := range []Dictionary{d, d2} {
+		for field, codes := range src {
+			if out[field] == nil {
+				out[field] = make(map[string]string, len(codes))
+			}
+			for code, label := range codes {
+				out[field][code] = label
+			}
+		}
+	}
+	return out
+}
+
+// NewDictionaryJSON returns the Dictionary in a JSON object of fields whose
+// members are objects of codes and their labels:
+//
+//	{"Status": {"0": "under way using engine", "1": "at anchor"}}
+func NewDictionaryJSON(data []byte) (Dictionary, error) {
+	var d Dictionary
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("dictionary: json: %w", err)
+	}
+	return d, nil
+}
+
+// NewDictionaryCSV returns the Dictionary in a csv file with a header line of
+// DictionaryFields.
+func NewDictionaryCSV(r io.Reader) (Dictionary, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("dictionary: csv: %w", err)
+	}
+	h := Headers{Fields: header}
+	idx, err := h.require(strings.Split(DictionaryFields, ",")...)
+	if err != nil {
+		return nil, fmt.Errorf("dictionary: csv: %w", err)
+	}
+	d := make(Dictionary)
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("dictionary: csv: %w", err)
+		}
+		field, code := rec[idx["Field"].Idx], strings.TrimSpace(rec[idx["Code"].Idx])
+		if field == "" {
+			return nil, fmt.Errorf("dictionary: csv: line %d: empty field name", line)
+		}
+		if d[field] == nil {
+			d[field] = make(map[string]string)
+		}
+		d[field][code] = rec[idx["Label"].Idx]
+	}
+	return d, nil
+}
+
+// navigationalStatusLabels are the navigational status codes of ITU-R M.1371.
+var navigationalStatusLabels = [...]string{
+	0:  "under way using engine",
+	1:  "at anchor",
+	2:  "not under command",
+	3:  "restricted maneuverability",
+	4:  "constrained by her draught",
+	5:  "moored",
+	6:  "aground",
+	7:  "engaged in fishing",
+	8:  "under way sailing",
+	9:  "reserved for high speed craft",
+	10: "reserved for wing in ground",
+	11: "power-driven vessel towing astern",
+	12: "power-driven vessel pushing ahead or towing alongside",
+	13: "reserved",
+	14: "AIS-SART active",
+	15: "not defined",
+}
+
+// shipTypeLabels are the ship and cargo types of ITU-R M.1371 that are not in
+// a group of ten with hazardous categories.
+var shipTypeLabels = map[int]string{
+	0:  "not available",
+	30: "fishing",
+	31: "towing",
+	32: "towing, length exceeds 200m or breadth exceeds 25m",
+	33: "dredging or underwater operations",
+	34: "diving operations",
+	35: "military operations",
+	36: "sailing",
+	37: "pleasure craft",
+	50: "pilot vessel",
+	51: "search and rescue vessel",
+	52: "tug",
+	53: "port tender",
+	54: "anti-pollution equipment",
+	55: "law enforcement",
+	58: "medical transport",
+	59: "noncombatant ship",
+}
+
+// shipTypeGroups are the groups of ten ship and cargo types of ITU-R M.1371
+// whose second digit is the hazard category of the cargo.
+var shipTypeGroups = map[int]string{
+	20: "wing in ground",
+	40: "high speed craft",
+	60: "passenger",
+	70: "cargo",
+	80: "tanker",
+	90: "other type",
+}
+
+// marineCadastreTypeLabels are the vessel group codes of MarineCadastre.gov.
+var marineCadastreTypeLabels = map[int]string{
+	1001: "commercial fishing vessel",
+	1002: "fish processing vessel",
+	1003: "freight barge",
+	1004: "freight ship",
+	1005: "industrial vessel",
+	1006: "miscellaneous vessel",
+	1007: "mobile offshore drilling unit",
+	1008: "non-vessel",
+	1009: "non-self-propelled vessel",
+	1010: "offshore supply vessel",
+	1011: "oil recovery vessel",
+	1012: "passenger vessel",
+	1013: "public vessel, unclassified",
+	1014: "recreational vessel",
+	1015: "research vessel",
+	1016: "school ship",
+	1017: "tank barge",
+	1018: "tank ship",
+	1019: "towing vessel",
+}
+
+// DefaultDictionary returns a Dictionary of the navigational status codes of
+// ITU-R M.1371 for Status, and its ship and cargo types for VesselType and
+// Cargo, with the MarineCadastre.gov vessel group codes 1001 through 1019 added
+// to VesselType.  Codes that ITU-R M.1371 reserves have no label.
+func DefaultDictionary() Dictionary {
+	status := make(map[string]string, len(navigationalStatusLabels))
+	for code, label := range navigationalStatusLabels {
+		status[strconv.Itoa(code)] = label
+	}
+
+	shipTypes := make(map[string]string)
+	for code, label := range shipTypeLabels {
+		shipTypes[strconv.Itoa(code)] = label
+	}
+	for base, label := range shipTypeGroups {
+		shipTypes[strconv.Itoa(base)] = label
+		for i, cat := range []string{"A", "B", "C", "D"} {
+			shipTypes[strconv.Itoa(base+1+i)] = label + ", hazardous category " + cat
+		}
+		shipTypes[strconv.Itoa(base+9)] = label + ", no additional information"
+	}
+	vesselTypes := make(map[string]string, len(shipTypes)+len(marineCadastreTypeLabels))
+	for code, label := range shipTypes {
+		vesselTypes[code] = label
+	}
+	for code, label := range marineCadastreTypeLabels {
+		vesselTypes[strconv.Itoa(code)] = label
+	}
+
+	return Dictionary{
+		"Status":     status,
+		"VesselType": vesselTypes,
+		"Cargo":      shipTypes,
+	}
+}
+
+// WithDictionary returns a copy of h whose coded fields are labeled by d.
+func (h Headers) WithDictionary(d Dictionary) Headers {
+	h.Dictionary = d
+	return h
+}
+
+// SetDictionary attaches the Dictionary in the JSON object data, as read by
+// NewDictionaryJSON, to the Headers of the RecordSet.
+func (rs *RecordSet) SetDictionary(data []byte) error {
+	d, err := NewDictionaryJSON(data)
+	if err != nil {
+		return fmt.Errorf("set dictionary: %w", err)
+	}
+	rs.h = rs.h.WithDictionary(d)
+	return nil
+}
+
+// Expand returns a pointer to a new RecordSet with a column of labels appended
+// for each of fields, named by the field and LabelSuffix, holding the label of
+// the code in the field from the Dictionary of the Headers.  Without fields
+// every field of the Dictionary in the Headers is expanded, in the order of the
+// Headers.  A nil Dictionary is DefaultDictionary.  Codes without a label are
+// left empty.  The Headers must contain each of fields.  Expand consumes the
+// receiver.
+func (rs *RecordSet) Expand(fields ...string) (*RecordSet, error) {
+	h := rs.Headers()
+	d := h.Dictionary
+	if d == nil {
+		d = DefaultDictionary()
+	}
+	if len(fields) == 0 {
+		for _, f := range h.Fields {
+			if _, ok := d[f]; ok {
+				fields = append(fields, f)
+			}
+		}
+	}
+	idx, err := h.require(fields...)
+	if err != nil {
+		return nil, fmt.Errorf("expand: %w", err)
+	}
+
+	h2 := h
+	h2.Fields = append([]string(nil), h.Fields...)
+	for _, f := range fields {
+		h2.Fields = append(h2.Fields, f+LabelSuffix)
+	}
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(h2)
+
+	written := 0
+	for {
+		rec, err := rs.read(true) // each Record is written before the next is read
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("expand: read error on csv file: %w", err)
+		}
+		out := make(Record, len(*rec), len(*rec)+len(fields))
+		copy(out, *rec)
+		for _, f := range fields {
+			var label string
+			if i := idx[f].Idx; i < len(*rec) {
+				label, _ = d.Label(f, (*rec)[i])
+			}
+			out = append(out, label)
+		}
+		if err := rs2.Write(out); err != nil {
+			return nil, fmt.Errorf("expand: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, fmt.Errorf("expand: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("expand: csv flush error: %w", err)
+	}
+	return rs2, nil
+}
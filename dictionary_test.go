@@ -0,0 +1,118 @@
+package ais
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDictionary_Label(t *testing.T) {
+	d := DefaultDictionary()
+	tests := []struct {
+		field, code string
+		want        string
+		ok          bool
+	}{
+		{"Status", "5", "moored", true},
+		{"Status", "13", "reserved", true},
+		{"VesselType", "70.0", "cargo", true},
+		{"VesselType", "82", "tanker, hazardous category B", true},
+		{"VesselType", "1004", "freight ship", true},
+		{"Cargo", "1004", "", false},
+		{"VesselType", "25", "", false},
+		{"Heading", "511", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := d.Label(tt.field, tt.code)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Label(%q, %q) = %q, %v; want %q, %v", tt.field, tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewDictionary(t *testing.T) {
+	fromJSON, err := NewDictionaryJSON([]byte(`{"Status": {"5": "alongside"}, "Zone": {"A": "anchorage"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromCSV, err := NewDictionaryCSV(strings.NewReader("Field,Code,Label\nStatus,5,alongside\nZone, A ,anchorage\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, d := range map[string]Dictionary{"json": fromJSON, "csv": fromCSV} {
+		if got, _ := d.Label("Zone", "A"); got != "anchorage" {
+			t.Errorf("%s: Label(Zone, A) = %q, want anchorage", name, got)
+		}
+		merged := DefaultDictionary().Merge(d)
+		if got, _ := merged.Label("Status", "5"); got != "alongside" {
+			t.Errorf("%s: merged Label(Status, 5) = %q, want alongside", name, got)
+		}
+		if got, _ := merged.Label("Status", "1"); got != "at anchor" {
+			t.Errorf("%s: merged Label(Status, 1) = %q, want at anchor", name, got)
+		}
+	}
+
+	if _, err := NewDictionaryJSON([]byte(`{"Status": "moored"}`)); err == nil {
+		t.Error("NewDictionaryJSON() of a malformed dictionary should fail")
+	}
+	if _, err := NewDictionaryCSV(strings.NewReader("Field,Label\nStatus,moored\n")); err == nil {
+		t.Error("NewDictionaryCSV() without a Code column should fail")
+	}
+}
+
+func TestRecordSet_Expand(t *testing.T) {
+	const data = "MMSI,VesselType,Status\n" +
+		"1,70,5\n" +
+		"2,1019,\n" +
+		"3,,0\n"
+	want := [][]string{
+		{"1", "70", "5", "cargo", "moored"},
+		{"2", "1019", "", "towing vessel", ""},
+		{"3", "", "0", "", "under way using engine"},
+	}
+
+	rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs2, err := rs.Expand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rs2.Headers().Fields, ","); got != "MMSI,VesselType,Status,VesselTypeLabel,StatusLabel" {
+		t.Errorf("Expand() Headers = %s", got)
+	}
+	for i := 0; ; i++ {
+		rec, err := rs2.Read()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Errorf("Expand() returned %d Records, want %d", i, len(want))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(*rec, ","); got != strings.Join(want[i], ",") {
+			t.Errorf("Expand() Record %d = %s, want %s", i, got, strings.Join(want[i], ","))
+		}
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if err := rs.SetDictionary([]byte(`{"Status": {"5": "alongside"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	rs2, err = rs.Expand("Status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, _ := rs2.Read()
+	if got := (*rec)[3]; got != "alongside" {
+		t.Errorf("Expand() with SetDictionary label = %q, want alongside", got)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if _, err := rs.Expand("Cargo"); err == nil {
+		t.Error("Expand() of a missing field should fail")
+	}
+}
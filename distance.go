@@ -0,0 +1,214 @@
+package ais
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/FATHOM5/haversine"
+)
+
+// earthRadiusNM is the mean radius of the earth in nautical miles.
+const earthRadiusNM = 3440.065
+
+// WGS-84 ellipsoid parameters used by Vincenty.
+const (
+	wgs84A = 6378137.0         // semi-major axis in meters
+	wgs84F = 1 / 298.257223563 // flattening
+	wgs84B = wgs84A * (1 - wgs84F)
+)
+
+// metersPerNM is the number of meters in one international nautical mile.
+const metersPerNM = 1852.0
+
+// DistanceFunc is the signature for functions that return the distance in nautical
+// miles between two positions given in decimal degrees.  Haversine, Vincenty, and
+// Equirectangular are implementations provided by the package that may be passed
+// to WithDistanceFunc.
+type DistanceFunc func(lat1, lon1, lat2, lon2 float64) (nm float64)
+
+// ParseDistanceFunc returns the DistanceFunc of the package named by name:
+// haversine, equirectangular, or vincenty, which may also be called geodesic.
+// Case is ignored.  It lets command line tools and configuration files choose a
+// metric.
+func ParseDistanceFunc(name string) (DistanceFunc, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "haversine":
+		return Haversine, nil
+	case "equirectangular":
+		return Equirectangular, nil
+	case "vincenty", "geodesic":
+		return Vincenty, nil
+	}
+	return nil, fmt.Errorf("parse distance func: unknown metric %q", name)
+}
+
+// Haversine returns the great circle distance in nautical miles between two
+// positions on a spherical earth.  It is the same computation used by
+// Record.DistanceTo.
+func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	return haversine.Distance(haversine.Coord{Lat: lat1, Lon: lon1}, haversine.Coord{Lat: lat2, Lon: lon2})
+}
+
+// Equirectangular returns an approximate distance in nautical miles between two
+// positions by projecting them onto a plane scaled by the cosine of their mean
+// latitude.  It is considerably cheaper than Haversine and accurate to a small
+// fraction of a percent over the few nautical miles that separate interacting
+// vessels.
+func Equirectangular(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLon := lon2 - lon1
+	if dLon > 180 {
+		dLon -= 360
+	} else if dLon < -180 {
+		dLon += 360
+	}
+	x := dLon * rad * math.Cos((lat1+lat2)/2*rad)
+	y := (lat2 - lat1) * rad
+	return earthRadiusNM * math.Hypot(x, y)
+}
+
+// Vincenty returns the distance in nautical miles between two positions on the
+// WGS-84 ellipsoid using the Vincenty inverse formula.  For nearly antipodal
+// positions where the formula fails to converge Vincenty returns the Haversine
+// distance.
+func Vincenty(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	if lat1 == lat2 && lon1 == lon2 {
+		return 0
+	}
+
+	L := (lon2 - lon1) * rad
+	U1 := math.Atan((1 - wgs84F) * math.Tan(lat1*rad))
+	U2 := math.Atan((1 - wgs84F) * math.Tan(lat2*rad))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	converged := false
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0 // coincident points
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 { // both points on the equator
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*
+			(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return Haversine(lat1, lon1, lat2, lon2)
+	}
+
+	uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+	meters := wgs84B * A * (sigma - deltaSigma)
+	return meters / metersPerNM
+}
+
+// ErrPositionNotAvailable is returned by Record.DistanceTo when a Record has the
+// LAT of 91 or the LON of 181 that AIS reports for a position that is not
+// available.  It is wrapped in an ErrParse naming the field.
+var ErrPositionNotAvailable = errors.New("position not available")
+
+// DistanceUnit is the unit of the distance returned by Record.DistanceTo.
+type DistanceUnit int
+
+// Units of distance.
+const (
+	NauticalMiles DistanceUnit = iota
+	Kilometers
+	Meters
+)
+
+var distanceUnitNames = [...]string{"nm", "km", "m"}
+
+// String implements the Stringer interface for DistanceUnit.
+func (u DistanceUnit) String() string {
+	if u < 0 || int(u) >= len(distanceUnitNames) {
+		return fmt.Sprintf("DistanceUnit(%d)", int(u))
+	}
+	return distanceUnitNames[u]
+}
+
+// FromNM converts a distance in nautical miles to the unit u.
+func (u DistanceUnit) FromNM(nm float64) float64 {
+	switch u {
+	case Kilometers:
+		return nm * metersPerNM / 1000
+	case Meters:
+		return nm * metersPerNM
+	}
+	return nm
+}
+
+// DistanceTo returns the Haversine distance in the unit u between the LAT and
+// LON of r and of r2, both described by h.  Errors are an ErrMissingHeader when
+// h lacks either field and an ErrParse when a value is not a number or is the
+// placeholder for a position that is not available, which wraps
+// ErrPositionNotAvailable, so that a missing position is never measured as a
+// real one:
+//
+//	km, err := rec1.DistanceTo(rec2, h, ais.Kilometers)
+//	if errors.Is(err, ais.ErrPositionNotAvailable) {
+//		// skip the pair
+//	}
+func (r Record) DistanceTo(r2 Record, h Headers, u DistanceUnit) (float64, error) {
+	return r.DistanceWith(r2, h, u, Haversine)
+}
+
+// DistanceWith is DistanceTo measured with the metric fn, such as Vincenty for
+// the WGS-84 geodesic distance.  A nil fn is Haversine.
+func (r Record) DistanceWith(r2 Record, h Headers, u DistanceUnit, fn DistanceFunc) (float64, error) {
+	lat1, lon1, err := r.position(h)
+	if err != nil {
+		return 0, err
+	}
+	lat2, lon2, err := r2.position(h)
+	if err != nil {
+		return 0, err
+	}
+	if fn == nil {
+		fn = Haversine
+	}
+	return u.FromNM(fn(lat1, lon1, lat2, lon2)), nil
+}
+
+// position returns the LAT and LON of r described by h, with an ErrParse
+// wrapping ErrPositionNotAvailable for the AIS placeholders.
+func (r Record) position(h Headers) (lat, lon float64, err error) {
+	if lat, lon, err = r.LatLon(h); err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, checkPosition(lat, lon)
+}
+
+// checkPosition returns an ErrParse wrapping ErrPositionNotAvailable when lat or
+// lon is the AIS placeholder for a position that is not available.
+func checkPosition(lat, lon float64) error {
+	if lat == 91 {
+		return ErrParse{Field: "LAT", Err: ErrPositionNotAvailable}
+	}
+	if lon == 181 {
+		return ErrParse{Field: "LON", Err: ErrPositionNotAvailable}
+	}
+	return nil
+}
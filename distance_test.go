@@ -0,0 +1,134 @@
+package ais
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDistanceFuncs(t *testing.T) {
+	// Flinders Peak to Buninyong, the standard Vincenty test case of 54972.271m.
+	const lat1, lon1, lat2, lon2 = -37.95103341, 144.42486789, -37.65282114, 143.92649554
+	const want = 54972.271 / metersPerNM
+
+	tests := []struct {
+		name string
+		fn   DistanceFunc
+		tol  float64 // relative tolerance
+	}{
+		{name: "Vincenty", fn: Vincenty, tol: 1e-6},
+		{name: "Haversine", fn: Haversine, tol: 5e-3},
+		{name: "Equirectangular", fn: Equirectangular, tol: 5e-3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.fn(lat1, lon1, lat2, lon2)
+			if math.Abs(got-want)/want > tt.tol {
+				t.Errorf("%s() = %v, want %v", tt.name, got, want)
+			}
+			if d := tt.fn(lat1, lon1, lat1, lon1); d != 0 {
+				t.Errorf("%s() for coincident points = %v, want 0", tt.name, d)
+			}
+		})
+	}
+}
+
+func TestEquirectangular_Antimeridian(t *testing.T) {
+	got := Equirectangular(0, 179.95, 0, -179.95)
+	want := Haversine(0, 179.95, 0, -179.95)
+	if math.Abs(got-want) > 1e-6 {
+		t.Errorf("Equirectangular() across the antimeridian = %v, want %v", got, want)
+	}
+}
+
+func TestRecord_DistanceTo(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "LAT", "LON"}}
+	rec1 := Record{"1", "36.90512", "-76.32652"}
+	rec2 := Record{"2", "36.91512", "-76.22652"}
+	nm := Haversine(36.90512, -76.32652, 36.91512, -76.22652)
+	for _, tt := range []struct {
+		unit DistanceUnit
+		want float64
+	}{
+		{NauticalMiles, nm},
+		{Kilometers, nm * 1.852},
+		{Meters, nm * 1852},
+	} {
+		got, err := rec1.DistanceTo(rec2, h, tt.unit)
+		if err != nil {
+			t.Fatalf("DistanceTo(%v) error = %v", tt.unit, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9*tt.want {
+			t.Errorf("DistanceTo(%v) = %v, want %v", tt.unit, got, tt.want)
+		}
+	}
+	if old, _ := rec1.Distance(rec2, 1, 2); old != nm {
+		t.Errorf("Distance = %v, want %v", old, nm)
+	}
+
+	for _, bad := range []Record{{"3", "91", "-76.2"}, {"3", "36.9", "181"}} {
+		_, err := rec1.DistanceTo(bad, h, NauticalMiles)
+		if !errors.Is(err, ErrPositionNotAvailable) {
+			t.Errorf("DistanceTo(%v) error = %v, want ErrPositionNotAvailable", bad, err)
+		}
+		if _, err := bad.Distance(rec1, 1, 2); !errors.Is(err, ErrPositionNotAvailable) {
+			t.Errorf("Distance(%v) error = %v, want ErrPositionNotAvailable", bad, err)
+		}
+	}
+	var pe ErrParse
+	if _, err := rec1.DistanceTo(Record{"3", "x", "1"}, h, Meters); !errors.As(err, &pe) || pe.Field != "LAT" {
+		t.Errorf("DistanceTo with a bad LAT error = %v, want ErrParse for LAT", err)
+	}
+	if _, err := rec1.Distance(Record{"3", "1", "x"}, 1, 2); !errors.As(err, &pe) || pe.Field != "LON" {
+		t.Errorf("Distance with a bad LON error = %v, want ErrParse for LON", err)
+	}
+	var me ErrMissingHeader
+	if _, err := rec1.DistanceTo(rec2, Headers{Fields: []string{"MMSI"}}, NauticalMiles); !errors.As(err, &me) {
+		t.Errorf("DistanceTo without LAT error = %v, want ErrMissingHeader", err)
+	}
+	if s := DistanceUnit(7).String(); s != "DistanceUnit(7)" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestParseDistanceFunc(t *testing.T) {
+	// Helsinki to Gdansk, a Baltic baseline where the ellipsoid matters.
+	const lat1, lon1, lat2, lon2 = 60.15, 24.95, 54.35, 18.65
+	for _, tt := range []struct {
+		name string
+		want DistanceFunc
+	}{
+		{"haversine", Haversine},
+		{"Equirectangular", Equirectangular},
+		{"vincenty", Vincenty},
+		{" GEODESIC ", Vincenty},
+	} {
+		fn, err := ParseDistanceFunc(tt.name)
+		if err != nil {
+			t.Fatalf("ParseDistanceFunc(%q) error = %v", tt.name, err)
+		}
+		if got, want := fn(lat1, lon1, lat2, lon2), tt.want(lat1, lon1, lat2, lon2); got != want {
+			t.Errorf("ParseDistanceFunc(%q) = %v, want %v", tt.name, got, want)
+		}
+	}
+	if _, err := ParseDistanceFunc("manhattan"); err == nil {
+		t.Error("ParseDistanceFunc of an unknown metric returned no error")
+	}
+
+	h := Headers{Fields: []string{"LAT", "LON"}}
+	rec1, rec2 := Record{"60.15", "24.95"}, Record{"54.35", "18.65"}
+	geo, err := rec1.DistanceWith(rec2, h, Meters, Vincenty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Vincenty(lat1, lon1, lat2, lon2) * metersPerNM; geo != want {
+		t.Errorf("DistanceWith(Vincenty) = %v, want %v", geo, want)
+	}
+	hav, _ := rec1.DistanceTo(rec2, h, Meters)
+	if def, _ := rec1.DistanceWith(rec2, h, Meters, nil); def != hav {
+		t.Errorf("DistanceWith(nil) = %v, want the DistanceTo value %v", def, hav)
+	}
+	if d := math.Abs(geo - hav); d < 100 {
+		t.Errorf("geodesic and haversine distances differ by %vm, want the ellipsoid to matter", d)
+	}
+}
@@ -0,0 +1,320 @@
+package ais
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ElasticIndexer writes RecordSets and Interactions to an Elasticsearch index
+// through the bulk API.  Each Record or interaction becomes one document typed as
+// for SaveJSONL, with a geo_point location field built from LAT and LON so that
+// positions can be mapped in Kibana.  Documents are sent in batches, and batches
+// or documents rejected because the cluster is busy are retried with exponential
+// backoff.  An ElasticIndexer should not be used concurrently.
+type ElasticIndexer struct {
+	URL   string // base URL of the cluster, for example http://localhost:9200
+	Index string
+
+	Client             *http.Client
+	Username, Password string // basic authentication when Username is not empty
+
+	BatchSize  int           // documents per bulk request
+	MaxRetries int           // retries of a failed request before giving up
+	RetryWait  time.Duration // wait before the first retry, doubled for each retry
+}
+
+// Defaults for the batching and retry settings of NewElasticIndexer.
+const (
+	DefaultElasticBatchSize  = 1000
+	DefaultElasticMaxRetries = 3
+	DefaultElasticRetryWait  = time.Second
+)
+
+// elasticLocationField is the geo_point field added to documents.  Interactions
+// have a location field for each vessel, suffixed _1 and _2.
+const elasticLocationField = "location"
+
+// NewElasticIndexer returns an *ElasticIndexer for index on the cluster at url
+// with the default batch size and retry settings and http.DefaultClient.
+func NewElasticIndexer(url, index string) *ElasticIndexer {
+	return &ElasticIndexer{
+		URL:        strings.TrimSuffix(url, "/"),
+		Index:      index,
+		Client:     http.DefaultClient,
+		BatchSize:  DefaultElasticBatchSize,
+		MaxRetries: DefaultElasticMaxRetries,
+		RetryWait:  DefaultElasticRetryWait,
+	}
+}
+
+// elasticDocs converts rows into bulk API documents.
+type elasticDocs struct {
+	enc     *jsonEncoder
+	latLons [][2]int // LAT and LON indices for each location field
+	suffix  []string
+}
+
+func newElasticDocs(h Headers) *elasticDocs {
+	d := &elasticDocs{enc: newJSONEncoder(h)}
+	for _, suffix := range []string{"", "_1", "_2"} {
+		if idx, ok := h.ContainsMulti("LAT"+suffix, "LON"+suffix); ok {
+			d.latLons = append(d.latLons, [2]int{idx["LAT"+suffix].Idx, idx["LON"+suffix].Idx})
+			d.suffix = append(d.suffix, suffix)
+		}
+	}
+	return d
+}
+
+// mapping returns the body of the create index request.
+func (d *elasticDocs) mapping() []byte {
+	props := make(map[string]interface{})
+	for i, f := range d.enc.fields {
+		typ := "keyword"
+		switch d.enc.types[i] {
+		case "timestamp":
+			typ = "date"
+		case "double":
+			typ = "double"
+		}
+		props[f] = map[string]string{"type": typ}
+	}
+	for _, suffix := range d.suffix {
+		props[elasticLocationField+suffix] = map[string]string{"type": "geo_point"}
+	}
+	b, _ := json.Marshal(map[string]interface{}{"mappings": map[string]interface{}{"properties": props}})
+	return b
+}
+
+// document returns the source of the document for row.  A location is omitted
+// when its LAT or LON is not a valid position.
+func (d *elasticDocs) document(row []string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	if err := d.enc.members(&buf, row); err != nil {
+		return nil, err
+	}
+	rec := Record(row)
+	for i, ll := range d.latLons {
+		lat, err1 := rec.ParseFloat(ll[0])
+		lon, err2 := rec.ParseFloat(ll[1])
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			continue
+		}
+		fmt.Fprintf(&buf, `,"%s%s":{"lat":%s,"lon":%s}`, elasticLocationField, d.suffix[i],
+			strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// IndexRecordSet creates the index with a mapping for the Headers of rs, unless
+// it already exists, and indexes every remaining Record.  It returns the number
+// of documents indexed.  IndexRecordSet consumes rs.
+func (e *ElasticIndexer) IndexRecordSet(ctx context.Context, rs *RecordSet) (int, error) {
+	docs := newElasticDocs(rs.Headers())
+	if err := e.createIndex(ctx, docs.mapping()); err != nil {
+		return 0, fmt.Errorf("elastic index recordset: %w", err)
+	}
+	var batch [][]byte
+	indexed := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return indexed, fmt.Errorf("elastic index recordset: read error on csv file: %w", err)
+		}
+		doc, err := docs.document(*rec)
+		if err != nil {
+			return indexed, fmt.Errorf("elastic index recordset: %w", err)
+		}
+		batch = append(batch, doc)
+		if len(batch) >= e.batchSize() {
+			if err := e.bulk(ctx, batch); err != nil {
+				return indexed, fmt.Errorf("elastic index recordset: %w", err)
+			}
+			indexed += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := e.bulk(ctx, batch); err != nil {
+		return indexed, fmt.Errorf("elastic index recordset: %w", err)
+	}
+	return indexed + len(batch), nil
+}
+
+// IndexInteractions creates the index with a mapping for the OutputHeaders of
+// inter, unless it already exists, and indexes every interaction with a
+// location_1 and location_2 for the two vessels.  It returns the number of
+// documents indexed.
+func (e *ElasticIndexer) IndexInteractions(ctx context.Context, inter *Interactions) (int, error) {
+	docs := newElasticDocs(inter.OutputHeaders)
+	if err := e.createIndex(ctx, docs.mapping()); err != nil {
+		return 0, fmt.Errorf("elastic index interactions: %w", err)
+	}
+	var batch [][]byte
+	indexed := 0
+	err := inter.each(func(hash Hash128, pair *RecordPair) error {
+		row, err := inter.row(hash, pair)
+		if err != nil {
+			return err
+		}
+		doc, err := docs.document(row)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, doc)
+		if len(batch) >= e.batchSize() {
+			if err := e.bulk(ctx, batch); err != nil {
+				return err
+			}
+			indexed += len(batch)
+			batch = batch[:0]
+		}
+		return nil
+	})
+	if err == nil {
+		err = e.bulk(ctx, batch)
+	}
+	if err != nil {
+		return indexed, fmt.Errorf("elastic index interactions: %w", err)
+	}
+	return indexed + len(batch), nil
+}
+
+func (e *ElasticIndexer) batchSize() int {
+	if e.BatchSize <= 0 {
+		return DefaultElasticBatchSize
+	}
+	return e.BatchSize
+}
+
+// do sends a request and returns the status code and body of the response.
+func (e *ElasticIndexer) do(ctx context.Context, method, path, contentType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, e.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	if e.Username != "" {
+		req.SetBasicAuth(e.Username, e.Password)
+	}
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, b, err
+}
+
+// createIndex creates the index with mapping.  An index that already exists is
+// left unchanged.
+func (e *ElasticIndexer) createIndex(ctx context.Context, mapping []byte) error {
+	status, body, err := e.do(ctx, "PUT", "/"+e.Index, "application/json", mapping)
+	if err != nil {
+		return fmt.Errorf("create index: %w", err)
+	}
+	if status/100 == 2 || bytes.Contains(body, []byte("resource_already_exists_exception")) {
+		return nil
+	}
+	return fmt.Errorf("create index: %s: %s", http.StatusText(status), body)
+}
+
+// elasticBulkResponse is the part of a bulk API response used to find the
+// documents that failed.
+type elasticBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// retryable reports whether a request or document that failed with status may
+// succeed later.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// bulk indexes docs, retrying the whole request after a transport error or a
+// retryable status, and retrying only the rejected documents when some of them
+// fail with a retryable status.  Documents that fail for any other reason, such
+// as a mapping conflict, cause an error.
+func (e *ElasticIndexer) bulk(ctx context.Context, docs [][]byte) error {
+	action := []byte(fmt.Sprintf(`{"index":{"_index":%q}}`+"\n", e.Index))
+	wait := e.RetryWait
+	var last error // why the previous attempt is retried
+	for attempt := 0; len(docs) > 0; attempt++ {
+		if attempt > 0 {
+			if attempt > e.MaxRetries {
+				return fmt.Errorf("bulk: %d documents still failing after %d retries: %w", len(docs), e.MaxRetries, last)
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return fmt.Errorf("bulk: %w", ctx.Err())
+			}
+			wait *= 2
+		}
+
+		var body bytes.Buffer
+		for _, doc := range docs {
+			body.Write(action)
+			body.Write(doc)
+			body.WriteByte('\n')
+		}
+		status, resp, err := e.do(ctx, "POST", "/_bulk", "application/x-ndjson", body.Bytes())
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("bulk: %w", ctx.Err())
+			}
+			last = err
+			continue
+		}
+		if retryable(status) {
+			last = fmt.Errorf("%s: %s", http.StatusText(status), resp)
+			continue
+		}
+		if status/100 != 2 {
+			return fmt.Errorf("bulk: %s: %s", http.StatusText(status), resp)
+		}
+
+		var br elasticBulkResponse
+		if err := json.Unmarshal(resp, &br); err != nil {
+			return fmt.Errorf("bulk: unable to parse response: %w", err)
+		}
+		if !br.Errors {
+			return nil
+		}
+		var retry [][]byte
+		for i, item := range br.Items {
+			for _, result := range item {
+				switch {
+				case result.Status/100 == 2:
+				case retryable(result.Status) && i < len(docs):
+					retry = append(retry, docs[i])
+					last = fmt.Errorf("document rejected with status %d: %s", result.Status, result.Error)
+				default:
+					return fmt.Errorf("bulk: document rejected with status %d: %s", result.Status, result.Error)
+				}
+			}
+		}
+		docs = retry
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package ais
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestElasticIndexer_IndexRecordSet(t *testing.T) {
+	var mapping map[string]interface{}
+	var bulkCalls int
+	indexed := make(map[string]int) // BaseDateTime of each indexed document
+	var sample map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/ais":
+			json.NewDecoder(r.Body).Decode(&mapping)
+			w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == "POST" && r.URL.Path == "/_bulk":
+			bulkCalls++
+			if bulkCalls == 1 { // the cluster is busy
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			var items []string
+			s := bufio.NewScanner(r.Body)
+			for n := 0; s.Scan(); n++ {
+				if n%2 == 0 {
+					continue // action line
+				}
+				var doc map[string]interface{}
+				json.Unmarshal(s.Bytes(), &doc)
+				sample = doc
+				status := 201
+				if bulkCalls == 2 && n == 1 { // reject the first document once
+					status = 429
+				} else {
+					indexed[doc["BaseDateTime"].(string)]++
+				}
+				items = append(items, fmt.Sprintf(`{"index":{"status":%d}}`, status))
+			}
+			fmt.Fprintf(w, `{"errors":%v,"items":[%s]}`, bulkCalls == 2, strings.Join(items, ","))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	e := NewElasticIndexer(srv.URL+"/", "ais")
+	e.BatchSize = 4
+	e.RetryWait = time.Millisecond
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	n, err := e.IndexRecordSet(context.Background(), rs)
+	if err != nil {
+		t.Fatalf("ElasticIndexer.IndexRecordSet() error = %v", err)
+	}
+	if n != 10 || len(indexed) != 10 {
+		t.Errorf("ElasticIndexer.IndexRecordSet() = %d, server indexed %d unique documents, want 10", n, len(indexed))
+	}
+	for ts, count := range indexed {
+		if count != 1 {
+			t.Errorf("document %s indexed %d times, want 1", ts, count)
+		}
+	}
+
+	props := mapping["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+	for field, want := range map[string]string{"location": "geo_point", "BaseDateTime": "date", "LAT": "double", "MMSI": "keyword"} {
+		if got := props[field].(map[string]interface{})["type"]; got != want {
+			t.Errorf("mapping %s type = %v, want %s", field, got, want)
+		}
+	}
+	if loc, ok := sample["location"].(map[string]interface{}); !ok || loc["lat"] == nil {
+		t.Errorf("document location = %v, want a geo_point", sample["location"])
+	}
+}
+
+func TestElasticIndexer_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/exists":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"resource_already_exists_exception"}}`))
+		case "/_bulk":
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	if _, err := NewElasticIndexer(srv.URL, "secret").IndexRecordSet(context.Background(), rs); err == nil {
+		t.Error("ElasticIndexer.IndexRecordSet() expected error for an unauthorized index")
+	}
+
+	c := testClusters(1, 2)[0]
+	for _, rec := range c.Data() {
+		*rec = append(*rec, "0x1")
+	}
+	inter, _ := NewInteractions(goodHeaders)
+	inter.AddCluster(c)
+	_, err := NewElasticIndexer(srv.URL, "exists").IndexInteractions(context.Background(), inter)
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("ElasticIndexer.IndexInteractions() error = %v, want rejected document", err)
+	}
+}
+
+func TestElasticIndexer_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The bulk request fails with a retryable status after the Context is
+	// canceled, so the indexer stops instead of retrying.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_bulk" {
+			cancel()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	e := NewElasticIndexer(srv.URL, "ais")
+	e.RetryWait = time.Hour
+	if _, err := e.IndexRecordSet(ctx, rs); !errors.Is(err, context.Canceled) {
+		t.Errorf("ElasticIndexer.IndexRecordSet() error = %v, want context.Canceled", err)
+	}
+}
+
+// elasticTransport fails every bulk request with err and sends the others to
+// the server.
+type elasticTransport struct {
+	err error
+}
+
+func (et elasticTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path == "/_bulk" {
+		return nil, et.err
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestElasticIndexer_TransportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	errTLS := errors.New("tls: handshake failure")
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	e := NewElasticIndexer(srv.URL, "ais")
+	e.Client = &http.Client{Transport: elasticTransport{errTLS}}
+	e.MaxRetries = 2
+	e.RetryWait = time.Millisecond
+	_, err := e.IndexRecordSet(context.Background(), rs)
+	if !errors.Is(err, errTLS) || !strings.Contains(err.Error(), "after 2 retries") {
+		t.Errorf("ElasticIndexer.IndexRecordSet() error = %v, want the transport error after 2 retries", err)
+	}
+}
@@ -0,0 +1,107 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+)
+
+// EncounterFields is the optional column header written by Interactions.Save
+// after any CPA and risk columns when encounter classification is enabled with
+// SetEncounter.
+const EncounterFields = "Encounter"
+
+// Sector limits in degrees used to classify encounters.  HeadOnSector is the
+// half-width of the sector ahead of each vessel in which the other must lie for a
+// head-on encounter.  OvertakingSector is the angle abaft the beam beyond which an
+// approaching vessel is overtaking, as defined by COLREGS Rule 13.
+const (
+	HeadOnSector     = 6.0
+	OvertakingSector = 22.5
+)
+
+// Encounter is the COLREGS classification of a two-vessel encounter from the
+// point of view of the first vessel of a RecordPair.
+type Encounter int
+
+const (
+	// NoEncounter is a pair of vessels that are not closing.
+	NoEncounter Encounter = iota
+
+	// HeadOn vessels are on reciprocal or nearly reciprocal courses (Rule 14).
+	HeadOn
+
+	// CrossingGiveWay is a crossing situation with the other vessel on the
+	// starboard side, so the first vessel must keep out of the way (Rule 15).
+	CrossingGiveWay
+
+	// CrossingStandOn is a crossing situation with the other vessel on the port side.
+	CrossingStandOn
+
+	// Overtaking is the first vessel coming up on the other from more than
+	// OvertakingSector abaft its beam (Rule 13).
+	Overtaking
+
+	// Overtaken is the first vessel being overtaken by the other.
+	Overtaken
+)
+
+var encounterNames = [...]string{
+	NoEncounter:     "none",
+	HeadOn:          "head-on",
+	CrossingGiveWay: "crossing give-way",
+	CrossingStandOn: "crossing stand-on",
+	Overtaking:      "overtaking",
+	Overtaken:       "overtaken",
+}
+
+// String implements the Stringer interface for Encounter.
+func (e Encounter) String() string {
+	if e < 0 || int(e) >= len(encounterNames) {
+		return fmt.Sprintf("Encounter(%d)", int(e))
+	}
+	return encounterNames[e]
+}
+
+// Encounter classifies the pair into a COLREGS encounter type using the relative
+// geometry of their reported positions, SOG, and COG.  The Headers have the same
+// requirements as CPA.  Pairs whose closest point of approach has passed are
+// classified as NoEncounter.  COG is used in place of heading, so the result is
+// the encounter implied by the vessels' motion over ground.
+func (p *RecordPair) Encounter(h Headers) (Encounter, error) {
+	k1, k2, rx, ry, vx, vy, err := p.relativeMotion(h)
+	if err != nil {
+		return NoEncounter, fmt.Errorf("encounter: %w", err)
+	}
+	if _, hours := closestApproach(rx, ry, vx, vy); hours <= 0 {
+		return NoEncounter, nil
+	}
+
+	// Bearings of each vessel from the other, relative to the observer's course.
+	bearing := math.Atan2(rx, ry) * 180 / math.Pi
+	rel1 := math.Mod(bearing-k1.cog+720, 360)     // vessel 2 seen from vessel 1
+	rel2 := math.Mod(bearing+180-k2.cog+720, 360) // vessel 1 seen from vessel 2
+
+	ahead := func(rel float64) bool { return rel <= HeadOnSector || rel >= 360-HeadOnSector }
+	astern := func(rel float64) bool { return rel > 90+OvertakingSector && rel < 270-OvertakingSector }
+
+	switch {
+	case ahead(rel1) && ahead(rel2):
+		return HeadOn, nil
+	case astern(rel2):
+		return Overtaking, nil
+	case astern(rel1):
+		return Overtaken, nil
+	case rel1 < 180:
+		return CrossingGiveWay, nil
+	}
+	return CrossingStandOn, nil
+}
+
+// SetEncounter controls whether the COLREGS encounter class described by
+// EncounterFields is computed for each pair and written by Save.  Like SetCPA,
+// calling SetEncounter resets OutputHeaders.  Pairs with unavailable SOG or COG
+// values are written with an empty Encounter field.
+func (inter *Interactions) SetEncounter(on bool) {
+	inter.encounter = on
+	inter.resetOutputHeaders()
+}
@@ -0,0 +1,91 @@
+package ais
+
+import "testing"
+
+func TestRecordPair_Encounter(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec1    Record
+		rec2    Record
+		want    Encounter
+		wantErr bool
+	}{
+		{
+			name: "head on",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "0.0166667", "0.0", "10.0", "180.0"},
+			want: HeadOn,
+		},
+		{
+			name: "crossing from starboard",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "0.0333333", "0.0166667", "10.0", "270.0"},
+			want: CrossingGiveWay,
+		},
+		{
+			name: "crossing from port",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "0.0333333", "-0.0166667", "10.0", "90.0"},
+			want: CrossingStandOn,
+		},
+		{
+			name: "overtaking",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "15.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "0.0166667", "0.0", "10.0", "0.0"},
+			want: Overtaking,
+		},
+		{
+			name: "overtaken",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "-0.0166667", "0.0", "15.0", "0.0"},
+			want: Overtaken,
+		},
+		{
+			name: "opening",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "-0.0166667", "0.0", "10.0", "180.0"},
+			want: NoEncounter,
+		},
+		{
+			name:    "COG not available",
+			rec1:    Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "360.0"},
+			rec2:    Record{"2", "2017-12-01T00:00:00", "0.1", "0.0", "10.0", "180.0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &RecordPair{rec1: &tt.rec1, rec2: &tt.rec2}
+			got, err := p.Encounter(kinematicHeaders)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecordPair.Encounter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RecordPair.Encounter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractions_SetEncounter(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	inter.SetCPA(true)
+	inter.SetEncounter(true)
+	i, ok := inter.OutputHeaders.Contains("Encounter")
+	if !ok || i != 4 {
+		t.Fatalf("Interactions.SetEncounter(true) Encounter index = %d, %v, want 4, true", i, ok)
+	}
+
+	rec1 := Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0", "", "", "", "", "", "", "", "", "", ""}
+	rec2 := Record{"2", "2017-12-01T00:00:00", "0.0166667", "0.0", "10.0", "180.0", "", "", "", "", "", "", "", "", "", ""}
+	row, err := inter.row(Hash128{}, &RecordPair{rec1: &rec1, rec2: &rec2})
+	if err != nil {
+		t.Fatalf("Interactions.row() error = %v", err)
+	}
+	if row[i] != "head-on" {
+		t.Errorf("Interactions.row() Encounter = %q, want %q", row[i], "head-on")
+	}
+	if Encounter(42).String() != "Encounter(42)" {
+		t.Errorf("Encounter.String() = %q, want %q", Encounter(42).String(), "Encounter(42)")
+	}
+}
@@ -0,0 +1,55 @@
+package ais
+
+import "fmt"
+
+// ErrMissingHeader is the error returned when the Headers do not contain a field
+// that an operation requires.  It is usually wrapped with the name of the
+// operation, so use errors.As to test for it and recover the Field.
+type ErrMissingHeader struct {
+	Field string
+}
+
+func (e ErrMissingHeader) Error() string {
+	return "headers does not contain " + e.Field
+}
+
+// ErrParse is the error returned when a line of the input or a field of a Record
+// cannot be parsed.  Line is the line of the input and is zero when the Record is
+// no longer associated with a position in a file.  Field is the header of the
+// field that failed, and is empty for errors in the csv structure of a line.  Err
+// is the underlying error, such as a *strconv.NumError, and is available through
+// errors.Unwrap.  A caller scanning a RecordSet can test for ErrParse with
+// errors.As to skip bad lines and abort on any other error.
+type ErrParse struct {
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e ErrParse) Error() string {
+	msg := "unable to parse"
+	if e.Field != "" {
+		msg += " " + e.Field
+	}
+	if e.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", e.Line, msg)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e ErrParse) Unwrap() error { return e.Err }
+
+// require is ContainsMulti with an ErrMissingHeader for the first of fields
+// that h does not contain.
+func (h Headers) require(fields ...string) (map[string]HeaderMap, error) {
+	idx := make(map[string]HeaderMap, len(fields))
+	for _, f := range fields {
+		i, ok := h.Contains(f)
+		if !ok {
+			return nil, ErrMissingHeader{Field: f}
+		}
+		idx[f] = HeaderMap{Present: true, Idx: i}
+	}
+	return idx, nil
+}
@@ -0,0 +1,45 @@
+package ais
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestErrMissingHeader(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader("BaseDateTime,LAT,LON\n2017-12-01T00:00:00,1,2\n"), Headers{})
+	_, err := rs.UniqueVessels()
+	var missing ErrMissingHeader
+	if !errors.As(err, &missing) || missing.Field != "MMSI" {
+		t.Fatalf("RecordSet.UniqueVessels() error = %v, want ErrMissingHeader for MMSI", err)
+	}
+
+	_, err = NewTrack(Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT"}}, nil)
+	if !errors.As(err, &missing) || missing.Field != "LON" {
+		t.Errorf("NewTrack() error = %v, want ErrMissingHeader for LON", err)
+	}
+}
+
+func TestErrParse(t *testing.T) {
+	data := "MMSI,BaseDateTime,LAT,LON\n1,2017-12-01T00:00:00,1,2\n2,2017-12-01T00:00:00,1\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if _, err := rs.Read(); err != nil {
+		t.Fatalf("RecordSet.Read() error = %v", err)
+	}
+	_, err := rs.Read()
+	var perr ErrParse
+	if !errors.As(err, &perr) || perr.Line != 3 {
+		t.Fatalf("RecordSet.Read() error = %v, want ErrParse on line 3", err)
+	}
+
+	b := &Box{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180, LatIndex: 2, LonIndex: 3}
+	_, err = b.Match(&Record{"1", "2017-12-01T00:00:00", "north", "2"})
+	if !errors.As(err, &perr) || perr.Field != "LAT" {
+		t.Fatalf("Box.Match() error = %v, want ErrParse for LAT", err)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("Box.Match() error = %v, want to unwrap to *strconv.NumError", err)
+	}
+}
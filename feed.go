@@ -0,0 +1,200 @@
+package ais
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Default reconnect backoff limits for a Feed.
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// Feed is a client for a live stream of NMEA 0183 AIS sentences such as the
+// output of a networked AIS receiver or an aggregator like aishub.  For the "tcp"
+// network the Feed dials Address and reads sentences from the connection.  For the
+// "udp" network the Feed listens on Address for datagrams pushed by the receiver.
+// Sentences are decoded with a Decoder and the resulting Records are delivered on
+// the channel returned by Records.  Lost connections are retried with an
+// exponential backoff between MinBackoff and MaxBackoff, so a Feed is suitable for
+// long-running monitoring services.  The same Decoder is used across
+// reconnects, so the static data of each vessel is kept for the life of the
+// Feed, and only the parts of multi-part messages cut off by a lost connection
+// are dropped.
+type Feed struct {
+	Network    string        // "tcp" or "udp"
+	Address    string        // host:port to dial for tcp or listen on for udp
+	MinBackoff time.Duration // first delay before reconnecting
+	MaxBackoff time.Duration // longest delay between reconnect attempts
+
+	// OnError, if non-nil, is called with connection and decoding errors.  The Feed
+	// keeps running after every error until its context is canceled.
+	OnError func(error)
+
+	// Clock is passed to the Decoder for sentences without a tag block timestamp.
+	Clock func() time.Time
+
+	// Stations is passed to the Decoder to decode base station and aid to
+	// navigation reports.  See Decoder.Stations.
+	Stations bool
+
+	// Metrics, if non-nil, counts the Records delivered and the sentences that
+	// cannot be decoded.
+	Metrics *Metrics
+}
+
+// NewFeed returns a *Feed for the network and address with the default backoff
+// limits.
+func NewFeed(network, address string) *Feed {
+	return &Feed{
+		Network:    network,
+		Address:    address,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// Headers returns the Headers that describe the Records delivered by the Feed.
+func (f *Feed) Headers() Headers { return DefaultHeaders() }
+
+// Records starts the Feed and returns the channel on which decoded Records are
+// delivered.  The channel is closed after ctx is canceled.  Clients must keep
+// receiving from the channel because a slow consumer stalls the network reads.
+func (f *Feed) Records(ctx context.Context) <-chan *Record {
+	out := make(chan *Record)
+	go f.run(ctx, out)
+	return out
+}
+
+func (f *Feed) run(ctx context.Context, out chan<- *Record) {
+	defer close(out)
+	dec := NewDecoder(bytes.NewReader(nil))
+	if f.Clock != nil {
+		dec.Clock = f.Clock
+	}
+	dec.Stations = f.Stations
+
+	backoff := f.MinBackoff
+	if backoff <= 0 {
+		backoff = DefaultMinBackoff
+	}
+	for {
+		dec.dropFragments()
+		delivered, err := f.session(ctx, dec, out)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			f.report(fmt.Errorf("feed %s %s: %w", f.Network, f.Address, err))
+		}
+		if delivered {
+			backoff = f.MinBackoff
+			if backoff <= 0 {
+				backoff = DefaultMinBackoff
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if max := f.MaxBackoff; max > 0 && backoff > max {
+			backoff = max
+		} else if max <= 0 && backoff > DefaultMaxBackoff {
+			backoff = DefaultMaxBackoff
+		}
+	}
+}
+
+// session runs a single connection, decoding with dec, until it fails or ctx is
+// canceled.  It reports whether any Record was delivered so that the backoff can
+// be reset.
+func (f *Feed) session(ctx context.Context, dec *Decoder, out chan<- *Record) (bool, error) {
+	var conn io.Closer
+	var err error
+	switch f.Network {
+	case "tcp", "tcp4", "tcp6":
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, f.Network, f.Address)
+	case "udp", "udp4", "udp6":
+		conn, err = net.ListenPacket(f.Network, f.Address)
+	default:
+		return false, fmt.Errorf("unsupported network")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Unblock the pending read when the context is canceled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	delivered := false
+	deliver := func(line string) bool {
+		rec, err := dec.DecodeLine(line)
+		if err != nil {
+			f.Metrics.addParseError()
+			f.report(fmt.Errorf("feed %s %s: %w", f.Network, f.Address, err))
+			return true
+		}
+		if rec == nil {
+			return true
+		}
+		select {
+		case out <- rec:
+			f.Metrics.addRecord()
+			delivered = true
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if pc, ok := conn.(net.PacketConn); ok {
+		buf := make([]byte, 65536)
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				return delivered, err
+			}
+			s := bufio.NewScanner(bytes.NewReader(buf[:n]))
+			for s.Scan() {
+				if !deliver(s.Text()) {
+					return delivered, nil
+				}
+			}
+		}
+	}
+
+	s := bufio.NewScanner(conn.(io.Reader))
+	for s.Scan() {
+		if !deliver(s.Text()) {
+			return delivered, nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return delivered, err
+	}
+	return delivered, io.EOF
+}
+
+func (f *Feed) report(err error) {
+	if f.OnError != nil {
+		f.OnError(err)
+	}
+}
@@ -0,0 +1,170 @@
+package ais
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFeed_TCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	// Each connection sends one sentence and hangs up so the Feed must reconnect.
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(conn, "garbage\r\n%s\r\n", testSentence)
+			conn.Close()
+		}
+	}()
+
+	f := NewFeed("tcp", ln.Addr().String())
+	f.MinBackoff = time.Millisecond
+	f.MaxBackoff = 10 * time.Millisecond
+	f.Clock = testClock
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	recs := f.Records(ctx)
+	for i := 0; i < 3; i++ {
+		rec, ok := <-recs
+		if !ok {
+			t.Fatalf("Feed.Records() closed after %d records", i)
+		}
+		if !reflect.DeepEqual(*rec, testSentenceRec) {
+			t.Errorf("Feed.Records() = %v, want %v", *rec, testSentenceRec)
+		}
+	}
+
+	cancel()
+	for range recs {
+	}
+}
+
+func TestFeed_UDP(t *testing.T) {
+	// Find a free port for the Feed to listen on.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() error = %v", err)
+	}
+	addr := pc.LocalAddr().String()
+	pc.Close()
+
+	f := NewFeed("udp", addr)
+	f.MinBackoff = time.Millisecond
+	f.Clock = testClock
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	recs := f.Records(ctx)
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	// Datagrams sent before the Feed is listening are lost, so keep sending.
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case rec, ok := <-recs:
+			if !ok {
+				t.Fatal("Feed.Records() closed before delivering a record")
+			}
+			if !reflect.DeepEqual(*rec, testSentenceRec) {
+				t.Errorf("Feed.Records() = %v, want %v", *rec, testSentenceRec)
+			}
+			return
+		case <-tick.C:
+			fmt.Fprintf(conn, "%s\r\n", testSentence)
+		}
+	}
+}
+
+func TestFeed_UnsupportedNetwork(t *testing.T) {
+	errs := make(chan error, 1)
+	f := NewFeed("unix", "/nonexistent")
+	f.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	recs := f.Records(ctx)
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Error("Feed.OnError not called for unsupported network")
+	}
+	cancel()
+	if _, ok := <-recs; ok {
+		t.Error("Feed.Records() delivered a record for unsupported network")
+	}
+}
+
+func TestFeed_StaticAcrossReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	type5 := new(bitWriter).uint(6, 5).uint(2, 0).uint(30, 371798000).uint(2, 0).
+		uint(30, 9134270).text(42, "3FOF8").text(120, "EVER DIADEM").uint(8, 70).
+		uint(9, 225).uint(9, 70).uint(6, 1).uint(6, 31).uint(4, 1).
+		uint(4, 5).uint(5, 15).uint(5, 14).uint(6, 0).uint(8, 122).
+		text(120, "NEW YORK").uint(1, 0).uint(1, 0).armor()
+
+	// The first connection only sends the static report of the vessel and the
+	// second only its position.
+	go func() {
+		for n := 0; ; n++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if n == 0 {
+				fmt.Fprintf(conn, "%s\r\n%s\r\n", sentence("AIVDM,2,1,5,A,"+type5[:40]+",0"), sentence("AIVDM,2,2,5,A,"+type5[40:]+",2"))
+			} else {
+				fmt.Fprintf(conn, "%s\r\n", testSentence)
+			}
+			conn.Close()
+		}
+	}()
+
+	f := NewFeed("tcp", ln.Addr().String())
+	f.MinBackoff = time.Millisecond
+	f.MaxBackoff = 10 * time.Millisecond
+	f.Clock = testClock
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	recs := f.Records(ctx)
+	rec, ok := <-recs
+	if !ok {
+		t.Fatal("Feed.Records() closed before delivering a record")
+	}
+	want := append(Record{}, testSentenceRec...)
+	copy(want[7:], []string{"EVER DIADEM", "IMO9134270", "3FOF8", "70", "under way using engine", "295", "32", "12.2"})
+	if !reflect.DeepEqual(*rec, want) {
+		t.Errorf("Feed.Records() after reconnect = %v, want %v", *rec, want)
+	}
+
+	cancel()
+	for range recs {
+	}
+}
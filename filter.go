@@ -0,0 +1,380 @@
+package ais
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a compiled filter expression that implements the Matching interface.
+// Expressions compare the fields of a Record, named by their Headers, with
+// literals or with other fields, for example
+//
+//	SOG > 5 && VesselType in (70, 71, 80)
+//	(Status == "moored" || SOG < 0.5) && !(MMSI in (366940480))
+//
+// The comparison operators are ==, !=, <, <=, >, and >=, and a field may be
+// tested for membership in a list with in and not in.  Comparisons are combined
+// with &&, ||, and !, and grouped with parentheses.  Strings are quoted with
+// single or double quotes.  Field names that are not simple identifiers, such as
+// Distance(nm), are quoted with backquotes.
+//
+// Two values are compared as numbers when both parse as numbers and as strings
+// otherwise.  A comparison between a number literal and a field that does not
+// parse as a number, such as an empty SOG, is false for every operator except !=.
+type Filter struct {
+	expr  string
+	match func(rec *Record) bool
+}
+
+// CompileFilter parses expr against the Headers h and returns a Filter that can
+// be passed to Subset or applied to Records directly with Match.  Every field
+// named in expr must be present in h.
+func CompileFilter(expr string, h Headers) (*Filter, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+	p := &filterParser{toks: toks, h: h}
+	match, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("filter: unexpected %q at offset %d", t.text, t.pos)
+	}
+	return &Filter{expr: expr, match: match}, nil
+}
+
+// Match implements the Matching interface for a Filter.  Match never returns a
+// non-nil error.
+func (f *Filter) Match(rec *Record) (bool, error) {
+	return f.match(rec), nil
+}
+
+// String returns the source expression of the Filter.
+func (f *Filter) String() string { return f.expr }
+
+// Filter returns a pointer to a new RecordSet holding the Records that match the
+// filter expression expr, which is compiled once against the Headers of rs.  See
+// Filter for the expression syntax.  Like Subset, Filter returns ErrEmptySet when
+// no Records match.
+func (rs *RecordSet) Filter(expr string) (*RecordSet, error) {
+	f, err := CompileFilter(expr, rs.Headers())
+	if err != nil {
+		return nil, err
+	}
+	return rs.Subset(f)
+}
+
+type filterTokKind int
+
+const (
+	tokEOF filterTokKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp     // comparison operator
+	tokAnd    // &&
+	tokOr     // ||
+	tokNot    // !
+	tokIn     // in
+	tokNotKw  // not
+	tokLParen // (
+	tokRParen // )
+	tokComma  // ,
+)
+
+type filterTok struct {
+	kind filterTokKind
+	text string
+	pos  int
+}
+
+// lexFilter splits a filter expression into tokens.
+func lexFilter(s string) ([]filterTok, error) {
+	var toks []filterTok
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, filterTok{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, filterTok{tokRParen, ")", i})
+			i++
+		case c == ',':
+			toks = append(toks, filterTok{tokComma, ",", i})
+			i++
+		case strings.HasPrefix(s[i:], "&&"):
+			toks = append(toks, filterTok{tokAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(s[i:], "||"):
+			toks = append(toks, filterTok{tokOr, "||", i})
+			i += 2
+		case strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="),
+			strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			toks = append(toks, filterTok{tokOp, s[i : i+2], i})
+			i += 2
+		case c == '<' || c == '>':
+			toks = append(toks, filterTok{tokOp, string(c), i})
+			i++
+		case c == '!':
+			toks = append(toks, filterTok{tokNot, "!", i})
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote at offset %d", i)
+			}
+			kind := tokString
+			if c == '`' {
+				kind = tokIdent
+			}
+			toks = append(toks, filterTok{kind, s[i+1 : i+1+end], i})
+			i += end + 2
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				(s[j] >= '0' && s[j] <= '9') || ((s[j] == '-' || s[j] == '+') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			if _, err := strconv.ParseFloat(s[i:j], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", s[i:j], i)
+			}
+			toks = append(toks, filterTok{tokNumber, s[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			word := s[i:j]
+			switch word {
+			case "in":
+				toks = append(toks, filterTok{tokIn, word, i})
+			case "not":
+				toks = append(toks, filterTok{tokNotKw, word, i})
+			default:
+				toks = append(toks, filterTok{tokIdent, word, i})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return append(toks, filterTok{tokEOF, "", len(s)}), nil
+}
+
+// filterParser is a recursive descent parser that compiles tokens directly into
+// closures.
+type filterParser struct {
+	toks []filterTok
+	pos  int
+	h    Headers
+}
+
+func (p *filterParser) peek() filterTok { return p.toks[p.pos] }
+
+func (p *filterParser) next() filterTok {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) expect(kind filterTokKind, what string) error {
+	if t := p.next(); t.kind != kind {
+		return fmt.Errorf("expected %s at offset %d", what, t.pos)
+	}
+	return nil
+}
+
+// or := and { "||" and }
+func (p *filterParser) or() (func(*Record) bool, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(rec *Record) bool { return l(rec) || right(rec) }
+	}
+	return left, nil
+}
+
+// and := unary { "&&" unary }
+func (p *filterParser) and() (func(*Record) bool, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(rec *Record) bool { return l(rec) && right(rec) }
+	}
+	return left, nil
+}
+
+// unary := "!" unary | "(" or ")" | comparison
+func (p *filterParser) unary() (func(*Record) bool, error) {
+	switch p.peek().kind {
+	case tokNot:
+		p.next()
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(rec *Record) bool { return !inner(rec) }, nil
+	case tokLParen:
+		p.next()
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(tokRParen, ")")
+	}
+	return p.comparison()
+}
+
+// filterOperand is a field reference or a literal.
+type filterOperand struct {
+	idx   int // field index, or -1 for a literal
+	lit   string
+	num   float64
+	isNum bool // literal is a number
+}
+
+// value returns the string value of the operand and, if it parses, its number.
+func (o filterOperand) value(rec *Record) (s string, num float64, isNum bool) {
+	if o.idx < 0 {
+		return o.lit, o.num, o.isNum
+	}
+	if o.idx >= len(*rec) {
+		return "", 0, false
+	}
+	s = (*rec)[o.idx]
+	num, err := strconv.ParseFloat(s, 64)
+	return s, num, err == nil
+}
+
+func (p *filterParser) operand() (filterOperand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokIdent:
+		idx, ok := p.h.Contains(t.text)
+		if !ok {
+			return filterOperand{}, fmt.Errorf("headers do not contain %s", t.text)
+		}
+		return filterOperand{idx: idx}, nil
+	case tokNumber:
+		num, _ := strconv.ParseFloat(t.text, 64)
+		return filterOperand{idx: -1, lit: t.text, num: num, isNum: true}, nil
+	case tokString:
+		return filterOperand{idx: -1, lit: t.text}, nil
+	}
+	return filterOperand{}, fmt.Errorf("expected field or literal at offset %d", t.pos)
+}
+
+// comparison := operand op operand | operand ["not"] "in" "(" operand {"," operand} ")"
+func (p *filterParser) comparison() (func(*Record) bool, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+	switch t.kind {
+	case tokOp:
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		op := t.text
+		return func(rec *Record) bool { return compareOperands(left, right, op, rec) }, nil
+	case tokNotKw, tokIn:
+		negate := t.kind == tokNotKw
+		if negate {
+			if err := p.expect(tokIn, "in after not"); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect(tokLParen, "( after in"); err != nil {
+			return nil, err
+		}
+		var list []filterOperand
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, o)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(tokRParen, ")"); err != nil {
+			return nil, err
+		}
+		return func(rec *Record) bool {
+			for _, o := range list {
+				if compareOperands(left, o, "==", rec) {
+					return !negate
+				}
+			}
+			return negate
+		}, nil
+	}
+	return nil, fmt.Errorf("expected comparison operator or in at offset %d", t.pos)
+}
+
+// compareOperands applies op to the values of the two operands for rec.
+func compareOperands(left, right filterOperand, op string, rec *Record) bool {
+	ls, ln, lnum := left.value(rec)
+	rs, rn, rnum := right.value(rec)
+
+	var cmp int
+	switch {
+	case lnum && rnum:
+		switch {
+		case ln < rn:
+			cmp = -1
+		case ln > rn:
+			cmp = 1
+		}
+	case (left.idx < 0 && left.isNum) || (right.idx < 0 && right.isNum):
+		return op == "!=" // a number literal never matches a non-numeric field
+	default:
+		cmp = strings.Compare(ls, rs)
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0 // ">="
+}
@@ -0,0 +1,74 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileFilter(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "SOG", "VesselType", "Status", "Distance(nm)"}}
+	rec := Record{"366940480", "7.5", "70", "under way", ""}
+
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "SOG > 5", want: true},
+		{expr: "SOG >= 7.5 && SOG <= 7.5", want: true},
+		{expr: "SOG < 5", want: false},
+		{expr: "SOG > 5 && VesselType in (70, 71, 80)", want: true},
+		{expr: "VesselType not in (70,71)", want: false},
+		{expr: "Status == 'under way'", want: true},
+		{expr: `Status != "moored" && !(MMSI == 366940480)`, want: false},
+		{expr: "SOG < 1 || (VesselType == 70 && MMSI > 300000000)", want: true},
+		{expr: "`Distance(nm)` < 1", want: false},
+		{expr: "`Distance(nm)` != 1", want: true},
+		{expr: "`Distance(nm)` == ''", want: true},
+		{expr: "SOG > -1.5e1", want: true},
+		{expr: "Draft > 5", wantErr: true},
+		{expr: "SOG >", wantErr: true},
+		{expr: "SOG > 5 &&", wantErr: true},
+		{expr: "(SOG > 5", wantErr: true},
+		{expr: "SOG > 5 SOG", wantErr: true},
+		{expr: "VesselType in 70", wantErr: true},
+		{expr: "Status == 'moored", wantErr: true},
+		{expr: "SOG ~ 5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := CompileFilter(tt.expr, h)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, _ := f.Match(&rec)
+			if got != tt.want {
+				t.Errorf("Filter.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordSet_Filter(t *testing.T) {
+	data := "MMSI,SOG,VesselType\n1,0.0,70\n2,12.1,70\n3,15.0,30\n4,9.9,80\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs2, err := rs.Filter("SOG > 5 && VesselType in (70, 71, 80)")
+	if err != nil {
+		t.Fatalf("RecordSet.Filter() error = %v", err)
+	}
+	var got []string
+	for rs2.Next() {
+		got = append(got, (*rs2.Record())[0])
+	}
+	if strings.Join(got, ",") != "2,4" {
+		t.Errorf("RecordSet.Filter() matched MMSIs %v, want [2 4]", got)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if _, err := rs.Filter("SOG > 100"); err != ErrEmptySet {
+		t.Errorf("RecordSet.Filter() error = %v, want ErrEmptySet", err)
+	}
+}
@@ -0,0 +1,196 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FishingFields are the Headers of the RecordSet returned by
+// RecordSet.Fishing.  Duration is in minutes.
+const FishingFields = "MMSI,Start,End,Duration,Records,MeanSOG,Straightness"
+
+// FishingRules configures the classification of fishing activity.  A vessel
+// towing gear, hauling, or setting moves slowly and turns often, so its speed
+// stays in a band of a few knots and its track folds back on itself, while a
+// vessel in transit is faster and goes straight.
+type FishingRules struct {
+	MinSOG, MaxSOG  float64       // knots, the band of fishing speeds
+	MaxOutOfBand    int           // consecutive reports outside the band tolerated within a segment
+	MaxStraightness float64       // greatest ratio of the distance made good to the distance sailed
+	MinDuration     time.Duration // shortest segment reported
+	MaxGap          time.Duration // a gap between reports longer than this ends a segment, zero for no limit
+	AllTypes        bool          // classify every vessel rather than those with a fishing VesselType
+}
+
+// DefaultFishingRules returns FishingRules for trawlers and other mobile gear:
+// speeds of 2 to 5 knots with no more than two reports outside that band in a
+// row, a distance made good of at most half the distance sailed, for at least
+// an hour, and no more than 30 minutes between reports.
+func DefaultFishingRules() FishingRules {
+	return FishingRules{
+		MinSOG:          2,
+		MaxSOG:          5,
+		MaxOutOfBand:    2,
+		MaxStraightness: 0.5,
+		MinDuration:     time.Hour,
+		MaxGap:          30 * time.Minute,
+	}
+}
+
+// FishingSegment is a period of likely fishing activity by a vessel.
+type FishingSegment struct {
+	MMSI         string
+	Start, End   time.Time
+	Records      int
+	MeanSOG      float64 // knots, of the reports in the band
+	Straightness float64 // distance made good divided by distance sailed, zero for a vessel that returned to its start
+}
+
+// Duration returns the time elapsed between the first and last reports of the
+// segment.
+func (s FishingSegment) Duration() time.Duration { return s.End.Sub(s.Start) }
+
+// Fishing returns the segments of likely fishing activity of the vessel.  A
+// segment is a run of reports with a SOG in [rules.MinSOG, rules.MaxSOG],
+// tolerating up to rules.MaxOutOfBand reports in a row outside it, that lasts at
+// least rules.MinDuration and loiters, with a Straightness of no more than
+// rules.MaxStraightness.  A run at fishing speed in a straight line, such as a
+// slow transit, is not reported.  Unless rules.AllTypes is set only a vessel
+// with a fishing VesselType is classified, and the Track of any other vessel
+// has no segments.  The segments are heuristic, suited to estimating fishing
+// effort over a region rather than to judging a single vessel.  The Headers
+// must contain SOG, and VesselType unless rules.AllTypes is set.
+func (t *Track) Fishing(rules FishingRules) ([]FishingSegment, error) {
+	if rules.MaxSOG <= rules.MinSOG || rules.MinSOG < 0 {
+		return nil, fmt.Errorf("track fishing: SOG band [%v, %v] is empty", rules.MinSOG, rules.MaxSOG)
+	}
+	sogIndex, ok := t.h.Contains("SOG")
+	if !ok {
+		return nil, fmt.Errorf("track fishing: %w", ErrMissingHeader{Field: "SOG"})
+	}
+	if t.Len() == 0 {
+		return nil, nil
+	}
+	if !rules.AllTypes {
+		vt, err := t.data[0].VesselType(t.h)
+		if err != nil {
+			return nil, fmt.Errorf("track fishing: %w", err)
+		}
+		if vt.Category() != Fishing {
+			return nil, nil
+		}
+	}
+
+	var segs []FishingSegment
+	var cur FishingSegment
+	var sumSOG, sailed float64
+	var inBand, out int // reports of cur in the band, and outside it since the last in it
+	var lat0, lon0, latN, lonN float64
+	end := func() {
+		if inBand > 0 && cur.Duration() >= rules.MinDuration {
+			if sailed > 0 {
+				cur.Straightness = Haversine(lat0, lon0, latN, lonN) / sailed
+			}
+			if cur.Straightness <= rules.MaxStraightness {
+				cur.MeanSOG = sumSOG / float64(inBand)
+				segs = append(segs, cur)
+			}
+		}
+		cur = FishingSegment{MMSI: t.MMSI}
+		sumSOG, sailed, inBand, out = 0, 0, 0, 0
+	}
+	end()
+
+	for i, rec := range t.data {
+		if inBand > 0 && rules.MaxGap > 0 && t.times[i].Sub(cur.End) > rules.MaxGap {
+			end()
+		}
+		sog, err := rec.ParseFloat(sogIndex)
+		if err != nil || math.IsNaN(sog) || sog < rules.MinSOG || sog > rules.MaxSOG {
+			if inBand == 0 {
+				continue
+			}
+			if out++; out > rules.MaxOutOfBand {
+				end()
+			}
+			continue
+		}
+		lat, lon, err := t.position(i)
+		if err != nil {
+			return nil, fmt.Errorf("track fishing: %w", err)
+		}
+		if inBand == 0 {
+			cur.Start = t.times[i]
+			lat0, lon0 = lat, lon
+		} else {
+			sailed += Haversine(latN, lonN, lat, lon)
+		}
+		// The reports outside the band are counted once the run goes on.
+		cur.Records += out + 1
+		cur.End = t.times[i]
+		latN, lonN = lat, lon
+		sumSOG += sog
+		inBand++
+		out = 0
+	}
+	end()
+	return segs, nil
+}
+
+// Fishing returns a fishing activity report with the FishingFields Headers and
+// one Record for every FishingSegment of every vessel in the RecordSet, ordered
+// by MMSI and then Start.  The rules are the same as for Track.Fishing.
+// Fishing holds the Tracks of every vessel in memory and consumes the receiver.
+func (rs *RecordSet) Fishing(rules FishingRules) (*RecordSet, error) {
+	required := []string{"SOG"}
+	if !rules.AllTypes {
+		required = append(required, "VesselType")
+	}
+	if _, err := rs.Headers().require(required...); err != nil {
+		return nil, fmt.Errorf("fishing: %w", err)
+	}
+	tracks, err := rs.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("fishing: %w", err)
+	}
+	mmsis := make([]string, 0, len(tracks))
+	for mmsi := range tracks {
+		mmsis = append(mmsis, mmsi)
+	}
+	sort.Strings(mmsis)
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(Headers{Fields: strings.Split(FishingFields, ",")})
+	written := 0
+	for _, mmsi := range mmsis {
+		segs, err := tracks[mmsi].Fishing(rules)
+		if err != nil {
+			return nil, fmt.Errorf("fishing: %w", err)
+		}
+		for _, s := range segs {
+			rs2.Write(Record{
+				s.MMSI,
+				s.Start.Format(TimeLayout),
+				s.End.Format(TimeLayout),
+				fmt.Sprintf("%.1f", s.Duration().Minutes()),
+				strconv.Itoa(s.Records),
+				fmt.Sprintf("%.1f", s.MeanSOG),
+				fmt.Sprintf("%.2f", s.Straightness),
+			})
+			written++
+			if written%flushThreshold == 0 {
+				if err := rs2.Flush(); err != nil {
+					return nil, fmt.Errorf("fishing: csv flush error: %w", err)
+				}
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("fishing: csv flush error: %w", err)
+	}
+	return rs2, nil
+}
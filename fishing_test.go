@@ -0,0 +1,116 @@
+package ais
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fishingData returns reports every 10 minutes of a vessel of the given type
+// that tows back and forth at 3 knots for two hours, with one report at 6
+// knots, then steams off at 10 knots, and of a fishing vessel that makes a slow
+// straight transit.
+func fishingData(vesselType string) string {
+	var b strings.Builder
+	b.WriteString("MMSI,BaseDateTime,LAT,LON,SOG,VesselType\n")
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= 12; i++ {
+		sog := "3.0"
+		if i == 5 {
+			sog = "6.0"
+		}
+		lon := -76.0 + 0.01*float64(i%4) // tows east three legs, then back
+		if i%4 == 3 {
+			lon = -76.0 + 0.01
+		}
+		fmt.Fprintf(&b, "300000000,%s,36.50000,%.5f,%s,%s\n", start.Add(time.Duration(i)*10*time.Minute).Format(TimeLayout), lon, sog, vesselType)
+	}
+	for i := 13; i <= 16; i++ {
+		fmt.Fprintf(&b, "300000000,%s,36.50000,%.5f,10.0,%s\n", start.Add(time.Duration(i)*10*time.Minute).Format(TimeLayout), -75.9+0.03*float64(i), vesselType)
+	}
+	for i := 0; i <= 12; i++ {
+		fmt.Fprintf(&b, "300000001,%s,37.00000,%.5f,3.0,30\n", start.Add(time.Duration(i)*10*time.Minute).Format(TimeLayout), -76.0+0.01*float64(i))
+	}
+	return b.String()
+}
+
+func TestTrack_Fishing(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(fishingData("30")), Headers{})
+	tracks, err := rs.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	segs, err := tracks["300000000"].Fishing(DefaultFishingRules())
+	if err != nil {
+		t.Fatalf("Track.Fishing() error = %v", err)
+	}
+	if len(segs) != 1 {
+		t.Fatalf("Track.Fishing() returned %d segments, want 1: %+v", len(segs), segs)
+	}
+	s := segs[0]
+	if s.Records != 13 || s.Duration() != 2*time.Hour || s.MeanSOG != 3 || s.Straightness > 0.5 {
+		t.Errorf("Track.Fishing() = %+v", s)
+	}
+
+	// A slow transit in a straight line is not fishing.
+	segs, err = tracks["300000001"].Fishing(DefaultFishingRules())
+	if err != nil || len(segs) != 0 {
+		t.Errorf("Track.Fishing() straight transit = %+v, %v, want none", segs, err)
+	}
+
+	rules := DefaultFishingRules()
+	rules.MaxOutOfBand = 0
+	segs, err = tracks["300000000"].Fishing(rules)
+	if err != nil || len(segs) != 1 || segs[0].Start.Format(TimeLayout) != "2017-12-01T01:00:00" {
+		t.Errorf("Track.Fishing() MaxOutOfBand 0 = %+v, %v, want the segment after the 6 knot report", segs, err)
+	}
+
+	rules = DefaultFishingRules()
+	rules.MinSOG = 5
+	if _, err := tracks["300000000"].Fishing(rules); err == nil {
+		t.Error("Track.Fishing() expected error for empty SOG band")
+	}
+}
+
+func TestTrack_FishingVesselType(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(fishingData("70")), Headers{})
+	tracks, err := rs.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	segs, err := tracks["300000000"].Fishing(DefaultFishingRules())
+	if err != nil || len(segs) != 0 {
+		t.Errorf("Track.Fishing() cargo = %+v, %v, want none", segs, err)
+	}
+	rules := DefaultFishingRules()
+	rules.AllTypes = true
+	segs, err = tracks["300000000"].Fishing(rules)
+	if err != nil || len(segs) != 1 {
+		t.Errorf("Track.Fishing() AllTypes = %+v, %v, want one segment", segs, err)
+	}
+}
+
+func TestRecordSet_Fishing(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(fishingData("30")), Headers{})
+	report, err := rs.Fishing(DefaultFishingRules())
+	if err != nil {
+		t.Fatalf("RecordSet.Fishing() error = %v", err)
+	}
+	if !report.Headers().Equals(Headers{Fields: strings.Split(FishingFields, ",")}) {
+		t.Errorf("RecordSet.Fishing() headers = %v", report.Headers())
+	}
+	rec, err := report.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "300000000,2017-12-01T00:00:00,2017-12-01T02:00:00,120.0,13,3.0"
+	if got := strings.Join((*rec)[:6], ","); got != want {
+		t.Errorf("RecordSet.Fishing() = %s, want %s", got, want)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT,LON,SOG\n"), Headers{})
+	if _, err := rs.Fishing(DefaultFishingRules()); err == nil {
+		t.Error("RecordSet.Fishing() expected error for missing VesselType")
+	}
+}
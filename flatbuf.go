@@ -0,0 +1,314 @@
+package ais
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// fbField is one slot of a flatbuffer table under construction.  Exactly one of
+// scalar or ref is set for a present field.
+type fbField struct {
+	scalar []byte      // little endian value
+	ref    interface{} // *fbTable, string, []*fbTable, or fbStructs
+}
+
+// fbTable is a flatbuffer table under construction indexed by field slot.  It
+// supports the subset of the FlatBuffers encoding needed to write Arrow IPC
+// metadata.
+type fbTable []fbField
+
+// fbStructs is a vector of n inline structs already encoded in data.
+type fbStructs struct {
+	align, n int
+	data     []byte
+}
+
+func (t *fbTable) set(slot int, f fbField) {
+	for len(*t) <= slot {
+		*t = append(*t, fbField{})
+	}
+	(*t)[slot] = f
+}
+
+// scalar sets slot to the fixed size value v, which must be a bool or a sized
+// integer type.
+func (t *fbTable) scalar(slot int, v interface{}) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, v)
+	t.set(slot, fbField{scalar: buf.Bytes()})
+}
+
+// ref sets slot to an offset to the *fbTable, string, []*fbTable, or fbStructs v.
+func (t *fbTable) ref(slot int, v interface{}) { t.set(slot, fbField{ref: v}) }
+
+// fbBuilder serializes fbTables front to back.  Every object is written before
+// the objects it references so that all offsets point forward as the format
+// requires, and each vtable is written immediately before its table.
+type fbBuilder struct {
+	buf []byte
+}
+
+// finish returns the encoded buffer with root as its root table, padded to a
+// multiple of eight bytes.
+func (b *fbBuilder) finish(root *fbTable) []byte {
+	b.buf = make([]byte, 4)
+	pos := b.table(root)
+	binary.LittleEndian.PutUint32(b.buf, uint32(pos))
+	b.pad(8)
+	return b.buf
+}
+
+func (b *fbBuilder) pad(align int) {
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) u32(v uint32) {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], v)
+	b.buf = append(b.buf, tmp[:]...)
+}
+
+func (b *fbBuilder) u16(v uint16) {
+	var tmp [2]byte
+	binary.LittleEndian.PutUint16(tmp[:], v)
+	b.buf = append(b.buf, tmp[:]...)
+}
+
+// table writes the vtable and table for t followed by the objects it references
+// and returns the position of the table.
+func (b *fbBuilder) table(t *fbTable) int {
+	size := func(f fbField) int {
+		if f.ref != nil {
+			return 4
+		}
+		return len(f.scalar)
+	}
+	var slots []int
+	for slot, f := range *t {
+		if f.ref != nil || f.scalar != nil {
+			slots = append(slots, slot)
+		}
+	}
+	// Larger fields first keeps every field naturally aligned.
+	sort.SliceStable(slots, func(i, j int) bool { return size((*t)[slots[i]]) > size((*t)[slots[j]]) })
+
+	offsets := make([]int, len(*t))
+	off, align := 4, 4
+	for _, slot := range slots {
+		n := size((*t)[slot])
+		for off%n != 0 {
+			off++
+		}
+		offsets[slot] = off
+		off += n
+		if n > align {
+			align = n
+		}
+	}
+
+	b.pad(2)
+	vt := len(b.buf)
+	b.u16(uint16(4 + 2*len(*t)))
+	b.u16(uint16(off))
+	for _, o := range offsets {
+		b.u16(uint16(o))
+	}
+	b.pad(align)
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, off)...)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(pos-vt))
+
+	for _, slot := range slots {
+		if f := (*t)[slot]; f.scalar != nil {
+			copy(b.buf[pos+offsets[slot]:], f.scalar)
+		}
+	}
+	for _, slot := range slots {
+		if f := (*t)[slot]; f.ref != nil {
+			at := pos + offsets[slot]
+			child := b.object(f.ref)
+			binary.LittleEndian.PutUint32(b.buf[at:], uint32(child-at))
+		}
+	}
+	return pos
+}
+
+// object writes a referenced object and returns its position.
+func (b *fbBuilder) object(v interface{}) int {
+	switch v := v.(type) {
+	case *fbTable:
+		return b.table(v)
+	case string:
+		b.pad(4)
+		pos := len(b.buf)
+		b.u32(uint32(len(v)))
+		b.buf = append(b.buf, v...)
+		b.buf = append(b.buf, 0)
+		return pos
+	case []*fbTable:
+		b.pad(4)
+		pos := len(b.buf)
+		b.u32(uint32(len(v)))
+		b.buf = append(b.buf, make([]byte, 4*len(v))...)
+		for i, t := range v {
+			at := pos + 4 + 4*i
+			child := b.table(t)
+			binary.LittleEndian.PutUint32(b.buf[at:], uint32(child-at))
+		}
+		return pos
+	case fbStructs:
+		for (len(b.buf)+4)%v.align != 0 {
+			b.buf = append(b.buf, 0)
+		}
+		pos := len(b.buf)
+		b.u32(uint32(v.n))
+		b.buf = append(b.buf, v.data...)
+		return pos
+	}
+	panic(fmt.Sprintf("flatbuffer: unsupported object %T", v))
+}
+
+// fbBuf is an encoded flatbuffer being read.  Reads outside of the buffer record
+// an error in err and return zero values, so a malformed buffer can be decoded
+// without checking every access and the error tested once at the end.
+type fbBuf struct {
+	b   []byte
+	err error
+}
+
+func (f *fbBuf) check(pos, n int) bool {
+	if pos < 0 || n < 0 || pos+n > len(f.b) {
+		if f.err == nil {
+			f.err = fmt.Errorf("flatbuffer: offset %d is out of range", pos)
+		}
+		return false
+	}
+	return true
+}
+
+func (f *fbBuf) u8(pos int) uint8 {
+	if !f.check(pos, 1) {
+		return 0
+	}
+	return f.b[pos]
+}
+
+func (f *fbBuf) u16(pos int) uint16 {
+	if !f.check(pos, 2) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(f.b[pos:])
+}
+
+func (f *fbBuf) u32(pos int) uint32 {
+	if !f.check(pos, 4) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(f.b[pos:])
+}
+
+func (f *fbBuf) u64(pos int) uint64 {
+	if !f.check(pos, 8) {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(f.b[pos:])
+}
+
+// root returns the root table of the buffer.
+func (f *fbBuf) root() fbReader { return fbReader{f, int(f.u32(0))} }
+
+// fbReader reads the fields of a table at pos.
+type fbReader struct {
+	f   *fbBuf
+	pos int
+}
+
+// field returns the position of slot, or zero when the field is not present.
+func (r fbReader) field(slot int) int {
+	vt := r.pos - int(int32(r.f.u32(r.pos)))
+	if 4+2*slot >= int(r.f.u16(vt)) {
+		return 0
+	}
+	off := int(r.f.u16(vt + 4 + 2*slot))
+	if off == 0 {
+		return 0
+	}
+	return r.pos + off
+}
+
+func (r fbReader) u8(slot int) uint8 {
+	if p := r.field(slot); p != 0 {
+		return r.f.u8(p)
+	}
+	return 0
+}
+
+func (r fbReader) i16(slot int) int16 {
+	if p := r.field(slot); p != 0 {
+		return int16(r.f.u16(p))
+	}
+	return 0
+}
+
+func (r fbReader) i32(slot int) int32 {
+	if p := r.field(slot); p != 0 {
+		return int32(r.f.u32(p))
+	}
+	return 0
+}
+
+func (r fbReader) i64(slot int) int64 {
+	if p := r.field(slot); p != 0 {
+		return int64(r.f.u64(p))
+	}
+	return 0
+}
+
+// deref follows the offset stored at pos.
+func (r fbReader) deref(pos int) int { return pos + int(r.f.u32(pos)) }
+
+// table returns the table referenced by slot.
+func (r fbReader) table(slot int) (fbReader, bool) {
+	p := r.field(slot)
+	if p == 0 {
+		return fbReader{}, false
+	}
+	return fbReader{r.f, r.deref(p)}, true
+}
+
+func (r fbReader) str(slot int) string {
+	p := r.field(slot)
+	if p == 0 {
+		return ""
+	}
+	p = r.deref(p)
+	n := int(r.f.u32(p))
+	if !r.f.check(p+4, n) {
+		return ""
+	}
+	return string(r.f.b[p+4 : p+4+n])
+}
+
+// vector returns the position of the first element and the length of the vector
+// referenced by slot.
+func (r fbReader) vector(slot int) (start, n int) {
+	p := r.field(slot)
+	if p == 0 {
+		return 0, 0
+	}
+	p = r.deref(p)
+	n = int(r.f.u32(p))
+	if !r.f.check(p+4, n) { // every element is at least one byte
+		return 0, 0
+	}
+	return p + 4, n
+}
+
+// elem returns the i'th table of a vector of tables starting at start.
+func (r fbReader) elem(start, i int) fbReader {
+	return fbReader{r.f, r.deref(start + 4*i)}
+}
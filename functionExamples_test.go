@@ -2,6 +2,7 @@
 package ais_test
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -31,6 +32,37 @@ func ExampleRecord_Distance() {
 	// The ships are 4.8nm away from one another.
 }
 
+// Example demonstrates DistanceTo in each DistanceUnit and its error for a
+// position that is not available.
+func ExampleRecord_DistanceTo() {
+	h := strings.Split("MMSI,BaseDateTime,LAT,LON,SOG,COG,Heading,VesselName,IMO,CallSign,VesselType,Status,Length,Width,Draft,Cargo", ",")
+	headers := ais.Headers{Fields: h}
+
+	data1 := strings.Split("477307900,2017-12-01T00:00:03,36.90512,-76.32652,0.0,131.0,352.0,FIRST,IMO9739666,VRPJ6,1004,moored,337,,,", ",")
+	data2 := strings.Split("477307902,2017-12-01T00:00:03,36.91512,-76.22652,2.3,311.0,182.0,SECOND,IMO9739800,XHYSF,,underway using engines,337,,,", ",")
+	rec1 := ais.Record(data1)
+	rec2 := ais.Record(data2)
+
+	for _, u := range []ais.DistanceUnit{ais.NauticalMiles, ais.Kilometers, ais.Meters} {
+		d, err := rec1.DistanceTo(rec2, headers, u)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%.1f %v\n", d, u)
+	}
+
+	data2[2] = "91"
+	if _, err := rec1.DistanceTo(rec2, headers, ais.NauticalMiles); errors.Is(err, ais.ErrPositionNotAvailable) {
+		fmt.Println("The second position is not available.")
+	}
+
+	// Output:
+	// 4.8 nm
+	// 9.0 km
+	// 8960.2 m
+	// The second position is not available.
+}
+
 func ExampleRecord_ParseTime() {
 	h := strings.Split("MMSI,BaseDateTime,LAT,LON,SOG,COG,Heading,VesselName,IMO,CallSign,VesselType,Status,Length,Width,Draft,Cargo", ",")
 	data := strings.Split("477307900,2017-12-01T00:00:03,36.90512,-76.32652,0.0,131.0,352.0,FIRST,IMO9739666,VRPJ6,1004,moored,337,,,", ",")
@@ -0,0 +1,97 @@
+//go:build go1.18
+// +build go1.18
+
+package ais
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// FuzzRecordRoundTrip checks that every Record written by a RecordSet is read
+// back unchanged, whatever its fields hold.
+func FuzzRecordRoundTrip(f *testing.F) {
+	f.Add("477307901", "MAERSK, KENSINGTON", `"QUOTED"`, byte(','))
+	f.Add("#1", "TWO\nLINES", "\xff\xfe", byte(';'))
+	f.Add("", "", "", byte('\t'))
+	f.Fuzz(func(t *testing.T, a, b, c string, comma byte) {
+		if validDelimiter(rune(comma)) != nil || comma >= 0x80 {
+			return
+		}
+		// encoding/csv reads \r\n inside a quoted field as \n.
+		for _, s := range []string{a, b, c} {
+			if strings.Contains(s, "\r\n") || strings.HasSuffix(s, "\r") {
+				return
+			}
+		}
+		rec := Record{a, b, c}
+		var buf bytes.Buffer
+		w := newRecordWriter(&buf)
+		w.Comma = rune(comma)
+		w.Write(rec)
+		w.Flush()
+		rs, err := NewRecordSetFromReader(&buf, Headers{Fields: []string{"A", "B", "C"}}, Delimiter(rune(comma)), LazyQuotes(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rs.Read()
+		if err != nil {
+			t.Fatalf("Read() error = %v for %q", err, rec.Data())
+		}
+		if !reflect.DeepEqual(*got, rec) {
+			t.Errorf("round trip = %q, want %q", *got, rec)
+		}
+		if _, err := rs.Read(); err != io.EOF {
+			t.Errorf("second Read() error = %v, want io.EOF", err)
+		}
+	})
+}
+
+// FuzzMergeSortRoundTrip checks that MergeRecordSets and SortExternal write
+// every Record so that it is read back unchanged, as FuzzRecordRoundTrip does for
+// a RecordSet.
+func FuzzMergeSortRoundTrip(f *testing.F) {
+	f.Add("#VRPJ6", "MAERSK, KENSINGTON", byte(','))
+	f.Add("", "TWO\nLINES", byte(';'))
+	f.Add(" A", `"`, byte('\t'))
+	f.Fuzz(func(t *testing.T, a, b string, comma byte) {
+		if validDelimiter(rune(comma)) != nil || comma >= 0x80 {
+			return
+		}
+		for _, s := range []string{a, b} {
+			if strings.Contains(s, "\r\n") || strings.HasSuffix(s, "\r") {
+				return
+			}
+		}
+		h := Headers{Fields: []string{"CallSign", "BaseDateTime", "VesselName"}}
+		recs := []Record{{a, "2017-12-01T00:00:02", b}, {b, "2017-12-01T00:00:01", a}}
+		want := []Record{recs[1], recs[0]}
+		for name, got := range mergeSortRoundTrip(t, h, recs, rune(comma), QuoteMinimal) {
+			if name == "merge" {
+				if !reflect.DeepEqual(got, recs) {
+					t.Errorf("%s round trip = %q, want %q", name, got, recs)
+				}
+			} else if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s round trip = %q, want %q", name, got, want)
+			}
+		}
+	})
+}
+
+// FuzzReadRecordSet checks that reading arbitrary input returns errors rather
+// than panicking.
+func FuzzReadRecordSet(f *testing.F) {
+	f.Add([]byte(testString))
+	f.Add([]byte(byteOrderMark + "MMSI;LAT\r\n1;\"2\r\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rs, err := NewRecordSetFromReader(bytes.NewReader(data), Headers{})
+		if err != nil {
+			return
+		}
+		for rs.Next() {
+		}
+	})
+}
@@ -0,0 +1,353 @@
+// Package gen generates synthetic AIS data for benchmarks and for tests that
+// cannot ship real data.  Each vessel sails a great-circle route from a random
+// start in an area at a constant speed, reporting at a fixed interval with
+// noise added to its position, speed, and course.  Pairs of vessels are set on
+// routes that cross at a known time and place, so the encounters in the data
+// are known in advance.  The same Config, including its Seed, always generates
+// the same Records, so benchmarks built on it are reproducible anywhere.
+//
+// A day of traffic for 500 vessels in Chesapeake Bay reporting every 30
+// seconds, a tenth of them in encounters, is generated with
+//
+//	cfg := gen.DefaultConfig()
+//	cfg.Vessels = 500
+//	cfg.EncounterRate = 0.1
+//	g, err := gen.New(cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	rs, err := g.RecordSet()
+package gen
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FATHOM5/ais"
+)
+
+// earthRadius is the mean radius of the earth in nautical miles.
+const earthRadius = 3440.065
+
+// firstMMSI is the MMSI of the first generated vessel, in the block of MIDs
+// allocated to the United States.
+const firstMMSI = 366900000
+
+// vesselTypes are the VesselTypes given to the generated vessels in turn.
+var vesselTypes = []string{"70", "80", "60", "30", "52", "37", "1004", "1024"}
+
+// Config configures a Generator.
+type Config struct {
+	Start    time.Time     // time of the first reports
+	Duration time.Duration // time span of the reports
+	Interval time.Duration // time between the reports of a vessel
+
+	Vessels int     // number of vessels
+	Area    ais.Box // area of the start of every route and the encounters, LatIndex and LonIndex are unused
+
+	MinSOG, MaxSOG float64 // knots, range of the constant speed of each vessel
+
+	PositionNoise float64 // nm, standard deviation of the noise added to each position
+	SOGNoise      float64 // knots, standard deviation of the noise added to each SOG
+	COGNoise      float64 // degrees, standard deviation of the noise added to each COG
+
+	// EncounterRate is the fraction of vessels, in [0, 1], set on routes that
+	// cross the route of another vessel.  Each encounter takes two of them.
+	EncounterRate float64
+
+	// Seed seeds the random numbers of the Generator.
+	Seed int64
+}
+
+// DefaultConfig returns a Config for one day of traffic of 100 vessels in
+// Chesapeake Bay sailing at 5 to 20 knots and reporting every 30 seconds, with
+// GPS grade noise of 10 m and a tenth of the vessels in encounters.
+func DefaultConfig() Config {
+	return Config{
+		Start:         time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC),
+		Duration:      24 * time.Hour,
+		Interval:      30 * time.Second,
+		Vessels:       100,
+		Area:          ais.Box{MinLat: 36.8, MaxLat: 39.6, MinLon: -76.6, MaxLon: -75.9},
+		MinSOG:        5,
+		MaxSOG:        20,
+		PositionNoise: 0.005,
+		SOGNoise:      0.1,
+		COGNoise:      1,
+		EncounterRate: 0.1,
+		Seed:          1,
+	}
+}
+
+// Encounter is a crossing of the routes of two vessels set up by a Generator.
+// Without noise the vessels are at the same place at Time.
+type Encounter struct {
+	MMSI1, MMSI2 string // MMSI1 sorts before MMSI2
+	Time         time.Time
+	Lat, Lon     float64
+}
+
+// vessel is the route of one generated vessel.
+type vessel struct {
+	mmsi       string
+	vesselType string
+	lat, lon   float64       // position at Config.Start
+	bearing    float64       // initial bearing of the great circle, degrees
+	sog        float64       // knots
+	phase      time.Duration // offset of the reports of the vessel from the report ticks
+}
+
+// Generator generates the Records described by a Config.  Create one with New.
+type Generator struct {
+	cfg        Config
+	vessels    []vessel
+	encounters []Encounter
+}
+
+// New returns a Generator for cfg, with the routes of every vessel chosen.
+func New(cfg Config) (*Generator, error) {
+	switch {
+	case cfg.Duration <= 0 || cfg.Interval <= 0:
+		return nil, fmt.Errorf("gen: duration and interval must be positive, got %v and %v", cfg.Duration, cfg.Interval)
+	case cfg.Vessels <= 0:
+		return nil, fmt.Errorf("gen: vessels must be positive, got %d", cfg.Vessels)
+	case cfg.MinSOG < 0 || cfg.MaxSOG < cfg.MinSOG:
+		return nil, fmt.Errorf("gen: speed range [%v, %v] is not valid", cfg.MinSOG, cfg.MaxSOG)
+	case cfg.EncounterRate < 0 || cfg.EncounterRate > 1:
+		return nil, fmt.Errorf("gen: encounter rate must be in [0, 1], got %v", cfg.EncounterRate)
+	case cfg.Area.MaxLat <= cfg.Area.MinLat || cfg.Area.MaxLon <= cfg.Area.MinLon:
+		return nil, fmt.Errorf("gen: area %+v is empty", cfg.Area)
+	}
+
+	r := rand.New(rand.NewSource(cfg.Seed))
+	g := &Generator{cfg: cfg, vessels: make([]vessel, cfg.Vessels)}
+	for i := range g.vessels {
+		g.vessels[i] = vessel{
+			mmsi:       strconv.Itoa(firstMMSI + i),
+			vesselType: vesselTypes[i%len(vesselTypes)],
+			lat:        cfg.Area.MinLat + r.Float64()*(cfg.Area.MaxLat-cfg.Area.MinLat),
+			lon:        cfg.Area.MinLon + r.Float64()*(cfg.Area.MaxLon-cfg.Area.MinLon),
+			bearing:    r.Float64() * 360,
+			sog:        cfg.MinSOG + r.Float64()*(cfg.MaxSOG-cfg.MinSOG),
+			phase:      time.Duration(r.Int63n(int64(cfg.Interval))),
+		}
+	}
+
+	// The two vessels of each encounter are moved onto routes that pass
+	// through a random point of the Area at a time in the middle half of the
+	// Duration, crossing at an angle of 30 to 150 degrees.
+	pairs := int(cfg.EncounterRate * float64(cfg.Vessels) / 2)
+	for k := 0; k < pairs; k++ {
+		a, b := &g.vessels[2*k], &g.vessels[2*k+1]
+		at := cfg.Duration/4 + time.Duration(r.Int63n(int64(cfg.Duration/2)+1))
+		lat, lon := randomIn(r, cfg.Area)
+		cross := 30 + r.Float64()*120
+		if r.Intn(2) == 0 {
+			cross = -cross
+		}
+		a.through(lat, lon, a.bearing, at)
+		b.through(lat, lon, a.bearing+cross, at)
+
+		e := Encounter{MMSI1: a.mmsi, MMSI2: b.mmsi, Time: cfg.Start.Add(at), Lat: lat, Lon: lon}
+		if e.MMSI2 < e.MMSI1 {
+			e.MMSI1, e.MMSI2 = e.MMSI2, e.MMSI1
+		}
+		g.encounters = append(g.encounters, e)
+	}
+	return g, nil
+}
+
+// randomIn returns a random position in the Box.
+func randomIn(r *rand.Rand, b ais.Box) (lat, lon float64) {
+	return b.MinLat + r.Float64()*(b.MaxLat-b.MinLat), b.MinLon + r.Float64()*(b.MaxLon-b.MinLon)
+}
+
+// through sets the route of the vessel so that it passes lat, lon on the
+// course bearing d after Config.Start.
+func (v *vessel) through(lat, lon, bearing float64, d time.Duration) {
+	nm := v.sog * d.Hours()
+	v.lat, v.lon = destination(lat, lon, bearing, -nm)
+	v.bearing = math.Mod(bearing+360, 360)
+	if nm > 0 {
+		v.bearing = initialBearing(v.lat, v.lon, lat, lon)
+	}
+}
+
+// at returns the position of the vessel d after Config.Start, and the distance
+// sailed in nm.
+func (v *vessel) at(d time.Duration) (lat, lon, nm float64) {
+	nm = v.sog * d.Hours()
+	lat, lon = destination(v.lat, v.lon, v.bearing, nm)
+	return lat, lon, nm
+}
+
+// courseAt returns the course of the vessel d after Config.Start, which
+// changes along a great circle.
+func (v *vessel) courseAt(d time.Duration) float64 {
+	lat, lon, nm := v.at(d)
+	if v.sog == 0 {
+		return v.bearing
+	}
+	lat2, lon2 := destination(v.lat, v.lon, v.bearing, nm+0.1)
+	return initialBearing(lat, lon, lat2, lon2)
+}
+
+// Encounters returns the encounters set up by the Generator, sorted by Time.
+func (g *Generator) Encounters() []Encounter {
+	out := append([]Encounter(nil), g.encounters...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
+
+// Headers returns the Headers of the generated Records, the DefaultFields of a
+// MarineCadastre.gov file.
+func (g *Generator) Headers() ais.Headers {
+	return ais.Headers{Fields: strings.Split(ais.DefaultFields, ",")}
+}
+
+// Len returns the number of Records generated.
+func (g *Generator) Len() int {
+	return g.ticks() * len(g.vessels)
+}
+
+// ticks returns the number of reports of each vessel.
+func (g *Generator) ticks() int {
+	return int(g.cfg.Duration / g.cfg.Interval)
+}
+
+// Each calls fn with every generated Record in BaseDateTime order, stopping at
+// the first error.  The Record passed to fn is reused for the next call.
+func (g *Generator) Each(fn func(rec ais.Record) error) error {
+	r := rand.New(rand.NewSource(g.cfg.Seed + 1))
+	rec := make(ais.Record, len(g.Headers().Fields))
+	order := make([]int, len(g.vessels))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return g.vessels[order[i]].phase < g.vessels[order[j]].phase
+	})
+
+	for k := 0; k < g.ticks(); k++ {
+		tick := time.Duration(k) * g.cfg.Interval
+		for _, i := range order {
+			v := &g.vessels[i]
+			d := tick + v.phase
+			lat, lon, _ := v.at(d)
+			if g.cfg.PositionNoise > 0 {
+				lat, lon = destination(lat, lon, r.Float64()*360, math.Abs(r.NormFloat64())*g.cfg.PositionNoise)
+			}
+			sog := math.Max(0, v.sog+r.NormFloat64()*g.cfg.SOGNoise)
+			cog := math.Mod(v.courseAt(d)+r.NormFloat64()*g.cfg.COGNoise+360, 360)
+			cog = math.Mod(math.Round(cog*10)/10, 360) // 359.96 is written 0.0, not 360.0
+
+			rec[0] = v.mmsi
+			rec[1] = g.cfg.Start.Add(d).UTC().Format(ais.TimeLayout)
+			rec[2] = strconv.FormatFloat(lat, 'f', 5, 64)
+			rec[3] = strconv.FormatFloat(lon, 'f', 5, 64)
+			rec[4] = strconv.FormatFloat(sog, 'f', 1, 64)
+			rec[5] = strconv.FormatFloat(cog, 'f', 1, 64)
+			rec[6] = strconv.Itoa(int(math.Round(cog)) % 360)
+			rec[7] = "SYNTHETIC " + v.mmsi[len(v.mmsi)-5:]
+			rec[10] = v.vesselType
+			rec[11] = "under way using engine"
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSet returns a pointer to a new in-memory RecordSet of every generated
+// Record, sorted by BaseDateTime.
+func (g *Generator) RecordSet() (*ais.RecordSet, error) {
+	rs := ais.NewRecordSet()
+	rs.SetHeaders(g.Headers())
+	err := g.Each(func(rec ais.Record) error { return rs.Write(rec) })
+	if err != nil {
+		return nil, fmt.Errorf("gen: %w", err)
+	}
+	if err := rs.Flush(); err != nil {
+		return nil, fmt.Errorf("gen: %w", err)
+	}
+	return rs, nil
+}
+
+// WriteTo writes the Headers and every generated Record to w as csv.
+func (g *Generator) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	csvw := csv.NewWriter(bw)
+	csvw.Write(g.Headers().Fields)
+	err := g.Each(func(rec ais.Record) error { return csvw.Write(rec) })
+	csvw.Flush()
+	if err == nil {
+		err = csvw.Error()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		return cw.n, fmt.Errorf("gen: %w", err)
+	}
+	return cw.n, nil
+}
+
+// Save writes the generated Records to a csv file that OpenRecordSet reads.
+func (g *Generator) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("gen: %w", err)
+	}
+	defer f.Close()
+	if _, err := g.WriteTo(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// countWriter counts the bytes written through it.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// destination returns the position nm nautical miles from lat, lon along the
+// great circle with the initial bearing in degrees true.  A negative nm moves
+// the opposite way.
+func destination(lat, lon, bearing, nm float64) (float64, float64) {
+	const rad = math.Pi / 180
+	delta := nm / earthRadius
+	phi1, lambda1, theta := lat*rad, lon*rad, bearing*rad
+	sinPhi2 := math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta)
+	phi2 := math.Asin(math.Max(-1, math.Min(1, sinPhi2)))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1), math.Cos(delta)-math.Sin(phi1)*sinPhi2)
+	return phi2 / rad, math.Mod(lambda2/rad+540, 360) - 180
+}
+
+// initialBearing returns the initial bearing in degrees true of the great
+// circle from lat1, lon1 to lat2, lon2.
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	phi1, phi2 := lat1*rad, lat2*rad
+	dLambda := (lon2 - lon1) * rad
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return math.Mod(math.Atan2(y, x)/rad+360, 360)
+}
@@ -0,0 +1,172 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FATHOM5/ais"
+)
+
+// smallConfig returns a Config of an hour of traffic for 20 vessels.
+func smallConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Duration = time.Hour
+	cfg.Interval = time.Minute
+	cfg.Vessels = 20
+	cfg.EncounterRate = 0.2
+	return cfg
+}
+
+func TestNew(t *testing.T) {
+	bad := []func(*Config){
+		func(c *Config) { c.Interval = 0 },
+		func(c *Config) { c.Vessels = 0 },
+		func(c *Config) { c.MaxSOG = 1 },
+		func(c *Config) { c.EncounterRate = 2 },
+		func(c *Config) { c.Area = ais.Box{} },
+	}
+	for i, f := range bad {
+		cfg := smallConfig()
+		f(&cfg)
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New() case %d expected error", i)
+		}
+	}
+}
+
+func TestGenerator_Deterministic(t *testing.T) {
+	var out [2]bytes.Buffer
+	for i := range out {
+		g, err := New(smallConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.WriteTo(&out[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(out[0].Bytes(), out[1].Bytes()) {
+		t.Error("Generator.WriteTo() differs for the same Config")
+	}
+
+	cfg := smallConfig()
+	cfg.Seed = 2
+	g, _ := New(cfg)
+	var other bytes.Buffer
+	g.WriteTo(&other)
+	if bytes.Equal(out[0].Bytes(), other.Bytes()) {
+		t.Error("Generator.WriteTo() is the same for different seeds")
+	}
+}
+
+func TestGenerator_RecordSet(t *testing.T) {
+	g, err := New(smallConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := g.RecordSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := rs.Headers()
+	timeIndex, _ := h.Contains("BaseDateTime")
+	schema := ais.DefaultSchema()
+	n := 0
+	var last string
+	for rs.Next() {
+		rec := *rs.Record()
+		if invalid := schema.Check(rec, h); len(invalid) > 0 {
+			t.Errorf("record %d has invalid %v: %v", n, invalid, rec)
+		}
+		if rec[timeIndex] < last {
+			t.Errorf("record %d at %s is before %s", n, rec[timeIndex], last)
+		}
+		last = rec[timeIndex]
+		n++
+	}
+	if err := rs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != g.Len() || n != 20*60 {
+		t.Errorf("Generator.RecordSet() has %d records, Len() = %d, want %d", n, g.Len(), 20*60)
+	}
+}
+
+func TestGenerator_Encounters(t *testing.T) {
+	cfg := smallConfig()
+	cfg.PositionNoise = 0
+	g, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encs := g.Encounters()
+	if len(encs) != 2 {
+		t.Fatalf("Generator.Encounters() = %d encounters, want 2", len(encs))
+	}
+	for _, e := range encs {
+		if e.Time.Before(cfg.Start.Add(cfg.Duration/4)) || e.Time.After(cfg.Start.Add(3*cfg.Duration/4)) {
+			t.Errorf("encounter at %v is outside the middle of the duration", e.Time)
+		}
+		for _, v := range g.vessels {
+			if v.mmsi != e.MMSI1 && v.mmsi != e.MMSI2 {
+				continue
+			}
+			lat, lon, _ := v.at(e.Time.Sub(cfg.Start))
+			if d := ais.Haversine(lat, lon, e.Lat, e.Lon); d > 0.001 {
+				t.Errorf("vessel %s is %v nm from its encounter", v.mmsi, d)
+			}
+		}
+	}
+}
+
+func TestGenerator_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "synthetic.csv")
+	g, _ := New(smallConfig())
+	if err := g.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ais.OpenRecordSet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if !rs.Headers().Equals(g.Headers()) {
+		t.Errorf("saved headers = %v", rs.Headers())
+	}
+}
+
+// BenchmarkFindInteractions runs the interaction pipeline on an hour of
+// synthetic traffic, the same for every run and on every machine.
+func BenchmarkFindInteractions(b *testing.B) {
+	cfg := DefaultConfig()
+	cfg.Duration = time.Hour
+	cfg.Vessels = 200
+	g, err := New(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := g.WriteTo(&buf); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rs, err := ais.NewRecordSetFromReader(bytes.NewReader(buf.Bytes()), ais.Headers{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := rs.FindInteractions(context.Background(), ais.InteractionParams{Sorted: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package ais
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geofence is an area made of one or more polygons, each with an outer ring and
+// any number of holes, that restricts an analysis to a port approach, traffic
+// separation scheme, wind farm, or other region of interest.  Geofence implements
+// the Matching interface so it can be passed to Subset in the same way as a Box,
+// which requires setting LatIndex and LonIndex.  RecordSet.Within sets the index
+// values from the Headers automatically.
+//
+// Vertices are treated as planar longitude and latitude coordinates, which is
+// accurate for the small areas geofences usually describe.  Polygons that cross
+// the antimeridian are not supported.  Positions exactly on an edge may be
+// reported as either inside or outside.
+type Geofence struct {
+	LatIndex, LonIndex int
+	Exclude            bool // Match returns true for positions outside the fence
+	polygons           []fencePolygon
+}
+
+// fencePolygon is an outer ring followed by its holes.  Each ring is a slice of
+// [lon, lat] vertices.  The bounding box of the outer ring rejects most points
+// before the ring tests are run.
+type fencePolygon struct {
+	rings                          [][][2]float64
+	minLat, maxLat, minLon, maxLon float64
+}
+
+// NewGeofenceWKT returns a Geofence from the Well-Known Text representation of a
+// POLYGON or MULTIPOLYGON, for example
+//      POLYGON ((-76.3 36.9, -76.0 36.9, -76.0 37.1, -76.3 37.1, -76.3 36.9))
+// Coordinates are longitude then latitude and any Z or M values are ignored.
+func NewGeofenceWKT(wkt string) (*Geofence, error) {
+	p := &wktParser{s: strings.TrimSpace(wkt)}
+	kind := strings.ToUpper(p.word())
+
+	var polys [][][][2]float64
+	switch kind {
+	case "POLYGON":
+		poly, err := p.polygon()
+		if err != nil {
+			return nil, fmt.Errorf("geofence: wkt: %w", err)
+		}
+		polys = append(polys, poly)
+	case "MULTIPOLYGON":
+		if err := p.expect('('); err != nil {
+			return nil, fmt.Errorf("geofence: wkt: %w", err)
+		}
+		for {
+			poly, err := p.polygon()
+			if err != nil {
+				return nil, fmt.Errorf("geofence: wkt: %w", err)
+			}
+			polys = append(polys, poly)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, fmt.Errorf("geofence: wkt: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("geofence: wkt: unsupported geometry %q", kind)
+	}
+	if p.peek() != 0 {
+		return nil, fmt.Errorf("geofence: wkt: unexpected text at offset %d", p.pos)
+	}
+	return newGeofence(polys)
+}
+
+// NewGeofenceGeoJSON returns a Geofence from a GeoJSON Polygon or MultiPolygon
+// geometry, a Feature with one of those geometries, or a FeatureCollection whose
+// Features all have polygonal geometries.
+func NewGeofenceGeoJSON(data []byte) (*Geofence, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("geofence: geojson: %w", err)
+	}
+	polys, err := obj.polygons()
+	if err != nil {
+		return nil, fmt.Errorf("geofence: geojson: %w", err)
+	}
+	return newGeofence(polys)
+}
+
+// geoJSONObject holds the members of any GeoJSON object that a Geofence can be
+// built from.
+type geoJSONObject struct {
+	Type        string                 `json:"type"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+	Geometry    *geoJSONObject         `json:"geometry"`
+	Features    []geoJSONObject        `json:"features"`
+	Properties  map[string]interface{} `json:"properties"`
+}
+
+func (obj *geoJSONObject) polygons() ([][][][2]float64, error) {
+	switch obj.Type {
+	case "Polygon":
+		var poly [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &poly); err != nil {
+			return nil, err
+		}
+		rings, err := geoJSONRings(poly)
+		return [][][][2]float64{rings}, err
+	case "MultiPolygon":
+		var multi [][][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &multi); err != nil {
+			return nil, err
+		}
+		var polys [][][][2]float64
+		for _, poly := range multi {
+			rings, err := geoJSONRings(poly)
+			if err != nil {
+				return nil, err
+			}
+			polys = append(polys, rings)
+		}
+		return polys, nil
+	case "Feature":
+		if obj.Geometry == nil {
+			return nil, fmt.Errorf("feature has no geometry")
+		}
+		return obj.Geometry.polygons()
+	case "FeatureCollection":
+		var polys [][][][2]float64
+		for i := range obj.Features {
+			p, err := obj.Features[i].polygons()
+			if err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			polys = append(polys, p...)
+		}
+		return polys, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", obj.Type)
+}
+
+func geoJSONRings(poly [][][]float64) ([][][2]float64, error) {
+	rings := make([][][2]float64, len(poly))
+	for i, ring := range poly {
+		for _, pos := range ring {
+			if len(pos) < 2 {
+				return nil, fmt.Errorf("position must have longitude and latitude")
+			}
+			rings[i] = append(rings[i], [2]float64{pos[0], pos[1]})
+		}
+	}
+	return rings, nil
+}
+
+// newGeofence validates the rings and computes the bounding box of each polygon.
+func newGeofence(polys [][][][2]float64) (*Geofence, error) {
+	if len(polys) == 0 {
+		return nil, fmt.Errorf("geofence: no polygons")
+	}
+	g := new(Geofence)
+	for _, rings := range polys {
+		if len(rings) == 0 {
+			return nil, fmt.Errorf("geofence: polygon has no rings")
+		}
+		for _, ring := range rings {
+			n := len(ring)
+			if n > 0 && ring[0] == ring[n-1] {
+				n--
+			}
+			if n < 3 {
+				return nil, fmt.Errorf("geofence: ring must have at least three vertices")
+			}
+		}
+		fp := fencePolygon{
+			rings:  rings,
+			minLat: math.Inf(1), maxLat: math.Inf(-1),
+			minLon: math.Inf(1), maxLon: math.Inf(-1),
+		}
+		for _, v := range rings[0] {
+			fp.minLon, fp.maxLon = math.Min(fp.minLon, v[0]), math.Max(fp.maxLon, v[0])
+			fp.minLat, fp.maxLat = math.Min(fp.minLat, v[1]), math.Max(fp.maxLat, v[1])
+		}
+		g.polygons = append(g.polygons, fp)
+	}
+	return g, nil
+}
+
+// Contains reports whether the position is inside the Geofence, meaning inside
+// the outer ring of one of its polygons and not inside any of that polygon's
+// holes.  Contains ignores Exclude.
+func (g *Geofence) Contains(lat, lon float64) bool {
+	for _, p := range g.polygons {
+		if lat < p.minLat || lat > p.maxLat || lon < p.minLon || lon > p.maxLon {
+			continue
+		}
+		if !inRing(p.rings[0], lat, lon) {
+			continue
+		}
+		inHole := false
+		for _, hole := range p.rings[1:] {
+			if inRing(hole, lat, lon) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// inRing is the even-odd ray casting test for a point in a ring of [lon, lat]
+// vertices.  The ring does not need to be explicitly closed.
+func inRing(ring [][2]float64, lat, lon float64) bool {
+	in := false
+	j := len(ring) - 1
+	for i := range ring {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			in = !in
+		}
+		j = i
+	}
+	return in
+}
+
+// Match implements the Matching interface for a Geofence.  It returns true for
+// Records inside the Geofence, or outside of it when Exclude is set.  When Match
+// returns a non-nil error the bool value will be false.
+func (g *Geofence) Match(rec *Record) (bool, error) {
+	lat, err := rec.ParseFloat(g.LatIndex)
+	if err != nil {
+		return false, ErrParse{Field: "LAT", Err: err}
+	}
+	lon, err := rec.ParseFloat(g.LonIndex)
+	if err != nil {
+		return false, ErrParse{Field: "LON", Err: err}
+	}
+	return g.Contains(lat, lon) != g.Exclude, nil
+}
+
+// Within returns a pointer to a new RecordSet holding the Records that Match
+// the Geofence, using the LAT and LON Headers of rs in place of the LatIndex
+// and LonIndex of fence.  Set Exclude on the fence to keep the Records outside
+// of it instead.  Like Subset, Within returns ErrEmptySet when no Records match.
+func (rs *RecordSet) Within(fence *Geofence) (*RecordSet, error) {
+	idx, err := rs.Headers().require("LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("within: %w", err)
+	}
+	g := *fence
+	g.LatIndex, g.LonIndex = idx["LAT"].Idx, idx["LON"].Idx
+	return rs.Subset(&g)
+}
+
+// WithGeofence limits the Interactions to pairs of Records that both Match the
+// Geofence.  The LatIndex and LonIndex of the fence are replaced by the LAT and
+// LON index values of the Interactions RecordHeaders.
+func WithGeofence(fence *Geofence) InteractionOption {
+	return func(inter *Interactions) error {
+		if fence == nil {
+			return fmt.Errorf("geofence must not be nil")
+		}
+		g := *fence
+		g.LatIndex, g.LonIndex = inter.hashIndices[2], inter.hashIndices[3]
+		inter.fence = &g
+		return nil
+	}
+}
+
+// wktParser is a minimal recursive descent parser for WKT polygons.
+type wktParser struct {
+	s   string
+	pos int
+}
+
+// peek returns the next non-space byte without consuming it, or 0 at the end.
+func (p *wktParser) peek() byte {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *wktParser) expect(c byte) error {
+	if got := p.peek(); got != c {
+		return fmt.Errorf("expected %q at offset %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// word consumes a geometry keyword along with any Z, M, or ZM dimension suffix.
+func (p *wktParser) word() string {
+	p.peek()
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != '(' {
+		p.pos++
+	}
+	fields := strings.Fields(p.s[start:p.pos])
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// polygon parses a parenthesized list of rings.
+func (p *wktParser) polygon() ([][][2]float64, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var rings [][][2]float64
+	for {
+		ring, err := p.ring()
+		if err != nil {
+			return nil, err
+		}
+		rings = append(rings, ring)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	return rings, p.expect(')')
+}
+
+// ring parses a parenthesized list of coordinates.
+func (p *wktParser) ring() ([][2]float64, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var ring [][2]float64
+	for {
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] != ',' && p.s[p.pos] != ')' {
+			p.pos++
+		}
+		fields := strings.Fields(p.s[start:p.pos])
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("coordinate at offset %d must have longitude and latitude", start)
+		}
+		lon, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		lat, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		ring = append(ring, [2]float64{lon, lat})
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	return ring, p.expect(')')
+}
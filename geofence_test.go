@@ -0,0 +1,110 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+)
+
+var testFence, _ = NewGeofenceWKT("POLYGON ((-75.9955 29.9, -75.9 29.9, -75.9 30.1, -75.9955 30.1, -75.9955 29.9))")
+
+func TestGeofence_Contains(t *testing.T) {
+	square := "(-76 36, -75 36, -75 37, -76 37, -76 36)"
+	hole := "(-75.6 36.4, -75.4 36.4, -75.4 36.6, -75.6 36.6, -75.6 36.4)"
+	geojson := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[
+			[[-76,36],[-75,36],[-75,37],[-76,37],[-76,36]],
+			[[-75.6,36.4],[-75.4,36.4],[-75.4,36.6],[-75.6,36.6]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"MultiPolygon","coordinates":[
+			[[[-71,40],[-70,40],[-70,41]]]]}}]}`
+
+	fromWKT, err := NewGeofenceWKT("MULTIPOLYGON (((-71 40, -70 40, -70 41)), (" + square + ", " + hole + "))")
+	if err != nil {
+		t.Fatalf("NewGeofenceWKT() error = %v", err)
+	}
+	fromJSON, err := NewGeofenceGeoJSON([]byte(geojson))
+	if err != nil {
+		t.Fatalf("NewGeofenceGeoJSON() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     bool
+	}{
+		{"inside square", 36.2, -75.8, true},
+		{"inside hole", 36.5, -75.5, false},
+		{"inside triangle", 40.2, -70.1, true},
+		{"outside triangle", 40.8, -70.9, false},
+		{"outside all", 38, -74, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fromWKT.Contains(tt.lat, tt.lon); got != tt.want {
+				t.Errorf("Geofence.Contains() from WKT = %v, want %v", got, tt.want)
+			}
+			if got := fromJSON.Contains(tt.lat, tt.lon); got != tt.want {
+				t.Errorf("Geofence.Contains() from GeoJSON = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGeofence_Errors(t *testing.T) {
+	for _, wkt := range []string{
+		"LINESTRING (0 0, 1 1)",
+		"POLYGON ((0 0, 1 1))",
+		"POLYGON ((0 0, 1 0, 1 1, 0 0)",
+		"POLYGON ((0 0, 1 0, x 1, 0 0))",
+		"POLYGON ((0 0, 1 0, 1 1, 0 0)) extra",
+	} {
+		if _, err := NewGeofenceWKT(wkt); err == nil {
+			t.Errorf("NewGeofenceWKT(%q) expected error", wkt)
+		}
+	}
+	for _, js := range []string{
+		`{"type":"Point","coordinates":[0,0]}`,
+		`{"type":"Feature"}`,
+		`{"type":"Polygon","coordinates":[[[0],[1,0],[1,1]]]}`,
+		`not json`,
+	} {
+		if _, err := NewGeofenceGeoJSON([]byte(js)); err == nil {
+			t.Errorf("NewGeofenceGeoJSON(%q) expected error", js)
+		}
+	}
+}
+
+func TestRecordSet_Within(t *testing.T) {
+	data := "MMSI,LAT,LON\n1,30.0,-75.99\n2,30.0,-76.1\n3,30.05,-75.95\n"
+	count := func(rs *RecordSet) int {
+		n := 0
+		for rs.Next() {
+			n++
+		}
+		return n
+	}
+
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	in, err := rs.Within(testFence)
+	if err != nil {
+		t.Fatalf("RecordSet.Within() error = %v", err)
+	}
+	if n := count(in); n != 2 {
+		t.Errorf("RecordSet.Within() returned %d records, want 2", n)
+	}
+
+	exclude := *testFence
+	exclude.Exclude = true
+	rs, _ = NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	out, err := rs.Within(&exclude)
+	if err != nil {
+		t.Fatalf("RecordSet.Within() with Exclude error = %v", err)
+	}
+	if n := count(out); n != 1 {
+		t.Errorf("RecordSet.Within() with Exclude returned %d records, want 1", n)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI\n1\n"), Headers{})
+	if _, err := rs.Within(testFence); err == nil {
+		t.Error("RecordSet.Within() expected error for headers without LAT and LON")
+	}
+}
@@ -0,0 +1,111 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mmcloughlin/geohash"
+)
+
+// Geohash is an integer geohash of a chosen precision, the cell of the
+// latitude and longitude grid that the package groups vessels by.  Hash holds
+// the interleaved bits of the cell, longitude first, in its low Bits bits, so a
+// Geohash of 22 bits is the value written in the Geohash field by a Geohasher.
+// It exposes the geohash machinery of the package for spatial bucketing of any
+// kind without another library:
+//
+//	g := ais.EncodeGeohash(36.9, -76.3, 30)
+//	box := g.Box()
+//	for _, n := range g.Neighbors() {
+//		...
+//	}
+type Geohash struct {
+	Hash uint64
+	Bits uint
+}
+
+// EncodeGeohash returns the Geohash of bits bits, from 1 to 64, that holds the
+// position.
+func EncodeGeohash(lat, lon float64, bits uint) Geohash {
+	return Geohash{Hash: geohash.EncodeIntWithPrecision(lat, lon, bits), Bits: bits}
+}
+
+// ParseGeohash returns the Geohash of bits bits written in s, such as the
+// 0x-prefixed hexadecimal value of a Geohash field.  Values without a prefix
+// are decimal.
+func ParseGeohash(s string, bits uint) (Geohash, error) {
+	if bits == 0 || bits > 64 {
+		return Geohash{}, fmt.Errorf("parse geohash: bits must be from 1 to 64, got %d", bits)
+	}
+	hash, err := strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+	if err != nil {
+		return Geohash{}, fmt.Errorf("parse geohash: %w", err)
+	}
+	if bits < 64 && hash>>bits != 0 {
+		return Geohash{}, fmt.Errorf("parse geohash: %s has more than %d bits", s, bits)
+	}
+	return Geohash{Hash: hash, Bits: bits}, nil
+}
+
+// String returns the Hash in the 0x-prefixed hexadecimal form of the Geohash
+// field.
+func (g Geohash) String() string {
+	return fmt.Sprintf("%#x", g.Hash)
+}
+
+// Box returns the bounds of the cell of g.  The LatIndex and LonIndex of the Box
+// are zero, so set them before using it as a Matching.
+func (g Geohash) Box() Box {
+	b := geohash.BoundingBoxIntWithPrecision(g.Hash, g.Bits)
+	return Box{MinLat: b.MinLat, MaxLat: b.MaxLat, MinLon: b.MinLng, MaxLon: b.MaxLng}
+}
+
+// Center returns the position at the center of the cell of g.
+func (g Geohash) Center() (lat, lon float64) {
+	return geohash.BoundingBoxIntWithPrecision(g.Hash, g.Bits).Center()
+}
+
+// Neighbors returns the eight cells of the same precision around g, in the order
+// N, NE, E, SE, S, SW, W, NW.  Cells at the poles and across the antimeridian
+// wrap as they do in the underlying library, so a neighbor may repeat.
+func (g Geohash) Neighbors() []Geohash {
+	hashes := geohash.NeighborsIntWithPrecision(g.Hash, g.Bits)
+	n := make([]Geohash, len(hashes))
+	for i, h := range hashes {
+		n[i] = Geohash{Hash: h, Bits: g.Bits}
+	}
+	return n
+}
+
+// GeohashCellSize returns the height and width in degrees of the cells of a
+// Geohash of bits bits.  Longitude takes the first bit of each pair, so the
+// cells of an odd precision are square in degrees and those of an even precision
+// twice as wide as they are high.
+func GeohashCellSize(bits uint) (latDeg, lonDeg float64) {
+	return 180 / math.Exp2(float64(bits/2)), 360 / math.Exp2(float64(bits-bits/2))
+}
+
+// GeohashCellSizeNM returns the height and width in nautical miles of the cells
+// of a Geohash of bits bits at latitude lat, where a degree of longitude is
+// cos(lat) times shorter than at the equator.
+func GeohashCellSizeNM(bits uint, lat float64) (height, width float64) {
+	latDeg, lonDeg := GeohashCellSize(bits)
+	return latDeg * 60, lonDeg * 60 * math.Cos(lat*math.Pi/180)
+}
+
+// GeohashPrecision returns the largest number of bits, and so the smallest
+// cells, for which the cells of a Geohash at latitude lat are at least nm
+// nautical miles high and wide.  It chooses the precision of a Geohasher or of
+// InteractionParams for a distance of interest, such as the WithMaxDistance of
+// an Interactions, since vessels nm apart then share a cell or lie in neighboring
+// cells.  It returns 1 when even the cells of 2 bits are too small.
+func GeohashPrecision(nm, lat float64) uint {
+	for bits := uint(64); bits > 1; bits-- {
+		if h, w := GeohashCellSizeNM(bits, lat); h >= nm && w >= nm {
+			return bits
+		}
+	}
+	return 1
+}
@@ -0,0 +1,88 @@
+package ais
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeohash(t *testing.T) {
+	g := EncodeGeohash(36.9, -76.3, DefaultInteractionPrecision)
+	if g.Bits != DefaultInteractionPrecision {
+		t.Fatalf("Bits = %d", g.Bits)
+	}
+	rec := Record{"36.9", "-76.3"}
+	f, err := geohashGenerator{bits: DefaultInteractionPrecision}.Generate(rec, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.String() != string(f) {
+		t.Errorf("String() = %s, want the Geohash field %s", g, f)
+	}
+	p, err := ParseGeohash(string(f), DefaultInteractionPrecision)
+	if err != nil || p != g {
+		t.Errorf("ParseGeohash(%s) = %v, %v, want %v", f, p, err, g)
+	}
+
+	b := g.Box()
+	if !b.Contains(36.9, -76.3) {
+		t.Errorf("Box() %+v does not contain the position", b)
+	}
+	latDeg, lonDeg := GeohashCellSize(g.Bits)
+	if math.Abs(b.MaxLat-b.MinLat-latDeg) > 1e-9 || math.Abs(b.MaxLon-b.MinLon-lonDeg) > 1e-9 {
+		t.Errorf("Box() %+v is not %v by %v degrees", b, latDeg, lonDeg)
+	}
+	lat, lon := g.Center()
+	if lat != (b.MinLat+b.MaxLat)/2 || lon != (b.MinLon+b.MaxLon)/2 {
+		t.Errorf("Center() = %v, %v, want the center of %+v", lat, lon, b)
+	}
+
+	n := g.Neighbors()
+	if len(n) != 8 {
+		t.Fatalf("Neighbors() returned %d cells", len(n))
+	}
+	north, east := n[0].Box(), n[2].Box()
+	if north.MinLat != b.MaxLat || north.MinLon != b.MinLon {
+		t.Errorf("north neighbor %+v is not above %+v", north, b)
+	}
+	if east.MinLon != b.MaxLon || east.MinLat != b.MinLat {
+		t.Errorf("east neighbor %+v is not beside %+v", east, b)
+	}
+
+	for _, s := range []string{"zz", "0x400000"} {
+		if _, err := ParseGeohash(s, DefaultInteractionPrecision); err == nil {
+			t.Errorf("ParseGeohash(%q) returned no error", s)
+		}
+	}
+	if _, err := ParseGeohash("1", 0); err == nil {
+		t.Error("ParseGeohash with 0 bits returned no error")
+	}
+}
+
+func TestGeohashCellSize(t *testing.T) {
+	for _, tt := range []struct {
+		bits           uint
+		latDeg, lonDeg float64
+	}{
+		{1, 180, 180}, {2, 90, 180}, {5, 45, 45}, {22, 180.0 / 2048, 360.0 / 2048},
+	} {
+		latDeg, lonDeg := GeohashCellSize(tt.bits)
+		if latDeg != tt.latDeg || lonDeg != tt.lonDeg {
+			t.Errorf("GeohashCellSize(%d) = %v, %v, want %v, %v", tt.bits, latDeg, lonDeg, tt.latDeg, tt.lonDeg)
+		}
+	}
+	h, w := GeohashCellSizeNM(22, 60)
+	if math.Abs(h-180.0/2048*60) > 1e-9 || math.Abs(w-360.0/2048*30) > 1e-9 {
+		t.Errorf("GeohashCellSizeNM(22, 60) = %v, %v", h, w)
+	}
+
+	bits := GeohashPrecision(0.5, 55)
+	if h, w := GeohashCellSizeNM(bits, 55); h < 0.5 || w < 0.5 {
+		t.Errorf("GeohashPrecision(0.5, 55) = %d with cells of %v by %v nm", bits, h, w)
+	}
+	if h, w := GeohashCellSizeNM(bits+1, 55); h >= 0.5 && w >= 0.5 {
+		t.Errorf("GeohashPrecision(0.5, 55) = %d, but %d bits also fit", bits, bits+1)
+	}
+	if got := GeohashPrecision(1e6, 0); got != 1 {
+		t.Errorf("GeohashPrecision(1e6, 0) = %d, want 1", got)
+	}
+}
@@ -0,0 +1,184 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// geoJSONFeature is a single GeoJSON Feature.  Properties are written with the
+// string values held in the Records.
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONGeometry   `json:"geometry"`
+	Properties map[string]string `json:"properties"`
+}
+
+// geoJSONGeometry is a GeoJSON Point or LineString.  Coordinates are in the
+// GeoJSON order of longitude then latitude.
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// geoJSONWriter streams the Features of a FeatureCollection to an io.Writer so
+// that large sets never have to be held in memory.
+type geoJSONWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newGeoJSONWriter(w io.Writer) (*geoJSONWriter, error) {
+	gw := &geoJSONWriter{w: bufio.NewWriter(w)}
+	_, err := gw.w.WriteString(`{"type":"FeatureCollection","features":[`)
+	return gw, err
+}
+
+func (gw *geoJSONWriter) write(f geoJSONFeature) error {
+	if gw.count > 0 {
+		if err := gw.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	gw.count++
+	_, err = gw.w.Write(b)
+	return err
+}
+
+func (gw *geoJSONWriter) close() error {
+	if _, err := gw.w.WriteString("]}\n"); err != nil {
+		return err
+	}
+	return gw.w.Flush()
+}
+
+// properties zips the fields and values into a map for a Feature.
+func properties(fields, values []string) map[string]string {
+	props := make(map[string]string, len(fields))
+	for i, f := range fields {
+		if i < len(values) {
+			props[f] = values[i]
+		}
+	}
+	return props
+}
+
+// position returns the [lon, lat] GeoJSON coordinate of a Record.
+func position(rec *Record, latIndex, lonIndex int) ([]float64, error) {
+	lat, err := rec.ParseFloat(latIndex)
+	if err != nil {
+		return nil, ErrParse{Field: "LAT", Err: err}
+	}
+	lon, err := rec.ParseFloat(lonIndex)
+	if err != nil {
+		return nil, ErrParse{Field: "LON", Err: err}
+	}
+	return []float64{lon, lat}, nil
+}
+
+// SaveGeoJSON writes the RecordSet to filename as a GeoJSON FeatureCollection with
+// one Point Feature per Record.  The Headers of the RecordSet are used as the
+// property names of each Feature.  The Headers must contain "LAT" and "LON".  Like
+// Save, SaveGeoJSON reads the RecordSet to the end of its data.
+func (rs *RecordSet) SaveGeoJSON(filename string) error {
+	idx, err := rs.Headers().require("LAT", "LON")
+	if err != nil {
+		return fmt.Errorf("recordset save geojson: %w", err)
+	}
+	latIndex, lonIndex := idx["LAT"].Idx, idx["LON"].Idx
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("recordset save geojson: %w", err)
+	}
+	defer out.Close()
+
+	gw, err := newGeoJSONWriter(out)
+	if err != nil {
+		return fmt.Errorf("recordset save geojson: %w", err)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("recordset save geojson: %w", err)
+		}
+		coord, err := position(rec, latIndex, lonIndex)
+		if err != nil {
+			return fmt.Errorf("recordset save geojson: %w", err)
+		}
+		err = gw.write(geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: coord},
+			Properties: properties(rs.Headers().Fields, *rec),
+		})
+		if err != nil {
+			return fmt.Errorf("recordset save geojson: %w", err)
+		}
+	}
+	if err := gw.close(); err != nil {
+		return fmt.Errorf("recordset save geojson: %w", err)
+	}
+	return nil
+}
+
+// SaveGeoJSON writes the interactions to filename as a GeoJSON FeatureCollection.
+// Each interaction is a LineString Feature from the position of the first vessel
+// to the position of the second vessel.  The properties of each Feature are the
+// same fields written by Save and are named by OutputHeaders.
+func (inter *Interactions) SaveGeoJSON(filename string) error {
+	idx, err := inter.RecordHeaders.require("LAT", "LON")
+	if err != nil {
+		return fmt.Errorf("interactions save geojson: %w", err)
+	}
+	latIndex, lonIndex := idx["LAT"].Idx, idx["LON"].Idx
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("interactions save geojson: %w", err)
+	}
+	defer out.Close()
+	return inter.writeGeoJSON(out, latIndex, lonIndex)
+}
+
+// writeGeoJSON writes the interactions to out as a GeoJSON FeatureCollection.
+func (inter *Interactions) writeGeoJSON(out io.Writer, latIndex, lonIndex int) error {
+	gw, err := newGeoJSONWriter(out)
+	if err != nil {
+		return fmt.Errorf("interactions save geojson: %w", err)
+	}
+	err = inter.each(func(hash Hash128, pair *RecordPair) error {
+		p1, err := position(pair.rec1, latIndex, lonIndex)
+		if err != nil {
+			return err
+		}
+		p2, err := position(pair.rec2, latIndex, lonIndex)
+		if err != nil {
+			return err
+		}
+		row, err := inter.row(hash, pair)
+		if err != nil {
+			return err
+		}
+		return gw.write(geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: [][]float64{p1, p2}},
+			Properties: properties(inter.OutputHeaders.Fields, row),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("interactions save geojson: %w", err)
+	}
+	if err := gw.close(); err != nil {
+		return fmt.Errorf("interactions save geojson: %w", err)
+	}
+	return nil
+}
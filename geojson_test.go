@@ -0,0 +1,94 @@
+package ais
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// featureCollection is the minimal GeoJSON structure needed to check output.
+type featureCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]string `json:"properties"`
+	} `json:"features"`
+}
+
+func readFeatureCollection(t *testing.T, filename string) featureCollection {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unable to read %s: %v", filename, err)
+	}
+	var fc featureCollection
+	if err := json.Unmarshal(b, &fc); err != nil {
+		t.Fatalf("invalid GeoJSON in %s: %v", filename, err)
+	}
+	return fc
+}
+
+func TestRecordSet_SaveGeoJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+
+	filename := filepath.Join(dir, "ten.geojson")
+	if err := rs.SaveGeoJSON(filename); err != nil {
+		t.Fatalf("RecordSet.SaveGeoJSON() error = %v", err)
+	}
+	fc := readFeatureCollection(t, filename)
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 10 {
+		t.Fatalf("RecordSet.SaveGeoJSON() wrote %s with %d features, want FeatureCollection with 10", fc.Type, len(fc.Features))
+	}
+	f := fc.Features[0]
+	if f.Geometry.Type != "Point" || string(f.Geometry.Coordinates) != "[-76.32652,31.90512]" {
+		t.Errorf("RecordSet.SaveGeoJSON() first geometry = %s %s", f.Geometry.Type, f.Geometry.Coordinates)
+	}
+	if f.Properties["MMSI"] != "477307901" {
+		t.Errorf("RecordSet.SaveGeoJSON() first MMSI = %s, want 477307901", f.Properties["MMSI"])
+	}
+
+	badSet := NewRecordSet()
+	badSet.SetHeaders(Headers{Fields: []string{"MMSI"}})
+	if err := badSet.SaveGeoJSON(filepath.Join(dir, "bad.geojson")); err == nil {
+		t.Errorf("RecordSet.SaveGeoJSON() expected error for headers without LAT and LON")
+	}
+}
+
+func TestInteractions_SaveGeoJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inter, _ := NewInteractions(goodHeaders)
+	inter.AddCluster(testClusters(1, 3)[0])
+
+	filename := filepath.Join(dir, "inter.geojson")
+	if err := inter.SaveGeoJSON(filename); err != nil {
+		t.Fatalf("Interactions.SaveGeoJSON() error = %v", err)
+	}
+	fc := readFeatureCollection(t, filename)
+	if len(fc.Features) != 3 {
+		t.Fatalf("Interactions.SaveGeoJSON() wrote %d features, want 3", len(fc.Features))
+	}
+	for _, f := range fc.Features {
+		if f.Geometry.Type != "LineString" {
+			t.Errorf("Interactions.SaveGeoJSON() geometry = %s, want LineString", f.Geometry.Type)
+		}
+		if _, ok := f.Properties["Distance(nm)"]; !ok {
+			t.Errorf("Interactions.SaveGeoJSON() properties missing Distance(nm)")
+		}
+	}
+}
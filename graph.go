@@ -0,0 +1,229 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GraphEdgeFields are the columns of the edge list written by SaveGraph to a
+// file ending in .csv.  Source and Target are MMSIs, Weight is the number of
+// interactions between the two vessels, and MinDistance(nm) is the closest they
+// came in any of them.  The Source, Target, and Weight names are those expected
+// by the spreadsheet import of Gephi.
+const GraphEdgeFields = "Source,Target,Weight,MinDistance(nm)"
+
+// GraphEdge is the encounter history of one pair of vessels in the network of
+// an Interactions set.
+type GraphEdge struct {
+	MMSI1, MMSI2 string  // MMSI1 sorts before MMSI2
+	Count        int     // number of interactions between the vessels
+	MinDistance  float64 // least distance in nm between the vessels in any interaction
+}
+
+// Graph returns the encounter network of the set, with one GraphEdge for every
+// pair of vessels that interact, sorted by MMSI1 and then MMSI2.  The
+// RecordHeaders must contain MMSI, LAT, and LON.
+func (inter *Interactions) Graph() ([]GraphEdge, error) {
+	idx, err := inter.RecordHeaders.require("MMSI", "LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("interactions graph: %w", err)
+	}
+	mmsiIndex := idx["MMSI"].Idx
+
+	edges := make(map[[2]string]*GraphEdge)
+	err = inter.each(func(hash Hash128, pair *RecordPair) error {
+		m1, m2 := (*pair.rec1)[mmsiIndex], (*pair.rec2)[mmsiIndex]
+		if m2 < m1 {
+			m1, m2 = m2, m1
+		}
+		d, err := inter.distanceOf(pair)
+		if err != nil {
+			return err
+		}
+		e, ok := edges[[2]string{m1, m2}]
+		if !ok {
+			e = &GraphEdge{MMSI1: m1, MMSI2: m2, MinDistance: math.Inf(1)}
+			edges[[2]string{m1, m2}] = e
+		}
+		e.Count++
+		e.MinDistance = math.Min(e.MinDistance, d)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("interactions graph: %w", err)
+	}
+
+	graph := make([]GraphEdge, 0, len(edges))
+	for _, e := range edges {
+		graph = append(graph, *e)
+	}
+	sort.Slice(graph, func(i, j int) bool {
+		if graph[i].MMSI1 != graph[j].MMSI1 {
+			return graph[i].MMSI1 < graph[j].MMSI1
+		}
+		return graph[i].MMSI2 < graph[j].MMSI2
+	})
+	return graph, nil
+}
+
+// SaveGraph writes the encounter network of the set to filename for network
+// analysis in tools such as Gephi or NetworkX.  The nodes of the undirected
+// graph are MMSIs and each edge joins two vessels that interact, weighted by
+// the number of their interactions and carrying the least distance between
+// them.  The format is chosen by the extension of filename: GraphML for
+// .graphml, Graphviz DOT for .dot or .gv, and an edge list with GraphEdgeFields
+// for .csv.
+func (inter *Interactions) SaveGraph(filename string) error {
+	var write func(io.Writer, []GraphEdge) error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".graphml":
+		write = writeGraphML
+	case ".dot", ".gv":
+		write = writeDOT
+	case ".csv":
+		write = writeEdgeList
+	default:
+		return fmt.Errorf("interactions save graph: unknown graph format for %s", filename)
+	}
+
+	graph, err := inter.Graph()
+	if err != nil {
+		return fmt.Errorf("interactions save graph: %w", err)
+	}
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("interactions save graph: %w", err)
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	if err := write(w, graph); err != nil {
+		return fmt.Errorf("interactions save graph: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("interactions save graph: %w", err)
+	}
+	return out.Close()
+}
+
+// graphNodes returns the sorted MMSIs of the graph.
+func graphNodes(graph []GraphEdge) []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, e := range graph {
+		for _, m := range []string{e.MMSI1, e.MMSI2} {
+			if !seen[m] {
+				seen[m] = true
+				nodes = append(nodes, m)
+			}
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// formatDistance formats a distance in nm as it is written by Save.
+func formatDistance(d float64) string {
+	return strconv.FormatFloat(d, 'f', 1, 64)
+}
+
+func writeEdgeList(out io.Writer, graph []GraphEdge) error {
+	w := csv.NewWriter(out)
+	w.Write(strings.Split(GraphEdgeFields, ","))
+	for _, e := range graph {
+		w.Write([]string{e.MMSI1, e.MMSI2, strconv.Itoa(e.Count), formatDistance(e.MinDistance)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeDOT(out io.Writer, graph []GraphEdge) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "graph interactions {")
+	for _, m := range graphNodes(graph) {
+		fmt.Fprintf(w, "\t%q;\n", m)
+	}
+	for _, e := range graph {
+		fmt.Fprintf(w, "\t%q -- %q [weight=%d, mindistance=%s];\n", e.MMSI1, e.MMSI2, e.Count, formatDistance(e.MinDistance))
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+// graphMLKey declares an attribute of the nodes or edges of a GraphML graph.
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID string `xml:"id,attr"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+func writeGraphML(out io.Writer, graph []GraphEdge) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+			{ID: "mindistance", For: "edge", Name: "mindistance", Type: "double"},
+		},
+	}
+	doc.Graph.ID = "interactions"
+	doc.Graph.EdgeDefault = "undirected"
+	for _, m := range graphNodes(graph) {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: m})
+	}
+	for _, e := range graph {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.MMSI1,
+			Target: e.MMSI2,
+			Data: []graphMLData{
+				{Key: "weight", Value: strconv.Itoa(e.Count)},
+				{Key: "mindistance", Value: formatDistance(e.MinDistance)},
+			},
+		})
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
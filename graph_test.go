@@ -0,0 +1,84 @@
+package ais
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInteractions_Graph(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	for _, times := range [][3]string{
+		{"2017-12-01T00:00:00", "30.00000", "30.01000"},
+		{"2017-12-01T00:01:00", "30.00000", "30.02000"},
+	} {
+		c := new(Cluster)
+		for _, v := range [][2]string{{"100000002", times[1]}, {"100000001", times[2]}, {"100000003", "31.00000"}} {
+			rec := Record{v[0], times[0], v[1], "-76.00000", "", "", "", "", "", "", "", "", "", "", "", ""}
+			c.Append(&rec)
+		}
+		if err := inter.AddCluster(c); err != nil {
+			t.Fatalf("Interactions.AddCluster() error = %v", err)
+		}
+	}
+
+	graph, err := inter.Graph()
+	if err != nil {
+		t.Fatalf("Interactions.Graph() error = %v", err)
+	}
+	if len(graph) != 3 {
+		t.Fatalf("Interactions.Graph() = %v, want 3 edges", graph)
+	}
+	e := graph[0]
+	if e.MMSI1 != "100000001" || e.MMSI2 != "100000002" || e.Count != 2 || formatDistance(e.MinDistance) != "0.6" {
+		t.Errorf("Interactions.Graph() first edge = %+v, want 100000001-100000002 with 2 interactions at 0.6 nm", e)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	csvFile := filepath.Join(dir, "graph.csv")
+	if err := inter.SaveGraph(csvFile); err != nil {
+		t.Fatalf("Interactions.SaveGraph() error = %v", err)
+	}
+	f, _ := os.Open(csvFile)
+	rows, _ := csv.NewReader(f).ReadAll()
+	f.Close()
+	if len(rows) != 4 || !reflect.DeepEqual(rows[1], []string{"100000001", "100000002", "2", "0.6"}) {
+		t.Errorf("Interactions.SaveGraph() csv = %v", rows)
+	}
+
+	dotFile := filepath.Join(dir, "graph.dot")
+	if err := inter.SaveGraph(dotFile); err != nil {
+		t.Fatalf("Interactions.SaveGraph() error = %v", err)
+	}
+	dot, _ := ioutil.ReadFile(dotFile)
+	if !strings.Contains(string(dot), `"100000001" -- "100000002" [weight=2, mindistance=0.6];`) {
+		t.Errorf("Interactions.SaveGraph() dot =\n%s", dot)
+	}
+
+	mlFile := filepath.Join(dir, "graph.graphml")
+	if err := inter.SaveGraph(mlFile); err != nil {
+		t.Fatalf("Interactions.SaveGraph() error = %v", err)
+	}
+	ml, _ := ioutil.ReadFile(mlFile)
+	var doc graphML
+	if err := xml.Unmarshal(ml, &doc); err != nil {
+		t.Fatalf("Interactions.SaveGraph() wrote invalid GraphML: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 3 {
+		t.Errorf("Interactions.SaveGraph() GraphML has %d nodes and %d edges, want 3 and 3", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+
+	if err := inter.SaveGraph(filepath.Join(dir, "graph.txt")); err == nil {
+		t.Error("Interactions.SaveGraph() expected error for unknown extension")
+	}
+}
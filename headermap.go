@@ -0,0 +1,116 @@
+package ais
+
+// HeaderMapping maps the field names used by the package, such as MMSI,
+// BaseDateTime, LAT, and LON, to the names the same fields have in other AIS
+// archives.  Aliases are compared without regard to case.  Set the Mapping of
+// the Headers of a RecordSet to read files that use other names, for example
+//
+//	rs, _ := ais.OpenRecordSet("aisdk_20171201.csv")
+//	rs.SetHeaders(rs.Headers().WithMapping(ais.DMAMapping))
+//
+// after which Contains("LAT") finds the Latitude column and every method that
+// looks up fields by name works on the file.  A HeaderMapping only renames
+// fields, so values must still be in the units and formats described by
+// DefaultFields.
+type HeaderMapping map[string][]string
+
+// MarineCadastreMapping holds the snake case names used by the MarineCadastre.gov
+// files published since 2024 for the fields of DefaultFields.
+var MarineCadastreMapping = HeaderMapping{
+	"MMSI":         {"mmsi"},
+	"BaseDateTime": {"base_date_time"},
+	"LAT":          {"latitude"},
+	"LON":          {"longitude"},
+	"SOG":          {"sog"},
+	"COG":          {"cog"},
+	"Heading":      {"heading"},
+	"VesselName":   {"vessel_name"},
+	"IMO":          {"imo"},
+	"CallSign":     {"call_sign"},
+	"VesselType":   {"vessel_type"},
+	"Status":       {"status"},
+	"Length":       {"length"},
+	"Width":        {"width"},
+	"Draft":        {"draft"},
+	"Cargo":        {"cargo"},
+
+	"TransceiverClass": {"transceiver", "transceiver_class"},
+}
+
+// EMODnetMapping holds the names used by the AIS extracts of the European Marine
+// Observation and Data Network and similar European archives.
+var EMODnetMapping = HeaderMapping{
+	"MMSI":         {"mmsi"},
+	"BaseDateTime": {"timestamp", "ts", "datetime", "time"},
+	"LAT":          {"lat", "latitude"},
+	"LON":          {"lon", "long", "longitude"},
+	"SOG":          {"sog", "speed"},
+	"COG":          {"cog", "course"},
+	"Heading":      {"heading", "hdg"},
+	"VesselName":   {"shipname", "name", "vesselname"},
+	"IMO":          {"imo"},
+	"CallSign":     {"callsign"},
+	"VesselType":   {"shiptype", "vesseltype", "type"},
+	"Status":       {"navstatus", "status"},
+	"Length":       {"length"},
+	"Width":        {"width"},
+	"Draft":        {"draught", "draft"},
+}
+
+// DMAMapping holds the names used by the AIS files of the Danish Maritime
+// Authority.  The header line of those files begins with "# Timestamp", which
+// OpenRecordSet skips as a comment, so remove the leading "# " before opening a
+// file or pass Headers built from its first line to NewRecordSetFromReader.  Its
+// timestamps are written as dd/mm/yyyy hh:mm:ss rather than TimeLayout, so also
+// set a TimeParser made by NewTimeParser("02/01/2006 15:04:05").
+var DMAMapping = HeaderMapping{
+	"MMSI":         {"MMSI"},
+	"BaseDateTime": {"Timestamp", "# Timestamp"},
+	"LAT":          {"Latitude"},
+	"LON":          {"Longitude"},
+	"SOG":          {"SOG"},
+	"COG":          {"COG"},
+	"Heading":      {"Heading"},
+	"VesselName":   {"Name"},
+	"IMO":          {"IMO"},
+	"CallSign":     {"Callsign"},
+	"VesselType":   {"Ship type"},
+	"Status":       {"Navigational status"},
+	"Length":       {"Length"},
+	"Width":        {"Width"},
+	"Draft":        {"Draught"},
+	"Cargo":        {"Cargo type"},
+
+	"TransceiverClass": {"Type of mobile"},
+}
+
+// WithMapping returns a copy of h that resolves field names through m.
+func (h Headers) WithMapping(m HeaderMapping) Headers {
+	h.Mapping = m
+	return h
+}
+
+// ReadMapping sets the Mapping of the Headers of the RecordSet returned by
+// OpenRecordSet, NewRecordSetFromReader, or OpenIndexedRecordSet, so that
+// options such as IncludeMMSI and the sidecar index of OpenIndexedRecordSet find
+// the fields of the file by their aliases in m.
+func ReadMapping(m HeaderMapping) OpenOption {
+	return func(c *openConfig) error {
+		c.mapping = m
+		return nil
+	}
+}
+
+// Canonical returns a copy of h with every field that is an alias in the Mapping
+// renamed to the name used by the package, and no Mapping.  Saving a RecordSet
+// after setting its Headers to the Canonical Headers converts a file to the
+// MarineCadastre.gov column names.
+func (h Headers) Canonical() Headers {
+	fields := append([]string(nil), h.Fields...)
+	for name := range h.Mapping {
+		if i, ok := h.Contains(name); ok {
+			fields[i] = name
+		}
+	}
+	return Headers{Fields: fields}
+}
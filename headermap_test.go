@@ -0,0 +1,43 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeaders_WithMapping(t *testing.T) {
+	data := "mmsi,timestamp,lat,lon,sog\n" +
+		"219000001,2017-12-01T00:00:00,55.1,11.2,10\n" +
+		"219000001,2017-12-01T00:10:00,55.2,11.3,10\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if _, ok := rs.Headers().Contains("MMSI"); ok {
+		t.Fatal("Headers.Contains(MMSI) = true without a Mapping")
+	}
+	rs.SetHeaders(rs.Headers().WithMapping(EMODnetMapping))
+
+	idx, ok := rs.Headers().ContainsMulti("MMSI", "BaseDateTime", "LAT", "LON", "SOG")
+	if !ok || idx["LAT"].Idx != 2 || idx["BaseDateTime"].Idx != 1 {
+		t.Fatalf("Headers.ContainsMulti() = %v, %v, want the aliased columns", idx, ok)
+	}
+	tracks, err := rs.Tracks()
+	if err != nil {
+		t.Fatalf("RecordSet.Tracks() error = %v", err)
+	}
+	if tr := tracks["219000001"]; tr == nil || tr.Len() != 2 {
+		t.Errorf("RecordSet.Tracks() = %v, want one track of two records", tracks)
+	}
+
+	h := Headers{Fields: []string{"Timestamp", "MMSI", "Latitude", "Longitude", "Ship type"}, Mapping: DMAMapping}
+	if got := strings.Join(h.Canonical().Fields, ","); got != "BaseDateTime,MMSI,LAT,LON,VesselType" {
+		t.Errorf("Headers.Canonical() = %s", got)
+	}
+	rec := Record{"01/12/2017 00:00:00", "219000001", "55.5", "12.5", "Cargo"}
+	if lat, lon, err := rec.LatLon(h); err != nil || lat != 55.5 || lon != 12.5 {
+		t.Errorf("Record.LatLon() = %v, %v, %v, want 55.5, 12.5", lat, lon, err)
+	}
+
+	mc := Headers{Fields: []string{"mmsi", "base_date_time", "longitude", "latitude"}, Mapping: MarineCadastreMapping}
+	if i, ok := mc.Contains("LAT"); !ok || i != 3 {
+		t.Errorf("Headers.Contains(LAT) = %d, %v, want 3, true", i, ok)
+	}
+}
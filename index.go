@@ -0,0 +1,344 @@
+package ais
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// IndexExt is the extension appended to the name of a csv file to name its
+// sidecar index.
+const IndexExt = ".idx"
+
+// indexVersion is incremented whenever the layout of indexFile changes so that
+// sidecar files written by older versions are rebuilt.
+const indexVersion = 2
+
+// indexFile is the gob encoded content of a sidecar index.  Size and ModTime of
+// the indexed csv file are stored so that a stale index is detected and rebuilt,
+// as are the delimiter and whether a TimeParser read the times so that an index
+// built with other options is rebuilt.
+type indexFile struct {
+	Version    int
+	Size       int64
+	ModTime    int64
+	Comma      rune
+	TimeParser bool         // BaseDateTime was parsed with ReadTimeParser
+	Entries    []indexEntry // sorted by Time then Offset
+}
+
+// indexEntry locates a single Record in the csv file.
+type indexEntry struct {
+	Offset, Length int64
+	Time           int64 // Unix seconds of BaseDateTime, zero if it does not parse
+	MMSI           string
+}
+
+// recordIndex is the in-memory form of an index held by an indexed RecordSet.
+type recordIndex struct {
+	f       *os.File
+	comma   rune
+	entries []indexEntry
+	byMMSI  map[string][]int // positions in entries
+}
+
+// BuildIndex scans the uncompressed csv file filename and writes a sidecar index
+// of the byte offset of every Record by MMSI and by BaseDateTime to filename plus
+// IndexExt.  The Headers of the file must contain MMSI and BaseDateTime.  Calling
+// BuildIndex is only necessary to prepare the index ahead of time, since
+// OpenIndexedRecordSet builds a missing or stale index itself.  The options are
+// those accepted by OpenIndexedRecordSet.
+func BuildIndex(filename string, opts ...OpenOption) error {
+	cfg, err := indexConfig(opts)
+	if err != nil {
+		return fmt.Errorf("build index: %w", err)
+	}
+	_, err = buildIndex(filename, cfg)
+	return err
+}
+
+// indexConfig applies opts, which may only be those an index can honor.
+func indexConfig(opts []OpenOption) (*openConfig, error) {
+	cfg := &openConfig{comma: ','}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.columns != nil || cfg.mmsis != nil || cfg.intern != nil || cfg.charset != UTF8 {
+		return nil, fmt.Errorf("only Delimiter, LazyQuotes, ReadMetrics, ReadTimeParser, and ReadMapping apply to an indexed recordset")
+	}
+	return cfg, nil
+}
+
+func buildIndex(filename string, cfg *openConfig) (*indexFile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("build index: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("build index: %w", err)
+	}
+	if rc, err := decompress(f); err != nil || rc != nil {
+		return nil, fmt.Errorf("build index: %s must be an uncompressed csv file", filename)
+	}
+
+	idx := &indexFile{
+		Version:    indexVersion,
+		Size:       fi.Size(),
+		ModTime:    fi.ModTime().UnixNano(),
+		Comma:      cfg.comma,
+		TimeParser: cfg.time != nil,
+	}
+	var h Headers
+	var mmsiIndex, timeIndex int
+	headers := false
+
+	br := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := readCSVLine(br)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("build index: %w", err)
+		}
+		start := offset
+		offset += int64(len(line))
+
+		trimmed := bytes.TrimRight(line, "\r\n")
+		if len(trimmed) > 0 && trimmed[0] != '#' { // encoding/csv skips empty and comment lines
+			r := csv.NewReader(bytes.NewReader(trimmed))
+			r.Comma = cfg.comma
+			r.LazyQuotes = true
+			fields, perr := r.Read()
+			if perr != nil {
+				return nil, fmt.Errorf("build index: offset %d: %w", start, perr)
+			}
+			if !headers {
+				h = cfg.headers(Headers{Fields: trimBOM(fields)})
+				hm, err := h.require("MMSI", "BaseDateTime")
+				if err != nil {
+					return nil, fmt.Errorf("build index: %w", err)
+				}
+				mmsiIndex, timeIndex = hm["MMSI"].Idx, hm["BaseDateTime"].Idx
+				headers = true
+			} else {
+				e := indexEntry{Offset: start, Length: int64(len(line))}
+				if mmsiIndex < len(fields) {
+					e.MMSI = fields[mmsiIndex]
+				}
+				if timeIndex < len(fields) {
+					if t, terr := h.parseTime(fields[timeIndex]); terr == nil {
+						e.Time = t.Unix()
+					}
+				}
+				idx.Entries = append(idx.Entries, e)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if !headers {
+		return nil, fmt.Errorf("build index: %s has no headers", filename)
+	}
+	sort.SliceStable(idx.Entries, func(i, j int) bool { return idx.Entries[i].Time < idx.Entries[j].Time })
+
+	out, err := os.Create(filename + IndexExt)
+	if err != nil {
+		return nil, fmt.Errorf("build index: %w", err)
+	}
+	w := bufio.NewWriter(out)
+	if err := gob.NewEncoder(w).Encode(idx); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("build index: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("build index: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return nil, fmt.Errorf("build index: %w", err)
+	}
+	return idx, nil
+}
+
+// readCSVLine returns the bytes of one csv record including its line ending.  A
+// newline inside a quoted field does not end the record.
+func readCSVLine(br *bufio.Reader) ([]byte, error) {
+	var line []byte
+	quotes := 0
+	for {
+		b, err := br.ReadBytes('\n')
+		line = append(line, b...)
+		quotes += bytes.Count(b, []byte{'"'})
+		if err != nil || quotes%2 == 0 {
+			return line, err
+		}
+	}
+}
+
+// loadIndex reads the sidecar index of filename, returning nil if it is missing,
+// unreadable, stale, or built with another delimiter, or without a TimeParser
+// when cfg has one.
+func loadIndex(filename string, fi os.FileInfo, cfg *openConfig) *indexFile {
+	f, err := os.Open(filename + IndexExt)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	idx := new(indexFile)
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(idx); err != nil {
+		return nil
+	}
+	if idx.Version != indexVersion || idx.Size != fi.Size() || idx.ModTime != fi.ModTime().UnixNano() {
+		return nil
+	}
+	if idx.Comma != cfg.comma || (cfg.time != nil && !idx.TimeParser) {
+		return nil
+	}
+	return idx
+}
+
+// OpenIndexedRecordSet is OpenRecordSet for an uncompressed csv file with a
+// sidecar index, which allows ByMMSI and TimeRange to seek directly to the
+// matching Records instead of scanning the whole file.  The index at filename
+// plus IndexExt is built if it does not exist or no longer matches the size and
+// modification time of the file.  The returned RecordSet can also be read
+// sequentially like any other RecordSet.
+//
+// The options Delimiter, LazyQuotes, ReadMetrics, ReadTimeParser, and
+// ReadMapping apply as they do to OpenRecordSet, and the index is built with the
+// same delimiter, TimeParser, and Mapping, so TimeRange finds the Records of a
+// file opened with ReadTimeParser(FlexibleTimeParser) whatever the layout of
+// their timestamps.  An index built without a TimeParser is rebuilt when one is
+// given.  The other options are an error.
+func OpenIndexedRecordSet(filename string, opts ...OpenOption) (*RecordSet, error) {
+	cfg, err := indexConfig(opts)
+	if err != nil {
+		return nil, fmt.Errorf("open indexed recordset: %w", err)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open indexed recordset: %w", err)
+	}
+	idx := loadIndex(filename, fi, cfg)
+	if idx == nil {
+		idx, err = buildIndex(filename, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("open indexed recordset: %w", err)
+		}
+	}
+
+	rs, err := OpenRecordSet(filename, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("open indexed recordset: %w", err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		rs.Close()
+		return nil, fmt.Errorf("open indexed recordset: %w", err)
+	}
+	ri := &recordIndex{f: f, comma: cfg.comma, entries: idx.Entries, byMMSI: make(map[string][]int)}
+	for i, e := range ri.entries {
+		ri.byMMSI[e.MMSI] = append(ri.byMMSI[e.MMSI], i)
+	}
+	rs.index = ri
+	return rs, nil
+}
+
+// ByMMSI returns a pointer to a new RecordSet holding every Record of the
+// vessel in BaseDateTime order, read directly from the positions recorded in
+// the index.  It returns an error if rs was not opened with
+// OpenIndexedRecordSet and ErrEmptySet if the vessel has no Records.  Unlike
+// Subset, ByMMSI does not consume rs.
+func (rs *RecordSet) ByMMSI(mmsi string) (*RecordSet, error) {
+	if rs.index == nil {
+		return nil, fmt.Errorf("by mmsi: recordset is not indexed")
+	}
+	rs2, err := rs.index.read(rs.Headers(), rs.index.byMMSI[mmsi])
+	if err == ErrEmptySet {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("by mmsi: %w", err)
+	}
+	return rs2, nil
+}
+
+// TimeRange returns a pointer to a new RecordSet holding the Records with a
+// BaseDateTime in the interval [t1, t2), in BaseDateTime order, read directly
+// from the positions recorded in the index.  It returns an error if rs was not
+// opened with OpenIndexedRecordSet and ErrEmptySet if no Records are in the
+// interval.  Unlike Subset, TimeRange does not consume rs.
+func (rs *RecordSet) TimeRange(t1, t2 time.Time) (*RecordSet, error) {
+	if rs.index == nil {
+		return nil, fmt.Errorf("time range: recordset is not indexed")
+	}
+	entries := rs.index.entries
+	lo := sort.Search(len(entries), func(i int) bool { return entries[i].Time >= t1.Unix() })
+	hi := sort.Search(len(entries), func(i int) bool { return entries[i].Time >= t2.Unix() })
+	pos := make([]int, 0, hi-lo)
+	for i := lo; i < hi; i++ {
+		pos = append(pos, i)
+	}
+	rs2, err := rs.index.read(rs.Headers(), pos)
+	if err == ErrEmptySet {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("time range: %w", err)
+	}
+	return rs2, nil
+}
+
+// read returns a new RecordSet of the entries at the positions in pos.
+func (ri *recordIndex) read(h Headers, pos []int) (*RecordSet, error) {
+	if len(pos) == 0 {
+		return nil, ErrEmptySet
+	}
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(h)
+	var buf []byte
+	for n, i := range pos {
+		e := ri.entries[i]
+		if int64(cap(buf)) < e.Length {
+			buf = make([]byte, e.Length)
+		}
+		buf = buf[:e.Length]
+		if _, err := ri.f.ReadAt(buf, e.Offset); err != nil {
+			return nil, err
+		}
+		r := csv.NewReader(bytes.NewReader(buf))
+		r.Comma = ri.comma
+		r.LazyQuotes = true
+		rec, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("offset %d: %w", e.Offset, err)
+		}
+		if err := rs2.Write(rec); err != nil {
+			return nil, err
+		}
+		if (n+1)%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, err
+	}
+	return rs2, nil
+}
+
+// close closes the file handle used for random access.
+func (ri *recordIndex) close() error {
+	return ri.f.Close()
+}
@@ -0,0 +1,135 @@
+package ais
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOpenIndexedRecordSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, _ := ioutil.ReadFile("testdata/track.csv")
+	filename := filepath.Join(dir, "track.csv")
+	ioutil.WriteFile(filename, b, 0644)
+
+	rs, err := OpenIndexedRecordSet(filename)
+	if err != nil {
+		t.Fatalf("OpenIndexedRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+	if _, err := os.Stat(filename + IndexExt); err != nil {
+		t.Errorf("OpenIndexedRecordSet() did not write the sidecar index: %v", err)
+	}
+
+	vessel, err := rs.ByMMSI("477307901")
+	if err != nil {
+		t.Fatalf("RecordSet.ByMMSI() error = %v", err)
+	}
+	want := []string{"2017-12-01T00:00:01", "2017-12-01T00:01:01", "2017-12-01T00:02:01"}
+	var got []string
+	for vessel.Next() {
+		got = append(got, (*vessel.Record())[1])
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[2] != want[2] {
+		t.Errorf("RecordSet.ByMMSI() times = %v, want %v", got, want)
+	}
+	if _, err := rs.ByMMSI("000000001"); err != ErrEmptySet {
+		t.Errorf("RecordSet.ByMMSI() for missing vessel error = %v, want ErrEmptySet", err)
+	}
+
+	t1 := time.Date(2017, time.December, 1, 0, 0, 5, 0, time.UTC)
+	window, err := rs.TimeRange(t1, t1.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("RecordSet.TimeRange() error = %v", err)
+	}
+	n := 0
+	for window.Next() {
+		n++
+	}
+	if n != 6 { // 00:00:05 through 00:00:09 and 00:01:01
+		t.Errorf("RecordSet.TimeRange() returned %d records, want 6", n)
+	}
+
+	// The RecordSet can still be read sequentially, and reopening uses the sidecar.
+	n = 0
+	for rs.Next() {
+		n++
+	}
+	if n != 12 {
+		t.Errorf("indexed RecordSet.Next() read %d records, want 12", n)
+	}
+	rs2, err := OpenIndexedRecordSet(filename)
+	if err != nil {
+		t.Fatalf("OpenIndexedRecordSet() reopen error = %v", err)
+	}
+	defer rs2.Close()
+	if len(rs2.index.entries) != 12 {
+		t.Errorf("reopened index holds %d entries, want 12", len(rs2.index.entries))
+	}
+
+	plain, _ := OpenRecordSet("testdata/ten.csv")
+	defer plain.Close()
+	if _, err := plain.ByMMSI("477307901"); err == nil {
+		t.Error("RecordSet.ByMMSI() expected error for a RecordSet without an index")
+	}
+}
+
+func TestOpenIndexedRecordSet_TimeParser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Snake case headers and timestamps in three layouts, one of them an epoch.
+	data := "mmsi;base_date_time;latitude;longitude\n" +
+		"100000001;2017-12-01T00:00:00;30.0;-76.0\n" +
+		"100000002;1512086460;30.1;-76.1\n" +
+		"100000001;2017-12-01T00:02:00Z;30.2;-76.2\n" +
+		"100000002;2017-12-01T01:03:00+01:00;30.3;-76.3\n"
+	filename := filepath.Join(dir, "mixed.csv")
+	ioutil.WriteFile(filename, []byte(data), 0644)
+
+	if _, err := OpenIndexedRecordSet(filename, Delimiter(';')); err == nil {
+		t.Error("OpenIndexedRecordSet() expected error for headers without MMSI and BaseDateTime")
+	}
+	if _, err := OpenIndexedRecordSet(filename, ReadColumns("mmsi")); err == nil {
+		t.Error("OpenIndexedRecordSet() expected error for ReadColumns")
+	}
+
+	// A stale index built with TimeLayout is rebuilt once a TimeParser is given.
+	if err := BuildIndex(filename, Delimiter(';'), ReadMapping(MarineCadastreMapping)); err != nil {
+		t.Fatalf("BuildIndex() error = %v", err)
+	}
+	rs, err := OpenIndexedRecordSet(filename, Delimiter(';'), ReadMapping(MarineCadastreMapping), ReadTimeParser(FlexibleTimeParser))
+	if err != nil {
+		t.Fatalf("OpenIndexedRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+
+	t1 := time.Date(2017, time.December, 1, 0, 1, 0, 0, time.UTC)
+	window, err := rs.TimeRange(t1, t1.Add(3*time.Minute))
+	if err != nil {
+		t.Fatalf("RecordSet.TimeRange() error = %v", err)
+	}
+	var got []string
+	for window.Next() {
+		tm, err := window.Record().Time(window.Headers())
+		if err != nil {
+			t.Fatalf("Record.Time() error = %v", err)
+		}
+		got = append(got, tm.Format(TimeLayout))
+	}
+	want := []string{"2017-12-01T00:01:00", "2017-12-01T00:02:00", "2017-12-01T00:03:00"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RecordSet.TimeRange() times = %v, want %v", got, want)
+	}
+}
@@ -1,36 +1,166 @@
 package ais
 
 import (
-	"encoding/csv"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"hash/fnv"
+	"io"
+	"math"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// pairShards is the number of independently locked maps that hold the RecordPairs
+// of an Interactions set.  Sharding the set allows AddClustersParallel to insert
+// interactions from many goroutines without contending on a single lock.
+const pairShards = 64
+
 // InteractionFields are the default column headers used to write a csv file of two vessel
-// interactions. The first field InteractionHash is an ParirHash64 return value that uniquely
+// interactions. The first field InteractionHash is a PairHash128 return value that uniquely
 // identifies this interaction and Distance(nm) is the haversine distance between the two vessels.
+// Earlier releases wrote a PairHash64 as the InteractionHash, which PairHash64Version with
+// HashV1 reproduces for matching old files against new ones.
 const InteractionFields = "InteractionHash,Distance(nm)," +
 	"MMSI_1,BaseDateTime_1,LAT_1,LON_1,SOG_1,COG_1,Heading_1,VesselName_1,IMO_1,CallSign_1,VesselType_1,Status_1,Length_1,Width_1,Draft_1,Cargo_1,Geohash_1," +
 	"MMSI_2,BaseDateTime_2,LAT_2,LON_2,SOG_2,COG_2,Heading_2,VesselName_2,IMO_2,CallSign_2,VesselType_2,Status_2,Length_2,Width_2,Draft_2,Cargo_2,Geohash_2"
 
 // RecordPair holds pointers to two Records.
 type RecordPair struct {
-	rec1 *Record
-	rec2 *Record
+	rec1    *Record
+	rec2    *Record
+	dist    float64 // distance in nm computed when the pair was added
+	hasDist bool    // dist is set
+	spill   int64   // entry of a pair read back from the spill file, zero for a pair in memory
+}
+
+// pairShard is one lock protected portion of the map[hash]*RecordPair held by
+// Interactions.
+type pairShard struct {
+	sync.Mutex
+	m map[Hash128]*RecordPair // index is PairHash128 return value
 }
 
 // Interactions is an abstraction for two-vessel interactions.  It requires a set of
 // Headers that correspond to the Record slices being compared and it requires a set of
 // Headers for the output.  The default for OutputHeaders is the const InteractionFields
 // with a nil dictionary. The data held by interactions is a
-// map[hash]*RecordPair.  This guarantees a non-duplicative set of interactions in the output.
+// map[hash]*RecordPair split across shards.  This guarantees a non-duplicative set of
+// interactions in the output.
 type Interactions struct {
-	RecordHeaders Headers                // for the Records that will be used to create interactions
-	OutputHeaders Headers                // for an output RecordSet that may be written from the 2-ship interactions
-	hashIndices   [4]int                 // Headers index values for MMSI, BaseDateTime, LAT, and LON
-	data          map[uint64]*RecordPair // uint64 index is PairHash64 return value
+	RecordHeaders Headers               // for the Records that will be used to create interactions
+	OutputHeaders Headers               // for an output RecordSet that may be written from the 2-ship interactions
+	hashIndices   [4]int                // Headers index values for MMSI, BaseDateTime, LAT, and LON
+	data          [pairShards]pairShard // RecordPairs sharded by hash
+	cpa           bool                  // write CPAFields in the output
+	risk          bool                  // write CPAFields and RiskFields in the output
+	encounter     bool                  // write EncounterFields in the output
+	maxDistance   float64               // pairs farther apart in nm are not stored, zero for no limit
+	distance      DistanceFunc          // metric used for maxDistance and the Distance(nm) output
+	maxTimeGap    time.Duration         // pairs reported farther apart in time are not stored, zero for no limit
+	fence         *Geofence             // pairs with either Record outside the fence are not stored, nil for no fence
+	timeBucket    time.Duration         // pairs are collapsed to the closest per vessel pair and bucket, zero for no collapse
+	closest       bool                  // pairs are collapsed to the closest per vessel pair over the whole set
+	columns       []string              // output columns chosen by SetColumns, nil for every column
+	projection    []int                 // index in the full row of each output column, bearingColumn for Bearing
+	recordColumns bool                  // OutputHeaders are built from the RecordHeaders, set by WithRecordColumns
+	order         OutputOrder           // order of the interactions written by Save
+	stations      *StationFilter        // pairs with either MMSI outside the station classes are not stored, nil for all
+	categories    *CategoryFilter       // pairs with either VesselType outside the categories are not stored, nil for all
+	appended      appendState           // pairs written by AppendCSV
+	spill         *spillStore           // pairs moved out of memory by WithMemoryLimit, nil for no limit
+	metrics       *Metrics              // counts the interactions added, set by WithMetrics
+}
+
+// InteractionOption configures an Interactions set created by
+// NewInteractionsWithOptions.
+type InteractionOption func(*Interactions) error
+
+// WithMaxDistance limits the Interactions to pairs of Records that are no more than
+// nm nautical miles apart.  Without this option every pair of Records in a Cluster
+// is stored regardless of their separation.
+func WithMaxDistance(nm float64) InteractionOption {
+	return func(inter *Interactions) error {
+		if nm <= 0 || math.IsNaN(nm) {
+			return fmt.Errorf("max distance must be greater than zero, got %v", nm)
+		}
+		inter.maxDistance = nm
+		return nil
+	}
+}
+
+// WithDistanceFunc sets the metric used to compare pairs against WithMaxDistance and
+// to compute the Distance(nm) output field.  The default is Haversine; pass
+// Vincenty for the WGS-84 geodesic distance.
+func WithDistanceFunc(fn DistanceFunc) InteractionOption {
+	return func(inter *Interactions) error {
+		if fn == nil {
+			return fmt.Errorf("distance func must not be nil")
+		}
+		inter.distance = fn
+		return nil
+	}
+}
+
+// WithMaxTimeGap limits the Interactions to pairs of Records whose BaseDateTime
+// values differ by no more than d.  Without this option membership in the same
+// Cluster is the only requirement for two Records to be paired, which can pair
+// reports that are far apart in time when a Cluster is built from a wide Window.
+func WithMaxTimeGap(d time.Duration) InteractionOption {
+	return func(inter *Interactions) error {
+		if d <= 0 {
+			return fmt.Errorf("max time gap must be greater than zero, got %v", d)
+		}
+		inter.maxTimeGap = d
+		return nil
+	}
+}
+
+// WithTimeBucket collapses the Interactions to a single pair of Records for each
+// pair of vessels in each bucket of width d.  Two vessels shadowing each other for
+// an hour otherwise produce an interaction for every pair of their reports.  The
+// bucket of a pair is the earlier of its two BaseDateTime values truncated to a
+// multiple of d, and the pair kept for each bucket is the one with the minimum
+// distance.  The InteractionHash of a collapsed interaction identifies the vessel
+// pair and bucket rather than the Records.
+func WithTimeBucket(d time.Duration) InteractionOption {
+	return func(inter *Interactions) error {
+		if d <= 0 {
+			return fmt.Errorf("time bucket must be greater than zero, got %v", d)
+		}
+		inter.timeBucket = d
+		return nil
+	}
+}
+
+// WithClosestApproach keeps only the closest pair of Records for each pair of
+// vessels over everything added to the Interactions, which is the single
+// interaction a near miss study needs for each vessel pair.  Each new pair
+// replaces the stored pair of the same vessels when it is closer.  It is the
+// limit of WithTimeBucket for a bucket as wide as the data and cannot be combined
+// with it.  The InteractionHash of each interaction identifies the vessel pair.
+func WithClosestApproach() InteractionOption {
+	return func(inter *Interactions) error {
+		inter.closest = true
+		return nil
+	}
+}
+
+// WithRecordColumns builds the OutputHeaders from the RecordHeaders of each vessel
+// suffixed with _1 and _2 in place of the const InteractionFields, which only
+// names the columns of Records with DefaultFields plus Geohash.  It is needed to
+// write Interactions of Records read from a file with any other set of fields.
+func WithRecordColumns() InteractionOption {
+	return func(inter *Interactions) error {
+		inter.recordColumns = true
+		inter.resetOutputHeaders()
+		return nil
+	}
 }
 
 // NewInteractions creates a new set of interactions.  It requires a set of Headers from the
@@ -38,130 +168,819 @@ type Interactions struct {
 // "BaseDateTime", "LAT", and "LON" in order to uniquely identify an interaction. The returned
 // *Interactions has its output file Headers set to ais.InteractionHeaders by default.
 func NewInteractions(h Headers) (*Interactions, error) {
+	return NewInteractionsWithOptions(h)
+}
+
+// NewInteractionsWithOptions creates a new set of interactions configured by the
+// provided options.  The Headers have the same requirements as NewInteractions.  It
+// returns a nil *Interactions and an error if any option is invalid.
+func NewInteractionsWithOptions(h Headers, opts ...InteractionOption) (*Interactions, error) {
 	inter := new(Interactions)
-	inter.OutputHeaders = Headers{
-		Fields: strings.Split(InteractionFields, ","),
-	}
+	inter.distance = Haversine
 	inter.RecordHeaders = h
-	inter.data = make(map[uint64]*RecordPair)
+	inter.resetOutputHeaders()
+	for i := range inter.data {
+		inter.data[i].m = make(map[Hash128]*RecordPair)
+	}
 
 	// Find the index values for the required headers now so that the expensive parsing
 	// operation only has to be perormed once at initilization
-	mmsiIndex, _ := h.Contains("MMSI")
-	timeIndex, _ := h.Contains("BaseDateTime")
-	latIndex, _ := h.Contains("LAT")
-	lonIndex, _ := h.Contains("LON")
-	inter.hashIndices = [4]int{mmsiIndex, timeIndex, latIndex, lonIndex}
+	for i, field := range [...]string{"MMSI", "BaseDateTime", "LAT", "LON"} {
+		j, ok := h.Contains(field)
+		if !ok {
+			warn("new interactions: interactions cannot be hashed", "missing", field)
+		}
+		inter.hashIndices[i] = j
+	}
+
+	for _, opt := range opts {
+		if err := opt(inter); err != nil {
+			return nil, fmt.Errorf("new interactions: %w", err)
+		}
+	}
+	if inter.maxDistance > 0 {
+		if _, err := h.require("LAT", "LON"); err != nil {
+			return nil, fmt.Errorf("new interactions: max distance: %w", err)
+		}
+	}
+	if inter.maxTimeGap > 0 {
+		if _, err := h.require("BaseDateTime"); err != nil {
+			return nil, fmt.Errorf("new interactions: max time gap: %w", err)
+		}
+	}
+	if inter.fence != nil {
+		if _, err := h.require("LAT", "LON"); err != nil {
+			return nil, fmt.Errorf("new interactions: geofence: %w", err)
+		}
+	}
+	if inter.stations != nil {
+		if _, err := h.require("MMSI"); err != nil {
+			return nil, fmt.Errorf("new interactions: station classes: %w", err)
+		}
+	}
+	if inter.timeBucket > 0 {
+		if _, err := h.require("MMSI", "BaseDateTime", "LAT", "LON"); err != nil {
+			return nil, fmt.Errorf("new interactions: time bucket: %w", err)
+		}
+	}
+	if inter.closest {
+		if inter.timeBucket > 0 {
+			return nil, fmt.Errorf("new interactions: closest approach cannot be combined with a time bucket")
+		}
+		if _, err := h.require("MMSI", "LAT", "LON"); err != nil {
+			return nil, fmt.Errorf("new interactions: closest approach: %w", err)
+		}
+	}
 
 	return inter, nil
 }
 
-// Len returns the number of Interactions in the set.
+// pairDistance returns the distance in nautical miles between the two Records
+// using the DistanceFunc of the Interactions.
+func (inter *Interactions) pairDistance(rec1, rec2 *Record) (float64, error) {
+	latIndex, lonIndex := inter.hashIndices[2], inter.hashIndices[3]
+	lat1, err := rec1.ParseFloat(latIndex)
+	if err != nil {
+		return 0, ErrParse{Field: "LAT", Err: err}
+	}
+	lon1, err := rec1.ParseFloat(lonIndex)
+	if err != nil {
+		return 0, ErrParse{Field: "LON", Err: err}
+	}
+	lat2, err := rec2.ParseFloat(latIndex)
+	if err != nil {
+		return 0, ErrParse{Field: "LAT", Err: err}
+	}
+	lon2, err := rec2.ParseFloat(lonIndex)
+	if err != nil {
+		return 0, ErrParse{Field: "LON", Err: err}
+	}
+	return inter.distance(lat1, lon1, lat2, lon2), nil
+}
+
+// distanceOf returns the distance in nautical miles between the Records of
+// pair, which is computed when the pair is added from the positions parsed once
+// per Cluster, so Save and the other readers of the set do not parse them again.
+func (inter *Interactions) distanceOf(pair *RecordPair) (float64, error) {
+	if pair.hasDist {
+		return pair.dist, nil
+	}
+	return inter.pairDistance(pair.rec1, pair.rec2)
+}
+
+// clusterPositions parses the positions of the Records in a Cluster once for
+// all of their pairs.  It returns nil when the RecordHeaders do not contain LAT
+// and LON.
+func (inter *Interactions) clusterPositions(data []*Record) *Positions {
+	if _, err := inter.RecordHeaders.require("LAT", "LON"); err != nil {
+		return nil
+	}
+	return parsePositions(data, inter.hashIndices[2], inter.hashIndices[3])
+}
+
+// SetCPA controls whether the closest point of approach columns described by
+// CPAFields are computed for each pair and written by Save.  Calling SetCPA resets
+// OutputHeaders to InteractionFields with CPAFields inserted after Distance(nm)
+// when on is true.  Computing CPA requires the RecordHeaders to contain SOG and COG
+// in addition to the fields required by NewInteractions.  Pairs with unavailable
+// SOG or COG values are written with empty CPA fields.
+func (inter *Interactions) SetCPA(on bool) {
+	inter.cpa = on
+	inter.resetOutputHeaders()
+}
+
+// resetOutputHeaders rebuilds OutputHeaders from InteractionFields, or from the
+// RecordHeaders after WithRecordColumns, with the optional computed columns
+// inserted after Distance(nm).
+func (inter *Interactions) resetOutputHeaders() {
+	fields := strings.Split(InteractionFields, ",")
+	if inter.recordColumns {
+		fields = fields[:2]
+		for _, suffix := range []string{"_1", "_2"} {
+			for _, f := range inter.RecordHeaders.Fields {
+				fields = append(fields, f+suffix)
+			}
+		}
+	}
+	var extra []string
+	if inter.cpa || inter.risk {
+		extra = append(extra, strings.Split(CPAFields, ",")...)
+	}
+	if inter.risk {
+		extra = append(extra, strings.Split(RiskFields, ",")...)
+	}
+	if inter.encounter {
+		extra = append(extra, EncounterFields)
+	}
+	fields = append(fields[:2:2], append(extra, fields[2:]...)...)
+	inter.OutputHeaders = Headers{Fields: fields, Time: inter.RecordHeaders.Time}
+	inter.project()
+}
+
+// Len returns the number of Interactions in the set, including those spilled
+// to disk by WithMemoryLimit.
 func (inter *Interactions) Len() int {
-	return len(inter.data)
+	n := inter.spilledLen()
+	for i := range inter.data {
+		inter.data[i].Lock()
+		n += len(inter.data[i].m)
+		inter.data[i].Unlock()
+	}
+	return n
+}
+
+// insert adds pair to the set under hash unless the pair has already been stored.
+// Because hash is a CanonicalPairHash128 the same pair always maps to the same
+// shard regardless of the order its Records were seen in.
+func (inter *Interactions) insert(hash Hash128, pair *RecordPair) {
+	if inter.spill != nil {
+		if _, ok := inter.spill.spilled(hash); ok {
+			return
+		}
+	}
+	shard := &inter.data[hash.shard()]
+	shard.Lock()
+	_, ok := shard.m[hash]
+	if !ok {
+		shard.m[hash] = pair
+	}
+	shard.Unlock()
+	if !ok {
+		inter.metrics.addInteraction()
+	}
+	if !ok && inter.spill != nil {
+		inter.spill.grow(1, pairSize(pair))
+		inter.maybeSpill()
+	}
 }
 
 // AddCluster adds all of the interactions in a given cluster to the set of Interactions
 func (inter *Interactions) AddCluster(c *Cluster) error {
-	for i := range c.Data() {
-		err := inter.writeInteractions(c.data[i:])
+	return inter.AddClusterContext(context.Background(), c)
+}
+
+// AddClusterContext is AddCluster with a Context that is checked before the
+// interactions of each Record in the Cluster are added.  Interactions added before
+// ctx is canceled remain in the set.
+func (inter *Interactions) AddClusterContext(ctx context.Context, c *Cluster) error {
+	pt := startProgress(ctx, "add cluster", nil)
+	if err := inter.addCluster(ctx, c, pt); err != nil {
+		return err
+	}
+	pt.done(c.Size())
+	return nil
+}
+
+// addCluster adds the interactions of c and reports each Record of the Cluster
+// to pt.
+func (inter *Interactions) addCluster(ctx context.Context, c *Cluster, pt *progressTracker) error {
+	n := len(c.data)
+	if c.home > 0 {
+		n = c.home // pairs of neighbor Records are added by their own Cluster
+	}
+	pos := inter.clusterPositions(c.data)
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("add cluster: %w", err)
+		}
+		err := inter.writeInteractions(c.data, pos, i)
 		if err != nil {
 			return err
 		}
+		pt.update(i + 1)
+	}
+	return nil
+}
+
+// timeGap returns the absolute difference between the BaseDateTime of two Records.
+func (inter *Interactions) timeGap(rec1, rec2 *Record) (time.Duration, error) {
+	t1, err := inter.RecordHeaders.parseTime((*rec1)[inter.hashIndices[1]])
+	if err != nil {
+		return 0, ErrParse{Field: "BaseDateTime", Err: err}
+	}
+	t2, err := inter.RecordHeaders.parseTime((*rec2)[inter.hashIndices[1]])
+	if err != nil {
+		return 0, ErrParse{Field: "BaseDateTime", Err: err}
+	}
+	gap := t1.Sub(t2)
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap, nil
+}
+
+// WriteInteraction appends to the set the pairs of data[i] with each later
+// Record in data, whose positions are pos or are parsed as needed when pos is
+// nil.  Calls to writeInteractions stemming from a sliding window will not hold their
+// order because the Window holds its data in a map, so a given pair may be seen as
+// {rec1, rec2} or {rec2, rec1}.  addPair puts each pair in canonical order before
+// it is hashed so both orders are stored once under the same key.
+func (inter *Interactions) writeInteractions(data []*Record, pos *Positions, i int) error {
+	if len(data)-i <= 1 { // only write two vessel interactions
+		return nil
+	}
+	p1 := unparsed
+	if pos != nil {
+		p1 = latLon{pos.Lat[i], pos.Lon[i]}
+	}
+	for j := i + 1; j < len(data); j++ {
+		p2 := unparsed
+		if pos != nil {
+			p2 = latLon{pos.Lat[j], pos.Lon[j]}
+		}
+		if err := inter.addPairAt(data[i], data[j], p1, p2); err != nil {
+			return fmt.Errorf("write interactions: %w", err)
+		}
 	}
 	return nil
 }
 
-// WriteInteraction appends to the set for each pair of interaction in the slice.
-// Note that calls to writeInteractions stemming from a sliding window will not hold
-// their order due to the randomization of ranging over a map.  This occurs because
-// the Window holds its data in a map and after a Slide() the order of these records
-// will be iterated differently. Therefore, this means that the PairHash for a given
-// pair of records may be recorded as the hash of {rec1, rec2} or {rec2, rec1} and
-// both must be checked for existence before a new *RecordPair is inserted into the
-// interactions map.
-func (inter *Interactions) writeInteractions(data []*Record) error {
-	if len(data) <= 1 { // only write two vessel interactions
+// latLon is the parsed position of a Record, NaN when it is not known.
+type latLon struct{ lat, lon float64 }
+
+var unparsed = latLon{math.NaN(), math.NaN()}
+
+func (p latLon) known() bool { return !math.IsNaN(p.lat) && !math.IsNaN(p.lon) }
+
+// addPair stores the interaction between rec1 and rec2 unless the Records are
+// from the same MMSI or the pair is excluded by the max time gap, max distance,
+// geofence, station class, or vessel category options of the Interactions.  With a time bucket
+// the pair replaces a farther pair of the same vessels in the same bucket, and
+// with closest approach a farther pair of the same vessels.
+func (inter *Interactions) addPair(rec1, rec2 *Record) error {
+	return inter.addPairAt(rec1, rec2, unparsed, unparsed)
+}
+
+// addPairAt is addPair with the positions of the Records already parsed.  The
+// Records are parsed again when either position is NaN, so that a Record with
+// an unparsable position returns the same error.
+func (inter *Interactions) addPairAt(rec1, rec2 *Record, p1, p2 latLon) error {
+	// Ignore pairs where it is subsequent reports of the same MMSI
+	if (*rec1)[inter.hashIndices[0]] == (*rec2)[inter.hashIndices[0]] {
 		return nil
 	}
-	rec1 := data[0]
-	for _, rec2 := range data[1:] {
-		// Ignore pairs where it is subsequent reports of the same MMSI
-		if (*rec1)[inter.hashIndices[0]] == (*rec2)[inter.hashIndices[0]] {
-			continue
+	if inter.stations != nil {
+		for _, rec := range []*Record{rec1, rec2} {
+			ok, err := inter.stations.Match(rec)
+			if err != nil {
+				warn("interactions: dropped pair", "MMSI", (*rec)[inter.hashIndices[0]], "error", err)
+			}
+			if !ok {
+				return nil
+			}
+		}
+	}
+	if inter.categories != nil {
+		for _, rec := range []*Record{rec1, rec2} {
+			ok, err := inter.categories.Match(rec)
+			if err != nil {
+				warn("interactions: dropped pair", "MMSI", (*rec)[inter.hashIndices[0]], "error", err)
+			}
+			if !ok {
+				return nil
+			}
+		}
+	}
+	if inter.fence != nil {
+		for _, rec := range []*Record{rec1, rec2} {
+			in, err := inter.fence.Match(rec)
+			if err != nil {
+				return err
+			}
+			if !in {
+				return nil
+			}
 		}
-		hash, err := PairHash64(rec1, rec2, inter.hashIndices)
-		hash2, err := PairHash64(rec2, rec1, inter.hashIndices)
+	}
+	if inter.maxTimeGap > 0 {
+		gap, err := inter.timeGap(rec1, rec2)
 		if err != nil {
-			return fmt.Errorf("write interactions: %v", err)
+			return err
 		}
-		_, ok1 := inter.data[hash]
-		_, ok2 := inter.data[hash2]
-		if !ok1 && !ok2 { // neither Record order has been inserted
-			inter.data[hash] = &RecordPair{rec1, rec2}
+		if gap > inter.maxTimeGap {
+			return nil
 		}
 	}
+	pair := &RecordPair{rec1: rec1, rec2: rec2}
+	if p1.known() && p2.known() {
+		pair.dist, pair.hasDist = inter.distance(p1.lat, p1.lon, p2.lat, p2.lon), true
+	} else if inter.maxDistance > 0 || inter.timeBucket > 0 || inter.closest {
+		d, err := inter.pairDistance(rec1, rec2)
+		if err != nil {
+			return err
+		}
+		pair.dist, pair.hasDist = d, true
+	}
+	if inter.maxDistance > 0 && pair.dist > inter.maxDistance {
+		return nil
+	}
+	if !pairLess(rec1, rec2, inter.hashIndices) { // store the pair in canonical order
+		pair.rec1, pair.rec2 = rec2, rec1
+	}
+	if inter.timeBucket > 0 || inter.closest {
+		hash, err := inter.bucketHash(pair.rec1, pair.rec2)
+		if err != nil {
+			return err
+		}
+		return inter.insertNearest(hash, pair)
+	}
+	hash, err := PairHash128(pair.rec1, pair.rec2, inter.hashIndices)
+	if err != nil {
+		return err
+	}
+	inter.insert(hash, pair)
 	return nil
 }
 
-// Save the interactions to a CSV file.
-func (inter *Interactions) Save(filename string) error {
-	out, err := os.Create(filename)
+// bucketHash returns the key of the vessel pair and time bucket of a pair in
+// canonical order.  With closest approach the key is the vessel pair alone.
+func (inter *Interactions) bucketHash(rec1, rec2 *Record) (Hash128, error) {
+	var sum Hash128
+	if inter.closest {
+		h128 := fnv.New128a()
+		for _, s := range []string{(*rec1)[inter.hashIndices[0]], (*rec2)[inter.hashIndices[0]]} {
+			h128.Write([]byte(s))
+			h128.Write([]byte{0})
+		}
+		copy(sum[:], h128.Sum(nil))
+		return sum, nil
+	}
+	t1, err := inter.RecordHeaders.parseTime((*rec1)[inter.hashIndices[1]])
+	if err != nil {
+		return sum, ErrParse{Field: "BaseDateTime", Err: err}
+	}
+	t2, err := inter.RecordHeaders.parseTime((*rec2)[inter.hashIndices[1]])
+	if err != nil {
+		return sum, ErrParse{Field: "BaseDateTime", Err: err}
+	}
+	if t2.Before(t1) {
+		t1 = t2
+	}
+	bucket := t1.Truncate(inter.timeBucket).Format(TimeLayout)
+
+	h128 := fnv.New128a()
+	for _, s := range []string{(*rec1)[inter.hashIndices[0]], (*rec2)[inter.hashIndices[0]], bucket} {
+		h128.Write([]byte(s))
+		h128.Write([]byte{0})
+	}
+	copy(sum[:], h128.Sum(nil))
+	return sum, nil
+}
+
+// insertNearest adds pair to the set under hash, replacing any pair already
+// stored under hash that is farther apart.  The distance of pair must be set.
+func (inter *Interactions) insertNearest(hash Hash128, pair *RecordPair) error {
+	var spilled bool
+	if inter.spill != nil {
+		var ref spillRef
+		if ref, spilled = inter.spill.spilled(hash); spilled && ref.dist <= pair.dist {
+			return nil
+		}
+	}
+	shard := &inter.data[hash.shard()]
+	shard.Lock()
+	old, ok := shard.m[hash]
+	if ok {
+		oldD, err := inter.distanceOf(old)
+		if err != nil {
+			shard.Unlock()
+			return err
+		}
+		if oldD <= pair.dist {
+			shard.Unlock()
+			return nil
+		}
+	}
+	shard.m[hash] = pair
+	shard.Unlock()
+	if !ok && !spilled {
+		inter.metrics.addInteraction()
+	}
+	if inter.spill != nil {
+		if ok {
+			inter.spill.grow(0, pairSize(pair)-pairSize(old))
+		} else {
+			inter.spill.grow(1, pairSize(pair))
+		}
+		inter.maybeSpill()
+	}
+	return nil
+}
+
+// AddSpatialIndex adds the interactions between every Record in idx and the other
+// Records within nm nautical miles of it.  Unlike AddCluster, pairs that straddle
+// the boundary of a geohash cell are found.  The max distance and max time gap
+// options of the Interactions still apply to each candidate pair.
+func (inter *Interactions) AddSpatialIndex(idx *SpatialIndex, nm float64) error {
+	if nm <= 0 || math.IsNaN(nm) {
+		return fmt.Errorf("add spatial index: radius must be greater than zero, got %v", nm)
+	}
+	for _, rec1 := range idx.Records() {
+		lat, err := rec1.ParseFloat(idx.latIndex)
+		if err != nil {
+			return fmt.Errorf("add spatial index: %w", ErrParse{Field: "LAT", Err: err})
+		}
+		lon, err := rec1.ParseFloat(idx.lonIndex)
+		if err != nil {
+			return fmt.Errorf("add spatial index: %w", ErrParse{Field: "LON", Err: err})
+		}
+		for _, rec2 := range idx.WithinRadius(lat, lon, nm) {
+			if rec2 == rec1 {
+				continue
+			}
+			if err := inter.addPair(rec1, rec2); err != nil {
+				return fmt.Errorf("add spatial index: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// AddClustersParallel adds all of the interactions in clusters to the set of
+// Interactions using the provided number of worker goroutines.  For workers less
+// than one the value of runtime.GOMAXPROCS(0) is used.  The resulting set is the same
+// as calling AddCluster on each Cluster in turn, but the hashing and insertion of
+// interactions is spread across workers.  The first error encountered by any
+// worker is returned and the remaining clusters are not processed.
+func (inter *Interactions) AddClustersParallel(clusters []*Cluster, workers int) error {
+	return inter.AddClustersParallelContext(context.Background(), clusters, workers)
+}
+
+// AddClustersParallelContext is AddClustersParallel with a Context.  When ctx is
+// canceled the workers stop and the Context error is returned.
+func (inter *Interactions) AddClustersParallelContext(ctx context.Context, clusters []*Cluster, workers int) error {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	jobs := make(chan *Cluster)
+	done := make(chan struct{})
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	pt := startProgress(ctx, "add clusters", nil)
+	var added int64
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				if err := inter.addCluster(ctx, c, nil); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(done)
+					})
+				}
+				pt.update(int(atomic.AddInt64(&added, 1)))
+			}
+		}()
+	}
+
+feed:
+	for _, c := range clusters {
+		select {
+		case jobs <- c:
+		case <-done:
+			break feed
+		case <-ctx.Done():
+			once.Do(func() {
+				firstErr = fmt.Errorf("add clusters: %w", ctx.Err())
+				close(done)
+			})
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr == nil {
+		pt.done(int(added))
+	}
+
+	return firstErr
+}
+
+// each calls fn for every hash and *RecordPair in the set in the OutputOrder of
+// the Interactions.  Unordered iteration holds the lock of the shard being
+// visited, while ordered iteration sorts a snapshot of the set.  Iteration stops
+// at the first error returned by fn.
+func (inter *Interactions) each(fn func(hash Hash128, pair *RecordPair) error) error {
+	if inter.order != Unordered {
+		return inter.eachOrdered(fn)
+	}
+	return inter.eachUnordered(fn)
+}
+
+// eachUnordered calls fn for every hash and *RecordPair in the set, first those
+// spilled to disk and then those in memory.
+func (inter *Interactions) eachUnordered(fn func(hash Hash128, pair *RecordPair) error) error {
+	if err := inter.eachSpilled(fn); err != nil {
+		return err
+	}
+	for i := range inter.data {
+		shard := &inter.data[i]
+		shard.Lock()
+		for hash, pair := range shard.m {
+			if err := fn(hash, pair); err != nil {
+				shard.Unlock()
+				return err
+			}
+		}
+		shard.Unlock()
+	}
+	return nil
+}
+
+// row returns the output fields described by OutputHeaders for a single pair.
+func (inter *Interactions) row(hash Hash128, pair *RecordPair) ([]string, error) {
+	d, err := inter.distanceOf(pair)
 	if err != nil {
-		return fmt.Errorf("interactions save: %v", err)
+		return nil, err
+	}
+	pairData := []string{hash.String(), fmt.Sprintf("%.1f", d)}
+	if inter.risk {
+		pairData = append(pairData, inter.riskColumns(pair)...)
+	} else if inter.cpa {
+		cpa, tcpa, err := pair.CPA(inter.RecordHeaders)
+		if err != nil {
+			pairData = append(pairData, "", "")
+		} else {
+			pairData = append(pairData, fmt.Sprintf("%.2f", cpa), fmt.Sprintf("%.1f", tcpa.Minutes()))
+		}
 	}
+	if inter.encounter {
+		if e, err := pair.Encounter(inter.RecordHeaders); err != nil {
+			pairData = append(pairData, "")
+		} else {
+			pairData = append(pairData, e.String())
+		}
+	}
+	pairData = append(pairData, (*pair.rec1)...)
+	pairData = append(pairData, (*pair.rec2)...)
+	if inter.projection != nil {
+		return inter.projectRow(pair, pairData), nil
+	}
+	return pairData, nil
+}
 
-	w := csv.NewWriter(out)
-	err = w.Write(inter.OutputHeaders.Fields)
+// Save the interactions to a CSV file.  Rows are written in the OutputOrder set
+// by SetOrder.
+func (inter *Interactions) Save(filename string) error {
+	return inter.SaveContext(context.Background(), filename)
+}
+
+// SaveContext is Save with a Context that stops the write when it is canceled.
+// The partially written file is left on disk.
+func (inter *Interactions) SaveContext(ctx context.Context, filename string) error {
+	out, err := os.Create(filename)
 	if err != nil {
-		return fmt.Errorf("interactions save: %v", err)
+		return fmt.Errorf("interactions save: %w", err)
 	}
-	w.Flush()
+	defer out.Close()
+	return inter.writeCSV(ctx, out)
+}
+
+// WriteCSV writes the interactions as csv to w, which may be os.Stdout, a
+// compressing writer, or a network connection, in place of the file created by
+// Save.
+func (inter *Interactions) WriteCSV(w io.Writer) error {
+	return inter.WriteCSVContext(context.Background(), w)
+}
+
+// WriteCSVContext is WriteCSV with a Context that stops the write when it is
+// canceled.
+func (inter *Interactions) WriteCSVContext(ctx context.Context, w io.Writer) error {
+	return inter.writeCSV(ctx, w)
+}
+
+// writeCSV writes the OutputHeaders and every interaction to out as csv.
+func (inter *Interactions) writeCSV(ctx context.Context, out io.Writer) error {
+	_, err := inter.writeRows(ctx, out, true, nil)
+	return err
+}
 
-	latIndex, _ := inter.RecordHeaders.Contains("LAT")
-	lonIndex, _ := inter.RecordHeaders.Contains("LON")
+// writeRows writes the OutputHeaders when header is set and then every
+// interaction for which keep, when it is not nil, returns true, to out as csv.
+// It returns the number of interactions written.
+func (inter *Interactions) writeRows(ctx context.Context, out io.Writer, header bool, keep func(Hash128, *RecordPair) bool) (int, error) {
+	w := newRecordWriter(out)
+	if header {
+		err := w.Write(inter.OutputHeaders.Fields)
+		if err != nil {
+			return 0, fmt.Errorf("interactions save: %w", err)
+		}
+		w.Flush()
+	}
 
+	pt := startProgress(ctx, "interactions save", nil)
 	written := 1
-	for hash, pair := range inter.data {
-		d, err := pair.rec1.Distance(*(pair.rec2), latIndex, lonIndex)
+	err := inter.each(func(hash Hash128, pair *RecordPair) error {
+		if keep != nil && !keep(hash, pair) {
+			return nil
+		}
+		if err := canceled(ctx, written-1); err != nil { // the header was written first
+			return fmt.Errorf("interactions save: %w", err)
+		}
+		pt.update(written - 1)
+		pairData, err := inter.row(hash, pair)
 		if err != nil {
-			return fmt.Errorf("interactions save: %v", err)
+			return fmt.Errorf("interactions save: %w", err)
 		}
-		pairData := []string{fmt.Sprintf("%0#16x", hash), fmt.Sprintf("%.1f", d)}
-		pairData = append(pairData, (*pair.rec1)...)
-		pairData = append(pairData, (*pair.rec2)...)
 		w.Write(pairData)
 		written++
 		if written%flushThreshold == 0 {
 			w.Flush()
 			if err := w.Error(); err != nil {
-				return fmt.Errorf("interactions save: flush error: %v", err)
+				return fmt.Errorf("interactions save: flush error: %w", err)
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return written - 1, err
 	}
 	w.Flush()
 	if err := w.Error(); err != nil {
-		return fmt.Errorf("interactions save: flush error: %v", err)
+		return written - 1, fmt.Errorf("interactions save: flush error: %w", err)
 	}
+	pt.done(written - 1)
 
-	return nil
+	return written - 1, nil
 }
 
+// HashVersion identifies the scheme used to compute a PairHash64 or PairHash128.
+// Hashes saved by earlier releases can be reproduced with PairHash64Version and
+// HashV1 to match them against hashes computed with the CurrentHashVersion.
+type HashVersion int
+
+const (
+	// HashV1 is the scheme of PairHash64 before HashV2.  It ignored indices and
+	// hashed the first four fields of each Record back to back, whatever they
+	// held, so it is only correct for Records whose first four fields are MMSI,
+	// BaseDateTime, LAT, and LON, as in DefaultFields.  PairHash128 has no HashV1
+	// scheme.
+	HashV1 HashVersion = 1
+
+	// HashV2 hashes the fields identified by indices, each followed by a zero byte
+	// so that adjacent values cannot run together, after a leading version byte
+	// that makes every HashV2 value differ from the HashV1 value of the same pair.
+	HashV2 HashVersion = 2
+
+	// CurrentHashVersion is the scheme used by PairHash64 and PairHash128, and so
+	// of the InteractionHash written by Interactions.
+	CurrentHashVersion = HashV2
+)
+
 // PairHash64 returns a 64 bit fnv hash from two AIS records based on the string values of
 // MMSI, BaseDateTime, LAT, and LON for each vessel. Indices must
 // contain the index values in rec1 and rec2 for MMSI, BaseDateTime, LAT and LON.
+// The hash is computed with the CurrentHashVersion.
 func PairHash64(rec1, rec2 *Record, indices [4]int) (uint64, error) {
+	return PairHash64Version(rec1, rec2, indices, CurrentHashVersion)
+}
+
+// PairHash64Version is PairHash64 computed with the scheme of version v.
+func PairHash64Version(rec1, rec2 *Record, indices [4]int, v HashVersion) (uint64, error) {
 	h64 := fnv.New64a()
-	for i := range indices {
-		_, err := h64.Write([]byte((*rec1)[i]))
-		if err != nil {
-			return 0, err
+	switch v {
+	case HashV1:
+		for i := range indices {
+			h64.Write([]byte((*rec1)[i]))
+			h64.Write([]byte((*rec2)[i]))
 		}
-		_, err = h64.Write([]byte((*rec2)[i]))
-		if err != nil {
-			return 0, err
+	case HashV2:
+		h64.Write([]byte{byte(v)})
+		for _, idx := range indices {
+			h64.Write([]byte((*rec1)[idx]))
+			h64.Write([]byte{0})
+			h64.Write([]byte((*rec2)[idx]))
+			h64.Write([]byte{0})
 		}
+	default:
+		return 0, fmt.Errorf("pair hash: unknown hash version %d", v)
 	}
-
 	return h64.Sum64(), nil
 }
+
+// Hash128 is a 128 bit hash that identifies an interaction.  At 64 bits the
+// chance of two different pairs colliding becomes significant when billions of
+// pairs are processed, and a collision silently merges two interactions, so
+// Interactions are keyed on a Hash128.
+type Hash128 [16]byte
+
+// String returns the hash as a 0x prefixed, 32 digit hexadecimal number.
+func (h Hash128) String() string {
+	return fmt.Sprintf("%#x", h[:])
+}
+
+// ParseHash128 parses the 0x prefixed hexadecimal form of a Hash128 written by
+// its String method.
+func ParseHash128(s string) (Hash128, error) {
+	var h Hash128
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return h, fmt.Errorf("parse hash: %w", err)
+	}
+	if len(b) != len(h) {
+		return h, fmt.Errorf("parse hash: %d bytes, want %d", len(b), len(h))
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// shard returns the index of the pairShard that holds the hash.
+func (h Hash128) shard() uint64 {
+	return binary.BigEndian.Uint64(h[8:]) % pairShards
+}
+
+// PairHash128 returns a 128 bit fnv hash from two AIS records based on the string
+// values of MMSI, BaseDateTime, LAT, and LON for each vessel.  Indices must contain
+// the index values in rec1 and rec2 for MMSI, BaseDateTime, LAT and LON.
+// The hash is computed with the CurrentHashVersion.
+func PairHash128(rec1, rec2 *Record, indices [4]int) (Hash128, error) {
+	return PairHash128Version(rec1, rec2, indices, CurrentHashVersion)
+}
+
+// PairHash128Version is PairHash128 computed with the scheme of version v, which
+// must be HashV2 or later.
+func PairHash128Version(rec1, rec2 *Record, indices [4]int, v HashVersion) (Hash128, error) {
+	var sum Hash128
+	h128 := fnv.New128a()
+	switch v {
+	case HashV2:
+		h128.Write([]byte{byte(v)})
+		for _, idx := range indices {
+			h128.Write([]byte((*rec1)[idx]))
+			h128.Write([]byte{0})
+			h128.Write([]byte((*rec2)[idx]))
+			h128.Write([]byte{0})
+		}
+	default:
+		return sum, fmt.Errorf("pair hash: unknown hash version %d", v)
+	}
+	copy(sum[:], h128.Sum(nil))
+	return sum, nil
+}
+
+// pairLess reports whether rec1 sorts before or equal to rec2 when their MMSI,
+// BaseDateTime, LAT, and LON values are compared lexicographically in that order.
+func pairLess(rec1, rec2 *Record, indices [4]int) bool {
+	for _, idx := range indices {
+		if (*rec1)[idx] != (*rec2)[idx] {
+			return (*rec1)[idx] < (*rec2)[idx]
+		}
+	}
+	return true
+}
+
+// CanonicalPairHash128 is an order independent PairHash128.  The two Records are
+// sorted by their MMSI, BaseDateTime, LAT, and LON values before hashing, so
+// CanonicalPairHash128(rec1, rec2, indices) equals
+// CanonicalPairHash128(rec2, rec1, indices) and a pair only has to be hashed and
+// looked up once.
+func CanonicalPairHash128(rec1, rec2 *Record, indices [4]int) (Hash128, error) {
+	if !pairLess(rec1, rec2, indices) {
+		rec1, rec2 = rec2, rec1
+	}
+	return PairHash128(rec1, rec2, indices)
+}
@@ -0,0 +1,480 @@
+package ais
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// testClusters returns n Clusters that each hold size Records from distinct vessels.
+func testClusters(n, size int) []*Cluster {
+	start := time.Date(2017, time.December, 1, 0, 0, 0, 0, time.UTC)
+	clusters := make([]*Cluster, n)
+	for i := range clusters {
+		c := new(Cluster)
+		for j := 0; j < size; j++ {
+			rec := Record{
+				fmt.Sprintf("%09d", 100000000+j),
+				start.Add(time.Duration(i) * time.Second).Format(TimeLayout),
+				fmt.Sprintf("%.5f", 30+float64(i)*0.001),
+				fmt.Sprintf("%.5f", -76+float64(j)*0.001),
+				"10.0", "90.0", "511.0", "", "", "", "", "", "", "", "", "",
+			}
+			c.Append(&rec)
+		}
+		clusters[i] = c
+	}
+	return clusters
+}
+
+func TestInteractions_AddClustersParallel(t *testing.T) {
+	clusters := testClusters(50, 10)
+
+	seq, _ := NewInteractions(goodHeaders)
+	for _, c := range clusters {
+		if err := seq.AddCluster(c); err != nil {
+			t.Fatalf("Interactions.AddCluster() error = %v", err)
+		}
+	}
+
+	for _, workers := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			par, _ := NewInteractions(goodHeaders)
+			if err := par.AddClustersParallel(clusters, workers); err != nil {
+				t.Fatalf("Interactions.AddClustersParallel() error = %v", err)
+			}
+			if par.Len() != seq.Len() {
+				t.Errorf("Interactions.AddClustersParallel() Len() = %d, want %d", par.Len(), seq.Len())
+			}
+			if want := 50 * 10 * 9 / 2; par.Len() != want {
+				t.Errorf("Interactions.AddClustersParallel() Len() = %d, want %d", par.Len(), want)
+			}
+		})
+	}
+}
+
+func BenchmarkInteractions_AddCluster(b *testing.B) {
+	clusters := testClusters(200, 50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inter, _ := NewInteractions(goodHeaders)
+		for _, c := range clusters {
+			inter.AddCluster(c)
+		}
+	}
+}
+
+func BenchmarkInteractions_AddClustersParallel(b *testing.B) {
+	clusters := testClusters(200, 50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inter, _ := NewInteractions(goodHeaders)
+		inter.AddClustersParallel(clusters, 0)
+	}
+}
+
+func TestNewInteractionsWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       Headers
+		opts    []InteractionOption
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name:    "no options stores every pair",
+			h:       goodHeaders,
+			wantLen: 45,
+		},
+		{
+			name:    "max distance",
+			h:       goodHeaders,
+			opts:    []InteractionOption{WithMaxDistance(0.2)},
+			wantLen: 24, // vessels are 0.052nm apart in testClusters so 9+8+7 pairs are within 3 steps
+		},
+		{
+			name:    "max distance with equirectangular metric",
+			h:       goodHeaders,
+			opts:    []InteractionOption{WithMaxDistance(0.2), WithDistanceFunc(Equirectangular)},
+			wantLen: 24,
+		},
+		{
+			name:    "max time gap",
+			h:       goodHeaders,
+			opts:    []InteractionOption{WithMaxTimeGap(time.Second)},
+			wantLen: 45, // every vessel in a cluster from testClusters reports at the same time
+		},
+		{
+			name:    "zero max time gap",
+			h:       goodHeaders,
+			opts:    []InteractionOption{WithMaxTimeGap(0)},
+			wantErr: true,
+		},
+		{
+			name:    "max time gap without BaseDateTime",
+			h:       Headers{Fields: []string{"MMSI", "LAT", "LON"}},
+			opts:    []InteractionOption{WithMaxTimeGap(time.Minute)},
+			wantErr: true,
+		},
+		{
+			name:    "negative max distance",
+			h:       goodHeaders,
+			opts:    []InteractionOption{WithMaxDistance(-1)},
+			wantErr: true,
+		},
+		{
+			name:    "nil distance func",
+			h:       goodHeaders,
+			opts:    []InteractionOption{WithDistanceFunc(nil)},
+			wantErr: true,
+		},
+		{
+			name:    "max distance without LAT and LON",
+			h:       Headers{Fields: []string{"MMSI", "BaseDateTime"}},
+			opts:    []InteractionOption{WithMaxDistance(1)},
+			wantErr: true,
+		},
+		{
+			name:    "geofence",
+			h:       goodHeaders,
+			opts:    []InteractionOption{WithGeofence(testFence)},
+			wantLen: 10, // the first 5 vessels of testClusters are inside testFence
+		},
+		{
+			name:    "geofence without LAT and LON",
+			h:       Headers{Fields: []string{"MMSI", "BaseDateTime"}},
+			opts:    []InteractionOption{WithGeofence(testFence)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inter, err := NewInteractionsWithOptions(tt.h, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewInteractionsWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := inter.AddCluster(testClusters(1, 10)[0]); err != nil {
+				t.Fatalf("Interactions.AddCluster() error = %v", err)
+			}
+			if inter.Len() != tt.wantLen {
+				t.Errorf("Interactions.Len() = %d, want %d", inter.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestInteractions_WithMaxTimeGap(t *testing.T) {
+	inter, err := NewInteractionsWithOptions(goodHeaders, WithMaxTimeGap(90*time.Second))
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	c := new(Cluster)
+	for i, ts := range []string{"2017-12-01T00:00:00", "2017-12-01T00:01:00", "2017-12-01T00:03:00"} {
+		rec := Record{fmt.Sprintf("%09d", i), ts, "30.0", "-76.0", "", "", "", "", "", "", "", "", "", "", "", ""}
+		c.Append(&rec)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	// Only the first two reports are within 90 seconds of one another.
+	if inter.Len() != 1 {
+		t.Errorf("Interactions.Len() = %d, want 1", inter.Len())
+	}
+
+	bad := new(Cluster)
+	bad.Append(&testRec0)
+	bad.Append(&testRec4)
+	if err := inter.AddCluster(bad); err == nil {
+		t.Errorf("Interactions.AddCluster() expected error for unparsable BaseDateTime")
+	}
+}
+
+func TestPairHash128(t *testing.T) {
+	c := testClusters(1, 3)[0]
+	indices := [4]int{0, 1, 2, 3}
+	h12, err := PairHash128(c.data[0], c.data[1], indices)
+	if err != nil {
+		t.Fatalf("PairHash128() error = %v", err)
+	}
+	h21, _ := PairHash128(c.data[1], c.data[0], indices)
+	h13, _ := PairHash128(c.data[0], c.data[2], indices)
+	if h12 == h13 || h12 == h21 {
+		t.Errorf("PairHash128() returned equal hashes for different pairs")
+	}
+	if s := h12.String(); len(s) != 34 || s[:2] != "0x" {
+		t.Errorf("Hash128.String() = %q, want 0x followed by 32 hex digits", s)
+	}
+	if h, err := ParseHash128(h12.String()); err != nil || h != h12 {
+		t.Errorf("ParseHash128(%s) = %s, %v", h12, h, err)
+	}
+	if _, err := ParseHash128("0x1234"); err == nil {
+		t.Errorf("ParseHash128() of a short hash error = nil, want an error")
+	}
+
+	// Moving a digit across the boundary of two values must change the hash.
+	a1 := Record{"366940480", "2017-12-01T00:00:00", "36.9", "1.5"}
+	a2 := Record{"366940480", "2017-12-01T00:00:00", "36.91", "1.5"}
+	b1 := Record{"366940480", "2017-12-01T00:00:00", "36.9", "11.5"}
+	b2 := Record{"366940480", "2017-12-01T00:00:00", "36.9", "1.5"}
+	ha, _ := PairHash128(&a1, &a2, indices)
+	hb, _ := PairHash128(&b1, &b2, indices)
+	if ha == hb {
+		t.Errorf("PairHash128() = %s for two different pairs", ha)
+	}
+}
+
+func TestCanonicalPairHash128(t *testing.T) {
+	c := testClusters(1, 2)[0]
+	indices := [4]int{0, 1, 2, 3}
+	h12, err := CanonicalPairHash128(c.data[0], c.data[1], indices)
+	if err != nil {
+		t.Fatalf("CanonicalPairHash128() error = %v", err)
+	}
+	h21, _ := CanonicalPairHash128(c.data[1], c.data[0], indices)
+	if h12 != h21 {
+		t.Errorf("CanonicalPairHash128() = %v and %v for the two orders of one pair", h12, h21)
+	}
+
+	// Adding a pair in both orders stores it once, with the lower MMSI first.
+	inter, _ := NewInteractions(goodHeaders)
+	inter.addPair(c.data[1], c.data[0])
+	inter.addPair(c.data[0], c.data[1])
+	if inter.Len() != 1 {
+		t.Fatalf("Interactions.Len() = %d, want 1", inter.Len())
+	}
+	inter.each(func(hash Hash128, pair *RecordPair) error {
+		if hash != h12 || pair.rec1 != c.data[0] {
+			t.Errorf("Interactions stored %v with first MMSI %s, want %v with %s", hash, (*pair.rec1)[0], h12, (*c.data[0])[0])
+		}
+		return nil
+	})
+}
+
+func TestInteractions_WithTimeBucket(t *testing.T) {
+	// Two vessels converge over ten minutes, reporting every minute.
+	c := new(Cluster)
+	start := time.Date(2017, time.December, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		for j, mmsi := range []string{"100000000", "100000001"} {
+			rec := Record{
+				mmsi,
+				start.Add(time.Duration(i) * time.Minute).Format(TimeLayout),
+				"30.00000",
+				fmt.Sprintf("%.5f", -76+float64(j)*0.01*float64(10-i)),
+				"10.0", "90.0", "511.0", "", "", "", "", "", "", "", "", "",
+			}
+			c.Append(&rec)
+		}
+	}
+
+	inter, err := NewInteractionsWithOptions(goodHeaders, WithTimeBucket(5*time.Minute), WithMaxTimeGap(time.Second))
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	if inter.Len() != 2 {
+		t.Fatalf("Interactions.Len() = %d, want 2 buckets", inter.Len())
+	}
+	var times []string
+	inter.each(func(hash Hash128, pair *RecordPair) error {
+		times = append(times, (*pair.rec1)[1])
+		return nil
+	})
+	sort.Strings(times)
+	want := []string{"2017-12-01T00:04:00", "2017-12-01T00:09:00"}
+	if !reflect.DeepEqual(times, want) {
+		t.Errorf("Interactions with time bucket kept pairs at %v, want %v", times, want)
+	}
+
+	if _, err := NewInteractionsWithOptions(goodHeaders, WithTimeBucket(0)); err == nil {
+		t.Error("NewInteractionsWithOptions() expected error for zero time bucket")
+	}
+}
+
+func TestInteractions_WithClosestApproach(t *testing.T) {
+	// Two vessels converge and then separate over twenty minutes, reporting every
+	// minute, and a third vessel passes by once.
+	c := new(Cluster)
+	start := time.Date(2017, time.December, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		for j, mmsi := range []string{"100000000", "100000001"} {
+			rec := Record{
+				mmsi,
+				start.Add(time.Duration(i) * time.Minute).Format(TimeLayout),
+				"30.00000",
+				fmt.Sprintf("%.5f", -76+float64(j)*0.001*math.Abs(float64(12-i))),
+				"10.0", "90.0", "511.0", "", "", "", "", "", "", "", "", "",
+			}
+			c.Append(&rec)
+		}
+	}
+	other := Record{"100000002", start.Format(TimeLayout), "30.00100", "-76.00000", "", "", "", "", "", "", "", "", "", "", "", ""}
+	c.Append(&other)
+
+	inter, err := NewInteractionsWithOptions(goodHeaders, WithClosestApproach(), WithMaxTimeGap(time.Second))
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	if inter.Len() != 3 {
+		t.Fatalf("Interactions.Len() = %d, want 3 vessel pairs", inter.Len())
+	}
+	var times []string
+	inter.each(func(hash Hash128, pair *RecordPair) error {
+		if (*pair.rec1)[0] == "100000000" && (*pair.rec2)[0] == "100000001" {
+			times = append(times, (*pair.rec1)[1])
+		}
+		return nil
+	})
+	if len(times) != 1 || times[0] != "2017-12-01T00:12:00" {
+		t.Errorf("Interactions with closest approach kept pairs at %v, want [2017-12-01T00:12:00]", times)
+	}
+
+	if _, err := NewInteractionsWithOptions(goodHeaders, WithClosestApproach(), WithTimeBucket(time.Minute)); err == nil {
+		t.Error("NewInteractionsWithOptions() expected error for closest approach with a time bucket")
+	}
+	if _, err := NewInteractionsWithOptions(Headers{Fields: []string{"MMSI"}}, WithClosestApproach()); err == nil {
+		t.Error("NewInteractionsWithOptions() expected error for missing LAT and LON headers")
+	}
+}
+
+func TestInteractions_WithRecordColumns(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	if got := strings.Join(inter.OutputHeaders.Fields, ","); got != InteractionFields {
+		t.Errorf("default OutputHeaders = %s, want InteractionFields", got)
+	}
+
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON", "Source"}}
+	inter, err := NewInteractionsWithOptions(h, WithRecordColumns())
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	inter.SetCPA(true)
+	want := "InteractionHash,Distance(nm)," + CPAFields +
+		",MMSI_1,BaseDateTime_1,LAT_1,LON_1,Source_1,MMSI_2,BaseDateTime_2,LAT_2,LON_2,Source_2"
+	if got := strings.Join(inter.OutputHeaders.Fields, ","); got != want {
+		t.Errorf("OutputHeaders with record columns = %s, want %s", got, want)
+	}
+}
+
+func TestPairHash64(t *testing.T) {
+	c := testClusters(1, 2)[0]
+	rec1, rec2 := c.data[0], c.data[1]
+
+	// HashV1 reproduces the hashes saved before HashV2, which hashed the first
+	// four fields whatever the indices.
+	v1, err := PairHash64Version(rec1, rec2, [4]int{12, 13, 14, 15}, HashV1)
+	if err != nil {
+		t.Fatalf("PairHash64Version() error = %v", err)
+	}
+	legacy := fnv.New64a()
+	for i := 0; i < 4; i++ {
+		legacy.Write([]byte((*rec1)[i]))
+		legacy.Write([]byte((*rec2)[i]))
+	}
+	if v1 != legacy.Sum64() {
+		t.Errorf("PairHash64Version(HashV1) = %#x, want %#x", v1, legacy.Sum64())
+	}
+
+	indices := [4]int{0, 1, 2, 3}
+	v2, _ := PairHash64(rec1, rec2, indices)
+	if v1, _ := PairHash64Version(rec1, rec2, indices, HashV1); v1 == v2 {
+		t.Error("PairHash64() returned the HashV1 value")
+	}
+	if _, err := PairHash64Version(rec1, rec2, indices, 0); err == nil {
+		t.Error("PairHash64Version() expected error for unknown version")
+	}
+}
+
+func TestPairHash128Version(t *testing.T) {
+	c := testClusters(1, 2)[0]
+	rec1, rec2 := c.data[0], c.data[1]
+	indices := [4]int{0, 1, 2, 3}
+
+	// HashV2 hashes a version byte and then each value followed by a zero byte.
+	v2, err := PairHash128Version(rec1, rec2, indices, HashV2)
+	if err != nil {
+		t.Fatalf("PairHash128Version() error = %v", err)
+	}
+	h := fnv.New128a()
+	h.Write([]byte{byte(HashV2)})
+	for _, i := range indices {
+		h.Write([]byte((*rec1)[i] + "\x00" + (*rec2)[i] + "\x00"))
+	}
+	var want Hash128
+	copy(want[:], h.Sum(nil))
+	if v2 != want {
+		t.Errorf("PairHash128Version(HashV2) = %s, want %s", v2, want)
+	}
+	if cur, _ := PairHash128(rec1, rec2, indices); cur != v2 {
+		t.Errorf("PairHash128() = %s, want the HashV2 value %s", cur, v2)
+	}
+
+	// PairHash128 never shipped with HashV1.
+	for _, v := range []HashVersion{0, HashV1} {
+		if _, err := PairHash128Version(rec1, rec2, indices, v); err == nil {
+			t.Errorf("PairHash128Version(%d) expected error", v)
+		}
+	}
+}
+
+func TestPairHash64_Properties(t *testing.T) {
+	cfg := &quick.Config{MaxCount: 200}
+
+	// The hash depends on the fields at indices and on no other field.
+	onlyIndices := func(a, b [6]string, other string) bool {
+		rec1, rec2 := Record(a[:]), Record(b[:])
+		indices := [4]int{5, 3, 1, 0}
+		h, _ := PairHash64(&rec1, &rec2, indices)
+		rec1[2], rec2[4] = other, other
+		g, _ := PairHash64(&rec1, &rec2, indices)
+		return h == g
+	}
+	if err := quick.Check(onlyIndices, cfg); err != nil {
+		t.Errorf("PairHash64() depends on fields outside indices: %v", err)
+	}
+
+	// Reordering the fields of both Records along with indices leaves the hash
+	// unchanged.
+	reordered := func(a, b [4]string) bool {
+		rec1, rec2 := Record(a[:]), Record(b[:])
+		h, _ := PairHash64(&rec1, &rec2, [4]int{0, 1, 2, 3})
+		r1 := Record{a[3], a[2], a[1], a[0]}
+		r2 := Record{b[3], b[2], b[1], b[0]}
+		g, _ := PairHash64(&r1, &r2, [4]int{3, 2, 1, 0})
+		return h == g
+	}
+	if err := quick.Check(reordered, cfg); err != nil {
+		t.Errorf("PairHash64() is not determined by the indexed values: %v", err)
+	}
+
+	// Moving text from the end of one field to the start of the next changes the
+	// hash.
+	boundaries := func(a, b [4]string, s string) bool {
+		if s == "" {
+			return true
+		}
+		indices := [4]int{0, 1, 2, 3}
+		rec1, rec2 := Record{a[0] + s, a[1], a[2], a[3]}, Record(b[:])
+		h, _ := PairHash64(&rec1, &rec2, indices)
+		rec1 = Record{a[0], s + a[1], a[2], a[3]}
+		g, _ := PairHash64(&rec1, &rec2, indices)
+		return h != g
+	}
+	if err := quick.Check(boundaries, cfg); err != nil {
+		t.Errorf("PairHash64() collides when field boundaries move: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package ais
+
+import "fmt"
+
+// DefaultInternFields are the fields shared by ReadInterned when no fields are
+// given.  They describe the vessel rather than its position, so each value is
+// repeated in every Record of a vessel.
+var DefaultInternFields = []string{"VesselName", "CallSign", "IMO", "VesselType"}
+
+// MaxInterned is the largest number of distinct values a RecordSet opened with
+// ReadInterned shares.  Values first read after the limit is reached are copied
+// like any other field, which bounds the memory held by a column that turns out
+// not to repeat.
+var MaxInterned = 1 << 20
+
+// ReadInterned shares the storage of identical values of the named fields
+// among every Record read from the RecordSet, in place of a copy per Record.
+// The vessel name, call sign, IMO number, and vessel type of a ship repeat in
+// each of the thousands of reports it makes in a day, so interning them cuts
+// the memory held by Records read into a slice or a Window.  The other fields of
+// a Record are copied into one string, which releases the line they were parsed
+// from.  BenchmarkReadInterned reports the heap held per Record with and without
+// interning.  With no fields the DefaultInternFields that are
+// in the file are interned, and named fields must be in the Headers.  Records
+// reused by Next after SetReuseRecord are not interned, since they are not
+// kept.
+func ReadInterned(fields ...string) OpenOption {
+	return func(c *openConfig) error {
+		c.intern = append([]string{}, fields...)
+		return nil
+	}
+}
+
+// internPool holds the interned values of a RecordSet.
+type internPool struct {
+	shared []bool // by field index in the Record
+	values map[string]string
+}
+
+// internFields interns the fields of the Records read from rs from now on, or
+// the DefaultInternFields in the Headers if fields is empty.
+func (rs *RecordSet) internFields(fields []string) error {
+	pool := &internPool{
+		shared: make([]bool, len(rs.h.Fields)),
+		values: make(map[string]string),
+	}
+	if len(fields) == 0 {
+		for _, f := range DefaultInternFields {
+			if i, ok := rs.h.Contains(f); ok {
+				pool.shared[i] = true
+			}
+		}
+	} else {
+		idx, err := rs.h.require(fields...)
+		if err != nil {
+			return fmt.Errorf("read interned: %w", err)
+		}
+		for _, f := range fields {
+			pool.shared[idx[f].Idx] = true
+		}
+	}
+	rs.pool = pool
+	return nil
+}
+
+// record replaces the fields of rec, parsed from one line, with values that do
+// not share the memory of the line: interned fields with their interned copy and
+// the others with slices of a single new string, which costs one allocation
+// rather than one per field.
+func (p *internPool) record(rec []string) {
+	n := 0
+	for i, f := range rec {
+		if !p.interned(i) {
+			n += len(f)
+		}
+	}
+	b := make([]byte, 0, n)
+	for i, f := range rec {
+		if !p.interned(i) {
+			b = append(b, f...)
+		}
+	}
+	rest := string(b)
+	for i, f := range rec {
+		if p.interned(i) {
+			rec[i] = p.intern(f)
+			continue
+		}
+		rec[i], rest = rest[:len(f)], rest[len(f):]
+	}
+}
+
+func (p *internPool) interned(i int) bool {
+	return i < len(p.shared) && p.shared[i]
+}
+
+// intern returns the shared copy of s.
+func (p *internPool) intern(s string) string {
+	if v, ok := p.values[s]; ok {
+		return v
+	}
+	v := string(append([]byte(nil), s...))
+	if len(p.values) < MaxInterned {
+		p.values[v] = v
+	}
+	return v
+}
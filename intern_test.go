@@ -0,0 +1,130 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestReadInterned(t *testing.T) {
+	data := `MMSI,BaseDateTime,LAT,LON,VesselName,CallSign
+1,2017-12-01T00:00:00,36.9,-76.1,EVER GIVEN,H3RC
+1,2017-12-01T00:01:00,36.9,-76.1,EVER GIVEN,H3RC
+2,2017-12-01T00:01:00,36.8,-76.0,MAERSK OHIO,WMAO
+`
+	rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{}, ReadInterned())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recs []*Record
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("read %d records, want 3", len(recs))
+	}
+	if (*recs[0])[4] != "EVER GIVEN" || (*recs[2])[5] != "WMAO" || (*recs[1])[2] != "36.9" {
+		t.Errorf("records = %v %v %v", *recs[0], *recs[1], *recs[2])
+	}
+	for _, i := range []int{4, 5} {
+		a, b := (*recs[0])[i], (*recs[1])[i]
+		if (*reflectString(a)).Data != (*reflectString(b)).Data {
+			t.Errorf("field %d of the first two records is not shared", i)
+		}
+	}
+	if a, b := (*recs[0])[2], (*recs[1])[2]; (*reflectString(a)).Data == (*reflectString(b)).Data {
+		t.Error("LAT, which is not interned, is shared")
+	}
+
+	rs, err = NewRecordSetFromReader(strings.NewReader(data), Headers{},
+		ReadColumns("MMSI", "VesselName"), ReadInterned("VesselName"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1, _ := rs.Read()
+	r2, _ := rs.Read()
+	if (*r1)[1] != "EVER GIVEN" || (*reflectString((*r1)[1])).Data != (*reflectString((*r2)[1])).Data {
+		t.Errorf("projected VesselName %q not shared with %q", (*r1)[1], (*r2)[1])
+	}
+
+	if _, err := NewRecordSetFromReader(strings.NewReader(data), Headers{}, ReadInterned("IMO")); err == nil {
+		t.Error("ReadInterned with a missing field returned no error")
+	}
+}
+
+type stringHeader struct {
+	Data uintptr
+	Len  int
+}
+
+func reflectString(s string) *stringHeader {
+	return (*stringHeader)(unsafe.Pointer(&s))
+}
+
+// internData returns a csv file of n Records reported by 100 vessels in turn.
+func internData(n int) string {
+	var b strings.Builder
+	b.WriteString("MMSI,BaseDateTime,LAT,LON,SOG,COG,Heading,VesselName,IMO,CallSign,VesselType,Status,Length,Width,Draft,Cargo\n")
+	for i := 0; i < n; i++ {
+		v := i % 100
+		fmt.Fprintf(&b, "%d,2017-12-01T%02d:%02d:%02d,36.%05d,-76.%05d,12.3,131.0,130.0,VESSEL NUMBER %d,IMO%07d,WDC%04d,70,under way using engine,200,32,10.5,70\n",
+			366000000+v, i/3600%24, i/60%60, i%60, i, i, v, 9000000+v, v)
+	}
+	return b.String()
+}
+
+// BenchmarkReadInterned reads every Record of a file into a slice with and
+// without ReadInterned, with every field and with ReadColumns, and reports the heap held per Record.
+func BenchmarkReadInterned(b *testing.B) {
+	data := internData(20000)
+	cols := []string{"MMSI", "BaseDateTime", "LAT", "LON", "VesselName", "IMO", "CallSign", "VesselType"}
+	for _, bm := range []struct {
+		name string
+		opts []OpenOption
+	}{
+		{"plain", nil},
+		{"interned", []OpenOption{ReadInterned()}},
+		{"columns", []OpenOption{ReadColumns(cols...)}},
+		{"columns-interned", []OpenOption{ReadColumns(cols...), ReadInterned()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			var held uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{}, bm.opts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var recs []*Record
+				for {
+					rec, err := rs.Read()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+					recs = append(recs, rec)
+				}
+				rs = nil
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				held += after.HeapAlloc - before.HeapAlloc
+				runtime.KeepAlive(recs)
+			}
+			b.ReportMetric(float64(held)/float64(b.N)/20000, "heapB/record")
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Interpolation selects how Track.Interpolate estimates positions between two
+// reports.
+type Interpolation int
+
+const (
+	// Linear interpolates latitude and longitude independently.  It is accurate for
+	// the short gaps between reports from a moving vessel.
+	Linear Interpolation = iota
+
+	// GreatCircle interpolates along the great circle between the two reports.
+	GreatCircle
+)
+
+// String implements the Stringer interface for Interpolation.
+func (m Interpolation) String() string {
+	switch m {
+	case Linear:
+		return "linear"
+	case GreatCircle:
+		return "great circle"
+	}
+	return fmt.Sprintf("Interpolation(%d)", int(m))
+}
+
+// Interpolate returns a new *Track with one Record at every multiple of interval
+// between the start and end of the Track.  Sample times are aligned to multiples
+// of interval rather than to the first report so that the interpolated Tracks of
+// different vessels share a common time base and can be compared at the same
+// instant.  LAT and LON are interpolated with method.  SOG is interpolated
+// linearly, and COG and Heading are interpolated along the shorter arc when both
+// reports have available values.  All other fields are copied from the earlier
+// report.
+func (t *Track) Interpolate(interval time.Duration, method Interpolation) (*Track, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("track interpolate: interval must be positive, got %v", interval)
+	}
+	if method != Linear && method != GreatCircle {
+		return nil, fmt.Errorf("track interpolate: unknown method %v", method)
+	}
+
+	it := &Track{MMSI: t.MMSI, h: t.h, idx: t.idx}
+	ts := t.Start().Truncate(interval)
+	if ts.Before(t.Start()) {
+		ts = ts.Add(interval)
+	}
+
+	j := 0
+	for ; !ts.After(t.End()); ts = ts.Add(interval) {
+		for j+1 < len(t.times) && !t.times[j+1].After(ts) {
+			j++
+		}
+		rec := make(Record, len(t.data[j]))
+		copy(rec, t.data[j])
+		if t.times[j].Before(ts) {
+			f := float64(ts.Sub(t.times[j])) / float64(t.times[j+1].Sub(t.times[j]))
+			if err := t.interpolateFields(rec, j, f, method); err != nil {
+				return nil, fmt.Errorf("track interpolate: %w", err)
+			}
+		}
+		rec[t.idx["BaseDateTime"].Idx] = ts.Format(TimeLayout)
+		it.data = append(it.data, rec)
+		it.times = append(it.times, ts)
+	}
+	if len(it.data) == 0 {
+		return nil, ErrEmptySet
+	}
+	return it, nil
+}
+
+// interpolateFields sets the kinematic fields of rec to the values a fraction f
+// of the way from the j'th Record of the Track to the next.
+func (t *Track) interpolateFields(rec Record, j int, f float64, method Interpolation) error {
+	lat1, lon1, err := t.position(j)
+	if err != nil {
+		return err
+	}
+	lat2, lon2, err := t.position(j + 1)
+	if err != nil {
+		return err
+	}
+	var lat, lon float64
+	if method == GreatCircle {
+		lat, lon = slerp(lat1, lon1, lat2, lon2, f)
+	} else {
+		dLon := lon2 - lon1
+		if dLon > 180 {
+			dLon -= 360
+		} else if dLon < -180 {
+			dLon += 360
+		}
+		lat = lat1 + (lat2-lat1)*f
+		lon = normalizeLon(lon1 + dLon*f)
+	}
+	rec[t.idx["LAT"].Idx] = fmt.Sprintf("%.5f", lat)
+	rec[t.idx["LON"].Idx] = fmt.Sprintf("%.5f", lon)
+
+	h := t.h
+	rec1, rec2 := t.data[j], t.data[j+1]
+	if i, ok := h.Contains("SOG"); ok {
+		v1, err1 := rec1.ParseFloat(i)
+		v2, err2 := rec2.ParseFloat(i)
+		if err1 == nil && err2 == nil && v1 < 102.3 && v2 < 102.3 {
+			rec[i] = fmt.Sprintf("%.1f", v1+(v2-v1)*f)
+		}
+	}
+	for _, field := range []string{"COG", "Heading"} {
+		i, ok := h.Contains(field)
+		if !ok {
+			continue
+		}
+		v1, err1 := rec1.ParseFloat(i)
+		v2, err2 := rec2.ParseFloat(i)
+		if err1 == nil && err2 == nil && v1 < 360 && v2 < 360 {
+			rec[i] = fmt.Sprintf("%.1f", interpolateAngle(v1, v2, f))
+		}
+	}
+	return nil
+}
+
+// Interpolate returns a new *RecordSet holding the interpolated Track of every
+// vessel in the receiver, as described by Track.Interpolate.  The Records of the
+// returned RecordSet are sorted by BaseDateTime and then by MMSI so that all of
+// the vessels at each sample time are adjacent.  Interpolate consumes the
+// receiver.
+func (rs *RecordSet) Interpolate(interval time.Duration, method Interpolation) (*RecordSet, error) {
+	tracks, err := rs.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("interpolate: %w", err)
+	}
+	var all []*Track
+	for _, t := range tracks {
+		it, err := t.Interpolate(interval, method)
+		if err == ErrEmptySet {
+			continue // the track falls between two sample times
+		}
+		if err != nil {
+			return nil, fmt.Errorf("interpolate: %w", err)
+		}
+		all = append(all, it)
+	}
+
+	type sample struct {
+		t    time.Time
+		mmsi string
+		rec  Record
+	}
+	var samples []sample
+	for _, t := range all {
+		for i, rec := range t.data {
+			samples = append(samples, sample{t.times[i], t.MMSI, rec})
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		if !samples[i].t.Equal(samples[j].t) {
+			return samples[i].t.Before(samples[j].t)
+		}
+		return samples[i].mmsi < samples[j].mmsi
+	})
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(rs.Headers())
+	for i, s := range samples {
+		rs2.Write(s.rec)
+		if (i+1)%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, fmt.Errorf("interpolate: flush error writing to new recordset: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("interpolate: flush error writing to new recordset: %w", err)
+	}
+	return rs2, nil
+}
+
+// slerp returns the position a fraction f of the way along the great circle
+// from lat1, lon1 to lat2, lon2.
+func slerp(lat1, lon1, lat2, lon2, f float64) (lat, lon float64) {
+	const deg = 180 / math.Pi
+	p, q := unitVector(lat1, lon1), unitVector(lat2, lon2)
+	dot := p[0]*q[0] + p[1]*q[1] + p[2]*q[2]
+	omega := math.Acos(math.Max(-1, math.Min(1, dot)))
+	if omega < 1e-12 {
+		return lat1, lon1
+	}
+	a := math.Sin((1-f)*omega) / math.Sin(omega)
+	b := math.Sin(f*omega) / math.Sin(omega)
+	x, y, z := a*p[0]+b*q[0], a*p[1]+b*q[1], a*p[2]+b*q[2]
+	return math.Atan2(z, math.Hypot(x, y)) * deg, math.Atan2(y, x) * deg
+}
+
+// interpolateAngle returns the angle in degrees a fraction f of the way from a1 to
+// a2 along the shorter arc, normalized to [0, 360).
+func interpolateAngle(a1, a2, f float64) float64 {
+	d := math.Mod(a2-a1+540, 360) - 180
+	a := math.Mod(a1+d*f, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+// normalizeLon returns lon wrapped to the range [-180, 180].
+func normalizeLon(lon float64) float64 {
+	for lon > 180 {
+		lon -= 360
+	}
+	for lon < -180 {
+		lon += 360
+	}
+	return lon
+}
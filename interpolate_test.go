@@ -0,0 +1,106 @@
+package ais
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrack_Interpolate(t *testing.T) {
+	tr, err := NewTrack(goodHeaders, []Record{track1, track2, track3})
+	if err != nil {
+		t.Fatalf("NewTrack() error = %v", err)
+	}
+
+	// Samples fall on the minute, 59 seconds into each one minute leg.  The great
+	// circle positions differ slightly from the linear ones.
+	tests := []struct {
+		method Interpolation
+		want   [][]string
+	}{
+		{Linear, [][]string{
+			{"2017-12-01T00:01:00", "31.80679", "-76.42485"},
+			{"2017-12-01T00:02:00", "31.70679", "-76.52485"},
+		}},
+		{GreatCircle, [][]string{
+			{"2017-12-01T00:01:00", "31.80679", "-76.42486"},
+			{"2017-12-01T00:02:00", "31.70679", "-76.52486"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method.String(), func(t *testing.T) {
+			it, err := tr.Interpolate(time.Minute, tt.method)
+			if err != nil {
+				t.Fatalf("Track.Interpolate() error = %v", err)
+			}
+			want := tt.want
+			if it.Len() != len(want) {
+				t.Fatalf("Track.Interpolate() Len() = %d, want %d", it.Len(), len(want))
+			}
+			for i, rec := range it.Records() {
+				if got := []string(rec[1:4]); !reflect.DeepEqual(got, want[i]) {
+					t.Errorf("Track.Interpolate() record %d = %v, want %v", i, got, want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := tr.Interpolate(0, Linear); err == nil {
+		t.Error("Track.Interpolate() expected error for zero interval")
+	}
+	if _, err := tr.Interpolate(time.Minute, Interpolation(7)); err == nil {
+		t.Error("Track.Interpolate() expected error for unknown method")
+	}
+	if _, err := tr.Interpolate(time.Hour, Linear); err != ErrEmptySet {
+		t.Errorf("Track.Interpolate() error = %v, want %v", err, ErrEmptySet)
+	}
+}
+
+func TestRecordSet_Interpolate(t *testing.T) {
+	data := `MMSI,BaseDateTime,LAT,LON,SOG,COG,Heading
+200000000,2017-12-01T00:00:50,10.0,179.9,10.0,350.0,511
+100000000,2017-12-01T00:00:30,0.0,0.0,10.0,90.0,90.0
+100000000,2017-12-01T00:01:30,0.0,0.1,12.0,90.0,90.0
+200000000,2017-12-01T00:01:10,10.0,-179.9,12.0,10.0,511
+`
+	rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if err != nil {
+		t.Fatalf("NewRecordSetFromReader() error = %v", err)
+	}
+	out, err := rs.Interpolate(time.Minute, Linear)
+	if err != nil {
+		t.Fatalf("RecordSet.Interpolate() error = %v", err)
+	}
+
+	want := []Record{
+		{"100000000", "2017-12-01T00:01:00", "0.00000", "0.05000", "11.0", "90.0", "90.0"},
+		{"200000000", "2017-12-01T00:01:00", "10.00000", "180.00000", "11.0", "0.0", "511"},
+	}
+	for i, w := range want {
+		rec, err := out.Read()
+		if err != nil {
+			t.Fatalf("RecordSet.Read() error = %v", err)
+		}
+		if !reflect.DeepEqual(*rec, w) {
+			t.Errorf("RecordSet.Interpolate() record %d = %v, want %v", i, *rec, w)
+		}
+	}
+}
+
+func TestInterpolateAngle(t *testing.T) {
+	tests := []struct {
+		a1, a2, f, want float64
+	}{
+		{10, 30, 0.5, 20},
+		{350, 10, 0.5, 0},
+		{10, 350, 0.25, 5},
+		{90, 270, 0, 90},
+	}
+	for _, tt := range tests {
+		if got := interpolateAngle(tt.a1, tt.a2, tt.f); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("interpolateAngle(%v, %v, %v) = %v, want %v", tt.a1, tt.a2, tt.f, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+)
+
+// Join returns a pointer to a new RecordSet that left-joins the fields of other
+// onto the Records of rs wherever the key field of both Records is equal.  It is
+// intended for years of MarineCadastre data where static vessel attributes such
+// as VesselType, Length, and Width are distributed in a separate file from the
+// dynamic position reports, so key is usually "MMSI".
+//
+// The Headers of the new RecordSet are the Headers of rs followed by every field
+// of other except key and any field that rs already contains.  Records of rs
+// with no match in other are kept with empty values for the joined fields.  When
+// other holds more than one Record for a key the last one read is used.
+//
+// Only other is held in memory, so it should be the smaller static file.  The
+// Records of rs are streamed through and rs may be arbitrarily large.  Join
+// consumes both RecordSets.
+func (rs *RecordSet) Join(other *RecordSet, key string) (*RecordSet, error) {
+	lkey, ok := rs.Headers().Contains(key)
+	if !ok {
+		return nil, fmt.Errorf("join: %w", ErrMissingHeader{Field: key})
+	}
+	rkey, ok := other.Headers().Contains(key)
+	if !ok {
+		return nil, fmt.Errorf("join: other %w", ErrMissingHeader{Field: key})
+	}
+
+	// Choose the columns of other to carry over.
+	h := rs.Headers()
+	h.Fields = append([]string{}, h.Fields...)
+	var cols []int
+	for i, field := range other.Headers().Fields {
+		if i == rkey {
+			continue
+		}
+		if _, dup := rs.Headers().Contains(field); dup {
+			continue
+		}
+		cols = append(cols, i)
+		h.Fields = append(h.Fields, field)
+	}
+
+	static := make(map[string][]string)
+	for {
+		rec, err := other.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("join: read error on other csv file: %w", err)
+		}
+		vals := make([]string, len(cols))
+		for j, c := range cols {
+			vals[j] = (*rec)[c]
+		}
+		static[(*rec)[rkey]] = vals
+	}
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(h)
+	empty := make([]string, len(cols))
+	written := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("join: read error on csv file: %w", err)
+		}
+		vals, ok := static[(*rec)[lkey]]
+		if !ok {
+			vals = empty
+		}
+		if err := rs2.Write(append(*rec, vals...)); err != nil {
+			return nil, fmt.Errorf("join: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, fmt.Errorf("join: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("join: csv flush error: %w", err)
+	}
+	return rs2, nil
+}
@@ -0,0 +1,49 @@
+package ais
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordSet_Join(t *testing.T) {
+	dynamic := `MMSI,BaseDateTime,LAT,LON,VesselName
+366940480,2017-12-01T00:00:00,30.0,-76.0,EVER GRACE
+477307901,2017-12-01T00:00:00,31.0,-77.0,
+366940480,2017-12-01T00:01:00,30.1,-76.1,EVER GRACE
+999999998,2017-12-01T00:01:00,32.0,-78.0,UNKNOWN
+`
+	static := `MMSI,VesselName,VesselType,Length
+366940480,EVER GRACE,70,300
+477307901,MSC ANNA,79,250
+477307901,MSC ANNA,70,250
+`
+	rs, _ := NewRecordSetFromReader(strings.NewReader(dynamic), Headers{})
+	other, _ := NewRecordSetFromReader(strings.NewReader(static), Headers{})
+	rs2, err := rs.Join(other, "MMSI")
+	if err != nil {
+		t.Fatalf("RecordSet.Join() error = %v", err)
+	}
+
+	wantFields := []string{"MMSI", "BaseDateTime", "LAT", "LON", "VesselName", "VesselType", "Length"}
+	if !reflect.DeepEqual(rs2.Headers().Fields, wantFields) {
+		t.Errorf("RecordSet.Join() headers = %v, want %v", rs2.Headers().Fields, wantFields)
+	}
+	want := [][2]string{{"70", "300"}, {"70", "250"}, {"70", "300"}, {"", ""}}
+	i := 0
+	for ; rs2.Next(); i++ {
+		rec := *rs2.Record()
+		if got := [2]string{rec[5], rec[6]}; got != want[i] {
+			t.Errorf("RecordSet.Join() record %d joined = %v, want %v", i, got, want[i])
+		}
+	}
+	if i != len(want) {
+		t.Errorf("RecordSet.Join() returned %d records, want %d", i, len(want))
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(dynamic), Headers{})
+	other, _ = NewRecordSetFromReader(strings.NewReader("VesselType\n70\n"), Headers{})
+	if _, err := rs.Join(other, "MMSI"); err == nil {
+		t.Error("RecordSet.Join() expected error for other without key")
+	}
+}
@@ -0,0 +1,336 @@
+package ais
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonlNumbers are the fields, in addition to those that SaveSQL stores as DOUBLE
+// PRECISION, that SaveJSONL writes as JSON numbers.  As for SaveSQL, fields that
+// end in _1 or _2 are typed by the name without the suffix.
+var jsonlNumbers = map[string]bool{
+	"Heading":              true,
+	"VesselType":           true,
+	"Length":               true,
+	"Width":                true,
+	"Draft":                true,
+	"Cargo":                true,
+	"RelativeBearing(deg)": true,
+	"BCR(nm)":              true,
+	"RiskIndex":            true,
+}
+
+// jsonEncoder encodes rows of string fields as the members of a JSON object
+// with the keys in field order.
+type jsonEncoder struct {
+	fields []string
+	keys   [][]byte // encoded keys with their colon
+	types  []string // "timestamp", "double", or "text" as for SaveSQL
+
+	timeParser TimeParser // from the Headers, nil for TimeLayout
+}
+
+func newJSONEncoder(h Headers) *jsonEncoder {
+	enc := &jsonEncoder{fields: h.Fields, timeParser: h.Time}
+	for _, f := range h.Fields {
+		key, _ := json.Marshal(f)
+		enc.keys = append(enc.keys, append(key, ':'))
+		enc.types = append(enc.types, jsonType(f))
+	}
+	return enc
+}
+
+// jsonType returns "timestamp", "double", or "text" for field.
+func jsonType(field string) string {
+	if jsonlNumbers[strings.TrimSuffix(strings.TrimSuffix(field, "_1"), "_2")] {
+		return "double"
+	}
+	return sqlType(field)
+}
+
+// members writes the comma separated members for row to buf.  Empty values in
+// typed fields are written as null.
+func (enc *jsonEncoder) members(buf *bytes.Buffer, row []string) error {
+	for i, key := range enc.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		v := ""
+		if i < len(row) {
+			v = row[i]
+		}
+		if enc.types[i] != "text" {
+			v = strings.TrimSpace(v)
+		}
+		if v == "" && enc.types[i] != "text" {
+			buf.WriteString("null")
+			continue
+		}
+		switch enc.types[i] {
+		case "timestamp":
+			t, err := Headers{Time: enc.timeParser}.parseTime(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", enc.fields[i], err)
+			}
+			buf.WriteString(`"` + t.Format(time.RFC3339) + `"`)
+		case "double":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", enc.fields[i], err)
+			}
+			switch {
+			case math.IsNaN(f) || math.IsInf(f, 0):
+				buf.WriteString("null")
+			case json.Valid([]byte(v)):
+				buf.WriteString(v) // keeps the text of the value, such as 131.0
+			default:
+				buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+			}
+		default:
+			s, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			buf.Write(s)
+		}
+	}
+	return nil
+}
+
+// jsonlWriter writes rows of string fields as JSON objects, one per line.
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *jsonEncoder
+	buf bytes.Buffer
+}
+
+func newJSONLWriter(w io.Writer, h Headers) *jsonlWriter {
+	return &jsonlWriter{w: bufio.NewWriter(w), enc: newJSONEncoder(h)}
+}
+
+// write writes one row.
+func (jw *jsonlWriter) write(row []string) error {
+	jw.buf.Reset()
+	jw.buf.WriteByte('{')
+	if err := jw.enc.members(&jw.buf, row); err != nil {
+		return err
+	}
+	jw.buf.WriteString("}\n")
+	_, err := jw.w.Write(jw.buf.Bytes())
+	return err
+}
+
+func (jw *jsonlWriter) close() error { return jw.w.Flush() }
+
+// SaveJSONL writes the RecordSet to filename in the JSON Lines format with one
+// JSON object per Record, keyed by the Headers in order, for ingestion by tools
+// such as Elasticsearch and Logstash.  BaseDateTime is written as an RFC 3339
+// timestamp in UTC, and LAT, LON, SOG, COG, Heading, VesselType, Length, Width,
+// Draft, and Cargo are written as numbers.  Empty values in those fields are
+// written as null and every other field is written as a string.  Like Save,
+// SaveJSONL reads the RecordSet to the end of its data.
+func (rs *RecordSet) SaveJSONL(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("recordset save jsonl: %w", err)
+	}
+	defer out.Close()
+
+	jw := newJSONLWriter(out, rs.Headers())
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("recordset save jsonl: %w", err)
+		}
+		if err := jw.write(*rec); err != nil {
+			return fmt.Errorf("recordset save jsonl: %w", err)
+		}
+	}
+	if err := jw.close(); err != nil {
+		return fmt.Errorf("recordset save jsonl: %w", err)
+	}
+	return nil
+}
+
+// SaveJSONL writes the interactions to filename in the JSON Lines format with one
+// JSON object per interaction keyed by the OutputHeaders.  Fields are typed as
+// for RecordSet.SaveJSONL, with the computed distances and CPA values written as
+// numbers.
+func (inter *Interactions) SaveJSONL(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("interactions save jsonl: %w", err)
+	}
+	defer out.Close()
+	return inter.writeJSONL(out)
+}
+
+// writeJSONL writes the interactions to out in the JSON Lines format.
+func (inter *Interactions) writeJSONL(out io.Writer) error {
+	jw := newJSONLWriter(out, inter.OutputHeaders)
+	err := inter.each(func(hash Hash128, pair *RecordPair) error {
+		row, err := inter.row(hash, pair)
+		if err != nil {
+			return err
+		}
+		return jw.write(row)
+	})
+	if err != nil {
+		return fmt.Errorf("interactions save jsonl: %w", err)
+	}
+	if err := jw.close(); err != nil {
+		return fmt.Errorf("interactions save jsonl: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes the interactions to out as a JSON array of objects typed as
+// for SaveJSONL.
+func (inter *Interactions) writeJSON(out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	enc := newJSONEncoder(inter.OutputHeaders)
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	first := true
+	err := inter.each(func(hash Hash128, pair *RecordPair) error {
+		row, err := inter.row(hash, pair)
+		if err != nil {
+			return err
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.WriteByte('{')
+		if err := enc.members(&buf, row); err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+		_, err = bw.Write(buf.Bytes())
+		buf.Reset()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	buf.WriteString("]\n")
+	if _, err := bw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// OpenJSONL reads a JSON Lines file of flat objects, such as one written by
+// RecordSet.SaveJSONL or Interactions.SaveJSONL, into a new in-memory *RecordSet.
+// The Headers are the keys of the first object in order, and later objects may
+// omit keys but may not add new ones.  Numbers keep their text, null is read as
+// an empty value, and RFC 3339 strings in BaseDateTime fields are formatted with
+// TimeLayout in UTC so that the Records match the MarineCadastre csv
+// representation.  Blank lines are skipped.
+func OpenJSONL(filename string) (*RecordSet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open jsonl: %w", err)
+	}
+	defer f.Close()
+
+	rs := NewRecordSet()
+	var h Headers
+	index := make(map[string]int)
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("open jsonl: %w", err)
+		}
+		if len(bytes.TrimSpace(b)) > 0 {
+			keys, values, perr := parseJSONLine(b)
+			if perr != nil {
+				return nil, fmt.Errorf("open jsonl: line %d: %w", line, perr)
+			}
+			if h.Fields == nil {
+				h.Fields = keys
+				for i, k := range keys {
+					index[k] = i
+				}
+				rs.SetHeaders(h)
+			}
+			rec := make(Record, len(h.Fields))
+			for i, k := range keys {
+				j, ok := index[k]
+				if !ok {
+					return nil, fmt.Errorf("open jsonl: line %d: field %q is not in the first line", line, k)
+				}
+				rec[j] = values[i]
+				if sqlType(k) == "timestamp" && values[i] != "" {
+					if t, err := time.Parse(time.RFC3339, values[i]); err == nil {
+						rec[j] = t.UTC().Format(TimeLayout)
+					}
+				}
+			}
+			if err := rs.Write(rec); err != nil {
+				return nil, fmt.Errorf("open jsonl: %w", err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if h.Fields == nil {
+		return nil, ErrEmptySet
+	}
+	if err := rs.Flush(); err != nil {
+		return nil, fmt.Errorf("open jsonl: %w", err)
+	}
+	return rs, nil
+}
+
+// parseJSONLine returns the keys in order and the string values of a flat JSON
+// object.
+func parseJSONLine(b []byte) (keys, values []string, err error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("line is not a json object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := tok.(string)
+		if tok, err = dec.Token(); err != nil {
+			return nil, nil, err
+		}
+		var v string
+		switch tok := tok.(type) {
+		case nil:
+		case string:
+			v = tok
+		case json.Number:
+			v = tok.String()
+		case bool:
+			v = strconv.FormatBool(tok)
+		default:
+			return nil, nil, fmt.Errorf("field %q is not a string, number, boolean or null", key)
+		}
+		keys = append(keys, key)
+		values = append(values, v)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	return keys, values, nil
+}
@@ -0,0 +1,169 @@
+package ais
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordSet_SaveJSONL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ten.jsonl")
+
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	if err := rs.SaveJSONL(filename); err != nil {
+		t.Fatalf("RecordSet.SaveJSONL() error = %v", err)
+	}
+
+	b, _ := ioutil.ReadFile(filename)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("RecordSet.SaveJSONL() wrote %d lines, want 10", len(lines))
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &obj); err != nil {
+		t.Fatalf("RecordSet.SaveJSONL() wrote invalid json: %v", err)
+	}
+	if _, ok := obj["LAT"].(float64); !ok {
+		t.Errorf("RecordSet.SaveJSONL() LAT = %#v, want a number", obj["LAT"])
+	}
+	if _, ok := obj["MMSI"].(string); !ok {
+		t.Errorf("RecordSet.SaveJSONL() MMSI = %#v, want a string", obj["MMSI"])
+	}
+	if ts, _ := obj["BaseDateTime"].(string); !strings.HasSuffix(ts, "Z") {
+		t.Errorf("RecordSet.SaveJSONL() BaseDateTime = %q, want RFC 3339 in UTC", ts)
+	}
+
+	got, err := OpenJSONL(filename)
+	if err != nil {
+		t.Fatalf("OpenJSONL() error = %v", err)
+	}
+	if !got.Headers().Equals(goodHeaders) {
+		t.Errorf("OpenJSONL() headers = %v, want %v", got.Headers(), goodHeaders)
+	}
+	want, _ := OpenRecordSet("testdata/ten.csv")
+	defer want.Close()
+	n := 0
+	for {
+		wantRec, err := want.Read()
+		if err == io.EOF {
+			break
+		}
+		gotRec, err := got.Read()
+		if err != nil {
+			t.Fatalf("record %d: Read() error = %v", n, err)
+		}
+		for i := range *wantRec { // blank numbers are written as null
+			(*wantRec)[i] = strings.TrimSpace((*wantRec)[i])
+		}
+		if !reflect.DeepEqual(gotRec, wantRec) {
+			t.Errorf("record %d = %v, want %v", n, *gotRec, *wantRec)
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("OpenJSONL() read %d records, want 10", n)
+	}
+}
+
+func TestOpenJSONL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "missing keys and null",
+			data: `{"MMSI":"1","LAT":30.5,"Valid":true}` + "\n\n" + `{"LAT":null,"MMSI":"2"}` + "\n",
+			want: []string{"1,30.5,true", "2,,"},
+		},
+		{
+			name:    "new key",
+			data:    `{"MMSI":"1"}` + "\n" + `{"LAT":1}` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "nested object",
+			data:    `{"MMSI":{"a":1}}` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			data:    "MMSI,LAT\n",
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, strings.Repeat("x", i+1)+".jsonl")
+			ioutil.WriteFile(filename, []byte(tt.data), 0644)
+			rs, err := OpenJSONL(filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenJSONL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var got []string
+			for {
+				rec, err := rs.Read()
+				if err != nil {
+					break
+				}
+				got = append(got, strings.Join(*rec, ","))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OpenJSONL() records = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractions_SaveJSONL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The OutputHeaders include the Geohash added by Geohasher.
+	c := testClusters(1, 3)[0]
+	for _, rec := range c.Data() {
+		*rec = append(*rec, "0x1")
+	}
+	inter, _ := NewInteractions(goodHeaders)
+	inter.AddCluster(c)
+
+	filename := filepath.Join(dir, "inter.jsonl")
+	if err := inter.SaveJSONL(filename); err != nil {
+		t.Fatalf("Interactions.SaveJSONL() error = %v", err)
+	}
+	rs, err := OpenJSONL(filename)
+	if err != nil {
+		t.Fatalf("OpenJSONL() error = %v", err)
+	}
+	if !rs.Headers().Equals(inter.OutputHeaders) {
+		t.Errorf("OpenJSONL() headers = %v, want %v", rs.Headers(), inter.OutputHeaders)
+	}
+	b, _ := ioutil.ReadFile(filename)
+	if !strings.Contains(string(b), `"Distance(nm)":0.`) {
+		t.Errorf("Interactions.SaveJSONL() did not write Distance(nm) as a number:\n%s", b)
+	}
+}
@@ -0,0 +1,232 @@
+package ais
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kmlPlacemark is a KML Placemark with a Point, LineString, or gx:Track geometry.
+type kmlPlacemark struct {
+	XMLName      xml.Name         `xml:"Placemark"`
+	Name         string           `xml:"name,omitempty"`
+	TimeStamp    *kmlTimeStamp    `xml:"TimeStamp,omitempty"`
+	ExtendedData *kmlExtendedData `xml:"ExtendedData,omitempty"`
+	Point        *kmlCoordinates  `xml:"Point,omitempty"`
+	LineString   *kmlCoordinates  `xml:"LineString,omitempty"`
+	Track        *kmlTrack        `xml:"gx:Track,omitempty"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// kmlCoordinates is the body of a Point or LineString.  Coordinates are
+// space separated lon,lat tuples.
+type kmlCoordinates struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// kmlTrack is a gx:Track with one when and gx:coord element per position.
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"gx:coord"`
+}
+
+// kmlWriter streams Placemarks into a KML Document.  Files ending in .kmz are
+// written as a zip archive holding a single doc.kml.
+type kmlWriter struct {
+	w   *bufio.Writer
+	enc *xml.Encoder
+	zw  *zip.Writer
+}
+
+func newKMLWriter(out io.Writer, filename, name string) (*kmlWriter, error) {
+	kw := new(kmlWriter)
+	if strings.EqualFold(filepath.Ext(filename), ".kmz") {
+		kw.zw = zip.NewWriter(out)
+		doc, err := kw.zw.Create("doc.kml")
+		if err != nil {
+			return nil, err
+		}
+		out = doc
+	}
+	kw.w = bufio.NewWriter(out)
+	kw.enc = xml.NewEncoder(kw.w)
+
+	kw.w.WriteString(xml.Header)
+	kw.w.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2"><Document><name>`)
+	if err := xml.EscapeText(kw.w, []byte(name)); err != nil {
+		return nil, err
+	}
+	_, err := kw.w.WriteString("</name>\n")
+	return kw, err
+}
+
+func (kw *kmlWriter) write(p kmlPlacemark) error {
+	if err := kw.enc.Encode(p); err != nil {
+		return err
+	}
+	return kw.w.WriteByte('\n')
+}
+
+func (kw *kmlWriter) close() error {
+	if err := kw.enc.Flush(); err != nil {
+		return err
+	}
+	if _, err := kw.w.WriteString("</Document></kml>\n"); err != nil {
+		return err
+	}
+	if err := kw.w.Flush(); err != nil {
+		return err
+	}
+	if kw.zw != nil {
+		return kw.zw.Close()
+	}
+	return nil
+}
+
+// newKMLExtendedData returns the ExtendedData of a Placemark from the fields and values.
+func newKMLExtendedData(fields, values []string) *kmlExtendedData {
+	ed := new(kmlExtendedData)
+	for i, f := range fields {
+		if i < len(values) {
+			ed.Data = append(ed.Data, kmlData{Name: f, Value: values[i]})
+		}
+	}
+	return ed
+}
+
+// kmlTime formats a time in the xsd:dateTime form required by KML.
+func kmlTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// kmlCoord formats a position as a KML lon,lat tuple.
+func kmlCoord(lat, lon float64) string {
+	return strconv.FormatFloat(lon, 'f', -1, 64) + "," + strconv.FormatFloat(lat, 'f', -1, 64)
+}
+
+// SaveKML writes the Track to filename as a KML Document that opens directly
+// in Google Earth.  The Document holds a gx:Track Placemark of the whole Track,
+// which Google Earth animates with its time slider, followed by a time-stamped
+// Point Placemark for each Record carrying the Record's fields as ExtendedData.
+// When filename ends in .kmz the Document is written as a KMZ archive.
+func (t *Track) SaveKML(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("track save kml: %w", err)
+	}
+	defer out.Close()
+
+	kw, err := newKMLWriter(out, filename, t.MMSI)
+	if err != nil {
+		return fmt.Errorf("track save kml: %w", err)
+	}
+	track := new(kmlTrack)
+	points := make([]kmlPlacemark, t.Len())
+	for i := range t.data {
+		lat, lon, err := t.position(i)
+		if err != nil {
+			return fmt.Errorf("track save kml: %w", err)
+		}
+		when := kmlTime(t.times[i])
+		track.When = append(track.When, when)
+		track.Coord = append(track.Coord, strconv.FormatFloat(lon, 'f', -1, 64)+" "+strconv.FormatFloat(lat, 'f', -1, 64)+" 0")
+		points[i] = kmlPlacemark{
+			Name:         t.times[i].Format(TimeLayout),
+			TimeStamp:    &kmlTimeStamp{When: when},
+			ExtendedData: newKMLExtendedData(t.h.Fields, t.data[i]),
+			Point:        &kmlCoordinates{Coordinates: kmlCoord(lat, lon)},
+		}
+	}
+	if err := kw.write(kmlPlacemark{Name: t.MMSI, Track: track}); err != nil {
+		return fmt.Errorf("track save kml: %w", err)
+	}
+	for _, p := range points {
+		if err := kw.write(p); err != nil {
+			return fmt.Errorf("track save kml: %w", err)
+		}
+	}
+	if err := kw.close(); err != nil {
+		return fmt.Errorf("track save kml: %w", err)
+	}
+	return nil
+}
+
+// SaveKML writes the interactions to filename as a KML Document.  Each
+// interaction is a LineString Placemark from the first vessel to the second,
+// named by the two MMSIs and time-stamped with the BaseDateTime of the first
+// vessel's Record.  The ExtendedData of each Placemark holds the same fields
+// written by Save, named by OutputHeaders.  When filename ends in .kmz the
+// Document is written as a KMZ archive.
+func (inter *Interactions) SaveKML(filename string) error {
+	idx, err := inter.RecordHeaders.require("MMSI", "BaseDateTime", "LAT", "LON")
+	if err != nil {
+		return fmt.Errorf("interactions save kml: %w", err)
+	}
+	latIndex, lonIndex := idx["LAT"].Idx, idx["LON"].Idx
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("interactions save kml: %w", err)
+	}
+	defer out.Close()
+
+	kw, err := newKMLWriter(out, filename, "Interactions")
+	if err != nil {
+		return fmt.Errorf("interactions save kml: %w", err)
+	}
+	err = inter.each(func(hash Hash128, pair *RecordPair) error {
+		var coords []string
+		for _, rec := range []*Record{pair.rec1, pair.rec2} {
+			lat, err := rec.ParseFloat(latIndex)
+			if err != nil {
+				return ErrParse{Field: "LAT", Err: err}
+			}
+			lon, err := rec.ParseFloat(lonIndex)
+			if err != nil {
+				return ErrParse{Field: "LON", Err: err}
+			}
+			coords = append(coords, kmlCoord(lat, lon))
+		}
+		t, err := inter.RecordHeaders.parseTime((*pair.rec1)[idx["BaseDateTime"].Idx])
+		if err != nil {
+			return ErrParse{Field: "BaseDateTime", Err: err}
+		}
+		row, err := inter.row(hash, pair)
+		if err != nil {
+			return err
+		}
+		mmsi := idx["MMSI"].Idx
+		return kw.write(kmlPlacemark{
+			Name:         (*pair.rec1)[mmsi] + " - " + (*pair.rec2)[mmsi],
+			TimeStamp:    &kmlTimeStamp{When: kmlTime(t)},
+			ExtendedData: newKMLExtendedData(inter.OutputHeaders.Fields, row),
+			LineString:   &kmlCoordinates{Coordinates: strings.Join(coords, " ")},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("interactions save kml: %w", err)
+	}
+	if err := kw.close(); err != nil {
+		return fmt.Errorf("interactions save kml: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package ais
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// kmlDocument is the minimal KML structure needed to check output.
+type kmlDocument struct {
+	Placemarks []struct {
+		Name       string   `xml:"name"`
+		When       string   `xml:"TimeStamp>when"`
+		Point      string   `xml:"Point>coordinates"`
+		LineString string   `xml:"LineString>coordinates"`
+		TrackWhen  []string `xml:"Track>when"`
+		TrackCoord []string `xml:"Track>coord"`
+		Data       []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value"`
+		} `xml:"ExtendedData>Data"`
+	} `xml:"Document>Placemark"`
+}
+
+func readKML(t *testing.T, b []byte) kmlDocument {
+	var doc kmlDocument
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("invalid KML: %v", err)
+	}
+	return doc
+}
+
+func TestTrack_SaveKML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rs, _ := OpenRecordSet("testdata/track.csv")
+	defer rs.Close()
+	tracks, err := rs.Tracks()
+	if err != nil {
+		t.Fatalf("RecordSet.Tracks() error = %v", err)
+	}
+	track := tracks["477307901"]
+
+	filename := filepath.Join(dir, "track.kml")
+	if err := track.SaveKML(filename); err != nil {
+		t.Fatalf("Track.SaveKML() error = %v", err)
+	}
+	b, _ := ioutil.ReadFile(filename)
+	if !strings.Contains(string(b), "<gx:Track>") {
+		t.Errorf("Track.SaveKML() output does not contain a gx:Track element")
+	}
+	doc := readKML(t, b)
+	if len(doc.Placemarks) != 4 {
+		t.Fatalf("Track.SaveKML() wrote %d placemarks, want 4", len(doc.Placemarks))
+	}
+	gx := doc.Placemarks[0]
+	if gx.Name != "477307901" || len(gx.TrackWhen) != 3 || len(gx.TrackCoord) != 3 {
+		t.Errorf("Track.SaveKML() gx:Track = %+v, want 3 positions for 477307901", gx)
+	}
+	if gx.TrackWhen[0] != "2017-12-01T00:00:01Z" || gx.TrackCoord[0] != "-76.32652 31.90512 0" {
+		t.Errorf("Track.SaveKML() first track position = %s %s", gx.TrackWhen[0], gx.TrackCoord[0])
+	}
+	p := doc.Placemarks[1]
+	if p.When != "2017-12-01T00:00:01Z" || p.Point != "-76.32652,31.90512" {
+		t.Errorf("Track.SaveKML() first point = %s %s", p.When, p.Point)
+	}
+	if len(p.Data) == 0 || p.Data[0].Name != "MMSI" || p.Data[0].Value != "477307901" {
+		t.Errorf("Track.SaveKML() first point extended data = %+v", p.Data)
+	}
+}
+
+func TestInteractions_SaveKML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inter, _ := NewInteractions(goodHeaders)
+	if err := inter.AddCluster(testClusters(1, 3)[0]); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+
+	filename := filepath.Join(dir, "interactions.kmz")
+	if err := inter.SaveKML(filename); err != nil {
+		t.Fatalf("Interactions.SaveKML() error = %v", err)
+	}
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatalf("Interactions.SaveKML() did not write a KMZ archive: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 1 || zr.File[0].Name != "doc.kml" {
+		t.Fatalf("Interactions.SaveKML() archive holds %d files, want doc.kml", len(zr.File))
+	}
+	rc, _ := zr.File[0].Open()
+	b, _ := ioutil.ReadAll(rc)
+	rc.Close()
+
+	doc := readKML(t, b)
+	if len(doc.Placemarks) != inter.Len() {
+		t.Fatalf("Interactions.SaveKML() wrote %d placemarks, want %d", len(doc.Placemarks), inter.Len())
+	}
+	for _, p := range doc.Placemarks {
+		if len(strings.Fields(p.LineString)) != 2 || p.When != "2017-12-01T00:00:00Z" {
+			t.Errorf("Interactions.SaveKML() placemark %s = %q at %s", p.Name, p.LineString, p.When)
+		}
+		if len(p.Data) < 2 || p.Data[0].Name != "InteractionHash" || p.Data[1].Name != "Distance(nm)" {
+			t.Errorf("Interactions.SaveKML() placemark %s extended data = %+v", p.Name, p.Data)
+		}
+	}
+}
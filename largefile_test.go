@@ -0,0 +1,158 @@
+package ais
+
+import (
+	"bufio"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var slow = flag.Bool("slow", false, "run the tests that generate multi-GB files")
+
+// windowsData is the first lines of ten.csv as Excel saves them on Windows,
+// with a byte order mark and \r\n line endings.
+var windowsData = byteOrderMark + strings.Replace(strings.SplitN(testString, "\n", 2)[1], "\n", "\r\n", -1)
+
+func TestOpenRecordSet_Windows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "windows.csv")
+	if err := ioutil.WriteFile(filename, []byte(windowsData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := OpenRecordSet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if !rs.Headers().Equals(goodHeaders) {
+		t.Errorf("OpenRecordSet() headers = %q, want %q", rs.Headers().Fields, goodHeaders.Fields)
+	}
+	n := 0
+	for rs.Next() {
+		rec := rs.Record()
+		if last := (*rec)[len(*rec)-1]; strings.ContainsAny(last, "\r\n") {
+			t.Errorf("OpenRecordSet() record %d ends with %q", n, last)
+		}
+		n++
+	}
+	if err := rs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("OpenRecordSet() read %d records, want 3", n)
+	}
+
+	irs, err := OpenIndexedRecordSet(filename)
+	if err != nil {
+		t.Fatalf("OpenIndexedRecordSet() error = %v", err)
+	}
+	defer irs.Close()
+	rs2, err := irs.ByMMSI("338029922")
+	if err != nil {
+		t.Fatalf("ByMMSI() error = %v", err)
+	}
+	rec, err := rs2.Read()
+	if err != nil || (*rec)[1] != "2017-12-01T00:00:02" {
+		t.Errorf("ByMMSI() = %v, %v", rec, err)
+	}
+}
+
+func TestNewRecordSetFromReader_Windows(t *testing.T) {
+	rs, err := NewRecordSetFromReader(strings.NewReader(windowsData), Headers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.Headers().Equals(goodHeaders) {
+		t.Errorf("NewRecordSetFromReader() headers = %q, want %q", rs.Headers().Fields, goodHeaders.Fields)
+	}
+}
+
+func TestReadBufferSize(t *testing.T) {
+	if _, err := OpenRecordSet("testdata/ten.csv", ReadBufferSize(0)); err == nil {
+		t.Error("OpenRecordSet() expected error for zero buffer size")
+	}
+	rs, err := OpenRecordSet("testdata/ten.csv", ReadBufferSize(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	n := 0
+	for rs.Next() {
+		n++
+	}
+	if err := rs.Err(); err != nil || n != 10 {
+		t.Errorf("OpenRecordSet() with a 16 byte buffer read %d records, %v, want 10", n, err)
+	}
+}
+
+// TestOpenRecordSet_LargeFile reads a generated file larger than 4 GB, past
+// every 32 bit offset, and runs only with -slow.
+func TestOpenRecordSet_LargeFile(t *testing.T) {
+	if !*slow {
+		t.Skip("generates a 4.5 GB file, run with -slow")
+	}
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "large.csv")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bufio.NewWriterSize(f, DefaultReadBufferSize)
+	w.WriteString(strings.Join(goodHeaders.Fields, ",") + "\r\n")
+	const size = 4<<30 + 512<<20
+	var written int64
+	var lines int
+	line := make([]byte, 0, 256)
+	for written < size {
+		line = strconv.AppendInt(line[:0], int64(100000000+lines%900000000), 10)
+		line = append(line, ",2017-12-01T00:00:01,31.90512,-76.32652,0.0,131.0,352.0,FIRST,IMO9739666,VRPJ6,1004,moored,337,,,\r\n"...)
+		n, err := w.Write(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		written += int64(n)
+		lines++
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := OpenRecordSet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	n := 0
+	var last string
+	for rs.Next() {
+		if n == lines-1 {
+			last = (*rs.Record())[0]
+		}
+		n++
+	}
+	if err := rs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != lines {
+		t.Errorf("OpenRecordSet() read %d records, want %d", n, lines)
+	}
+	if want := strconv.Itoa(100000000 + (lines-1)%900000000); last != want {
+		t.Errorf("OpenRecordSet() last MMSI = %s, want %s", last, want)
+	}
+}
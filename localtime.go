@@ -0,0 +1,201 @@
+package ais
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalTimeLayout is the layout of the LocalDateTime field appended by a
+// LocalTimer, TimeLayout with the offset of the zone.
+const LocalTimeLayout = "2006-01-02T15:04:05-07:00"
+
+// DiurnalFields are the Headers of the csv file written by Diurnal.Save.
+const DiurnalFields = "Section,Key,Count"
+
+// ZoneFunc returns the time zone at a position.  NauticalZone and the Location
+// method of a TimeZones are ZoneFuncs.
+type ZoneFunc func(lat, lon float64) *time.Location
+
+// nauticalZones holds the 25 nautical time zones, from UTC-12 to UTC+12.
+var nauticalZones [25]*time.Location
+
+func init() {
+	for i := range nauticalZones {
+		h := i - 12
+		nauticalZones[i] = time.FixedZone(fmt.Sprintf("UTC%+03d", h), h*3600)
+	}
+}
+
+// NauticalZone returns the nautical time zone at a position, the whole number
+// of hours nearest to lon/15, which is the local time kept at sea outside
+// territorial waters.  Near land the civil time zone from a TimeZones may
+// differ by an hour or more.
+func NauticalZone(lat, lon float64) *time.Location {
+	if math.IsNaN(lon) {
+		return time.UTC
+	}
+	lon = math.Mod(lon, 360)
+	if lon > 180 {
+		lon -= 360
+	} else if lon < -180 {
+		lon += 360
+	}
+	return nauticalZones[int(math.Round(lon/15))+12]
+}
+
+// LocalTime returns the BaseDateTime of the Record in the time zone that zone
+// returns for its LAT and LON, so that reports can be grouped by the hour of
+// the day at the vessel rather than in UTC.
+func (r Record) LocalTime(h Headers, zone ZoneFunc) (time.Time, error) {
+	t, err := r.Time(h)
+	if err != nil {
+		return time.Time{}, err
+	}
+	lat, lon, err := r.LatLon(h)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(zone(lat, lon)), nil
+}
+
+// LocalTimer implements the Generator interface to append the local time of
+// each Record.  Pass it to AppendField with the required headers
+// BaseDateTime, LAT, and LON, in that order:
+//
+//	rs2, err := rs.AppendField("LocalDateTime", []string{"BaseDateTime", "LAT", "LON"},
+//		ais.LocalTimer{Zone: ais.NauticalZone})
+//
+// The field is written in LocalTimeLayout.  Set Time to the TimeParser of the
+// Headers of the RecordSet when its times are not in TimeLayout.
+type LocalTimer struct {
+	Zone ZoneFunc   // NauticalZone when nil
+	Time TimeParser // TimeLayout when nil
+}
+
+// Generate implements the Generator interface for a LocalTimer.
+func (lt LocalTimer) Generate(rec Record, index ...int) (Field, error) {
+	if len(index) != 3 {
+		return "", fmt.Errorf("local timer: want the BaseDateTime, LAT, and LON indices, got %d indices", len(index))
+	}
+	t, err := Headers{Time: lt.Time}.parseTime(rec[index[0]])
+	if err != nil {
+		return "", fmt.Errorf("local timer: %w", ErrParse{Field: "BaseDateTime", Err: err})
+	}
+	lat, err := rec.ParseFloat(index[1])
+	if err != nil {
+		return "", fmt.Errorf("local timer: %w", ErrParse{Field: "LAT", Err: err})
+	}
+	lon, err := rec.ParseFloat(index[2])
+	if err != nil {
+		return "", fmt.Errorf("local timer: %w", ErrParse{Field: "LON", Err: err})
+	}
+	zone := lt.Zone
+	if zone == nil {
+		zone = NauticalZone
+	}
+	return Field(t.In(zone(lat, lon)).Format(LocalTimeLayout)), nil
+}
+
+// Diurnal counts Records or interactions by the local hour of the day and day
+// of the week at the position of the vessel, so that traffic patterns reflect
+// local day and night rather than UTC.
+type Diurnal struct {
+	Total    int
+	Hours    [24]int // by local hour of the day
+	Weekdays [7]int  // by local day of the week, Sunday first
+}
+
+func (d *Diurnal) add(t time.Time) {
+	d.Total++
+	d.Hours[t.Hour()]++
+	d.Weekdays[t.Weekday()]++
+}
+
+// Diurnal counts the Records of the RecordSet by their local time with zone,
+// which is NauticalZone when nil.  The Headers must contain BaseDateTime, LAT,
+// and LON.  Diurnal consumes the RecordSet.
+func (rs *RecordSet) Diurnal(zone ZoneFunc) (*Diurnal, error) {
+	if _, err := rs.Headers().require("BaseDateTime", "LAT", "LON"); err != nil {
+		return nil, fmt.Errorf("diurnal: %w", err)
+	}
+	if zone == nil {
+		zone = NauticalZone
+	}
+	h := rs.Headers()
+	d := new(Diurnal)
+	for {
+		rec, err := rs.read(true)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("diurnal: read error on csv file: %w", err)
+		}
+		t, err := rec.LocalTime(h, zone)
+		if err != nil {
+			return nil, fmt.Errorf("diurnal: %w", err)
+		}
+		d.add(t)
+	}
+	return d, nil
+}
+
+// Diurnal counts the interactions in the set by the local time of the report
+// of the first vessel at its position with zone, which is NauticalZone when
+// nil.  It is the local time equivalent of the UTC Hours of Summary.
+func (inter *Interactions) Diurnal(zone ZoneFunc) (*Diurnal, error) {
+	if _, err := inter.RecordHeaders.require("BaseDateTime", "LAT", "LON"); err != nil {
+		return nil, fmt.Errorf("interactions diurnal: %w", err)
+	}
+	if zone == nil {
+		zone = NauticalZone
+	}
+	d := new(Diurnal)
+	err := inter.each(func(hash Hash128, pair *RecordPair) error {
+		t, err := pair.rec1.LocalTime(inter.RecordHeaders, zone)
+		if err != nil {
+			return err
+		}
+		d.add(t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("interactions diurnal: %w", err)
+	}
+	return d, nil
+}
+
+// Save writes the counts to a csv file with the Headers in DiurnalFields.  The
+// Section of each row is total, hour, or weekday.  Hour Keys are 00 through 23
+// and weekday Keys are the English day names.
+func (d *Diurnal) Save(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("diurnal save: %w", err)
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	w.Write(strings.Split(DiurnalFields, ","))
+	row := func(section, key string, n int) {
+		w.Write([]string{section, key, strconv.Itoa(n)})
+	}
+	row("total", "", d.Total)
+	for h, n := range d.Hours {
+		row("hour", fmt.Sprintf("%02d", h), n)
+	}
+	for day, n := range d.Weekdays {
+		row("weekday", time.Weekday(day).String(), n)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("diurnal save: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package ais
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNauticalZone(t *testing.T) {
+	tests := []struct {
+		lon  float64
+		want int // offset in hours
+	}{
+		{0, 0},
+		{-76.3, -5},
+		{7.4, 0},
+		{7.6, 1},
+		{179.9, 12},
+		{-180, -12},
+		{190, -11},
+	}
+	for _, tt := range tests {
+		_, offset := time.Date(2017, 12, 1, 0, 0, 0, 0, NauticalZone(37, tt.lon)).Zone()
+		if offset != tt.want*3600 {
+			t.Errorf("NauticalZone(37, %v) offset = %dh, want %dh", tt.lon, offset/3600, tt.want)
+		}
+	}
+}
+
+// diurnalData has two reports near Norfolk, five hours behind UTC at sea, and
+// one in the Mediterranean.
+const diurnalData = "MMSI,BaseDateTime,LAT,LON\n" +
+	"111111111,2017-12-01T03:00:00,36.9,-76.1\n" +
+	"222222222,2017-12-01T03:00:00,36.9,-76.2\n" +
+	"333333333,2017-12-01T03:00:00,36.0,15.1\n"
+
+func TestRecord_LocalTime(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(diurnalData), Headers{})
+	rec, err := rs.Read()
+	if err != nil {
+		t.Fatalf("RecordSet.Read() error = %v", err)
+	}
+	lt, err := rec.LocalTime(rs.Headers(), NauticalZone)
+	if err != nil {
+		t.Fatalf("Record.LocalTime() error = %v", err)
+	}
+	if got, want := lt.Format(LocalTimeLayout), "2017-11-30T22:00:00-05:00"; got != want {
+		t.Errorf("Record.LocalTime() = %s, want %s", got, want)
+	}
+}
+
+func TestLocalTimer(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(diurnalData), Headers{})
+	rs2, err := rs.AppendField("LocalDateTime", []string{"BaseDateTime", "LAT", "LON"}, LocalTimer{})
+	if err != nil {
+		t.Fatalf("RecordSet.AppendField() error = %v", err)
+	}
+	defer rs2.Close()
+	want := []string{"2017-11-30T22:00:00-05:00", "2017-11-30T22:00:00-05:00", "2017-12-01T04:00:00+01:00"}
+	for i, w := range want {
+		rec, err := rs2.Read()
+		if err != nil {
+			t.Fatalf("RecordSet.Read() error = %v", err)
+		}
+		if got := (*rec)[4]; got != w {
+			t.Errorf("record %d LocalDateTime = %s, want %s", i, got, w)
+		}
+	}
+}
+
+func TestLocalTimer_TimeParser(t *testing.T) {
+	data := "MMSI,BaseDateTime,LAT,LON\n111111111,01/12/2017 03:00:00,36.9,-76.1\n"
+	p := NewTimeParser("02/01/2006 15:04:05")
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{Time: p})
+	rs2, err := rs.AppendField("LocalDateTime", []string{"BaseDateTime", "LAT", "LON"}, LocalTimer{Time: p})
+	if err != nil {
+		t.Fatalf("RecordSet.AppendField() error = %v", err)
+	}
+	defer rs2.Close()
+	rec, err := rs2.Read()
+	if err != nil {
+		t.Fatalf("RecordSet.Read() error = %v", err)
+	}
+	if got, want := (*rec)[4], "2017-11-30T22:00:00-05:00"; got != want {
+		t.Errorf("LocalTimer with a TimeParser LocalDateTime = %s, want %s", got, want)
+	}
+
+	if _, err := (LocalTimer{}).Generate(*rec, 1, 2, 3); err == nil {
+		t.Error("LocalTimer.Generate() expected error for a time not in TimeLayout without a TimeParser")
+	}
+}
+
+func TestRecordSet_Diurnal(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(diurnalData), Headers{})
+	d, err := rs.Diurnal(nil)
+	if err != nil {
+		t.Fatalf("RecordSet.Diurnal() error = %v", err)
+	}
+	if d.Total != 3 || d.Hours[22] != 2 || d.Hours[4] != 1 || d.Hours[3] != 0 {
+		t.Errorf("Diurnal() = %+v, want 2 records at 22 and 1 at 04", d)
+	}
+	if d.Weekdays[time.Thursday] != 2 || d.Weekdays[time.Friday] != 1 {
+		t.Errorf("Diurnal() weekdays = %v, want 2 on Thursday and 1 on Friday", d.Weekdays)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "diurnal.csv")
+	if err := d.Save(filename); err != nil {
+		t.Fatalf("Diurnal.Save() error = %v", err)
+	}
+	out, _ := ioutil.ReadFile(filename)
+	for _, line := range []string{DiurnalFields, "total,,3", "hour,22,2", "weekday,Thursday,2"} {
+		if !strings.Contains(string(out), line+"\n") {
+			t.Errorf("Diurnal.Save() output has no line %q", line)
+		}
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,LAT,LON\n"), Headers{})
+	if _, err := rs.Diurnal(nil); err == nil {
+		t.Errorf("Diurnal() without BaseDateTime error = nil, want an error")
+	}
+}
+
+func TestInteractions_Diurnal(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(diurnalData), Headers{})
+	inter, _ := NewInteractions(rs.Headers())
+	c := new(Cluster)
+	for {
+		rec, err := rs.Read()
+		if err != nil {
+			break
+		}
+		c.Append(rec)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	d, err := inter.Diurnal(NauticalZone)
+	if err != nil {
+		t.Fatalf("Interactions.Diurnal() error = %v", err)
+	}
+	if d.Total != inter.Len() || d.Hours[22]+d.Hours[4] != d.Total {
+		t.Errorf("Interactions.Diurnal() = %+v, want every interaction at 22 or 04", d)
+	}
+}
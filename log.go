@@ -0,0 +1,66 @@
+package ais
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Logger receives warnings about data that the package skips rather than
+// fails on, such as Records dropped because a field cannot be parsed or checks
+// turned off because the Headers lack a field.  Warn is called with a message
+// and alternating keys and values, the signature of the Warn method of a
+// *slog.Logger, so
+//
+//	ais.SetLogger(slog.Default())
+//
+// sends the warnings to the default structured logger.  Warn may be called from
+// several goroutines at once.
+type Logger interface {
+	Warn(msg string, args ...interface{})
+}
+
+// LoggerFunc is a function that implements the Logger interface.
+type LoggerFunc func(msg string, args ...interface{})
+
+// Warn calls f(msg, args...).
+func (f LoggerFunc) Warn(msg string, args ...interface{}) { f(msg, args...) }
+
+// PrintfLogger returns a Logger that writes each warning as a single line with
+// printf, such as log.Printf, in the form
+//
+//	ais: crop box: dropped record with unparsable position record=12 LAT=abc LON=-76.1
+func PrintfLogger(printf func(format string, v ...interface{})) Logger {
+	return LoggerFunc(func(msg string, args ...interface{}) {
+		var b strings.Builder
+		b.WriteString("ais: ")
+		b.WriteString(msg)
+		for i := 0; i < len(args); i += 2 {
+			if i+1 < len(args) {
+				fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+			} else {
+				fmt.Fprintf(&b, " %v", args[i])
+			}
+		}
+		printf("%s", b.String())
+	})
+}
+
+// loggerHolder wraps the Logger of SetLogger, since an atomic.Value cannot hold
+// nil or values of different types.
+type loggerHolder struct{ l Logger }
+
+var logger atomic.Value // loggerHolder
+
+// SetLogger sets the Logger that receives the warnings of the package.  The
+// default, and a nil Logger, discards them.
+func SetLogger(l Logger) {
+	logger.Store(loggerHolder{l})
+}
+
+// warn sends msg and args to the Logger of SetLogger, if any.
+func warn(msg string, args ...interface{}) {
+	if h, ok := logger.Load().(loggerHolder); ok && h.l != nil {
+		h.l.Warn(msg, args...)
+	}
+}
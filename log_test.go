@@ -0,0 +1,77 @@
+package ais
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testLogger records the warnings sent to it.
+type testLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) Warn(msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, msg)
+}
+
+func TestSetLogger_CropBox(t *testing.T) {
+	l := new(testLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	data := "MMSI,BaseDateTime,LAT,LON\n" +
+		"123456789,2017-12-01T00:00:00,30.0,-76.0\n" +
+		"123456789,2017-12-01T00:00:01,abc,-76.0\n" +
+		"123456789,2017-12-01T00:00:02,45.0,-76.0\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs2, err := rs.CropBox(Box{MinLat: 29, MaxLat: 31, MinLon: -77, MaxLon: -75})
+	if err != nil {
+		t.Fatalf("RecordSet.CropBox() error = %v", err)
+	}
+	defer rs2.Close()
+	// Only the unparsable Record is reported, not the one outside the box.
+	if len(l.msgs) != 1 || !strings.Contains(l.msgs[0], "unparsable position") {
+		t.Errorf("warnings = %q, want one for the unparsable position", l.msgs)
+	}
+}
+
+func TestSetLogger_MissingHeaders(t *testing.T) {
+	l := new(testLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON"}}
+	if _, err := NewInteractions(h); err != nil {
+		t.Fatalf("NewInteractions() error = %v", err)
+	}
+	if len(l.msgs) != 0 {
+		t.Errorf("warnings = %q for complete headers, want none", l.msgs)
+	}
+	if _, err := NewInteractions(Headers{Fields: []string{"MMSI", "LAT", "LON"}}); err != nil {
+		t.Fatalf("NewInteractions() error = %v", err)
+	}
+	if len(l.msgs) != 1 {
+		t.Errorf("warnings = %q without BaseDateTime, want one", l.msgs)
+	}
+}
+
+func TestSetLogger_Nil(t *testing.T) {
+	SetLogger(nil)
+	warn("no logger", "key", 1) // must not panic
+}
+
+func TestPrintfLogger(t *testing.T) {
+	var got string
+	l := PrintfLogger(func(format string, v ...interface{}) {
+		got = fmt.Sprintf(format, v...)
+	})
+	l.Warn("crop box: dropped record", "record", 12, "LAT", "abc", "odd")
+	if want := "ais: crop box: dropped record record=12 LAT=abc odd"; got != want {
+		t.Errorf("PrintfLogger() wrote %q, want %q", got, want)
+	}
+}
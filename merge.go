@@ -0,0 +1,361 @@
+package ais
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// DefaultRunSize is the number of Records sorted in memory at a time by the
+// external merge sort used by MergeRecordSets.  Memory use is bounded by roughly
+// DefaultRunSize Records per concurrently sorted input file.
+const DefaultRunSize = 1000000
+
+// MergeOption configures MergeRecordSets.
+type MergeOption func(*mergeConfig) error
+
+type mergeConfig struct {
+	sort    bool
+	tmpDir  string
+	runSize int
+	save    []SaveOption // applied to the output
+}
+
+// MergeSortByTime sorts the merged output by BaseDateTime with an external merge
+// sort that writes sorted runs of the input to temporary files in tmpDir.  An
+// empty tmpDir uses the default directory for temporary files.  Records with equal
+// BaseDateTime values keep the order of the input paths.
+func MergeSortByTime(tmpDir string) MergeOption {
+	return func(c *mergeConfig) error {
+		c.sort = true
+		c.tmpDir = tmpDir
+		return nil
+	}
+}
+
+// MergeRunSize sets the number of Records sorted in memory at a time when the
+// output is sorted.  The default is DefaultRunSize.
+func MergeRunSize(n int) MergeOption {
+	return func(c *mergeConfig) error {
+		if n <= 0 {
+			return fmt.Errorf("run size must be greater than zero, got %d", n)
+		}
+		c.runSize = n
+		return nil
+	}
+}
+
+// MergeSaveOptions sets how the merged output is written with the options of
+// RecordSet.Save, such as SaveDelimiter and SaveQuoting.
+func MergeSaveOptions(opts ...SaveOption) MergeOption {
+	return func(c *mergeConfig) error {
+		c.save = append(c.save, opts...)
+		return nil
+	}
+}
+
+// MergeRecordSets concatenates the AIS files in paths into the single file out,
+// which is how daily MarineCadastre.gov files are combined for a multi-day study.
+// Every file must have the same Headers as the first.  By default the Records
+// are streamed to out in the order of paths.  With MergeSortByTime the output is
+// sorted by BaseDateTime, and the input files are split into sorted runs
+// concurrently before the runs are merged.  Memory use is bounded in both cases
+// so the inputs may be much larger than the available RAM.  Compressed inputs are
+// read transparently and out is compressed according to its extension as in
+// RecordSet.Save.  Every field is written so that it reads back unchanged, as it
+// is by RecordSet.Save.
+func MergeRecordSets(paths []string, out string, opts ...MergeOption) error {
+	cfg := &mergeConfig{runSize: DefaultRunSize}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return fmt.Errorf("merge: %w", err)
+		}
+	}
+	save := &saveConfig{comma: ','}
+	for _, opt := range cfg.save {
+		if err := opt(save); err != nil {
+			return fmt.Errorf("merge: %w", err)
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("merge: no input files")
+	}
+
+	// Validate the Headers of every file before any output is written.
+	var h Headers
+	for i, path := range paths {
+		rs, err := OpenRecordSet(path)
+		if err != nil {
+			return fmt.Errorf("merge: %w", err)
+		}
+		rs.Close()
+		if i == 0 {
+			h = rs.Headers()
+		} else if !h.Equals(rs.Headers()) {
+			return fmt.Errorf("merge: headers of %s do not match %s", path, paths[0])
+		}
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("merge: %w", err)
+	}
+	defer f.Close()
+	var dst io.Writer = f
+	cw, err := compressor(f, out)
+	if err != nil {
+		return fmt.Errorf("merge: %w", err)
+	}
+	if cw != nil {
+		dst = cw
+	}
+	w := newRecordWriter(save.charset.encoder(dst))
+	w.Comma, w.Quote = save.comma, save.quote
+	if err := w.Write(h.Fields); err != nil {
+		return fmt.Errorf("merge: %w", err)
+	}
+
+	if cfg.sort {
+		err = mergeSorted(paths, h, w, cfg)
+	} else {
+		err = mergeConcat(paths, w)
+	}
+	if err != nil {
+		return fmt.Errorf("merge: %w", err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("merge: flush error: %w", err)
+	}
+	if cw != nil {
+		if err := cw.Close(); err != nil {
+			return fmt.Errorf("merge: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeConcat streams the Records of each file to w in order.
+func mergeConcat(paths []string, w *recordWriter) error {
+	for _, path := range paths {
+		rs, err := OpenRecordSet(path)
+		if err != nil {
+			return err
+		}
+		for {
+			rec, err := rs.read(true)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				rs.Close()
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			w.Write(*rec)
+		}
+		rs.Close()
+	}
+	return nil
+}
+
+// mergeSorted splits every file into sorted runs, concurrently across files, and
+// merges all of the runs into w.
+func mergeSorted(paths []string, h Headers, w *recordWriter, cfg *mergeConfig) error {
+	key, err := sortKey(h, "BaseDateTime")
+	if err != nil {
+		return fmt.Errorf("sorting: %w", err)
+	}
+
+	runs := make([][]string, len(paths))
+	errs := make([]error, len(paths))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			runs[i], errs[i] = writeRuns(paths[i], key, cfg)
+		}(i)
+	}
+	wg.Wait()
+
+	var all []string
+	defer func() {
+		for _, r := range runs {
+			for _, name := range r {
+				os.Remove(name)
+			}
+		}
+	}()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%s: %w", paths[i], err)
+		}
+		all = append(all, runs[i]...)
+	}
+	return mergeRuns(all, key, w)
+}
+
+// keyedRecord is a Record with its parsed sort key.
+type keyedRecord struct {
+	key int64
+	rec Record
+}
+
+// writeRuns opens the file and writes its sorted runs with writeRecordRuns.
+func writeRuns(path string, key func(Record) (int64, error), cfg *mergeConfig) ([]string, error) {
+	rs, err := OpenRecordSet(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	return writeRecordRuns(rs, key, cfg)
+}
+
+// writeRecordRuns reads rs in chunks of cfg.runSize Records, sorts each chunk by
+// key and writes it to a temporary file.  It returns the names of the run files,
+// including those written before any error so that they can be removed.
+func writeRecordRuns(rs *RecordSet, key func(Record) (int64, error), cfg *mergeConfig) ([]string, error) {
+	var names []string
+	chunk := make([]keyedRecord, 0, cfg.runSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		sort.SliceStable(chunk, func(i, j int) bool { return chunk[i].key < chunk[j].key })
+		tmp, err := ioutil.TempFile(cfg.tmpDir, "ais-run-")
+		if err != nil {
+			return err
+		}
+		names = append(names, tmp.Name())
+		w := newRecordWriter(tmp)
+		for _, kr := range chunk {
+			w.Write(kr.rec)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			tmp.Close()
+			return err
+		}
+		chunk = chunk[:0]
+		return tmp.Close()
+	}
+
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return names, err
+		}
+		k, err := key(*rec)
+		if err != nil {
+			return names, err
+		}
+		chunk = append(chunk, keyedRecord{k, *rec})
+		if len(chunk) == cfg.runSize {
+			if err := flush(); err != nil {
+				return names, err
+			}
+		}
+	}
+	return names, flush()
+}
+
+// runReader is the head of one sorted run during the k-way merge.
+type runReader struct {
+	r    *csv.Reader
+	f    *os.File
+	head keyedRecord
+	seq  int // position of the run, used to keep the merge stable
+}
+
+// runHeap orders the runs by the key of their head Record.
+type runHeap []*runReader
+
+func (rh runHeap) Len() int { return len(rh) }
+func (rh runHeap) Less(i, j int) bool {
+	if rh[i].head.key != rh[j].head.key {
+		return rh[i].head.key < rh[j].head.key
+	}
+	return rh[i].seq < rh[j].seq
+}
+func (rh runHeap) Swap(i, j int)       { rh[i], rh[j] = rh[j], rh[i] }
+func (rh *runHeap) Push(x interface{}) { *rh = append(*rh, x.(*runReader)) }
+func (rh *runHeap) Pop() interface{} {
+	old := *rh
+	rr := old[len(old)-1]
+	*rh = old[:len(old)-1]
+	return rr
+}
+
+// advance reads the next Record of the run into head.  It returns io.EOF at the
+// end of the run.
+func (rr *runReader) advance(key func(Record) (int64, error)) error {
+	rec, err := rr.r.Read()
+	if err != nil {
+		return err
+	}
+	k, err := key(rec)
+	if err != nil {
+		return err
+	}
+	rr.head = keyedRecord{k, rec}
+	return nil
+}
+
+// mergeRuns performs a k-way merge of the sorted run files into w.
+func mergeRuns(names []string, key func(Record) (int64, error), w *recordWriter) error {
+	rh := make(runHeap, 0, len(names))
+	defer func() {
+		for _, rr := range rh {
+			rr.f.Close()
+		}
+	}()
+	for i, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		rr := &runReader{r: csv.NewReader(bufio.NewReader(f)), f: f, seq: i}
+		rr.r.LazyQuotes = true
+		rr.r.FieldsPerRecord = -1
+		if err := rr.advance(key); err == io.EOF {
+			f.Close()
+			continue
+		} else if err != nil {
+			f.Close()
+			return err
+		}
+		rh = append(rh, rr)
+	}
+	heap.Init(&rh)
+
+	for rh.Len() > 0 {
+		rr := rh[0]
+		if err := w.Write(rr.head.rec); err != nil {
+			return err
+		}
+		err := rr.advance(key)
+		switch {
+		case err == io.EOF:
+			heap.Pop(&rh)
+			rr.f.Close()
+		case err != nil:
+			return err
+		default:
+			heap.Fix(&rh, 0)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package ais
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeRecordSets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, _ := ioutil.ReadFile("testdata/track.csv")
+	lines := strings.SplitAfter(strings.TrimRight(string(b), "\n"), "\n")
+	day1 := filepath.Join(dir, "day1.csv")
+	day2 := filepath.Join(dir, "day2.csv.gz")
+	ioutil.WriteFile(day1, []byte(strings.Join(lines[:7], "")), 0644)
+	rs, _ := NewRecordSetFromReader(strings.NewReader(lines[0]+strings.Join(lines[7:], "")+"\n"), Headers{})
+	if err := rs.Save(day2); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) []string {
+		rs, err := OpenRecordSet(name)
+		if err != nil {
+			t.Fatalf("OpenRecordSet(%s) error = %v", name, err)
+		}
+		defer rs.Close()
+		var times []string
+		for rs.Next() {
+			times = append(times, (*rs.Record())[1])
+		}
+		return times
+	}
+
+	concat := filepath.Join(dir, "concat.csv")
+	if err := MergeRecordSets([]string{day1, day2}, concat); err != nil {
+		t.Fatalf("MergeRecordSets() error = %v", err)
+	}
+	if got := read(concat); len(got) != 12 || got[5] != "2017-12-01T00:01:01" || got[11] != "2017-12-01T00:02:01" {
+		t.Errorf("MergeRecordSets() times = %v, want the records of track.csv in file order", got)
+	}
+
+	sorted := filepath.Join(dir, "sorted.csv.gz")
+	if err := MergeRecordSets([]string{day1, day2}, sorted, MergeSortByTime(dir), MergeRunSize(2)); err != nil {
+		t.Fatalf("MergeRecordSets() sorted error = %v", err)
+	}
+	got := read(sorted)
+	if len(got) != 12 {
+		t.Fatalf("MergeRecordSets() sorted wrote %d records, want 12", len(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] < got[i-1] {
+			t.Errorf("MergeRecordSets() sorted output out of order at %d: %s after %s", i, got[i], got[i-1])
+		}
+	}
+	if runs, _ := filepath.Glob(filepath.Join(dir, "ais-run-*")); len(runs) != 0 {
+		t.Errorf("MergeRecordSets() left %d run files behind", len(runs))
+	}
+
+	other := filepath.Join(dir, "other.csv")
+	ioutil.WriteFile(other, []byte("MMSI,LAT,LON\n1,2,3\n"), 0644)
+	if err := MergeRecordSets([]string{day1, other}, concat); err == nil {
+		t.Error("MergeRecordSets() expected error for mismatched headers")
+	}
+	if err := MergeRecordSets(nil, concat); err == nil {
+		t.Error("MergeRecordSets() expected error for no input files")
+	}
+}
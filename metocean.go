@@ -0,0 +1,350 @@
+package ais
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment supplies environmental data, such as wind speed, significant wave
+// height, or visibility, at a time and position.  Fields names the variables
+// and Values returns them in the same order, NaN for a variable that is not
+// known at the time and position.  A Grid read from a csv subset of a NetCDF or
+// GRIB file is an Environment.
+type Environment interface {
+	Fields() []string
+	Values(t time.Time, lat, lon float64) ([]float64, error)
+}
+
+// JoinEnvironment returns a pointer to a new RecordSet with the Fields of env
+// appended to the Headers of rs and the Values of env at the BaseDateTime, LAT,
+// and LON of each Record appended to it.  A value that is not known is left
+// empty.  Joining the environment before Interactions are found carries it
+// into the _1 and _2 columns of the interactions, so a risk analysis can use it
+// as a covariate.  The Headers must contain BaseDateTime, LAT, and LON and no
+// field named as one of the Fields of env.  JoinEnvironment consumes rs.
+func (rs *RecordSet) JoinEnvironment(env Environment) (*RecordSet, error) {
+	h := rs.Headers()
+	if _, err := h.require("BaseDateTime", "LAT", "LON"); err != nil {
+		return nil, fmt.Errorf("join environment: %w", err)
+	}
+	fields := env.Fields()
+	for _, field := range fields {
+		if _, dup := h.Contains(field); dup {
+			return nil, fmt.Errorf("join environment: headers already contain %s", field)
+		}
+	}
+	h2 := h
+	h2.Fields = append(append([]string{}, h.Fields...), fields...)
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(h2)
+
+	written := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("join environment: read error on csv file: %w", err)
+		}
+		t, err := rec.Time(h)
+		if err != nil {
+			return nil, fmt.Errorf("join environment: %w", err)
+		}
+		lat, lon, err := rec.LatLon(h)
+		if err != nil {
+			return nil, fmt.Errorf("join environment: %w", err)
+		}
+		vals, err := env.Values(t, lat, lon)
+		if err != nil {
+			return nil, fmt.Errorf("join environment: %w", err)
+		}
+		if len(vals) != len(fields) {
+			return nil, fmt.Errorf("join environment: %d values for %d fields", len(vals), len(fields))
+		}
+		for _, v := range vals {
+			if math.IsNaN(v) {
+				*rec = append(*rec, "")
+				continue
+			}
+			*rec = append(*rec, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		if err := rs2.Write(*rec); err != nil {
+			return nil, fmt.Errorf("join environment: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, fmt.Errorf("join environment: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("join environment: csv flush error: %w", err)
+	}
+	return rs2, nil
+}
+
+// Grid is an Environment of variables on a regular grid of times, latitudes,
+// and longitudes, as exported from a NetCDF or GRIB file to csv by tools such as
+// xarray's to_dataframe().to_csv() or grib_get_data.  Values are interpolated
+// linearly in time and bilinearly in position from the grid points around the
+// query that have a value, so that a vessel near a masked land point still gets
+// the value of the sea points beside it.  Queries more than one step outside the
+// grid are NaN.
+type Grid struct {
+	fields           []string
+	start            time.Time // first time of the grid
+	times            []float64 // seconds since start
+	lats, lons       []float64
+	wrap             bool      // longitudes are 0 to 360
+	data             []float64 // by time, lat, lon, and field
+	nlat, nlon, nvar int
+}
+
+// gridTimeFields, gridLatFields, and gridLonFields are the column names, in
+// lower case, recognized as the coordinates of a Grid file.
+var (
+	gridTimeFields = []string{"time", "valid_time", "basedatetime", "datetime"}
+	gridLatFields  = []string{"lat", "latitude"}
+	gridLonFields  = []string{"lon", "longitude"}
+)
+
+// gridTimeLayouts are the layouts tried in turn to parse the times of a Grid.
+var gridTimeLayouts = []string{TimeLayout, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// OpenGrid reads a Grid from a csv file with a time, a latitude, and a
+// longitude column, named time or valid_time, lat or latitude, and lon or
+// longitude, and one column for each variable, which become the Fields of the
+// Grid.  Times are UTC in TimeLayout, RFC 3339, or "2006-01-02 15:04:05".  A
+// file without a time column, such as a grid of water depths, holds values
+// that apply at every time.
+// Every row holds the variables at one grid point and an empty or NaN value is
+// not known.  Grid points absent from the file are not known either.
+func OpenGrid(filename string) (*Grid, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open grid: %w", err)
+	}
+	defer f.Close()
+	g, err := readGrid(f)
+	if err != nil {
+		return nil, fmt.Errorf("open grid: %s: %w", filename, err)
+	}
+	return g, nil
+}
+
+func readGrid(in io.Reader) (*Grid, error) {
+	r := csv.NewReader(in)
+	r.Comment = '#'
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	header = trimBOM(header)
+	find := func(names []string) int {
+		for i, field := range header {
+			for _, name := range names {
+				if strings.EqualFold(strings.TrimSpace(field), name) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	ti, lai, loi := find(gridTimeFields), find(gridLatFields), find(gridLonFields)
+	for i, name := range []string{"lat", "lon"} {
+		if []int{lai, loi}[i] < 0 {
+			return nil, ErrMissingHeader{Field: name}
+		}
+	}
+	g := new(Grid)
+	var vars []int
+	for i, field := range header {
+		if i != ti && i != lai && i != loi {
+			vars = append(vars, i)
+			g.fields = append(g.fields, strings.TrimSpace(field))
+		}
+	}
+	if len(vars) == 0 {
+		return nil, fmt.Errorf("no variables")
+	}
+
+	type point struct {
+		t        time.Time
+		lat, lon float64
+		vals     []float64
+	}
+	var points []point
+	times := make(map[time.Time]bool)
+	lats := make(map[float64]bool)
+	lons := make(map[float64]bool)
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var p point
+		if ti >= 0 {
+			if p.t, err = parseGridTime(rec[ti]); err != nil {
+				return nil, ErrParse{Line: line, Field: header[ti], Err: err}
+			}
+		}
+		if p.lat, err = strconv.ParseFloat(strings.TrimSpace(rec[lai]), 64); err != nil {
+			return nil, ErrParse{Line: line, Field: header[lai], Err: err}
+		}
+		if p.lon, err = strconv.ParseFloat(strings.TrimSpace(rec[loi]), 64); err != nil {
+			return nil, ErrParse{Line: line, Field: header[loi], Err: err}
+		}
+		for _, i := range vars {
+			v := math.NaN()
+			if s := strings.TrimSpace(rec[i]); s != "" {
+				if v, err = strconv.ParseFloat(s, 64); err != nil {
+					return nil, ErrParse{Line: line, Field: header[i], Err: err}
+				}
+			}
+			p.vals = append(p.vals, v)
+		}
+		points = append(points, p)
+		times[p.t], lats[p.lat], lons[p.lon] = true, true, true
+	}
+	if len(points) == 0 {
+		return nil, ErrEmptySet
+	}
+
+	g.start = points[0].t
+	for t := range times {
+		if t.Before(g.start) {
+			g.start = t
+		}
+	}
+	secs := make(map[float64]bool, len(times))
+	for t := range times {
+		secs[t.Sub(g.start).Seconds()] = true
+	}
+	g.times, g.lats, g.lons = sortedKeys(secs), sortedKeys(lats), sortedKeys(lons)
+	g.wrap = g.lons[len(g.lons)-1] > 180
+	g.nlat, g.nlon, g.nvar = len(g.lats), len(g.lons), len(vars)
+	g.data = make([]float64, len(g.times)*g.nlat*g.nlon*g.nvar)
+	for i := range g.data {
+		g.data[i] = math.NaN()
+	}
+	for _, p := range points {
+		ti := sort.SearchFloat64s(g.times, p.t.Sub(g.start).Seconds())
+		copy(g.data[g.offset(ti, sort.SearchFloat64s(g.lats, p.lat), sort.SearchFloat64s(g.lons, p.lon)):], p.vals)
+	}
+	return g, nil
+}
+
+func parseGridTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	var err error
+	for _, layout := range gridTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func sortedKeys(m map[float64]bool) []float64 {
+	keys := make([]float64, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Float64s(keys)
+	return keys
+}
+
+// offset returns the index in data of the first variable at a grid point.
+func (g *Grid) offset(t, lat, lon int) int {
+	return ((t*g.nlat+lat)*g.nlon + lon) * g.nvar
+}
+
+// Fields implements the Environment interface for a Grid.
+func (g *Grid) Fields() []string { return g.fields }
+
+// Values implements the Environment interface for a Grid.
+func (g *Grid) Values(t time.Time, lat, lon float64) ([]float64, error) {
+	if g.wrap && lon < 0 {
+		lon += 360
+	}
+	vals := make([]float64, g.nvar)
+	for i := range vals {
+		vals[i] = math.NaN()
+	}
+	ts, ok := bracket(g.times, t.Sub(g.start).Seconds())
+	if !ok {
+		return vals, nil
+	}
+	las, ok := bracket(g.lats, lat)
+	if !ok {
+		return vals, nil
+	}
+	los, ok := bracket(g.lons, lon)
+	if !ok {
+		return vals, nil
+	}
+	for v := range vals {
+		var sum, weights float64
+		for _, a := range ts {
+			for _, b := range las {
+				for _, c := range los {
+					w := a.w * b.w * c.w
+					x := g.data[g.offset(a.i, b.i, c.i)+v]
+					if w == 0 || math.IsNaN(x) {
+						continue
+					}
+					sum += w * x
+					weights += w
+				}
+			}
+		}
+		if weights > 0 {
+			vals[v] = sum / weights
+		}
+	}
+	return vals, nil
+}
+
+// gridWeight is the interpolation weight of one grid index.
+type gridWeight struct {
+	i int
+	w float64
+}
+
+// bracket returns the grid indices on either side of x in the sorted axis with
+// their linear interpolation weights, and false when x is more than one step
+// outside the axis.  An axis of one value applies at every x.
+func bracket(axis []float64, x float64) ([2]gridWeight, bool) {
+	n := len(axis)
+	if n == 1 {
+		return [2]gridWeight{{0, 1}, {0, 0}}, true
+	}
+	j := sort.SearchFloat64s(axis, x)
+	switch {
+	case j == 0:
+		if axis[0]-x > axis[1]-axis[0] {
+			return [2]gridWeight{}, false
+		}
+		return [2]gridWeight{{0, 1}, {0, 0}}, true
+	case j == n:
+		if x-axis[n-1] > axis[n-1]-axis[n-2] {
+			return [2]gridWeight{}, false
+		}
+		return [2]gridWeight{{n - 1, 1}, {n - 1, 0}}, true
+	}
+	f := (x - axis[j-1]) / (axis[j] - axis[j-1])
+	return [2]gridWeight{{j - 1, 1 - f}, {j, f}}, true
+}
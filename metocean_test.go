@@ -0,0 +1,132 @@
+package ais
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGrid is two hourly steps of a one degree grid.  WaveHeight is masked at
+// the land point 37,-76.
+const testGrid = `time,latitude,longitude,WindSpeed,WaveHeight
+2017-12-01 00:00:00,36,-77,10,1.0
+2017-12-01 00:00:00,36,-76,10,1.0
+2017-12-01 00:00:00,37,-77,10,1.0
+2017-12-01 00:00:00,37,-76,10,
+2017-12-01 01:00:00,36,-77,20,2.0
+2017-12-01 01:00:00,36,-76,20,2.0
+2017-12-01 01:00:00,37,-77,20,2.0
+2017-12-01 01:00:00,37,-76,20,
+`
+
+func openTestGrid(t *testing.T, data string) *Grid {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "grid.csv")
+	if err := ioutil.WriteFile(filename, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+	g, err := OpenGrid(filename)
+	if err != nil {
+		t.Fatalf("OpenGrid() error = %v", err)
+	}
+	return g
+}
+
+func TestGrid_Values(t *testing.T) {
+	g := openTestGrid(t, testGrid)
+	if got := strings.Join(g.Fields(), ","); got != "WindSpeed,WaveHeight" {
+		t.Errorf("Grid.Fields() = %s, want WindSpeed,WaveHeight", got)
+	}
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		t          time.Time
+		lat, lon   float64
+		wind, wave float64 // NaN for not known
+	}{
+		{"grid point", start, 36, -77, 10, 1},
+		{"half hour", start.Add(30 * time.Minute), 36.5, -76.5, 15, 1.5},
+		{"near a masked point", start, 36.9, -76.1, 10, 1},
+		{"masked point", start, 37, -76, 10, math.NaN()},
+		{"one step past the end", start.Add(90 * time.Minute), 36, -77, 20, 2},
+		{"outside", start, 40, -77, math.NaN(), math.NaN()},
+		{"too late", start.Add(3 * time.Hour), 36, -77, math.NaN(), math.NaN()},
+	}
+	for _, tt := range tests {
+		vals, err := g.Values(tt.t, tt.lat, tt.lon)
+		if err != nil {
+			t.Fatalf("%s: Grid.Values() error = %v", tt.name, err)
+		}
+		for i, want := range []float64{tt.wind, tt.wave} {
+			got := vals[i]
+			if math.IsNaN(got) != math.IsNaN(want) || !math.IsNaN(want) && math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s: %s = %v, want %v", tt.name, g.Fields()[i], got, want)
+			}
+		}
+	}
+}
+
+func TestGrid_WrappedLongitudes(t *testing.T) {
+	g := openTestGrid(t, "lat,lon,time,Visibility\n"+
+		"36,283,2017-12-01T00:00:00,5\n"+
+		"36,284,2017-12-01T00:00:00,7\n")
+	vals, _ := g.Values(time.Date(2017, 12, 1, 6, 0, 0, 0, time.UTC), 36, -76.5)
+	if vals[0] != 6 {
+		t.Errorf("Visibility at -76.5 = %v, want 6", vals[0])
+	}
+}
+
+func TestOpenGrid_Errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no latitude":  "time,lon,WindSpeed\n2017-12-01T00:00:00,-76,1\n",
+		"no variables": "time,lat,lon\n2017-12-01T00:00:00,36,-76\n",
+		"bad value":    "time,lat,lon,WindSpeed\n2017-12-01T00:00:00,36,-76,calm\n",
+		"empty":        "time,lat,lon,WindSpeed\n",
+	} {
+		if _, err := readGrid(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: readGrid() error = nil, want an error", name)
+		}
+	}
+	if _, err := OpenGrid("testdata/missing.csv"); err == nil {
+		t.Errorf("OpenGrid() of a missing file error = nil, want an error")
+	}
+}
+
+func TestRecordSet_JoinEnvironment(t *testing.T) {
+	g := openTestGrid(t, testGrid)
+	data := "MMSI,BaseDateTime,LAT,LON\n" +
+		"111111111,2017-12-01T00:30:00,36.5,-76.5\n" +
+		"222222222,2017-12-01T00:00:00,45.0,-70.0\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs2, err := rs.JoinEnvironment(g)
+	if err != nil {
+		t.Fatalf("RecordSet.JoinEnvironment() error = %v", err)
+	}
+	defer rs2.Close()
+	if got := strings.Join(rs2.Headers().Fields, ","); got != "MMSI,BaseDateTime,LAT,LON,WindSpeed,WaveHeight" {
+		t.Errorf("Headers = %s", got)
+	}
+	for _, want := range []string{"15,1.5", ","} {
+		rec, err := rs2.Read()
+		if err != nil {
+			t.Fatalf("RecordSet.Read() error = %v", err)
+		}
+		if got := strings.Join((*rec)[4:], ","); got != want {
+			t.Errorf("joined values = %q, want %q", got, want)
+		}
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT,LON,WindSpeed\n"), Headers{})
+	if _, err := rs.JoinEnvironment(g); err == nil {
+		t.Errorf("JoinEnvironment() with a duplicate field error = nil, want an error")
+	}
+}
@@ -0,0 +1,123 @@
+package ais
+
+import (
+	"encoding/json"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics counts the work done by a pipeline so that a long running
+// deployment, such as a live Feed whose Records are windowed and searched for
+// interactions, can be monitored.  A single *Metrics may be shared by a Feed,
+// the RecordSets opened with ReadMetrics, and the Interactions sets created
+// with WithMetrics, and all of its methods are safe for concurrent use.
+//
+// *Metrics implements expvar.Var, so
+//
+//	m := ais.NewMetrics()
+//	expvar.Publish("ais", m)
+//
+// serves the counts as JSON on /debug/vars.  A Prometheus collector can read the
+// same values from Snapshot.
+type Metrics struct {
+	// The counts are first so that they are 64-bit aligned for atomic access
+	// on 32-bit platforms.
+	records      int64
+	parseErrors  int64
+	interactions int64
+	slides       int64
+	start        time.Time
+}
+
+// NewMetrics returns a *Metrics with every count at zero.  The rate of Records
+// parsed is averaged over the time since NewMetrics.
+func NewMetrics() *Metrics {
+	return &Metrics{start: time.Now()}
+}
+
+// MetricsSnapshot holds the values of a Metrics at one time.
+type MetricsSnapshot struct {
+	Records          int64   // Records read from a RecordSet or decoded by a Feed
+	RecordsPerSecond float64 // Records per second since NewMetrics
+	ParseErrors      int64   // lines or sentences that could not be parsed
+	Interactions     int64   // interactions added to an Interactions set
+	WindowSlides     int64   // steps of a Window by SlideWindow
+	HeapInUse        uint64  // bytes of the heap in use by the process
+	Uptime           float64 // seconds since NewMetrics
+}
+
+// Snapshot returns the current values of m.  It reads the memory statistics of
+// the runtime, which stops the world briefly, so it should be called at the
+// rate of a monitoring scrape rather than for every Record.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s := MetricsSnapshot{
+		Records:      atomic.LoadInt64(&m.records),
+		ParseErrors:  atomic.LoadInt64(&m.parseErrors),
+		Interactions: atomic.LoadInt64(&m.interactions),
+		WindowSlides: atomic.LoadInt64(&m.slides),
+		HeapInUse:    ms.HeapInuse,
+		Uptime:       time.Since(m.start).Seconds(),
+	}
+	if s.Uptime > 0 {
+		s.RecordsPerSecond = float64(s.Records) / s.Uptime
+	}
+	return s
+}
+
+// String returns the Snapshot of m as JSON, which implements expvar.Var.
+func (m *Metrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// The count methods accept a nil *Metrics, which counts nothing, so callers
+// need not check whether metrics are turned on.
+
+func (m *Metrics) addRecord() {
+	if m != nil {
+		atomic.AddInt64(&m.records, 1)
+	}
+}
+
+func (m *Metrics) addParseError() {
+	if m != nil {
+		atomic.AddInt64(&m.parseErrors, 1)
+	}
+}
+
+func (m *Metrics) addInteraction() {
+	if m != nil {
+		atomic.AddInt64(&m.interactions, 1)
+	}
+}
+
+func (m *Metrics) addSlide() {
+	if m != nil {
+		atomic.AddInt64(&m.slides, 1)
+	}
+}
+
+// ReadMetrics counts the Records read from the RecordSet, the lines that cannot
+// be parsed, and the steps of the Windows of SlideWindow in m.
+func ReadMetrics(m *Metrics) OpenOption {
+	return func(c *openConfig) error {
+		c.meter = m
+		return nil
+	}
+}
+
+// WithMetrics counts the interactions added to the set in m.  An interaction
+// that replaces a farther pair with WithTimeBucket or WithClosestApproach is not
+// counted again.
+func WithMetrics(m *Metrics) InteractionOption {
+	return func(inter *Interactions) error {
+		inter.metrics = m
+		return nil
+	}
+}
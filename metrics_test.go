@@ -0,0 +1,94 @@
+package ais
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_RecordSet(t *testing.T) {
+	m := NewMetrics()
+	rs, err := OpenRecordSet("testdata/ten.csv", ReadMetrics(m))
+	if err != nil {
+		t.Fatalf("OpenRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+	windows := 0
+	err = rs.SlideWindow(5*time.Second, 5*time.Second, func(win *Window) error {
+		windows++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RecordSet.SlideWindow() error = %v", err)
+	}
+
+	s := m.Snapshot()
+	if s.Records != 10 {
+		t.Errorf("Records = %d, want 10", s.Records)
+	}
+	if s.WindowSlides < int64(windows-1) {
+		t.Errorf("WindowSlides = %d, want at least %d", s.WindowSlides, windows-1)
+	}
+	if s.ParseErrors != 0 {
+		t.Errorf("ParseErrors = %d, want 0", s.ParseErrors)
+	}
+	if s.RecordsPerSecond <= 0 || s.HeapInUse == 0 {
+		t.Errorf("RecordsPerSecond = %v, HeapInUse = %d, want both positive", s.RecordsPerSecond, s.HeapInUse)
+	}
+}
+
+func TestMetrics_ParseErrors(t *testing.T) {
+	m := NewMetrics()
+	data := "MMSI,BaseDateTime,LAT,LON\n" +
+		"123456789,2017-12-01T00:00:00,30.0,-76.0\n" +
+		"123456789,2017-12-01T00:00:01,30.0\n" +
+		"123456789,2017-12-01T00:00:02,30.0,-76.0\n"
+	rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{}, ReadMetrics(m), LazyQuotes(false))
+	if err != nil {
+		t.Fatalf("NewRecordSetFromReader() error = %v", err)
+	}
+	for {
+		_, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+	}
+	s := m.Snapshot()
+	if s.Records != 2 || s.ParseErrors != 1 {
+		t.Errorf("Records, ParseErrors = %d, %d, want 2, 1", s.Records, s.ParseErrors)
+	}
+}
+
+func TestMetrics_Interactions(t *testing.T) {
+	m := NewMetrics()
+	inter, err := NewInteractionsWithOptions(goodHeaders, WithMetrics(m))
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	clusters := testClusters(3, 4)
+	for _, c := range append(clusters, clusters...) { // repeated pairs are not counted
+		if err := inter.AddCluster(c); err != nil {
+			t.Fatalf("Interactions.AddCluster() error = %v", err)
+		}
+	}
+	if got, want := m.Snapshot().Interactions, int64(inter.Len()); got != want {
+		t.Errorf("Interactions = %d, want %d", got, want)
+	}
+}
+
+func TestMetrics_String(t *testing.T) {
+	m := NewMetrics()
+	m.addRecord()
+	var s MetricsSnapshot
+	if err := json.Unmarshal([]byte(m.String()), &s); err != nil {
+		t.Fatalf("Metrics.String() is not JSON: %v", err)
+	}
+	if s.Records != 1 {
+		t.Errorf("Records = %d, want 1", s.Records)
+	}
+
+	var nilMetrics *Metrics
+	nilMetrics.addRecord() // must not panic
+}
@@ -0,0 +1,154 @@
+package ais
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MMSI is a Maritime Mobile Service Identity, the nine digit number that
+// identifies the station transmitting an AIS message.  It is held as a string
+// so that the leading zeros of group and coast station identities are kept.
+type MMSI string
+
+// MMSI validation errors returned by MMSI.Validate.
+var (
+	ErrMMSILength      = errors.New("mmsi: must be exactly nine digits")
+	ErrMMSIPlaceholder = errors.New("mmsi: placeholder value")
+	ErrMMSIPrefix      = errors.New("mmsi: unassigned leading digits")
+	ErrMMSIUnknownMID  = errors.New("mmsi: unallocated maritime identification digits")
+)
+
+// MID returns the three Maritime Identification Digits embedded in the MMSI
+// following ITU-R M.585.  Ship stations carry the MID in the first three
+// digits, group ship stations (0MID), coast stations (00MID), SAR aircraft
+// (111MID), handheld radios (8MID), craft associated with a parent ship
+// (98MID) and aids to navigation (99MID) carry it after their prefix.  MID
+// returns 0 for identities that do not contain a MID, such as the 970, 972
+// and 974 prefixes of SART, MOB and EPIRB-AIS devices, and for malformed MMSIs.
+func (m MMSI) MID() int {
+	if len(m) != 9 {
+		return 0
+	}
+	var digits string
+	switch {
+	case m[0] >= '2' && m[0] <= '7':
+		digits = string(m[0:3])
+	case m[:2] == "00":
+		digits = string(m[2:5])
+	case m[0] == '0', m[0] == '8':
+		digits = string(m[1:4])
+	case m[:3] == "111":
+		digits = string(m[3:6])
+	case m[:2] == "98", m[:2] == "99":
+		digits = string(m[2:5])
+	default:
+		return 0
+	}
+	mid, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+	return mid
+}
+
+// Validate returns nil when the MMSI is nine digits, is not one of the
+// placeholder values that unconfigured transponders commonly transmit
+// (all zeros, a single repeated digit, or 123456789), and contains a MID that
+// has been allocated by the ITU.  Autonomous devices with 970, 972 and 974
+// prefixes are valid without a MID.
+func (m MMSI) Validate() error {
+	if len(m) != 9 {
+		return ErrMMSILength
+	}
+	for _, c := range m {
+		if c < '0' || c > '9' {
+			return ErrMMSILength
+		}
+	}
+	if m == "123456789" || m == MMSI(repeatByte(m[0], 9)) {
+		return ErrMMSIPlaceholder
+	}
+	switch m[:3] {
+	case "970", "972", "974":
+		return nil
+	}
+	if m[0] == '1' && m[:3] != "111" || m[0] == '9' && m[:2] != "98" && m[:2] != "99" {
+		return ErrMMSIPrefix
+	}
+	if _, ok := midTable[m.MID()]; !ok {
+		return ErrMMSIUnknownMID
+	}
+	return nil
+}
+
+// Flag returns the ISO 3166-1 alpha-2 code of the flag state or territory
+// allocated the MMSI's MID, or an empty string if there is no allocated MID.
+func (m MMSI) Flag() string {
+	return midTable[m.MID()].code
+}
+
+// Country returns the name of the country or territory allocated the MMSI's
+// MID, or an empty string if there is no allocated MID.
+func (m MMSI) Country() string {
+	return midTable[m.MID()].country
+}
+
+// repeatByte returns a string of n copies of c.
+func repeatByte(c byte, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = c
+	}
+	return string(b)
+}
+
+// ValidFields is the column header appended by RecordSet.Validate.
+const ValidFields = "MMSIValid"
+
+// Validate returns a pointer to a new RecordSet with a ValidFields column
+// appended to every Record that is "true" when the Record's MMSI passes
+// MMSI.Validate and "false" otherwise, along with the number of Records
+// flagged as invalid.  Flagging rather than dropping lets a data-quality pass
+// report on the bad reports before a Subset removes them.  The Headers must
+// contain MMSI.  Validate consumes the receiver.
+func (rs *RecordSet) Validate() (*RecordSet, int, error) {
+	idx, ok := rs.Headers().Contains("MMSI")
+	if !ok {
+		return nil, 0, fmt.Errorf("validate: %w", ErrMissingHeader{Field: "MMSI"})
+	}
+
+	rs2 := NewRecordSet()
+	h := rs.Headers()
+	h.Fields = append(append([]string{}, h.Fields...), ValidFields)
+	rs2.SetHeaders(h)
+
+	invalid, written := 0, 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("validate: read error on csv file: %w", err)
+		}
+		valid := MMSI((*rec)[idx]).Validate() == nil
+		if !valid {
+			invalid++
+		}
+		if err := rs2.Write(append(*rec, strconv.FormatBool(valid))); err != nil {
+			return nil, 0, fmt.Errorf("validate: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, 0, fmt.Errorf("validate: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, 0, fmt.Errorf("validate: csv flush error: %w", err)
+	}
+	return rs2, invalid, nil
+}
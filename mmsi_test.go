@@ -0,0 +1,74 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMMSI(t *testing.T) {
+	tests := []struct {
+		mmsi    MMSI
+		wantErr error
+		mid     int
+		flag    string
+		country string
+	}{
+		{"366940480", nil, 366, "US", "United States"},
+		{"477307901", nil, 477, "HK", "Hong Kong"},
+		{"003669999", nil, 366, "US", "United States"},
+		{"036699999", nil, 366, "US", "United States"},
+		{"111232500", nil, 232, "GB", "United Kingdom"},
+		{"992351234", nil, 235, "GB", "United Kingdom"},
+		{"970123456", nil, 0, "", ""},
+		{"000000000", ErrMMSIPlaceholder, 0, "", ""},
+		{"123456789", ErrMMSIPlaceholder, 0, "", ""},
+		{"777777777", ErrMMSIPlaceholder, 777, "", ""},
+		{"36694048", ErrMMSILength, 0, "", ""},
+		{"36694048A", ErrMMSILength, 366, "US", "United States"},
+		{"199999999", ErrMMSIPrefix, 0, "", ""},
+		{"217000000", ErrMMSIUnknownMID, 217, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mmsi), func(t *testing.T) {
+			if err := tt.mmsi.Validate(); err != tt.wantErr {
+				t.Errorf("MMSI.Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := tt.mmsi.MID(); got != tt.mid {
+				t.Errorf("MMSI.MID() = %d, want %d", got, tt.mid)
+			}
+			if got := tt.mmsi.Flag(); got != tt.flag {
+				t.Errorf("MMSI.Flag() = %q, want %q", got, tt.flag)
+			}
+			if got := tt.mmsi.Country(); got != tt.country {
+				t.Errorf("MMSI.Country() = %q, want %q", got, tt.country)
+			}
+		})
+	}
+}
+
+func TestRecordSet_Validate(t *testing.T) {
+	data := "MMSI,LAT\n366940480,30.0\n000000000,30.0\n123456789,30.0\n477307901,30.0\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs2, invalid, err := rs.Validate()
+	if err != nil {
+		t.Fatalf("RecordSet.Validate() error = %v", err)
+	}
+	if invalid != 2 {
+		t.Errorf("RecordSet.Validate() invalid = %d, want 2", invalid)
+	}
+	idx, ok := rs2.Headers().Contains(ValidFields)
+	if !ok || idx != 2 {
+		t.Fatalf("RecordSet.Validate() %s index = %d, %v, want 2, true", ValidFields, idx, ok)
+	}
+	want := []string{"true", "false", "false", "true"}
+	for i := 0; rs2.Next(); i++ {
+		if got := (*rs2.Record())[idx]; got != want[i] {
+			t.Errorf("RecordSet.Validate() record %d = %q, want %q", i, got, want[i])
+		}
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("LAT\n30.0\n"), Headers{})
+	if _, _, err := rs.Validate(); err == nil {
+		t.Error("RecordSet.Validate() expected error for headers without MMSI")
+	}
+}
@@ -0,0 +1,139 @@
+package ais
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readMMSIs(t *testing.T, rs *RecordSet, index int) []string {
+	t.Helper()
+	var got []string
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, (*rec)[index])
+	}
+}
+
+func TestOpenRecordSet_IncludeMMSI(t *testing.T) {
+	rs, err := OpenRecordSet("testdata/ten.csv", IncludeMMSI("367605855", " 338029922", "123456789"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	want := []string{"338029922", "367605855"}
+	if got := readMMSIs(t, rs, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("IncludeMMSI read %v, want %v", got, want)
+	}
+
+	rs, err = OpenRecordSet("testdata/ten.csv", IncludeMMSI("367605855"), ReadColumns("LAT", "LON"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	rec, err := rs.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*rec) != 2 {
+		t.Errorf("projected record %v has %d fields, want 2", *rec, len(*rec))
+	}
+	if _, err := rs.Read(); err != io.EOF {
+		t.Errorf("second Read err = %v, want io.EOF", err)
+	}
+}
+
+func TestOpenRecordSet_ExcludeMMSI(t *testing.T) {
+	rs, err := OpenRecordSet("testdata/ten.csv", ExcludeMMSI("477307901", "367180910"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	got := readMMSIs(t, rs, 0)
+	if len(got) != 8 || got[0] != "338029922" || got[7] != "367157579" {
+		t.Errorf("ExcludeMMSI read %v", got)
+	}
+
+	data := "LAT,LON\n1,2\n"
+	if _, err := NewRecordSetFromReader(strings.NewReader(data), Headers{}, ExcludeMMSI("1")); err == nil {
+		t.Error("ExcludeMMSI without an MMSI header returned no error")
+	}
+}
+
+func TestLoadMMSIs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "fleet.txt")
+	if err := ioutil.WriteFile(filename, []byte("# fleet\n367605855\n\n 338029922 \r\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadMMSIs(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"367605855", "338029922"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadMMSIs = %v, want %v", got, want)
+	}
+	if _, err := LoadMMSIs(filepath.Join(dir, "nope.txt")); err == nil {
+		t.Error("LoadMMSIs of a missing file returned no error")
+	}
+}
+
+func TestOpenRecordSet_IncludeMMSISave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "fleet.csv")
+
+	rs, err := OpenRecordSet("testdata/ten.csv", IncludeMMSI("477307901"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if err := rs.Save(filename); err != nil {
+		t.Fatalf("RecordSet.Save() error = %v", err)
+	}
+	saved, err := OpenRecordSet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Close()
+	if got := readMMSIs(t, saved, 0); !reflect.DeepEqual(got, []string{"477307901"}) {
+		t.Errorf("Save with IncludeMMSI wrote %v, want [477307901]", got)
+	}
+
+	rs, err = OpenRecordSet("testdata/ten.csv", ExcludeMMSI("477307901", "367180910"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	rs2, err := rs.AppendField("Geohash", []string{"LAT", "LON"}, NewGeohasher(rs))
+	if err != nil {
+		t.Fatalf("RecordSet.AppendField() error = %v", err)
+	}
+	defer rs2.Close()
+	got := readMMSIs(t, rs2, 0)
+	if len(got) != 8 {
+		t.Errorf("AppendField with ExcludeMMSI wrote %d Records, want 8", len(got))
+	}
+	for _, m := range got {
+		if m == "477307901" || m == "367180910" {
+			t.Errorf("AppendField with ExcludeMMSI wrote excluded MMSI %s", m)
+		}
+	}
+}
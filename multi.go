@@ -0,0 +1,256 @@
+package ais
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MultiInteractionFields are the columns written by MultiInteractions.Save.
+// MMSIs lists the participants separated by semicolons, and PairDistances the
+// least distance of each pair of them as MMSI1-MMSI2:nm, also separated by
+// semicolons.
+const MultiInteractionFields = "Start,Vessels,MMSIs,MinDistance(nm),MaxDistance(nm),PairDistances"
+
+// PairDistance is the least distance between two vessels of a MultiInteraction.
+type PairDistance struct {
+	MMSI1, MMSI2 string  // MMSI1 sorts before MMSI2
+	Distance     float64 // nm
+}
+
+// MultiInteraction is a convergence of three or more vessels that are all
+// within a radius of each other in one time window, such as a congested
+// anchorage, a convoy, or several vessels crossing at once.
+type MultiInteraction struct {
+	Start time.Time      // start of the time window
+	MMSIs []string       // the participants, sorted
+	Pairs []PairDistance // least distance of every pair of participants in the window, sorted by MMSI1 and MMSI2
+}
+
+// MinDistance returns the least distance in nm between any two participants.
+func (m MultiInteraction) MinDistance() float64 {
+	d := math.Inf(1)
+	for _, p := range m.Pairs {
+		d = math.Min(d, p.Distance)
+	}
+	return d
+}
+
+// MaxDistance returns the greatest of the least distances in nm between the
+// pairs of participants, which is no more than the radius of the search.
+func (m MultiInteraction) MaxDistance() float64 {
+	d := 0.0
+	for _, p := range m.Pairs {
+		d = math.Max(d, p.Distance)
+	}
+	return d
+}
+
+// MultiInteractions are the convergences found by Interactions.MultiInteractions,
+// sorted by Start and then by MMSIs.
+type MultiInteractions []MultiInteraction
+
+// MultiInteractions returns the groups of at least k vessels, where k is three
+// or more, in which every pair of vessels has an interaction within radius
+// nautical miles in the same time window of the given width.  The windows
+// start at multiples of window, and an interaction belongs to the window of the
+// earlier of its two Records.  Each group is a maximal clique of the graph of
+// the vessels that interact in a window, so a vessel close to two others that
+// are far apart joins two groups rather than chaining them together.  The
+// groups are found among the pairs stored in the set, so create the
+// Interactions with WithMaxDistance of at least radius and WithMaxTimeGap of at
+// most window.  The RecordHeaders must contain MMSI, BaseDateTime, LAT, and LON.
+func (inter *Interactions) MultiInteractions(radius float64, window time.Duration, k int) (MultiInteractions, error) {
+	if radius <= 0 || math.IsNaN(radius) {
+		return nil, fmt.Errorf("multi interactions: radius must be greater than zero, got %v", radius)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("multi interactions: window must be greater than zero, got %v", window)
+	}
+	if k < 3 {
+		return nil, fmt.Errorf("multi interactions: k must be at least 3, got %d", k)
+	}
+	idx, err := inter.RecordHeaders.require("MMSI", "BaseDateTime", "LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("multi interactions: %w", err)
+	}
+	mmsiIndex, timeIndex := idx["MMSI"].Idx, idx["BaseDateTime"].Idx
+
+	// edges holds the least distance of each pair of vessels in each window.
+	type edgeKey struct {
+		start        time.Time
+		mmsi1, mmsi2 string
+	}
+	edges := make(map[edgeKey]float64)
+	err = inter.each(func(hash Hash128, pair *RecordPair) error {
+		d, err := inter.distanceOf(pair)
+		if err != nil {
+			return err
+		}
+		if d > radius {
+			return nil
+		}
+		t1, err := inter.RecordHeaders.parseTime((*pair.rec1)[timeIndex])
+		if err != nil {
+			return ErrParse{Field: "BaseDateTime", Err: err}
+		}
+		t2, err := inter.RecordHeaders.parseTime((*pair.rec2)[timeIndex])
+		if err != nil {
+			return ErrParse{Field: "BaseDateTime", Err: err}
+		}
+		if t2.Before(t1) {
+			t1 = t2
+		}
+		key := edgeKey{t1.UTC().Truncate(window), (*pair.rec1)[mmsiIndex], (*pair.rec2)[mmsiIndex]}
+		if key.mmsi2 < key.mmsi1 {
+			key.mmsi1, key.mmsi2 = key.mmsi2, key.mmsi1
+		}
+		if old, ok := edges[key]; !ok || d < old {
+			edges[key] = d
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("multi interactions: %w", err)
+	}
+
+	graphs := make(map[time.Time]map[string]map[string]float64)
+	for key, d := range edges {
+		g, ok := graphs[key.start]
+		if !ok {
+			g = make(map[string]map[string]float64)
+			graphs[key.start] = g
+		}
+		for _, e := range [][2]string{{key.mmsi1, key.mmsi2}, {key.mmsi2, key.mmsi1}} {
+			if g[e[0]] == nil {
+				g[e[0]] = make(map[string]float64)
+			}
+			g[e[0]][e[1]] = d
+		}
+	}
+
+	var out MultiInteractions
+	for start, g := range graphs {
+		for _, clique := range maximalCliques(g, k) {
+			m := MultiInteraction{Start: start, MMSIs: clique}
+			for i, a := range clique {
+				for _, b := range clique[i+1:] {
+					m.Pairs = append(m.Pairs, PairDistance{a, b, g[a][b]})
+				}
+			}
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return strings.Join(out[i].MMSIs, ",") < strings.Join(out[j].MMSIs, ",")
+	})
+	return out, nil
+}
+
+// maximalCliques returns the sorted maximal cliques of at least k vertices of
+// the undirected graph g using the Bron-Kerbosch algorithm with pivoting.
+func maximalCliques(g map[string]map[string]float64, k int) [][]string {
+	var cliques [][]string
+	var expand func(r, p, x []string)
+	expand = func(r, p, x []string) {
+		if len(p) == 0 && len(x) == 0 {
+			if len(r) >= k {
+				clique := append([]string(nil), r...)
+				sort.Strings(clique)
+				cliques = append(cliques, clique)
+			}
+			return
+		}
+		if len(r)+len(p) < k {
+			return
+		}
+		// The pivot is the vertex of p or x with the most neighbors in p.
+		pivot, most := "", -1
+		for _, u := range append(append([]string(nil), p...), x...) {
+			n := 0
+			for _, v := range p {
+				if _, ok := g[u][v]; ok {
+					n++
+				}
+			}
+			if n > most {
+				pivot, most = u, n
+			}
+		}
+		for _, v := range append([]string(nil), p...) {
+			if _, ok := g[pivot][v]; ok {
+				continue
+			}
+			var p2, x2 []string
+			for _, u := range p {
+				if _, ok := g[v][u]; ok {
+					p2 = append(p2, u)
+				}
+			}
+			for _, u := range x {
+				if _, ok := g[v][u]; ok {
+					x2 = append(x2, u)
+				}
+			}
+			expand(append(r, v), p2, x2)
+			p = removeString(p, v)
+			x = append(x, v)
+		}
+	}
+
+	vertices := make([]string, 0, len(g))
+	for v := range g {
+		vertices = append(vertices, v)
+	}
+	sort.Strings(vertices)
+	expand(nil, vertices, nil)
+	return cliques
+}
+
+// removeString returns s without the first occurrence of v.
+func removeString(s []string, v string) []string {
+	for i, u := range s {
+		if u == v {
+			return append(s[:i:i], s[i+1:]...)
+		}
+	}
+	return s
+}
+
+// Save writes the convergences to a csv file with MultiInteractionFields.
+func (mi MultiInteractions) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("multi interactions save: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(strings.Split(MultiInteractionFields, ","))
+	for _, m := range mi {
+		pairs := make([]string, len(m.Pairs))
+		for i, p := range m.Pairs {
+			pairs[i] = p.MMSI1 + "-" + p.MMSI2 + ":" + formatDistance(p.Distance)
+		}
+		w.Write([]string{
+			m.Start.Format(TimeLayout),
+			strconv.Itoa(len(m.MMSIs)),
+			strings.Join(m.MMSIs, ";"),
+			formatDistance(m.MinDistance()),
+			formatDistance(m.MaxDistance()),
+			strings.Join(pairs, ";"),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("multi interactions save: %w", err)
+	}
+	return f.Close()
+}
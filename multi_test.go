@@ -0,0 +1,89 @@
+package ais
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInteractions_MultiInteractions(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON"}}
+	c := new(Cluster)
+	for _, rec := range []Record{
+		{"100000001", "2017-12-01T00:01:00", "36.90000", "-76.10000"},
+		{"100000002", "2017-12-01T00:01:10", "36.90500", "-76.10000"},
+		{"100000003", "2017-12-01T00:01:20", "36.90000", "-76.10600"},
+		{"100000004", "2017-12-01T00:01:30", "36.89000", "-76.11500"}, // near 3 only
+		{"100000001", "2017-12-01T00:21:00", "36.95000", "-76.10000"}, // a later window of two
+		{"100000002", "2017-12-01T00:21:10", "36.95500", "-76.10000"},
+	} {
+		rec := rec
+		c.Append(&rec)
+	}
+	inter, err := NewInteractionsWithOptions(h, WithMaxDistance(1), WithMaxTimeGap(5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatal(err)
+	}
+
+	mi, err := inter.MultiInteractions(0.6, 10*time.Minute, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mi) != 1 {
+		t.Fatalf("MultiInteractions() = %+v, want one group", mi)
+	}
+	m := mi[0]
+	if got := strings.Join(m.MMSIs, ","); got != "100000001,100000002,100000003" {
+		t.Errorf("MMSIs = %s", got)
+	}
+	if !m.Start.Equal(time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Start = %v", m.Start)
+	}
+	if len(m.Pairs) != 3 || m.Pairs[0].MMSI1 != "100000001" || m.Pairs[0].MMSI2 != "100000002" {
+		t.Errorf("Pairs = %+v", m.Pairs)
+	}
+	if d := m.MinDistance(); d < 0.28 || d > 0.3 { // 1 and 3, 0.006 degrees of longitude apart
+		t.Errorf("MinDistance() = %v, want 0.29", d)
+	}
+	if d := m.MaxDistance(); d > 0.6 {
+		t.Errorf("MaxDistance() = %v, want at most the radius", d)
+	}
+
+	// With a larger radius 4 joins 3 and 1 but not 2, so there are two groups.
+	mi, err = inter.MultiInteractions(1, 10*time.Minute, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groups []string
+	for _, m := range mi {
+		groups = append(groups, strings.Join(m.MMSIs, ","))
+	}
+	if got := strings.Join(groups, " "); got != "100000001,100000002,100000003 100000001,100000003,100000004" {
+		t.Errorf("MultiInteractions(1nm) groups = %s", got)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "multi.csv")
+	if err := mi.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filename)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != MultiInteractionFields {
+		t.Errorf("Save() wrote\n%s", data)
+	}
+
+	if _, err := inter.MultiInteractions(1, time.Minute, 2); err == nil {
+		t.Error("MultiInteractions() with k of 2 should fail")
+	}
+}
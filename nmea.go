@@ -0,0 +1,546 @@
+package ais
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// navStatus holds the MarineCadastre.gov text values for the AIS navigational
+// status codes 0 through 15 reported in Class A position reports.
+var navStatus = [16]string{
+	"under way using engine",
+	"at anchor",
+	"not under command",
+	"restricted maneuverability",
+	"constrained by her draught",
+	"moored",
+	"aground",
+	"engaged in fishing",
+	"under way sailing",
+	"reserved for future amendment",
+	"reserved for future amendment",
+	"power-driven vessel towing astern",
+	"power-driven vessel pushing ahead or towing alongside",
+	"reserved for future use",
+	"AIS-SART is active",
+	"undefined",
+}
+
+// Decoder reads raw NMEA 0183 !AIVDM and !AIVDO sentences from an io.Reader and
+// decodes them into Records with the fields described by DefaultHeaders.  Multi-part
+// messages are reassembled before decoding.  Sentences that are prefixed with an
+// NMEA 4.0 tag block carrying a unix timestamp (c:) use that time for BaseDateTime.
+// All other sentences use the time returned by Clock, which defaults to the current
+// UTC time.
+//
+// Message types that cannot be represented as a position Record are skipped by
+// Decode, so a Decoder can be pointed directly at the output of a receiver.  The
+// static and voyage related data of message types 5 and 24 is remembered for each
+// MMSI instead.  It fills the VesselName, IMO, CallSign, VesselType, Length, Width,
+// and Draft fields of later position reports from the same vessel, so decoded
+// Records carry the MarineCadastre.gov column set, and is available from Static.
+type Decoder struct {
+	// Clock returns the time assigned to sentences that do not carry their own
+	// timestamp.
+	Clock func() time.Time
+
+	// Stations controls whether base station reports (type 4) and aid to
+	// navigation reports (type 21) are decoded into Records.  They are skipped
+	// by default because fixed stations create false encounters in interaction
+	// analysis.  Station Records have empty SOG, COG, and Heading fields, and
+	// the name and dimensions of an aid to navigation are written to VesselName,
+	// Length, and Width.
+	Stations bool
+
+	s      *bufio.Scanner
+	h      Headers
+	idx    map[string]int
+	parts  map[string]*fragments
+	static map[string]*StaticData
+	line   int
+	count  int    // AIVDM/AIVDO sentences seen by DecodeLine
+	source string // set by SetProvenance
+	prov   bool
+}
+
+// StaticData is the static and voyage related data of a vessel reported in AIS
+// message types 5, 19, and 24.  Fields that have not been reported hold their
+// zero value.
+type StaticData struct {
+	IMO         uint64  // IMO number
+	CallSign    string  // radio call sign
+	VesselName  string  // name of the vessel
+	VesselType  uint64  // AIS ship and cargo type code
+	Length      uint64  // meters, the sum of the distances to the bow and stern
+	Width       uint64  // meters, the sum of the distances to port and starboard
+	Draft       float64 // meters
+	Destination string  // destination entered by the crew
+
+	// ETA is the estimated time of arrival at Destination.  AIS does not
+	// report a year, so the ETA is placed in the year that makes it closest to
+	// the time of the report.  It is the zero time when not available.
+	ETA time.Time
+}
+
+// Limits on the gap between the parts of a multi-part message, which are sent
+// one after another, beyond which the parts received so far are dropped.
+const (
+	fragmentMaxSentences = 8
+	fragmentMaxAge       = time.Minute
+)
+
+// fragments holds the payloads of a multi-part message until every part has
+// been received.
+type fragments struct {
+	payloads []string
+	received int
+	t        time.Time
+	line     int      // line of the first part received
+	raw      []string // the lines of the parts in the order received
+	last     int      // Decoder count of the last part received
+}
+
+// stale reports whether a part of the message seen as sentence count of the
+// Decoder, with tag block time t, is too far from the parts received so far to
+// belong to the same message.
+func (frag *fragments) stale(count int, t time.Time) bool {
+	if count-frag.last > fragmentMaxSentences {
+		return true
+	}
+	if t.IsZero() || frag.t.IsZero() {
+		return false
+	}
+	age := t.Sub(frag.t)
+	return age > fragmentMaxAge || age < -fragmentMaxAge
+}
+
+// NewDecoder returns a *Decoder that reads sentences from r.
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{
+		Clock:  func() time.Time { return time.Now().UTC() },
+		s:      bufio.NewScanner(r),
+		h:      DefaultHeaders(),
+		idx:    make(map[string]int),
+		parts:  make(map[string]*fragments),
+		static: make(map[string]*StaticData),
+	}
+	for i, f := range d.h.Fields {
+		d.idx[f] = i
+	}
+	return d
+}
+
+// Headers returns the Headers that describe the Records returned by Decode.
+func (d *Decoder) Headers() Headers { return d.h }
+
+// SetProvenance appends ProvenanceFields and SentenceField to the Headers of the
+// Decoder so that every Record carries source, the line number of the sentence
+// counted by Decode, and the original line of receiver output.  The lines of a
+// multi-part message are joined by a space and Line is that of the first part.
+// SetProvenance must be called before the first call to Decode.
+func (d *Decoder) SetProvenance(source string) {
+	if !d.prov {
+		d.h.Fields = append(d.h.Fields, strings.Split(ProvenanceFields, ",")...)
+		d.h.Fields = append(d.h.Fields, SentenceField)
+		for i, f := range d.h.Fields {
+			d.idx[f] = i
+		}
+	}
+	d.prov = true
+	d.source = source
+}
+
+// Static returns the static and voyage related data decoded so far for the
+// vessel with the nine digit mmsi.  The bool is false if no static data has been
+// received for the vessel.
+func (d *Decoder) Static(mmsi string) (StaticData, bool) {
+	s, ok := d.static[mmsi]
+	if !ok {
+		return StaticData{}, false
+	}
+	return *s, true
+}
+
+// Decode reads sentences until a complete message is decoded into a *Record.  It
+// returns io.EOF when the underlying reader is exhausted.  Errors for a malformed
+// sentence identify the line number of the offending sentence and do not stop the
+// Decoder, so clients may log the error and call Decode again.
+func (d *Decoder) Decode() (*Record, error) {
+	for d.s.Scan() {
+		d.line++
+		rec, err := d.DecodeLine(d.s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("decode: line %d: %w", d.line, err)
+		}
+		if rec != nil {
+			return rec, nil
+		}
+	}
+	if err := d.s.Err(); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	return nil, io.EOF
+}
+
+// DecodeLine decodes a single line of receiver output.  It returns a nil *Record
+// and a nil error when the line does not contain an AIVDM/AIVDO sentence, when the
+// sentence is one part of a multi-part message that is not yet complete, or when
+// the message type is not decoded by the package.  The parts of a multi-part
+// message must arrive in order and close together; a part whose earlier parts
+// were lost is an error, and parts received more than a few sentences or a
+// minute before the next are dropped.
+func (d *Decoder) DecodeLine(line string) (*Record, error) {
+	line = strings.TrimSpace(line)
+	start := strings.Index(line, "!AIVD")
+	if start < 0 {
+		return nil, nil
+	}
+	d.count++
+	t := d.tagTime(line[:start])
+	sentence := line[start:]
+
+	star := strings.LastIndex(sentence, "*")
+	if star < 0 || len(sentence) < star+3 {
+		return nil, fmt.Errorf("sentence missing checksum: %s", sentence)
+	}
+	want, err := strconv.ParseUint(sentence[star+1:star+3], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse checksum: %s", sentence)
+	}
+	if got := nmeaChecksum(sentence[1:star]); got != byte(want) {
+		return nil, fmt.Errorf("checksum mismatch: got %02X, want %02X", got, want)
+	}
+
+	f := strings.Split(sentence[1:star], ",")
+	if len(f) != 7 {
+		return nil, fmt.Errorf("sentence has %d fields, want 7", len(f))
+	}
+	count, err := strconv.Atoi(f[1])
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid fragment count %q", f[1])
+	}
+	num, err := strconv.Atoi(f[2])
+	if err != nil || num < 1 || num > count {
+		return nil, fmt.Errorf("invalid fragment number %q", f[2])
+	}
+
+	payload := f[5]
+	first, raw := d.line, line
+	if count > 1 {
+		key := f[3] + "," + f[4]
+		frag, ok := d.parts[key]
+		if ok && frag.stale(d.count, t) {
+			delete(d.parts, key)
+			ok = false
+		}
+		if num == 1 {
+			frag = &fragments{payloads: make([]string, count), t: t, line: d.line}
+			d.parts[key] = frag
+		} else if !ok || len(frag.payloads) != count || frag.received != num-1 {
+			delete(d.parts, key)
+			return nil, fmt.Errorf("fragment %d of %d of message %q received without the parts before it", num, count, f[3])
+		}
+		frag.received++
+		frag.payloads[num-1] = payload
+		frag.raw = append(frag.raw, line)
+		frag.last = d.count
+		if frag.received < count {
+			return nil, nil
+		}
+		delete(d.parts, key)
+		payload = strings.Join(frag.payloads, "")
+		t = frag.t
+		first, raw = frag.line, strings.Join(frag.raw, " ")
+	}
+
+	bits, err := unarmor(payload)
+	if err != nil {
+		return nil, err
+	}
+	if t.IsZero() {
+		t = d.Clock()
+	}
+	rec, err := d.record(bits, t)
+	if rec != nil && d.prov {
+		(*rec)[d.idx["Source"]] = d.source
+		(*rec)[d.idx["Line"]] = strconv.Itoa(first)
+		(*rec)[d.idx[SentenceField]] = raw
+	}
+	return rec, err
+}
+
+// dropFragments discards the parts of multi-part messages that have not been
+// completed, such as those cut off when a Feed loses its connection.
+func (d *Decoder) dropFragments() {
+	for key := range d.parts {
+		delete(d.parts, key)
+	}
+}
+
+// tagTime returns the time carried in the c: field of an NMEA 4.0 tag block, or
+// the zero time if the prefix does not contain one.
+func (d *Decoder) tagTime(prefix string) time.Time {
+	prefix = strings.Trim(prefix, `\`)
+	if star := strings.Index(prefix, "*"); star >= 0 {
+		prefix = prefix[:star]
+	}
+	for _, field := range strings.Split(prefix, ",") {
+		if !strings.HasPrefix(field, "c:") {
+			continue
+		}
+		sec, err := strconv.ParseInt(field[2:], 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+		if sec > 1e11 { // some receivers report milliseconds
+			return time.Unix(0, sec*int64(time.Millisecond)).UTC()
+		}
+		return time.Unix(sec, 0).UTC()
+	}
+	return time.Time{}
+}
+
+// record converts the decoded bits of a message into a *Record.  It returns nil
+// for message types that are not decoded.
+func (d *Decoder) record(b bitfield, t time.Time) (*Record, error) {
+	if len(b) < 38 {
+		return nil, fmt.Errorf("payload too short: %d bits", len(b))
+	}
+	rec := make(Record, len(d.h.Fields))
+	set := func(field, val string) { rec[d.idx[field]] = val }
+
+	mmsi := fmt.Sprintf("%09d", b.uint(8, 30))
+	set("MMSI", mmsi)
+	set("BaseDateTime", t.Format(TimeLayout))
+
+	switch msgType := b.uint(0, 6); msgType {
+	case 1, 2, 3:
+		if len(b) < 137 {
+			return nil, fmt.Errorf("type %d payload too short: %d bits", msgType, len(b))
+		}
+		set("Status", navStatus[b.uint(38, 4)])
+		d.setPosition(rec, b.uint(50, 10), b.int(61, 28), b.int(89, 27), b.uint(116, 12), b.uint(128, 9))
+	case 18, 19:
+		if len(b) < 133 {
+			return nil, fmt.Errorf("type %d payload too short: %d bits", msgType, len(b))
+		}
+		d.setPosition(rec, b.uint(46, 10), b.int(57, 28), b.int(85, 27), b.uint(112, 12), b.uint(124, 9))
+		if msgType == 19 && len(b) >= 301 {
+			s := d.staticFor(mmsi)
+			s.VesselName = b.text(143, 120)
+			s.VesselType = b.uint(263, 8)
+			s.Length, s.Width = b.uint(271, 9)+b.uint(280, 9), b.uint(289, 6)+b.uint(295, 6)
+		}
+	case 4:
+		if !d.Stations {
+			return nil, nil
+		}
+		if len(b) < 134 {
+			return nil, fmt.Errorf("type 4 payload too short: %d bits", len(b))
+		}
+		d.setLatLon(rec, b.int(79, 28), b.int(107, 27))
+		return &rec, nil
+	case 21:
+		if !d.Stations {
+			return nil, nil
+		}
+		if len(b) < 249 {
+			return nil, fmt.Errorf("type 21 payload too short: %d bits", len(b))
+		}
+		d.setLatLon(rec, b.int(164, 28), b.int(192, 27))
+		set("VesselName", b.text(43, 120))
+		if length := b.uint(219, 9) + b.uint(228, 9); length > 0 {
+			set("Length", strconv.FormatUint(length, 10))
+		}
+		if width := b.uint(237, 6) + b.uint(243, 6); width > 0 {
+			set("Width", strconv.FormatUint(width, 10))
+		}
+		return &rec, nil
+	case 5:
+		if len(b) < 420 {
+			return nil, fmt.Errorf("type 5 payload too short: %d bits", len(b))
+		}
+		s := d.staticFor(mmsi)
+		s.IMO = b.uint(40, 30)
+		s.CallSign = b.text(70, 42)
+		s.VesselName = b.text(112, 120)
+		s.VesselType = b.uint(232, 8)
+		s.Length, s.Width = b.uint(240, 9)+b.uint(249, 9), b.uint(258, 6)+b.uint(264, 6)
+		s.ETA = etaTime(t, b.uint(274, 4), b.uint(278, 5), b.uint(283, 5), b.uint(288, 6))
+		s.Draft = float64(b.uint(294, 8)) / 10
+		s.Destination = b.text(302, 120)
+		return nil, nil
+	case 24:
+		s := d.staticFor(mmsi)
+		switch part := b.uint(38, 2); {
+		case part == 0 && len(b) >= 160:
+			s.VesselName = b.text(40, 120)
+		case part == 1 && len(b) >= 162:
+			s.VesselType = b.uint(40, 8)
+			s.CallSign = b.text(90, 42)
+			if !strings.HasPrefix(mmsi, "98") { // auxiliary craft report their mothership here
+				s.Length, s.Width = b.uint(132, 9)+b.uint(141, 9), b.uint(150, 6)+b.uint(156, 6)
+			}
+		default:
+			return nil, fmt.Errorf("type 24 part %d payload too short: %d bits", part, len(b))
+		}
+		return nil, nil
+	default:
+		return nil, nil
+	}
+	d.setStatic(rec, mmsi)
+	return &rec, nil
+}
+
+// setLatLon writes a position in 1/10000 minute units into rec.
+func (d *Decoder) setLatLon(rec Record, lon, lat int64) {
+	rec[d.idx["LON"]] = fmt.Sprintf("%.5f", float64(lon)/600000)
+	rec[d.idx["LAT"]] = fmt.Sprintf("%.5f", float64(lat)/600000)
+}
+
+// staticFor returns the StaticData held for mmsi, creating it if necessary.
+func (d *Decoder) staticFor(mmsi string) *StaticData {
+	s, ok := d.static[mmsi]
+	if !ok {
+		s = new(StaticData)
+		d.static[mmsi] = s
+	}
+	return s
+}
+
+// setStatic fills the static fields of a position report from the StaticData
+// held for mmsi.
+func (d *Decoder) setStatic(rec Record, mmsi string) {
+	s, ok := d.static[mmsi]
+	if !ok {
+		return
+	}
+	if s.VesselName != "" {
+		rec[d.idx["VesselName"]] = s.VesselName
+	}
+	if s.IMO != 0 {
+		rec[d.idx["IMO"]] = fmt.Sprintf("IMO%d", s.IMO)
+	}
+	if s.CallSign != "" {
+		rec[d.idx["CallSign"]] = s.CallSign
+	}
+	if s.VesselType != 0 {
+		rec[d.idx["VesselType"]] = strconv.FormatUint(s.VesselType, 10)
+	}
+	if s.Length > 0 {
+		rec[d.idx["Length"]] = strconv.FormatUint(s.Length, 10)
+	}
+	if s.Width > 0 {
+		rec[d.idx["Width"]] = strconv.FormatUint(s.Width, 10)
+	}
+	if s.Draft > 0 {
+		rec[d.idx["Draft"]] = fmt.Sprintf("%.1f", s.Draft)
+	}
+}
+
+// etaTime converts the month, day, hour and minute of an ETA into a time in the
+// year that places it closest to the report time t.  It returns the zero time
+// when the month or day is not available.  An unavailable hour or minute is
+// treated as zero.
+func etaTime(t time.Time, month, day, hour, minute uint64) time.Time {
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}
+	}
+	if hour > 23 {
+		hour = 0
+	}
+	if minute > 59 {
+		minute = 0
+	}
+	best := time.Time{}
+	for _, year := range []int{t.Year() - 1, t.Year(), t.Year() + 1} {
+		eta := time.Date(year, time.Month(month), int(day), int(hour), int(minute), 0, 0, time.UTC)
+		if best.IsZero() || absDuration(eta.Sub(t)) < absDuration(best.Sub(t)) {
+			best = eta
+		}
+	}
+	return best
+}
+
+// absDuration returns the absolute value of d.
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// setPosition writes the raw kinematic values of a position report into rec.
+func (d *Decoder) setPosition(rec Record, sog uint64, lon, lat int64, cog, heading uint64) {
+	rec[d.idx["SOG"]] = fmt.Sprintf("%.1f", float64(sog)/10)
+	d.setLatLon(rec, lon, lat)
+	rec[d.idx["COG"]] = fmt.Sprintf("%.1f", float64(cog)/10)
+	rec[d.idx["Heading"]] = fmt.Sprintf("%.1f", float64(heading))
+}
+
+// nmeaChecksum returns the XOR of every byte in s.
+func nmeaChecksum(s string) byte {
+	var sum byte
+	for i := 0; i < len(s); i++ {
+		sum ^= s[i]
+	}
+	return sum
+}
+
+// bitfield holds a decoded AIS payload with one bit per byte.
+type bitfield []byte
+
+// unarmor decodes the 6-bit ASCII armoring of an AIS payload.
+func unarmor(payload string) (bitfield, error) {
+	b := make(bitfield, 0, 6*len(payload))
+	for i := 0; i < len(payload); i++ {
+		c := payload[i]
+		if c < 48 || c > 119 || (c > 87 && c < 96) {
+			return nil, fmt.Errorf("invalid payload character %q", c)
+		}
+		v := c - 48
+		if v > 40 {
+			v -= 8
+		}
+		for j := 5; j >= 0; j-- {
+			b = append(b, (v>>uint(j))&1)
+		}
+	}
+	return b, nil
+}
+
+// uint returns the unsigned value of the n bits starting at start.
+func (b bitfield) uint(start, n int) uint64 {
+	var v uint64
+	for i := start; i < start+n && i < len(b); i++ {
+		v = v<<1 | uint64(b[i])
+	}
+	return v
+}
+
+// int returns the two's complement signed value of the n bits starting at start.
+func (b bitfield) int(start, n int) int64 {
+	v := b.uint(start, n)
+	if v&(1<<uint(n-1)) != 0 {
+		return int64(v) - int64(1)<<uint(n)
+	}
+	return int64(v)
+}
+
+// text returns the 6-bit ASCII string held in the n bits starting at start with
+// the '@' padding and trailing spaces removed.
+func (b bitfield) text(start, n int) string {
+	var sb strings.Builder
+	for i := start; i+6 <= start+n && i+6 <= len(b); i += 6 {
+		c := byte(b.uint(i, 6))
+		if c < 32 {
+			c += 64
+		}
+		sb.WriteByte(c)
+	}
+	return strings.TrimRight(sb.String(), "@ ")
+}
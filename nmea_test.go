@@ -0,0 +1,268 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSentence is a Class A position report for MMSI 371798000.
+const testSentence = "!AIVDM,1,1,,A,15RTgt0PAso;90TKcjM8h6g208CQ,0*4A"
+
+var testClock = func() time.Time { return time.Date(2017, time.December, 1, 0, 0, 1, 0, time.UTC) }
+
+var testSentenceRec = Record{"371798000", "2017-12-01T00:00:01", "48.38163", "-123.39538", "12.3", "224.0", "215.0",
+	"", "", "", "", "under way using engine", "", "", "", ""}
+
+// sentence wraps body in the leading ! and trailing checksum of an NMEA sentence.
+func sentence(body string) string {
+	return fmt.Sprintf("!%s*%02X", body, nmeaChecksum(body))
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	payload := "15RTgt0PAso;90TKcjM8h6g208CQ"
+	tagged := append(Record{}, testSentenceRec...)
+	tagged[1] = "2009-05-05T17:20:35"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []Record
+		wantErr bool
+	}{
+		{
+			name:  "single part position report",
+			input: testSentence + "\n",
+			want:  []Record{testSentenceRec},
+		},
+		{
+			name: "two part message",
+			input: sentence("AIVDM,2,1,3,A,"+payload[:14]+",0") + "\n" +
+				sentence("AIVDM,2,2,3,A,"+payload[14:]+",0") + "\n",
+			want: []Record{testSentenceRec},
+		},
+		{
+			name:  "tag block timestamp",
+			input: `\s:rORBCOMM000,c:1241544035*4A\` + testSentence + "\n",
+			want:  []Record{tagged},
+		},
+		{
+			name:  "non AIS lines are skipped",
+			input: "$GPGGA,123519,4807.038,N\n\n" + testSentence + "\n",
+			want:  []Record{testSentenceRec},
+		},
+		{
+			name:  "unsupported message type is skipped",
+			input: sentence("AIVDM,1,1,,A,"+"<"+payload[1:]+",0") + "\n",
+			want:  nil,
+		},
+		{
+			name:    "bad checksum",
+			input:   strings.Replace(testSentence, "*4A", "*4B", 1) + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid payload character",
+			input:   sentence("AIVDM,1,1,,A,15RT~t0PAso;90TKcjM8h6g208CQ,0") + "\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tt.input))
+			d.Clock = testClock
+			var got []Record
+			for {
+				rec, err := d.Decode()
+				if err == io.EOF {
+					break
+				}
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				got = append(got, *rec)
+			}
+			if tt.wantErr {
+				t.Fatalf("Decoder.Decode() expected an error")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decoder.Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_LostFragments(t *testing.T) {
+	payload := "15RTgt0PAso;90TKcjM8h6g208CQ"
+	part1 := sentence("AIVDM,2,1,3,A," + payload[:14] + ",0")
+	part2 := sentence("AIVDM,2,2,3,A," + payload[14:] + ",0")
+	tag := func(sec int, s string) string {
+		body := fmt.Sprintf("c:%d", 1512086400+sec)
+		return fmt.Sprintf(`\%s*%02X\%s`, body, nmeaChecksum(body), s)
+	}
+
+	tests := []struct {
+		name  string
+		lines []string
+		want  int // Records decoded before the final testSentence
+	}{
+		{"first part dropped", []string{part2}, 0},
+		{"stale first part", []string{part1, testSentence, testSentence, testSentence, testSentence,
+			testSentence, testSentence, testSentence, testSentence, part2}, 8},
+		{"first part a minute earlier", []string{tag(0, part1), tag(120, part2)}, 0},
+		{"second part twice", []string{part1, part2, part2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := strings.Join(tt.lines, "\n") + "\n" + testSentence + "\n"
+			d := NewDecoder(strings.NewReader(input))
+			d.Clock = testClock
+			var errs, recs int
+			for {
+				rec, err := d.Decode()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					errs++
+					continue
+				}
+				if !reflect.DeepEqual((*rec)[2:], testSentenceRec[2:]) {
+					t.Errorf("Decoder.Decode() = %v, want only whole position reports", *rec)
+				}
+				recs++
+			}
+			if errs != 1 {
+				t.Errorf("Decoder.Decode() returned %d errors, want 1 for the lost part", errs)
+			}
+			if recs != tt.want+1 {
+				t.Errorf("Decoder.Decode() returned %d records, want %d", recs, tt.want+1)
+			}
+		})
+	}
+}
+
+func TestBitfield(t *testing.T) {
+	b, err := unarmor("15RTgt0PAso;90TKcjM8h6g208CQ")
+	if err != nil {
+		t.Fatalf("unarmor() error = %v", err)
+	}
+	if got := b.uint(0, 6); got != 1 {
+		t.Errorf("bitfield.uint() message type = %v, want 1", got)
+	}
+	if got := b.int(61, 28); got != -74037230 {
+		t.Errorf("bitfield.int() lon = %v, want -74037230", got)
+	}
+
+	name, _ := unarmor("85<<?0P")
+	if got := name.text(0, 42); got != "HELLO" {
+		t.Errorf("bitfield.text() = %q, want %q", got, "HELLO")
+	}
+}
+
+// bitWriter builds an AIS payload for tests one field at a time.
+type bitWriter bitfield
+
+func (w *bitWriter) uint(n int, v uint64) *bitWriter {
+	for i := n - 1; i >= 0; i-- {
+		*w = append(*w, byte(v>>uint(i))&1)
+	}
+	return w
+}
+
+func (w *bitWriter) text(n int, s string) *bitWriter {
+	for i := 0; i < n/6; i++ {
+		c := byte('@')
+		if i < len(s) {
+			c = s[i]
+		}
+		if c >= 64 {
+			c -= 64
+		}
+		w.uint(6, uint64(c))
+	}
+	return w
+}
+
+// armor returns the 6-bit ASCII payload of the bits, padded to a multiple of 6.
+func (w *bitWriter) armor() string {
+	for len(*w)%6 != 0 {
+		*w = append(*w, 0)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(*w); i += 6 {
+		v := byte(bitfield(*w).uint(i, 6))
+		if v > 39 {
+			v += 8
+		}
+		sb.WriteByte(v + 48)
+	}
+	return sb.String()
+}
+
+func TestDecoder_Static(t *testing.T) {
+	type5 := new(bitWriter).uint(6, 5).uint(2, 0).uint(30, 371798000).uint(2, 0).
+		uint(30, 9134270).text(42, "3FOF8").text(120, "EVER DIADEM").uint(8, 70).
+		uint(9, 225).uint(9, 70).uint(6, 1).uint(6, 31).uint(4, 1).
+		uint(4, 5).uint(5, 15).uint(5, 14).uint(6, 0).uint(8, 122).
+		text(120, "NEW YORK").uint(1, 0).uint(1, 0).armor()
+	type24A := new(bitWriter).uint(6, 24).uint(2, 0).uint(30, 338029922).uint(2, 0).
+		text(120, "SECOND").armor()
+	type24B := new(bitWriter).uint(6, 24).uint(2, 0).uint(30, 338029922).uint(2, 1).
+		uint(8, 37).uint(18, 0).uint(4, 0).uint(20, 0).text(42, "WDC1234").
+		uint(9, 8).uint(9, 4).uint(6, 2).uint(6, 2).uint(6, 0).armor()
+
+	input := sentence("AIVDM,2,1,5,A,"+type5[:40]+",0") + "\n" +
+		sentence("AIVDM,2,2,5,A,"+type5[40:]+",2") + "\n" +
+		testSentence + "\n" +
+		sentence("AIVDM,1,1,,B,"+type24A+",0") + "\n" +
+		sentence("AIVDM,1,1,,B,"+type24B+",0") + "\n"
+
+	d := NewDecoder(strings.NewReader(input))
+	d.Clock = testClock
+	rec, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	want := append(Record{}, testSentenceRec...)
+	copy(want[7:], []string{"EVER DIADEM", "IMO9134270", "3FOF8", "70", "under way using engine", "295", "32", "12.2"})
+	if !reflect.DeepEqual(*rec, want) {
+		t.Errorf("Decoder.Decode() = %v, want %v", *rec, want)
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("Decoder.Decode() after static reports error = %v, want io.EOF", err)
+	}
+
+	s, ok := d.Static("371798000")
+	if !ok {
+		t.Fatal("Decoder.Static() did not find type 5 data")
+	}
+	eta := time.Date(2018, time.May, 15, 14, 0, 0, 0, time.UTC) // closer to the December 2017 report than May 2017
+	if s.Destination != "NEW YORK" || !s.ETA.Equal(eta) || s.IMO != 9134270 {
+		t.Errorf("Decoder.Static() = %+v, want destination NEW YORK and ETA %v", s, eta)
+	}
+	s, ok = d.Static("338029922")
+	want24 := StaticData{VesselName: "SECOND", VesselType: 37, CallSign: "WDC1234", Length: 12, Width: 4}
+	if !ok || s != want24 {
+		t.Errorf("Decoder.Static() type 24 = %+v, want %+v", s, want24)
+	}
+	if _, ok := d.Static("000000001"); ok {
+		t.Error("Decoder.Static() found data for an unknown vessel")
+	}
+}
+
+func TestEtaTime(t *testing.T) {
+	report := time.Date(2017, time.December, 30, 0, 0, 0, 0, time.UTC)
+	if got := etaTime(report, 1, 2, 24, 60); !got.Equal(time.Date(2018, time.January, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("etaTime() across new year = %v", got)
+	}
+	if got := etaTime(report, 0, 0, 24, 60); !got.IsZero() {
+		t.Errorf("etaTime() not available = %v, want zero time", got)
+	}
+}
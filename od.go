@@ -0,0 +1,254 @@
+package ais
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ODFields are the columns written by ODMatrix.Save.  Period is the start of the
+// period of the departures in TimeLayout, or empty when the matrix has a single
+// period, and Category is a VesselCategory.
+const ODFields = "Period,Category,Origin,Destination,Count"
+
+// Port is a named area where voyages begin and end, such as a harbor, anchorage,
+// or terminal.
+type Port struct {
+	Name  string
+	Fence *Geofence
+}
+
+// NewPortsGeoJSON returns a Port for each Feature of a GeoJSON FeatureCollection
+// with a polygonal geometry, named by the nameProperty member of the properties
+// of the Feature.
+func NewPortsGeoJSON(data []byte, nameProperty string) ([]Port, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("ports: geojson: %w", err)
+	}
+	if obj.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("ports: geojson: type %q is not a FeatureCollection", obj.Type)
+	}
+	ports := make([]Port, len(obj.Features))
+	for i := range obj.Features {
+		f := &obj.Features[i]
+		name, ok := f.Properties[nameProperty]
+		if !ok {
+			return nil, fmt.Errorf("ports: geojson: feature %d has no property %q", i, nameProperty)
+		}
+		polys, err := f.polygons()
+		if err != nil {
+			return nil, fmt.Errorf("ports: geojson: feature %d: %w", i, err)
+		}
+		fence, err := newGeofence(polys)
+		if err != nil {
+			return nil, fmt.Errorf("ports: feature %d: %w", i, err)
+		}
+		ports[i] = Port{Name: fmt.Sprint(name), Fence: fence}
+	}
+	return ports, nil
+}
+
+// Voyage is a passage of one vessel from one Port to a different Port.
+type Voyage struct {
+	MMSI                string
+	Origin, Destination string    // names of the Ports
+	Departure, Arrival  time.Time // last report in Origin and first report in Destination
+	Category            VesselCategory
+}
+
+// Voyages returns the passages of the Track between ports.  A voyage begins
+// with the last Record of the Track inside one Port and ends with the next
+// Record inside a different Port; Records outside every Port are the voyage
+// itself.  A vessel that leaves a Port and returns to it without visiting
+// another, such as a fishing trip, makes no voyage.  A position inside more than
+// one Port belongs to the first of them.  The Category of each Voyage is that of
+// the VesselType of the first Record of the Track, or UnknownCategory when the
+// Headers do not contain VesselType.
+func (t *Track) Voyages(ports []Port) ([]Voyage, error) {
+	if len(t.data) == 0 {
+		return nil, nil
+	}
+	category := UnknownCategory
+	if vt, err := t.data[0].VesselType(t.h); err == nil {
+		category = vt.Category()
+	}
+
+	var voyages []Voyage
+	current := -1 // Port of the most recent Record inside any Port
+	var departure time.Time
+	for i := range t.data {
+		lat, lon, err := t.position(i)
+		if err != nil {
+			return nil, fmt.Errorf("track voyages: %w", err)
+		}
+		p := portAt(ports, lat, lon)
+		if p < 0 {
+			continue
+		}
+		if current >= 0 && p != current {
+			voyages = append(voyages, Voyage{
+				MMSI:        t.MMSI,
+				Origin:      ports[current].Name,
+				Destination: ports[p].Name,
+				Departure:   departure,
+				Arrival:     t.times[i],
+				Category:    category,
+			})
+		}
+		current = p
+		departure = t.times[i]
+	}
+	return voyages, nil
+}
+
+// portAt returns the index of the first Port containing the position, or -1.
+func portAt(ports []Port, lat, lon float64) int {
+	for i, p := range ports {
+		if p.Fence.Contains(lat, lon) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ODKey is one cell of an ODMatrix.
+type ODKey struct {
+	Period              time.Time // start of the period of the Departure, zero for a single period
+	Category            VesselCategory
+	Origin, Destination string
+}
+
+// ODCount is the number of voyages in one cell of an ODMatrix.
+type ODCount struct {
+	ODKey
+	Count int
+}
+
+// ODMatrix counts the voyages between pairs of Ports by VesselCategory and
+// period of departure, the origin-destination matrix of the traffic in an area.
+// Create one with NewODMatrix and add the vessels with AddTrack or
+// AddRecordSet.  Set SegmentGap or MaxJump before adding Tracks to split each
+// Track with Track.Segment, so that voyages are not counted across a long loss
+// of coverage or between two vessels transmitting the same MMSI.
+type ODMatrix struct {
+	Ports      []Port
+	Period     time.Duration // width of the departure periods, zero for a single period
+	SegmentGap time.Duration // gap argument of Track.Segment, zero to not split on time
+	MaxJump    float64       // maxJump argument of Track.Segment in nm, zero to not split on position
+	counts     map[ODKey]int
+}
+
+// NewODMatrix returns an empty ODMatrix for the ports with departures grouped in
+// periods of the given width, such as 24*time.Hour for daily counts.  Periods
+// start at whole multiples of the width since the zero time in UTC.
+func NewODMatrix(ports []Port, period time.Duration) (*ODMatrix, error) {
+	if len(ports) < 2 {
+		return nil, fmt.Errorf("new od matrix: need at least two ports, got %d", len(ports))
+	}
+	if period < 0 {
+		return nil, fmt.Errorf("new od matrix: period must not be negative, got %v", period)
+	}
+	for i, p := range ports {
+		if p.Fence == nil {
+			return nil, fmt.Errorf("new od matrix: port %d (%s) has no fence", i, p.Name)
+		}
+	}
+	return &ODMatrix{Ports: ports, Period: period, counts: make(map[ODKey]int)}, nil
+}
+
+// AddTrack counts the voyages of a Track.
+func (od *ODMatrix) AddTrack(t *Track) error {
+	segs := []Track{*t}
+	if od.SegmentGap > 0 || od.MaxJump > 0 {
+		var err error
+		if segs, err = t.Segment(od.SegmentGap, od.MaxJump); err != nil {
+			return fmt.Errorf("od matrix: %w", err)
+		}
+	}
+	for i := range segs {
+		voyages, err := segs[i].Voyages(od.Ports)
+		if err != nil {
+			return fmt.Errorf("od matrix: %w", err)
+		}
+		for _, v := range voyages {
+			od.Add(v)
+		}
+	}
+	return nil
+}
+
+// AddRecordSet counts the voyages of every vessel in the RecordSet, which is
+// read into Tracks with RecordSet.Tracks and consumed.
+func (od *ODMatrix) AddRecordSet(rs *RecordSet) error {
+	tracks, err := rs.Tracks()
+	if err != nil {
+		return fmt.Errorf("od matrix: %w", err)
+	}
+	for _, t := range tracks {
+		if err := od.AddTrack(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add counts a single Voyage.
+func (od *ODMatrix) Add(v Voyage) {
+	k := ODKey{Category: v.Category, Origin: v.Origin, Destination: v.Destination}
+	if od.Period > 0 {
+		k.Period = v.Departure.UTC().Truncate(od.Period)
+	}
+	od.counts[k]++
+}
+
+// Counts returns the non-empty cells of the matrix sorted by Period, Category,
+// Origin, and Destination.
+func (od *ODMatrix) Counts() []ODCount {
+	counts := make([]ODCount, 0, len(od.counts))
+	for k, n := range od.counts {
+		counts = append(counts, ODCount{k, n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		switch {
+		case !a.Period.Equal(b.Period):
+			return a.Period.Before(b.Period)
+		case a.Category != b.Category:
+			return a.Category < b.Category
+		case a.Origin != b.Origin:
+			return a.Origin < b.Origin
+		}
+		return a.Destination < b.Destination
+	})
+	return counts
+}
+
+// Save writes the non-empty cells of the matrix to a csv file with ODFields, in
+// the order of Counts.
+func (od *ODMatrix) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("od matrix save: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(strings.Split(ODFields, ","))
+	for _, c := range od.Counts() {
+		period := ""
+		if od.Period > 0 {
+			period = c.Period.Format(TimeLayout)
+		}
+		w.Write([]string{period, c.Category.String(), c.Origin, c.Destination, strconv.Itoa(c.Count)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("od matrix save: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,93 @@
+package ais
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestODMatrix(t *testing.T) {
+	ports, err := NewPortsGeoJSON([]byte(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {"name": "Alpha"},
+		 "geometry": {"type": "Polygon", "coordinates": [[[-76.1, 35.9], [-75.9, 35.9], [-75.9, 36.1], [-76.1, 36.1], [-76.1, 35.9]]]}},
+		{"type": "Feature", "properties": {"name": "Bravo"},
+		 "geometry": {"type": "Polygon", "coordinates": [[[-75.1, 35.9], [-74.9, 35.9], [-74.9, 36.1], [-75.1, 36.1], [-75.1, 35.9]]]}}
+	]}`), "name")
+	if err != nil {
+		t.Fatalf("NewPortsGeoJSON() error = %v", err)
+	}
+	if len(ports) != 2 || ports[0].Name != "Alpha" || ports[1].Name != "Bravo" {
+		t.Fatalf("NewPortsGeoJSON() = %v, want Alpha and Bravo", ports)
+	}
+
+	// Alpha, at sea, Bravo, Bravo, at sea, Alpha, at sea, and back to Alpha.
+	var recs []Record
+	for i, lon := range []string{"-76.0", "-75.5", "-75.0", "-75.05", "-75.5", "-76.0", "-76.5", "-76.05"} {
+		ts := time.Date(2017, 12, 1, 22+i, 0, 0, 0, time.UTC).Format(TimeLayout)
+		recs = append(recs, Record{"100000001", ts, "36.0", lon, "", "", "", "", "", "", "70", "", "", "", "", ""})
+	}
+	track, err := NewTrack(goodHeaders, recs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	voyages, err := track.Voyages(ports)
+	if err != nil {
+		t.Fatalf("Track.Voyages() error = %v", err)
+	}
+	if len(voyages) != 2 {
+		t.Fatalf("Track.Voyages() = %v, want 2 voyages", voyages)
+	}
+	v := voyages[0]
+	if v.Origin != "Alpha" || v.Destination != "Bravo" || v.Category != Cargo ||
+		!v.Departure.Equal(track.times[0]) || !v.Arrival.Equal(track.times[2]) {
+		t.Errorf("Track.Voyages()[0] = %+v", v)
+	}
+	if v := voyages[1]; v.Origin != "Bravo" || v.Destination != "Alpha" || !v.Departure.Equal(track.times[3]) {
+		t.Errorf("Track.Voyages()[1] = %+v", v)
+	}
+
+	od, err := NewODMatrix(ports, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewODMatrix() error = %v", err)
+	}
+	if err := od.AddTrack(track); err != nil {
+		t.Fatalf("ODMatrix.AddTrack() error = %v", err)
+	}
+	counts := od.Counts()
+	if len(counts) != 2 || counts[0].Origin != "Alpha" || counts[1].Origin != "Bravo" ||
+		counts[1].Period.Sub(counts[0].Period) != 24*time.Hour {
+		t.Errorf("ODMatrix.Counts() = %v, want one voyage on each of two days", counts)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "od.csv")
+	if err := od.Save(filename); err != nil {
+		t.Fatalf("ODMatrix.Save() error = %v", err)
+	}
+	f, _ := os.Open(filename)
+	defer f.Close()
+	rows, _ := csv.NewReader(f).ReadAll()
+	if want := []string{"2017-12-01T00:00:00", "cargo", "Alpha", "Bravo", "1"}; len(rows) != 3 || !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("ODMatrix.Save() wrote %v, want second row %v", rows, want)
+	}
+
+	// Splitting the Track at every gap of more than 30 minutes leaves no voyage.
+	od, _ = NewODMatrix(ports, 0)
+	od.SegmentGap = 30 * time.Minute
+	od.AddTrack(track)
+	if n := len(od.Counts()); n != 0 {
+		t.Errorf("ODMatrix.AddTrack() with SegmentGap counted %d cells, want 0", n)
+	}
+
+	if _, err := NewODMatrix(ports[:1], 0); err == nil {
+		t.Error("NewODMatrix() expected error for a single port")
+	}
+}
@@ -0,0 +1,99 @@
+package ais
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// OutputOrder is the order in which Save and the other methods that write an
+// Interactions set visit its interactions.
+type OutputOrder int
+
+const (
+	// Unordered writes interactions in the order of the underlying maps, which
+	// changes from run to run.  It is the default and the fastest because the set
+	// is not copied.
+	Unordered OutputOrder = iota
+
+	// OrderByTime writes interactions by the earlier BaseDateTime of their two
+	// Records, and by InteractionHash for interactions at the same time.
+	OrderByTime
+
+	// OrderByHash writes interactions by InteractionHash, an order that depends
+	// only on the interactions in the set.
+	OrderByHash
+)
+
+var outputOrderNames = [...]string{
+	Unordered:   "unordered",
+	OrderByTime: "time",
+	OrderByHash: "hash",
+}
+
+// String implements the Stringer interface for OutputOrder.
+func (o OutputOrder) String() string {
+	if o < 0 || int(o) >= len(outputOrderNames) {
+		return fmt.Sprintf("OutputOrder(%d)", int(o))
+	}
+	return outputOrderNames[o]
+}
+
+// SetOrder sets the order of the interactions written by Save, WriteCSV,
+// SaveGeoJSON, SaveKML, SaveJSONL, and SaveSQL.  A fixed order makes the output of
+// two runs over the same data identical, so results can be compared with diff in
+// regression tests.  Ordered output sorts a copy of the set in memory before it
+// is written.  OrderByTime requires the RecordHeaders to contain BaseDateTime.
+func (inter *Interactions) SetOrder(o OutputOrder) error {
+	switch o {
+	case Unordered, OrderByHash:
+	case OrderByTime:
+		if _, err := inter.RecordHeaders.require("BaseDateTime"); err != nil {
+			return fmt.Errorf("set order: %w", err)
+		}
+	default:
+		return fmt.Errorf("set order: unknown order %v", o)
+	}
+	inter.order = o
+	return nil
+}
+
+// orderedPair is one interaction of a sorted snapshot of the set.
+type orderedPair struct {
+	hash Hash128
+	pair *RecordPair
+	time string // earlier BaseDateTime of the pair for OrderByTime
+}
+
+// eachOrdered calls fn for every interaction in the OutputOrder of the set.
+func (inter *Interactions) eachOrdered(fn func(hash Hash128, pair *RecordPair) error) error {
+	timeIndex := inter.hashIndices[1]
+	pairs := make([]orderedPair, 0, inter.Len())
+	err := inter.eachUnordered(func(hash Hash128, pair *RecordPair) error {
+		op := orderedPair{hash: hash, pair: pair}
+		if inter.order == OrderByTime {
+			// TimeLayout sorts as text, so the times need not be parsed.
+			op.time = (*pair.rec1)[timeIndex]
+			if t2 := (*pair.rec2)[timeIndex]; t2 < op.time {
+				op.time = t2
+			}
+		}
+		pairs = append(pairs, op)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].time != pairs[j].time {
+			return pairs[i].time < pairs[j].time
+		}
+		return bytes.Compare(pairs[i].hash[:], pairs[j].hash[:]) < 0
+	})
+	for _, op := range pairs {
+		if err := fn(op.hash, op.pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
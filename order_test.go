@@ -0,0 +1,70 @@
+package ais
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"sort"
+	"testing"
+)
+
+func TestInteractions_SetOrder(t *testing.T) {
+	inter, _ := NewInteractionsWithOptions(goodHeaders, WithRecordColumns())
+	for _, c := range testClusters(20, 3) {
+		if err := inter.AddCluster(c); err != nil {
+			t.Fatalf("Interactions.AddCluster() error = %v", err)
+		}
+	}
+	timeIndex, _ := inter.OutputHeaders.Contains("BaseDateTime_1")
+
+	rows := func() [][]string {
+		var buf bytes.Buffer
+		if err := inter.WriteCSV(&buf); err != nil {
+			t.Fatalf("Interactions.WriteCSV() error = %v", err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != inter.Len()+1 {
+			t.Fatalf("Interactions.WriteCSV() wrote %d rows, want %d", len(rows), inter.Len()+1)
+		}
+		return rows[1:]
+	}
+
+	if err := inter.SetOrder(OrderByHash); err != nil {
+		t.Fatalf("Interactions.SetOrder() error = %v", err)
+	}
+	byHash := rows()
+	if !sort.SliceIsSorted(byHash, func(i, j int) bool { return byHash[i][0] < byHash[j][0] }) {
+		t.Error("rows written with OrderByHash are not sorted by InteractionHash")
+	}
+
+	if err := inter.SetOrder(OrderByTime); err != nil {
+		t.Fatalf("Interactions.SetOrder() error = %v", err)
+	}
+	byTime := rows()
+	if !sort.SliceIsSorted(byTime, func(i, j int) bool { return byTime[i][timeIndex] < byTime[j][timeIndex] }) {
+		t.Error("rows written with OrderByTime are not sorted by BaseDateTime")
+	}
+	again := rows()
+	for i := range byTime {
+		if byTime[i][0] != again[i][0] {
+			t.Fatalf("two writes with OrderByTime differ at row %d", i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := inter.WriteCSVContext(ctx, &bytes.Buffer{}); err == nil {
+		t.Error("Interactions.WriteCSVContext() expected error for canceled context")
+	}
+
+	bad, _ := NewInteractions(Headers{Fields: []string{"MMSI", "LAT", "LON"}})
+	if err := bad.SetOrder(OrderByTime); err == nil {
+		t.Error("Interactions.SetOrder() expected error for missing BaseDateTime header")
+	}
+	if err := inter.SetOrder(OutputOrder(9)); err == nil {
+		t.Error("Interactions.SetOrder() expected error for unknown order")
+	}
+}
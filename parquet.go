@@ -0,0 +1,603 @@
+package ais
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parquet physical types, converted types, encodings and repetition values used
+// by SaveParquet and OpenParquet.  The names follow parquet.thrift.
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetFloat     = 4
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetOptional = 1
+
+	parquetDataPage = 0
+)
+
+// parquetMagic begins and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// parquetTypes maps the Headers that SaveParquet stores as typed Parquet columns to
+// their Parquet physical type.  BaseDateTime is stored as an INT64 TIMESTAMP_MILLIS.
+// Every other field is stored as a UTF8 BYTE_ARRAY.
+var parquetTypes = map[string]int32{
+	"BaseDateTime": parquetInt64,
+	"LAT":          parquetDouble,
+	"LON":          parquetDouble,
+	"SOG":          parquetDouble,
+	"COG":          parquetDouble,
+}
+
+// parquetColumn describes one column of a flat Parquet schema.
+type parquetColumn struct {
+	name       string
+	typ        int32
+	converted  int32 // -1 when the column has no converted type
+	repetition int32
+}
+
+// parquetChunk holds the metadata for one column chunk that has been written.
+type parquetChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+// parquetWriter buffers a row group of Records and writes each column as a single
+// uncompressed PLAIN encoded data page.
+type parquetWriter struct {
+	w          *bufio.Writer
+	offset     int64
+	cols       []parquetColumn
+	buf        [][]string // buffered values by column for the current row group
+	rows       int
+	numRows    int64
+	rowGroups  [][]parquetChunk
+	rowCounts  []int64
+	timeParser TimeParser // from the Headers, nil for TimeLayout
+}
+
+func newParquetWriter(w io.Writer, h Headers) (*parquetWriter, error) {
+	pw := &parquetWriter{w: bufio.NewWriter(w), timeParser: h.Time}
+	for _, name := range h.Fields {
+		col := parquetColumn{name: name, typ: parquetByteArray, converted: parquetUTF8, repetition: parquetOptional}
+		if typ, ok := parquetTypes[name]; ok {
+			col.typ, col.converted = typ, -1
+			if name == "BaseDateTime" {
+				col.converted = parquetTimestampMillis
+			}
+		}
+		pw.cols = append(pw.cols, col)
+	}
+	pw.buf = make([][]string, len(pw.cols))
+	return pw, pw.write([]byte(parquetMagic))
+}
+
+func (pw *parquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// append adds a Record to the current row group.  Records with fewer fields than
+// the Headers are padded with empty values.
+func (pw *parquetWriter) append(rec Record) error {
+	for i := range pw.cols {
+		v := ""
+		if i < len(rec) {
+			v = rec[i]
+		}
+		pw.buf[i] = append(pw.buf[i], v)
+	}
+	pw.rows++
+	if pw.rows == flushThreshold {
+		return pw.flushRowGroup()
+	}
+	return nil
+}
+
+func (pw *parquetWriter) flushRowGroup() error {
+	if pw.rows == 0 {
+		return nil
+	}
+	chunks := make([]parquetChunk, len(pw.cols))
+	for i, col := range pw.cols {
+		page, err := encodeParquetPage(col, pw.buf[i], pw.timeParser)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.name, err)
+		}
+		chunks[i] = parquetChunk{offset: pw.offset, size: int64(len(page)), numValues: int64(pw.rows)}
+		if err := pw.write(page); err != nil {
+			return err
+		}
+		pw.buf[i] = pw.buf[i][:0]
+	}
+	pw.rowGroups = append(pw.rowGroups, chunks)
+	pw.rowCounts = append(pw.rowCounts, int64(pw.rows))
+	pw.numRows += int64(pw.rows)
+	pw.rows = 0
+	return nil
+}
+
+// close writes the final row group and the file footer.
+func (pw *parquetWriter) close() error {
+	if err := pw.flushRowGroup(); err != nil {
+		return err
+	}
+
+	var t thriftWriter
+	t.i32(1, 1) // version
+	t.list(2, thriftStruct, len(pw.cols)+1)
+	t.beginStruct(0)
+	t.str(4, "schema")
+	t.i32(5, int32(len(pw.cols)))
+	t.endStruct()
+	for _, col := range pw.cols {
+		t.beginStruct(0)
+		t.i32(1, col.typ)
+		t.i32(3, col.repetition)
+		t.str(4, col.name)
+		if col.converted >= 0 {
+			t.i32(6, col.converted)
+		}
+		t.endStruct()
+	}
+	t.i64(3, pw.numRows)
+	t.list(4, thriftStruct, len(pw.rowGroups))
+	for g, chunks := range pw.rowGroups {
+		var total int64
+		t.beginStruct(0)
+		t.list(1, thriftStruct, len(chunks))
+		for i, c := range chunks {
+			total += c.size
+			t.beginStruct(0)
+			t.i64(2, c.offset)
+			t.beginStruct(3)
+			t.i32(1, pw.cols[i].typ)
+			t.list(2, thriftI32, 2)
+			t.elemI32(parquetPlain)
+			t.elemI32(parquetRLE)
+			t.list(3, thriftBinary, 1)
+			t.elemStr(pw.cols[i].name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, c.numValues)
+			t.i64(6, c.size)
+			t.i64(7, c.size)
+			t.i64(9, c.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, total)
+		t.i64(3, pw.rowCounts[g])
+		t.endStruct()
+	}
+	t.str(6, "github.com/FATHOM5/ais")
+	t.buf.WriteByte(0)
+
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], uint32(t.buf.Len()))
+	if err := pw.write(t.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := pw.write(footer[:]); err != nil {
+		return err
+	}
+	if err := pw.write([]byte(parquetMagic)); err != nil {
+		return err
+	}
+	return pw.w.Flush()
+}
+
+// encodeParquetPage returns a page header and data page holding values.  Empty
+// values in typed columns are written as nulls.  Timestamps are parsed with p.
+func encodeParquetPage(col parquetColumn, values []string, p TimeParser) ([]byte, error) {
+	levels := make([]byte, len(values))
+	var data bytes.Buffer
+	var b [8]byte
+	for i, v := range values {
+		if v == "" && col.typ != parquetByteArray {
+			continue
+		}
+		levels[i] = 1
+		switch col.typ {
+		case parquetDouble:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, err
+			}
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+			data.Write(b[:])
+		case parquetInt64:
+			t, err := Headers{Time: p}.parseTime(strings.TrimSpace(v))
+			if err != nil {
+				return nil, err
+			}
+			binary.LittleEndian.PutUint64(b[:], uint64(t.UnixNano()/int64(time.Millisecond)))
+			data.Write(b[:])
+		default:
+			binary.LittleEndian.PutUint32(b[:4], uint32(len(v)))
+			data.Write(b[:4])
+			data.WriteString(v)
+		}
+	}
+
+	defLevels := encodeRLE(levels)
+	var body bytes.Buffer
+	binary.LittleEndian.PutUint32(b[:4], uint32(len(defLevels)))
+	body.Write(b[:4])
+	body.Write(defLevels)
+	body.Write(data.Bytes())
+
+	var t thriftWriter
+	t.i32(1, parquetDataPage)
+	t.i32(2, int32(body.Len()))
+	t.i32(3, int32(body.Len()))
+	t.beginStruct(5)
+	t.i32(1, int32(len(values)))
+	t.i32(2, parquetPlain)
+	t.i32(3, parquetRLE)
+	t.i32(4, parquetRLE)
+	t.endStruct()
+	t.buf.WriteByte(0)
+
+	return append(t.buf.Bytes(), body.Bytes()...), nil
+}
+
+// encodeRLE writes levels of bit width one in the RLE run form of the Parquet
+// RLE/bit-packing hybrid encoding.
+func encodeRLE(levels []byte) []byte {
+	var out []byte
+	var tmp [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		n := binary.PutUvarint(tmp[:], uint64(j-i)<<1)
+		out = append(out, tmp[:n]...)
+		out = append(out, levels[i])
+		i = j
+	}
+	return out
+}
+
+// decodeHybrid reads n levels of bit width one from the Parquet RLE/bit-packing
+// hybrid encoding.  Runs longer than the levels left are cut short.
+func decodeHybrid(r *bytes.Reader, n int) ([]byte, error) {
+	var levels []byte
+	for len(levels) < n {
+		h, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if h&1 == 0 { // RLE run
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			for i := uint64(0); i < h>>1 && len(levels) < n; i++ {
+				levels = append(levels, v&1)
+			}
+			continue
+		}
+		for g := uint64(0); g < h>>1; g++ { // bit-packed groups of eight values
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			for i := uint(0); i < 8; i++ {
+				levels = append(levels, (v>>i)&1)
+			}
+		}
+	}
+	return levels[:n], nil
+}
+
+// SaveParquet writes the RecordSet to filename in the Apache Parquet columnar
+// format.  The schema is derived from the Headers of the RecordSet with the typed
+// columns LAT, LON, SOG, COG, and BaseDateTime and UTF8 strings for every other field.  Empty
+// values in typed columns are stored as nulls.  Records are written in row groups
+// of up to 250,000 records using uncompressed PLAIN encoding so that the output
+// can be read by any Parquet implementation.  Like Save, SaveParquet reads the
+// RecordSet to the end of its data.
+func (rs *RecordSet) SaveParquet(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("recordset save parquet: %w", err)
+	}
+	defer out.Close()
+
+	pw, err := newParquetWriter(out, rs.Headers())
+	if err != nil {
+		return fmt.Errorf("recordset save parquet: %w", err)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("recordset save parquet: %w", err)
+		}
+		if err := pw.append(*rec); err != nil {
+			return fmt.Errorf("recordset save parquet: %w", err)
+		}
+	}
+	if err := pw.close(); err != nil {
+		return fmt.Errorf("recordset save parquet: %w", err)
+	}
+	return nil
+}
+
+// OpenParquet reads a Parquet file with a flat schema, such as one written by
+// SaveParquet, into a new in-memory *RecordSet.  The Headers of the RecordSet are
+// the column names of the file.  DOUBLE values are formatted with at least one
+// decimal place and TIMESTAMP_MILLIS values are formatted with TimeLayout so that
+// the Records match the MarineCadastre csv representation.  Only uncompressed
+// PLAIN encoded data pages are supported.  Lengths and counts read from the file
+// are checked against its size, so a corrupt file returns an error.
+func OpenParquet(filename string) (*RecordSet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+
+	var tail [8]byte
+	if fi.Size() < 12 {
+		return nil, fmt.Errorf("open parquet: %s is too small to be a parquet file", filename)
+	}
+	if _, err := f.ReadAt(tail[:], fi.Size()-8); err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	if string(tail[4:]) != parquetMagic {
+		return nil, fmt.Errorf("open parquet: %s is not a parquet file", filename)
+	}
+	metaLen := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if metaLen > fi.Size()-8 {
+		return nil, fmt.Errorf("open parquet: %s has a footer of %d bytes, longer than the file", filename, metaLen)
+	}
+	metaBuf := make([]byte, metaLen)
+	if _, err := f.ReadAt(metaBuf, fi.Size()-8-metaLen); err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	tr := thriftReader{r: bytes.NewReader(metaBuf)}
+	meta, err := tr.readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: unable to read metadata: %w", err)
+	}
+
+	var cols []parquetColumn
+	for i, s := range meta.list(2) {
+		el, _ := s.(thriftFields)
+		if i == 0 { // root element
+			continue
+		}
+		if el.int(5) > 0 {
+			return nil, fmt.Errorf("open parquet: nested column %s is not supported", el.str(4))
+		}
+		col := parquetColumn{name: el.str(4), typ: int32(el.int(1)), converted: -1, repetition: int32(el.int(3))}
+		if _, ok := el[6]; ok {
+			col.converted = int32(el.int(6))
+		}
+		cols = append(cols, col)
+	}
+
+	rs := NewRecordSet()
+	h := Headers{}
+	for _, col := range cols {
+		h.Fields = append(h.Fields, col.name)
+	}
+	rs.SetHeaders(h)
+
+	for _, g := range meta.list(4) {
+		group, _ := g.(thriftFields)
+		numRows := int(group.int(3))
+		values := make([][]string, len(cols))
+		decoded := 0
+		for i, c := range group.list(1) {
+			if i >= len(cols) {
+				break
+			}
+			cc, ok := c.(thriftFields)
+			if !ok {
+				return nil, fmt.Errorf("open parquet: column %s has malformed chunk metadata", cols[i].name)
+			}
+			cm := cc.child(3)
+			if cm.int(4) != 0 {
+				return nil, fmt.Errorf("open parquet: column %s is compressed", cols[i].name)
+			}
+			offset, size, numValues := cm.int(9), cm.int(7), cm.int(5)
+			if offset < 0 || size < 0 || size > fi.Size()-offset {
+				return nil, fmt.Errorf("open parquet: column %s has a chunk of %d bytes at offset %d outside the file", cols[i].name, size, offset)
+			}
+			if numValues < 0 {
+				return nil, fmt.Errorf("open parquet: column %s has a chunk of %d values", cols[i].name, numValues)
+			}
+			chunk := make([]byte, size)
+			if _, err := f.ReadAt(chunk, offset); err != nil {
+				return nil, fmt.Errorf("open parquet: %w", err)
+			}
+			values[i], err = decodeParquetChunk(cols[i], chunk, int(numValues))
+			if err != nil {
+				return nil, fmt.Errorf("open parquet: column %s: %w", cols[i].name, err)
+			}
+			if len(values[i]) > decoded {
+				decoded = len(values[i])
+			}
+		}
+		for r := 0; r < numRows && r < decoded; r++ {
+			rec := make(Record, len(cols))
+			for i := range cols {
+				if r < len(values[i]) {
+					rec[i] = values[i][r]
+				}
+			}
+			if err := rs.Write(rec); err != nil {
+				return nil, fmt.Errorf("open parquet: %w", err)
+			}
+		}
+	}
+	if err := rs.Flush(); err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	return rs, nil
+}
+
+// decodeParquetChunk returns the string values of a column chunk.
+func decodeParquetChunk(col parquetColumn, chunk []byte, numValues int) ([]string, error) {
+	r := bytes.NewReader(chunk)
+	capacity := numValues
+	if capacity > len(chunk) {
+		capacity = len(chunk)
+	}
+	values := make([]string, 0, capacity)
+	for len(values) < numValues && r.Len() > 0 {
+		tr := thriftReader{r: r}
+		ph, err := tr.readStruct()
+		if err != nil {
+			return nil, err
+		}
+		size := ph.int(3)
+		if ph.int(1) != parquetDataPage {
+			return nil, fmt.Errorf("page type %d is not supported", ph.int(1))
+		}
+		dph := ph.child(5)
+		if dph.int(2) != parquetPlain {
+			return nil, fmt.Errorf("encoding %d is not supported", dph.int(2))
+		}
+		if size < 0 || size > int64(r.Len()) {
+			return nil, fmt.Errorf("page of %d bytes is longer than the %d bytes left in the chunk", size, r.Len())
+		}
+		if n := dph.int(1); n < 0 || n > int64(numValues-len(values)) {
+			return nil, fmt.Errorf("page of %d values is more than the %d values left in the chunk", n, numValues-len(values))
+		}
+		page := make([]byte, size)
+		if _, err := io.ReadFull(r, page); err != nil {
+			return nil, err
+		}
+		vals, err := decodeParquetPage(col, page, int(dph.int(1)))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, vals...)
+	}
+	return values, nil
+}
+
+// decodeParquetPage returns the n string values held in a PLAIN data page.
+func decodeParquetPage(col parquetColumn, page []byte, n int) ([]string, error) {
+	r := bytes.NewReader(page)
+	var levels []byte
+	if col.repetition == parquetOptional {
+		var l uint32
+		if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+			return nil, err
+		}
+		if int64(l) > int64(r.Len()) {
+			return nil, fmt.Errorf("definition levels of %d bytes are longer than the %d bytes left in the page", l, r.Len())
+		}
+		defLevels := make([]byte, l)
+		if _, err := io.ReadFull(r, defLevels); err != nil {
+			return nil, err
+		}
+		var err error
+		if levels, err = decodeHybrid(bytes.NewReader(defLevels), n); err != nil {
+			return nil, err
+		}
+	} else {
+		width := 4
+		if col.typ == parquetInt64 || col.typ == parquetDouble {
+			width = 8
+		}
+		if n > r.Len()/width {
+			return nil, fmt.Errorf("page of %d bytes cannot hold %d values", len(page), n)
+		}
+		levels = make([]byte, n)
+		for i := range levels {
+			levels[i] = 1
+		}
+	}
+
+	values := make([]string, n)
+	var b [8]byte
+	for i := range values {
+		if levels[i] == 0 {
+			continue
+		}
+		switch col.typ {
+		case parquetInt32, parquetFloat:
+			if _, err := io.ReadFull(r, b[:4]); err != nil {
+				return nil, err
+			}
+			u := binary.LittleEndian.Uint32(b[:4])
+			if col.typ == parquetFloat {
+				values[i] = formatDouble(float64(math.Float32frombits(u)))
+			} else {
+				values[i] = strconv.FormatInt(int64(int32(u)), 10)
+			}
+		case parquetInt64, parquetDouble:
+			if _, err := io.ReadFull(r, b[:]); err != nil {
+				return nil, err
+			}
+			u := binary.LittleEndian.Uint64(b[:])
+			switch {
+			case col.typ == parquetDouble:
+				values[i] = formatDouble(math.Float64frombits(u))
+			case col.converted == parquetTimestampMillis:
+				values[i] = time.Unix(0, int64(u)*int64(time.Millisecond)).UTC().Format(TimeLayout)
+			default:
+				values[i] = strconv.FormatInt(int64(u), 10)
+			}
+		case parquetByteArray:
+			if _, err := io.ReadFull(r, b[:4]); err != nil {
+				return nil, err
+			}
+			l := binary.LittleEndian.Uint32(b[:4])
+			if int64(l) > int64(r.Len()) {
+				return nil, fmt.Errorf("byte array of %d bytes is longer than the %d bytes left in the page", l, r.Len())
+			}
+			s := make([]byte, l)
+			if _, err := io.ReadFull(r, s); err != nil {
+				return nil, err
+			}
+			values[i] = string(s)
+		default:
+			return nil, fmt.Errorf("physical type %d is not supported", col.typ)
+		}
+	}
+	return values, nil
+}
+
+// formatDouble formats f with the fewest digits that represent it exactly and at
+// least one decimal place, which matches values such as "131.0" in AIS csv files.
+func formatDouble(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsAny(s, ".NI") {
+		s += ".0"
+	}
+	return s
+}
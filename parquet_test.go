@@ -0,0 +1,273 @@
+package ais
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordSet_SaveParquet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ten.parquet")
+
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	if err := rs.SaveParquet(filename); err != nil {
+		t.Fatalf("RecordSet.SaveParquet() error = %v", err)
+	}
+
+	got, err := OpenParquet(filename)
+	if err != nil {
+		t.Fatalf("OpenParquet() error = %v", err)
+	}
+	if !got.Headers().Equals(goodHeaders) {
+		t.Errorf("OpenParquet() headers = %v, want %v", got.Headers(), goodHeaders)
+	}
+
+	want, _ := OpenRecordSet("testdata/ten.csv")
+	defer want.Close()
+	n := 0
+	for {
+		wantRec, err := want.Read()
+		if err == io.EOF {
+			break
+		}
+		gotRec, err := got.Read()
+		if err != nil {
+			t.Fatalf("record %d: Read() error = %v", n, err)
+		}
+		for _, field := range []string{"MMSI", "BaseDateTime", "VesselName", "Status", "Length", "Cargo"} {
+			i, _ := goodHeaders.Contains(field)
+			if (*gotRec)[i] != (*wantRec)[i] {
+				t.Errorf("record %d: %s = %q, want %q", n, field, (*gotRec)[i], (*wantRec)[i])
+			}
+		}
+		for _, field := range []string{"LAT", "LON", "SOG", "COG"} {
+			i, _ := goodHeaders.Contains(field)
+			g, _ := gotRec.ParseFloat(i)
+			w, _ := wantRec.ParseFloat(i)
+			if g != w {
+				t.Errorf("record %d: %s = %v, want %v", n, field, g, w)
+			}
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("OpenParquet() read %d records, want 10", n)
+	}
+}
+
+func TestOpenParquet_NotParquet(t *testing.T) {
+	if _, err := OpenParquet("testdata/ten.csv"); err == nil {
+		t.Errorf("OpenParquet() expected error for a csv file")
+	}
+	if _, err := OpenParquet("doesNotExist.parquet"); err == nil {
+		t.Errorf("OpenParquet() expected error for missing file")
+	}
+}
+
+func TestOpenParquet_CorruptFooter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "corrupt.parquet")
+
+	// A footer length of 4 GiB in a file of 16 bytes.
+	data := []byte("PAR1\x00\x00\x00\x00\xff\xff\xff\xffPAR1")
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenParquet(filename); err == nil || !strings.Contains(err.Error(), "footer") {
+		t.Errorf("OpenParquet() error = %v, want an error for the footer length", err)
+	}
+}
+
+// parquetLayout describes a one column Parquet file for the corrupt file tests.
+type parquetLayout struct {
+	typ, repetition int32
+	rows, values    int64
+	size, offset    int64 // of the chunk, -1 for the page bytes after the magic
+	notStruct       bool  // write the column chunk as an i32
+}
+
+// parquetPage returns a data page header of n values and size bytes followed by
+// body.
+func parquetPage(n, size int32, body []byte) []byte {
+	var t thriftWriter
+	t.i32(1, parquetDataPage)
+	t.i32(2, size)
+	t.i32(3, size)
+	t.beginStruct(5)
+	t.i32(1, n)
+	t.i32(2, parquetPlain)
+	t.endStruct()
+	t.buf.WriteByte(0)
+	return append(t.buf.Bytes(), body...)
+}
+
+// writeParquet writes the chunk bytes and a footer for l to filename.  A non-nil
+// meta replaces the footer.
+func writeParquet(t *testing.T, filename string, l parquetLayout, chunk, meta []byte) {
+	t.Helper()
+	if meta == nil {
+		if l.size == -1 {
+			l.size = int64(len(chunk))
+		}
+		if l.offset == -1 {
+			l.offset = int64(len(parquetMagic))
+		}
+		var w thriftWriter
+		w.i32(1, 1)
+		w.list(2, thriftStruct, 2)
+		w.beginStruct(0)
+		w.str(4, "schema")
+		w.i32(5, 1)
+		w.endStruct()
+		w.beginStruct(0)
+		w.i32(1, l.typ)
+		w.i32(3, l.repetition)
+		w.str(4, "MMSI")
+		w.endStruct()
+		w.i64(3, l.rows)
+		w.list(4, thriftStruct, 1)
+		w.beginStruct(0)
+		if l.notStruct {
+			w.list(1, thriftI32, 1)
+			w.elemI32(7)
+		} else {
+			w.list(1, thriftStruct, 1)
+			w.beginStruct(0)
+			w.beginStruct(3)
+			w.i32(1, l.typ)
+			w.i32(4, 0)
+			w.i64(5, l.values)
+			w.i64(7, l.size)
+			w.i64(9, l.offset)
+			w.endStruct()
+			w.endStruct()
+		}
+		w.i64(3, l.rows)
+		w.endStruct()
+		w.buf.WriteByte(0)
+		meta = w.buf.Bytes()
+	}
+	var b bytes.Buffer
+	b.WriteString(parquetMagic)
+	b.Write(chunk)
+	b.Write(meta)
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], uint32(len(meta)))
+	b.Write(footer[:])
+	b.WriteString(parquetMagic)
+	if err := ioutil.WriteFile(filename, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenParquet_Corrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "corrupt.parquet")
+
+	required := parquetLayout{typ: parquetByteArray, rows: 1, values: 1, size: -1, offset: -1}
+	optional := required
+	optional.repetition = parquetOptional
+	huge := []byte{0x00, 0x00, 0x00, 0xc0} // a length of about 3.2 GB
+	value := []byte{0x03, 0x00, 0x00, 0x00, '1', '2', '3'}
+
+	tests := []struct {
+		name   string
+		layout parquetLayout
+		chunk  []byte
+		meta   []byte
+		want   string
+	}{
+		{"byte array longer than page", required, parquetPage(1, 8, append(huge, 'x', 'y', 'z', 'w')), nil, "byte array"},
+		{"definition levels longer than page", optional, parquetPage(1, 8, append(huge, 0x02, 0x01, 0x00, 0x00)), nil, "definition levels"},
+		{"page longer than chunk", required, parquetPage(1, 1<<30, value), nil, "page of 1073741824 bytes"},
+		{"negative page size", required, parquetPage(1, -7, value), nil, "page of -7 bytes"},
+		{"negative page values", required, parquetPage(-1, 7, value), nil, "page of -1 values"},
+		{"page values beyond chunk", required, parquetPage(5, 7, value), nil, "page of 5 values"},
+		{"required values beyond page", parquetLayout{typ: parquetByteArray, rows: 1 << 30, values: 1 << 30, size: -1, offset: -1}, parquetPage(1<<30, 7, value), nil, "cannot hold"},
+		{"negative chunk size", parquetLayout{typ: parquetByteArray, rows: 1, values: 1, size: -5, offset: -1}, parquetPage(1, 7, value), nil, "outside the file"},
+		{"chunk past end of file", parquetLayout{typ: parquetByteArray, rows: 1, values: 1, size: 1 << 40, offset: -1}, parquetPage(1, 7, value), nil, "outside the file"},
+		{"negative chunk offset", parquetLayout{typ: parquetByteArray, rows: 1, values: 1, size: -1, offset: -4}, parquetPage(1, 7, value), nil, "outside the file"},
+		{"negative chunk values", parquetLayout{typ: parquetByteArray, rows: 1, values: -1, size: -1, offset: -1}, parquetPage(1, 7, value), nil, "-1 values"},
+		{"chunk metadata not a struct", parquetLayout{typ: parquetByteArray, rows: 1, notStruct: true}, nil, nil, "malformed chunk"},
+		{"thrift binary longer than footer", required, nil, []byte{0x48, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x00}, "binary of"},
+		{"thrift list longer than footer", required, nil, []byte{0x29, 0xfc, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x00}, "list of"},
+		{"thrift nested too deep", required, nil, bytes.Repeat([]byte{0x1c}, 2*thriftMaxDepth), "nested deeper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeParquet(t, filename, tt.layout, tt.chunk, tt.meta)
+			if _, err := OpenParquet(filename); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("OpenParquet() error = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// A row group that claims more rows than its columns hold reads only the
+	// rows that were decoded.
+	l := required
+	l.rows = 1 << 30
+	writeParquet(t, filename, l, parquetPage(1, 7, value), nil)
+	rs, err := OpenParquet(filename)
+	if err != nil {
+		t.Fatalf("OpenParquet() error = %v", err)
+	}
+	defer rs.Close()
+	n := 0
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (*rec)[0] != "123" {
+			t.Errorf("OpenParquet() record = %v, want [123]", *rec)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("OpenParquet() read %d records, want 1", n)
+	}
+}
+
+func TestDecodeHybrid(t *testing.T) {
+	levels := []byte{1, 1, 1, 0, 0, 1, 0, 1, 1, 1}
+	got, err := decodeHybrid(bytes.NewReader(encodeRLE(levels)), len(levels))
+	if err != nil {
+		t.Fatalf("decodeHybrid() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, levels) {
+		t.Errorf("decodeHybrid(encodeRLE()) = %v, want %v", got, levels)
+	}
+
+	// One bit-packed group of eight values, 0b10110001, as written by other
+	// Parquet implementations.
+	got, err = decodeHybrid(bytes.NewReader([]byte{0x03, 0xb1}), 8)
+	if err != nil {
+		t.Fatalf("decodeHybrid() error = %v", err)
+	}
+	if want := []byte{1, 0, 0, 0, 1, 1, 0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeHybrid() bit-packed = %v, want %v", got, want)
+	}
+}
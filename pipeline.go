@@ -0,0 +1,636 @@
+package ais
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultPipelineBuffer is the capacity of the channels between the stages of
+// a Pipeline.
+const DefaultPipelineBuffer = 256
+
+// RecordFunc is a record stage of a Pipeline added by Map.  It returns the
+// Record to pass to the next stage, which may be rec itself after changing it in
+// place, or nil to drop rec.  A non-nil error stops the Pipeline.
+type RecordFunc func(rec *Record) (*Record, error)
+
+// ClusterFunc is the cluster stage of a Pipeline.  It returns the Clusters of
+// the Records in win, such as the Clusters of win.FindClustersNeighbors for the
+// EightNeighbors strategy.  The Window is a copy that the Pipeline does not
+// change, so it may be kept.
+type ClusterFunc func(win *Window) ([]*Cluster, error)
+
+// Pipeline is a streaming flow of composable stages, from the Records parsed
+// from a RecordSet through record stages such as Clean and Map, a Window, a
+// Cluster stage and an Interact stage to an optional Sink of the interactions.
+// Stages run concurrently and are
+// connected by channels that hold at most Buffer items, so a slow stage applies
+// backpressure to the stages before it rather than letting them fill memory,
+// and each stage has its own number of workers.  Build a Pipeline with
+// NewPipeline and the methods that add stages, which return the Pipeline so
+// they can be chained, then call Run:
+//
+//	p := ais.NewPipeline(rs).
+//		Clean(ais.DefaultCleanRules()).
+//		Geohash(ais.DefaultInteractionPrecision, 4).
+//		Window(10*time.Minute, 5*time.Minute).
+//		Cluster(4, nil).
+//		Interact(4, ais.WithMaxDistance(0.5))
+//	inter, err := p.Run(ctx)
+//
+// Without a Sink the interactions are collected in the set returned by Run.
+// With one they are passed to the Sink as each Window is done and only the
+// pairs that a later Window may find again are held.
+//
+// Record stages keep the order of the Records whatever their number of
+// workers, so the Records must be sorted by time as for SlideWindow.  Errors
+// made while building the Pipeline, such as a record stage after the Window, are
+// returned by Run.  A Pipeline runs once.
+type Pipeline struct {
+	rs     *RecordSet
+	h      Headers // Headers of the Records leaving the record stages
+	buffer int
+	err    error // first error made while building the Pipeline
+	ran    bool
+
+	records []pipelineStage
+	clean   *CleanReport
+
+	width, step    time.Duration // zero without a Window
+	cluster        ClusterFunc
+	clusterWorkers int
+	hasCluster     bool
+	inter          *Interactions
+	interOpts      []InteractionOption
+	interWorkers   int
+	sink           InteractionFunc
+}
+
+// InteractionFunc is the sink stage of a Pipeline added by Sink.  It is called
+// once with each new interaction, identified by its hash as in the
+// InteractionHash output field.  A non-nil error stops the Pipeline.
+type InteractionFunc func(hash Hash128, pair *RecordPair) error
+
+// pipelineStage is a record stage of a Pipeline.
+type pipelineStage struct {
+	name    string
+	workers int
+	fn      RecordFunc
+}
+
+// NewPipeline returns a Pipeline whose parse stage reads the Records of rs.
+// The Pipeline consumes rs when it runs.
+func NewPipeline(rs *RecordSet) *Pipeline {
+	return &Pipeline{rs: rs, h: rs.Headers(), buffer: DefaultPipelineBuffer}
+}
+
+// Headers returns the Headers of the Records leaving the record stages added so
+// far, including the fields added by AppendField and Geohash, for use by the
+// RecordFunc of a later stage.
+func (p *Pipeline) Headers() Headers { return p.h }
+
+// Buffer sets the capacity of the channels between stages in place of
+// DefaultPipelineBuffer.  A larger buffer smooths out stages of uneven speed at
+// the cost of memory.
+func (p *Pipeline) Buffer(n int) *Pipeline {
+	if n < 1 {
+		p.fail(fmt.Errorf("buffer must be at least 1, got %d", n))
+		return p
+	}
+	p.buffer = n
+	return p
+}
+
+// fail records the first error made while building the Pipeline.
+func (p *Pipeline) fail(err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("pipeline: %w", err)
+	}
+}
+
+// Map adds a record stage named name, used in its errors, that calls fn with
+// each Record on workers goroutines.  For workers less than one the value of
+// runtime.GOMAXPROCS(0) is used.  With more than one worker fn must be safe
+// for concurrent use.
+func (p *Pipeline) Map(name string, workers int, fn RecordFunc) *Pipeline {
+	if p.width > 0 {
+		p.fail(fmt.Errorf("%s: record stages must come before the Window", name))
+		return p
+	}
+	if fn == nil {
+		p.fail(fmt.Errorf("%s: nil RecordFunc", name))
+		return p
+	}
+	p.records = append(p.records, pipelineStage{name: name, workers: workers, fn: fn})
+	return p
+}
+
+// AppendField adds a record stage that appends the Field made by gen from the
+// requiredHeaders, as RecordSet.AppendField does, under newField.
+func (p *Pipeline) AppendField(newField string, requiredHeaders []string, gen Generator, workers int) *Pipeline {
+	var indices []int
+	for _, target := range requiredHeaders {
+		i, ok := p.h.Contains(target)
+		if !ok {
+			p.fail(fmt.Errorf("append %s: %w", newField, ErrMissingHeader{Field: target}))
+			return p
+		}
+		indices = append(indices, i)
+	}
+	p.Map("append "+newField, workers, func(rec *Record) (*Record, error) {
+		f, err := gen.Generate(*rec, indices...)
+		if err != nil {
+			return nil, err
+		}
+		*rec = append(*rec, string(f))
+		return rec, nil
+	})
+	if p.err == nil {
+		p.h.Fields = append(append([]string{}, p.h.Fields...), newField)
+	}
+	return p
+}
+
+// Geohash adds a record stage that appends a Geohash field of bits bits, the
+// field the default cluster stage groups Records by.
+func (p *Pipeline) Geohash(bits uint, workers int) *Pipeline {
+	if bits == 0 || bits > 64 {
+		p.fail(fmt.Errorf("geohash: bits must be from 1 to 64, got %d", bits))
+		return p
+	}
+	return p.AppendField("Geohash", []string{"LAT", "LON"}, geohashGenerator{bits}, workers)
+}
+
+// Clean adds a record stage that applies rules as RecordSet.CleanKinematics
+// does, dropping or flagging the Records that fail.  The implied speed check
+// compares each Record with the last one of its vessel, so the stage has one
+// worker.  CleanReport returns what it removed once Run returns.
+func (p *Pipeline) Clean(rules CleanRules) *Pipeline {
+	kc, err := newKinematicCleaner(p.h, rules)
+	if err != nil {
+		p.fail(fmt.Errorf("clean: %w", err))
+		return p
+	}
+	p.Map("clean", 1, func(rec *Record) (*Record, error) {
+		keep, err := kc.clean(rec)
+		if err != nil || !keep {
+			return nil, err
+		}
+		return rec, nil
+	})
+	if p.err == nil {
+		p.clean = kc.report
+		if rules.Flag {
+			p.h.Fields = append(append([]string{}, p.h.Fields...), KinematicFields)
+		}
+	}
+	return p
+}
+
+// CleanReport returns the report of the Clean stage, nil without one.  It is
+// complete once Run returns.
+func (p *Pipeline) CleanReport() *CleanReport { return p.clean }
+
+// Tap adds a record stage on one goroutine that calls fn with each Record that
+// leaves the record stages, in order, to write or count them.  The Records are
+// passed on to the Window, if there is one.
+func (p *Pipeline) Tap(fn func(rec *Record) error) *Pipeline {
+	if fn == nil {
+		p.fail(fmt.Errorf("tap: nil func"))
+		return p
+	}
+	return p.Map("tap", 1, func(rec *Record) (*Record, error) {
+		return rec, fn(rec)
+	})
+}
+
+// Window adds the window stage, which slides a Window of the given width by
+// step through the Records as SlideWindow does and passes a copy of each
+// Window to the cluster stage.
+func (p *Pipeline) Window(width, step time.Duration) *Pipeline {
+	if width <= 0 || step <= 0 {
+		p.fail(fmt.Errorf("window: width and step must be positive, got %v and %v", width, step))
+		return p
+	}
+	if p.width > 0 {
+		p.fail(fmt.Errorf("window: the Pipeline already has a Window"))
+		return p
+	}
+	p.width, p.step = width, step
+	return p
+}
+
+// Cluster adds the cluster stage, which calls fn with each Window on workers
+// goroutines, as for Map, and passes the Clusters of each Window to the
+// interact stage in the order of the Windows.  A nil fn groups the Records by
+// the Geohash field with Window.FindClusters and keeps the Clusters of more
+// than one Record, as FindInteractions does with the SingleCell strategy.
+func (p *Pipeline) Cluster(workers int, fn ClusterFunc) *Pipeline {
+	if p.width == 0 {
+		p.fail(fmt.Errorf("cluster: the Pipeline has no Window"))
+		return p
+	}
+	if fn == nil {
+		geoIndex, ok := p.h.Contains("Geohash")
+		if !ok {
+			p.fail(fmt.Errorf("cluster: %w", ErrMissingHeader{Field: "Geohash"}))
+			return p
+		}
+		fn = func(win *Window) ([]*Cluster, error) {
+			var clusters []*Cluster
+			for _, c := range win.FindClusters(geoIndex).Clusters() {
+				if c.Size() > 1 {
+					clusters = append(clusters, c)
+				}
+			}
+			return clusters, nil
+		}
+	}
+	p.cluster, p.clusterWorkers, p.hasCluster = fn, workers, true
+	return p
+}
+
+// Interact adds the interact stage, which adds the Clusters to a new set of
+// Interactions created with opts on workers goroutines.  The set is returned by
+// Run.
+func (p *Pipeline) Interact(workers int, opts ...InteractionOption) *Pipeline {
+	if !p.hasCluster {
+		p.fail(fmt.Errorf("interact: the Pipeline has no cluster stage"))
+		return p
+	}
+	inter, err := NewInteractionsWithOptions(p.h, opts...)
+	if err != nil {
+		p.fail(fmt.Errorf("interact: %w", err))
+		return p
+	}
+	p.inter, p.interOpts, p.interWorkers = inter, opts, workers
+	return p
+}
+
+// Sink adds the sink stage, which calls fn on one goroutine with each
+// interaction found by the interact stage, in the order of the Windows, instead
+// of collecting them in the set returned by Run.  A pair of Records found again
+// by a later, overlapping Window is passed once.  The options WithTimeBucket,
+// WithClosestApproach, and WithMemoryLimit of the interact stage choose among
+// or spill every pair of the set and cannot be combined with a Sink.
+func (p *Pipeline) Sink(fn InteractionFunc) *Pipeline {
+	if p.inter == nil {
+		p.fail(fmt.Errorf("sink: the Pipeline has no interact stage"))
+		return p
+	}
+	if fn == nil {
+		p.fail(fmt.Errorf("sink: nil InteractionFunc"))
+		return p
+	}
+	if p.inter.timeBucket > 0 || p.inter.closest || p.inter.spill != nil {
+		p.fail(fmt.Errorf("sink: the interact stage needs the whole set of interactions"))
+		return p
+	}
+	p.sink = fn
+	return p
+}
+
+// windowClusters are the Clusters found in the Window whose left marker is
+// left.
+type windowClusters struct {
+	left     time.Time
+	clusters []*Cluster
+}
+
+// windowPairs are the interactions found in the Window whose left marker is
+// left, and the earlier BaseDateTime of the Records of each pair.
+type windowPairs struct {
+	left   time.Time
+	hashes []Hash128
+	pairs  []*RecordPair
+	starts []time.Time
+}
+
+// Run runs every stage of the Pipeline until the Records of the RecordSet are
+// exhausted, ctx is canceled, or a stage returns an error, which stops the
+// other stages and is returned.  It returns the Interactions of the interact
+// stage, or nil without one or with a Sink.
+func (p *Pipeline) Run(ctx context.Context) (*Interactions, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.ran {
+		return nil, fmt.Errorf("pipeline: already run")
+	}
+	p.ran = true
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	var wg sync.WaitGroup
+
+	// parse
+	ch := make(chan interface{}, p.buffer)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(ch)
+		for {
+			rec, err := p.rs.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				fail(fmt.Errorf("pipeline: parse: %w", err))
+				return
+			}
+			select {
+			case ch <- rec:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var out <-chan interface{} = ch
+
+	for _, st := range p.records {
+		st := st
+		out = p.ordered(ctx, &wg, out, st.workers, fail, func(v interface{}) (interface{}, error) {
+			rec, err := st.fn(v.(*Record))
+			if err != nil {
+				return nil, fmt.Errorf("pipeline: %s: %w", st.name, err)
+			}
+			if rec == nil {
+				return nil, nil
+			}
+			return rec, nil
+		})
+	}
+
+	if p.width > 0 {
+		out = p.window(ctx, &wg, out, fail)
+	}
+	if p.hasCluster {
+		out = p.ordered(ctx, &wg, out, p.clusterWorkers, fail, func(v interface{}) (interface{}, error) {
+			win := v.(*Window)
+			clusters, err := p.cluster(win)
+			if err != nil {
+				return nil, fmt.Errorf("pipeline: cluster: %w", err)
+			}
+			if len(clusters) == 0 {
+				return nil, nil
+			}
+			return windowClusters{win.Left(), clusters}, nil
+		})
+	}
+
+	switch {
+	case p.sink != nil:
+		out = p.ordered(ctx, &wg, out, p.interWorkers, fail, func(v interface{}) (interface{}, error) {
+			wp, err := p.interactWindow(ctx, v.(windowClusters))
+			if err != nil {
+				return nil, fmt.Errorf("pipeline: interact: %w", err)
+			}
+			return wp, nil
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.drain(out); err != nil {
+				fail(fmt.Errorf("pipeline: sink: %w", err))
+			}
+		}()
+	case p.inter != nil:
+		for i := 0; i < numWorkers(p.interWorkers); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for v := range out {
+					for _, c := range v.(windowClusters).clusters {
+						if err := p.inter.addCluster(ctx, c, nil); err != nil {
+							fail(fmt.Errorf("pipeline: interact: %w", err))
+						}
+					}
+				}
+			}()
+		}
+	default:
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range out {
+			}
+		}()
+	}
+
+	wg.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		// ctx was canceled by the caller, since fail was never called.
+		firstErr = fmt.Errorf("pipeline: %w", ctx.Err())
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if p.sink != nil {
+		return nil, nil
+	}
+	return p.inter, nil
+}
+
+// interactWindow adds the Clusters of one Window to a set of Interactions of
+// its own and returns its pairs.
+func (p *Pipeline) interactWindow(ctx context.Context, wc windowClusters) (windowPairs, error) {
+	wp := windowPairs{left: wc.left}
+	set, err := NewInteractionsWithOptions(p.h, p.interOpts...)
+	if err != nil {
+		return wp, err
+	}
+	for _, c := range wc.clusters {
+		if err := set.addCluster(ctx, c, nil); err != nil {
+			return wp, err
+		}
+	}
+	timeIndex := set.hashIndices[1]
+	err = set.each(func(hash Hash128, pair *RecordPair) error {
+		t1, err := p.h.parseTime((*pair.rec1)[timeIndex])
+		if err != nil {
+			return ErrParse{Field: "BaseDateTime", Err: err}
+		}
+		t2, err := p.h.parseTime((*pair.rec2)[timeIndex])
+		if err != nil {
+			return ErrParse{Field: "BaseDateTime", Err: err}
+		}
+		if t2.Before(t1) {
+			t1 = t2
+		}
+		wp.hashes = append(wp.hashes, hash)
+		wp.pairs = append(wp.pairs, pair)
+		wp.starts = append(wp.starts, t1)
+		return nil
+	})
+	return wp, err
+}
+
+// drain passes the new pairs of each Window of in to the sink.  A pair is
+// remembered until the Windows pass the earlier of its Records, after which no
+// Window can find it again.
+func (p *Pipeline) drain(in <-chan interface{}) error {
+	seen := make(map[Hash128]time.Time)
+	for v := range in {
+		wp := v.(windowPairs)
+		for hash, start := range seen {
+			if start.Before(wp.left) {
+				delete(seen, hash)
+			}
+		}
+		for i, hash := range wp.hashes {
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+			seen[hash] = wp.starts[i]
+			if err := p.sink(hash, wp.pairs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// numWorkers returns n, or runtime.GOMAXPROCS(0) for n less than one.
+func numWorkers(n int) int {
+	if n < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return n
+}
+
+// ordered runs fn on n workers over the items of in and returns a channel of
+// the non-nil results in the order of in.  Each item reserves a slot in a queue
+// of pending results before it is handed to a worker, so at most the buffer plus
+// the workers are in flight and a slow consumer stalls the stage.  The first
+// error is passed to fail and the stage stops when ctx is done.  Every
+// goroutine is counted in wg.
+func (p *Pipeline) ordered(ctx context.Context, wg *sync.WaitGroup, in <-chan interface{}, n int, fail func(error),
+	fn func(interface{}) (interface{}, error)) <-chan interface{} {
+	type job struct {
+		v   interface{}
+		res chan interface{}
+	}
+	n = numWorkers(n)
+	out := make(chan interface{}, p.buffer)
+	jobs := make(chan job)
+	pending := make(chan chan interface{}, p.buffer+n)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(pending)
+		for v := range in {
+			res := make(chan interface{}, 1)
+			select {
+			case pending <- res:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{v, res}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r, err := fn(j.v)
+				if err != nil {
+					fail(err)
+				}
+				j.res <- r
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(out)
+		for res := range pending {
+			var v interface{}
+			select {
+			case v = <-res:
+			case <-ctx.Done():
+				return
+			}
+			if v == nil {
+				continue
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// window runs the window stage over the Records of in and returns a channel of
+// copies of each full Window.
+func (p *Pipeline) window(ctx context.Context, wg *sync.WaitGroup, in <-chan interface{}, fail func(error)) <-chan interface{} {
+	out := make(chan interface{}, p.buffer)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(out)
+		v, ok := <-in
+		if !ok {
+			return
+		}
+		first := v.(*Record)
+		win, err := newWindow(p.h, first, p.width)
+		if err != nil {
+			fail(fmt.Errorf("pipeline: window: %w", err))
+			return
+		}
+		win.metrics = p.rs.meter
+		next := func() (*Record, error) {
+			if first != nil {
+				rec := first
+				first = nil
+				return rec, nil
+			}
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return nil, io.EOF
+				}
+				return v.(*Record), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		err = win.slide(p.h, p.step, next, func(win *Window) error {
+			select {
+			case out <- win.snapshot():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			fail(fmt.Errorf("pipeline: window: %w", err))
+		}
+	}()
+	return out
+}
@@ -0,0 +1,228 @@
+package ais
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pipelineData returns a csv file of n Records of one vessel a second apart.
+func pipelineData(n int) string {
+	var b strings.Builder
+	b.WriteString("MMSI,BaseDateTime,LAT,LON,SOG\n")
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "366000001,%s,36.%04d,-76.0,10.0\n", start.Add(time.Duration(i)*time.Second).Format(TimeLayout), i)
+	}
+	return b.String()
+}
+
+func TestPipeline_Interactions(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(detectData), Headers{})
+	sorted, err := rs.SortByTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen int
+	p := NewPipeline(sorted).
+		Clean(DefaultCleanRules()).
+		Geohash(DefaultInteractionPrecision, 4).
+		Tap(func(rec *Record) error { seen++; return nil }).
+		Window(2*time.Minute, time.Minute).
+		Cluster(2, nil).
+		Interact(2)
+	inter, err := p.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// The same four pairs as TestRecordSet_FindInteractions.
+	if inter.Len() != 4 {
+		t.Errorf("Run() Len() = %d, want 4", inter.Len())
+	}
+	if seen != 5 || p.CleanReport().Records != 5 {
+		t.Errorf("tap saw %d records, clean report %v", seen, p.CleanReport())
+	}
+	if _, ok := p.Headers().Contains("Geohash"); !ok {
+		t.Errorf("Headers() = %v, want Geohash", p.Headers())
+	}
+	if _, err := p.Run(context.Background()); err == nil {
+		t.Error("second Run() returned no error")
+	}
+}
+
+func TestPipeline_Sink(t *testing.T) {
+	run := func(sink InteractionFunc) *Interactions {
+		t.Helper()
+		rs, _ := NewRecordSetFromReader(strings.NewReader(detectData), Headers{})
+		sorted, err := rs.SortByTime()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := NewPipeline(sorted).
+			Geohash(DefaultInteractionPrecision, 2).
+			Window(2*time.Minute, time.Minute).
+			Cluster(2, nil).
+			Interact(2)
+		if sink != nil {
+			p.Sink(sink)
+		}
+		inter, err := p.Run(context.Background())
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		return inter
+	}
+
+	want := make(map[Hash128]bool)
+	run(nil).each(func(hash Hash128, pair *RecordPair) error {
+		want[hash] = true
+		return nil
+	})
+	got := make(map[Hash128]int)
+	if inter := run(func(hash Hash128, pair *RecordPair) error {
+		got[hash]++
+		return nil
+	}); inter != nil {
+		t.Errorf("Run() with a Sink = %v, want nil Interactions", inter)
+	}
+	if len(got) != len(want) {
+		t.Errorf("sink saw %d interactions, want %d", len(got), len(want))
+	}
+	for hash, n := range got {
+		if !want[hash] || n != 1 {
+			t.Errorf("sink saw %s %d times, want once and in the collected set", hash, n)
+		}
+	}
+
+	errStop := errors.New("stop")
+	rs, _ := NewRecordSetFromReader(strings.NewReader(detectData), Headers{})
+	sorted, _ := rs.SortByTime()
+	_, err := NewPipeline(sorted).Geohash(DefaultInteractionPrecision, 1).
+		Window(2*time.Minute, time.Minute).Cluster(1, nil).Interact(1).
+		Sink(func(Hash128, *RecordPair) error { return errStop }).
+		Run(context.Background())
+	if !errors.Is(err, errStop) {
+		t.Errorf("Run() error = %v, want the error of the sink", err)
+	}
+}
+
+func TestPipeline_Order(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(pipelineData(500)), Headers{})
+	var got []string
+	p := NewPipeline(rs).Buffer(8).
+		Map("jitter", 8, func(rec *Record) (*Record, error) {
+			time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+			if strings.HasSuffix((*rec)[2], "7") {
+				return nil, nil // drop
+			}
+			return rec, nil
+		}).
+		Tap(func(rec *Record) error {
+			got = append(got, (*rec)[2])
+			return nil
+		})
+	if inter, err := p.Run(context.Background()); err != nil || inter != nil {
+		t.Fatalf("Run() = %v, %v", inter, err)
+	}
+	if len(got) != 450 {
+		t.Fatalf("tap saw %d records, want 450", len(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("record %s follows %s, want the input order", got[i], got[i-1])
+		}
+	}
+}
+
+func TestPipeline_Backpressure(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(pipelineData(1000)), Headers{})
+	var mapped int64
+	release := make(chan struct{})
+	p := NewPipeline(rs).Buffer(1).
+		Map("count", 1, func(rec *Record) (*Record, error) {
+			atomic.AddInt64(&mapped, 1)
+			return rec, nil
+		}).
+		Tap(func(rec *Record) error {
+			<-release
+			return nil
+		})
+	done := make(chan error)
+	go func() {
+		_, err := p.Run(context.Background())
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt64(&mapped); n > 20 {
+		t.Errorf("%d records mapped while the tap was blocked, want the buffers to stop the stage", n)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if mapped != 1000 {
+		t.Errorf("mapped %d records, want 1000", mapped)
+	}
+}
+
+func TestPipeline_Errors(t *testing.T) {
+	errStop := errors.New("stop")
+	rs, _ := NewRecordSetFromReader(strings.NewReader(pipelineData(1000)), Headers{})
+	var n int64
+	_, err := NewPipeline(rs).Map("fail", 4, func(rec *Record) (*Record, error) {
+		if atomic.AddInt64(&n, 1) == 10 {
+			return nil, errStop
+		}
+		return rec, nil
+	}).Window(time.Minute, time.Minute).Run(context.Background())
+	if !errors.Is(err, errStop) || !strings.Contains(err.Error(), "fail") {
+		t.Errorf("Run() error = %v, want the error of the fail stage", err)
+	}
+
+	build := []struct {
+		name string
+		p    func(rs *RecordSet) *Pipeline
+	}{
+		{"map after window", func(rs *RecordSet) *Pipeline {
+			return NewPipeline(rs).Window(time.Minute, time.Minute).Map("late", 1, func(rec *Record) (*Record, error) { return rec, nil })
+		}},
+		{"cluster without window", func(rs *RecordSet) *Pipeline { return NewPipeline(rs).Cluster(1, nil) }},
+		{"cluster without geohash", func(rs *RecordSet) *Pipeline {
+			return NewPipeline(rs).Window(time.Minute, time.Minute).Cluster(1, nil)
+		}},
+		{"interact without cluster", func(rs *RecordSet) *Pipeline { return NewPipeline(rs).Interact(1) }},
+		{"sink without interact", func(rs *RecordSet) *Pipeline {
+			return NewPipeline(rs).Sink(func(Hash128, *RecordPair) error { return nil })
+		}},
+		{"sink with closest approach", func(rs *RecordSet) *Pipeline {
+			return NewPipeline(rs).Geohash(22, 1).Window(time.Minute, time.Minute).Cluster(1, nil).
+				Interact(1, WithClosestApproach()).Sink(func(Hash128, *RecordPair) error { return nil })
+		}},
+		{"bad window", func(rs *RecordSet) *Pipeline { return NewPipeline(rs).Window(0, time.Minute) }},
+		{"bad buffer", func(rs *RecordSet) *Pipeline { return NewPipeline(rs).Buffer(0) }},
+		{"missing header", func(rs *RecordSet) *Pipeline {
+			return NewPipeline(rs).AppendField("X", []string{"Nope"}, geohashGenerator{22}, 1)
+		}},
+	}
+	for _, tt := range build {
+		rs, _ := NewRecordSetFromReader(strings.NewReader(pipelineData(10)), Headers{})
+		if _, err := tt.p(rs).Run(context.Background()); err == nil {
+			t.Errorf("%s: Run() returned no error", tt.name)
+		}
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(pipelineData(1000)), Headers{})
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = NewPipeline(rs).Tap(func(rec *Record) error {
+		cancel()
+		return nil
+	}).Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() with a canceled context error = %v, want context.Canceled", err)
+	}
+}
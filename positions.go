@@ -0,0 +1,101 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+)
+
+// Positions holds the parsed LAT and LON of a slice of Records as two parallel
+// columns, so that the distances between many pairs of the Records are computed
+// without parsing the same fields again for every pair.  Lat[i] and Lon[i] are
+// the position of the i-th Record, or NaN when either field cannot be parsed.
+type Positions struct {
+	Lat []float64
+	Lon []float64
+}
+
+// NewPositions parses the positions of recs, which are described by h.  The
+// Headers must contain LAT and LON.  A Record whose position cannot be parsed
+// is not an error; its position is NaN and so are its distances.
+func NewPositions(recs []*Record, h Headers) (*Positions, error) {
+	idx, err := h.require("LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("new positions: %w", err)
+	}
+	return parsePositions(recs, idx["LAT"].Idx, idx["LON"].Idx), nil
+}
+
+// parsePositions returns the Positions of recs with LAT and LON at latIndex and
+// lonIndex.
+func parsePositions(recs []*Record, latIndex, lonIndex int) *Positions {
+	p := &Positions{Lat: make([]float64, len(recs)), Lon: make([]float64, len(recs))}
+	for i, rec := range recs {
+		p.Lat[i], p.Lon[i] = math.NaN(), math.NaN()
+		if latIndex >= len(*rec) || lonIndex >= len(*rec) {
+			continue
+		}
+		lat, okLat := parseCoord((*rec)[latIndex])
+		lon, okLon := parseCoord((*rec)[lonIndex])
+		if okLat && okLon {
+			p.Lat[i], p.Lon[i] = lat, lon
+		}
+	}
+	return p
+}
+
+// Len returns the number of positions.
+func (p *Positions) Len() int { return len(p.Lat) }
+
+// Distances appends to dst the distance in nautical miles between the two
+// positions of each pair, given as indices into p, and returns the extended
+// slice.  A nil fn computes the Haversine distance with the cosine of each
+// latitude computed once rather than once per pair, which is the fast path
+// when each position takes part in many pairs.
+func (p *Positions) Distances(fn DistanceFunc, pairs [][2]int, dst []float64) []float64 {
+	if fn != nil {
+		for _, pr := range pairs {
+			i, j := pr[0], pr[1]
+			dst = append(dst, fn(p.Lat[i], p.Lon[i], p.Lat[j], p.Lon[j]))
+		}
+		return dst
+	}
+
+	const rad = math.Pi / 180
+	cosLat := make([]float64, len(p.Lat))
+	for i, lat := range p.Lat {
+		cosLat[i] = math.Cos(lat * rad)
+	}
+	for _, pr := range pairs {
+		i, j := pr[0], pr[1]
+		dst = append(dst, haversineCos(p.Lat[i], p.Lon[i], cosLat[i], p.Lat[j], p.Lon[j], cosLat[j]))
+	}
+	return dst
+}
+
+// HaversineBatch appends to dst the Haversine distance in nautical miles
+// between the positions lat1[i], lon1[i] and lat2[i], lon2[i] for every i and
+// returns the extended slice.  The four slices must have the same length.  The
+// positions are read as columns in a single loop without calls through a
+// DistanceFunc, a layout the compiler can keep in registers and that is ready
+// for vectorization.
+func HaversineBatch(lat1, lon1, lat2, lon2, dst []float64) []float64 {
+	n := len(lat1)
+	if len(lon1) != n || len(lat2) != n || len(lon2) != n {
+		panic("ais: HaversineBatch slices have different lengths")
+	}
+	const rad = math.Pi / 180
+	lon1, lat2, lon2 = lon1[:n], lat2[:n], lon2[:n] // hoist the bounds checks
+	for i := 0; i < n; i++ {
+		dst = append(dst, haversineCos(lat1[i], lon1[i], math.Cos(lat1[i]*rad), lat2[i], lon2[i], math.Cos(lat2[i]*rad)))
+	}
+	return dst
+}
+
+// haversineCos is Haversine with the cosine of each latitude already known.
+func haversineCos(lat1, lon1, cos1, lat2, lon2, cos2 float64) float64 {
+	const rad = math.Pi / 180
+	sinDLat := math.Sin((lat2 - lat1) * rad / 2)
+	sinDLon := math.Sin((lon2 - lon1) * rad / 2)
+	a := sinDLat*sinDLat + cos1*cos2*sinDLon*sinDLon
+	return 2 * earthRadiusNM * math.Asin(math.Sqrt(math.Min(1, a)))
+}
@@ -0,0 +1,107 @@
+package ais
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPositions_Distances(t *testing.T) {
+	recs := []*Record{
+		{"1", "2017-12-01T00:00:00", "36.90000", "-76.10000"},
+		{"2", "2017-12-01T00:00:00", "36.95000", "-76.05000"},
+		{"3", "2017-12-01T00:00:00", "bad", "-76.00000"},
+		{"4", "2017-12-01T00:00:00", "-37.95103341", "144.42486789"},
+	}
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON"}}
+	p, err := NewPositions(recs, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != len(recs) {
+		t.Fatalf("Len() = %d, want %d", p.Len(), len(recs))
+	}
+	if !math.IsNaN(p.Lat[2]) || !math.IsNaN(p.Lon[2]) {
+		t.Errorf("unparsable position = %v, %v, want NaN", p.Lat[2], p.Lon[2])
+	}
+
+	pairs := [][2]int{{0, 1}, {1, 0}, {0, 3}, {0, 0}, {0, 2}}
+	fast := p.Distances(nil, pairs, nil)
+	viaFn := p.Distances(Haversine, pairs, nil)
+	for k, pr := range pairs {
+		i, j := pr[0], pr[1]
+		want := Haversine(p.Lat[i], p.Lon[i], p.Lat[j], p.Lon[j])
+		if math.IsNaN(want) {
+			if !math.IsNaN(fast[k]) || !math.IsNaN(viaFn[k]) {
+				t.Errorf("pair %v = %v, %v, want NaN", pr, fast[k], viaFn[k])
+			}
+			continue
+		}
+		if math.Abs(fast[k]-want) > 1e-6 || viaFn[k] != want {
+			t.Errorf("pair %v = %v, %v, want %v", pr, fast[k], viaFn[k], want)
+		}
+	}
+
+	lat1, lon1 := []float64{p.Lat[0], p.Lat[0]}, []float64{p.Lon[0], p.Lon[0]}
+	lat2, lon2 := []float64{p.Lat[1], p.Lat[3]}, []float64{p.Lon[1], p.Lon[3]}
+	batch := HaversineBatch(lat1, lon1, lat2, lon2, nil)
+	for k := range batch {
+		if want := Haversine(lat1[k], lon1[k], lat2[k], lon2[k]); math.Abs(batch[k]-want) > 1e-6 {
+			t.Errorf("HaversineBatch()[%d] = %v, want %v", k, batch[k], want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("HaversineBatch() of unequal slices did not panic")
+		}
+	}()
+	HaversineBatch(lat1, lon1, lat2[:1], lon2, nil)
+}
+
+func TestNewPositions_MissingHeader(t *testing.T) {
+	if _, err := NewPositions(nil, Headers{Fields: []string{"MMSI", "LAT"}}); err == nil {
+		t.Error("NewPositions() without LON should fail")
+	}
+}
+
+func TestInteractions_CachedDistance(t *testing.T) {
+	c := new(Cluster)
+	for _, rec := range []Record{
+		{"1", "2017-12-01T00:00:00", "36.90000", "-76.10000"},
+		{"2", "2017-12-01T00:00:10", "36.95000", "-76.05000"},
+	} {
+		rec := rec
+		c.Append(&rec)
+	}
+	inter, err := NewInteractions(Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatal(err)
+	}
+	want := Haversine(36.9, -76.1, 36.95, -76.05)
+	inter.each(func(hash Hash128, pair *RecordPair) error {
+		if !pair.hasDist || pair.dist != want {
+			t.Errorf("stored distance = %v (set %v), want %v", pair.dist, pair.hasDist, want)
+		}
+		return nil
+	})
+}
+
+func BenchmarkPositions_Distances(b *testing.B) {
+	clusters := testClusters(1, 200)
+	recs := clusters[0].Data()
+	var pairs [][2]int
+	for i := range recs {
+		for j := i + 1; j < len(recs); j++ {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	dst := make([]float64, 0, len(pairs))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, _ := NewPositions(recs, goodHeaders)
+		dst = p.Distances(nil, pairs, dst[:0])
+	}
+}
@@ -0,0 +1,117 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMinRecall is the fraction of the pairs of vessels within the radius
+// passed to TunePrecision that a SingleCell plan is chosen to find.
+const DefaultMinRecall = 0.9
+
+// maxPlanLat bounds the latitude used to size geohash cells, because cells
+// become arbitrarily narrow at the poles.
+const maxPlanLat = 89.0
+
+// NeighborStrategy selects which geohash cells are compared when vessels are
+// grouped into clusters.
+type NeighborStrategy int
+
+const (
+	// SingleCell compares only the vessels that share a geohash cell, which is
+	// what Window.FindClusters does.  Pairs that straddle the edge of a cell are
+	// missed, so the cells must be large compared to the interaction radius.
+	SingleCell NeighborStrategy = iota
+
+	// EightNeighbors compares the vessels of each cell with those of its eight
+	// neighbors, which finds every pair closer than the size of a cell.
+	EightNeighbors
+)
+
+func (s NeighborStrategy) String() string {
+	switch s {
+	case SingleCell:
+		return "single cell"
+	case EightNeighbors:
+		return "eight neighbors"
+	}
+	return fmt.Sprintf("NeighborStrategy(%d)", int(s))
+}
+
+// PrecisionPlan is the geohash precision chosen by TunePrecision.
+type PrecisionPlan struct {
+	Bits       uint             // precision of the integer geohash
+	Strategy   NeighborStrategy // cells compared for each cluster
+	CellHeight float64          // nautical miles
+	CellWidth  float64          // nautical miles at the latitude of the band farthest from the equator
+	Recall     float64          // expected fraction of the pairs within the radius that are compared
+}
+
+// TunePrecision returns the highest geohash precision, and so the smallest
+// clusters, that still compares the vessels within radius nautical miles of each
+// other for data between the latitudes minLat and maxLat.  Cells shrink in width
+// away from the equator, so the plan is sized for the latitude of the band
+// farthest from it.
+//
+// With EightNeighbors the cells are at least radius high and wide and the plan
+// finds every pair.  With SingleCell a pair is only found when both vessels fall
+// in the same cell, and the precision is chosen so that the expected Recall for
+// pairs at the radius, over every bearing between the vessels, is at least
+// DefaultMinRecall.  The Bits of the plan can be used as the Precision of
+// InteractionParams.
+func TunePrecision(radius, minLat, maxLat float64, strategy NeighborStrategy) (PrecisionPlan, error) {
+	if radius <= 0 || math.IsNaN(radius) || math.IsInf(radius, 0) {
+		return PrecisionPlan{}, fmt.Errorf("tune precision: radius must be positive, got %v", radius)
+	}
+	if minLat < -90 || maxLat > 90 || minLat > maxLat {
+		return PrecisionPlan{}, fmt.Errorf("tune precision: invalid latitude band %v to %v", minLat, maxLat)
+	}
+	if strategy != SingleCell && strategy != EightNeighbors {
+		return PrecisionPlan{}, fmt.Errorf("tune precision: unknown strategy %v", strategy)
+	}
+	lat := math.Min(math.Max(math.Abs(minLat), math.Abs(maxLat)), maxPlanLat)
+
+	var best PrecisionPlan
+	for bits := uint(1); bits <= 64; bits++ {
+		p := PrecisionPlan{Bits: bits, Strategy: strategy}
+		p.CellHeight, p.CellWidth = geohashCellSize(bits, lat)
+		if strategy == EightNeighbors {
+			if p.CellHeight < radius || p.CellWidth < radius {
+				break
+			}
+			p.Recall = 1
+		} else {
+			p.Recall = cellRecall(radius, p.CellWidth, p.CellHeight)
+			if p.Recall < DefaultMinRecall {
+				break
+			}
+		}
+		best = p
+	}
+	if best.Bits == 0 {
+		return PrecisionPlan{}, fmt.Errorf("tune precision: radius %v nm is too large for a geohash", radius)
+	}
+	return best, nil
+}
+
+// geohashCellSize returns the height and width in nautical miles of a cell of an
+// integer geohash with bits of precision at lat degrees.  Longitude takes the
+// odd bits, so it has the extra bit of an odd precision.
+func geohashCellSize(bits uint, lat float64) (height, width float64) {
+	latBits, lonBits := bits/2, bits-bits/2
+	height = 180 / math.Pow(2, float64(latBits)) * 60
+	width = 360 / math.Pow(2, float64(lonBits)) * 60 * math.Cos(lat*math.Pi/180)
+	return height, width
+}
+
+// cellRecall returns the probability that two points r apart, at a uniformly
+// random bearing and position, fall in the same cell of a w by h grid.  For a
+// bearing θ the probability is (1 - r|cos θ|/w)(1 - r|sin θ|/h), whose mean over
+// θ is 1 - 2(a+b)/π + ab/π with a = r/w and b = r/h.
+func cellRecall(r, w, h float64) float64 {
+	a, b := r/w, r/h
+	if a >= 1 || b >= 1 {
+		return 0
+	}
+	return 1 - 2*(a+b)/math.Pi + a*b/math.Pi
+}
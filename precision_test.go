@@ -0,0 +1,40 @@
+package ais
+
+import "testing"
+
+func TestTunePrecision(t *testing.T) {
+	for _, radius := range []float64{0.1, 1, 5, 50} {
+		near, err := TunePrecision(radius, 36.5, 39.5, EightNeighbors)
+		if err != nil {
+			t.Fatalf("TunePrecision(%v, EightNeighbors) error = %v", radius, err)
+		}
+		if near.CellHeight < radius || near.CellWidth < radius || near.Recall != 1 {
+			t.Errorf("TunePrecision(%v, EightNeighbors) = %+v, want cells at least the radius", radius, near)
+		}
+		h, w := geohashCellSize(near.Bits+1, 39.5)
+		if h >= radius && w >= radius {
+			t.Errorf("TunePrecision(%v, EightNeighbors) = %d bits, %d bits also fits", radius, near.Bits, near.Bits+1)
+		}
+
+		single, err := TunePrecision(radius, 36.5, 39.5, SingleCell)
+		if err != nil {
+			t.Fatalf("TunePrecision(%v, SingleCell) error = %v", radius, err)
+		}
+		if single.Recall < DefaultMinRecall || single.Bits >= near.Bits {
+			t.Errorf("TunePrecision(%v, SingleCell) = %+v, want coarser cells than %d bits", radius, single, near.Bits)
+		}
+	}
+
+	// Cells narrow toward the poles, so the same radius needs a lower precision.
+	equator, _ := TunePrecision(1, -1, 1, EightNeighbors)
+	north, _ := TunePrecision(1, 60, 70, EightNeighbors)
+	if north.Bits >= equator.Bits {
+		t.Errorf("TunePrecision() at 70N = %d bits, want fewer than %d at the equator", north.Bits, equator.Bits)
+	}
+
+	for _, tt := range []struct{ radius, min, max float64 }{{0, 0, 1}, {1, 10, 5}, {1, -91, 0}, {20000, 0, 1}} {
+		if _, err := TunePrecision(tt.radius, tt.min, tt.max, SingleCell); err == nil {
+			t.Errorf("TunePrecision(%v, %v, %v) expected error", tt.radius, tt.min, tt.max)
+		}
+	}
+}
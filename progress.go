@@ -0,0 +1,118 @@
+package ais
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressInterval is the number of Records processed between reports to a
+// Progress.  It is a multiple of cancelCheckInterval.
+var progressInterval = 64 * cancelCheckInterval
+
+// ProgressInfo is a report of how far a long-running operation has gone.
+type ProgressInfo struct {
+	Op         string        // the operation, named as in its error messages
+	Records    int           // Records, pairs, or clusters processed so far
+	Bytes      int64         // bytes of the input file read so far, 0 when unknown
+	TotalBytes int64         // size of the input file, 0 when unknown
+	Elapsed    time.Duration // time since the operation started
+	Done       bool          // set on the final report of a completed operation
+}
+
+// ETA returns the estimated time remaining from the fraction of the input file
+// read so far.  It returns zero when the size of the input is unknown, as it is
+// for a RecordSet that was not opened from a file.
+func (p ProgressInfo) ETA() time.Duration {
+	if p.Done || p.Bytes <= 0 || p.TotalBytes <= 0 || p.Bytes > p.TotalBytes {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * float64(p.TotalBytes-p.Bytes) / float64(p.Bytes))
+}
+
+// Progress receives reports from the Context variants of long-running methods,
+// such as RecordSet.SaveContext, SubsetContext, SortByTimeContext,
+// Interactions.AddClusterContext and SaveContext.  Progress is called every
+// 65536 Records and once more when the operation completes.  Calls for a single
+// operation are never concurrent, but Progress must return quickly because the
+// operation waits for it.
+type Progress interface {
+	Progress(ProgressInfo)
+}
+
+// ProgressFunc is a function that implements the Progress interface.
+type ProgressFunc func(ProgressInfo)
+
+// Progress calls f(p).
+func (f ProgressFunc) Progress(p ProgressInfo) { f(p) }
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx that carries p.  Passing the returned
+// Context to a Context variant method turns on its progress reports, for example
+//
+//	ctx := ais.WithProgress(context.Background(), ais.ProgressFunc(func(p ais.ProgressInfo) {
+//		log.Printf("%s: %d records, eta %v", p.Op, p.Records, p.ETA())
+//	}))
+//	rs, err = rs.SortByTimeContext(ctx)
+func WithProgress(ctx context.Context, p Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// progressTracker sends the reports for one operation.  A nil *progressTracker
+// is valid and does nothing, which is what startProgress returns when ctx
+// carries no Progress.
+type progressTracker struct {
+	mu    sync.Mutex
+	p     Progress
+	op    string
+	start time.Time
+	src   *os.File
+	total int64
+}
+
+// startProgress returns a tracker for op reading from rs, which may be nil when
+// the operation does not read a RecordSet.
+func startProgress(ctx context.Context, op string, rs *RecordSet) *progressTracker {
+	p, ok := ctx.Value(progressKey{}).(Progress)
+	if !ok || p == nil {
+		return nil
+	}
+	t := &progressTracker{p: p, op: op, start: time.Now()}
+	if rs != nil && rs.src != nil {
+		if fi, err := rs.src.Stat(); err == nil {
+			t.src, t.total = rs.src, fi.Size()
+		}
+	}
+	return t
+}
+
+// update reports n on every progressInterval'th value of n.
+func (t *progressTracker) update(n int) {
+	if t == nil || n == 0 || n%progressInterval != 0 {
+		return
+	}
+	t.report(n, false)
+}
+
+// done sends the final report.
+func (t *progressTracker) done(n int) {
+	if t == nil {
+		return
+	}
+	t.report(n, true)
+}
+
+func (t *progressTracker) report(n int, done bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info := ProgressInfo{Op: t.op, Records: n, Elapsed: time.Since(t.start), Done: done}
+	if t.src != nil {
+		if off, err := t.src.Seek(0, io.SeekCurrent); err == nil {
+			info.Bytes, info.TotalBytes = off, t.total
+		}
+	}
+	t.p.Progress(info)
+}
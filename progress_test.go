@@ -0,0 +1,69 @@
+package ais
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(n int) { progressInterval = n }(progressInterval)
+	progressInterval = 4
+
+	var reports []ProgressInfo
+	ctx := WithProgress(context.Background(), ProgressFunc(func(p ProgressInfo) {
+		reports = append(reports, p)
+	}))
+
+	rs, err := OpenRecordSet("testdata/ten.csv")
+	if err != nil {
+		t.Fatalf("OpenRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+	if err := rs.SaveContext(ctx, filepath.Join(dir, "ten.csv")); err != nil {
+		t.Fatalf("RecordSet.SaveContext() error = %v", err)
+	}
+
+	fi, _ := os.Stat("testdata/ten.csv")
+	if len(reports) != 3 {
+		t.Fatalf("Progress called %d times, want 3: %+v", len(reports), reports)
+	}
+	if p := reports[0]; p.Op != "recordset save" || p.Records != 4 || p.Done || p.TotalBytes != fi.Size() {
+		t.Errorf("first report = %+v, want 4 records of %d bytes", p, fi.Size())
+	}
+	last := reports[2]
+	if !last.Done || last.Records != 10 || last.Bytes != fi.Size() || last.ETA() != 0 {
+		t.Errorf("final report = %+v, want 10 records and all %d bytes read", last, fi.Size())
+	}
+
+	// Without a Progress in the Context nothing is reported.
+	reports = nil
+	rs2, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs2.Close()
+	if _, err := rs2.SortByTimeContext(context.Background()); err != nil {
+		t.Fatalf("RecordSet.SortByTimeContext() error = %v", err)
+	}
+	if len(reports) != 0 {
+		t.Errorf("Progress called %d times without WithProgress, want 0", len(reports))
+	}
+}
+
+func TestProgressInfo_ETA(t *testing.T) {
+	p := ProgressInfo{Bytes: 25, TotalBytes: 100, Elapsed: time.Minute}
+	if got := p.ETA(); got != 3*time.Minute {
+		t.Errorf("ProgressInfo.ETA() = %v, want 3m0s", got)
+	}
+	p.TotalBytes = 0
+	if got := p.ETA(); got != 0 {
+		t.Errorf("ProgressInfo.ETA() with unknown size = %v, want 0", got)
+	}
+}
@@ -0,0 +1,115 @@
+package ais
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrMotionNotAvailable is returned by Record.Project when a Record reports the
+// AIS not available value for SOG or COG, so it cannot be dead reckoned.
+var ErrMotionNotAvailable = errors.New("speed or course not available")
+
+// Project returns a copy of the Record dead reckoned forward by d: its position
+// moved along the great circle leaving on its COG for the distance covered at
+// its SOG, and its BaseDateTime advanced by d.  A negative d projects the
+// Record backward.  Other fields, including SOG and COG, are copied unchanged.
+// The Headers must contain BaseDateTime, LAT, LON, SOG, and COG, and
+// ErrMotionNotAvailable is returned for a SOG of 102.3 or COG of 360 or more.
+// Dead reckoning assumes the vessel holds its course and speed, so projections
+// of more than a few minutes should be treated as estimates.
+func (r Record) Project(d time.Duration, h Headers) (Record, error) {
+	t, err := r.Time(h)
+	if err != nil {
+		return nil, fmt.Errorf("project: %w", err)
+	}
+	lat, lon, err := r.LatLon(h)
+	if err != nil {
+		return nil, fmt.Errorf("project: %w", err)
+	}
+	sog, err := r.SOG(h)
+	if err != nil {
+		return nil, fmt.Errorf("project: %w", err)
+	}
+	cog, err := r.COG(h)
+	if err != nil {
+		return nil, fmt.Errorf("project: %w", err)
+	}
+	if sog < 0 || sog >= 102.3 || cog < 0 || cog >= COGNotAvailable {
+		return nil, fmt.Errorf("project: %w", ErrMotionNotAvailable)
+	}
+
+	lat, lon = destination(lat, lon, cog, sog*d.Hours())
+	rec := make(Record, len(r))
+	copy(rec, r)
+	if err := rec.setPosition(h, lat, lon); err != nil {
+		return nil, fmt.Errorf("project: %w", err)
+	}
+	i, _ := h.index("BaseDateTime")
+	rec[i] = t.Add(d).Format(TimeLayout)
+	return rec, nil
+}
+
+// setPosition writes lat and lon to the LAT and LON fields of r in the format
+// used by Track.Interpolate.
+func (r Record) setPosition(h Headers, lat, lon float64) error {
+	latIndex, err := h.index("LAT")
+	if err != nil {
+		return err
+	}
+	lonIndex, err := h.index("LON")
+	if err != nil {
+		return err
+	}
+	r[latIndex] = fmt.Sprintf("%.5f", lat)
+	r[lonIndex] = fmt.Sprintf("%.5f", lon)
+	return nil
+}
+
+// destination returns the position nm nautical miles from lat, lon along the
+// great circle with the initial bearing in degrees true.  A negative nm moves
+// the opposite way.
+func destination(lat, lon, bearing, nm float64) (float64, float64) {
+	const rad = math.Pi / 180
+	delta := nm / earthRadiusNM
+	phi1, lambda1, theta := lat*rad, lon*rad, bearing*rad
+	sinPhi2 := math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta)
+	phi2 := math.Asin(math.Max(-1, math.Min(1, sinPhi2)))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1), math.Cos(delta)-math.Sin(phi1)*sinPhi2)
+	return phi2 / rad, normalizeLon(lambda2 / rad)
+}
+
+// At returns the state of the vessel at time ts as a Record with BaseDateTime ts.
+// Between two reports the fields are interpolated as by Track.Interpolate with
+// Linear interpolation, and at a report time the Record is a copy of the
+// report.  Before the start or after the end of the Track the first or last
+// Record is dead reckoned to ts with Record.Project, which requires SOG and COG.
+// At puts the Tracks of two vessels in the same instant, for example to compute
+// the risk of an encounter from their states at one time rather than from
+// reports seconds or minutes apart.
+func (t *Track) At(ts time.Time) (Record, error) {
+	if len(t.data) == 0 {
+		return nil, ErrEmptySet
+	}
+	if ts.Before(t.Start()) {
+		return t.data[0].Project(ts.Sub(t.Start()), t.h)
+	}
+	if ts.After(t.End()) {
+		return t.data[len(t.data)-1].Project(ts.Sub(t.End()), t.h)
+	}
+
+	// j is the last Record at or before ts.
+	j := sort.Search(len(t.times), func(i int) bool { return t.times[i].After(ts) }) - 1
+	rec := make(Record, len(t.data[j]))
+	copy(rec, t.data[j])
+	if t.times[j].Before(ts) {
+		f := float64(ts.Sub(t.times[j])) / float64(t.times[j+1].Sub(t.times[j]))
+		if err := t.interpolateFields(rec, j, f, Linear); err != nil {
+			return nil, fmt.Errorf("track at: %w", err)
+		}
+	}
+	rec[t.idx["BaseDateTime"].Idx] = ts.Format(TimeLayout)
+	return rec, nil
+}
@@ -0,0 +1,70 @@
+package ais
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRecord_Project(t *testing.T) {
+	rec := Record{"100000001", "2017-12-01T00:00:00", "30.00000", "-76.00000", "12.0", "90.0", "511", "", "", "", "70", "", "", "", "", ""}
+	got, err := rec.Project(30*time.Minute, goodHeaders)
+	if err != nil {
+		t.Fatalf("Record.Project() error = %v", err)
+	}
+	if got[1] != "2017-12-01T00:30:00" {
+		t.Errorf("Record.Project() BaseDateTime = %s, want 2017-12-01T00:30:00", got[1])
+	}
+	lat, lon, _ := got.LatLon(goodHeaders)
+	if d := Haversine(30, -76, lat, lon); math.Abs(d-6) > 0.01 || lon <= -76 || math.Abs(lat-30) > 0.001 {
+		t.Errorf("Record.Project() position = %v, %v, %.3f nm east, want 6 nm east", lat, lon, d)
+	}
+	if rec[2] != "30.00000" {
+		t.Errorf("Record.Project() modified the receiver")
+	}
+
+	// Holding 090 along a great circle is not the way back along the outbound
+	// leg, but over 6 nm the two differ by far less than a cable.
+	back, _ := got.Project(-30*time.Minute, goodHeaders)
+	lat, lon, _ = back.LatLon(goodHeaders)
+	if d := Haversine(30, -76, lat, lon); d > 0.01 || back[1] != rec[1] {
+		t.Errorf("Record.Project() backward = %v, want the original position and time", back)
+	}
+
+	rec[5] = "360.0"
+	if _, err := rec.Project(time.Minute, goodHeaders); !errors.Is(err, ErrMotionNotAvailable) {
+		t.Errorf("Record.Project() error = %v, want ErrMotionNotAvailable", err)
+	}
+}
+
+func TestTrack_At(t *testing.T) {
+	track, err := NewTrack(goodHeaders, []Record{
+		{"100000001", "2017-12-01T00:00:00", "30.00000", "-76.00000", "12.0", "0.0", "511", "", "", "", "70", "", "", "", "", ""},
+		{"100000001", "2017-12-01T00:10:00", "30.03333", "-76.00000", "12.0", "0.0", "511", "", "", "", "70", "", "", "", "", ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := track.Start()
+	tests := []struct {
+		offset time.Duration
+		lat    float64
+	}{
+		{0, 30},
+		{5 * time.Minute, 30.01667},
+		{10 * time.Minute, 30.03333},
+		{15 * time.Minute, 30.05},
+		{-5 * time.Minute, 29.98333},
+	}
+	for _, tt := range tests {
+		rec, err := track.At(start.Add(tt.offset))
+		if err != nil {
+			t.Fatalf("Track.At(%v) error = %v", tt.offset, err)
+		}
+		lat, _, _ := rec.LatLon(goodHeaders)
+		if math.Abs(lat-tt.lat) > 0.00002 || rec[1] != start.Add(tt.offset).Format(TimeLayout) {
+			t.Errorf("Track.At(%v) = %v, want LAT %.5f", tt.offset, rec, tt.lat)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProvenanceFields are the headers appended by OpenRecordSetWithProvenance and
+// Decoder.SetProvenance.  Source names the file or stream a Record was read from
+// and Line is the line number of the Record within it, counting from one.
+// Because they are ordinary fields they are carried through Subset, sorting,
+// clustering, and into the _1 and _2 columns written by Interactions, so a
+// flagged interaction can be traced back to the raw lines that produced it.
+const ProvenanceFields = "Source,Line"
+
+// SentenceField is the header of the original NMEA sentences of a decoded
+// Record, appended after ProvenanceFields by Decoder.SetProvenance.
+const SentenceField = "Sentence"
+
+// OpenRecordSetWithProvenance is OpenRecordSet for a RecordSet whose Headers end
+// with ProvenanceFields.  Source is filename and Line is the line of the file
+// that held the Record, so the first Record after the headers is on line 2.
+// Every Record must be on a single line, which is always the case for
+// MarineCadastre.gov files; line numbers after a quoted field that spans lines
+// are not correct.  Like a compressed RecordSet, the returned RecordSet is read
+// only.
+func OpenRecordSetWithProvenance(filename string) (*RecordSet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open recordset: %w", err)
+	}
+	rc, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open recordset: %w", err)
+	}
+	var src io.ReadCloser = f
+	if rc != nil {
+		src = rc
+	}
+	rs, err := NewRecordSetFromReader(newProvenanceReader(src, filename), Headers{})
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("open recordset: %w", err)
+	}
+	rs.src = f
+	return rs, nil
+}
+
+// provenanceReader rewrites csv text a line at a time, appending the
+// ProvenanceFields headers to the first line and the source and line number to
+// every later line.  Comment and blank lines are passed through unchanged.
+type provenanceReader struct {
+	r       *bufio.Reader
+	c       io.Closer
+	source  string // quoted for csv
+	line    int
+	headers bool
+	pending string
+	err     error
+}
+
+func newProvenanceReader(r io.ReadCloser, source string) *provenanceReader {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{source})
+	w.Flush()
+	return &provenanceReader{
+		r:      bufio.NewReader(r),
+		c:      r,
+		source: strings.TrimRight(b.String(), "\n"),
+	}
+}
+
+func (p *provenanceReader) Read(b []byte) (int, error) {
+	for p.pending == "" {
+		if p.err != nil {
+			return 0, p.err
+		}
+		line, err := p.r.ReadString('\n')
+		p.err = err
+		if line == "" {
+			continue
+		}
+		p.line++
+		text := strings.TrimRight(line, "\r\n")
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+			p.pending = line
+		case !p.headers:
+			p.headers = true
+			p.pending = text + "," + ProvenanceFields + "\n"
+		default:
+			p.pending = text + "," + p.source + "," + strconv.Itoa(p.line) + "\n"
+		}
+	}
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// Close closes the underlying reader.
+func (p *provenanceReader) Close() error { return p.c.Close() }
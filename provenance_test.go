@@ -0,0 +1,105 @@
+package ais
+
+import (
+	"context"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOpenRecordSetWithProvenance(t *testing.T) {
+	rs, err := OpenRecordSetWithProvenance("testdata/ten.csv")
+	if err != nil {
+		t.Fatalf("OpenRecordSetWithProvenance() error = %v", err)
+	}
+	defer rs.Close()
+
+	idx, ok := rs.Headers().ContainsMulti("MMSI", "Source", "Line")
+	if !ok || idx["Line"].Idx != len(rs.Headers().Fields)-1 {
+		t.Fatalf("Headers = %v, want ProvenanceFields appended", rs.Headers().Fields)
+	}
+	line := 1
+	for rs.Next() {
+		line++
+		rec := *rs.Record()
+		if rec[idx["Source"].Idx] != "testdata/ten.csv" || rec[idx["Line"].Idx] != strconv.Itoa(line) {
+			t.Errorf("Record %v, want Source testdata/ten.csv and Line %d", rec, line)
+		}
+	}
+	if err := rs.Err(); err != nil {
+		t.Fatalf("RecordSet.Next() error = %v", err)
+	}
+	if line != 11 {
+		t.Errorf("read %d lines, want 11", line)
+	}
+}
+
+func TestProvenanceReader(t *testing.T) {
+	in := "# comment\r\nMMSI,LAT\r\n1,2.0\n\n3,4.0"
+	rs, err := NewRecordSetFromReader(newProvenanceReader(ioutil.NopCloser(strings.NewReader(in)), "a,b.csv"), Headers{})
+	if err != nil {
+		t.Fatalf("NewRecordSetFromReader() error = %v", err)
+	}
+	if got := strings.Join(rs.Headers().Fields, ","); got != "MMSI,LAT,Source,Line" {
+		t.Errorf("Headers = %s, want MMSI,LAT,Source,Line", got)
+	}
+	var got []string
+	for rs.Next() {
+		got = append(got, strings.Join(*rs.Record(), "|"))
+	}
+	want := []string{"1|2.0|a,b.csv|3", "3|4.0|a,b.csv|5"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Records = %q, want %q", got, want)
+	}
+}
+
+func TestDecoder_SetProvenance(t *testing.T) {
+	payload := "15RTgt0PAso;90TKcjM8h6g208CQ"
+	part1 := sentence("AIVDM,2,1,3,A," + payload[:14] + ",0")
+	part2 := sentence("AIVDM,2,2,3,A," + payload[14:] + ",0")
+	d := NewDecoder(strings.NewReader(testSentence + "\n$GPGGA\n" + part1 + "\n" + part2 + "\n"))
+	d.Clock = testClock
+	d.SetProvenance("rx1")
+
+	h := d.Headers()
+	idx, ok := h.ContainsMulti("Source", "Line", SentenceField)
+	if !ok {
+		t.Fatalf("Headers = %v, want ProvenanceFields and Sentence", h.Fields)
+	}
+	want := [][3]string{
+		{"rx1", "1", testSentence},
+		{"rx1", "3", part1 + " " + part2},
+	}
+	for _, w := range want {
+		rec, err := d.Decode()
+		if err != nil {
+			t.Fatalf("Decoder.Decode() error = %v", err)
+		}
+		got := [3]string{(*rec)[idx["Source"].Idx], (*rec)[idx["Line"].Idx], (*rec)[idx[SentenceField].Idx]}
+		if got != w {
+			t.Errorf("Decoder.Decode() provenance = %q, want %q", got, w)
+		}
+	}
+}
+
+func TestProvenance_Interactions(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(detectData), "\n")
+	h := Headers{Fields: append(strings.Split(lines[0], ","), strings.Split(ProvenanceFields, ",")...)}
+	rs := NewRecordSet()
+	rs.SetHeaders(h)
+	for _, line := range lines[1:] {
+		rec := Record(strings.Split(line, ","))
+		rec = append(rec, "day.csv", "7")
+		rs.Write(rec)
+	}
+	rs.Flush()
+
+	inter, err := rs.FindInteractions(context.Background(), InteractionParams{})
+	if err != nil {
+		t.Fatalf("RecordSet.FindInteractions() error = %v", err)
+	}
+	if _, ok := inter.OutputHeaders.ContainsMulti("Source_1", "Line_1", "Source_2", "Line_2"); !ok {
+		t.Errorf("OutputHeaders = %v, want the provenance of both vessels", inter.OutputHeaders.Fields)
+	}
+}
@@ -0,0 +1,375 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultReplayWindow is the time on either side of the closest approach that
+// a Replay shows when no window is given.
+const DefaultReplayWindow = 15 * time.Minute
+
+// replaySample is one position of a vessel in a Replay.
+type replaySample struct {
+	t        time.Time
+	lat, lon float64
+	rec      Record
+}
+
+// Replay is the tracks of two vessels around their closest approach, written by
+// SaveCZML or SaveGeoJSON so that analysts can replay an encounter in Cesium, QGIS
+// with its temporal controller, kepler.gl, or Leaflet.
+type Replay struct {
+	MMSI1, MMSI2 string
+	Closest      time.Time // time of the closest approach
+	Distance     float64   // nm between the vessels at Closest
+	Start, End   time.Time // Closest less and plus the window
+
+	h                  Headers
+	samples1, samples2 []replaySample
+}
+
+// NewReplay returns the Replay of the Tracks of two vessels within window of
+// their closest approach near around, the time of an interaction.  The closest
+// approach is searched for within window of around, over the time both Tracks
+// span, assuming each vessel moves in a straight line at constant speed between
+// its reports.  A zero around searches the whole time the Tracks span and a
+// zero window is DefaultReplayWindow.  The samples of each vessel are its
+// Records between Start and End and its interpolated position at Closest.  The
+// Tracks must share their Headers.
+func NewReplay(t1, t2 *Track, around time.Time, window time.Duration) (*Replay, error) {
+	if window <= 0 {
+		window = DefaultReplayWindow
+	}
+	from, to := t1.Start(), t1.End()
+	if t2.Start().After(from) {
+		from = t2.Start()
+	}
+	if t2.End().Before(to) {
+		to = t2.End()
+	}
+	if !around.IsZero() {
+		if a := around.Add(-window); a.After(from) {
+			from = a
+		}
+		if b := around.Add(window); b.Before(to) {
+			to = b
+		}
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("new replay: tracks %s and %s do not overlap", t1.MMSI, t2.MMSI)
+	}
+
+	// The candidate times are the reports of either vessel between from and to.
+	times := []time.Time{from, to}
+	for _, t := range []*Track{t1, t2} {
+		for _, ts := range t.times {
+			if !ts.Before(from) && !ts.After(to) {
+				times = append(times, ts)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	r := &Replay{MMSI1: t1.MMSI, MMSI2: t2.MMSI, h: t1.h, Distance: math.Inf(1)}
+	var prevT time.Time
+	var prevX, prevY float64
+	for i, ts := range times {
+		lat1, lon1, err := t1.positionAt(ts)
+		if err != nil {
+			return nil, fmt.Errorf("new replay: %w", err)
+		}
+		lat2, lon2, err := t2.positionAt(ts)
+		if err != nil {
+			return nil, fmt.Errorf("new replay: %w", err)
+		}
+		// Vessel 2 relative to vessel 1 on a local plane in nm.
+		x := (lon2 - lon1) * math.Cos((lat1+lat2)/2*math.Pi/180) * 60
+		y := (lat2 - lat1) * 60
+		if d := math.Hypot(x, y); d < r.Distance {
+			r.Distance, r.Closest = d, ts
+		}
+		if i > 0 {
+			if hours := ts.Sub(prevT).Hours(); hours > 0 {
+				d, at := closestApproach(prevX, prevY, (x-prevX)/hours, (y-prevY)/hours)
+				if at > 0 && at < hours && d < r.Distance {
+					r.Distance, r.Closest = d, prevT.Add(time.Duration(at*float64(time.Hour)))
+				}
+			}
+		}
+		prevT, prevX, prevY = ts, x, y
+	}
+	r.Start, r.End = r.Closest.Add(-window), r.Closest.Add(window)
+
+	var err error
+	if r.samples1, err = t1.replaySamples(r.Start, r.End, r.Closest); err != nil {
+		return nil, fmt.Errorf("new replay: %w", err)
+	}
+	if r.samples2, err = t2.replaySamples(r.Start, r.End, r.Closest); err != nil {
+		return nil, fmt.Errorf("new replay: %w", err)
+	}
+	return r, nil
+}
+
+// positionAt returns the position of the Track at ts, which must be within the
+// time the Track spans, interpolated linearly between its reports.
+func (t *Track) positionAt(ts time.Time) (lat, lon float64, err error) {
+	j := sort.Search(len(t.times), func(i int) bool { return t.times[i].After(ts) }) - 1
+	if j < 0 {
+		j = 0
+	}
+	lat, lon, err = t.position(j)
+	if err != nil || j == len(t.times)-1 || !t.times[j].Before(ts) {
+		return lat, lon, err
+	}
+	lat2, lon2, err := t.position(j + 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	f := float64(ts.Sub(t.times[j])) / float64(t.times[j+1].Sub(t.times[j]))
+	return lat + f*(lat2-lat), lon + f*(lon2-lon), nil
+}
+
+// replaySamples returns the Records of the Track from start to end, with the
+// position at closest added when no Record was reported then.
+func (t *Track) replaySamples(start, end, closest time.Time) ([]replaySample, error) {
+	var samples []replaySample
+	atClosest := false
+	for i, ts := range t.times {
+		if ts.Before(start) || ts.After(end) {
+			continue
+		}
+		lat, lon, err := t.position(i)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, replaySample{ts, lat, lon, t.data[i]})
+		atClosest = atClosest || ts.Equal(closest)
+	}
+	if !atClosest {
+		rec, err := t.At(closest)
+		if err != nil {
+			return nil, err
+		}
+		lat, lon, err := t.positionAt(closest)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, replaySample{closest, lat, lon, rec})
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].t.Before(samples[j].t) })
+	}
+	return samples, nil
+}
+
+// errReplayFound stops the search of Interactions.Replay.
+var errReplayFound = errors.New("found")
+
+// Replay returns the Replay of the interaction with the hash, the first column
+// of the file written by Save, from the Tracks of its two vessels, as returned
+// by RecordSet.Tracks.  The closest approach is searched for within window of
+// the BaseDateTime of the first vessel of the interaction.
+func (inter *Interactions) Replay(hash Hash128, tracks map[string]*Track, window time.Duration) (*Replay, error) {
+	idx, err := inter.RecordHeaders.require("MMSI", "BaseDateTime")
+	if err != nil {
+		return nil, fmt.Errorf("interactions replay: %w", err)
+	}
+	var rec1, rec2 Record
+	err = inter.eachUnordered(func(h Hash128, pair *RecordPair) error {
+		if h != hash {
+			return nil
+		}
+		rec1, rec2 = *pair.rec1, *pair.rec2
+		return errReplayFound
+	})
+	if err != nil && err != errReplayFound {
+		return nil, fmt.Errorf("interactions replay: %w", err)
+	}
+	if rec1 == nil {
+		return nil, fmt.Errorf("interactions replay: no interaction %s", hash)
+	}
+	around, err := inter.RecordHeaders.parseTime(rec1[idx["BaseDateTime"].Idx])
+	if err != nil {
+		return nil, fmt.Errorf("interactions replay: %w", err)
+	}
+	var pair [2]*Track
+	for i, rec := range []Record{rec1, rec2} {
+		mmsi := rec[idx["MMSI"].Idx]
+		if pair[i] = tracks[mmsi]; pair[i] == nil {
+			return nil, fmt.Errorf("interactions replay: no track for %s", mmsi)
+		}
+	}
+	r, err := NewReplay(pair[0], pair[1], around, window)
+	if err != nil {
+		return nil, fmt.Errorf("interactions replay: %w", err)
+	}
+	return r, nil
+}
+
+// replayColors are the CZML rgba colors of the first and second vessels.
+var replayColors = [2][4]int{{255, 64, 64, 255}, {64, 160, 255, 255}}
+
+// name returns the VesselName of the samples when the Headers hold one, or else
+// the MMSI.
+func (r *Replay) name(mmsi string, samples []replaySample) string {
+	if i, ok := r.h.Contains("VesselName"); ok && len(samples) > 0 {
+		if name := strings.TrimSpace(samples[0].rec[i]); name != "" {
+			return name
+		}
+	}
+	return mmsi
+}
+
+// SaveCZML writes the Replay to filename as a CZML document that Cesium plays
+// from Start to End.  Each vessel is a point with a label and a trailing path
+// whose position is interpolated linearly between its samples, and a line joins
+// the two vessels at the closest approach.
+func (r *Replay) SaveCZML(filename string) error {
+	interval := kmlTime(r.Start) + "/" + kmlTime(r.End)
+	packets := []map[string]interface{}{{
+		"id":      "document",
+		"name":    r.MMSI1 + " - " + r.MMSI2,
+		"version": "1.0",
+		"clock": map[string]interface{}{
+			"interval":    interval,
+			"currentTime": kmlTime(r.Start),
+			"multiplier":  10,
+			"range":       "LOOP_STOP",
+			"step":        "SYSTEM_CLOCK_MULTIPLIER",
+		},
+	}}
+	for i, v := range []struct {
+		mmsi    string
+		samples []replaySample
+	}{{r.MMSI1, r.samples1}, {r.MMSI2, r.samples2}} {
+		var coords []float64
+		for _, s := range v.samples {
+			coords = append(coords, s.t.Sub(r.Start).Seconds(), s.lon, s.lat, 0)
+		}
+		color := map[string]interface{}{"rgba": replayColors[i]}
+		packets = append(packets, map[string]interface{}{
+			"id":           v.mmsi,
+			"name":         r.name(v.mmsi, v.samples),
+			"availability": interval,
+			"position": map[string]interface{}{
+				"epoch":                  kmlTime(r.Start),
+				"cartographicDegrees":    coords,
+				"interpolationAlgorithm": "LINEAR",
+			},
+			"point": map[string]interface{}{"pixelSize": 10, "color": color},
+			"label": map[string]interface{}{
+				"text":             r.name(v.mmsi, v.samples),
+				"font":             "12pt sans-serif",
+				"pixelOffset":      map[string]interface{}{"cartesian2": []int{12, 0}},
+				"horizontalOrigin": "LEFT",
+			},
+			"path": map[string]interface{}{
+				"leadTime":  0,
+				"trailTime": r.End.Sub(r.Start).Seconds(),
+				"width":     2,
+				"material":  map[string]interface{}{"solidColor": map[string]interface{}{"color": color}},
+			},
+		})
+	}
+	if cpa1, cpa2, ok := r.closestSamples(); ok {
+		packets = append(packets, map[string]interface{}{
+			"id":           "cpa",
+			"name":         fmt.Sprintf("CPA %.2f nm", r.Distance),
+			"availability": kmlTime(r.Closest) + "/" + kmlTime(r.End),
+			"polyline": map[string]interface{}{
+				"positions": map[string]interface{}{"cartographicDegrees": []float64{cpa1.lon, cpa1.lat, 0, cpa2.lon, cpa2.lat, 0}},
+				"width":     1,
+				"material":  map[string]interface{}{"solidColor": map[string]interface{}{"color": map[string]interface{}{"rgba": []int{255, 255, 0, 255}}}},
+			},
+		})
+	}
+	return r.saveJSON(filename, packets, "replay save czml")
+}
+
+// closestSamples returns the samples of both vessels at Closest.
+func (r *Replay) closestSamples() (s1, s2 replaySample, ok bool) {
+	find := func(samples []replaySample) (replaySample, bool) {
+		for _, s := range samples {
+			if s.t.Equal(r.Closest) {
+				return s, true
+			}
+		}
+		return replaySample{}, false
+	}
+	s1, ok1 := find(r.samples1)
+	s2, ok2 := find(r.samples2)
+	return s1, s2, ok1 && ok2
+}
+
+// SaveGeoJSON writes the Replay to filename as a timestamped GeoJSON
+// FeatureCollection.  Each sample is a Point Feature with the fields of its
+// Record and a time property in RFC 3339, as QGIS and Leaflet.TimeDimension
+// expect, and each vessel is a LineString Feature whose coordinates carry the
+// Unix time as a fourth value, as the kepler.gl trip layer expects.  A Point
+// Feature named CPA marks the midpoint of the vessels at the closest approach.
+func (r *Replay) SaveGeoJSON(filename string) error {
+	type feature struct {
+		Type       string                 `json:"type"`
+		Geometry   geoJSONGeometry        `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	var features []feature
+	for _, v := range []struct {
+		mmsi    string
+		samples []replaySample
+	}{{r.MMSI1, r.samples1}, {r.MMSI2, r.samples2}} {
+		var trip [][]float64
+		for _, s := range v.samples {
+			props := make(map[string]interface{}, len(r.h.Fields)+1)
+			for i, f := range r.h.Fields {
+				if i < len(s.rec) {
+					props[f] = s.rec[i]
+				}
+			}
+			props["time"] = kmlTime(s.t)
+			features = append(features, feature{"Feature", geoJSONGeometry{"Point", []float64{s.lon, s.lat}}, props})
+			trip = append(trip, []float64{s.lon, s.lat, 0, float64(s.t.Unix())})
+		}
+		if len(trip) < 2 {
+			continue
+		}
+		features = append(features, feature{"Feature", geoJSONGeometry{"LineString", trip}, map[string]interface{}{
+			"MMSI":  v.mmsi,
+			"name":  r.name(v.mmsi, v.samples),
+			"start": kmlTime(v.samples[0].t),
+			"end":   kmlTime(v.samples[len(v.samples)-1].t),
+		}})
+	}
+	if s1, s2, ok := r.closestSamples(); ok {
+		features = append(features, feature{"Feature", geoJSONGeometry{"Point", []float64{(s1.lon + s2.lon) / 2, (s1.lat + s2.lat) / 2}},
+			map[string]interface{}{
+				"name":    "CPA",
+				"time":    kmlTime(r.Closest),
+				"CPA(nm)": strconv.FormatFloat(r.Distance, 'f', 3, 64),
+			}})
+	}
+	return r.saveJSON(filename, map[string]interface{}{"type": "FeatureCollection", "features": features}, "replay save geojson")
+}
+
+func (r *Replay) saveJSON(filename string, v interface{}, op string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,132 @@
+package ais
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// replayTracks returns a vessel steaming north along 76W and one steaming east
+// along 36.1N that crosses ahead of it, reporting thirty seconds after the
+// first, so their closest approach falls between reports.
+func replayTracks(t *testing.T) (*Track, *Track) {
+	t.Helper()
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON", "VesselName"}}
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	var recs1, recs2 []Record
+	for k := 0; k <= 40; k++ {
+		t1 := start.Add(time.Duration(k) * time.Minute)
+		t2 := t1.Add(30 * time.Second)
+		recs1 = append(recs1, Record{"111111111", t1.Format(TimeLayout),
+			fmt.Sprintf("%.5f", 36+0.005*float64(k)), "-76.00000", "NORTHBOUND"})
+		recs2 = append(recs2, Record{"222222222", t2.Format(TimeLayout),
+			"36.10000", fmt.Sprintf("%.5f", -76.1+0.005*(float64(k)+0.5)), ""})
+	}
+	t1, err := NewTrack(h, recs1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := NewTrack(h, recs2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return t1, t2
+}
+
+func TestNewReplay(t *testing.T) {
+	t1, t2 := replayTracks(t)
+	r, err := NewReplay(t1, t2, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("NewReplay() error = %v", err)
+	}
+	// Both vessels are at 36.1N 76W twenty minutes in.
+	want := time.Date(2017, 12, 1, 0, 20, 0, 0, time.UTC)
+	if d := r.Closest.Sub(want); d < -time.Second || d > time.Second || r.Distance > 0.01 {
+		t.Errorf("NewReplay() closest approach %.3f nm at %v, want 0 at %v", r.Distance, r.Closest, want)
+	}
+	if r.End.Sub(r.Start) != 2*DefaultReplayWindow {
+		t.Errorf("NewReplay() from %v to %v, want %v either side", r.Start, r.End, DefaultReplayWindow)
+	}
+	// 31 reports of the first vessel, and 30 of the second plus its position at
+	// the closest approach.
+	if len(r.samples1) != 31 || len(r.samples2) != 31 {
+		t.Errorf("NewReplay() samples = %d and %d, want 31 and 31", len(r.samples1), len(r.samples2))
+	}
+
+	late, _ := replayTracks(t)
+	late.times = append([]time.Time{}, late.times...)
+	for i := range late.times {
+		late.times[i] = late.times[i].Add(24 * time.Hour)
+	}
+	if _, err := NewReplay(late, t2, time.Time{}, 0); err == nil {
+		t.Errorf("NewReplay() of tracks that do not overlap error = nil, want an error")
+	}
+}
+
+func TestReplay_Save(t *testing.T) {
+	t1, t2 := replayTracks(t)
+	r, err := NewReplay(t1, t2, time.Date(2017, 12, 1, 0, 18, 0, 0, time.UTC), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("NewReplay() error = %v", err)
+	}
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "encounter.czml")
+	if err := r.SaveCZML(filename); err != nil {
+		t.Fatalf("Replay.SaveCZML() error = %v", err)
+	}
+	data, _ := ioutil.ReadFile(filename)
+	var packets []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Position struct {
+			Degrees []float64 `json:"cartographicDegrees"`
+		} `json:"position"`
+	}
+	if err := json.Unmarshal(data, &packets); err != nil {
+		t.Fatalf("Replay.SaveCZML() wrote invalid json: %v", err)
+	}
+	if len(packets) != 4 || packets[0].ID != "document" || packets[1].Name != "NORTHBOUND" ||
+		packets[2].Name != "222222222" || packets[3].ID != "cpa" {
+		t.Fatalf("Replay.SaveCZML() packets = %+v", packets)
+	}
+	if deg := packets[1].Position.Degrees; len(deg) != 4*11 || deg[0] != 0 || math.Abs(deg[2]-36.075) > 1e-9 {
+		t.Errorf("Replay.SaveCZML() first vessel positions = %v", deg)
+	}
+
+	filename = filepath.Join(dir, "encounter.geojson")
+	if err := r.SaveGeoJSON(filename); err != nil {
+		t.Fatalf("Replay.SaveGeoJSON() error = %v", err)
+	}
+	data, _ = ioutil.ReadFile(filename)
+	var fc struct {
+		Features []struct {
+			Geometry struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("Replay.SaveGeoJSON() wrote invalid json: %v", err)
+	}
+	// 11 points for each vessel, a line for each, and the CPA.
+	if len(fc.Features) != 25 {
+		t.Fatalf("Replay.SaveGeoJSON() wrote %d features, want 25", len(fc.Features))
+	}
+	if f := fc.Features[0]; f.Geometry.Type != "Point" || f.Properties["time"] != "2017-12-01T00:15:00Z" {
+		t.Errorf("Replay.SaveGeoJSON() first feature = %+v", f)
+	}
+	if f := fc.Features[24]; f.Properties["name"] != "CPA" {
+		t.Errorf("Replay.SaveGeoJSON() last feature = %+v", f)
+	}
+}
@@ -0,0 +1,100 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RiskFields are the optional column headers written by Interactions.Save after
+// CPAFields when collision risk scoring is enabled with SetRisk.
+// RelativeBearing(deg) is the bearing of the second vessel measured clockwise
+// from the course of the first vessel, BCR(nm) is the bow crossing range, and
+// RiskIndex is the aggregate risk described by Risk.
+const RiskFields = "RelativeBearing(deg),BCR(nm),RiskIndex"
+
+// Scales of the aggregate risk index.  A pair with a CPA of RiskCPAScale and a
+// TCPA of RiskTCPAScale has a RiskIndex of 1/e² (about 0.135).
+const (
+	RiskCPAScale  = 1.0              // nautical miles
+	RiskTCPAScale = 12 * time.Minute // time to closest approach
+)
+
+// Risk holds the standard collision risk metrics for a two-vessel encounter as
+// seen from the first vessel of a RecordPair.
+type Risk struct {
+	CPA             float64       // range at the closest point of approach in nautical miles
+	TCPA            time.Duration // time to the closest point of approach, negative when opening
+	RelativeBearing float64       // degrees clockwise from the first vessel's course to the second vessel
+	BCR             float64       // bow crossing range in nautical miles, NaN when the second vessel does not cross ahead
+	Index           float64       // aggregate risk from 0 (none) to 1 (collision course and imminent)
+}
+
+// Risk computes the collision risk metrics between the two Records of the pair.
+// The Headers have the same requirements as CPA and the same flat-earth and
+// constant velocity assumptions apply.
+//
+// BCR is the range ahead of the first vessel at which the second vessel crosses
+// its course line.  Index is exp(-CPA/RiskCPAScale) × exp(-TCPA/RiskTCPAScale) for
+// vessels that are closing and zero for vessels whose closest approach has
+// already passed.
+func (p *RecordPair) Risk(h Headers) (Risk, error) {
+	k1, _, rx, ry, vx, vy, err := p.relativeMotion(h)
+	if err != nil {
+		return Risk{}, fmt.Errorf("risk: %w", err)
+	}
+	cpa, hours := closestApproach(rx, ry, vx, vy)
+	r := Risk{
+		CPA:  cpa,
+		TCPA: time.Duration(hours * float64(time.Hour)),
+		BCR:  math.NaN(),
+	}
+
+	bearing := math.Atan2(rx, ry) * 180 / math.Pi
+	r.RelativeBearing = math.Mod(bearing-k1.cog+720, 360)
+
+	// Rotate the relative motion into the frame of the first vessel with its
+	// course along +y, then find where the second vessel crosses x = 0.
+	sin, cos := math.Sincos(k1.cog * math.Pi / 180)
+	ax, ay := rx*cos-ry*sin, rx*sin+ry*cos
+	avx, avy := vx*cos-vy*sin, vx*sin+vy*cos
+	if avx != 0 {
+		if t := -ax / avx; t >= 0 {
+			if y := ay + avy*t; y >= 0 {
+				r.BCR = y
+			}
+		}
+	}
+
+	if hours >= 0 {
+		r.Index = math.Exp(-cpa/RiskCPAScale) * math.Exp(-hours/RiskTCPAScale.Hours())
+	}
+	return r, nil
+}
+
+// SetRisk controls whether the collision risk columns described by RiskFields are
+// computed for each pair and written by Save.  Risk scoring includes the CPA and
+// TCPA, so CPAFields are written whenever risk is on, regardless of SetCPA.  Like
+// SetCPA, calling SetRisk resets OutputHeaders.  Pairs with unavailable SOG or COG
+// values are written with empty CPA and risk fields.
+func (inter *Interactions) SetRisk(on bool) {
+	inter.risk = on
+	inter.resetOutputHeaders()
+}
+
+// riskColumns returns the formatted values of CPAFields and RiskFields for a pair,
+// or empty fields when the risk cannot be computed.
+func (inter *Interactions) riskColumns(pair *RecordPair) []string {
+	r, err := pair.Risk(inter.RecordHeaders)
+	if err != nil {
+		return []string{"", "", "", "", ""}
+	}
+	bcr := ""
+	if !math.IsNaN(r.BCR) {
+		bcr = fmt.Sprintf("%.2f", r.BCR)
+	}
+	return []string{
+		fmt.Sprintf("%.2f", r.CPA), fmt.Sprintf("%.1f", r.TCPA.Minutes()),
+		fmt.Sprintf("%.1f", r.RelativeBearing), bcr, fmt.Sprintf("%.3f", r.Index),
+	}
+}
@@ -0,0 +1,99 @@
+package ais
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRecordPair_Risk(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec1    Record
+		rec2    Record
+		want    Risk
+		wantErr bool
+	}{
+		{
+			name: "crossing from starboard",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "0.0333333", "0.0166667", "10.0", "270.0"},
+			want: Risk{CPA: math.Sqrt2 / 2, TCPA: 9 * time.Minute, RelativeBearing: 26.565, BCR: 1, Index: 0.2329},
+		},
+		{
+			name: "head on with offset",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "0.0166667", "0.0016667", "10.0", "180.0"},
+			want: Risk{CPA: 0.1, TCPA: 3 * time.Minute, RelativeBearing: 5.711, BCR: math.NaN(), Index: 0.7047},
+		},
+		{
+			name: "opening astern",
+			rec1: Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "90.0"},
+			rec2: Record{"2", "2017-12-01T00:00:00", "0.0", "-0.0166667", "10.0", "270.0"},
+			want: Risk{CPA: 0, TCPA: -3 * time.Minute, RelativeBearing: 180, BCR: math.NaN(), Index: 0},
+		},
+		{
+			name:    "SOG not available",
+			rec1:    Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "102.3", "0.0"},
+			rec2:    Record{"2", "2017-12-01T00:00:00", "0.1", "0.0", "10.0", "180.0"},
+			wantErr: true,
+		},
+	}
+	near := func(a, b float64) bool {
+		if math.IsNaN(a) || math.IsNaN(b) {
+			return math.IsNaN(a) && math.IsNaN(b)
+		}
+		return math.Abs(a-b) < 1e-3
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &RecordPair{rec1: &tt.rec1, rec2: &tt.rec2}
+			got, err := p.Risk(kinematicHeaders)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecordPair.Risk() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !near(got.CPA, tt.want.CPA) || (got.TCPA-tt.want.TCPA).Round(time.Second) != 0 ||
+				!near(got.RelativeBearing, tt.want.RelativeBearing) || !near(got.BCR, tt.want.BCR) ||
+				!near(got.Index, tt.want.Index) {
+				t.Errorf("RecordPair.Risk() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractions_SetRisk(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	n := len(inter.OutputHeaders.Fields)
+
+	inter.SetRisk(true)
+	if got := len(inter.OutputHeaders.Fields); got != n+5 {
+		t.Errorf("Interactions.SetRisk(true) output fields = %d, want %d", got, n+5)
+	}
+	for field, want := range map[string]int{"CPA(nm)": 2, "TCPA(min)": 3, "RelativeBearing(deg)": 4, "RiskIndex": 6} {
+		if i, ok := inter.OutputHeaders.Contains(field); !ok || i != want {
+			t.Errorf("Interactions.SetRisk(true) %s index = %d, %v, want %d, true", field, i, ok, want)
+		}
+	}
+
+	rec1 := Record{"1", "2017-12-01T00:00:00", "0.0", "0.0", "10.0", "0.0", "", "", "", "", "", "", "", "", "", ""}
+	rec2 := Record{"2", "2017-12-01T00:00:00", "0.0333333", "0.0166667", "10.0", "270.0", "", "", "", "", "", "", "", "", "", ""}
+	row, err := inter.row(Hash128{}, &RecordPair{rec1: &rec1, rec2: &rec2})
+	if err != nil {
+		t.Fatalf("Interactions.row() error = %v", err)
+	}
+	want := []string{"0.71", "9.0", "26.6", "1.00", "0.233"}
+	for i, w := range want {
+		if row[2+i] != w {
+			t.Errorf("Interactions.row() field %s = %q, want %q", inter.OutputHeaders.Fields[2+i], row[2+i], w)
+		}
+	}
+
+	inter.SetCPA(true)
+	inter.SetRisk(false)
+	if got := len(inter.OutputHeaders.Fields); got != n+2 {
+		t.Errorf("Interactions.SetRisk(false) with CPA output fields = %d, want %d", got, n+2)
+	}
+}
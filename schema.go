@@ -0,0 +1,219 @@
+package ais
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// FieldDomain is the set of valid values of one field of a Record.  A value is
+// valid when it is a number in [Min, Max] or one of Special, and when Digits is
+// set, a string of exactly Digits decimal digits.
+type FieldDomain struct {
+	Min, Max   float64
+	Special    []float64 // valid values outside [Min, Max], such as a Heading of 511
+	Integer    bool      // the value must be a whole number, which may be written 352.0
+	Digits     int       // the value must be this many decimal digits, zero for any number
+	AllowEmpty bool      // an empty value is valid
+}
+
+// Valid reports whether s is in the domain.
+func (d FieldDomain) Valid(s string) bool {
+	if s == "" {
+		return d.AllowEmpty
+	}
+	if d.Digits > 0 {
+		if len(s) != d.Digits {
+			return false
+		}
+		for i := 0; i < len(s); i++ {
+			if s[i] < '0' || s[i] > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return false
+	}
+	for _, v := range d.Special {
+		if f == v {
+			return true
+		}
+	}
+	if d.Integer && f != math.Trunc(f) {
+		return false
+	}
+	return f >= d.Min && f <= d.Max
+}
+
+// Schema maps field names to their domains.  Fields of a Schema that are not in
+// the Headers of a RecordSet are not checked.
+type Schema map[string]FieldDomain
+
+// DefaultSchema returns the domains of the fields of ITU-R M.1371 position
+// reports: an MMSI of nine digits, a LAT in [-90, 90] and LON in [-180, 180], a
+// SOG in [0, 102.2], a COG in [0, 359.9], and a whole Heading in [0, 359] or 511
+// for not available.  An empty SOG, COG, or Heading is valid, since many
+// archives write missing values that way.
+func DefaultSchema() Schema {
+	return Schema{
+		"MMSI":    {Digits: 9},
+		"LAT":     {Min: -90, Max: 90},
+		"LON":     {Min: -180, Max: 180},
+		"SOG":     {Min: 0, Max: 102.2, AllowEmpty: true},
+		"COG":     {Min: 0, Max: 359.9, AllowEmpty: true},
+		"Heading": {Min: 0, Max: 359, Special: []float64{511}, Integer: true, AllowEmpty: true},
+	}
+}
+
+// Check returns the names of the fields of rec, described by h, whose values
+// are not in their domains, in the order of the Headers.  A field missing from
+// the end of a short Record is invalid.
+func (s Schema) Check(rec Record, h Headers) []string {
+	var invalid []string
+	for _, f := range s.fields(h) {
+		if f.index >= len(rec) || !f.domain.Valid(rec[f.index]) {
+			invalid = append(invalid, f.name)
+		}
+	}
+	return invalid
+}
+
+// schemaField is a field of a Schema found in a set of Headers.
+type schemaField struct {
+	name   string
+	index  int
+	domain FieldDomain
+}
+
+// fields returns the fields of s found in h in the order of h.
+func (s Schema) fields(h Headers) []schemaField {
+	var fields []schemaField
+	for name, d := range s {
+		if i, ok := h.Contains(name); ok {
+			fields = append(fields, schemaField{name, i, d})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].index < fields[j].index })
+	return fields
+}
+
+// InvalidAction is what RecordSet.ValidateSchema does with a value that is not
+// in its domain.
+type InvalidAction int
+
+const (
+	// KeepInvalid keeps invalid values, so ValidateSchema only counts them.
+	KeepInvalid InvalidAction = iota
+
+	// NullInvalid replaces invalid values with an empty string and keeps the
+	// Record.
+	NullInvalid
+
+	// DropInvalid drops every Record with an invalid value.
+	DropInvalid
+)
+
+var invalidActionNames = [...]string{
+	KeepInvalid: "keep",
+	NullInvalid: "null",
+	DropInvalid: "drop",
+}
+
+// String implements the Stringer interface for InvalidAction.
+func (a InvalidAction) String() string {
+	if a < 0 || int(a) >= len(invalidActionNames) {
+		return fmt.Sprintf("InvalidAction(%d)", int(a))
+	}
+	return invalidActionNames[a]
+}
+
+// ValidationReport tallies the values found outside their domains by
+// RecordSet.ValidateSchema.  A Record with several invalid values is counted
+// once in Invalid and once for each field in Fields.
+type ValidationReport struct {
+	Records int            // Records read
+	Invalid int            // Records with at least one invalid value
+	Dropped int            // Records dropped by DropInvalid
+	Fields  map[string]int // invalid values by field name
+}
+
+// String implements the Stringer interface for ValidationReport.
+func (r *ValidationReport) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d of %d records invalid, %d dropped\n", r.Invalid, r.Records, r.Dropped)
+	names := make([]string, 0, len(r.Fields))
+	for name := range r.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\t%s: %d\n", name, r.Fields[name])
+	}
+	return buf.String()
+}
+
+// ValidateSchema returns a pointer to a new RecordSet with the invalid values of
+// each Record, those outside their domain in s, kept, replaced with an empty
+// string, or dropped with their Record as chosen by action, and a
+// ValidationReport of the invalid values of each field.  Fields of s that are
+// not in the Headers are not checked.  Use DefaultSchema for the domains of the
+// kinematic fields and MMSI.  ValidateSchema consumes the receiver.
+func (rs *RecordSet) ValidateSchema(s Schema, action InvalidAction) (*RecordSet, *ValidationReport, error) {
+	if action < KeepInvalid || action > DropInvalid {
+		return nil, nil, fmt.Errorf("validate schema: unknown action %v", action)
+	}
+	h := rs.Headers()
+	fields := s.fields(h)
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(h)
+	report := &ValidationReport{Fields: make(map[string]int)}
+	written := 0
+	for {
+		rec, err := rs.read(true) // each Record is written before the next is read
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("validate schema: read error on csv file: %w", err)
+		}
+		report.Records++
+		invalid := false
+		for _, f := range fields {
+			if f.index < len(*rec) && f.domain.Valid((*rec)[f.index]) {
+				continue
+			}
+			invalid = true
+			report.Fields[f.name]++
+			if action == NullInvalid && f.index < len(*rec) {
+				(*rec)[f.index] = ""
+			}
+		}
+		if invalid {
+			report.Invalid++
+			if action == DropInvalid {
+				report.Dropped++
+				continue
+			}
+		}
+		if err := rs2.Write(*rec); err != nil {
+			return nil, nil, fmt.Errorf("validate schema: csv write error: %w", err)
+		}
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs2.Flush(); err != nil {
+				return nil, nil, fmt.Errorf("validate schema: csv flush error: %w", err)
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, nil, fmt.Errorf("validate schema: csv flush error: %w", err)
+	}
+	return rs2, report, nil
+}
@@ -0,0 +1,106 @@
+package ais
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFieldDomain_Valid(t *testing.T) {
+	s := DefaultSchema()
+	tests := []struct {
+		field, value string
+		want         bool
+	}{
+		{"MMSI", "477307901", true},
+		{"MMSI", "47730790", false},
+		{"MMSI", "47730790x", false},
+		{"LAT", "-90", true},
+		{"LAT", "91", false},
+		{"LON", "-180.00000", true},
+		{"LON", "abc", false},
+		{"SOG", "102.2", true},
+		{"SOG", "102.3", false},
+		{"SOG", "", true},
+		{"COG", "-0.1", false},
+		{"Heading", "352.0", true},
+		{"Heading", "511", true},
+		{"Heading", "360", false},
+		{"Heading", "12.5", false},
+		{"LAT", "", false},
+	}
+	for _, tt := range tests {
+		if got := s[tt.field].Valid(tt.value); got != tt.want {
+			t.Errorf("%s.Valid(%q) = %v, want %v", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRecordSet_ValidateSchema(t *testing.T) {
+	const data = "MMSI,BaseDateTime,LAT,LON,SOG,Heading\n" +
+		"477307901,2017-12-01T00:00:01,31.90512,-76.32652,0.0,352.0\n" +
+		"12345,2017-12-01T00:00:02,91.00000,-73.74403,37.7,511\n" +
+		"338029922,2017-12-01T00:00:03,43.60792,-74.20417,110.0,400\n"
+
+	tests := []struct {
+		action  InvalidAction
+		want    []string
+		dropped int
+	}{
+		{KeepInvalid, []string{
+			"477307901,2017-12-01T00:00:01,31.90512,-76.32652,0.0,352.0",
+			"12345,2017-12-01T00:00:02,91.00000,-73.74403,37.7,511",
+			"338029922,2017-12-01T00:00:03,43.60792,-74.20417,110.0,400",
+		}, 0},
+		{NullInvalid, []string{
+			"477307901,2017-12-01T00:00:01,31.90512,-76.32652,0.0,352.0",
+			",2017-12-01T00:00:02,,-73.74403,37.7,511",
+			"338029922,2017-12-01T00:00:03,43.60792,-74.20417,,",
+		}, 0},
+		{DropInvalid, []string{
+			"477307901,2017-12-01T00:00:01,31.90512,-76.32652,0.0,352.0",
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action.String(), func(t *testing.T) {
+			rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs2, report, err := rs.ValidateSchema(DefaultSchema(), tt.action)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for {
+				rec, err := rs2.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, strings.Join(*rec, ","))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("ValidateSchema(%v) =\n%s\nwant\n%s", tt.action, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if report.Records != 3 || report.Invalid != 2 || report.Dropped != tt.dropped {
+				t.Errorf("report = %+v", report)
+			}
+			for field, n := range map[string]int{"MMSI": 1, "LAT": 1, "SOG": 1, "Heading": 1} {
+				if report.Fields[field] != n {
+					t.Errorf("report.Fields[%s] = %d, want %d", field, report.Fields[field], n)
+				}
+			}
+		})
+	}
+}
+
+func TestSchema_Check(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "LAT", "LON"}}
+	got := DefaultSchema().Check(Record{"12345", "91", "0"}, h)
+	if strings.Join(got, ",") != "MMSI,LAT" {
+		t.Errorf("Check() = %v, want [MMSI LAT]", got)
+	}
+}
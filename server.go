@@ -0,0 +1,389 @@
+package ais
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxUpload is the largest RecordSet in bytes accepted by a Server with a
+// zero MaxUpload.
+const DefaultMaxUpload = 1 << 30
+
+// Defaults for the zero values of the storage limits of a Server.
+const (
+	DefaultMaxStore   = 4 << 30 // bytes of RecordSets held at once
+	DefaultMaxResults = 64      // Interactions held at once
+)
+
+// Server is an http.Handler that exposes interaction detection as a REST API so
+// that programs in other languages can use the package over HTTP.  Uploaded
+// RecordSets and the Interactions found in them are held in memory until they
+// are deleted or, once MaxStore bytes of RecordSets or MaxResults Interactions
+// are held, evicted least recently used first to make room for a new one.  A
+// request for an evicted resource fails with 404 Not Found.  The endpoints are
+//
+//	POST   /recordsets                    upload a csv RecordSet, optionally gzip encoded
+//	DELETE /recordsets/{id}
+//	POST   /recordsets/{id}/interactions  run RecordSet.FindInteractions
+//	GET    /interactions/{id}             fetch the Interactions
+//	DELETE /interactions/{id}
+//
+// The interactions endpoint accepts the query parameters window, slide, and
+// timegap as durations such as 10m, precision as geohash bits, distance in
+// nautical miles, and cpa, risk, and encounter as booleans that add the optional
+// output columns.  Interactions are returned as csv, json, jsonl, or geojson as
+// selected by the format query parameter, json by default.  Successful uploads
+// and runs respond with a JSON object holding the id of the new resource, and
+// errors with a JSON object holding an error message.
+type Server struct {
+	MaxUpload  int64 // bytes, DefaultMaxUpload when zero
+	MaxStore   int64 // bytes of every stored RecordSet, DefaultMaxStore when zero
+	MaxResults int   // stored Interactions, DefaultMaxResults when zero
+
+	mu      sync.Mutex
+	sets    map[string]*storedSet
+	results map[string]*storedResult
+	stored  int64  // bytes held in sets
+	clock   uint64 // incremented on every store and use of a resource
+}
+
+// storedSet is an uploaded RecordSet and the time it was last used.
+type storedSet struct {
+	data []byte
+	used uint64
+}
+
+// storedResult is a set of Interactions and the time it was last used.
+type storedResult struct {
+	inter *Interactions
+	used  uint64
+}
+
+// NewServer returns a *Server with no RecordSets.
+func NewServer() *Server {
+	return &Server{
+		sets:    make(map[string]*storedSet),
+		results: make(map[string]*storedResult),
+	}
+}
+
+func (s *Server) maxStore() int64 {
+	if s.MaxStore <= 0 {
+		return DefaultMaxStore
+	}
+	return s.MaxStore
+}
+
+// storeSet holds data under id after evicting the least recently used
+// RecordSets that would take the total over MaxStore.
+func (s *Server) storeSet(id string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.sets) > 0 && s.stored+int64(len(data)) > s.maxStore() {
+		oldest := ""
+		for k, set := range s.sets {
+			if oldest == "" || set.used < s.sets[oldest].used {
+				oldest = k
+			}
+		}
+		s.stored -= int64(len(s.sets[oldest].data))
+		delete(s.sets, oldest)
+	}
+	s.clock++
+	s.sets[id] = &storedSet{data: data, used: s.clock}
+	s.stored += int64(len(data))
+}
+
+// storeResult holds inter under id after evicting the least recently used
+// Interactions that would take the count over MaxResults.
+func (s *Server) storeResult(id string, inter *Interactions) {
+	max := s.MaxResults
+	if max <= 0 {
+		max = DefaultMaxResults
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.results) > 0 && len(s.results) >= max {
+		oldest := ""
+		for k, res := range s.results {
+			if oldest == "" || res.used < s.results[oldest].used {
+				oldest = k
+			}
+		}
+		delete(s.results, oldest)
+	}
+	s.clock++
+	s.results[id] = &storedResult{inter: inter, used: s.clock}
+}
+
+// set returns the RecordSet stored under id and marks it used.
+func (s *Server) set(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	set, ok := s.sets[id]
+	if !ok {
+		return nil, false
+	}
+	s.clock++
+	set.used = s.clock
+	return set.data, true
+}
+
+// result returns the Interactions stored under id and marks them used.
+func (s *Server) result(id string) (*Interactions, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.results[id]
+	if !ok {
+		return nil, false
+	}
+	s.clock++
+	res.used = s.clock
+	return res.inter, true
+}
+
+// serverError is the body of every error response.
+type serverError struct {
+	Error string `json:"error"`
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func httpError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSONResponse(w, status, serverError{fmt.Sprintf(format, args...)})
+}
+
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "recordsets" && r.Method == "POST":
+		s.upload(w, r)
+	case len(parts) == 2 && parts[0] == "recordsets" && r.Method == "DELETE":
+		s.delete(w, parts[1], true)
+	case len(parts) == 3 && parts[0] == "recordsets" && parts[2] == "interactions" && r.Method == "POST":
+		s.find(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "interactions" && r.Method == "GET":
+		s.fetch(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "interactions" && r.Method == "DELETE":
+		s.delete(w, parts[1], false)
+	default:
+		httpError(w, http.StatusNotFound, "no endpoint for %s %s", r.Method, r.URL.Path)
+	}
+}
+
+// upload stores a csv RecordSet after checking that every Record can be read.
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	max := s.MaxUpload
+	if max <= 0 {
+		max = DefaultMaxUpload
+	}
+	var body io.Reader = http.MaxBytesReader(w, r.Body, max)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "upload: %v", err)
+			return
+		}
+		defer zr.Close()
+		body = io.LimitReader(zr, max+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "upload: %v", err)
+		return
+	}
+	if int64(len(data)) > max {
+		httpError(w, http.StatusRequestEntityTooLarge, "upload: recordset is larger than %d bytes", max)
+		return
+	}
+	if int64(len(data)) > s.maxStore() {
+		httpError(w, http.StatusRequestEntityTooLarge, "upload: recordset is larger than the %d bytes stored", s.maxStore())
+		return
+	}
+
+	rs, err := NewRecordSetFromReader(bytes.NewReader(data), Headers{})
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "upload: %v", err)
+		return
+	}
+	if _, err := rs.Headers().require("MMSI", "BaseDateTime", "LAT", "LON"); err != nil {
+		httpError(w, http.StatusBadRequest, "upload: %v", err)
+		return
+	}
+	n := 0
+	for rs.Next() {
+		n++
+	}
+	if err := rs.Err(); err != nil {
+		httpError(w, http.StatusBadRequest, "upload: %v", err)
+		return
+	}
+
+	id := newID()
+	s.storeSet(id, data)
+	writeJSONResponse(w, http.StatusCreated, struct {
+		ID      string   `json:"id"`
+		Records int      `json:"records"`
+		Headers []string `json:"headers"`
+	}{id, n, rs.Headers().Fields})
+}
+
+func (s *Server) delete(w http.ResponseWriter, id string, recordset bool) {
+	s.mu.Lock()
+	var ok bool
+	if recordset {
+		var set *storedSet
+		if set, ok = s.sets[id]; ok {
+			s.stored -= int64(len(set.data))
+		}
+		delete(s.sets, id)
+	} else {
+		_, ok = s.results[id]
+		delete(s.results, id)
+	}
+	s.mu.Unlock()
+	if !ok {
+		httpError(w, http.StatusNotFound, "%s not found", id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// interactionParams parses the query parameters of the interactions endpoint.
+func interactionParams(q url.Values) (InteractionParams, []InteractionOption, error) {
+	var p InteractionParams
+	var opts []InteractionOption
+	var err error
+	duration := func(name string) time.Duration {
+		if v := q.Get(name); v != "" && err == nil {
+			var d time.Duration
+			if d, err = time.ParseDuration(v); err != nil {
+				err = fmt.Errorf("%s: %w", name, err)
+			}
+			return d
+		}
+		return 0
+	}
+	p.Window = duration("window")
+	p.Slide = duration("slide")
+	if gap := duration("timegap"); gap != 0 {
+		opts = append(opts, WithMaxTimeGap(gap))
+	}
+	if v := q.Get("precision"); v != "" && err == nil {
+		var bits uint64
+		if bits, err = strconv.ParseUint(v, 10, 8); err != nil {
+			err = fmt.Errorf("precision: %w", err)
+		}
+		p.Precision = uint(bits)
+	}
+	if v := q.Get("distance"); v != "" && err == nil {
+		var nm float64
+		if nm, err = strconv.ParseFloat(v, 64); err != nil {
+			err = fmt.Errorf("distance: %w", err)
+		}
+		opts = append(opts, WithMaxDistance(nm))
+	}
+	return p, opts, err
+}
+
+// find runs FindInteractions on an uploaded RecordSet and stores the result.
+func (s *Server) find(w http.ResponseWriter, r *http.Request, id string) {
+	data, ok := s.set(id)
+	if !ok {
+		httpError(w, http.StatusNotFound, "recordset %s not found", id)
+		return
+	}
+	q := r.URL.Query()
+	p, opts, err := interactionParams(q)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "interactions: %v", err)
+		return
+	}
+
+	rs, err := NewRecordSetFromReader(bytes.NewReader(data), Headers{})
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, "interactions: %v", err)
+		return
+	}
+	inter, err := rs.FindInteractions(r.Context(), p, opts...)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "interactions: %v", err)
+		return
+	}
+	for name, set := range map[string]func(bool){"cpa": inter.SetCPA, "risk": inter.SetRisk, "encounter": inter.SetEncounter} {
+		if on, _ := strconv.ParseBool(q.Get(name)); on {
+			set(true)
+		}
+	}
+
+	resultID := newID()
+	s.storeResult(resultID, inter)
+	writeJSONResponse(w, http.StatusCreated, struct {
+		ID           string `json:"id"`
+		Interactions int    `json:"interactions"`
+	}{resultID, inter.Len()})
+}
+
+// fetch writes stored Interactions in the requested format.
+func (s *Server) fetch(w http.ResponseWriter, r *http.Request, id string) {
+	inter, ok := s.result(id)
+	if !ok {
+		httpError(w, http.StatusNotFound, "interactions %s not found", id)
+		return
+	}
+
+	// Buffer the response so that an error can still be reported with a status.
+	var buf bytes.Buffer
+	var err error
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		err = inter.writeCSV(r.Context(), &buf)
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err = inter.writeJSONL(&buf)
+	case "geojson":
+		w.Header().Set("Content-Type", "application/geo+json")
+		var idx map[string]HeaderMap
+		if idx, err = inter.RecordHeaders.require("LAT", "LON"); err != nil {
+			break
+		}
+		err = inter.writeGeoJSON(&buf, idx["LAT"].Idx, idx["LON"].Idx)
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		err = inter.writeJSON(&buf)
+	default:
+		httpError(w, http.StatusBadRequest, "unknown format %q", format)
+		return
+	}
+	if err != nil {
+		w.Header().Del("Content-Type")
+		httpError(w, http.StatusInternalServerError, "interactions: %v", err)
+		return
+	}
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,151 @@
+package ais
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	srv := httptest.NewServer(NewServer())
+	defer srv.Close()
+
+	do := func(method, path string, body []byte, gz bool) (int, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, bytes.NewReader(body))
+		if gz {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var b bytes.Buffer
+		b.ReadFrom(resp.Body)
+		return resp.StatusCode, b.Bytes()
+	}
+
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	zw.Write([]byte(detectData))
+	zw.Close()
+	status, body := do("POST", "/recordsets", zipped.Bytes(), true)
+	var upload struct {
+		ID      string `json:"id"`
+		Records int    `json:"records"`
+	}
+	json.Unmarshal(body, &upload)
+	if status != http.StatusCreated || upload.Records != 5 {
+		t.Fatalf("POST /recordsets = %d %s, want 201 with 5 records", status, body)
+	}
+
+	status, body = do("POST", "/recordsets/"+upload.ID+"/interactions?window=2m&slide=1m&cpa=true", nil, false)
+	var run struct {
+		ID           string `json:"id"`
+		Interactions int    `json:"interactions"`
+	}
+	json.Unmarshal(body, &run)
+	if status != http.StatusCreated || run.Interactions != 4 {
+		t.Fatalf("POST interactions = %d %s, want 201 with 4 interactions", status, body)
+	}
+
+	status, body = do("GET", "/interactions/"+run.ID, nil, false)
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil || status != http.StatusOK || len(rows) != 4 {
+		t.Fatalf("GET interactions = %d %s", status, body)
+	}
+	if _, ok := rows[0]["CPA(nm)"].(float64); !ok {
+		t.Errorf("GET interactions CPA(nm) = %#v, want a number", rows[0]["CPA(nm)"])
+	}
+	for _, format := range []string{"csv", "jsonl", "geojson"} {
+		if status, body = do("GET", "/interactions/"+run.ID+"?format="+format, nil, false); status != http.StatusOK {
+			t.Errorf("GET interactions format %s = %d %s", format, status, body)
+		}
+	}
+	if status, _ = do("GET", "/interactions/"+run.ID+"?format=xml", nil, false); status != http.StatusBadRequest {
+		t.Errorf("GET interactions format xml = %d, want 400", status)
+	}
+
+	errs := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/recordsets", "MMSI,LAT\n1,2\n", http.StatusBadRequest},
+		{"POST", "/recordsets/" + upload.ID + "/interactions?window=ten", "", http.StatusBadRequest},
+		{"POST", "/recordsets/nope/interactions", "", http.StatusNotFound},
+		{"GET", "/recordsets", "", http.StatusNotFound},
+		{"DELETE", "/interactions/" + run.ID, "", http.StatusNoContent},
+		{"GET", "/interactions/" + run.ID, "", http.StatusNotFound},
+		{"DELETE", "/recordsets/" + upload.ID, "", http.StatusNoContent},
+		{"DELETE", "/recordsets/" + upload.ID, "", http.StatusNotFound},
+	}
+	for _, e := range errs {
+		status, body := do(e.method, e.path, []byte(e.body), false)
+		if status != e.want {
+			t.Errorf("%s %s = %d %s, want %d", e.method, e.path, status, body, e.want)
+		}
+		if status >= 400 && !strings.Contains(string(body), `"error"`) {
+			t.Errorf("%s %s body = %s, want an error object", e.method, e.path, body)
+		}
+	}
+
+	limited := NewServer()
+	limited.MaxUpload = 10
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, httptest.NewRequest("POST", "/recordsets", strings.NewReader(detectData)))
+	if rec.Code == http.StatusCreated {
+		t.Errorf("POST /recordsets over MaxUpload = %d, want an error", rec.Code)
+	}
+}
+
+func TestServer_Eviction(t *testing.T) {
+	srv := NewServer()
+	srv.MaxStore = int64(2*len(detectData) + 1)
+	srv.MaxResults = 1
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var resp struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.ID
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		status, id := do("POST", "/recordsets", detectData)
+		if status != http.StatusCreated {
+			t.Fatalf("POST /recordsets = %d, want 201", status)
+		}
+		ids = append(ids, id)
+		if i == 1 {
+			// Using the first RecordSet leaves the second least recently used.
+			if status, _ := do("POST", "/recordsets/"+ids[0]+"/interactions", ""); status != http.StatusCreated {
+				t.Fatalf("POST interactions = %d, want 201", status)
+			}
+		}
+	}
+	if srv.stored > srv.MaxStore {
+		t.Errorf("Server stored %d bytes, want at most MaxStore %d", srv.stored, srv.MaxStore)
+	}
+	for i, want := range []int{http.StatusCreated, http.StatusNotFound, http.StatusCreated} {
+		if status, _ := do("POST", "/recordsets/"+ids[i]+"/interactions", ""); status != want {
+			t.Errorf("POST interactions on recordset %d = %d, want %d", i, status, want)
+		}
+	}
+	if len(srv.results) != 1 {
+		t.Errorf("Server holds %d interactions, want MaxResults 1", len(srv.results))
+	}
+
+	srv.MaxStore = int64(len(detectData) - 1)
+	if status, _ := do("POST", "/recordsets", detectData); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST /recordsets over MaxStore = %d, want 413", status)
+	}
+}
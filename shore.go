@@ -0,0 +1,312 @@
+package ais
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ShoreDistanceField is the conventional name of the field appended by a
+// ShoreDistancer, the distance in nautical miles to the nearest coast.
+const ShoreDistanceField = "ShoreDistance(nm)"
+
+// coastCell is the size in degrees of the cells of the Coastline index.
+const coastCell = 0.25
+
+// Coastline is a set of shoreline segments, such as the GSHHG or Natural Earth
+// coastlines, that answers the distance from a position to the nearest coast
+// without a round trip through a spatial database.  Segments are indexed by
+// cells of a quarter degree, so a query reads only the segments near the
+// position.  Like a Geofence, a Coastline treats longitude and latitude as
+// planar within a cell and does not support lines that cross the antimeridian.
+type Coastline struct {
+	segs  [][4]float64 // lon1, lat1, lon2, lat2
+	cells map[[2]int][]int32
+	// bounds of the cells in the index, lat then lon
+	minI, maxI, minJ, maxJ int
+}
+
+// NewCoastlineWKT returns a Coastline from the Well-Known Text representation of
+// a LINESTRING, MULTILINESTRING, POLYGON, or MULTIPOLYGON.  The rings of polygons,
+// such as land masses, are used as lines.
+func NewCoastlineWKT(wkt string) (*Coastline, error) {
+	p := &wktParser{s: strings.TrimSpace(wkt)}
+	kind := strings.ToUpper(p.word())
+
+	var lines [][][2]float64
+	var err error
+	switch kind {
+	case "LINESTRING":
+		var line [][2]float64
+		line, err = p.ring()
+		lines = append(lines, line)
+	case "MULTILINESTRING", "POLYGON":
+		lines, err = p.polygon()
+	case "MULTIPOLYGON":
+		if err = p.expect('('); err != nil {
+			break
+		}
+		for {
+			var rings [][][2]float64
+			if rings, err = p.polygon(); err != nil {
+				break
+			}
+			lines = append(lines, rings...)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+		if err == nil {
+			err = p.expect(')')
+		}
+	default:
+		return nil, fmt.Errorf("coastline: wkt: unsupported geometry %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("coastline: wkt: %w", err)
+	}
+	if p.peek() != 0 {
+		return nil, fmt.Errorf("coastline: wkt: unexpected text at offset %d", p.pos)
+	}
+	return newCoastline(lines)
+}
+
+// NewCoastlineGeoJSON returns a Coastline from a GeoJSON LineString,
+// MultiLineString, Polygon, or MultiPolygon geometry, a Feature with one of those
+// geometries, or a FeatureCollection of such Features.  The rings of polygons
+// are used as lines.
+func NewCoastlineGeoJSON(data []byte) (*Coastline, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("coastline: geojson: %w", err)
+	}
+	lines, err := obj.lines()
+	if err != nil {
+		return nil, fmt.Errorf("coastline: geojson: %w", err)
+	}
+	return newCoastline(lines)
+}
+
+func (obj *geoJSONObject) lines() ([][][2]float64, error) {
+	switch obj.Type {
+	case "LineString":
+		var line [][]float64
+		if err := json.Unmarshal(obj.Coordinates, &line); err != nil {
+			return nil, err
+		}
+		return geoJSONRings([][][]float64{line})
+	case "MultiLineString":
+		var multi [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &multi); err != nil {
+			return nil, err
+		}
+		return geoJSONRings(multi)
+	case "Polygon", "MultiPolygon":
+		polys, err := obj.polygons()
+		if err != nil {
+			return nil, err
+		}
+		var lines [][][2]float64
+		for _, rings := range polys {
+			lines = append(lines, rings...)
+		}
+		return lines, nil
+	case "Feature":
+		if obj.Geometry == nil {
+			return nil, fmt.Errorf("feature has no geometry")
+		}
+		return obj.Geometry.lines()
+	case "FeatureCollection":
+		var lines [][][2]float64
+		for i := range obj.Features {
+			l, err := obj.Features[i].lines()
+			if err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			lines = append(lines, l...)
+		}
+		return lines, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", obj.Type)
+}
+
+// newCoastline splits the lines into segments and indexes each segment in every
+// cell its bounding box touches.
+func newCoastline(lines [][][2]float64) (*Coastline, error) {
+	c := &Coastline{cells: make(map[[2]int][]int32)}
+	c.minI, c.minJ = math.MaxInt32, math.MaxInt32
+	c.maxI, c.maxJ = math.MinInt32, math.MinInt32
+	for _, line := range lines {
+		if len(line) < 2 {
+			return nil, fmt.Errorf("coastline: line must have at least two vertices")
+		}
+		for k := 1; k < len(line); k++ {
+			a, b := line[k-1], line[k]
+			n := int32(len(c.segs))
+			c.segs = append(c.segs, [4]float64{a[0], a[1], b[0], b[1]})
+			i1, j1 := coastCellOf(math.Min(a[1], b[1]), math.Min(a[0], b[0]))
+			i2, j2 := coastCellOf(math.Max(a[1], b[1]), math.Max(a[0], b[0]))
+			for i := i1; i <= i2; i++ {
+				for j := j1; j <= j2; j++ {
+					c.cells[[2]int{i, j}] = append(c.cells[[2]int{i, j}], n)
+				}
+			}
+			c.minI, c.maxI = minInt(c.minI, i1), maxInt(c.maxI, i2)
+			c.minJ, c.maxJ = minInt(c.minJ, j1), maxInt(c.maxJ, j2)
+		}
+	}
+	if len(c.segs) == 0 {
+		return nil, fmt.Errorf("coastline: no lines")
+	}
+	return c, nil
+}
+
+func coastCellOf(lat, lon float64) (int, int) {
+	return int(math.Floor(lat / coastCell)), int(math.Floor(lon / coastCell))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Len returns the number of segments in the Coastline.
+func (c *Coastline) Len() int { return len(c.segs) }
+
+// Distance returns the distance in nautical miles from the position to the
+// nearest point of the Coastline.  The nearest point is found on a plane scaled
+// by the cosine of lat, which is exact enough to rank segments within the few
+// cells around the position, and the distance to it is the Haversine distance.
+// Cells are searched in rings around the position until no unread cell can hold
+// a nearer segment, so positions far offshore read more of the index.
+func (c *Coastline) Distance(lat, lon float64) float64 {
+	kx := math.Max(math.Cos(lat*math.Pi/180), 0.01) // scale of a degree of longitude
+	i0, j0 := coastCellOf(lat, lon)
+	best, bestLat, bestLon := math.Inf(1), 0.0, 0.0
+	seen := make(map[int32]bool)
+	visit := func(i, j int) {
+		for _, n := range c.cells[[2]int{i, j}] {
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			s := c.segs[n]
+			// Project onto the segment in planar nm relative to the position.
+			ax, ay := (s[0]-lon)*kx, s[1]-lat
+			bx, by := (s[2]-lon)*kx, s[3]-lat
+			dx, dy := bx-ax, by-ay
+			f := 0.0
+			if l2 := dx*dx + dy*dy; l2 > 0 {
+				f = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l2))
+			}
+			x, y := ax+f*dx, ay+f*dy
+			if d := math.Hypot(x, y) * 60; d < best {
+				best, bestLat, bestLon = d, s[1]+f*(s[3]-s[1]), s[0]+f*(s[2]-s[0])
+			}
+		}
+	}
+	maxR := maxInt(maxInt(abs(i0-c.minI), abs(i0-c.maxI)), maxInt(abs(j0-c.minJ), abs(j0-c.maxJ)))
+	for r := 0; r <= maxR; r++ {
+		// Every segment not yet read lies beyond r-1 whole cells of latitude or
+		// longitude from the position.
+		if float64(r-1)*coastCell*60*kx > best {
+			break
+		}
+		for j := j0 - r; j <= j0+r; j++ {
+			visit(i0-r, j)
+			if r > 0 {
+				visit(i0+r, j)
+			}
+		}
+		for i := i0 - r + 1; i <= i0+r-1; i++ {
+			visit(i, j0-r)
+			visit(i, j0+r)
+		}
+	}
+	return Haversine(lat, lon, bestLat, bestLon)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// DistanceToShore returns the distance in nautical miles from the LAT and LON
+// of the Record described by h to the nearest point of the Coastline.
+func (r Record) DistanceToShore(h Headers, c *Coastline) (float64, error) {
+	lat, lon, err := r.LatLon(h)
+	if err != nil {
+		return 0, err
+	}
+	return c.Distance(lat, lon), nil
+}
+
+// ShoreDistancer implements the Generator interface to append the distance to
+// the nearest coast of each Record, in nautical miles to two decimal places.
+// Pass it to AppendField with the required headers LAT and LON, in that order:
+//
+//	rs2, err := rs.AppendField(ais.ShoreDistanceField, []string{"LAT", "LON"},
+//		ais.ShoreDistancer{Coast: coast})
+type ShoreDistancer struct {
+	Coast *Coastline
+}
+
+// Generate implements the Generator interface for a ShoreDistancer.
+func (sd ShoreDistancer) Generate(rec Record, index ...int) (Field, error) {
+	if len(index) != 2 {
+		return "", fmt.Errorf("shore distancer: want the LAT and LON indices, got %d indices", len(index))
+	}
+	lat, err := rec.ParseFloat(index[0])
+	if err != nil {
+		return "", fmt.Errorf("shore distancer: %w", ErrParse{Field: "LAT", Err: err})
+	}
+	lon, err := rec.ParseFloat(index[1])
+	if err != nil {
+		return "", fmt.Errorf("shore distancer: %w", ErrParse{Field: "LON", Err: err})
+	}
+	return Field(strconv.FormatFloat(sd.Coast.Distance(lat, lon), 'f', 2, 64)), nil
+}
+
+// shoreMatch implements Matching for NearShore.
+type shoreMatch struct {
+	coast              *Coastline
+	nm                 float64
+	latIndex, lonIndex int
+}
+
+func (m shoreMatch) Match(rec *Record) (bool, error) {
+	lat, err := rec.ParseFloat(m.latIndex)
+	if err != nil {
+		return false, ErrParse{Field: "LAT", Err: err}
+	}
+	lon, err := rec.ParseFloat(m.lonIndex)
+	if err != nil {
+		return false, ErrParse{Field: "LON", Err: err}
+	}
+	return m.coast.Distance(lat, lon) <= m.nm, nil
+}
+
+// NearShore returns a pointer to a new RecordSet with the Records of rs that are
+// within nm nautical miles of the Coastline.  The Headers must contain LAT and
+// LON.  NearShore consumes rs.
+func (rs *RecordSet) NearShore(c *Coastline, nm float64) (*RecordSet, error) {
+	idx, err := rs.Headers().require("LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("near shore: %w", err)
+	}
+	return rs.Subset(shoreMatch{coast: c, nm: nm, latIndex: idx["LAT"].Idx, lonIndex: idx["LON"].Idx})
+}
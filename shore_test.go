@@ -0,0 +1,102 @@
+package ais
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// testCoast is a north-south shoreline along 76W from 36N to 37N with a cape
+// running east along 37N to 75.5W.
+const testCoast = "LINESTRING (-76 36, -76 37, -75.5 37)"
+
+func TestCoastline_Distance(t *testing.T) {
+	c, err := NewCoastlineWKT(testCoast)
+	if err != nil {
+		t.Fatalf("NewCoastlineWKT() error = %v", err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Coastline.Len() = %d, want 2", c.Len())
+	}
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     float64
+	}{
+		{"on the coast", 36.5, -76, 0},
+		{"east of the coast", 36.5, -75.9, Haversine(36.5, -75.9, 36.5, -76)},
+		{"south of the cape", 36.9, -75.7, Haversine(36.9, -75.7, 37, -75.7)},
+		{"past the southern end", 35, -76, 60.04},
+		{"far offshore", 36.5, -70, Haversine(36.5, -70, 37, -75.5)},
+	}
+	for _, tt := range tests {
+		if got := c.Distance(tt.lat, tt.lon); math.Abs(got-tt.want) > 0.05 {
+			t.Errorf("%s: Coastline.Distance(%v, %v) = %v, want %v", tt.name, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestNewCoastline(t *testing.T) {
+	for _, wkt := range []string{
+		"MULTILINESTRING ((-76 36, -76 37), (-75 36, -75 37))",
+		"POLYGON ((-76 36, -75 36, -75 37, -76 37, -76 36))",
+		"MULTIPOLYGON (((-76 36, -75 36, -75 37, -76 36)), ((-74 36, -73 36, -73 37, -74 36)))",
+	} {
+		if _, err := NewCoastlineWKT(wkt); err != nil {
+			t.Errorf("NewCoastlineWKT(%s) error = %v", wkt, err)
+		}
+	}
+	for _, wkt := range []string{"POINT (-76 36)", "LINESTRING (-76 36)", "LINESTRING (-76 36, -76 37"} {
+		if _, err := NewCoastlineWKT(wkt); err == nil {
+			t.Errorf("NewCoastlineWKT(%s) error = nil, want an error", wkt)
+		}
+	}
+
+	c, err := NewCoastlineGeoJSON([]byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[-76,36],[-76,37]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[-74,36],[-73,36],[-73,37],[-74,36]]]}}]}`))
+	if err != nil {
+		t.Fatalf("NewCoastlineGeoJSON() error = %v", err)
+	}
+	if c.Len() != 4 {
+		t.Errorf("Coastline.Len() = %d, want 4", c.Len())
+	}
+}
+
+func TestRecordSet_NearShore(t *testing.T) {
+	c, _ := NewCoastlineWKT(testCoast)
+	data := "MMSI,LAT,LON\n" +
+		"111111111,36.5,-75.95\n" +
+		"222222222,36.5,-75.0\n" +
+		"333333333,36.9,-75.6\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs2, err := rs.NearShore(c, 12)
+	if err != nil {
+		t.Fatalf("RecordSet.NearShore() error = %v", err)
+	}
+	defer rs2.Close()
+	var got []string
+	for {
+		rec, err := rs2.Read()
+		if err != nil {
+			break
+		}
+		got = append(got, (*rec)[0])
+		if d, err := rec.DistanceToShore(rs2.Headers(), c); err != nil || d > 12 {
+			t.Errorf("Record.DistanceToShore() = %v, %v, want no more than 12", d, err)
+		}
+	}
+	if strings.Join(got, ",") != "111111111,333333333" {
+		t.Errorf("RecordSet.NearShore() = %v, want 111111111 and 333333333", got)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs2, err = rs.AppendField(ShoreDistanceField, []string{"LAT", "LON"}, ShoreDistancer{Coast: c})
+	if err != nil {
+		t.Fatalf("RecordSet.AppendField() error = %v", err)
+	}
+	rec, _ := rs2.Read()
+	if got := (*rec)[3]; got != "2.41" {
+		t.Errorf("ShoreDistancer = %s, want 2.41", got)
+	}
+}
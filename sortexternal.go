@@ -0,0 +1,112 @@
+package ais
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// SortExternal returns a new RecordSet with the remaining Records of rs sorted in
+// ascending order by field, which must be BaseDateTime or MMSI.  Unlike
+// SortByTime, which loads the whole RecordSet into memory, SortExternal is an
+// external merge sort: it sorts runs of DefaultRunSize Records in memory, writes
+// each run to a temporary file in tmpDir, and merges the runs into a temporary
+// file from which the returned RecordSet reads.  Memory use is bounded by the run
+// size, so daily files of tens of gigabytes can be sorted before SlideWindow on a
+// machine with modest RAM.  An empty tmpDir uses the default directory for
+// temporary files, which must have room for twice the size of the data.
+//
+// The sort is stable, so sorting by BaseDateTime and then by MMSI orders every
+// vessel's reports in time.  The returned RecordSet is read only and removes its
+// temporary file when it is closed.  SortExternal consumes the receiver.
+func (rs *RecordSet) SortExternal(field, tmpDir string) (*RecordSet, error) {
+	return rs.sortExternal(field, &mergeConfig{tmpDir: tmpDir, runSize: DefaultRunSize})
+}
+
+func (rs *RecordSet) sortExternal(field string, cfg *mergeConfig) (*RecordSet, error) {
+	key, err := sortKey(rs.Headers(), field)
+	if err != nil {
+		return nil, fmt.Errorf("sort external: %w", err)
+	}
+
+	runs, err := writeRecordRuns(rs, key, cfg)
+	defer func() {
+		for _, name := range runs {
+			os.Remove(name)
+		}
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("sort external: %w", err)
+	}
+
+	f, err := ioutil.TempFile(cfg.tmpDir, "ais-sorted-")
+	if err != nil {
+		return nil, fmt.Errorf("sort external: %w", err)
+	}
+	fail := func(err error) (*RecordSet, error) {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("sort external: %w", err)
+	}
+	w := newRecordWriter(f)
+	if err := mergeRuns(runs, key, w); err != nil {
+		return fail(err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fail(err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return fail(err)
+	}
+
+	sorted, err := NewRecordSetFromReader(tempFile{f}, rs.Headers())
+	if err != nil {
+		return fail(err)
+	}
+	sorted.src = f
+	return sorted, nil
+}
+
+// sortKey returns the function that computes the int64 sort key of a Record for
+// field, which is BaseDateTime or MMSI.
+func sortKey(h Headers, field string) (func(Record) (int64, error), error) {
+	i, ok := h.Contains(field)
+	if !ok {
+		return nil, ErrMissingHeader{Field: field}
+	}
+	switch field {
+	case "BaseDateTime":
+		return func(rec Record) (int64, error) {
+			t, err := h.parseTime(rec[i])
+			if err != nil {
+				return 0, ErrParse{Field: field, Err: err}
+			}
+			return t.UnixNano(), nil
+		}, nil
+	case "MMSI":
+		return func(rec Record) (int64, error) {
+			n, err := strconv.ParseInt(rec[i], 10, 64)
+			if err != nil {
+				return 0, ErrParse{Field: field, Err: err}
+			}
+			return n, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("cannot sort by %s, only by BaseDateTime or MMSI", field)
+}
+
+// tempFile is a temporary file that is removed when it is closed.
+type tempFile struct {
+	*os.File
+}
+
+// Close closes and removes the file.
+func (t tempFile) Close() error {
+	err := t.File.Close()
+	if rerr := os.Remove(t.File.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecordSet_SortExternal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 25 reports from 5 vessels in random order, sorted in runs of 4.
+	var b strings.Builder
+	b.WriteString("MMSI,BaseDateTime,LAT,LON\n")
+	for _, i := range rand.New(rand.NewSource(1)).Perm(25) {
+		fmt.Fprintf(&b, "%09d,2017-12-01T00:%02d:00,30,-76\n", 100000000+i%5, i)
+	}
+	data := b.String()
+
+	for _, field := range []string{"BaseDateTime", "MMSI"} {
+		rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+		sorted, err := rs.sortExternal(field, &mergeConfig{tmpDir: dir, runSize: 4})
+		if err != nil {
+			t.Fatalf("RecordSet.SortExternal(%s) error = %v", field, err)
+		}
+		i, _ := sorted.Headers().Contains(field)
+		var prev string
+		n := 0
+		for {
+			rec, err := sorted.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("RecordSet.Read() error = %v", err)
+			}
+			if (*rec)[i] < prev {
+				t.Errorf("SortExternal(%s) wrote %s after %s", field, (*rec)[i], prev)
+			}
+			prev = (*rec)[i]
+			n++
+		}
+		if n != 25 {
+			t.Errorf("SortExternal(%s) returned %d records, want 25", field, n)
+		}
+		if err := sorted.Close(); err != nil {
+			t.Errorf("RecordSet.Close() error = %v", err)
+		}
+		if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+			t.Errorf("SortExternal(%s) left %d temporary files", field, len(files))
+		}
+	}
+
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if _, err := rs.SortExternal("LAT", dir); err == nil {
+		t.Error("RecordSet.SortExternal() expected error for LAT")
+	}
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,LAT\n1,30\n"), Headers{})
+	if _, err := rs.SortExternal("BaseDateTime", dir); err == nil {
+		t.Error("RecordSet.SortExternal() expected error for missing BaseDateTime header")
+	}
+}
@@ -0,0 +1,128 @@
+package ais
+
+import (
+	"strings"
+	"time"
+)
+
+// Source describes how a Record was transmitted and received.  Reports from
+// Class B transponders and reports received by satellite behave differently
+// from the terrestrial Class A reports that the default limits of cleaning and
+// windowing are tuned for.
+type Source struct {
+	ClassB    bool // transmitted by a Class B transponder
+	Satellite bool // received by a satellite rather than a shore station
+}
+
+// SourceTolerance relaxes the checks applied to the Records of one Source.
+type SourceTolerance struct {
+	// TimeSkew is how far the BaseDateTime of a Record may be from the time the
+	// position was reported.  Satellite reports are stamped with the time they
+	// were downlinked or aggregated, which may be minutes late.  Implied speeds
+	// are computed over the time between fixes plus TimeSkew, and SlideWindow
+	// accepts Records up to TimeSkew out of time order.
+	TimeSkew time.Duration
+
+	// MaxGap is the longest interval expected between reports, which replaces
+	// AnomalyRules.MaxGap when it is longer.  Class B transponders report less
+	// often than Class A, and satellites pass over a vessel only a few times a
+	// day.
+	MaxGap time.Duration
+}
+
+// SourceRules tells Class B and satellite Records apart and holds the
+// tolerances applied to them by CleanKinematics, DetectAnomalies, and
+// SlideWindow, so that a mixed terrestrial and satellite dataset is not
+// over-filtered by limits meant for terrestrial Class A reports.  Set them on
+// the Headers of a RecordSet with Headers.WithSourceRules.  A Record that is
+// both Class B and satellite gets the larger of each tolerance.
+type SourceRules struct {
+	// ClassField is the field holding the transceiver class, A or B, which may
+	// also be written Class A or Class B.  MarineCadastre.gov files from 2018
+	// name it TransceiverClass, and DMAMapping maps it to the Type of mobile
+	// field of the Danish Maritime Authority files.  When the Headers do not
+	// contain ClassField a Record with empty Status and Draft fields is Class B,
+	// since Class B transponders transmit neither while Class A transponders
+	// always report a navigational status.
+	ClassField string
+
+	// SatelliteField is the field that marks satellite reports with a value of
+	// S, SAT, satellite, true, or 1, without regard to case.  An empty
+	// SatelliteField treats every Record as terrestrial.
+	SatelliteField string
+
+	ClassB    SourceTolerance
+	Satellite SourceTolerance
+}
+
+// DefaultSourceRules returns SourceRules that read the class of each Record from
+// TransceiverClass and allow Class B vessels 24 hours between reports, and
+// satellite reports 5 minutes of time skew and 12 hours between reports.
+// SatelliteField must be set for the satellite tolerance to apply.
+func DefaultSourceRules() *SourceRules {
+	return &SourceRules{
+		ClassField: "TransceiverClass",
+		ClassB:     SourceTolerance{MaxGap: 24 * time.Hour},
+		Satellite:  SourceTolerance{TimeSkew: 5 * time.Minute, MaxGap: 12 * time.Hour},
+	}
+}
+
+// WithSourceRules returns a copy of h whose Records are classified and
+// tolerated by sr.  A nil sr treats every Record as terrestrial Class A.
+func (h Headers) WithSourceRules(sr *SourceRules) Headers {
+	h.Sources = sr
+	return h
+}
+
+// Source returns the Source of the Record described by h as classified by the
+// SourceRules of h.  Every Record is terrestrial Class A when h has none.
+func (r Record) Source(h Headers) Source {
+	sr := h.Sources
+	if sr == nil {
+		return Source{}
+	}
+	var s Source
+	if i, err := h.index(sr.ClassField); err == nil && sr.ClassField != "" {
+		if i < len(r) {
+			v := strings.ToUpper(strings.TrimSpace(r[i]))
+			s.ClassB = v == "B" || v == "CLASS B"
+		}
+	} else {
+		status, errStatus := h.index("Status")
+		draft, errDraft := h.index("Draft")
+		s.ClassB = errStatus == nil && errDraft == nil && status < len(r) && draft < len(r) &&
+			r[status] == "" && r[draft] == ""
+	}
+	if i, err := h.index(sr.SatelliteField); err == nil && sr.SatelliteField != "" && i < len(r) {
+		switch strings.ToLower(strings.TrimSpace(r[i])) {
+		case "s", "sat", "satellite", "true", "1":
+			s.Satellite = true
+		}
+	}
+	return s
+}
+
+// tolerance returns the SourceTolerance that applies to the Record described
+// by h.
+func (r Record) tolerance(h Headers) SourceTolerance {
+	var t SourceTolerance
+	if h.Sources == nil {
+		return t
+	}
+	s := r.Source(h)
+	for _, apply := range []struct {
+		ok  bool
+		tol SourceTolerance
+	}{{s.ClassB, h.Sources.ClassB}, {s.Satellite, h.Sources.Satellite}} {
+		if !apply.ok {
+			continue
+		}
+		if apply.tol.TimeSkew > t.TimeSkew {
+			t.TimeSkew = apply.tol.TimeSkew
+		}
+		if apply.tol.MaxGap > t.MaxGap {
+			t.MaxGap = apply.tol.MaxGap
+		}
+	}
+	return t
+}
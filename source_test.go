@@ -0,0 +1,79 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecord_Source(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "Status", "Draft", "TransceiverClass", "Sat"}}
+	sr := DefaultSourceRules()
+	sr.SatelliteField = "Sat"
+	tests := []struct {
+		rec  Record
+		want Source
+	}{
+		{Record{"1", "0", "5.0", "A", ""}, Source{}},
+		{Record{"1", "", "", "B", "0"}, Source{ClassB: true}},
+		{Record{"1", "0", "5.0", "Class B", "S"}, Source{ClassB: true, Satellite: true}},
+		{Record{"1", "", "", "A", "true"}, Source{Satellite: true}},
+	}
+	for _, tt := range tests {
+		if got := tt.rec.Source(h.WithSourceRules(sr)); got != tt.want {
+			t.Errorf("Record.Source(%v) = %+v, want %+v", tt.rec, got, tt.want)
+		}
+		if got := tt.rec.Source(h); got != (Source{}) {
+			t.Errorf("Record.Source(%v) without SourceRules = %+v, want terrestrial Class A", tt.rec, got)
+		}
+	}
+
+	// Without a TransceiverClass field empty Status and Draft mean Class B.
+	h = Headers{Fields: []string{"MMSI", "Status", "Draft"}}.WithSourceRules(sr)
+	if s := (Record{"1", "", ""}).Source(h); !s.ClassB {
+		t.Errorf("Record.Source() with empty Status and Draft = %+v, want Class B", s)
+	}
+	if s := (Record{"1", "15", ""}).Source(h); s.ClassB {
+		t.Errorf("Record.Source() with a Status = %+v, want Class A", s)
+	}
+}
+
+const satelliteData = `MMSI,BaseDateTime,LAT,LON,Sat
+100000000,2017-12-01T00:00:00,30.00000,-76.00000,
+100000000,2017-12-01T00:04:00,30.00000,-76.10000,S
+100000000,2017-12-01T00:02:00,30.00000,-76.05000,
+`
+
+func TestSourceRules_TimeSkew(t *testing.T) {
+	sr := &SourceRules{SatelliteField: "Sat", Satellite: SourceTolerance{TimeSkew: 5 * time.Minute}}
+
+	// The satellite fix 5.2 nm from the first implies 78 knots in 4 minutes but
+	// only 35 knots once the skew is allowed for, and the terrestrial fix then
+	// implies 78 knots from the first but 22 knots from the satellite fix.
+	rules := CleanRules{MaxImpliedSpeed: 50}
+	rs, _ := NewRecordSetFromReader(strings.NewReader(satelliteData), Headers{})
+	_, report, _ := rs.CleanKinematics(rules)
+	if report.Counts[ImpliedSpeed] != 2 {
+		t.Errorf("RecordSet.CleanKinematics() without SourceRules rejected %d, want 2", report.Counts[ImpliedSpeed])
+	}
+	rs, _ = NewRecordSetFromReader(strings.NewReader(satelliteData), Headers{})
+	rs.SetHeaders(rs.Headers().WithSourceRules(sr))
+	_, report, _ = rs.CleanKinematics(rules)
+	if report.Rejected != 0 {
+		t.Errorf("RecordSet.CleanKinematics() with SourceRules rejected %d, want 0", report.Rejected)
+	}
+
+	// The terrestrial fix at 00:02 follows the late satellite fix.
+	n := 0
+	count := func(win *Window) error { n += win.Len(); return nil }
+	rs, _ = NewRecordSetFromReader(strings.NewReader(satelliteData), Headers{})
+	if err := rs.SlideWindow(time.Hour, time.Hour, count); err == nil {
+		t.Error("RecordSet.SlideWindow() expected error for unsorted records without SourceRules")
+	}
+	n = 0
+	rs, _ = NewRecordSetFromReader(strings.NewReader(satelliteData), Headers{})
+	rs.SetHeaders(rs.Headers().WithSourceRules(sr))
+	if err := rs.SlideWindow(time.Hour, time.Hour, count); err != nil || n != 3 {
+		t.Errorf("RecordSet.SlideWindow() with TimeSkew = %d records, %v, want 3 records", n, err)
+	}
+}
@@ -0,0 +1,215 @@
+package ais
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// SpatialIndex is a k-d tree of Records keyed by position that answers proximity
+// queries without the cell boundary effects of geohash Clusters.  Positions are
+// stored as points on the unit sphere so queries are correct across the
+// antimeridian and near the poles.  A SpatialIndex is immutable once built and
+// is safe for concurrent queries.
+type SpatialIndex struct {
+	nodes              []spatialNode // implicit tree, the median of each range is its root
+	latIndex, lonIndex int
+}
+
+// spatialNode is one Record in the SpatialIndex along with its position.
+type spatialNode struct {
+	p        [3]float64 // unit sphere cartesian coordinates
+	lat, lon float64
+	rec      *Record
+}
+
+// NewSpatialIndex returns a *SpatialIndex holding recs.  The latIndex and lonIndex
+// arguments identify the LAT and LON fields of each Record.  An error is returned
+// if any Record position cannot be parsed.
+func NewSpatialIndex(recs []*Record, latIndex, lonIndex int) (*SpatialIndex, error) {
+	idx := &SpatialIndex{
+		nodes:    make([]spatialNode, len(recs)),
+		latIndex: latIndex,
+		lonIndex: lonIndex,
+	}
+	for i, rec := range recs {
+		lat, err := rec.ParseFloat(latIndex)
+		if err != nil {
+			return nil, fmt.Errorf("new spatial index: %w", ErrParse{Field: "LAT", Err: err})
+		}
+		lon, err := rec.ParseFloat(lonIndex)
+		if err != nil {
+			return nil, fmt.Errorf("new spatial index: %w", ErrParse{Field: "LON", Err: err})
+		}
+		idx.nodes[i] = spatialNode{p: unitVector(lat, lon), lat: lat, lon: lon, rec: rec}
+	}
+	idx.build(idx.nodes, 0)
+	return idx, nil
+}
+
+// SpatialIndex reads the remaining Records in the RecordSet into a new
+// *SpatialIndex.  The RecordSet Headers must contain LAT and LON.  Like other
+// methods that read the whole RecordSet, SpatialIndex consumes the receiver.
+func (rs *RecordSet) SpatialIndex() (*SpatialIndex, error) {
+	idxMap, err := rs.Headers().require("LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("spatial index: %w", err)
+	}
+	var recs []*Record
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("spatial index: read error on csv file: %w", err)
+		}
+		recs = append(recs, rec)
+	}
+	return NewSpatialIndex(recs, idxMap["LAT"].Idx, idxMap["LON"].Idx)
+}
+
+// SpatialIndex returns a *SpatialIndex of the Records currently in the Window.
+func (win *Window) SpatialIndex(latIndex, lonIndex int) (*SpatialIndex, error) {
+	return NewSpatialIndex(win.Records(), latIndex, lonIndex)
+}
+
+// Len returns the number of Records in the SpatialIndex.
+func (idx *SpatialIndex) Len() int { return len(idx.nodes) }
+
+// Records returns every Record in the SpatialIndex in tree order.
+func (idx *SpatialIndex) Records() []*Record {
+	recs := make([]*Record, len(idx.nodes))
+	for i := range idx.nodes {
+		recs[i] = idx.nodes[i].rec
+	}
+	return recs
+}
+
+// WithinRadius returns the Records whose positions are no more than nm nautical
+// miles from lat, lon using the Haversine distance.  The order of the returned
+// Records is unspecified.
+func (idx *SpatialIndex) WithinRadius(lat, lon, nm float64) []*Record {
+	var recs []*Record
+	q := unitVector(lat, lon)
+	r := chordLength(nm) * 1.001 // allow for differences in earth radius and rounding
+	idx.search(idx.nodes, 0, func(n *spatialNode) float64 {
+		if Haversine(lat, lon, n.lat, n.lon) <= nm {
+			recs = append(recs, n.rec)
+		}
+		return r
+	}, q)
+	return recs
+}
+
+// Nearest returns the k Records closest to lat, lon ordered from nearest to
+// farthest.  Fewer than k Records are returned when the SpatialIndex is smaller
+// than k.
+func (idx *SpatialIndex) Nearest(lat, lon float64, k int) []*Record {
+	if k <= 0 {
+		return nil
+	}
+	q := unitVector(lat, lon)
+	h := &nearestHeap{}
+	idx.search(idx.nodes, 0, func(n *spatialNode) float64 {
+		d := math.Sqrt(sqDist(q, n.p))
+		if h.Len() < k {
+			heap.Push(h, nearestItem{d, n.rec})
+		} else if d < (*h)[0].d {
+			(*h)[0] = nearestItem{d, n.rec}
+			heap.Fix(h, 0)
+		}
+		if h.Len() < k {
+			return math.Inf(1)
+		}
+		return (*h)[0].d
+	}, q)
+
+	recs := make([]*Record, h.Len())
+	for i := len(recs) - 1; i >= 0; i-- {
+		recs[i] = heap.Pop(h).(nearestItem).rec
+	}
+	return recs
+}
+
+// build arranges nodes in place so that the median along the splitting axis for
+// depth is at the middle of the slice, recursively.
+func (idx *SpatialIndex) build(nodes []spatialNode, depth int) {
+	if len(nodes) <= 1 {
+		return
+	}
+	axis := depth % 3
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].p[axis] < nodes[j].p[axis] })
+	mid := len(nodes) / 2
+	idx.build(nodes[:mid], depth+1)
+	idx.build(nodes[mid+1:], depth+1)
+}
+
+// search visits nodes that may lie within the current search radius of q.  The
+// visit function is called for each candidate node and returns the chord length
+// radius to use for pruning the rest of the search.
+func (idx *SpatialIndex) search(nodes []spatialNode, depth int, visit func(*spatialNode) float64, q [3]float64) float64 {
+	if len(nodes) == 0 {
+		return math.Inf(1)
+	}
+	axis := depth % 3
+	mid := len(nodes) / 2
+	r := visit(&nodes[mid])
+
+	diff := q[axis] - nodes[mid].p[axis]
+	near, far := nodes[:mid], nodes[mid+1:]
+	if diff > 0 {
+		near, far = far, near
+	}
+	if len(near) > 0 {
+		r = idx.search(near, depth+1, visit, q)
+	}
+	if len(far) > 0 && math.Abs(diff) <= r {
+		r = idx.search(far, depth+1, visit, q)
+	}
+	return r
+}
+
+// unitVector returns the cartesian coordinates of a position on the unit sphere.
+func unitVector(lat, lon float64) [3]float64 {
+	const rad = math.Pi / 180
+	sinLat, cosLat := math.Sincos(lat * rad)
+	sinLon, cosLon := math.Sincos(lon * rad)
+	return [3]float64{cosLat * cosLon, cosLat * sinLon, sinLat}
+}
+
+// chordLength returns the straight line distance through the unit sphere between
+// two points nm nautical miles apart on its surface.
+func chordLength(nm float64) float64 {
+	angle := nm / earthRadiusNM
+	if angle >= math.Pi {
+		return 2
+	}
+	return 2 * math.Sin(angle/2)
+}
+
+func sqDist(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// nearestHeap is a max-heap of the best candidates found by Nearest.
+type nearestItem struct {
+	d   float64
+	rec *Record
+}
+
+type nearestHeap []nearestItem
+
+func (h nearestHeap) Len() int            { return len(h) }
+func (h nearestHeap) Less(i, j int) bool  { return h[i].d > h[j].d }
+func (h nearestHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nearestHeap) Push(x interface{}) { *h = append(*h, x.(nearestItem)) }
+func (h *nearestHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
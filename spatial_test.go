@@ -0,0 +1,116 @@
+package ais
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// testPositions returns n Records with MMSI, LAT, and LON fields scattered around
+// lat, lon by up to spread degrees.
+func testPositions(n int, lat, lon, spread float64) []*Record {
+	rnd := rand.New(rand.NewSource(1))
+	recs := make([]*Record, n)
+	for i := range recs {
+		rec := Record{
+			fmt.Sprintf("%09d", 100000000+i),
+			fmt.Sprintf("%.5f", lat+(rnd.Float64()*2-1)*spread),
+			fmt.Sprintf("%.5f", lon+(rnd.Float64()*2-1)*spread),
+		}
+		recs[i] = &rec
+	}
+	return recs
+}
+
+func TestSpatialIndex_WithinRadius(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+	}{
+		{"mid latitude", 36.9, -76.1},
+		{"antimeridian", 0, 179.99},
+		{"near pole", 89.9, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recs := testPositions(500, tt.lat, tt.lon, 0.2)
+			idx, err := NewSpatialIndex(recs, 1, 2)
+			if err != nil {
+				t.Fatalf("NewSpatialIndex() error = %v", err)
+			}
+			for _, nm := range []float64{0.5, 2, 10} {
+				got := idx.WithinRadius(tt.lat, tt.lon, nm)
+				want := 0
+				for _, rec := range recs {
+					lat, _ := rec.ParseFloat(1)
+					lon, _ := rec.ParseFloat(2)
+					if Haversine(tt.lat, tt.lon, lat, lon) <= nm {
+						want++
+					}
+				}
+				if len(got) != want {
+					t.Errorf("SpatialIndex.WithinRadius(%v) returned %d records, want %d", nm, len(got), want)
+				}
+			}
+		})
+	}
+}
+
+func TestSpatialIndex_Nearest(t *testing.T) {
+	recs := testPositions(300, 36.9, -76.1, 0.5)
+	idx, err := NewSpatialIndex(recs, 1, 2)
+	if err != nil {
+		t.Fatalf("NewSpatialIndex() error = %v", err)
+	}
+
+	dist := func(rec *Record) float64 {
+		lat, _ := rec.ParseFloat(1)
+		lon, _ := rec.ParseFloat(2)
+		return Haversine(36.9, -76.1, lat, lon)
+	}
+	sorted := append([]*Record(nil), recs...)
+	sort.Slice(sorted, func(i, j int) bool { return dist(sorted[i]) < dist(sorted[j]) })
+
+	got := idx.Nearest(36.9, -76.1, 5)
+	if len(got) != 5 {
+		t.Fatalf("SpatialIndex.Nearest() returned %d records, want 5", len(got))
+	}
+	for i := range got {
+		if got[i] != sorted[i] {
+			t.Errorf("SpatialIndex.Nearest()[%d] = %v, want %v", i, *got[i], *sorted[i])
+		}
+	}
+	if got := idx.Nearest(36.9, -76.1, 1000); len(got) != len(recs) {
+		t.Errorf("SpatialIndex.Nearest() returned %d records, want %d", len(got), len(recs))
+	}
+
+	bad := Record{"100000000", "xx", "-76.1"}
+	if _, err := NewSpatialIndex([]*Record{&bad}, 1, 2); err == nil {
+		t.Error("NewSpatialIndex() expected error for unparsable LAT")
+	}
+}
+
+func TestInteractions_AddSpatialIndex(t *testing.T) {
+	// Two vessels 0.05nm apart on either side of a geohash cell boundary at the
+	// equator and prime meridian would be missed by geohash clusters.
+	recs := []*Record{
+		{"100000001", "2017-12-01T00:00:00", "0.00040", "-0.00040", "", "", "", "", "", "", "", "", "", "", "", ""},
+		{"100000002", "2017-12-01T00:00:00", "-0.00040", "0.00040", "", "", "", "", "", "", "", "", "", "", "", ""},
+		{"100000003", "2017-12-01T00:00:00", "1.00000", "1.00000", "", "", "", "", "", "", "", "", "", "", "", ""},
+	}
+	idx, err := NewSpatialIndex(recs, 2, 3)
+	if err != nil {
+		t.Fatalf("NewSpatialIndex() error = %v", err)
+	}
+	inter, _ := NewInteractions(goodHeaders)
+	if err := inter.AddSpatialIndex(idx, 1); err != nil {
+		t.Fatalf("Interactions.AddSpatialIndex() error = %v", err)
+	}
+	if inter.Len() != 1 {
+		t.Errorf("Interactions.Len() = %d, want 1", inter.Len())
+	}
+	if err := inter.AddSpatialIndex(idx, 0); err == nil {
+		t.Error("Interactions.AddSpatialIndex() expected error for zero radius")
+	}
+}
@@ -0,0 +1,254 @@
+package ais
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpeedZoneFields are the columns written by SpeedReports.Save, one row per
+// SpeedExceedance.  Duration is in minutes.
+const SpeedZoneFields = "MMSI,Zone,Limit,Start,End,Duration,Records,MaxSOG,MaxExceedance,LAT,LON"
+
+// SpeedZone is a named area with a speed limit in knots, such as a seasonal
+// management area for right whales or a no-wake zone.
+type SpeedZone struct {
+	Name  string
+	Fence *Geofence
+	Limit float64
+}
+
+// NewSpeedZonesGeoJSON returns a SpeedZone for each Feature of a GeoJSON
+// FeatureCollection with a polygonal geometry, named by the nameProperty member
+// of the properties of the Feature and limited to the speed in knots of its
+// limitProperty member, a number or a string holding one.
+func NewSpeedZonesGeoJSON(data []byte, nameProperty, limitProperty string) ([]SpeedZone, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("speed zones: geojson: %w", err)
+	}
+	if obj.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("speed zones: geojson: type %q is not a FeatureCollection", obj.Type)
+	}
+	zones := make([]SpeedZone, len(obj.Features))
+	for i := range obj.Features {
+		f := &obj.Features[i]
+		name, ok := f.Properties[nameProperty]
+		if !ok {
+			return nil, fmt.Errorf("speed zones: geojson: feature %d has no property %q", i, nameProperty)
+		}
+		var limit float64
+		switch v := f.Properties[limitProperty].(type) {
+		case float64:
+			limit = v
+		case string:
+			var err error
+			if limit, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+				return nil, fmt.Errorf("speed zones: geojson: feature %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("speed zones: geojson: feature %d has no numeric property %q", i, limitProperty)
+		}
+		polys, err := f.polygons()
+		if err != nil {
+			return nil, fmt.Errorf("speed zones: geojson: feature %d: %w", i, err)
+		}
+		fence, err := newGeofence(polys)
+		if err != nil {
+			return nil, fmt.Errorf("speed zones: feature %d: %w", i, err)
+		}
+		zones[i] = SpeedZone{Name: fmt.Sprint(name), Fence: fence, Limit: limit}
+	}
+	return zones, nil
+}
+
+// SpeedZoneRules configures RecordSet.CheckSpeedZones.
+type SpeedZoneRules struct {
+	Zones     []SpeedZone
+	Tolerance float64       // knots over the limit allowed before a Record exceeds it
+	MaxGap    time.Duration // longest time between the Records of one exceedance, zero for no limit
+}
+
+// DefaultSpeedZoneRules returns SpeedZoneRules with no tolerance that join
+// Records no more than ten minutes apart into one exceedance.  Zones are not
+// set.
+func DefaultSpeedZoneRules() SpeedZoneRules {
+	return SpeedZoneRules{MaxGap: 10 * time.Minute}
+}
+
+// SpeedExceedance is a run of consecutive Records of one vessel above the limit
+// of one SpeedZone.
+type SpeedExceedance struct {
+	Zone       string
+	Limit      float64
+	Start, End time.Time
+	Records    int
+	MaxSOG     float64
+	LAT, LON   float64 // position of the first Record
+}
+
+// Duration returns the time elapsed between the first and last Records of the
+// SpeedExceedance, zero for a single Record.
+func (e SpeedExceedance) Duration() time.Duration { return e.End.Sub(e.Start) }
+
+// MaxExceedance returns the knots by which MaxSOG exceeds the Limit.
+func (e SpeedExceedance) MaxExceedance() float64 { return e.MaxSOG - e.Limit }
+
+// SpeedReport lists the exceedances of one MMSI in the order they ended.
+// Duration and MaxExceedance are the total and largest over its Exceedances.
+type SpeedReport struct {
+	MMSI          string
+	Records       int // number of Records of the MMSI checked in a zone
+	Duration      time.Duration
+	MaxExceedance float64
+	Exceedances   []SpeedExceedance
+}
+
+// SpeedReports are the reports of the vessels with at least one exceedance,
+// sorted by MMSI.
+type SpeedReports []*SpeedReport
+
+// CheckSpeedZones reads the RecordSet and returns a report for every MMSI with a
+// SOG above the Limit plus the Tolerance of one of the Zones of rules, for
+// environmental compliance monitoring.  Consecutive Records of a vessel over the
+// limit of the same zone, no more than MaxGap apart, form one SpeedExceedance,
+// which a Record outside the zone or within its limit ends.  A position inside
+// more than one zone belongs to the first of them.  Records with a position or
+// SOG that is not available are skipped.  The RecordSet should be sorted by
+// time.  The Headers must contain MMSI, BaseDateTime, LAT, LON, and SOG.
+// CheckSpeedZones consumes the receiver.
+func (rs *RecordSet) CheckSpeedZones(rules SpeedZoneRules) (SpeedReports, error) {
+	h := rs.Headers()
+	idx, err := h.require("MMSI", "BaseDateTime", "LAT", "LON", "SOG")
+	if err != nil {
+		return nil, fmt.Errorf("check speed zones: %w", err)
+	}
+
+	reports := make(map[string]*SpeedReport)
+	open := make(map[string]*SpeedExceedance)
+	closeExceedance := func(mmsi string) {
+		e := open[mmsi]
+		if e == nil {
+			return
+		}
+		delete(open, mmsi)
+		r := reports[mmsi]
+		r.Duration += e.Duration()
+		if x := e.MaxExceedance(); x > r.MaxExceedance {
+			r.MaxExceedance = x
+		}
+		r.Exceedances = append(r.Exceedances, *e)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("check speed zones: read error on csv file: %w", err)
+		}
+		lat, errLat := rec.ParseFloat(idx["LAT"].Idx)
+		lon, errLon := rec.ParseFloat(idx["LON"].Idx)
+		if errLat != nil || errLon != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			continue
+		}
+		sog, err := rec.ParseFloat(idx["SOG"].Idx)
+		if err != nil || sog < 0 || sog >= 102.3 {
+			continue
+		}
+		var zone *SpeedZone
+		for i := range rules.Zones {
+			if rules.Zones[i].Fence.Contains(lat, lon) {
+				zone = &rules.Zones[i]
+				break
+			}
+		}
+		mmsi := (*rec)[idx["MMSI"].Idx]
+		if zone == nil {
+			closeExceedance(mmsi)
+			continue
+		}
+		t, err := h.parseTime((*rec)[idx["BaseDateTime"].Idx])
+		if err != nil {
+			return nil, fmt.Errorf("check speed zones: %w", err)
+		}
+
+		r, ok := reports[mmsi]
+		if !ok {
+			r = &SpeedReport{MMSI: mmsi}
+			reports[mmsi] = r
+		}
+		r.Records++
+		over := sog > zone.Limit+rules.Tolerance
+		e := open[mmsi]
+		if e != nil && (!over || e.Zone != zone.Name ||
+			rules.MaxGap > 0 && t.Sub(e.End) > rules.MaxGap) {
+			closeExceedance(mmsi)
+			e = nil
+		}
+		if !over {
+			continue
+		}
+		if e == nil {
+			e = &SpeedExceedance{Zone: zone.Name, Limit: zone.Limit, Start: t, LAT: lat, LON: lon}
+			open[mmsi] = e
+		}
+		e.End = t
+		e.Records++
+		if sog > e.MaxSOG {
+			e.MaxSOG = sog
+		}
+	}
+	for mmsi := range open {
+		closeExceedance(mmsi)
+	}
+
+	var out SpeedReports
+	for _, r := range reports {
+		if len(r.Exceedances) > 0 {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].MMSI < out[j].MMSI })
+	return out, nil
+}
+
+// Save writes every SpeedExceedance of the reports to a csv file with
+// SpeedZoneFields.
+func (sr SpeedReports) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("speed zones save: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(strings.Split(SpeedZoneFields, ","))
+	for _, r := range sr {
+		for _, e := range r.Exceedances {
+			w.Write([]string{
+				r.MMSI,
+				e.Zone,
+				strconv.FormatFloat(e.Limit, 'f', -1, 64),
+				e.Start.Format(TimeLayout),
+				e.End.Format(TimeLayout),
+				fmt.Sprintf("%.1f", e.Duration().Minutes()),
+				strconv.Itoa(e.Records),
+				strconv.FormatFloat(e.MaxSOG, 'f', -1, 64),
+				fmt.Sprintf("%.1f", e.MaxExceedance()),
+				strconv.FormatFloat(e.LAT, 'f', -1, 64),
+				strconv.FormatFloat(e.LON, 'f', -1, 64),
+			})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("speed zones save: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,104 @@
+package ais
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const speedZoneGeoJSON = `{"type":"FeatureCollection","features":[
+	{"type":"Feature","properties":{"name":"Chesapeake SMA","limit":10},
+	 "geometry":{"type":"Polygon","coordinates":[[[-76,36],[-75,36],[-75,37],[-76,37],[-76,36]]]}},
+	{"type":"Feature","properties":{"name":"No Wake","limit":"5"},
+	 "geometry":{"type":"Polygon","coordinates":[[[-77,36],[-76.5,36],[-76.5,37],[-77,37],[-77,36]]]}}]}`
+
+const speedZoneData = `MMSI,BaseDateTime,LAT,LON,SOG
+100000000,2017-12-01T00:00:00,36.50000,-75.50000,14.0
+200000000,2017-12-01T00:00:00,36.50000,-75.50000,9.5
+300000000,2017-12-01T00:00:00,36.50000,-76.80000,6.0
+100000000,2017-12-01T00:05:00,36.55000,-75.50000,15.5
+200000000,2017-12-01T00:05:00,36.55000,-75.50000,102.3
+100000000,2017-12-01T00:10:00,36.60000,-75.50000,9.0
+100000000,2017-12-01T00:15:00,36.65000,-75.50000,11.0
+100000000,2017-12-01T00:20:00,38.00000,-75.50000,20.0
+`
+
+func TestNewSpeedZonesGeoJSON(t *testing.T) {
+	zones, err := NewSpeedZonesGeoJSON([]byte(speedZoneGeoJSON), "name", "limit")
+	if err != nil {
+		t.Fatalf("NewSpeedZonesGeoJSON() error = %v", err)
+	}
+	if len(zones) != 2 || zones[0].Name != "Chesapeake SMA" || zones[0].Limit != 10 || zones[1].Limit != 5 {
+		t.Errorf("NewSpeedZonesGeoJSON() = %+v", zones)
+	}
+	if _, err := NewSpeedZonesGeoJSON([]byte(speedZoneGeoJSON), "name", "knots"); err == nil {
+		t.Errorf("NewSpeedZonesGeoJSON() without a limit error = nil, want an error")
+	}
+}
+
+func TestRecordSet_CheckSpeedZones(t *testing.T) {
+	zones, err := NewSpeedZonesGeoJSON([]byte(speedZoneGeoJSON), "name", "limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := DefaultSpeedZoneRules()
+	rules.Zones = zones
+	rs, _ := NewRecordSetFromReader(strings.NewReader(speedZoneData), Headers{})
+	reports, err := rs.CheckSpeedZones(rules)
+	if err != nil {
+		t.Fatalf("RecordSet.CheckSpeedZones() error = %v", err)
+	}
+	if len(reports) != 2 || reports[0].MMSI != "100000000" || reports[1].MMSI != "300000000" {
+		t.Fatalf("RecordSet.CheckSpeedZones() = %v, want reports for 100000000 and 300000000", reports)
+	}
+
+	// The Record within the limit ends the first exceedance.
+	r := reports[0]
+	if r.Records != 4 || len(r.Exceedances) != 2 || r.Duration != 5*time.Minute || r.MaxExceedance != 5.5 {
+		t.Fatalf("RecordSet.CheckSpeedZones() report = %+v", r)
+	}
+	if e := r.Exceedances[0]; e.Zone != "Chesapeake SMA" || e.Records != 2 || e.MaxSOG != 15.5 || e.LAT != 36.5 {
+		t.Errorf("RecordSet.CheckSpeedZones() exceedance = %+v", e)
+	}
+	if e := reports[1].Exceedances; len(e) != 1 || e[0].Zone != "No Wake" || e[0].Duration() != 0 {
+		t.Errorf("RecordSet.CheckSpeedZones() no wake exceedance = %+v", e)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "speed.csv")
+	if err := reports.Save(filename); err != nil {
+		t.Fatalf("SpeedReports.Save() error = %v", err)
+	}
+	f, _ := os.Open(filename)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != SpeedZoneFields {
+		t.Fatalf("SpeedReports.Save() wrote %v", rows)
+	}
+	if got, want := strings.Join(rows[1], ","), "100000000,Chesapeake SMA,10,2017-12-01T00:00:00,2017-12-01T00:05:00,5.0,2,15.5,5.5,36.5,-75.5"; got != want {
+		t.Errorf("SpeedReports.Save() row = %s, want %s", got, want)
+	}
+
+	// A tolerance of a knot forgives the 11 knot Record and the No Wake zone.
+	rules.Tolerance = 1
+	rs, _ = NewRecordSetFromReader(strings.NewReader(speedZoneData), Headers{})
+	if reports, _ := rs.CheckSpeedZones(rules); len(reports) != 1 || len(reports[0].Exceedances) != 1 {
+		t.Errorf("RecordSet.CheckSpeedZones() with tolerance = %v", reports)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT,LON\n"), Headers{})
+	if _, err := rs.CheckSpeedZones(rules); err == nil {
+		t.Errorf("RecordSet.CheckSpeedZones() without SOG error = nil, want an error")
+	}
+}
@@ -0,0 +1,301 @@
+package ais
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// spillStore holds the interactions of a set with a memory limit that have been
+// moved out of memory to a temporary file.  The file is an append only stream of
+// gob encoded spillEntry values, and refs records the latest entry of each hash
+// so that an entry replaced by a closer pair, with WithTimeBucket or
+// WithClosestApproach, is skipped when the file is read back.
+type spillStore struct {
+	mu       sync.Mutex
+	maxPairs int64 // pairs held in memory before they are spilled, zero for no limit
+	maxBytes int64 // estimated bytes held in memory before the pairs are spilled, zero for no limit
+	pairs    int64 // pairs in memory, updated atomically
+	bytes    int64 // estimated bytes of the pairs in memory, updated atomically
+	spilling int32 // set while a goroutine spills the set, updated atomically
+	f        *os.File
+	w        *bufio.Writer
+	enc      *gob.Encoder
+	refs     map[Hash128]spillRef
+	seq      int64 // sequence number of the last entry written
+	err      error // first error writing the file, returned by every later read
+}
+
+// spillRef is the latest entry of a hash in the spill file.
+type spillRef struct {
+	seq  int64
+	dist float64 // distance of the pair, set with WithTimeBucket or WithClosestApproach
+}
+
+// spillEntry is one pair written to the spill file.
+type spillEntry struct {
+	Hash       Hash128
+	Rec1, Rec2 []string
+	Dist       float64
+	HasDist    bool
+	Seq        int64
+}
+
+// WithMemoryLimit bounds the interactions held in memory to n pairs.  When an
+// insert takes the set over the limit every pair in memory is moved to a
+// temporary file, and the file is read back and merged with the pairs in memory
+// by Save, Len, and every other method that reads the whole set, so the output
+// is the same as without a limit.  Save with an OutputOrder other than
+// Unordered reads every spilled pair back into memory to sort it.  A set with a
+// memory limit should be closed with Close to remove the file.
+func WithMemoryLimit(n int) InteractionOption {
+	return func(inter *Interactions) error {
+		if n <= 0 {
+			return fmt.Errorf("memory limit must be greater than zero, got %d", n)
+		}
+		inter.spillStore().maxPairs = int64(n)
+		return nil
+	}
+}
+
+// WithMemoryLimitBytes bounds the interactions held in memory to an estimated
+// n bytes, counting the fields of both Records of each pair.  A Record shared
+// by several pairs is counted for each, so the estimate is an upper bound.  It
+// behaves as WithMemoryLimit otherwise and may be combined with it.
+func WithMemoryLimitBytes(n int64) InteractionOption {
+	return func(inter *Interactions) error {
+		if n <= 0 {
+			return fmt.Errorf("memory limit must be greater than zero, got %d bytes", n)
+		}
+		inter.spillStore().maxBytes = n
+		return nil
+	}
+}
+
+// spillStore returns the spillStore of the set, creating it for the first
+// memory limit option.
+func (inter *Interactions) spillStore() *spillStore {
+	if inter.spill == nil {
+		inter.spill = &spillStore{refs: make(map[Hash128]spillRef)}
+	}
+	return inter.spill
+}
+
+// pairSize returns the estimated bytes held in memory by pair.
+func pairSize(pair *RecordPair) int64 {
+	n := int64(64)
+	for _, rec := range []*Record{pair.rec1, pair.rec2} {
+		for _, field := range *rec {
+			n += 16 + int64(len(field))
+		}
+	}
+	return n
+}
+
+// grow records that the pairs in memory changed by pairs and bytes.
+func (s *spillStore) grow(pairs, bytes int64) {
+	atomic.AddInt64(&s.pairs, pairs)
+	atomic.AddInt64(&s.bytes, bytes)
+}
+
+// over reports whether the pairs in memory exceed a limit.
+func (s *spillStore) over() bool {
+	return s.maxPairs > 0 && atomic.LoadInt64(&s.pairs) > s.maxPairs ||
+		s.maxBytes > 0 && atomic.LoadInt64(&s.bytes) > s.maxBytes
+}
+
+// spilled returns the reference of hash if it has been spilled.
+func (s *spillStore) spilled(hash Hash128) (spillRef, bool) {
+	s.mu.Lock()
+	ref, ok := s.refs[hash]
+	s.mu.Unlock()
+	return ref, ok
+}
+
+// maybeSpill moves every pair in memory to the spill file when the set is over
+// its memory limit.  Only one goroutine spills at a time and the others go on
+// inserting.  An error writing the file is kept and returned when the set is
+// read.
+func (inter *Interactions) maybeSpill() {
+	s := inter.spill
+	if s == nil || !s.over() || !atomic.CompareAndSwapInt32(&s.spilling, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&s.spilling, 0)
+
+	// The pairs already written by AppendCSV are remembered by their entry
+	// once they leave memory.
+	inter.appended.Lock()
+	defer inter.appended.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	if s.f == nil {
+		f, err := ioutil.TempFile("", "ais-spill")
+		if err != nil {
+			s.err = fmt.Errorf("spill: %w", err)
+			return
+		}
+		s.f, s.w = f, bufio.NewWriter(f)
+		s.enc = gob.NewEncoder(s.w)
+	}
+
+	nearest := inter.timeBucket > 0 || inter.closest
+	for i := range inter.data {
+		shard := &inter.data[i]
+		shard.Lock()
+		for hash, pair := range shard.m {
+			ref, ok := s.refs[hash]
+			if !ok || !nearest || pair.dist < ref.dist {
+				s.seq++
+				e := spillEntry{Hash: hash, Rec1: *pair.rec1, Rec2: *pair.rec2, Dist: pair.dist, HasDist: pair.hasDist, Seq: s.seq}
+				if err := s.enc.Encode(&e); err != nil {
+					shard.Unlock()
+					s.err = fmt.Errorf("spill: %w", err)
+					return
+				}
+				s.refs[hash] = spillRef{seq: s.seq, dist: pair.dist}
+				if inter.appended.m[hash] == pair {
+					delete(inter.appended.m, hash)
+					inter.appended.spilled[hash] = s.seq
+				}
+			}
+			delete(shard.m, hash)
+			s.grow(-1, -pairSize(pair))
+		}
+		shard.Unlock()
+	}
+}
+
+// eachSpilled calls fn for the latest entry of every hash in the spill file
+// that is not also held in memory, where a closer pair replaced it.  The pairs
+// are read back into new Records.
+func (inter *Interactions) eachSpilled(fn func(hash Hash128, pair *RecordPair) error) error {
+	return inter.scanSpilled(false, fn)
+}
+
+// scanSpilled is eachSpilled that also calls fn for the entries of the hashes
+// held in memory when shadowed is set.
+func (inter *Interactions) scanSpilled(shadowed bool, fn func(hash Hash128, pair *RecordPair) error) error {
+	s := inter.spill
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if s.f == nil {
+		return nil
+	}
+	if err := s.w.Flush(); err != nil {
+		s.err = fmt.Errorf("spill: %w", err)
+		return s.err
+	}
+	size, err := s.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("spill: %w", err)
+	}
+	dec := gob.NewDecoder(bufio.NewReader(io.NewSectionReader(s.f, 0, size)))
+	for {
+		var e spillEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("spill: %w", err)
+		}
+		if ref, ok := s.refs[e.Hash]; !ok || ref.seq != e.Seq || !shadowed && inter.inMemory(e.Hash) {
+			continue
+		}
+		rec1, rec2 := Record(e.Rec1), Record(e.Rec2)
+		pair := &RecordPair{rec1: &rec1, rec2: &rec2, dist: e.Dist, hasDist: e.HasDist, spill: e.Seq}
+		if err := fn(e.Hash, pair); err != nil {
+			return err
+		}
+	}
+}
+
+// inMemory reports whether a pair is held in memory under hash.
+func (inter *Interactions) inMemory(hash Hash128) bool {
+	shard := &inter.data[hash.shard()]
+	shard.Lock()
+	_, ok := shard.m[hash]
+	shard.Unlock()
+	return ok
+}
+
+// spilledLen returns the number of spilled hashes not also held in memory.
+func (inter *Interactions) spilledLen() int {
+	s := inter.spill
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for hash := range s.refs {
+		if !inter.inMemory(hash) {
+			n++
+		}
+	}
+	return n
+}
+
+// forgetSpilled removes the spilled pairs for which drop returns true, so that
+// they are no longer read back, including those shadowed by a pair in memory.
+func (inter *Interactions) forgetSpilled(drop func(pair *RecordPair) (bool, error)) error {
+	var hashes []Hash128
+	err := inter.scanSpilled(true, func(hash Hash128, pair *RecordPair) error {
+		ok, err := drop(pair)
+		if ok {
+			hashes = append(hashes, hash)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(hashes) > 0 {
+		inter.spill.mu.Lock()
+		for _, hash := range hashes {
+			delete(inter.spill.refs, hash)
+		}
+		inter.spill.mu.Unlock()
+	}
+	return nil
+}
+
+// Close removes the temporary file of a set created with WithMemoryLimit or
+// WithMemoryLimitBytes, along with the spilled interactions it holds.  It does
+// nothing for a set without a memory limit, and the set must not be used after
+// it is closed.
+func (inter *Interactions) Close() error {
+	s := inter.spill
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	name := s.f.Name()
+	err := s.f.Close()
+	s.f, s.w, s.enc = nil, nil, nil
+	s.refs = make(map[Hash128]spillRef)
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	if err != nil {
+		return fmt.Errorf("interactions close: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package ais
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+)
+
+// spillOutput returns the Len and csv output by hash of an Interactions set
+// created with opts and filled with clusters in parallel.
+func spillOutput(t *testing.T, clusters []*Cluster, opts ...InteractionOption) (int, string) {
+	t.Helper()
+	inter, err := NewInteractionsWithOptions(goodHeaders, opts...)
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	defer inter.Close()
+	if err := inter.AddClustersParallel(clusters, 4); err != nil {
+		t.Fatalf("Interactions.AddClustersParallel() error = %v", err)
+	}
+	if err := inter.SetOrder(OrderByHash); err != nil {
+		t.Fatalf("Interactions.SetOrder() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := inter.WriteCSV(&buf); err != nil {
+		t.Fatalf("Interactions.WriteCSV() error = %v", err)
+	}
+	return inter.Len(), buf.String()
+}
+
+func TestWithMemoryLimit(t *testing.T) {
+	clusters := testClusters(40, 8)
+	tests := []struct {
+		name  string
+		base  []InteractionOption
+		limit InteractionOption
+	}{
+		{"pairs", nil, WithMemoryLimit(25)},
+		{"bytes", nil, WithMemoryLimitBytes(20000)},
+		{"closest", []InteractionOption{WithClosestApproach()}, WithMemoryLimit(3)},
+		{"time bucket", []InteractionOption{WithTimeBucket(10e9)}, WithMemoryLimit(10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantLen, want := spillOutput(t, clusters, tt.base...)
+			gotLen, got := spillOutput(t, clusters, append(tt.base, tt.limit)...)
+			if gotLen != wantLen {
+				t.Errorf("Len() = %d, want %d", gotLen, wantLen)
+			}
+			if got != want {
+				t.Errorf("output with a memory limit differs from the output without one")
+			}
+		})
+	}
+}
+
+func TestWithMemoryLimit_Invalid(t *testing.T) {
+	if _, err := NewInteractionsWithOptions(goodHeaders, WithMemoryLimit(0)); err == nil {
+		t.Errorf("WithMemoryLimit(0) error = nil, want an error")
+	}
+	if _, err := NewInteractionsWithOptions(goodHeaders, WithMemoryLimitBytes(-1)); err == nil {
+		t.Errorf("WithMemoryLimitBytes(-1) error = nil, want an error")
+	}
+}
+
+func TestWithMemoryLimit_Spills(t *testing.T) {
+	inter, _ := NewInteractionsWithOptions(goodHeaders, WithMemoryLimit(10))
+	for _, c := range testClusters(5, 6) {
+		if err := inter.AddCluster(c); err != nil {
+			t.Fatalf("Interactions.AddCluster() error = %v", err)
+		}
+	}
+	if inter.spill.f == nil {
+		t.Fatalf("no spill file after exceeding the memory limit")
+	}
+	if n := inter.spill.pairs; n > 10 {
+		t.Errorf("%d pairs in memory, want at most 10", n)
+	}
+	if got, want := inter.Len(), 5*15; got != want {
+		t.Errorf("Len() = %d, want %d", got, want)
+	}
+
+	name := inter.spill.f.Name()
+	if err := inter.Close(); err != nil {
+		t.Fatalf("Interactions.Close() error = %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("spill file %s not removed by Close, stat error = %v", name, err)
+	}
+}
+
+func TestWithMemoryLimit_AppendCSV(t *testing.T) {
+	inter, _ := NewInteractionsWithOptions(goodHeaders, WithMemoryLimit(10))
+	defer inter.Close()
+	clusters := testClusters(6, 6)
+	for _, c := range clusters[:3] {
+		inter.AddCluster(c)
+	}
+	var buf bytes.Buffer
+	n1, err := inter.AppendCSV(context.Background(), &buf, true)
+	if err != nil {
+		t.Fatalf("AppendCSV() error = %v", err)
+	}
+	for _, c := range clusters[3:] {
+		inter.AddCluster(c)
+	}
+	n2, err := inter.AppendCSV(context.Background(), &buf, false)
+	if err != nil {
+		t.Fatalf("AppendCSV() error = %v", err)
+	}
+	if n1 != 3*15 || n2 != 3*15 {
+		t.Errorf("AppendCSV() wrote %d and %d interactions, want 45 and 45", n1, n2)
+	}
+	if n, _ := inter.AppendCSV(context.Background(), &buf, false); n != 0 {
+		t.Errorf("AppendCSV() with nothing new wrote %d interactions, want 0", n)
+	}
+}
@@ -0,0 +1,310 @@
+package ais
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlDoubles are the non-Record fields of an Interactions table that SaveSQL
+// stores as DOUBLE PRECISION columns.
+var sqlDoubles = map[string]bool{
+	"Distance(nm)": true,
+	"CPA(nm)":      true,
+	"TCPA(min)":    true,
+}
+
+// sqlGeogPrefix begins the name of every PostGIS geography column created by
+// SaveSQL.  LoadSQL skips columns with this prefix.
+const sqlGeogPrefix = "geog"
+
+// sqlWriter bulk inserts rows of string fields into a typed table inside a single
+// transaction.
+type sqlWriter struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	stmt    *sql.Stmt
+	table   string
+	fields  []string
+	types   []string // "timestamp", "double", or "text" for each field
+	geogs   []string // suffixes of LAT and LON pairs that have a geography column
+	postgis bool
+
+	timeParser TimeParser // from the Headers, nil for TimeLayout
+}
+
+// newSQLWriter creates table with a column for each of the Fields of h and prepares the
+// insert statement.  BaseDateTime is stored as a TIMESTAMP, LAT, LON, SOG, COG, and
+// the computed interaction distances are stored as DOUBLE PRECISION, and every
+// other field is stored as TEXT.  Fields that end in _1 or _2, as in the output of
+// Interactions, are typed by the name without the suffix.
+func newSQLWriter(db *sql.DB, table string, h Headers) (*sqlWriter, error) {
+	fields := h.Fields
+	w := &sqlWriter{db: db, table: table, fields: fields, timeParser: h.Time}
+	postgres := isPostgres(db)
+	if postgres {
+		var v string
+		w.postgis = db.QueryRow("SELECT PostGIS_Version()").Scan(&v) == nil
+	}
+
+	cols := make([]string, len(fields))
+	holders := make([]string, len(fields))
+	w.types = make([]string, len(fields))
+	for i, f := range fields {
+		w.types[i] = sqlType(f)
+		switch w.types[i] {
+		case "timestamp":
+			cols[i] = quoteIdent(f) + " TIMESTAMP"
+		case "double":
+			cols[i] = quoteIdent(f) + " DOUBLE PRECISION"
+		default:
+			cols[i] = quoteIdent(f) + " TEXT"
+		}
+		holders[i] = "?"
+		if postgres {
+			holders[i] = "$" + strconv.Itoa(i+1)
+		}
+	}
+	if w.postgis {
+		h := Headers{Fields: fields}
+		for _, suffix := range []string{"", "_1", "_2"} {
+			if _, ok := h.ContainsMulti("LAT"+suffix, "LON"+suffix); ok {
+				w.geogs = append(w.geogs, suffix)
+				cols = append(cols, quoteIdent(sqlGeogPrefix+suffix)+" geography(Point,4326)")
+			}
+		}
+	}
+
+	create := fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdent(table), strings.Join(cols, ", "))
+	if _, err := db.Exec(create); err != nil {
+		return nil, err
+	}
+	var err error
+	if w.tx, err = db.Begin(); err != nil {
+		return nil, err
+	}
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = quoteIdent(f)
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table),
+		strings.Join(quoted, ", "), strings.Join(holders, ", "))
+	if w.stmt, err = w.tx.Prepare(insert); err != nil {
+		w.tx.Rollback()
+		return nil, err
+	}
+	return w, nil
+}
+
+// write inserts one row.  Empty fields are stored as NULL.
+func (w *sqlWriter) write(row []string) error {
+	args := make([]interface{}, len(w.fields))
+	for i := range w.fields {
+		if i >= len(row) || row[i] == "" {
+			continue
+		}
+		switch w.types[i] {
+		case "timestamp":
+			t, err := Headers{Time: w.timeParser}.parseTime(row[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", w.fields[i], err)
+			}
+			args[i] = t
+		case "double":
+			f, err := strconv.ParseFloat(row[i], 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", w.fields[i], err)
+			}
+			args[i] = f
+		default:
+			args[i] = row[i]
+		}
+	}
+	_, err := w.stmt.Exec(args...)
+	return err
+}
+
+// abort rolls back the transaction after a failed write.
+func (w *sqlWriter) abort() {
+	w.stmt.Close()
+	w.tx.Rollback()
+}
+
+// close commits the inserted rows and fills the geography columns.
+func (w *sqlWriter) close() error {
+	w.stmt.Close()
+	for _, suffix := range w.geogs {
+		update := fmt.Sprintf("UPDATE %s SET %s = ST_SetSRID(ST_MakePoint(%s, %s), 4326)::geography",
+			quoteIdent(w.table), quoteIdent(sqlGeogPrefix+suffix), quoteIdent("LON"+suffix), quoteIdent("LAT"+suffix))
+		if _, err := w.tx.Exec(update); err != nil {
+			w.tx.Rollback()
+			return err
+		}
+	}
+	return w.tx.Commit()
+}
+
+// SaveSQL creates table in db and inserts every remaining Record of the RecordSet
+// in a single transaction.  Columns are typed as described for Interactions.SaveSQL
+// and, when db is a PostgreSQL database with the PostGIS extension, a geog column
+// of type geography(Point,4326) is added and filled from LAT and LON.  SaveSQL
+// consumes the receiver.  The database driver must be imported by the caller.
+func (rs *RecordSet) SaveSQL(db *sql.DB, table string) error {
+	w, err := newSQLWriter(db, table, rs.Headers())
+	if err != nil {
+		return fmt.Errorf("recordset save sql: %w", err)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = w.write(*rec)
+		}
+		if err != nil {
+			w.abort()
+			return fmt.Errorf("recordset save sql: %w", err)
+		}
+	}
+	if err := w.close(); err != nil {
+		return fmt.Errorf("recordset save sql: %w", err)
+	}
+	return nil
+}
+
+// SaveSQL creates table in db with a column for each of the OutputHeaders and
+// inserts every interaction in a single transaction.  BaseDateTime columns are
+// TIMESTAMP, positions, speeds, courses, and the computed distances are DOUBLE
+// PRECISION, and all other columns are TEXT.  When db is a PostgreSQL database with
+// the PostGIS extension, geog_1 and geog_2 geography(Point,4326) columns are added
+// and filled from the positions of the two vessels.
+func (inter *Interactions) SaveSQL(db *sql.DB, table string) error {
+	w, err := newSQLWriter(db, table, inter.OutputHeaders)
+	if err != nil {
+		return fmt.Errorf("interactions save sql: %w", err)
+	}
+	err = inter.each(func(hash Hash128, pair *RecordPair) error {
+		row, err := inter.row(hash, pair)
+		if err != nil {
+			return err
+		}
+		return w.write(row)
+	})
+	if err != nil {
+		w.abort()
+		return fmt.Errorf("interactions save sql: %w", err)
+	}
+	if err := w.close(); err != nil {
+		return fmt.Errorf("interactions save sql: %w", err)
+	}
+	return nil
+}
+
+// LoadSQL reads every row of table into a new in-memory *RecordSet whose Headers
+// are the column names of the table.  It is the counterpart of both
+// RecordSet.SaveSQL and Interactions.SaveSQL.  Geography columns added for PostGIS
+// are omitted, timestamps are formatted with TimeLayout, and NULL values become
+// empty fields.
+func LoadSQL(db *sql.DB, table string) (*RecordSet, error) {
+	rows, err := db.Query("SELECT * FROM " + quoteIdent(table))
+	if err != nil {
+		return nil, fmt.Errorf("load sql: %w", err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("load sql: %w", err)
+	}
+	var keep []int
+	var fields []string
+	for i, c := range cols {
+		if strings.HasPrefix(c, sqlGeogPrefix) {
+			continue
+		}
+		keep = append(keep, i)
+		fields = append(fields, c)
+	}
+
+	rs := NewRecordSet()
+	rs.SetHeaders(Headers{Fields: fields})
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	written := 0
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("load sql: %w", err)
+		}
+		rec := make(Record, len(keep))
+		for j, i := range keep {
+			rec[j] = sqlString(vals[i])
+		}
+		rs.Write(rec)
+		written++
+		if written%flushThreshold == 0 {
+			if err := rs.Flush(); err != nil {
+				return nil, fmt.Errorf("load sql: %w", err)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load sql: %w", err)
+	}
+	if err := rs.Flush(); err != nil {
+		return nil, fmt.Errorf("load sql: %w", err)
+	}
+	return rs, nil
+}
+
+// sqlType returns the column type used by SaveSQL for a field.
+func sqlType(field string) string {
+	if sqlDoubles[field] {
+		return "double"
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(field, "_1"), "_2")
+	switch parquetTypes[name] {
+	case parquetInt64:
+		return "timestamp"
+	case parquetDouble:
+		return "double"
+	}
+	return "text"
+}
+
+// sqlString formats a value scanned from a database row as a Record field.
+func sqlString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.UTC().Format(TimeLayout)
+	case float64:
+		return formatDouble(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// isPostgres reports whether db uses a PostgreSQL driver, which determines the
+// placeholder syntax of the insert statement.
+func isPostgres(db *sql.DB) bool {
+	name := strings.ToLower(fmt.Sprintf("%T", db.Driver()))
+	return strings.Contains(name, "pq.") || strings.Contains(name, "pgx") || strings.Contains(name, "postgres")
+}
+
+// quoteIdent returns name quoted as an SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
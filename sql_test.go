@@ -0,0 +1,224 @@
+package ais
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memDriver is a minimal in-memory database/sql driver that understands the
+// statements issued by SaveSQL and LoadSQL.
+type memDriver struct {
+	sync.Mutex
+	tables map[string]*memTable
+}
+
+type memTable struct {
+	cols  []string
+	types []string
+	rows  [][]driver.Value
+}
+
+var (
+	testMemDriver = &memDriver{tables: make(map[string]*memTable)}
+	identRE       = regexp.MustCompile(`"((?:[^"]|"")*)"(?: ([A-Z ]+))?`)
+)
+
+func init() { sql.Register("aismem", testMemDriver) }
+
+func (d *memDriver) Open(name string) (driver.Conn, error) { return &memConn{d}, nil }
+
+type memConn struct{ d *memDriver }
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) { return &memStmt{c.d, query}, nil }
+func (c *memConn) Close() error                              { return nil }
+func (c *memConn) Begin() (driver.Tx, error)                 { return memTx{}, nil }
+
+type memTx struct{}
+
+func (memTx) Commit() error   { return nil }
+func (memTx) Rollback() error { return nil }
+
+type memStmt struct {
+	d     *memDriver
+	query string
+}
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.Lock()
+	defer s.d.Unlock()
+	idents := identRE.FindAllStringSubmatch(s.query, -1)
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		t := new(memTable)
+		for _, m := range idents[1:] {
+			t.cols = append(t.cols, m[1])
+			t.types = append(t.types, m[2])
+		}
+		s.d.tables[idents[0][1]] = t
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		t, ok := s.d.tables[idents[0][1]]
+		if !ok {
+			return nil, fmt.Errorf("no table %s", idents[0][1])
+		}
+		t.rows = append(t.rows, append([]driver.Value(nil), args...))
+	default:
+		return nil, fmt.Errorf("unsupported statement %q", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.Lock()
+	defer s.d.Unlock()
+	if strings.Contains(s.query, "PostGIS") {
+		return nil, fmt.Errorf("no postgis")
+	}
+	idents := identRE.FindAllStringSubmatch(s.query, -1)
+	t, ok := s.d.tables[idents[0][1]]
+	if !ok {
+		return nil, fmt.Errorf("no table %s", idents[0][1])
+	}
+	return &memRows{t: t}, nil
+}
+
+type memRows struct {
+	t *memTable
+	i int
+}
+
+func (r *memRows) Columns() []string { return r.t.cols }
+func (r *memRows) Close() error      { return nil }
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.t.rows) {
+		return io.EOF
+	}
+	copy(dest, r.t.rows[r.i])
+	r.i++
+	return nil
+}
+
+func TestRecordSet_SaveSQL(t *testing.T) {
+	db, err := sql.Open("aismem", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	rs, err := OpenRecordSet("testdata/ten.csv")
+	if err != nil {
+		t.Fatalf("OpenRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+	if err := rs.SaveSQL(db, "ten"); err != nil {
+		t.Fatalf("RecordSet.SaveSQL() error = %v", err)
+	}
+
+	table := testMemDriver.tables["ten"]
+	wantTypes := map[string]string{"MMSI": "TEXT", "BaseDateTime": "TIMESTAMP", "LAT": "DOUBLE PRECISION"}
+	for i, c := range table.cols {
+		if want, ok := wantTypes[c]; ok && table.types[i] != want {
+			t.Errorf("RecordSet.SaveSQL() column %s type = %s, want %s", c, table.types[i], want)
+		}
+	}
+	if ts, ok := table.rows[0][1].(time.Time); !ok || ts.Format(TimeLayout) != firstRec[1] {
+		t.Errorf("RecordSet.SaveSQL() BaseDateTime = %v, want time %s", table.rows[0][1], firstRec[1])
+	}
+
+	got, err := LoadSQL(db, "ten")
+	if err != nil {
+		t.Fatalf("LoadSQL() error = %v", err)
+	}
+	orig, _ := OpenRecordSet("testdata/ten.csv")
+	defer orig.Close()
+	if !got.Headers().Equals(orig.Headers()) {
+		t.Errorf("LoadSQL() Headers = %v, want %v", got.Headers(), orig.Headers())
+	}
+	n := 0
+	for {
+		want, err := orig.Read()
+		if err == io.EOF {
+			break
+		}
+		rec, err := got.Read()
+		if err != nil {
+			t.Fatalf("RecordSet.Read() error = %v", err)
+		}
+		for _, field := range []string{"MMSI", "BaseDateTime", "VesselName", "Status", "Length", "Cargo"} {
+			i, _ := goodHeaders.Contains(field)
+			if (*rec)[i] != (*want)[i] {
+				t.Errorf("record %d: %s = %q, want %q", n, field, (*rec)[i], (*want)[i])
+			}
+		}
+		for _, field := range []string{"LAT", "LON", "SOG", "COG"} {
+			i, _ := goodHeaders.Contains(field)
+			g, _ := rec.ParseFloat(i)
+			w, _ := want.ParseFloat(i)
+			if g != w {
+				t.Errorf("record %d: %s = %v, want %v", n, field, g, w)
+			}
+		}
+		n++
+	}
+	if n != 10 {
+		t.Errorf("LoadSQL() compared %d records, want 10", n)
+	}
+}
+
+func TestInteractions_SaveSQL(t *testing.T) {
+	db, err := sql.Open("aismem", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	inter, _ := NewInteractions(goodHeaders)
+	inter.SetCPA(true)
+	c := testClusters(1, 3)[0]
+	for _, rec := range c.Data() {
+		*rec = append(*rec, "0xdc3f2b0000000000") // Geohash field of InteractionFields
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	if err := inter.SaveSQL(db, "inter"); err != nil {
+		t.Fatalf("Interactions.SaveSQL() error = %v", err)
+	}
+
+	rs, err := LoadSQL(db, "inter")
+	if err != nil {
+		t.Fatalf("LoadSQL() error = %v", err)
+	}
+	n := 0
+	for rs.Next() {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("LoadSQL() returned %d interactions, want 3", n)
+	}
+}
+
+func TestSQLType(t *testing.T) {
+	tests := map[string]string{
+		"BaseDateTime":   "timestamp",
+		"BaseDateTime_2": "timestamp",
+		"LAT_1":          "double",
+		"Distance(nm)":   "double",
+		"MMSI":           "text",
+		"VesselName_1":   "text",
+	}
+	for field, want := range tests {
+		if got := sqlType(field); got != want {
+			t.Errorf("sqlType(%s) = %s, want %s", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package ais
+
+import "fmt"
+
+// StationClass is the kind of station identified by an MMSI under ITU-R M.585.
+// Besides vessels, AIS is transmitted by fixed base stations, aids to navigation,
+// search and rescue aircraft, and distress beacons, all of which create false
+// encounters when they are paired with vessels in an interaction analysis.
+type StationClass int
+
+const (
+	// UnknownStation is an MMSI that does not follow the ITU numbering scheme.
+	UnknownStation StationClass = iota
+
+	// ShipStation is a vessel, MIDxxxxxx.
+	ShipStation
+
+	// GroupStation is a group call identity for several ships, 0MIDxxxxx.
+	GroupStation
+
+	// CoastStation is a coast or AIS base station, 00MIDxxxx, which transmits
+	// base station reports (message type 4).
+	CoastStation
+
+	// SARAircraft is a search and rescue aircraft, 111MIDxxx.
+	SARAircraft
+
+	// Handheld is a handheld VHF transceiver with DSC and GNSS, 8MIDxxxxx.
+	Handheld
+
+	// AuxiliaryCraft is a craft associated with a parent ship, such as a
+	// lifeboat or tender, 98MIDxxxx.
+	AuxiliaryCraft
+
+	// AtoN is an aid to navigation, 99MIDxxxx, which transmits aid to
+	// navigation reports (message type 21).
+	AtoN
+
+	// SART is an AIS search and rescue transmitter, 970xxxxxx.
+	SART
+
+	// MOB is a man overboard device, 972xxxxxx.
+	MOB
+
+	// EPIRB is an EPIRB with an AIS locator, 974xxxxxx.
+	EPIRB
+)
+
+var stationClassNames = [...]string{
+	UnknownStation: "unknown",
+	ShipStation:    "ship",
+	GroupStation:   "group",
+	CoastStation:   "coast station",
+	SARAircraft:    "SAR aircraft",
+	Handheld:       "handheld",
+	AuxiliaryCraft: "auxiliary craft",
+	AtoN:           "aid to navigation",
+	SART:           "SART",
+	MOB:            "MOB",
+	EPIRB:          "EPIRB",
+}
+
+// String implements the Stringer interface for StationClass.
+func (c StationClass) String() string {
+	if c < 0 || int(c) >= len(stationClassNames) {
+		return fmt.Sprintf("StationClass(%d)", int(c))
+	}
+	return stationClassNames[c]
+}
+
+// Class returns the StationClass of the MMSI from its leading digits.  Malformed
+// MMSIs are UnknownStation.
+func (m MMSI) Class() StationClass {
+	if len(m) != 9 {
+		return UnknownStation
+	}
+	for _, c := range m {
+		if c < '0' || c > '9' {
+			return UnknownStation
+		}
+	}
+	switch {
+	case m[:3] == "970":
+		return SART
+	case m[:3] == "972":
+		return MOB
+	case m[:3] == "974":
+		return EPIRB
+	case m[:2] == "98":
+		return AuxiliaryCraft
+	case m[:2] == "99":
+		return AtoN
+	case m[:3] == "111":
+		return SARAircraft
+	case m[:2] == "00":
+		return CoastStation
+	case m[0] == '0':
+		return GroupStation
+	case m[0] == '8':
+		return Handheld
+	case m[0] >= '2' && m[0] <= '7':
+		return ShipStation
+	}
+	return UnknownStation
+}
+
+// StationFilter implements the Matching interface to select Records by the
+// StationClass of their MMSI.  Use NewStationFilter to create one.
+type StationFilter struct {
+	MMSIIndex int
+	classes   map[StationClass]bool
+}
+
+// NewStationFilter returns a StationFilter that matches Records whose MMSI,
+// found at mmsiIndex, belongs to one of the classes.  For example
+// NewStationFilter(0, ShipStation) removes base stations, aids to navigation,
+// and SARTs from a RecordSet passed to Subset.
+func NewStationFilter(mmsiIndex int, classes ...StationClass) *StationFilter {
+	sf := &StationFilter{MMSIIndex: mmsiIndex, classes: make(map[StationClass]bool)}
+	for _, c := range classes {
+		sf.classes[c] = true
+	}
+	return sf
+}
+
+// Match implements the Matching interface for a StationFilter.
+func (sf *StationFilter) Match(rec *Record) (bool, error) {
+	if sf.MMSIIndex >= len(*rec) {
+		return false, fmt.Errorf("station filter: record has no field %d", sf.MMSIIndex)
+	}
+	return sf.classes[MMSI((*rec)[sf.MMSIIndex]).Class()], nil
+}
+
+// WithStationClasses limits the Interactions to pairs of Records whose MMSIs
+// both belong to one of the classes.  WithStationClasses(ShipStation) keeps
+// base stations, aids to navigation, and distress beacons out of the analysis.
+func WithStationClasses(classes ...StationClass) InteractionOption {
+	return func(inter *Interactions) error {
+		if len(classes) == 0 {
+			return fmt.Errorf("station classes must not be empty")
+		}
+		inter.stations = NewStationFilter(inter.hashIndices[0], classes...)
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package ais
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMMSI_Class(t *testing.T) {
+	tests := map[MMSI]StationClass{
+		"366940480": ShipStation,
+		"036699999": GroupStation,
+		"003669999": CoastStation,
+		"111232500": SARAircraft,
+		"836612345": Handheld,
+		"983661234": AuxiliaryCraft,
+		"993661234": AtoN,
+		"970123456": SART,
+		"972123456": MOB,
+		"974123456": EPIRB,
+		"12345":     UnknownStation,
+		"912345678": UnknownStation,
+	}
+	for mmsi, want := range tests {
+		if got := mmsi.Class(); got != want {
+			t.Errorf("MMSI(%s).Class() = %v, want %v", mmsi, got, want)
+		}
+	}
+	if StationClass(99).String() != "StationClass(99)" {
+		t.Errorf("StationClass.String() = %q", StationClass(99).String())
+	}
+}
+
+func TestRecordSet_StationFilter(t *testing.T) {
+	data := "MMSI,LAT,LON\n366940480,30,-76\n003669999,30,-76\n993661234,30,-76\n970123456,30,-76\n477307901,30,-76\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	ships, err := rs.Subset(NewStationFilter(0, ShipStation))
+	if err != nil {
+		t.Fatalf("RecordSet.Subset() error = %v", err)
+	}
+	var got []string
+	for ships.Next() {
+		got = append(got, (*ships.Record())[0])
+	}
+	if want := []string{"366940480", "477307901"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecordSet.Subset(StationFilter) = %v, want %v", got, want)
+	}
+}
+
+func TestInteractions_WithStationClasses(t *testing.T) {
+	c := testClusters(1, 3)[0]
+	(*c.data[0])[0] = "366940480"
+	(*c.data[1])[0] = "477307901"
+	(*c.data[2])[0] = "993661234" // an aid to navigation alongside two vessels
+
+	inter, err := NewInteractionsWithOptions(goodHeaders, WithStationClasses(ShipStation))
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	if inter.Len() != 1 {
+		t.Errorf("Interactions.Len() = %d, want 1", inter.Len())
+	}
+	if _, err := NewInteractionsWithOptions(goodHeaders, WithStationClasses()); err == nil {
+		t.Error("NewInteractionsWithOptions() expected error for no station classes")
+	}
+}
+
+func TestDecoder_Stations(t *testing.T) {
+	type4 := new(bitWriter).uint(6, 4).uint(2, 0).uint(30, 3669999).uint(14, 2017).uint(4, 12).
+		uint(5, 1).uint(5, 0).uint(6, 0).uint(6, 1).uint(1, 1).
+		uint(28, 1<<28-76*600000).uint(27, 36*600000).uint(4, 7).uint(10, 0).uint(1, 0).uint(19, 0).armor()
+	type21 := new(bitWriter).uint(6, 21).uint(2, 0).uint(30, 993661234).uint(5, 14).text(120, "CHESAPEAKE LIGHT").
+		uint(1, 1).uint(28, 1<<28-75*600000).uint(27, 37*600000).
+		uint(9, 5).uint(9, 5).uint(6, 3).uint(6, 3).uint(4, 7).uint(6, 0).uint(1, 0).uint(8, 0).uint(1, 0).uint(1, 0).uint(1, 0).armor()
+	input := sentence("AIVDM,1,1,,A,"+type4+",0") + "\n" + sentence("AIVDM,1,1,,A,"+type21+",0") + "\n"
+
+	d := NewDecoder(strings.NewReader(input))
+	d.Clock = testClock
+	if _, err := d.Decode(); err != io.EOF {
+		t.Fatalf("Decoder.Decode() without Stations error = %v, want io.EOF", err)
+	}
+
+	d = NewDecoder(strings.NewReader(input))
+	d.Clock = testClock
+	d.Stations = true
+	base, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decoder.Decode() base station error = %v", err)
+	}
+	if want := (Record{"003669999", "2017-12-01T00:00:01", "36.00000", "-76.00000", "", "", "", "", "", "", "", "", "", "", "", ""}); !reflect.DeepEqual(*base, want) {
+		t.Errorf("Decoder.Decode() base station = %v, want %v", *base, want)
+	}
+	aton, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decoder.Decode() aid to navigation error = %v", err)
+	}
+	if want := (Record{"993661234", "2017-12-01T00:00:01", "37.00000", "-75.00000", "", "", "", "CHESAPEAKE LIGHT", "", "", "", "", "10", "6", "", ""}); !reflect.DeepEqual(*aton, want) {
+		t.Errorf("Decoder.Decode() aid to navigation = %v, want %v", *aton, want)
+	}
+}
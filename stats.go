@@ -0,0 +1,258 @@
+package ais
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsFields are the Headers of the csv file written by Stats.Save.
+const StatsFields = "Section,Key,Value"
+
+// IntervalBands are the upper limits of the report interval bands counted by
+// RecordSet.Stats.  A final band holds the intervals longer than the last
+// limit.
+var IntervalBands = []time.Duration{10 * time.Second, 30 * time.Second, time.Minute, 3 * time.Minute, 10 * time.Minute, time.Hour}
+
+// StatsMinGap is the shortest time without any Record that RecordSet.Stats
+// reports as a DataGap.
+var StatsMinGap = 10 * time.Minute
+
+// IntervalCount is the number of report intervals no longer than Max and longer
+// than the Max of the band before it.  The last band has a Max of zero, for no
+// limit.
+type IntervalCount struct {
+	Max   time.Duration
+	Count int
+}
+
+// ReportIntervals is the distribution of the time between consecutive reports
+// of the vessels of one VesselCategory.
+type ReportIntervals struct {
+	Category string
+	Count    int
+	Mean     time.Duration
+	Bands    []IntervalCount // by IntervalBands
+}
+
+// DataGap is a period in which the RecordSet holds no Record at all, such as a
+// receiver outage.
+type DataGap struct {
+	Start, End time.Time
+}
+
+// Duration returns the length of the DataGap.
+func (g DataGap) Duration() time.Duration { return g.End.Sub(g.Start) }
+
+// Stats is a data quality report of a RecordSet returned by RecordSet.Stats.
+type Stats struct {
+	Records    int
+	Vessels    int // unique MMSIs
+	Start, End time.Time
+	Hourly     map[time.Time]int // Records by the UTC hour they were reported in
+
+	// Coverage is the extent of the Positions, the Records with a valid LAT and
+	// LON.
+	Positions                      int
+	MinLat, MaxLat, MinLon, MaxLon float64
+
+	Intervals []ReportIntervals // by VesselCategory, for the categories with an interval
+	Gaps      []DataGap
+}
+
+// Stats reads the RecordSet and returns the basic data quality measures of a
+// day or other period of data: the number of Records and unique MMSIs, the
+// Records in each hour, the extent of the valid positions, the distribution of
+// the time between reports of each vessel by VesselCategory, and the DataGaps of
+// at least StatsMinGap, found to the minute, without any Record.  Report
+// intervals are measured between consecutive Records of an MMSI, so the
+// RecordSet should be sorted by time; repeated and out of order times are not
+// counted.  The Headers must contain MMSI and BaseDateTime.  Without LAT and
+// LON there is no coverage, and without VesselType every interval is in the
+// UnknownCategory.  Stats consumes the RecordSet.
+func (rs *RecordSet) Stats() (*Stats, error) {
+	h := rs.Headers()
+	idx, err := h.require("MMSI", "BaseDateTime")
+	if err != nil {
+		return nil, fmt.Errorf("stats: %w", err)
+	}
+	latIndex, haveLat := h.Contains("LAT")
+	lonIndex, haveLon := h.Contains("LON")
+
+	s := &Stats{
+		Hourly: make(map[time.Time]int),
+		MinLat: math.Inf(1), MaxLat: math.Inf(-1),
+		MinLon: math.Inf(1), MaxLon: math.Inf(-1),
+	}
+	last := make(map[string]time.Time)
+	minutes := make(map[int64]bool)
+	var intervals [OtherCategory + 1]struct {
+		n     int
+		sum   time.Duration
+		bands []int
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stats: read error on csv file: %w", err)
+		}
+		t, err := h.parseTime((*rec)[idx["BaseDateTime"].Idx])
+		if err != nil {
+			return nil, fmt.Errorf("stats: %w", err)
+		}
+		t = t.UTC()
+		s.Records++
+		if s.Start.IsZero() || t.Before(s.Start) {
+			s.Start = t
+		}
+		if t.After(s.End) {
+			s.End = t
+		}
+		s.Hourly[t.Truncate(time.Hour)]++
+		minutes[t.Unix()/60] = true
+
+		if haveLat && haveLon {
+			lat, errLat := rec.ParseFloat(latIndex)
+			lon, errLon := rec.ParseFloat(lonIndex)
+			if errLat == nil && errLon == nil && lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180 {
+				s.Positions++
+				s.MinLat, s.MaxLat = math.Min(s.MinLat, lat), math.Max(s.MaxLat, lat)
+				s.MinLon, s.MaxLon = math.Min(s.MinLon, lon), math.Max(s.MaxLon, lon)
+			}
+		}
+
+		mmsi := (*rec)[idx["MMSI"].Idx]
+		prev, seen := last[mmsi]
+		if !seen || t.After(prev) {
+			last[mmsi] = t
+		}
+		if !seen || !t.After(prev) {
+			continue
+		}
+		category := UnknownCategory
+		if vt, err := rec.VesselType(h); err == nil {
+			category = vt.Category()
+		}
+		in := &intervals[category]
+		if in.bands == nil {
+			in.bands = make([]int, len(IntervalBands)+1)
+		}
+		dt := t.Sub(prev)
+		in.n++
+		in.sum += dt
+		in.bands[sort.Search(len(IntervalBands), func(i int) bool { return IntervalBands[i] >= dt })]++
+	}
+	s.Vessels = len(last)
+	if s.Positions == 0 {
+		s.MinLat, s.MaxLat, s.MinLon, s.MaxLon = 0, 0, 0, 0
+	}
+
+	for c, in := range intervals {
+		if in.n == 0 {
+			continue
+		}
+		ri := ReportIntervals{Category: VesselCategory(c).String(), Count: in.n, Mean: in.sum / time.Duration(in.n)}
+		for i, n := range in.bands {
+			var max time.Duration
+			if i < len(IntervalBands) {
+				max = IntervalBands[i]
+			}
+			ri.Bands = append(ri.Bands, IntervalCount{Max: max, Count: n})
+		}
+		s.Intervals = append(s.Intervals, ri)
+	}
+
+	sorted := make([]int64, 0, len(minutes))
+	for m := range minutes {
+		sorted = append(sorted, m)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i := 1; i < len(sorted); i++ {
+		g := DataGap{Start: time.Unix((sorted[i-1]+1)*60, 0).UTC(), End: time.Unix(sorted[i]*60, 0).UTC()}
+		if g.Duration() >= StatsMinGap {
+			s.Gaps = append(s.Gaps, g)
+		}
+	}
+	return s, nil
+}
+
+// Save writes the Stats to a csv file with the Headers in StatsFields.  The
+// Section of each row is total, coverage, hour, interval, or gap.  Hour Keys are
+// the start of the hour in TimeLayout.  Interval Keys join the category and the
+// band limit, such as cargo/<=10s or cargo/>1h0m0s, or the category and mean,
+// whose Value is in seconds.  Gap Keys are the start of each DataGap and their
+// Values its length in minutes.
+func (s *Stats) Save(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("stats save: %w", err)
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	w.Write(strings.Split(StatsFields, ","))
+	row := func(section, key, value string) {
+		w.Write([]string{section, key, value})
+	}
+	row("total", "records", strconv.Itoa(s.Records))
+	row("total", "vessels", strconv.Itoa(s.Vessels))
+	row("total", "start", s.Start.Format(TimeLayout))
+	row("total", "end", s.End.Format(TimeLayout))
+	row("coverage", "positions", strconv.Itoa(s.Positions))
+	for _, c := range []struct {
+		key string
+		v   float64
+	}{{"minlat", s.MinLat}, {"maxlat", s.MaxLat}, {"minlon", s.MinLon}, {"maxlon", s.MaxLon}} {
+		row("coverage", c.key, strconv.FormatFloat(c.v, 'f', -1, 64))
+	}
+	hours := make([]time.Time, 0, len(s.Hourly))
+	for hour := range s.Hourly {
+		hours = append(hours, hour)
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Before(hours[j]) })
+	for _, hour := range hours {
+		row("hour", hour.Format(TimeLayout), strconv.Itoa(s.Hourly[hour]))
+	}
+	for _, ri := range s.Intervals {
+		row("interval", ri.Category+"/mean", fmt.Sprintf("%.1f", ri.Mean.Seconds()))
+		for i, b := range ri.Bands {
+			key := "<=" + b.Max.String()
+			if b.Max == 0 && i > 0 {
+				key = ">" + ri.Bands[i-1].Max.String()
+			}
+			row("interval", ri.Category+"/"+key, strconv.Itoa(b.Count))
+		}
+	}
+	for _, g := range s.Gaps {
+		row("gap", g.Start.Format(TimeLayout), fmt.Sprintf("%.0f", g.Duration().Minutes()))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("stats save: %w", err)
+	}
+	return out.Close()
+}
+
+// SaveJSON writes the Stats to a JSON file with the fields of Stats as members.
+// Durations are in nanoseconds, as encoding/json writes a time.Duration.
+func (s *Stats) SaveJSON(filename string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("stats save json: %w", err)
+	}
+	if err := ioutil.WriteFile(filename, append(b, '\n'), 0666); err != nil {
+		return fmt.Errorf("stats save json: %w", err)
+	}
+	return nil
+}
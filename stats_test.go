@@ -0,0 +1,83 @@
+package ais
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const statsData = `MMSI,BaseDateTime,LAT,LON,VesselType
+100000000,2017-12-01T00:00:00,36.0,-76.0,70
+200000000,2017-12-01T00:00:05,36.5,-75.5,
+100000000,2017-12-01T00:00:08,36.1,-76.1,70
+100000000,2017-12-01T00:00:08,36.1,-76.1,70
+200000000,2017-12-01T00:02:05,91.0,-75.5,
+100000000,2017-12-01T01:30:00,37.0,-76.2,70
+`
+
+func TestRecordSet_Stats(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(statsData), Headers{})
+	s, err := rs.Stats()
+	if err != nil {
+		t.Fatalf("RecordSet.Stats() error = %v", err)
+	}
+	if s.Records != 6 || s.Vessels != 2 || s.Positions != 5 {
+		t.Errorf("RecordSet.Stats() records, vessels, positions = %d, %d, %d, want 6, 2, 5", s.Records, s.Vessels, s.Positions)
+	}
+	if s.MinLat != 36 || s.MaxLat != 37 || s.MinLon != -76.2 || s.MaxLon != -75.5 {
+		t.Errorf("RecordSet.Stats() coverage = %v to %v, %v to %v", s.MinLat, s.MaxLat, s.MinLon, s.MaxLon)
+	}
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	if len(s.Hourly) != 2 || s.Hourly[start] != 5 || s.Hourly[start.Add(time.Hour)] != 1 {
+		t.Errorf("RecordSet.Stats() hourly = %v", s.Hourly)
+	}
+
+	// The repeated report is not an interval.
+	if len(s.Intervals) != 2 || s.Intervals[0].Category != "unknown" || s.Intervals[1].Category != "cargo" {
+		t.Fatalf("RecordSet.Stats() intervals = %+v", s.Intervals)
+	}
+	if in := s.Intervals[1]; in.Count != 2 || in.Bands[0].Count != 1 || in.Bands[len(in.Bands)-1].Count != 1 ||
+		in.Bands[len(in.Bands)-1].Max != 0 {
+		t.Errorf("RecordSet.Stats() cargo intervals = %+v", in)
+	}
+	if in := s.Intervals[0]; in.Count != 1 || in.Mean != 2*time.Minute || in.Bands[3].Count != 1 {
+		t.Errorf("RecordSet.Stats() unknown intervals = %+v", in)
+	}
+	if len(s.Gaps) != 1 || !s.Gaps[0].Start.Equal(start.Add(3*time.Minute)) || s.Gaps[0].Duration() != 87*time.Minute {
+		t.Errorf("RecordSet.Stats() gaps = %+v", s.Gaps)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "stats.csv")
+	if err := s.Save(filename); err != nil {
+		t.Fatalf("Stats.Save() error = %v", err)
+	}
+	data, _ := ioutil.ReadFile(filename)
+	for _, want := range []string{"total,vessels,2\n", "hour,2017-12-01T01:00:00,1\n", "interval,cargo/>1h0m0s,1\n", "gap,2017-12-01T00:03:00,87\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Stats.Save() wrote\n%s\nwant a row %q", data, want)
+		}
+	}
+	filename = filepath.Join(dir, "stats.json")
+	if err := s.SaveJSON(filename); err != nil {
+		t.Fatalf("Stats.SaveJSON() error = %v", err)
+	}
+	data, _ = ioutil.ReadFile(filename)
+	var got Stats
+	if err := json.Unmarshal(data, &got); err != nil || got.Records != 6 || len(got.Hourly) != 2 {
+		t.Errorf("Stats.SaveJSON() wrote %s, %v", data, err)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,LAT,LON\n"), Headers{})
+	if _, err := rs.Stats(); err == nil {
+		t.Errorf("RecordSet.Stats() without BaseDateTime error = nil, want an error")
+	}
+}
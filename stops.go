@@ -0,0 +1,181 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StopFields are the Headers of the RecordSet returned by RecordSet.Stops.
+// Duration is in minutes.
+const StopFields = "MMSI,LAT,LON,Start,End,Duration,Records"
+
+// Stop is a period during which a vessel stayed in one place, such as a port
+// call or a stay at anchor.  LAT and LON are the mean position of the reports
+// in the Stop.
+type Stop struct {
+	MMSI       string
+	Lat, Lon   float64
+	Start, End time.Time
+	Records    int
+}
+
+// Duration returns the time elapsed between the first and last reports of the
+// Stop.
+func (s Stop) Duration() time.Duration { return s.End.Sub(s.Start) }
+
+// Stops returns the periods in which the vessel reported a SOG below maxSOG knots
+// while staying within radius nautical miles of the mean position of the period,
+// for at least minDuration.  A report with a SOG at or above maxSOG, one farther
+// than radius from the mean position, or an unavailable SOG ends the period.
+// The Headers must contain SOG.
+func (t *Track) Stops(maxSOG, radius float64, minDuration time.Duration) ([]Stop, error) {
+	if maxSOG <= 0 || radius <= 0 {
+		return nil, fmt.Errorf("track stops: maxSOG and radius must be positive, got %v and %v", maxSOG, radius)
+	}
+	sogIndex, ok := t.h.Contains("SOG")
+	if !ok {
+		return nil, fmt.Errorf("track stops: %w", ErrMissingHeader{Field: "SOG"})
+	}
+
+	var stops []Stop
+	var cur Stop
+	var sumLat, sumLon float64
+	end := func() {
+		if cur.Records > 0 && cur.Duration() >= minDuration {
+			cur.Lat, cur.Lon = sumLat/float64(cur.Records), sumLon/float64(cur.Records)
+			stops = append(stops, cur)
+		}
+		cur = Stop{MMSI: t.MMSI}
+		sumLat, sumLon = 0, 0
+	}
+	cur.MMSI = t.MMSI
+
+	for i, rec := range t.data {
+		sog, err := rec.ParseFloat(sogIndex)
+		if err != nil || math.IsNaN(sog) || sog >= maxSOG || sog >= 102.3 {
+			end()
+			continue
+		}
+		lat, lon, err := t.position(i)
+		if err != nil {
+			return nil, fmt.Errorf("track stops: %w", err)
+		}
+		if cur.Records > 0 {
+			n := float64(cur.Records)
+			if Haversine(sumLat/n, sumLon/n, lat, lon) > radius {
+				end()
+			}
+		}
+		if cur.Records == 0 {
+			cur.Start = t.times[i]
+		}
+		cur.End = t.times[i]
+		cur.Records++
+		sumLat += lat
+		sumLon += lon
+	}
+	end()
+	return stops, nil
+}
+
+// Stops returns a stops report with the StopFields Headers and one Record for
+// every Stop of every vessel in the RecordSet, ordered by MMSI and then Start.
+// The arguments are the same as for Track.Stops.  Stops holds the Tracks of
+// every vessel in memory and consumes the receiver.
+func (rs *RecordSet) Stops(maxSOG, radius float64, minDuration time.Duration) (*RecordSet, error) {
+	if _, ok := rs.Headers().Contains("SOG"); !ok {
+		return nil, fmt.Errorf("stops: %w", ErrMissingHeader{Field: "SOG"})
+	}
+	tracks, err := rs.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("stops: %w", err)
+	}
+	mmsis := make([]string, 0, len(tracks))
+	for mmsi := range tracks {
+		mmsis = append(mmsis, mmsi)
+	}
+	sort.Strings(mmsis)
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(Headers{Fields: strings.Split(StopFields, ",")})
+	written := 0
+	for _, mmsi := range mmsis {
+		stops, err := tracks[mmsi].Stops(maxSOG, radius, minDuration)
+		if err != nil {
+			return nil, fmt.Errorf("stops: %w", err)
+		}
+		for _, s := range stops {
+			rs2.Write(Record{
+				s.MMSI,
+				strconv.FormatFloat(s.Lat, 'f', 5, 64),
+				strconv.FormatFloat(s.Lon, 'f', 5, 64),
+				s.Start.Format(TimeLayout),
+				s.End.Format(TimeLayout),
+				fmt.Sprintf("%.1f", s.Duration().Minutes()),
+				strconv.Itoa(s.Records),
+			})
+			written++
+			if written%flushThreshold == 0 {
+				if err := rs2.Flush(); err != nil {
+					return nil, fmt.Errorf("stops: csv flush error: %w", err)
+				}
+			}
+		}
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("stops: csv flush error: %w", err)
+	}
+	return rs2, nil
+}
+
+// StopFilter implements Matching for Records that are not reported during one of
+// a set of Stops, so that anchored and moored vessels can be removed with Subset
+// before encounter analysis.  Records with an MMSI and BaseDateTime inside the
+// span of a Stop for that MMSI do not match.
+type StopFilter struct {
+	MMSIIndex, TimeIndex int
+	stops                map[string][]Stop
+	timeParser           TimeParser // from the Headers, nil for TimeLayout
+}
+
+// NewStopFilter returns a *StopFilter for stops and Records described by h, which
+// must contain MMSI and BaseDateTime.  Times are parsed with the TimeParser of h.
+func NewStopFilter(h Headers, stops []Stop) (*StopFilter, error) {
+	idx, err := h.require("MMSI", "BaseDateTime")
+	if err != nil {
+		return nil, fmt.Errorf("new stop filter: %w", err)
+	}
+	f := &StopFilter{
+		MMSIIndex:  idx["MMSI"].Idx,
+		TimeIndex:  idx["BaseDateTime"].Idx,
+		stops:      make(map[string][]Stop),
+		timeParser: h.Time,
+	}
+	for _, s := range stops {
+		f.stops[s.MMSI] = append(f.stops[s.MMSI], s)
+	}
+	return f, nil
+}
+
+// Match implements the Matching interface.  It returns false for Records reported
+// during a Stop.
+func (f *StopFilter) Match(rec *Record) (bool, error) {
+	stops := f.stops[(*rec)[f.MMSIIndex]]
+	if len(stops) == 0 {
+		return true, nil
+	}
+	t, err := Headers{Time: f.timeParser}.parseTime((*rec)[f.TimeIndex])
+	if err != nil {
+		return false, fmt.Errorf("stop filter: %w", err)
+	}
+	for _, s := range stops {
+		if !t.Before(s.Start) && !t.After(s.End) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,119 @@
+package ais
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const stopsData = `MMSI,BaseDateTime,LAT,LON,SOG
+100000000,2017-12-01T00:00:00,30.00000,-76.00000,10.0
+100000000,2017-12-01T00:10:00,30.00000,-76.00000,0.1
+100000000,2017-12-01T00:40:00,30.00100,-76.00000,0.2
+100000000,2017-12-01T01:10:00,30.00000,-76.00100,0.0
+100000000,2017-12-01T01:20:00,30.10000,-76.00000,0.3
+100000000,2017-12-01T01:25:00,30.20000,-76.00000,12.0
+100000000,2017-12-01T01:30:00,30.30000,-76.00000,0.1
+200000000,2017-12-01T00:00:00,31.00000,-76.00000,0.1
+200000000,2017-12-01T02:00:00,31.00000,-76.00000,102.3
+200000000,2017-12-01T03:00:00,31.00000,-76.00000,0.1
+200000000,2017-12-01T05:00:00,31.00000,-76.00000,0.1
+`
+
+func TestTrack_Stops(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(stopsData), Headers{})
+	tracks, err := rs.Tracks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stops, err := tracks["100000000"].Stops(0.5, 0.5, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("Track.Stops() error = %v", err)
+	}
+	// The report at 01:20 is 6nm away, which ends the stop, and the single reports
+	// after it are shorter than the minimum duration.
+	if len(stops) != 1 {
+		t.Fatalf("Track.Stops() returned %d stops, want 1: %+v", len(stops), stops)
+	}
+	s := stops[0]
+	if s.Records != 3 || s.Duration() != time.Hour || s.Start.Format(TimeLayout) != "2017-12-01T00:10:00" {
+		t.Errorf("Track.Stops() = %+v", s)
+	}
+	if s.Lat < 30.0003 || s.Lat > 30.0004 {
+		t.Errorf("Track.Stops() Lat = %v, want mean position", s.Lat)
+	}
+
+	if _, err := tracks["100000000"].Stops(0, 1, time.Hour); err == nil {
+		t.Error("Track.Stops() expected error for zero maxSOG")
+	}
+}
+
+func TestRecordSet_Stops(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader(stopsData), Headers{})
+	report, err := rs.Stops(0.5, 0.5, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("RecordSet.Stops() error = %v", err)
+	}
+	if !report.Headers().Equals(Headers{Fields: strings.Split(StopFields, ",")}) {
+		t.Errorf("RecordSet.Stops() headers = %v", report.Headers())
+	}
+	var got []string
+	for {
+		rec, err := report.Read()
+		if err != nil {
+			break
+		}
+		got = append(got, strings.Join(*rec, ","))
+	}
+	want := []string{
+		"100000000,30.00033,-76.00033,2017-12-01T00:10:00,2017-12-01T01:10:00,60.0,3",
+		"200000000,31.00000,-76.00000,2017-12-01T03:00:00,2017-12-01T05:00:00,120.0,2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("RecordSet.Stops() report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT,LON\n"), Headers{})
+	if _, err := rs.Stops(0.5, 0.5, time.Minute); err == nil {
+		t.Error("RecordSet.Stops() expected error for headers without SOG")
+	}
+}
+
+func TestStopFilter(t *testing.T) {
+	h := Headers{Fields: strings.Split("MMSI,BaseDateTime,LAT,LON,SOG", ",")}
+	start, _ := time.Parse(TimeLayout, "2017-12-01T00:10:00")
+	f, err := NewStopFilter(h, []Stop{{MMSI: "100000000", Start: start, End: start.Add(time.Hour)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rec  Record
+		want bool
+	}{
+		{Record{"100000000", "2017-12-01T00:00:00", "0", "0", "0"}, true},
+		{Record{"100000000", "2017-12-01T00:10:00", "0", "0", "0"}, false},
+		{Record{"100000000", "2017-12-01T01:10:00", "0", "0", "0"}, false},
+		{Record{"200000000", "2017-12-01T00:30:00", "0", "0", "0"}, true},
+	}
+	for _, tt := range tests {
+		got, err := f.Match(&tt.rec)
+		if err != nil || got != tt.want {
+			t.Errorf("StopFilter.Match(%v) = %v, %v, want %v", tt.rec, got, err, tt.want)
+		}
+	}
+}
+
+func TestStopFilter_TimeParser(t *testing.T) {
+	h := Headers{Fields: strings.Split("MMSI,BaseDateTime", ","), Time: NewTimeParser("02/01/2006 15:04:05")}
+	start, _ := time.Parse(TimeLayout, "2017-12-01T00:10:00")
+	f, err := NewStopFilter(h, []Stop{{MMSI: "100000000", Start: start, End: start.Add(time.Hour)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rec, want := range map[string]bool{"01/12/2017 00:00:00": true, "01/12/2017 00:30:00": false} {
+		got, err := f.Match(&Record{"100000000", rec})
+		if err != nil || got != want {
+			t.Errorf("StopFilter.Match(%s) = %v, %v, want %v", rec, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package ais
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DistanceBands are the upper limits in nautical miles of the distance bands
+// counted by Interactions.Summary.  A final band holds the pairs farther apart
+// than the last limit.
+var DistanceBands = []float64{0.1, 0.25, 0.5, 1, 2, 5}
+
+// SummaryTopPairs is the number of vessel pairs listed in the TopPairs of an
+// InteractionSummary.
+const SummaryTopPairs = 10
+
+// SummaryFields are the Headers of the csv file written by
+// InteractionSummary.Save.
+const SummaryFields = "Section,Key,Count"
+
+// BandCount is the number of interactions no farther apart than Max nautical
+// miles and farther apart than the Max of the band before it.  The last band of
+// a summary has an infinite Max.
+type BandCount struct {
+	Max   float64
+	Count int
+}
+
+// TypePair is the VesselType codes of the two vessels of an interaction, the
+// smaller code first.  An unknown VesselType is the empty string.
+type TypePair [2]string
+
+// PairCount is the number of interactions between two vessels, the smaller MMSI
+// first.
+type PairCount struct {
+	MMSI1, MMSI2 string
+	Count        int
+}
+
+// InteractionSummary is an overview of an Interactions set returned by
+// Interactions.Summary.
+type InteractionSummary struct {
+	Total     int              // number of interactions
+	Distance  []BandCount      // interactions by DistanceBands
+	TypePairs map[TypePair]int // interactions by VesselType pair, empty without a VesselType header
+	Hours     [24]int          // interactions by UTC hour of day of the first vessel's report
+	TopPairs  []PairCount      // the SummaryTopPairs most frequent vessel pairs, most frequent first
+}
+
+// Summary counts the interactions in the set by distance band, by VesselType
+// pair, and by hour of day, and finds the vessel pairs that interact most often,
+// so that a large set can be reviewed without loading the output of Save into
+// another tool.  The RecordHeaders must contain MMSI, BaseDateTime, LAT, and LON.
+// Distances use the DistanceFunc of the Interactions.
+func (inter *Interactions) Summary() (*InteractionSummary, error) {
+	if _, err := inter.RecordHeaders.require("MMSI", "BaseDateTime", "LAT", "LON"); err != nil {
+		return nil, fmt.Errorf("interactions summary: %w", err)
+	}
+	mmsiIndex, timeIndex := inter.hashIndices[0], inter.hashIndices[1]
+	typeIndex, haveType := inter.RecordHeaders.Contains("VesselType")
+
+	s := &InteractionSummary{
+		Distance:  make([]BandCount, len(DistanceBands)+1),
+		TypePairs: make(map[TypePair]int),
+	}
+	for i, max := range DistanceBands {
+		s.Distance[i].Max = max
+	}
+	s.Distance[len(DistanceBands)].Max = math.Inf(1)
+
+	vessels := make(map[[2]string]int)
+	err := inter.each(func(hash Hash128, pair *RecordPair) error {
+		rec1, rec2 := *pair.rec1, *pair.rec2
+		d, err := inter.distanceOf(pair)
+		if err != nil {
+			return err
+		}
+		t, err := inter.RecordHeaders.parseTime(rec1[timeIndex])
+		if err != nil {
+			return ErrParse{Field: "BaseDateTime", Err: err}
+		}
+
+		s.Total++
+		s.Distance[sort.SearchFloat64s(DistanceBands, d)].Count++
+		s.Hours[t.UTC().Hour()]++
+		if haveType {
+			tp := TypePair{rec1[typeIndex], rec2[typeIndex]}
+			if tp[1] < tp[0] {
+				tp[0], tp[1] = tp[1], tp[0]
+			}
+			s.TypePairs[tp]++
+		}
+		vp := [2]string{rec1[mmsiIndex], rec2[mmsiIndex]}
+		if vp[1] < vp[0] {
+			vp[0], vp[1] = vp[1], vp[0]
+		}
+		vessels[vp]++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("interactions summary: %w", err)
+	}
+
+	for vp, n := range vessels {
+		s.TopPairs = append(s.TopPairs, PairCount{MMSI1: vp[0], MMSI2: vp[1], Count: n})
+	}
+	sort.Slice(s.TopPairs, func(i, j int) bool {
+		a, b := s.TopPairs[i], s.TopPairs[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.MMSI1 != b.MMSI1 {
+			return a.MMSI1 < b.MMSI1
+		}
+		return a.MMSI2 < b.MMSI2
+	})
+	if len(s.TopPairs) > SummaryTopPairs {
+		s.TopPairs = s.TopPairs[:SummaryTopPairs]
+	}
+	return s, nil
+}
+
+// Save writes the summary to a csv file with the Headers in SummaryFields.  The
+// Section of each row is total, distance, vesseltype, hour, or pair.  Distance
+// Keys are the band limit, such as <=0.5 or >5, VesselType Keys and pair Keys
+// join the two values with a slash, and hour Keys are 00 through 23.
+func (s *InteractionSummary) Save(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("summary save: %w", err)
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	w.Write(strings.Split(SummaryFields, ","))
+	row := func(section, key string, n int) {
+		w.Write([]string{section, key, strconv.Itoa(n)})
+	}
+	row("total", "", s.Total)
+	for i, b := range s.Distance {
+		key := "<=" + strconv.FormatFloat(b.Max, 'f', -1, 64)
+		if math.IsInf(b.Max, 1) && i > 0 {
+			key = ">" + strconv.FormatFloat(s.Distance[i-1].Max, 'f', -1, 64)
+		}
+		row("distance", key, b.Count)
+	}
+	types := make([]TypePair, 0, len(s.TypePairs))
+	for tp := range s.TypePairs {
+		types = append(types, tp)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i][0] != types[j][0] {
+			return types[i][0] < types[j][0]
+		}
+		return types[i][1] < types[j][1]
+	})
+	for _, tp := range types {
+		row("vesseltype", tp[0]+"/"+tp[1], s.TypePairs[tp])
+	}
+	for h, n := range s.Hours {
+		row("hour", fmt.Sprintf("%02d", h), n)
+	}
+	for _, p := range s.TopPairs {
+		row("pair", p.MMSI1+"/"+p.MMSI2, p.Count)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("summary save: %w", err)
+	}
+	return nil
+}
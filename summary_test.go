@@ -0,0 +1,72 @@
+package ais
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInteractions_Summary(t *testing.T) {
+	inter, _ := NewInteractions(goodHeaders)
+	rec := func(mmsi, ts, lat, vesselType string) *Record {
+		return &Record{mmsi, ts, lat, "-76.0", "", "", "", "", "", "", vesselType, "", "", "", "", ""}
+	}
+	c1 := new(Cluster)
+	c1.Append(rec("100000001", "2017-12-01T05:00:00", "30.000", "70"))
+	c1.Append(rec("100000002", "2017-12-01T05:00:00", "30.001", "80"))
+	c1.Append(rec("100000003", "2017-12-01T05:00:00", "30.010", ""))
+	c2 := new(Cluster)
+	c2.Append(rec("100000001", "2017-12-01T06:00:00", "30.000", "70"))
+	c2.Append(rec("100000002", "2017-12-01T06:00:00", "30.001", "80"))
+	for _, c := range []*Cluster{c1, c2} {
+		if err := inter.AddCluster(c); err != nil {
+			t.Fatalf("Interactions.AddCluster() error = %v", err)
+		}
+	}
+
+	s, err := inter.Summary()
+	if err != nil {
+		t.Fatalf("Interactions.Summary() error = %v", err)
+	}
+	if s.Total != 4 {
+		t.Errorf("Summary().Total = %d, want 4", s.Total)
+	}
+	if s.Distance[0].Count != 2 || s.Distance[3].Count != 2 {
+		t.Errorf("Summary().Distance = %v, want 2 in <=0.1 and 2 in <=1", s.Distance)
+	}
+	if s.Hours[5] != 3 || s.Hours[6] != 1 {
+		t.Errorf("Summary().Hours[5:7] = %v, want [3 1]", s.Hours[5:7])
+	}
+	wantTypes := map[TypePair]int{{"70", "80"}: 2, {"", "70"}: 1, {"", "80"}: 1}
+	for tp, n := range wantTypes {
+		if s.TypePairs[tp] != n {
+			t.Errorf("Summary().TypePairs[%v] = %d, want %d", tp, s.TypePairs[tp], n)
+		}
+	}
+	if len(s.TopPairs) != 3 || s.TopPairs[0] != (PairCount{"100000001", "100000002", 2}) {
+		t.Errorf("Summary().TopPairs = %v, want 3 pairs led by 100000001/100000002", s.TopPairs)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "summary.csv")
+	if err := s.Save(filename); err != nil {
+		t.Fatalf("InteractionSummary.Save() error = %v", err)
+	}
+	b, _ := ioutil.ReadFile(filename)
+	for _, line := range []string{SummaryFields, "total,,4", "distance,>5,0", "vesseltype,70/80,2", "hour,05,3", "pair,100000001/100000002,2"} {
+		if !strings.Contains(string(b), line+"\n") {
+			t.Errorf("InteractionSummary.Save() output is missing %q", line)
+		}
+	}
+
+	bad, _ := NewInteractions(Headers{Fields: []string{"LAT", "LON"}})
+	if _, err := bad.Summary(); err == nil {
+		t.Error("Interactions.Summary() expected error for missing MMSI header")
+	}
+}
@@ -0,0 +1,175 @@
+package ais
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ThinKeep selects the Record kept for each vessel and interval by
+// RecordSet.Thin.
+type ThinKeep int
+
+const (
+	// KeepFirst keeps the first Record of each interval.
+	KeepFirst ThinKeep = iota
+
+	// KeepLast keeps the last Record of each interval.
+	KeepLast
+
+	// KeepNearest keeps the Record nearest in time to each multiple of the
+	// interval, so the thinned track is sampled on a regular grid.  Its
+	// intervals are centered on the grid rather than starting at it.
+	KeepNearest
+)
+
+var thinKeepNames = [...]string{
+	KeepFirst:   "first",
+	KeepLast:    "last",
+	KeepNearest: "nearest",
+}
+
+// String implements the Stringer interface for ThinKeep.
+func (k ThinKeep) String() string {
+	if k < 0 || int(k) >= len(thinKeepNames) {
+		return fmt.Sprintf("ThinKeep(%d)", int(k))
+	}
+	return thinKeepNames[k]
+}
+
+// thinCandidate is the Record kept so far for one vessel in the current
+// interval.
+type thinCandidate struct {
+	rec    Record
+	t      time.Time
+	bucket time.Time
+}
+
+// Thin returns a pointer to a new RecordSet with at most one Record for each
+// MMSI in each interval, chosen by keep.  Intervals are aligned to multiples of
+// interval since the zero time, so the kept Records of different vessels fall
+// in the same intervals.  Satellite receivers often deliver the same report
+// several times in a burst and Class A transponders report every few seconds
+// under way, so thinning to a minute or so shrinks both a dataset and the
+// number of candidate pairs compared by FindInteractions many times over.  One
+// Record per vessel is held in memory while the RecordSet is read.  When the
+// RecordSet is sorted by time so is the result.  The Headers must contain MMSI
+// and BaseDateTime.  Like Subset, Thin returns ErrEmptySet when no Records are
+// written.  Thin consumes the receiver.
+func (rs *RecordSet) Thin(interval time.Duration, keep ThinKeep) (*RecordSet, error) {
+	return rs.ThinContext(context.Background(), interval, keep)
+}
+
+// ThinContext is Thin with a Context that stops the scan when it is canceled.
+func (rs *RecordSet) ThinContext(ctx context.Context, interval time.Duration, keep ThinKeep) (*RecordSet, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("thin: interval must be greater than zero, got %v", interval)
+	}
+	if keep < KeepFirst || keep > KeepNearest {
+		return nil, fmt.Errorf("thin: unknown keep %v", keep)
+	}
+	h := rs.Headers()
+	idx, err := h.require("MMSI", "BaseDateTime")
+	if err != nil {
+		return nil, fmt.Errorf("thin: %w", err)
+	}
+	mmsiIndex, timeIndex := idx["MMSI"].Idx, idx["BaseDateTime"].Idx
+
+	rs2 := NewRecordSet()
+	rs2.SetHeaders(h)
+	written := 0
+	write := func(cands []thinCandidate) error {
+		sort.Slice(cands, func(i, j int) bool {
+			if !cands[i].t.Equal(cands[j].t) {
+				return cands[i].t.Before(cands[j].t)
+			}
+			return cands[i].rec[mmsiIndex] < cands[j].rec[mmsiIndex]
+		})
+		for _, c := range cands {
+			if err := rs2.Write(c.rec); err != nil {
+				return fmt.Errorf("thin: csv write error: %w", err)
+			}
+			written++
+			if written%flushThreshold == 0 {
+				if err := rs2.Flush(); err != nil {
+					return fmt.Errorf("thin: csv flush error: %w", err)
+				}
+			}
+		}
+		return nil
+	}
+
+	// pending holds the candidate of each vessel, which is written when a
+	// Record of a later interval is read.
+	pending := make(map[string]*thinCandidate)
+	var current time.Time
+	pt := startProgress(ctx, "thin", rs)
+	n := 0
+	for ; ; n++ {
+		if err := canceled(ctx, n); err != nil {
+			return nil, fmt.Errorf("thin: %w", err)
+		}
+		pt.update(n)
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("thin: read error on csv file: %w", err)
+		}
+		t, err := h.parseTime((*rec)[timeIndex])
+		if err != nil {
+			return nil, fmt.Errorf("thin: %w", ErrParse{Field: "BaseDateTime", Err: err})
+		}
+		bucket := t.Truncate(interval)
+		if keep == KeepNearest {
+			bucket = t.Round(interval)
+		}
+
+		if bucket.After(current) {
+			// Every candidate is from an earlier interval.
+			cands := make([]thinCandidate, 0, len(pending))
+			for _, c := range pending {
+				cands = append(cands, *c)
+			}
+			if err := write(cands); err != nil {
+				return nil, err
+			}
+			pending = make(map[string]*thinCandidate)
+			current = bucket
+		}
+
+		mmsi := (*rec)[mmsiIndex]
+		c, ok := pending[mmsi]
+		switch {
+		case !ok:
+			pending[mmsi] = &thinCandidate{*rec, t, bucket}
+		case !c.bucket.Equal(bucket):
+			// An out of order Record of an earlier interval.
+			if err := write([]thinCandidate{*c}); err != nil {
+				return nil, err
+			}
+			pending[mmsi] = &thinCandidate{*rec, t, bucket}
+		case keep == KeepLast && !t.Before(c.t),
+			keep == KeepNearest && absDuration(t.Sub(bucket)) < absDuration(c.t.Sub(bucket)):
+			*c = thinCandidate{*rec, t, bucket}
+		}
+	}
+	cands := make([]thinCandidate, 0, len(pending))
+	for _, c := range pending {
+		cands = append(cands, *c)
+	}
+	if err := write(cands); err != nil {
+		return nil, err
+	}
+	if err := rs2.Flush(); err != nil {
+		return nil, fmt.Errorf("thin: csv flush error: %w", err)
+	}
+	pt.done(n)
+	if written == 0 {
+		return rs2, ErrEmptySet
+	}
+	return rs2, nil
+}
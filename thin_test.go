@@ -0,0 +1,78 @@
+package ais
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordSet_Thin(t *testing.T) {
+	const data = "MMSI,BaseDateTime,LAT,LON\n" +
+		"1,2017-12-01T00:00:05,36.00000,-76.00000\n" +
+		"2,2017-12-01T00:00:10,37.00000,-76.00000\n" +
+		"1,2017-12-01T00:00:20,36.00100,-76.00000\n" +
+		"1,2017-12-01T00:00:20,36.00100,-76.00000\n" +
+		"1,2017-12-01T00:00:50,36.00200,-76.00000\n" +
+		"2,2017-12-01T00:00:55,37.00100,-76.00000\n" +
+		"1,2017-12-01T00:01:10,36.00300,-76.00000\n"
+
+	tests := []struct {
+		keep ThinKeep
+		want []string // MMSI and BaseDateTime of the kept Records
+	}{
+		{KeepFirst, []string{
+			"1 2017-12-01T00:00:05", "2 2017-12-01T00:00:10", "1 2017-12-01T00:01:10",
+		}},
+		{KeepLast, []string{
+			"1 2017-12-01T00:00:50", "2 2017-12-01T00:00:55", "1 2017-12-01T00:01:10",
+		}},
+		{KeepNearest, []string{ // grid points at 00:00 and 00:01
+			"1 2017-12-01T00:00:05", "2 2017-12-01T00:00:10", "1 2017-12-01T00:00:50", "2 2017-12-01T00:00:55",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keep.String(), func(t *testing.T) {
+			rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs2, err := rs.Thin(time.Minute, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for {
+				rec, err := rs2.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, (*rec)[0]+" "+(*rec)[1])
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Thin(%v) =\n%s\nwant\n%s", tt.keep, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestRecordSet_ThinErrors(t *testing.T) {
+	rs, _ := NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime\n"), Headers{})
+	if _, err := rs.Thin(time.Minute, KeepFirst); err != ErrEmptySet {
+		t.Errorf("Thin() of an empty set = %v, want ErrEmptySet", err)
+	}
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,LAT\n1,2\n"), Headers{})
+	if _, err := rs.Thin(time.Minute, KeepFirst); err == nil {
+		t.Error("Thin() without BaseDateTime should fail")
+	}
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime\n"), Headers{})
+	if _, err := rs.Thin(0, KeepFirst); err == nil {
+		t.Error("Thin() with a zero interval should fail")
+	}
+	if s := ThinKeep(7).String(); s != "ThinKeep(7)" {
+		t.Errorf("ThinKeep(7).String() = %q", s)
+	}
+}
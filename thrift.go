@@ -0,0 +1,262 @@
+package ais
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Thrift compact protocol type identifiers used by the Parquet file metadata.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the subset of the Thrift compact protocol needed to write
+// Parquet metadata.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	lastID int16
+	stack  []int16
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func (w *thriftWriter) zigzag(v int64) { w.varint(uint64((v << 1) ^ (v >> 63))) }
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	if delta := id - w.lastID; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	w.lastID = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) str(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// list writes the header for a list of n elements of type elem.  The elements
+// are then written with the element methods or beginStruct(0).
+func (w *thriftWriter) list(id int16, elem byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elem)
+	} else {
+		w.buf.WriteByte(0xf0 | elem)
+		w.varint(uint64(n))
+	}
+}
+
+func (w *thriftWriter) elemI32(v int32) { w.zigzag(int64(v)) }
+
+func (w *thriftWriter) elemStr(s string) {
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// beginStruct opens a nested struct.  Struct elements of a list are opened with
+// an id of zero, which writes no field header.
+func (w *thriftWriter) beginStruct(id int16) {
+	if id != 0 {
+		w.field(id, thriftStruct)
+	}
+	w.stack = append(w.stack, w.lastID)
+	w.lastID = 0
+}
+
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.lastID = w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+}
+
+// thriftFields holds a decoded struct as a map of field id to value.  Values are
+// int64 for all integer types, bool, float64, []byte, []interface{} for lists and
+// sets, and thriftFields for nested structs.  Maps are skipped.
+type thriftFields map[int16]interface{}
+
+func (f thriftFields) int(id int16) int64 {
+	v, _ := f[id].(int64)
+	return v
+}
+
+func (f thriftFields) str(id int16) string {
+	v, _ := f[id].([]byte)
+	return string(v)
+}
+
+func (f thriftFields) list(id int16) []interface{} {
+	v, _ := f[id].([]interface{})
+	return v
+}
+
+func (f thriftFields) child(id int16) thriftFields {
+	v, _ := f[id].(thriftFields)
+	return v
+}
+
+// thriftMaxDepth is the deepest nesting of structs and lists that thriftReader
+// decodes.  Parquet metadata nests no more than a few levels.
+const thriftMaxDepth = 64
+
+// thriftReader decodes Thrift compact protocol structs from an in-memory buffer
+// so that callers can tell how many bytes each struct consumed.  Lengths and
+// counts are checked against the bytes left in the buffer before anything is
+// allocated so that corrupt metadata returns an error.
+type thriftReader struct {
+	r     *bytes.Reader
+	depth int
+}
+
+func (r *thriftReader) varint() (uint64, error) { return binary.ReadUvarint(r.r) }
+
+func (r *thriftReader) zigzag() (int64, error) {
+	u, err := r.varint()
+	return int64(u>>1) ^ -int64(u&1), err
+}
+
+func (r *thriftReader) readStruct() (thriftFields, error) {
+	if r.depth++; r.depth > thriftMaxDepth {
+		return nil, fmt.Errorf("thrift: nested deeper than %d levels", thriftMaxDepth)
+	}
+	defer func() { r.depth-- }()
+	f := make(thriftFields)
+	var lastID int16
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return f, nil
+		}
+		typ := b & 0x0f
+		id := lastID + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := r.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		lastID = id
+		switch typ {
+		case thriftTrue:
+			f[id] = true
+		case thriftFalse:
+			f[id] = false
+		default:
+			if f[id], err = r.value(typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (r *thriftReader) value(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftTrue, thriftFalse, thriftByte:
+		b, err := r.r.ReadByte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftDouble:
+		var b [8]byte
+		_, err := io.ReadFull(r.r, b[:])
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), err
+	case thriftBinary:
+		n, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.r.Len()) {
+			return nil, fmt.Errorf("thrift: binary of %d bytes is longer than the %d bytes left", n, r.r.Len())
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r.r, b)
+		return b, err
+	case thriftList, thriftSet:
+		h, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(h >> 4)
+		if n == 15 {
+			if n, err = r.varint(); err != nil {
+				return nil, err
+			}
+		}
+		if n > uint64(r.r.Len()) { // every element takes at least one byte
+			return nil, fmt.Errorf("thrift: list of %d elements is longer than the %d bytes left", n, r.r.Len())
+		}
+		if r.depth++; r.depth > thriftMaxDepth {
+			return nil, fmt.Errorf("thrift: nested deeper than %d levels", thriftMaxDepth)
+		}
+		defer func() { r.depth-- }()
+		elems := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := r.value(h & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, v)
+		}
+		return elems, nil
+	case thriftMap:
+		n, err := r.varint()
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		if n > uint64(r.r.Len()) {
+			return nil, fmt.Errorf("thrift: map of %d entries is longer than the %d bytes left", n, r.r.Len())
+		}
+		kv, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < 2*n; i++ {
+			typ := kv >> 4
+			if i%2 == 1 {
+				typ = kv & 0x0f
+			}
+			if _, err := r.value(typ); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStruct:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("thrift: unknown type %d", typ)
+}
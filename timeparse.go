@@ -0,0 +1,114 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeParser parses the BaseDateTime field of a Record.  Set the Time of the
+// Headers of a RecordSet to read files whose timestamps are not in TimeLayout,
+// for example
+//
+//	rs.SetHeaders(rs.Headers().WithTimeParser(ais.FlexibleTimeParser))
+//
+// after which sorting, windows, tracks, interactions, and Record.Time use the
+// parser.  A TimeParser must return times in UTC so that times read from
+// different layouts compare and format consistently.
+type TimeParser func(s string) (time.Time, error)
+
+// EpochLayout is a layout for NewTimeParser that reads a count of seconds since
+// the Unix epoch, with an optional fraction, such as 1512086474 or
+// 1512086474.250.
+const EpochLayout = "epoch"
+
+// FlexibleTimeParser accepts the timestamp layouts found in AIS archives:
+// TimeLayout, TimeLayout without seconds, both with a space in place of the T,
+// RFC 3339 with a zone or offset and optional fractional seconds, and epoch
+// seconds.  Times without a zone are taken to be UTC.  A file that mixes these
+// layouts can be read from start to end.
+var FlexibleTimeParser = NewTimeParser(
+	TimeLayout,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05Z07:00",
+	EpochLayout,
+)
+
+// NewTimeParser returns a TimeParser that tries each layout in order, as for
+// time.Parse, and returns the first time that parses, in UTC.  EpochLayout may be
+// given as one of the layouts.  Times without a zone are taken to be UTC.  For
+// example NewTimeParser("02/01/2006 15:04:05") reads the timestamps of the
+// Danish Maritime Authority described by DMAMapping.
+func NewTimeParser(layouts ...string) TimeParser {
+	layouts = append([]string(nil), layouts...)
+	return func(s string) (time.Time, error) {
+		s = strings.TrimSpace(s)
+		var first error
+		for _, layout := range layouts {
+			var t time.Time
+			var err error
+			if layout == EpochLayout {
+				t, err = parseEpoch(s)
+			} else {
+				t, err = time.Parse(layout, s)
+			}
+			if err == nil {
+				return t.UTC(), nil
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first == nil {
+			first = fmt.Errorf("no layouts to parse %q", s)
+		}
+		return time.Time{}, first
+	}
+}
+
+// parseEpoch parses s as seconds since the Unix epoch.
+func parseEpoch(s string) (time.Time, error) {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' && r != '-' }) >= 0 {
+		return time.Time{}, fmt.Errorf("parsing time %q as epoch seconds: invalid syntax", s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing time %q as epoch seconds: %w", s, err)
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), nil
+}
+
+// WithTimeParser returns a copy of h whose BaseDateTime is parsed with p.
+func (h Headers) WithTimeParser(p TimeParser) Headers {
+	h.Time = p
+	return h
+}
+
+// ReadTimeParser sets the TimeParser of the Headers of the RecordSet returned by
+// OpenRecordSet, NewRecordSetFromReader, or OpenIndexedRecordSet, which is the
+// same as calling SetHeaders with WithTimeParser after opening it except that
+// the sidecar index of OpenIndexedRecordSet is also built with p.
+func ReadTimeParser(p TimeParser) OpenOption {
+	return func(c *openConfig) error {
+		if p == nil {
+			return fmt.Errorf("read time parser: parser must not be nil")
+		}
+		c.time = p
+		return nil
+	}
+}
+
+// parseTime parses the BaseDateTime value s with the TimeParser of h, or with
+// TimeLayout when h has none.
+func (h Headers) parseTime(s string) (time.Time, error) {
+	if h.Time != nil {
+		return h.Time(s)
+	}
+	return time.Parse(TimeLayout, s)
+}
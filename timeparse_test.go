@@ -0,0 +1,124 @@
+package ais
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlexibleTimeParser(t *testing.T) {
+	want := time.Date(2017, time.December, 1, 0, 1, 14, 0, time.UTC)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"2017-12-01T00:01:14", want},
+		{"2017-12-01 00:01:14", want},
+		{"2017-12-01T00:01", want.Truncate(time.Minute)},
+		{"2017-12-01 00:01", want.Truncate(time.Minute)},
+		{"2017-12-01T00:01:14Z", want},
+		{"2017-12-01T01:01:14+01:00", want},
+		{"2017-11-30T19:01:14.5-05:00", want.Add(500 * time.Millisecond)},
+		{"1512086474", want},
+		{"1512086474.25", want.Add(250 * time.Millisecond)},
+		{" 2017-12-01T00:01:14 ", want},
+	}
+	for _, tt := range tests {
+		got, err := FlexibleTimeParser(tt.s)
+		if err != nil {
+			t.Errorf("FlexibleTimeParser(%q) error = %v", tt.s, err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("FlexibleTimeParser(%q) = %v, want %v in UTC", tt.s, got, tt.want)
+		}
+	}
+	for _, s := range []string{"", "yesterday", "12/01/2017", "1512086474x"} {
+		if _, err := FlexibleTimeParser(s); err == nil {
+			t.Errorf("FlexibleTimeParser(%q) expected error", s)
+		}
+	}
+
+	dma := NewTimeParser("02/01/2006 15:04:05")
+	if got, err := dma("01/12/2017 00:01:14"); err != nil || !got.Equal(want) {
+		t.Errorf("NewTimeParser() = %v, %v, want %v", got, err, want)
+	}
+	if _, err := NewTimeParser()("2017-12-01T00:01:14"); err == nil {
+		t.Error("NewTimeParser() with no layouts expected error")
+	}
+}
+
+func TestRecordSet_TimeParser(t *testing.T) {
+	data := "MMSI,BaseDateTime,LAT,LON\n" +
+		"1,2017-12-01 00:03:00,30,-76\n" +
+		"2,1512086520,30,-76\n" + // 00:02:00
+		"3,2017-12-01T00:01:00Z,30,-76\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	rs.SetHeaders(rs.Headers().WithTimeParser(FlexibleTimeParser))
+	sorted, err := rs.SortByTime()
+	if err != nil {
+		t.Fatalf("RecordSet.SortByTime() error = %v", err)
+	}
+	var mmsis []string
+	for {
+		rec, err := sorted.Read()
+		if err != nil {
+			break
+		}
+		mmsis = append(mmsis, (*rec)[0])
+		ts, err := rec.Time(rs.Headers())
+		if err != nil || ts.Location() != time.UTC {
+			t.Errorf("Record.Time() = %v, %v, want a time in UTC", ts, err)
+		}
+	}
+	if strings.Join(mmsis, ",") != "3,2,1" {
+		t.Errorf("RecordSet.SortByTime() order = %v, want [3 2 1]", mmsis)
+	}
+}
+
+func TestRecordSet_TimeParserExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// BaseDateTime in the day first layout of the Danish Maritime Authority.
+	data := "MMSI,BaseDateTime,LAT,LON\n" +
+		"219000001,05/06/2020 10:00:00,55.5,10.5\n"
+	want := time.Date(2020, time.June, 5, 10, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		ext  string
+		save func(*RecordSet, string) error
+		open func(string) (*RecordSet, error)
+	}{
+		{"jsonl", (*RecordSet).SaveJSONL, OpenJSONL},
+		{"parquet", (*RecordSet).SaveParquet, OpenParquet},
+		{"arrow", (*RecordSet).SaveArrow, OpenArrow},
+	} {
+		rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+		rs.SetHeaders(rs.Headers().WithTimeParser(NewTimeParser("02/01/2006 15:04:05")))
+		filename := filepath.Join(dir, "dma."+tt.ext)
+		if err := tt.save(rs, filename); err != nil {
+			t.Errorf("save %s error = %v", tt.ext, err)
+			continue
+		}
+		got, err := tt.open(filename)
+		if err != nil {
+			t.Errorf("open %s error = %v", tt.ext, err)
+			continue
+		}
+		rec, err := got.Read()
+		if err != nil {
+			t.Errorf("read %s error = %v", tt.ext, err)
+			continue
+		}
+		if ts, err := rec.Time(got.Headers()); err != nil || !ts.Equal(want) {
+			t.Errorf("%s BaseDateTime = %v, %v, want %v", tt.ext, ts, err, want)
+		}
+		got.Close()
+	}
+}
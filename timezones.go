@@ -0,0 +1,303 @@
+package ais
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TimeZones finds the civil time zone at a position from a set of time zone
+// polygons, such as the boundaries published by the timezone-boundary-builder
+// project as a shapefile or GeoJSON.  Positions outside every polygon, which
+// includes most of the open ocean, are in the NauticalZone.  The Location method
+// is a ZoneFunc.
+type TimeZones struct {
+	zones []timeZone
+}
+
+// timeZone is the area of one zone of a TimeZones.
+type timeZone struct {
+	fence *Geofence
+	loc   *time.Location
+}
+
+// TimeZoneField is the attribute of each shape or GeoJSON Feature that holds
+// the IANA name of its time zone, such as America/New_York.
+const TimeZoneField = "tzid"
+
+// Location returns the time zone at the position, the zone of the first
+// polygon that contains it or the NauticalZone.
+func (tz *TimeZones) Location(lat, lon float64) *time.Location {
+	for _, z := range tz.zones {
+		if z.fence.Contains(lat, lon) {
+			return z.loc
+		}
+	}
+	return NauticalZone(lat, lon)
+}
+
+// Len returns the number of zones in the set.
+func (tz *TimeZones) Len() int { return len(tz.zones) }
+
+// add appends the zone named tzid covering polys.
+func (tz *TimeZones) add(tzid string, polys [][][][2]float64) error {
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		return err
+	}
+	fence, err := newGeofence(polys)
+	if err != nil {
+		return fmt.Errorf("zone %s: %w", tzid, err)
+	}
+	tz.zones = append(tz.zones, timeZone{fence: fence, loc: loc})
+	return nil
+}
+
+// NewTimeZonesGeoJSON returns the TimeZones of a GeoJSON FeatureCollection
+// whose Features have polygonal geometries and a TimeZoneField property.  Time
+// zones are loaded with time.LoadLocation, so the time zone database must be
+// installed.
+func NewTimeZonesGeoJSON(data []byte) (*TimeZones, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("time zones: geojson: %w", err)
+	}
+	if obj.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("time zones: geojson: want a FeatureCollection, got %q", obj.Type)
+	}
+	tz := new(TimeZones)
+	for i := range obj.Features {
+		f := &obj.Features[i]
+		tzid, _ := f.Properties[TimeZoneField].(string)
+		if tzid == "" {
+			return nil, fmt.Errorf("time zones: geojson: feature %d has no %s", i, TimeZoneField)
+		}
+		polys, err := f.polygons()
+		if err != nil {
+			return nil, fmt.Errorf("time zones: geojson: feature %d: %w", i, err)
+		}
+		if err := tz.add(tzid, polys); err != nil {
+			return nil, fmt.Errorf("time zones: geojson: feature %d: %w", i, err)
+		}
+	}
+	return tz, nil
+}
+
+// OpenTimeZones returns the TimeZones of an ESRI shapefile of polygons.  The
+// filename is the .shp file, and the .dbf file beside it must have a
+// TimeZoneField column.  Time zones are loaded with time.LoadLocation, so the
+// time zone database must be installed.
+func OpenTimeZones(filename string) (*TimeZones, error) {
+	shapes, err := readShapes(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open time zones: %w", err)
+	}
+	dbf := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".dbf"
+	names, err := readDBFColumn(dbf, TimeZoneField)
+	if err != nil {
+		return nil, fmt.Errorf("open time zones: %w", err)
+	}
+	if len(names) != len(shapes) {
+		return nil, fmt.Errorf("open time zones: %s has %d records for %d shapes", dbf, len(names), len(shapes))
+	}
+	tz := new(TimeZones)
+	for i, polys := range shapes {
+		if len(polys) == 0 {
+			continue // a null shape
+		}
+		if err := tz.add(names[i], polys); err != nil {
+			return nil, fmt.Errorf("open time zones: shape %d: %w", i+1, err)
+		}
+	}
+	return tz, nil
+}
+
+// Shapefile shape types with polygon geometry.
+const (
+	shpNull     = 0
+	shpPolygon  = 5
+	shpPolygonZ = 15
+	shpPolygonM = 25
+)
+
+// readShapes returns the polygons of each record of the shapefile, each an
+// outer ring followed by its holes as newGeofence expects.  A null shape has no
+// polygons.
+func readShapes(filename string) ([][][][][2]float64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	var header [100]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%s: header: %w", filename, err)
+	}
+	if code := binary.BigEndian.Uint32(header[0:]); code != 9994 {
+		return nil, fmt.Errorf("%s: not a shapefile, file code %d", filename, code)
+	}
+
+	var shapes [][][][][2]float64
+	for {
+		var rh [8]byte
+		if _, err := io.ReadFull(r, rh[:]); err == io.EOF {
+			return shapes, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", filename, len(shapes)+1, err)
+		}
+		content := make([]byte, 2*int(binary.BigEndian.Uint32(rh[4:])))
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", filename, len(shapes)+1, err)
+		}
+		polys, err := shapePolygons(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", filename, len(shapes)+1, err)
+		}
+		shapes = append(shapes, polys)
+	}
+}
+
+// shapePolygons decodes the content of a polygon record.  The outer rings of a
+// shapefile polygon are clockwise and its holes counterclockwise, and each hole
+// belongs to the outer ring that contains it.
+func shapePolygons(b []byte) ([][][][2]float64, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("short record")
+	}
+	switch typ := binary.LittleEndian.Uint32(b); typ {
+	case shpNull:
+		return nil, nil
+	case shpPolygon, shpPolygonZ, shpPolygonM:
+	default:
+		return nil, fmt.Errorf("shape type %d is not a polygon", typ)
+	}
+	if len(b) < 44 {
+		return nil, fmt.Errorf("short record")
+	}
+	numParts := int(binary.LittleEndian.Uint32(b[36:]))
+	numPoints := int(binary.LittleEndian.Uint32(b[40:]))
+	pts := 44 + 4*numParts
+	if numParts < 0 || numPoints < 0 || len(b) < pts+16*numPoints {
+		return nil, fmt.Errorf("short record")
+	}
+
+	var outers, holes [][][2]float64
+	for i := 0; i < numParts; i++ {
+		start := int(binary.LittleEndian.Uint32(b[44+4*i:]))
+		end := numPoints
+		if i+1 < numParts {
+			end = int(binary.LittleEndian.Uint32(b[44+4*(i+1):]))
+		}
+		if start < 0 || start > end || end > numPoints {
+			return nil, fmt.Errorf("part %d out of range", i)
+		}
+		ring := make([][2]float64, 0, end-start)
+		for j := start; j < end; j++ {
+			p := b[pts+16*j:]
+			ring = append(ring, [2]float64{
+				math.Float64frombits(binary.LittleEndian.Uint64(p)),
+				math.Float64frombits(binary.LittleEndian.Uint64(p[8:])),
+			})
+		}
+		if ringArea(ring) <= 0 {
+			outers = append(outers, ring)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+	if len(outers) == 0 {
+		return nil, fmt.Errorf("polygon has no outer ring")
+	}
+
+	polys := make([][][][2]float64, len(outers))
+	for i, outer := range outers {
+		polys[i] = [][][2]float64{outer}
+	}
+	for _, hole := range holes {
+		k := len(polys) - 1
+		for i, outer := range outers {
+			if inRing(outer, hole[0][1], hole[0][0]) {
+				k = i
+				break
+			}
+		}
+		polys[k] = append(polys[k], hole)
+	}
+	return polys, nil
+}
+
+// ringArea returns the signed area of a ring of [lon, lat] vertices, negative
+// for a clockwise ring.
+func ringArea(ring [][2]float64) float64 {
+	var a float64
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		a += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return a / 2
+}
+
+// readDBFColumn returns the values of the named character column of each
+// record of a dBASE file, the attribute table of a shapefile.  The name is
+// matched without regard to case and the values are trimmed of padding.
+func readDBFColumn(filename, column string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	var header [32]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%s: header: %w", filename, err)
+	}
+	numRecords := int(binary.LittleEndian.Uint32(header[4:]))
+	headerLen := int(binary.LittleEndian.Uint16(header[8:]))
+	recordLen := int(binary.LittleEndian.Uint16(header[10:]))
+	if headerLen < 33 || recordLen < 1 {
+		return nil, fmt.Errorf("%s: not a dbf file", filename)
+	}
+
+	fields := make([]byte, headerLen-32)
+	if _, err := io.ReadFull(r, fields); err != nil {
+		return nil, fmt.Errorf("%s: header: %w", filename, err)
+	}
+	offset, width := -1, 0
+	pos := 1 // after the deletion flag
+	for i := 0; i+32 <= len(fields) && fields[i] != 0x0d; i += 32 {
+		name := string(bytes.TrimRight(fields[i:i+11], "\x00 "))
+		n := int(fields[i+16])
+		if strings.EqualFold(name, column) {
+			offset, width = pos, n
+			break
+		}
+		pos += n
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%s: %w", filename, ErrMissingHeader{Field: column})
+	}
+	if offset+width > recordLen {
+		return nil, fmt.Errorf("%s: column %s past the end of the record", filename, column)
+	}
+
+	values := make([]string, 0, numRecords)
+	rec := make([]byte, recordLen)
+	for i := 0; i < numRecords; i++ {
+		if _, err := io.ReadFull(r, rec); err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", filename, i+1, err)
+		}
+		values = append(values, strings.TrimSpace(string(bytes.TrimRight(rec[offset:offset+width], "\x00"))))
+	}
+	return values, nil
+}
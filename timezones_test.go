@@ -0,0 +1,164 @@
+package ais
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestShapefile writes a shapefile of single part polygons, each with an
+// optional hole, and a dbf file with their tzid values.
+func writeTestShapefile(t *testing.T, filename string, tzids []string, rings [][][][2]float64) {
+	t.Helper()
+	var recs bytes.Buffer
+	for i, parts := range rings {
+		var content bytes.Buffer
+		le := func(v interface{}) { binary.Write(&content, binary.LittleEndian, v) }
+		n := 0
+		for _, p := range parts {
+			n += len(p)
+		}
+		le(int32(shpPolygon))
+		le([4]float64{})
+		le(int32(len(parts)))
+		le(int32(n))
+		start := 0
+		for _, p := range parts {
+			le(int32(start))
+			start += len(p)
+		}
+		for _, p := range parts {
+			for _, v := range p {
+				le(v)
+			}
+		}
+		binary.Write(&recs, binary.BigEndian, [2]int32{int32(i + 1), int32(content.Len() / 2)})
+		recs.Write(content.Bytes())
+	}
+	var shp bytes.Buffer
+	binary.Write(&shp, binary.BigEndian, [7]int32{9994, 0, 0, 0, 0, 0, int32((100 + recs.Len()) / 2)})
+	binary.Write(&shp, binary.LittleEndian, [2]int32{1000, shpPolygon})
+	binary.Write(&shp, binary.LittleEndian, [8]float64{})
+	shp.Write(recs.Bytes())
+	if err := ioutil.WriteFile(filename, shp.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	const width = 40
+	var dbf bytes.Buffer
+	dbf.Write([]byte{3, 120, 1, 1})
+	binary.Write(&dbf, binary.LittleEndian, uint32(len(tzids)))
+	binary.Write(&dbf, binary.LittleEndian, [2]uint16{32 + 2*32 + 1, 1 + 4 + width})
+	dbf.Write(make([]byte, 20))
+	field := func(name string, typ byte, n int) {
+		desc := make([]byte, 32)
+		copy(desc, name)
+		desc[11], desc[16] = typ, byte(n)
+		dbf.Write(desc)
+	}
+	field("id", 'N', 4)
+	field("TZID", 'C', width)
+	dbf.WriteByte(0x0d)
+	for i, tzid := range tzids {
+		dbf.WriteString(" ")
+		dbf.WriteString(string([]byte{'0', '0', '0', byte('0' + i)}))
+		dbf.WriteString(tzid + string(bytes.Repeat([]byte{' '}, width-len(tzid))))
+	}
+	dbf.WriteByte(0x1a)
+	if err := ioutil.WriteFile(filename[:len(filename)-4]+".dbf", dbf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadZones loads the named locations or skips the test when the time zone
+// database is not installed.
+func loadZones(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if _, err := time.LoadLocation(name); err != nil {
+			t.Skipf("time zone database not available: %v", err)
+		}
+	}
+}
+
+func TestOpenTimeZones(t *testing.T) {
+	loadZones(t, "America/New_York", "America/Chicago")
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "zones.shp")
+
+	// Clockwise outer rings and a counterclockwise hole in the first.
+	east := [][2]float64{{-80, 35}, {-80, 40}, {-70, 40}, {-70, 35}, {-80, 35}}
+	hole := [][2]float64{{-76, 36}, {-75, 36}, {-75, 37}, {-76, 37}, {-76, 36}}
+	central := [][2]float64{{-95, 25}, {-95, 35}, {-85, 35}, {-85, 25}, {-95, 25}}
+	writeTestShapefile(t, filename, []string{"America/New_York", "America/Chicago"},
+		[][][][2]float64{{east, hole}, {central}})
+
+	tz, err := OpenTimeZones(filename)
+	if err != nil {
+		t.Fatalf("OpenTimeZones() error = %v", err)
+	}
+	if tz.Len() != 2 {
+		t.Fatalf("TimeZones.Len() = %d, want 2", tz.Len())
+	}
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"new york", 38, -74, "America/New_York"},
+		{"chicago", 30, -90, "America/Chicago"},
+		{"hole", 36.5, -75.5, "UTC-05"},
+		{"atlantic", 30, -40, "UTC-03"},
+	}
+	for _, tt := range tests {
+		if got := tz.Location(tt.lat, tt.lon).String(); got != tt.want {
+			t.Errorf("%s: TimeZones.Location(%v, %v) = %s, want %s", tt.name, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+
+	if _, err := OpenTimeZones(filepath.Join(dir, "missing.shp")); err == nil {
+		t.Errorf("OpenTimeZones() of a missing file error = nil, want an error")
+	}
+}
+
+func TestNewTimeZonesGeoJSON(t *testing.T) {
+	loadZones(t, "Europe/Rome")
+	data := []byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"tzid":"Europe/Rome"},
+		 "geometry":{"type":"Polygon","coordinates":[[[6,36],[19,36],[19,47],[6,47],[6,36]]]}}]}`)
+	tz, err := NewTimeZonesGeoJSON(data)
+	if err != nil {
+		t.Fatalf("NewTimeZonesGeoJSON() error = %v", err)
+	}
+	// A summer afternoon in Rome is two hours ahead of UTC.
+	utc := time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC)
+	if got := utc.In(tz.Location(41.9, 12.5)).Hour(); got != 14 {
+		t.Errorf("local hour in Rome = %d, want 14", got)
+	}
+
+	bad := []byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},
+		 "geometry":{"type":"Polygon","coordinates":[[[6,36],[19,36],[19,47],[6,36]]]}}]}`)
+	if _, err := NewTimeZonesGeoJSON(bad); err == nil {
+		t.Errorf("NewTimeZonesGeoJSON() without tzid error = nil, want an error")
+	}
+}
+
+func TestRingArea(t *testing.T) {
+	cw := [][2]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}}
+	if a := ringArea(cw); a != -1 {
+		t.Errorf("ringArea(clockwise) = %v, want -1", a)
+	}
+	if a := ringArea([][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}); math.Abs(a-1) > 1e-12 {
+		t.Errorf("ringArea(counterclockwise) = %v, want 1", a)
+	}
+}
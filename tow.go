@@ -0,0 +1,275 @@
+package ais
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PairedFields are the columns written by PairedSegments.Save.  Duration is in
+// minutes.
+const PairedFields = "MMSI1,MMSI2,Kind,Start,End,Duration,Samples,MeanDistance(nm),MeanSOG"
+
+// PairedKind classifies two vessels moving together.
+type PairedKind int
+
+const (
+	// EscortPair is two vessels moving together where neither is a tug, such as
+	// an escort, a pilot boat alongside, or vessels in company.
+	EscortPair PairedKind = iota
+
+	// TowPair is two vessels moving together where at least one is a tug or
+	// towing vessel, so one is most likely towing or pushing the other.
+	TowPair
+)
+
+var pairedKindNames = [...]string{
+	EscortPair: "escort",
+	TowPair:    "tow",
+}
+
+// String implements the Stringer interface for PairedKind.
+func (k PairedKind) String() string {
+	if k < 0 || int(k) >= len(pairedKindNames) {
+		return fmt.Sprintf("PairedKind(%d)", int(k))
+	}
+	return pairedKindNames[k]
+}
+
+// PairingRules configures the detection of paired movement.  Two vessels are
+// paired at a sample time when both are under way at MinSOG or more, they are
+// within MaxDistance of each other, and their SOG and COG differ by no more
+// than MaxSOGDiff and MaxCOGDiff.
+type PairingRules struct {
+	MaxDistance float64       // nm
+	MaxSOGDiff  float64       // knots
+	MaxCOGDiff  float64       // degrees
+	MinSOG      float64       // knots, below which a vessel is not under way
+	MinDuration time.Duration // shortest paired segment reported
+	Step        time.Duration // time between the samples of the two Tracks
+	MaxGap      time.Duration // samples between reports farther apart than this are skipped, zero for no limit
+}
+
+// DefaultPairingRules returns PairingRules for tows and escorts: vessels within
+// half a mile of each other at 2 knots or more, with speeds within 1.5 knots and
+// courses within 15 degrees, sampled every minute for at least 30 minutes, and
+// with no more than 10 minutes between the reports of either vessel.
+func DefaultPairingRules() PairingRules {
+	return PairingRules{
+		MaxDistance: 0.5,
+		MaxSOGDiff:  1.5,
+		MaxCOGDiff:  15,
+		MinSOG:      2,
+		MinDuration: 30 * time.Minute,
+		Step:        time.Minute,
+		MaxGap:      10 * time.Minute,
+	}
+}
+
+// PairedSegment is a period during which two vessels moved together.
+type PairedSegment struct {
+	MMSI1, MMSI2 string // MMSI1 sorts before MMSI2
+	Kind         PairedKind
+	Start, End   time.Time
+	Samples      int
+	MeanDistance float64 // nm
+	MeanSOG      float64 // knots, of both vessels
+}
+
+// Duration returns the time between the first and last samples of the segment.
+func (s PairedSegment) Duration() time.Duration { return s.End.Sub(s.Start) }
+
+// PairedSegments are paired movement segments sorted by MMSI1, MMSI2, and
+// Start.
+type PairedSegments []PairedSegment
+
+// PairedMovement returns the segments of at least rules.MinDuration during
+// which the vessels of Tracks t1 and t2 moved together.  Both Tracks are
+// sampled every rules.Step over the time they overlap with Track.At, and a
+// segment is a run of consecutive samples that meet the rules.  Unlike a near
+// miss, which lasts a few minutes, a tug and its tow or an escort and the
+// vessel it escorts keep station for hours, so the segments tell these pairs
+// apart from the encounters found by Interactions.  The Headers of both Tracks
+// must contain SOG and COG.
+func PairedMovement(t1, t2 *Track, rules PairingRules) (PairedSegments, error) {
+	if rules.Step <= 0 || rules.MaxDistance <= 0 {
+		return nil, fmt.Errorf("paired movement: step and max distance must be positive, got %v and %v", rules.Step, rules.MaxDistance)
+	}
+	if t1.Len() == 0 || t2.Len() == 0 {
+		return nil, nil
+	}
+	for _, t := range []*Track{t1, t2} {
+		if _, err := t.h.require("SOG", "COG"); err != nil {
+			return nil, fmt.Errorf("paired movement: %w", err)
+		}
+	}
+	if t2.MMSI < t1.MMSI {
+		t1, t2 = t2, t1
+	}
+	kind := EscortPair
+	for _, t := range []*Track{t1, t2} {
+		if vt, err := t.data[0].VesselType(t.h); err == nil && vt.Category() == Tug {
+			kind = TowPair
+		}
+	}
+
+	start, end := t1.Start(), t1.End()
+	if t2.Start().After(start) {
+		start = t2.Start()
+	}
+	if t2.End().Before(end) {
+		end = t2.End()
+	}
+	start = start.Truncate(rules.Step)
+	if start.Before(t1.Start()) || start.Before(t2.Start()) {
+		start = start.Add(rules.Step)
+	}
+
+	var segs PairedSegments
+	var cur PairedSegment
+	var sumDist, sumSOG float64
+	flush := func() {
+		if cur.Samples > 0 && cur.Duration() >= rules.MinDuration {
+			cur.MeanDistance = sumDist / float64(cur.Samples)
+			cur.MeanSOG = sumSOG / float64(2*cur.Samples)
+			segs = append(segs, cur)
+		}
+		cur = PairedSegment{MMSI1: t1.MMSI, MMSI2: t2.MMSI, Kind: kind}
+		sumDist, sumSOG = 0, 0
+	}
+	flush()
+	for ts := start; !ts.After(end); ts = ts.Add(rules.Step) {
+		if rules.MaxGap > 0 && (t1.gapAt(ts) > rules.MaxGap || t2.gapAt(ts) > rules.MaxGap) {
+			flush()
+			continue
+		}
+		d, sog, ok, err := pairedAt(t1, t2, ts, rules)
+		if err != nil {
+			return nil, fmt.Errorf("paired movement: %w", err)
+		}
+		if !ok {
+			flush()
+			continue
+		}
+		if cur.Samples == 0 {
+			cur.Start = ts
+		}
+		cur.End = ts
+		cur.Samples++
+		sumDist += d
+		sumSOG += sog
+	}
+	flush()
+	return segs, nil
+}
+
+// pairedAt returns the distance between the vessels of t1 and t2 at ts, the
+// sum of their SOGs, and whether they are moving together by the rules.
+func pairedAt(t1, t2 *Track, ts time.Time, rules PairingRules) (d, sog float64, ok bool, err error) {
+	rec1, err := t1.At(ts)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	rec2, err := t2.At(ts)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	lat1, lon1, err := rec1.LatLon(t1.h)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	lat2, lon2, err := rec2.LatLon(t2.h)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	sog1, err1 := rec1.SOG(t1.h)
+	sog2, err2 := rec2.SOG(t2.h)
+	cog1, err3 := rec1.COG(t1.h)
+	cog2, err4 := rec2.COG(t2.h)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil ||
+		sog1 >= 102.3 || sog2 >= 102.3 || cog1 >= COGNotAvailable || cog2 >= COGNotAvailable {
+		return 0, 0, false, nil
+	}
+	d = Haversine(lat1, lon1, lat2, lon2)
+	dCOG := math.Abs(math.Mod(cog1-cog2+540, 360) - 180)
+	ok = d <= rules.MaxDistance && sog1 >= rules.MinSOG && sog2 >= rules.MinSOG &&
+		math.Abs(sog1-sog2) <= rules.MaxSOGDiff && dCOG <= rules.MaxCOGDiff
+	return d, sog1 + sog2, ok, nil
+}
+
+// gapAt returns the time between the reports of the Track before and after ts,
+// or zero when ts is a report time.
+func (t *Track) gapAt(ts time.Time) time.Duration {
+	j := sort.Search(len(t.times), func(i int) bool { return !t.times[i].Before(ts) })
+	if j < len(t.times) && t.times[j].Equal(ts) {
+		return 0
+	}
+	if j == 0 || j == len(t.times) {
+		return time.Duration(math.MaxInt64)
+	}
+	return t.times[j].Sub(t.times[j-1])
+}
+
+// PairedMovement returns the paired movement segments of the vessel pairs of
+// the set, using their Tracks to follow each pair of vessels that came within
+// rules.MaxDistance of each other in any interaction.  The near misses among
+// the interactions have no segments, while tows and escorts do, so the result
+// separates prolonged paired movement from encounters.  tracks holds the
+// Tracks by MMSI, as returned by RecordSet.Tracks.
+func (inter *Interactions) PairedMovement(tracks map[string]*Track, rules PairingRules) (PairedSegments, error) {
+	graph, err := inter.Graph()
+	if err != nil {
+		return nil, fmt.Errorf("interactions paired movement: %w", err)
+	}
+	var segs PairedSegments
+	for _, e := range graph {
+		if e.MinDistance > rules.MaxDistance {
+			continue
+		}
+		t1, ok1 := tracks[e.MMSI1]
+		t2, ok2 := tracks[e.MMSI2]
+		if !ok1 || !ok2 {
+			continue
+		}
+		s, err := PairedMovement(t1, t2, rules)
+		if err != nil {
+			return nil, fmt.Errorf("interactions %s and %s: %w", e.MMSI1, e.MMSI2, err)
+		}
+		segs = append(segs, s...)
+	}
+	return segs, nil
+}
+
+// Save writes the segments to a csv file with PairedFields.
+func (ps PairedSegments) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("paired segments save: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(strings.Split(PairedFields, ","))
+	for _, s := range ps {
+		w.Write([]string{
+			s.MMSI1,
+			s.MMSI2,
+			s.Kind.String(),
+			s.Start.Format(TimeLayout),
+			s.End.Format(TimeLayout),
+			strconv.FormatFloat(s.Duration().Minutes(), 'f', 1, 64),
+			strconv.Itoa(s.Samples),
+			strconv.FormatFloat(s.MeanDistance, 'f', 2, 64),
+			strconv.FormatFloat(s.MeanSOG, 'f', 1, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("paired segments save: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,101 @@
+package ais
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// towTrack returns a Track heading east at sog knots from lat, lon with a report
+// every two minutes for the duration d.
+func towTrack(t *testing.T, mmsi, vesselType string, lat, lon, sog float64, d time.Duration) *Track {
+	t.Helper()
+	h := Headers{Fields: []string{"MMSI", "BaseDateTime", "LAT", "LON", "SOG", "COG", "VesselType"}}
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	var recs []Record
+	for ts := time.Duration(0); ts <= d; ts += 2 * time.Minute {
+		_, lon2 := destination(lat, lon, 90, sog*ts.Hours())
+		recs = append(recs, Record{
+			mmsi, start.Add(ts).Format(TimeLayout),
+			fmt.Sprintf("%.5f", lat), fmt.Sprintf("%.5f", lon2),
+			fmt.Sprintf("%.1f", sog), "90.0", vesselType,
+		})
+	}
+	tr, err := NewTrack(h, recs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestPairedMovement(t *testing.T) {
+	tug := towTrack(t, "366000001", "52", 36.9000, -76.1, 6, time.Hour)
+	barge := towTrack(t, "366000002", "1003", 36.8970, -76.1, 6, time.Hour)
+	escort := towTrack(t, "366000003", "35", 36.8970, -76.1, 6, time.Hour)
+	fast := towTrack(t, "366000004", "70", 36.9010, -76.1, 12, time.Hour)
+
+	segs, err := PairedMovement(barge, tug, DefaultPairingRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 1 {
+		t.Fatalf("PairedMovement(barge, tug) = %+v, want one segment", segs)
+	}
+	s := segs[0]
+	if s.MMSI1 != "366000001" || s.Kind != TowPair || s.Duration() != time.Hour || s.Samples != 61 {
+		t.Errorf("segment = %+v", s)
+	}
+	if s.MeanDistance < 0.17 || s.MeanDistance > 0.19 || s.MeanSOG < 5.9 || s.MeanSOG > 6.1 {
+		t.Errorf("segment means = %v nm, %v knots", s.MeanDistance, s.MeanSOG)
+	}
+
+	segs, err = PairedMovement(tug, escort, DefaultPairingRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 1 || segs[0].Kind != TowPair {
+		t.Errorf("PairedMovement(tug, escort) = %+v, want one tow", segs)
+	}
+	segs, err = PairedMovement(barge, escort, DefaultPairingRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 1 || segs[0].Kind != EscortPair {
+		t.Errorf("PairedMovement(barge, escort) = %+v, want one escort", segs)
+	}
+
+	// A faster vessel passes the tug, close for only a few minutes.
+	segs, err = PairedMovement(tug, fast, DefaultPairingRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 0 {
+		t.Errorf("PairedMovement(tug, fast) = %+v, want none", segs)
+	}
+}
+
+func TestInteractions_PairedMovement(t *testing.T) {
+	tracks := map[string]*Track{
+		"366000001": towTrack(t, "366000001", "52", 36.9000, -76.1, 6, time.Hour),
+		"366000002": towTrack(t, "366000002", "1003", 36.8970, -76.1, 6, time.Hour),
+	}
+	h := tracks["366000001"].Headers()
+	inter, err := NewInteractions(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(Cluster)
+	r1, r2 := tracks["366000001"].Records()[0], tracks["366000002"].Records()[0]
+	c.Append(&r1)
+	c.Append(&r2)
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatal(err)
+	}
+	segs, err := inter.PairedMovement(tracks, DefaultPairingRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 1 || segs[0].Kind != TowPair {
+		t.Errorf("Interactions.PairedMovement() = %+v, want one tow", segs)
+	}
+}
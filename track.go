@@ -0,0 +1,261 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Track is the trajectory of a single vessel: every Record for one MMSI sorted in
+// ascending order by BaseDateTime.  Tracks are usually created for all of the
+// vessels in a RecordSet at once by calling RecordSet.Tracks.
+type Track struct {
+	MMSI  string
+	h     Headers
+	idx   map[string]HeaderMap
+	data  []Record
+	times []time.Time
+}
+
+// NewTrack returns a *Track built from recs, which must all share the same MMSI.
+// The Headers must contain MMSI, BaseDateTime, LAT, and LON.  The records are
+// sorted by BaseDateTime and the order of records with equal timestamps is
+// preserved.
+func NewTrack(h Headers, recs []Record) (*Track, error) {
+	idx, err := h.require("MMSI", "BaseDateTime", "LAT", "LON")
+	if err != nil {
+		return nil, fmt.Errorf("new track: %w", err)
+	}
+	if len(recs) == 0 {
+		return nil, ErrEmptySet
+	}
+
+	t := &Track{
+		MMSI:  recs[0][idx["MMSI"].Idx],
+		h:     h,
+		idx:   idx,
+		data:  make([]Record, len(recs)),
+		times: make([]time.Time, len(recs)),
+	}
+	copy(t.data, recs)
+	for i, rec := range t.data {
+		if rec[idx["MMSI"].Idx] != t.MMSI {
+			return nil, fmt.Errorf("new track: record %d has MMSI %s, want %s", i, rec[idx["MMSI"].Idx], t.MMSI)
+		}
+		ts, err := h.parseTime(rec[idx["BaseDateTime"].Idx])
+		if err != nil {
+			return nil, fmt.Errorf("new track: %w", err)
+		}
+		t.times[i] = ts
+	}
+	sort.Stable(byTrackTime{t})
+	return t, nil
+}
+
+// byTrackTime sorts the records of a Track and their parsed times together.
+type byTrackTime struct{ t *Track }
+
+func (b byTrackTime) Len() int           { return len(b.t.data) }
+func (b byTrackTime) Less(i, j int) bool { return b.t.times[i].Before(b.t.times[j]) }
+func (b byTrackTime) Swap(i, j int) {
+	b.t.data[i], b.t.data[j] = b.t.data[j], b.t.data[i]
+	b.t.times[i], b.t.times[j] = b.t.times[j], b.t.times[i]
+}
+
+// Tracks reads the remaining Records in the RecordSet and groups them into a
+// *Track for each MMSI.  The returned map is keyed by MMSI.  Like other methods
+// that read the whole RecordSet, Tracks consumes the receiver.
+func (rs *RecordSet) Tracks() (map[string]*Track, error) {
+	mmsiIndex, ok := rs.Headers().Contains("MMSI")
+	if !ok {
+		return nil, fmt.Errorf("tracks: %w", ErrMissingHeader{Field: "MMSI"})
+	}
+
+	groups := make(map[string][]Record)
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tracks: read error on csv file: %w", err)
+		}
+		groups[(*rec)[mmsiIndex]] = append(groups[(*rec)[mmsiIndex]], *rec)
+	}
+
+	tracks := make(map[string]*Track, len(groups))
+	for mmsi, recs := range groups {
+		t, err := NewTrack(rs.Headers(), recs)
+		if err != nil {
+			return nil, fmt.Errorf("tracks: %w", err)
+		}
+		tracks[mmsi] = t
+	}
+	return tracks, nil
+}
+
+// Headers returns the Headers that describe the Records in the Track.
+func (t *Track) Headers() Headers { return t.h }
+
+// Len returns the number of Records in the Track.
+func (t *Track) Len() int { return len(t.data) }
+
+// Records returns the Records of the Track in time order.  The returned slice
+// shares its backing array with the Track and must not be modified.
+func (t *Track) Records() []Record { return t.data }
+
+// Start returns the time of the first Record in the Track.
+func (t *Track) Start() time.Time { return t.times[0] }
+
+// End returns the time of the last Record in the Track.
+func (t *Track) End() time.Time { return t.times[len(t.times)-1] }
+
+// Duration returns the time elapsed between the first and last Records.
+func (t *Track) Duration() time.Duration { return t.End().Sub(t.Start()) }
+
+// Distance returns the total distance in nautical miles travelled along the
+// Track, summed over each leg between consecutive Records.
+func (t *Track) Distance() (nm float64, err error) {
+	for i := 1; i < len(t.data); i++ {
+		lat1, lon1, err := t.position(i - 1)
+		if err != nil {
+			return 0, err
+		}
+		lat2, lon2, err := t.position(i)
+		if err != nil {
+			return 0, err
+		}
+		nm += Haversine(lat1, lon1, lat2, lon2)
+	}
+	return nm, nil
+}
+
+// AverageSpeed returns the distance travelled divided by the duration of the
+// Track in knots.  A Track with a duration of zero has an average speed of zero.
+func (t *Track) AverageSpeed() (knots float64, err error) {
+	nm, err := t.Distance()
+	if err != nil {
+		return 0, err
+	}
+	d := t.Duration()
+	if d <= 0 {
+		return 0, nil
+	}
+	return nm / d.Hours(), nil
+}
+
+// BoundingBox returns the smallest Box that contains every position in the Track.
+// The LatIndex and LonIndex of the Box are set from the Track Headers so the
+// returned value can be used directly as the Matching argument to Subset.
+func (t *Track) BoundingBox() (Box, error) {
+	b := Box{
+		MinLat: math.Inf(1), MaxLat: math.Inf(-1),
+		MinLon: math.Inf(1), MaxLon: math.Inf(-1),
+		LatIndex: t.idx["LAT"].Idx, LonIndex: t.idx["LON"].Idx,
+	}
+	for i := range t.data {
+		lat, lon, err := t.position(i)
+		if err != nil {
+			return Box{}, err
+		}
+		b.MinLat = math.Min(b.MinLat, lat)
+		b.MaxLat = math.Max(b.MaxLat, lat)
+		b.MinLon = math.Min(b.MinLon, lon)
+		b.MaxLon = math.Max(b.MaxLon, lon)
+	}
+	return b, nil
+}
+
+// Resample returns a new *Track with one Record every interval from the start of
+// the Track through its end.  Each resampled Record is a copy of the latest
+// original Record at or before the sample time with its BaseDateTime replaced by
+// the sample time, so values are held until the next report arrives.
+func (t *Track) Resample(interval time.Duration) (*Track, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("track resample: interval must be positive, got %v", interval)
+	}
+	timeIndex := t.idx["BaseDateTime"].Idx
+	n := int(t.Duration()/interval) + 1
+	rt := &Track{
+		MMSI:  t.MMSI,
+		h:     t.h,
+		idx:   t.idx,
+		data:  make([]Record, 0, n),
+		times: make([]time.Time, 0, n),
+	}
+
+	j := 0
+	for ts := t.Start(); !ts.After(t.End()); ts = ts.Add(interval) {
+		for j+1 < len(t.times) && !t.times[j+1].After(ts) {
+			j++
+		}
+		rec := make(Record, len(t.data[j]))
+		copy(rec, t.data[j])
+		rec[timeIndex] = ts.Format(TimeLayout)
+		rt.data = append(rt.data, rec)
+		rt.times = append(rt.times, ts)
+	}
+	return rt, nil
+}
+
+// Segment splits the Track into voyages wherever consecutive Records are more
+// than gap apart in time or more than maxJump nautical miles apart in position.
+// A vessel that leaves and re-enters receiver coverage, or a second vessel
+// transmitting the same MMSI, otherwise appears as a single Track with a long,
+// straight leg that distorts Distance and AverageSpeed.  A gap or maxJump of zero
+// disables that criterion.  The returned Tracks share their Records with the
+// receiver.
+func (t *Track) Segment(gap time.Duration, maxJump float64) ([]Track, error) {
+	if gap < 0 || maxJump < 0 {
+		return nil, fmt.Errorf("track segment: gap and maxJump must not be negative, got %v and %v", gap, maxJump)
+	}
+	var segs []Track
+	start := 0
+	split := func(end int) {
+		segs = append(segs, Track{
+			MMSI:  t.MMSI,
+			h:     t.h,
+			idx:   t.idx,
+			data:  t.data[start:end:end],
+			times: t.times[start:end:end],
+		})
+		start = end
+	}
+	for i := 1; i < len(t.data); i++ {
+		if gap > 0 && t.times[i].Sub(t.times[i-1]) > gap {
+			split(i)
+			continue
+		}
+		if maxJump > 0 {
+			lat1, lon1, err := t.position(i - 1)
+			if err != nil {
+				return nil, fmt.Errorf("track segment: %w", err)
+			}
+			lat2, lon2, err := t.position(i)
+			if err != nil {
+				return nil, fmt.Errorf("track segment: %w", err)
+			}
+			if Haversine(lat1, lon1, lat2, lon2) > maxJump {
+				split(i)
+			}
+		}
+	}
+	split(len(t.data))
+	return segs, nil
+}
+
+// position returns the parsed latitude and longitude of the i'th Record.
+func (t *Track) position(i int) (lat, lon float64, err error) {
+	rec := t.data[i]
+	lat, err = rec.ParseFloat(t.idx["LAT"].Idx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("track %s: %w", t.MMSI, ErrParse{Field: "LAT", Err: err})
+	}
+	lon, err = rec.ParseFloat(t.idx["LON"].Idx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("track %s: %w", t.MMSI, ErrParse{Field: "LON", Err: err})
+	}
+	return lat, lon, nil
+}
@@ -0,0 +1,153 @@
+package ais
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordSet_Tracks(t *testing.T) {
+	rs, err := OpenRecordSet("testdata/track.csv")
+	if err != nil {
+		t.Fatalf("OpenRecordSet() error = %v", err)
+	}
+	defer rs.Close()
+
+	tracks, err := rs.Tracks()
+	if err != nil {
+		t.Fatalf("RecordSet.Tracks() error = %v", err)
+	}
+	if len(tracks) != 10 {
+		t.Errorf("RecordSet.Tracks() returned %d tracks, want 10", len(tracks))
+	}
+
+	tr, ok := tracks["477307901"]
+	if !ok {
+		t.Fatal("RecordSet.Tracks() missing track for 477307901")
+	}
+	want := []Record{track1, track2, track3}
+	if !reflect.DeepEqual(tr.Records(), want) {
+		t.Errorf("Track.Records() = %v, want %v", tr.Records(), want)
+	}
+	if tr.Duration() != 2*time.Minute {
+		t.Errorf("Track.Duration() = %v, want %v", tr.Duration(), 2*time.Minute)
+	}
+
+	nm, err := tr.Distance()
+	if err != nil {
+		t.Fatalf("Track.Distance() error = %v", err)
+	}
+	legs := Haversine(31.90512, -76.32652, 31.80512, -76.42652) + Haversine(31.80512, -76.42652, 31.70512, -76.52652)
+	if math.Abs(nm-legs) > 1e-9 {
+		t.Errorf("Track.Distance() = %v, want %v", nm, legs)
+	}
+	kts, err := tr.AverageSpeed()
+	if err != nil {
+		t.Fatalf("Track.AverageSpeed() error = %v", err)
+	}
+	if math.Abs(kts-legs*30) > 1e-9 {
+		t.Errorf("Track.AverageSpeed() = %v, want %v", kts, legs*30)
+	}
+
+	box, err := tr.BoundingBox()
+	if err != nil {
+		t.Fatalf("Track.BoundingBox() error = %v", err)
+	}
+	wantBox := Box{MinLat: 31.70512, MaxLat: 31.90512, MinLon: -76.52652, MaxLon: -76.32652, LatIndex: 2, LonIndex: 3}
+	if box != wantBox {
+		t.Errorf("Track.BoundingBox() = %+v, want %+v", box, wantBox)
+	}
+}
+
+func TestNewTrack(t *testing.T) {
+	// Out of order records are sorted by time.
+	tr, err := NewTrack(goodHeaders, []Record{track3, track1, track2})
+	if err != nil {
+		t.Fatalf("NewTrack() error = %v", err)
+	}
+	if want := []Record{track1, track2, track3}; !reflect.DeepEqual(tr.Records(), want) {
+		t.Errorf("NewTrack() Records() = %v, want %v", tr.Records(), want)
+	}
+
+	if _, err := NewTrack(goodHeaders, []Record{track1, firstRec, Record(testRec0)}); err == nil {
+		t.Error("NewTrack() expected error for mixed MMSI")
+	}
+	if _, err := NewTrack(goodHeaders, nil); err != ErrEmptySet {
+		t.Errorf("NewTrack() error = %v, want %v", err, ErrEmptySet)
+	}
+	if _, err := NewTrack(badHeaders, []Record{track1}); err == nil {
+		t.Error("NewTrack() expected error for headers without BaseDateTime")
+	}
+}
+
+func TestTrack_Resample(t *testing.T) {
+	tr, err := NewTrack(goodHeaders, []Record{track1, track2, track3})
+	if err != nil {
+		t.Fatalf("NewTrack() error = %v", err)
+	}
+
+	rt, err := tr.Resample(45 * time.Second)
+	if err != nil {
+		t.Fatalf("Track.Resample() error = %v", err)
+	}
+	// Samples at 0s, 45s, 90s hold track1, track1, and track2.
+	wantTimes := []string{"2017-12-01T00:00:01", "2017-12-01T00:00:46", "2017-12-01T00:01:31"}
+	wantLats := []string{"31.90512", "31.90512", "31.80512"}
+	if rt.Len() != len(wantTimes) {
+		t.Fatalf("Track.Resample() Len() = %d, want %d", rt.Len(), len(wantTimes))
+	}
+	for i, rec := range rt.Records() {
+		if rec[1] != wantTimes[i] || rec[2] != wantLats[i] {
+			t.Errorf("Track.Resample() record %d = %v, want time %s lat %s", i, rec, wantTimes[i], wantLats[i])
+		}
+	}
+	if track1[1] != "2017-12-01T00:00:01" {
+		t.Error("Track.Resample() modified the original records")
+	}
+
+	if _, err := tr.Resample(0); err == nil {
+		t.Error("Track.Resample() expected error for zero interval")
+	}
+}
+
+func TestTrack_Segment(t *testing.T) {
+	track4 := make(Record, len(track3))
+	copy(track4, track3)
+	track4[1] = "2017-12-01T00:30:01"
+	tr, err := NewTrack(goodHeaders, []Record{track1, track2, track3, track4})
+	if err != nil {
+		t.Fatalf("NewTrack() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		gap     time.Duration
+		maxJump float64
+		want    []int
+	}{
+		{"no split", 0, 0, []int{4}},
+		{"reporting gap", 10 * time.Minute, 0, []int{3, 1}},
+		{"position jump", 0, 5, []int{1, 1, 2}}, // legs between track1, 2 and 3 are about 8nm
+		{"both", 10 * time.Minute, 5, []int{1, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segs, err := tr.Segment(tt.gap, tt.maxJump)
+			if err != nil {
+				t.Fatalf("Track.Segment() error = %v", err)
+			}
+			var got []int
+			for _, s := range segs {
+				got = append(got, s.Len())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Track.Segment() lengths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := tr.Segment(-time.Minute, 0); err == nil {
+		t.Error("Track.Segment() expected error for negative gap")
+	}
+}
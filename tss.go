@@ -0,0 +1,298 @@
+package ais
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TSSFields are the columns written by TSSReports.Save, one row per
+// TSSViolation.
+const TSSFields = "MMSI,Violation,Severity,Lane,Start,End,Records,LAT,LON,Deviation"
+
+// TSSViolationKind identifies a breach of a traffic separation scheme found by
+// RecordSet.CheckTSS.
+type TSSViolationKind int
+
+const (
+	// WrongWay is a vessel in a lane on a course within TSSRules.MaxDeviation of
+	// the reciprocal of the lane direction, proceeding against the traffic.
+	WrongWay TSSViolationKind = iota
+
+	// ShallowCrossing is a vessel in a lane on a course that neither follows the
+	// lane nor crosses it within TSSRules.MinCrossingAngle of a right angle, as
+	// Rule 10 of COLREGS requires.
+	ShallowCrossing
+
+	numTSSViolationKinds
+)
+
+var tssViolationKindNames = [...]string{
+	WrongWay:        "wrong way",
+	ShallowCrossing: "shallow crossing",
+}
+
+// String implements the Stringer interface for TSSViolationKind.
+func (k TSSViolationKind) String() string {
+	if k < 0 || k >= numTSSViolationKinds {
+		return fmt.Sprintf("TSSViolationKind(%d)", int(k))
+	}
+	return tssViolationKindNames[k]
+}
+
+// TSSSeverity grades a TSSViolation for a VTS operator.
+type TSSSeverity int
+
+const (
+	// TSSLow is a shallow crossing with the flow of the lane.
+	TSSLow TSSSeverity = iota
+
+	// TSSMedium is a shallow crossing against the flow of the lane.
+	TSSMedium
+
+	// TSSHigh is a vessel proceeding the wrong way in a lane.
+	TSSHigh
+
+	numTSSSeverities
+)
+
+var tssSeverityNames = [...]string{
+	TSSLow:    "low",
+	TSSMedium: "medium",
+	TSSHigh:   "high",
+}
+
+// String implements the Stringer interface for TSSSeverity.
+func (s TSSSeverity) String() string {
+	if s < 0 || s >= numTSSSeverities {
+		return fmt.Sprintf("TSSSeverity(%d)", int(s))
+	}
+	return tssSeverityNames[s]
+}
+
+// TSSLane is one traffic lane of a traffic separation scheme, the area of the
+// lane and the direction of traffic flow in it, in degrees true.
+type TSSLane struct {
+	Name      string
+	Fence     *Geofence
+	Direction float64
+}
+
+// TSSRules configures RecordSet.CheckTSS.  Angles are in degrees.
+type TSSRules struct {
+	Lanes            []TSSLane
+	MaxDeviation     float64       // farthest a course may be from the lane direction and still follow it
+	MinCrossingAngle float64       // smallest angle to the lane direction of a proper crossing
+	MinSOG           float64       // knots, slower Records are skipped since their COG is not reliable
+	MaxGap           time.Duration // longest time between the Records of one violation
+	MinRecords       int           // fewest Records in a violation that is reported
+}
+
+// DefaultTSSRules returns TSSRules that allow a course within 30 degrees of the
+// lane direction, take a crossing within 30 degrees of a right angle as proper,
+// skip Records slower than 2 knots, and report violations of at least two
+// Records no more than ten minutes apart.  Lanes are not set.
+func DefaultTSSRules() TSSRules {
+	return TSSRules{
+		MaxDeviation:     30,
+		MinCrossingAngle: 60,
+		MinSOG:           2,
+		MaxGap:           10 * time.Minute,
+		MinRecords:       2,
+	}
+}
+
+// classify returns the kind and severity of a violation for a course of cog in
+// a lane with direction dir, and false for a compliant course.
+func (rules TSSRules) classify(cog, dir float64) (TSSViolationKind, TSSSeverity, bool) {
+	d := math.Abs(math.Mod(cog-dir+540, 360) - 180) // 0 to 180 degrees off the lane direction
+	switch {
+	case d <= rules.MaxDeviation:
+		return 0, 0, false
+	case d >= 180-rules.MaxDeviation:
+		return WrongWay, TSSHigh, true
+	case math.Min(d, 180-d) < rules.MinCrossingAngle:
+		if d > 90 {
+			return ShallowCrossing, TSSMedium, true
+		}
+		return ShallowCrossing, TSSLow, true
+	}
+	return 0, 0, false
+}
+
+// TSSViolation is a run of consecutive Records of one vessel that breach the
+// rules of one lane in the same way.
+type TSSViolation struct {
+	Kind       TSSViolationKind
+	Severity   TSSSeverity // the highest of the Records
+	Lane       string
+	Start, End time.Time
+	Records    int
+	LAT, LON   float64 // position of the first Record
+	Deviation  float64 // degrees, the largest angle between a COG and the lane direction
+}
+
+// TSSReport lists the violations of one MMSI in the order they ended.
+type TSSReport struct {
+	MMSI       string
+	Records    int // number of Records of the MMSI checked in a lane
+	Counts     [numTSSViolationKinds]int
+	Violations []TSSViolation
+}
+
+// TSSReports are the reports of the vessels with at least one violation, sorted
+// by MMSI.
+type TSSReports []*TSSReport
+
+// CheckTSS reads the RecordSet and returns a report for every MMSI that
+// proceeded the WrongWay in one of the Lanes of rules or made a ShallowCrossing
+// of it.  The COG of each Record inside a lane is compared with the lane
+// direction, and consecutive Records of a vessel that breach the same lane in
+// the same way, no more than MaxGap apart, form one TSSViolation, so a transit
+// is reported once and a single bad COG is not reported at all when MinRecords
+// is two or more.  Records outside the lanes, with a position or COG that is
+// not available, or slower than MinSOG when the Headers contain SOG are
+// skipped.  The RecordSet should be sorted by time.  The Headers must contain
+// MMSI, BaseDateTime, LAT, LON, and COG.  CheckTSS consumes the receiver.
+func (rs *RecordSet) CheckTSS(rules TSSRules) (TSSReports, error) {
+	h := rs.Headers()
+	idx, err := h.require("MMSI", "BaseDateTime", "LAT", "LON", "COG")
+	if err != nil {
+		return nil, fmt.Errorf("check tss: %w", err)
+	}
+	sogIndex, haveSOG := h.Contains("SOG")
+
+	reports := make(map[string]*TSSReport)
+	open := make(map[string]*TSSViolation)
+	closeViolation := func(mmsi string) {
+		v := open[mmsi]
+		if v == nil {
+			return
+		}
+		delete(open, mmsi)
+		if v.Records < rules.MinRecords {
+			return
+		}
+		r := reports[mmsi]
+		r.Counts[v.Kind]++
+		r.Violations = append(r.Violations, *v)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("check tss: read error on csv file: %w", err)
+		}
+		lat, errLat := rec.ParseFloat(idx["LAT"].Idx)
+		lon, errLon := rec.ParseFloat(idx["LON"].Idx)
+		if errLat != nil || errLon != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			continue
+		}
+		cog, err := rec.ParseFloat(idx["COG"].Idx)
+		if err != nil || cog < 0 || cog >= COGNotAvailable {
+			continue
+		}
+		if haveSOG && rules.MinSOG > 0 {
+			if sog, err := rec.ParseFloat(sogIndex); err != nil || sog < rules.MinSOG {
+				continue
+			}
+		}
+		var lane *TSSLane
+		for i := range rules.Lanes {
+			if rules.Lanes[i].Fence.Contains(lat, lon) {
+				lane = &rules.Lanes[i]
+				break
+			}
+		}
+		mmsi := (*rec)[idx["MMSI"].Idx]
+		if lane == nil {
+			closeViolation(mmsi)
+			continue
+		}
+		t, err := h.parseTime((*rec)[idx["BaseDateTime"].Idx])
+		if err != nil {
+			return nil, fmt.Errorf("check tss: %w", err)
+		}
+
+		r, ok := reports[mmsi]
+		if !ok {
+			r = &TSSReport{MMSI: mmsi}
+			reports[mmsi] = r
+		}
+		r.Records++
+		kind, severity, bad := rules.classify(cog, lane.Direction)
+		v := open[mmsi]
+		if v != nil && (!bad || v.Kind != kind || v.Lane != lane.Name ||
+			rules.MaxGap > 0 && t.Sub(v.End) > rules.MaxGap) {
+			closeViolation(mmsi)
+			v = nil
+		}
+		if !bad {
+			continue
+		}
+		if v == nil {
+			v = &TSSViolation{Kind: kind, Lane: lane.Name, Start: t, LAT: lat, LON: lon}
+			open[mmsi] = v
+		}
+		v.End = t
+		v.Records++
+		if severity > v.Severity {
+			v.Severity = severity
+		}
+		if d := math.Abs(math.Mod(cog-lane.Direction+540, 360) - 180); d > v.Deviation {
+			v.Deviation = d
+		}
+	}
+	for mmsi := range open {
+		closeViolation(mmsi)
+	}
+
+	var out TSSReports
+	for _, r := range reports {
+		if len(r.Violations) > 0 {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].MMSI < out[j].MMSI })
+	return out, nil
+}
+
+// Save writes every TSSViolation of the reports to a csv file with TSSFields.
+func (tr TSSReports) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("tss save: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(strings.Split(TSSFields, ","))
+	for _, r := range tr {
+		for _, v := range r.Violations {
+			w.Write([]string{
+				r.MMSI,
+				v.Kind.String(),
+				v.Severity.String(),
+				v.Lane,
+				v.Start.Format(TimeLayout),
+				v.End.Format(TimeLayout),
+				strconv.Itoa(v.Records),
+				strconv.FormatFloat(v.LAT, 'f', -1, 64),
+				strconv.FormatFloat(v.LON, 'f', -1, 64),
+				strconv.FormatFloat(v.Deviation, 'f', 1, 64),
+			})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("tss save: %w", err)
+	}
+	return f.Close()
+}
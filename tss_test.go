@@ -0,0 +1,100 @@
+package ais
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const tssData = `MMSI,BaseDateTime,LAT,LON,SOG,COG
+100000000,2017-12-01T00:00:00,36.90000,-75.50000,12.0,181.0
+200000000,2017-12-01T00:00:00,36.20000,-75.90000,10.0,45.0
+300000000,2017-12-01T00:00:00,36.50000,-75.90000,10.0,90.0
+400000000,2017-12-01T00:00:00,36.50000,-75.50000,10.0,180.0
+500000000,2017-12-01T00:00:00,36.50000,-75.60000,1.0,180.0
+100000000,2017-12-01T00:03:00,36.80000,-75.50000,12.0,178.0
+200000000,2017-12-01T00:03:00,36.30000,-75.80000,10.0,44.0
+300000000,2017-12-01T00:03:00,36.50000,-75.80000,10.0,92.0
+400000000,2017-12-01T00:03:00,36.60000,-75.50000,10.0,2.0
+500000000,2017-12-01T00:03:00,36.45000,-75.60000,1.0,180.0
+100000000,2017-12-01T00:06:00,36.70000,-75.50000,12.0,185.0
+200000000,2017-12-01T00:06:00,37.50000,-75.00000,10.0,45.0
+100000000,2017-12-01T00:40:00,36.60000,-75.50000,12.0,140.0
+100000000,2017-12-01T00:43:00,36.55000,-75.45000,12.0,135.0
+`
+
+func TestRecordSet_CheckTSS(t *testing.T) {
+	north, err := NewGeofenceWKT("POLYGON ((-76 36, -75 36, -75 37, -76 37, -76 36))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := DefaultTSSRules()
+	rules.Lanes = []TSSLane{{Name: "northbound", Fence: north, Direction: 0}}
+	rs, _ := NewRecordSetFromReader(strings.NewReader(tssData), Headers{})
+	reports, err := rs.CheckTSS(rules)
+	if err != nil {
+		t.Fatalf("RecordSet.CheckTSS() error = %v", err)
+	}
+	if len(reports) != 2 || reports[0].MMSI != "100000000" || reports[1].MMSI != "200000000" {
+		t.Fatalf("RecordSet.CheckTSS() = %v, want reports for 100000000 and 200000000", reports)
+	}
+
+	r := reports[0]
+	if r.Records != 5 || r.Counts != [numTSSViolationKinds]int{1, 1} || len(r.Violations) != 2 {
+		t.Fatalf("RecordSet.CheckTSS() report = %+v", r)
+	}
+	if v := r.Violations[0]; v.Kind != WrongWay || v.Severity != TSSHigh || v.Records != 3 ||
+		v.Start.Minute() != 0 || v.End.Minute() != 6 || v.LAT != 36.9 || v.Deviation != 179 {
+		t.Errorf("RecordSet.CheckTSS() wrong way = %+v", v)
+	}
+	// The silence of more than MaxGap starts a new violation.
+	if v := r.Violations[1]; v.Kind != ShallowCrossing || v.Severity != TSSMedium || v.Records != 2 {
+		t.Errorf("RecordSet.CheckTSS() shallow crossing against the flow = %+v", v)
+	}
+	// The last Record of 200000000 is outside the lane.
+	if v := reports[1].Violations; len(v) != 1 || v[0].Severity != TSSLow || v[0].Records != 2 || v[0].Lane != "northbound" {
+		t.Errorf("RecordSet.CheckTSS() shallow crossing with the flow = %+v", v)
+	}
+
+	dir, err := ioutil.TempDir("", "ais")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tss.csv")
+	if err := reports.Save(filename); err != nil {
+		t.Fatalf("TSSReports.Save() error = %v", err)
+	}
+	f, _ := os.Open(filename)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != TSSFields {
+		t.Fatalf("TSSReports.Save() wrote %v", rows)
+	}
+	if got, want := strings.Join(rows[1], ","), "100000000,wrong way,high,northbound,2017-12-01T00:00:00,2017-12-01T00:06:00,3,36.9,-75.5,179.0"; got != want {
+		t.Errorf("TSSReports.Save() row = %s, want %s", got, want)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI,BaseDateTime,LAT,LON\n"), Headers{})
+	if _, err := rs.CheckTSS(rules); err == nil {
+		t.Errorf("RecordSet.CheckTSS() without COG error = nil, want an error")
+	}
+}
+
+func TestTSSViolationKind_String(t *testing.T) {
+	if got := ShallowCrossing.String(); got != "shallow crossing" {
+		t.Errorf("ShallowCrossing.String() = %s", got)
+	}
+	if got := TSSViolationKind(9).String(); got != "TSSViolationKind(9)" {
+		t.Errorf("TSSViolationKind(9).String() = %s", got)
+	}
+	if got := TSSSeverity(9).String(); got != "TSSSeverity(9)" {
+		t.Errorf("TSSSeverity(9).String() = %s", got)
+	}
+}
@@ -0,0 +1,243 @@
+package ais
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// VesselType is the numeric ship and cargo type of a vessel.  Codes 0 through 99
+// are the AIS ship types of ITU-R M.1371, where the first digit is the kind of
+// vessel and the second the hazard class of its cargo.  Codes 1001 through 1019
+// are the vessel groups that MarineCadastre.gov substitutes in the VesselType
+// field of its files from 2018 for vessels whose type is known from the
+// Coast Guard's vessel documentation.
+type VesselType int
+
+// VesselCategory is a coarse grouping of VesselTypes for breaking an analysis
+// down by the kind of traffic involved.
+type VesselCategory int
+
+const (
+	// UnknownCategory is a VesselType of 0, which AIS uses for not available, an
+	// empty field, or a code that is not allocated.
+	UnknownCategory VesselCategory = iota
+
+	// Cargo is a cargo ship (70-79) or freight ship or barge.
+	Cargo
+
+	// Tanker is a tanker (80-89) or tank ship or barge.
+	Tanker
+
+	// Fishing is a fishing vessel (30) or fish processing vessel.
+	Fishing
+
+	// Passenger is a passenger ship (60-69), including ferries and cruise ships.
+	Passenger
+
+	// Tug is a tug (52) or a vessel towing (31, 32).
+	Tug
+
+	// Pleasure is a pleasure craft (37) or sailing vessel (36).
+	Pleasure
+
+	// OtherCategory is any other allocated VesselType, such as pilot, search and
+	// rescue, law enforcement, and high speed craft, dredgers, and research
+	// vessels.
+	OtherCategory
+)
+
+var vesselCategoryNames = [...]string{
+	UnknownCategory: "unknown",
+	Cargo:           "cargo",
+	Tanker:          "tanker",
+	Fishing:         "fishing",
+	Passenger:       "passenger",
+	Tug:             "tug",
+	Pleasure:        "pleasure",
+	OtherCategory:   "other",
+}
+
+// String implements the Stringer interface for VesselCategory.
+func (c VesselCategory) String() string {
+	if c < 0 || int(c) >= len(vesselCategoryNames) {
+		return fmt.Sprintf("VesselCategory(%d)", int(c))
+	}
+	return vesselCategoryNames[c]
+}
+
+// ParseVesselCategory returns the VesselCategory named s as returned by String,
+// without regard to case.
+func ParseVesselCategory(s string) (VesselCategory, error) {
+	for c, name := range vesselCategoryNames {
+		if strings.EqualFold(s, name) {
+			return VesselCategory(c), nil
+		}
+	}
+	return UnknownCategory, fmt.Errorf("parse vessel category: unknown category %q", s)
+}
+
+// marineCadastreCategories maps the MarineCadastre.gov vessel group codes to a
+// VesselCategory.
+var marineCadastreCategories = map[VesselType]VesselCategory{
+	1001: Fishing,       // commercial fishing vessel
+	1002: Fishing,       // fish processing vessel
+	1003: Cargo,         // freight barge
+	1004: Cargo,         // freight ship
+	1005: OtherCategory, // industrial vessel
+	1006: OtherCategory, // miscellaneous vessel
+	1007: OtherCategory, // mobile offshore drilling unit
+	1008: OtherCategory, // non-vessel
+	1009: OtherCategory, // non-self-propelled vessel
+	1010: OtherCategory, // offshore supply vessel
+	1011: OtherCategory, // oil recovery vessel
+	1012: Passenger,     // passenger vessel
+	1013: OtherCategory, // public vessel, unclassified
+	1014: Pleasure,      // recreational vessel
+	1015: OtherCategory, // research vessel
+	1016: OtherCategory, // school ship
+	1017: Tanker,        // tank barge
+	1018: Tanker,        // tank ship
+	1019: Tug,           // towing vessel
+}
+
+// Category returns the VesselCategory of the type.
+func (t VesselType) Category() VesselCategory {
+	if c, ok := marineCadastreCategories[t]; ok {
+		return c
+	}
+	switch {
+	case t <= 0 || t >= 100:
+		return UnknownCategory
+	case t == 30:
+		return Fishing
+	case t == 31 || t == 32 || t == 52:
+		return Tug
+	case t == 36 || t == 37:
+		return Pleasure
+	case t >= 60 && t <= 69:
+		return Passenger
+	case t >= 70 && t <= 79:
+		return Cargo
+	case t >= 80 && t <= 89:
+		return Tanker
+	case t >= 20:
+		return OtherCategory
+	}
+	return UnknownCategory // 1-19 are reserved
+}
+
+// ParseVesselType returns the VesselType in s.  An empty s is a VesselType of 0,
+// not available, as it is in files with missing values.
+func ParseVesselType(s string) (VesselType, error) {
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64) // some files write 70.0
+	if err != nil {
+		return 0, err
+	}
+	return VesselType(f), nil
+}
+
+// VesselType returns the VesselType field of the Record described by h.
+func (r Record) VesselType(h Headers) (VesselType, error) {
+	i, err := h.index("VesselType")
+	if err != nil {
+		return 0, err
+	}
+	if i >= len(r) {
+		return 0, ErrParse{Field: "VesselType", Err: strconv.ErrSyntax}
+	}
+	t, err := ParseVesselType(r[i])
+	if err != nil {
+		return 0, ErrParse{Field: "VesselType", Err: err}
+	}
+	return t, nil
+}
+
+// CategoryFilter implements the Matching interface to select Records by the
+// VesselCategory of their VesselType.  Use NewCategoryFilter to create one.
+type CategoryFilter struct {
+	TypeIndex  int
+	categories map[VesselCategory]bool
+}
+
+// NewCategoryFilter returns a CategoryFilter that matches Records whose
+// VesselType, found at typeIndex, belongs to one of the categories.  For example
+// NewCategoryFilter(10, Cargo, Tanker) passed to Subset keeps the commercial
+// traffic of a RecordSet with DefaultFields.
+func NewCategoryFilter(typeIndex int, categories ...VesselCategory) *CategoryFilter {
+	cf := &CategoryFilter{TypeIndex: typeIndex, categories: make(map[VesselCategory]bool)}
+	for _, c := range categories {
+		cf.categories[c] = true
+	}
+	return cf
+}
+
+// Match implements the Matching interface for a CategoryFilter.  Records whose
+// VesselType cannot be parsed are UnknownCategory.
+func (cf *CategoryFilter) Match(rec *Record) (bool, error) {
+	if cf.TypeIndex >= len(*rec) {
+		return false, fmt.Errorf("category filter: record has no field %d", cf.TypeIndex)
+	}
+	t, _ := ParseVesselType((*rec)[cf.TypeIndex])
+	return cf.categories[t.Category()], nil
+}
+
+// WithVesselCategories limits the Interactions to pairs of Records whose
+// VesselTypes both belong to one of the categories.  The RecordHeaders must
+// contain VesselType.
+func WithVesselCategories(categories ...VesselCategory) InteractionOption {
+	return func(inter *Interactions) error {
+		if len(categories) == 0 {
+			return fmt.Errorf("vessel categories must not be empty")
+		}
+		i, ok := inter.RecordHeaders.Contains("VesselType")
+		if !ok {
+			return fmt.Errorf("vessel categories: %w", ErrMissingHeader{Field: "VesselType"})
+		}
+		inter.categories = NewCategoryFilter(i, categories...)
+		return nil
+	}
+}
+
+// GroupByType reads the remaining Records in the RecordSet and returns a new
+// RecordSet for each VesselCategory present, holding its Records in the order
+// they were read.  Like other methods that read the whole RecordSet, GroupByType
+// consumes the receiver.  The Headers must contain VesselType.
+func (rs *RecordSet) GroupByType() (map[VesselCategory]*RecordSet, error) {
+	typeIndex, ok := rs.Headers().Contains("VesselType")
+	if !ok {
+		return nil, fmt.Errorf("group by type: %w", ErrMissingHeader{Field: "VesselType"})
+	}
+
+	groups := make(map[VesselCategory]*RecordSet)
+	for {
+		rec, err := rs.read(true)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("group by type: read error on csv file: %w", err)
+		}
+		t, _ := ParseVesselType((*rec)[typeIndex])
+		c := t.Category()
+		g, ok := groups[c]
+		if !ok {
+			g = NewRecordSet()
+			g.SetHeaders(rs.Headers())
+			groups[c] = g
+		}
+		if err := g.Write(*rec); err != nil {
+			return nil, fmt.Errorf("group by type: csv write error: %w", err)
+		}
+	}
+	for _, g := range groups {
+		if err := g.Flush(); err != nil {
+			return nil, fmt.Errorf("group by type: csv flush error: %w", err)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,126 @@
+package ais
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestVesselType_Category(t *testing.T) {
+	tests := map[VesselType]VesselCategory{
+		0:    UnknownCategory,
+		15:   UnknownCategory,
+		30:   Fishing,
+		31:   Tug,
+		35:   OtherCategory,
+		37:   Pleasure,
+		52:   Tug,
+		60:   Passenger,
+		70:   Cargo,
+		79:   Cargo,
+		84:   Tanker,
+		99:   OtherCategory,
+		1001: Fishing,
+		1004: Cargo,
+		1012: Passenger,
+		1014: Pleasure,
+		1018: Tanker,
+		1019: Tug,
+		1099: UnknownCategory,
+	}
+	for vt, want := range tests {
+		if got := vt.Category(); got != want {
+			t.Errorf("VesselType(%d).Category() = %v, want %v", vt, got, want)
+		}
+	}
+	if c, err := ParseVesselCategory("Tanker"); err != nil || c != Tanker {
+		t.Errorf("ParseVesselCategory() = %v, %v, want tanker", c, err)
+	}
+	if _, err := ParseVesselCategory("submarine"); err == nil {
+		t.Error("ParseVesselCategory() expected error for unknown category")
+	}
+	if VesselCategory(99).String() != "VesselCategory(99)" {
+		t.Errorf("VesselCategory.String() = %q", VesselCategory(99).String())
+	}
+}
+
+func TestRecord_VesselType(t *testing.T) {
+	h := Headers{Fields: []string{"MMSI", "VesselType"}}
+	for s, want := range map[string]VesselType{"70": 70, "1004": 1004, "80.0": 80, "": 0} {
+		if got, err := (Record{"366940480", s}).VesselType(h); err != nil || got != want {
+			t.Errorf("Record.VesselType(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := (Record{"366940480", "cargo"}).VesselType(h); err == nil {
+		t.Error("Record.VesselType() expected error for unparsable VesselType")
+	}
+}
+
+func TestRecordSet_GroupByType(t *testing.T) {
+	data := "MMSI,VesselType\n1,70\n2,80\n3,71\n4,\n5,1018\n"
+	rs, _ := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	groups, err := rs.GroupByType()
+	if err != nil {
+		t.Fatalf("RecordSet.GroupByType() error = %v", err)
+	}
+	want := map[VesselCategory]int{Cargo: 2, Tanker: 2, UnknownCategory: 1}
+	if len(groups) != len(want) {
+		t.Errorf("RecordSet.GroupByType() returned %d groups, want %d", len(groups), len(want))
+	}
+	for c, n := range want {
+		g, ok := groups[c]
+		if !ok {
+			t.Errorf("RecordSet.GroupByType() has no %v group", c)
+			continue
+		}
+		got := 0
+		for {
+			if _, err := g.Read(); err == io.EOF {
+				break
+			}
+			got++
+		}
+		if got != n {
+			t.Errorf("RecordSet.GroupByType()[%v] has %d records, want %d", c, got, n)
+		}
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	commercial, err := rs.Subset(NewCategoryFilter(1, Tanker))
+	if err != nil {
+		t.Fatalf("RecordSet.Subset() error = %v", err)
+	}
+	rec, _ := commercial.Read()
+	if (*rec)[0] != "2" {
+		t.Errorf("CategoryFilter matched %v first, want MMSI 2", *rec)
+	}
+
+	rs, _ = NewRecordSetFromReader(strings.NewReader("MMSI\n1\n"), Headers{})
+	if _, err := rs.GroupByType(); err == nil {
+		t.Error("RecordSet.GroupByType() expected error for missing VesselType header")
+	}
+}
+
+func TestInteractions_WithVesselCategories(t *testing.T) {
+	inter, err := NewInteractionsWithOptions(goodHeaders, WithVesselCategories(Cargo, Tanker))
+	if err != nil {
+		t.Fatalf("NewInteractionsWithOptions() error = %v", err)
+	}
+	c := new(Cluster)
+	for _, v := range [][2]string{{"100000001", "70"}, {"100000002", "80"}, {"100000003", "37"}} {
+		rec := Record{v[0], "2017-12-01T00:00:00", "30.0", "-76.0", "", "", "", "", "", "", v[1], "", "", "", "", ""}
+		c.Append(&rec)
+	}
+	if err := inter.AddCluster(c); err != nil {
+		t.Fatalf("Interactions.AddCluster() error = %v", err)
+	}
+	if inter.Len() != 1 {
+		t.Errorf("Interactions.Len() = %d, want only the cargo and tanker pair", inter.Len())
+	}
+	if _, err := NewInteractionsWithOptions(goodHeaders, WithVesselCategories()); err == nil {
+		t.Error("NewInteractionsWithOptions() expected error for empty categories")
+	}
+	if _, err := NewInteractionsWithOptions(Headers{Fields: []string{"MMSI"}}, WithVesselCategories(Cargo)); err == nil {
+		t.Error("NewInteractionsWithOptions() expected error for missing VesselType header")
+	}
+}
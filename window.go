@@ -3,6 +3,8 @@ package ais
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"sort"
 	"time"
 )
 
@@ -11,8 +13,11 @@ import (
 type Window struct {
 	leftMarker, rightMarker time.Time
 	timeIndex               int
+	mmsiIndex               int        // index of MMSI, -1 when the Headers have none
+	timeParser              TimeParser // from the Headers of the RecordSet, nil for TimeLayout
 	width                   time.Duration
 	Data                    map[uint64]*Record
+	metrics                 *Metrics // counts the steps of SlideWindow, from the RecordSet
 }
 
 // NewWindow returns a *Window with the left marker set to the time in
@@ -23,19 +28,37 @@ type Window struct {
 // available to the client's first call to rs.Read(). For any non-nil error
 // NewWindow returns nil and the error.
 func NewWindow(rs *RecordSet, width time.Duration) (*Window, error) {
+	if _, ok := rs.Headers().Contains("BaseDateTime"); !ok {
+		return nil, fmt.Errorf("newwindow: %w", ErrMissingHeader{Field: "BaseDateTime"})
+	}
+	rec, err := rs.readFirst()
+	if err != nil {
+		return nil, fmt.Errorf("newwindow: %w", err)
+	}
+	win, err := newWindow(rs.Headers(), rec, width)
+	if err != nil {
+		return nil, fmt.Errorf("newwindow: %w", err)
+	}
+	return win, nil
+}
+
+// newWindow returns a *Window of the given width for Records described by h
+// whose left marker is the time of rec.
+func newWindow(h Headers, rec *Record, width time.Duration) (*Window, error) {
 	win := new(Window)
-	timeIndex, ok := rs.Headers().Contains("BaseDateTime")
+	timeIndex, ok := h.Contains("BaseDateTime")
 	if !ok {
-		return nil, fmt.Errorf("newwindow: headers does not contain BaseDateTime")
+		return nil, ErrMissingHeader{Field: "BaseDateTime"}
 	}
 	win.SetIndex(timeIndex)
-	rec, err := rs.readFirst()
-	if err != nil {
-		return nil, fmt.Errorf("newwindow: %v", err)
+	win.mmsiIndex = -1
+	if i, ok := h.Contains("MMSI"); ok {
+		win.mmsiIndex = i
 	}
-	t, err := time.Parse(TimeLayout, (*rec)[timeIndex])
+	win.timeParser = h.Time
+	t, err := win.parseTime(rec)
 	if err != nil {
-		return nil, fmt.Errorf("newwindow: %v", err)
+		return nil, err
 	}
 	win.SetLeft(t)
 	win.SetWidth(width)
@@ -43,6 +66,129 @@ func NewWindow(rs *RecordSet, width time.Duration) (*Window, error) {
 	return win, nil
 }
 
+// snapshot returns a copy of the Window that holds the same Records and is not
+// changed when win slides.
+func (win *Window) snapshot() *Window {
+	c := *win
+	c.Data = make(map[uint64]*Record, len(win.Data))
+	for hash, rec := range win.Data {
+		c.Data[hash] = rec
+	}
+	return &c
+}
+
+// WindowFunc is the callback invoked by SlideWindow for each step of the Window.
+// Returning a non-nil error stops the scan and the error is returned by
+// SlideWindow unchanged.
+type WindowFunc func(win *Window) error
+
+// SlideWindow steps a Window of the given width through a RecordSet that is
+// sorted by BaseDateTime, for example by SortByTime, and calls fn each time the
+// Window is full.  The Window starts at the time of the next Record and advances
+// by step, so consecutive windows overlap when step is less than width.  Steps
+// over gaps in the data that would leave the Window empty are skipped, and the
+// final partially filled Window is passed to fn when the RecordSet is exhausted.
+// When step is greater than width, Records between consecutive windows are not
+// passed to fn.
+// An error is returned if a Record is found that is earlier than the Record
+// before it, which means the RecordSet is not sorted.  When either Record is from
+// a Source with a TimeSkew in the SourceRules of the Headers the second may be up
+// to TimeSkew earlier, since satellite reports are often stamped late; such a
+// Record is added to the current Window, or dropped if it is earlier than the
+// Window.  SlideWindow
+// consumes the RecordSet.
+func (rs *RecordSet) SlideWindow(width, step time.Duration, fn WindowFunc) error {
+	if width <= 0 || step <= 0 {
+		return fmt.Errorf("slide window: width and step must be positive, got %v and %v", width, step)
+	}
+	win, err := NewWindow(rs, width)
+	if err != nil {
+		return fmt.Errorf("slide window: %w", err)
+	}
+	win.metrics = rs.meter
+	return win.slide(rs.Headers(), step, rs.Read, fn)
+}
+
+// slide steps the Window through the Records returned by next, described by h,
+// until next returns io.EOF, as described for SlideWindow.
+func (win *Window) slide(h Headers, step time.Duration, next func() (*Record, error), fn WindowFunc) error {
+	var last time.Time
+	var lastSkew time.Duration // TimeSkew of the Record at last
+	for {
+		rec, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("slide window: read error on csv file: %w", err)
+		}
+		t, err := win.parseTime(rec)
+		if err != nil {
+			return fmt.Errorf("slide window: %w", err)
+		}
+		skew := rec.tolerance(h).TimeSkew
+		if t.Before(last) {
+			if last.Sub(t) > skew && last.Sub(t) > lastSkew {
+				return fmt.Errorf("slide window: record at %s follows %s, recordset is not sorted by time",
+					t.Format(TimeLayout), last.Format(TimeLayout))
+			}
+		} else {
+			last, lastSkew = t, skew
+		}
+		if t.Before(win.Left()) {
+			if t.Before(last) {
+				warn("slide window: dropped late record earlier than the window",
+					"BaseDateTime", t.Format(TimeLayout), "window", win.Left().Format(TimeLayout))
+			}
+			continue // the Record falls in the gap between two windows
+		}
+
+		if !t.Before(win.Right()) {
+			if win.Len() > 0 {
+				if err := fn(win); err != nil {
+					return err
+				}
+			}
+			if err := win.advance(t, step, fn); err != nil {
+				return err
+			}
+			if !win.InWindow(t) {
+				continue // the Record falls in the gap between two windows
+			}
+		}
+		win.AddRecord(*rec)
+	}
+	if win.Len() > 0 {
+		return fn(win)
+	}
+	return nil
+}
+
+// advance slides the Window by step until it contains t, calling fn for each
+// intermediate Window that still holds Records.  Steps that would leave the
+// Window empty are skipped.  When step is greater than the width of the Window, t
+// may fall between two windows, in which case advance stops at the first Window
+// that starts after t.
+func (win *Window) advance(t time.Time, step time.Duration, fn WindowFunc) error {
+	for {
+		win.Slide(step)
+		if win.InWindow(t) || t.Before(win.Left()) {
+			return nil
+		}
+		if win.Len() > 0 {
+			if err := fn(win); err != nil {
+				return err
+			}
+			continue
+		}
+		// Move directly to the last step that starts at or before t.
+		win.Slide(t.Sub(win.Left()) / step * step)
+		if win.InWindow(t) {
+			return nil
+		}
+	}
+}
+
 // Left returns the left marker.
 func (win *Window) Left() time.Time { return win.leftMarker }
 
@@ -78,9 +224,7 @@ func (win *Window) AddRecord(rec Record) {
 	if win.Data == nil {
 		win.Data = make(map[uint64]*Record)
 	}
-	h := rec.Hash()
-	// fmt.Println("hash is: ", h)
-	win.Data[h] = &rec
+	win.Data[rec.Hash()] = &rec
 }
 
 // InWindow tests if a time is in the Window.
@@ -95,19 +239,26 @@ func (win *Window) InWindow(t time.Time) bool {
 // Errors are possible from parsing the BaseDateTime field of the
 // Record.
 func (win *Window) RecordInWindow(rec *Record) (bool, error) {
-	t, err := rec.ParseTime(win.timeIndex)
+	t, err := win.parseTime(rec)
 	if err != nil {
-		return false, fmt.Errorf("recordinwindow: %v", err)
+		return false, fmt.Errorf("recordinwindow: %w", err)
 	}
 	return win.InWindow(t), nil
 }
 
+// parseTime parses the BaseDateTime of rec with the TimeParser of the RecordSet
+// the Window was created from.
+func (win *Window) parseTime(rec *Record) (time.Time, error) {
+	return Headers{Time: win.timeParser}.parseTime((*rec)[win.timeIndex])
+}
+
 // Slide moves the window down by the time provided in the arugment dur.
 // Slide also removes any data from the Window that would no longer return
 // true from InWindow for the new left and right markers after the Slide.
 func (win *Window) Slide(dur time.Duration) {
 	win.SetLeft(win.leftMarker.Add(dur))
 	win.SetRight(win.leftMarker.Add(win.Width()))
+	win.metrics.addSlide()
 
 	win.validate()
 }
@@ -132,10 +283,106 @@ func (win *Window) validate() error {
 	return nil
 }
 
-// String implements the Stringer interface for Window.
+// Records returns the Records in the Window sorted by BaseDateTime, then by
+// MMSI, then by the Hash that keys them in Data, so that the order is the same
+// from run to run where ranging over Data is not.  Records whose time cannot be
+// parsed sort first.  The slice is new but the Records are those held in Data.
+func (win *Window) Records() []*Record {
+	type entry struct {
+		t    time.Time
+		mmsi string
+		hash uint64
+		rec  *Record
+	}
+	entries := make([]entry, 0, len(win.Data))
+	for hash, rec := range win.Data {
+		e := entry{hash: hash, rec: rec}
+		e.t, _ = win.parseTime(rec)
+		e.mmsi = win.mmsi(rec)
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if !a.t.Equal(b.t) {
+			return a.t.Before(b.t)
+		}
+		if a.mmsi != b.mmsi {
+			return a.mmsi < b.mmsi
+		}
+		return a.hash < b.hash
+	})
+	recs := make([]*Record, len(entries))
+	for i := range entries {
+		recs[i] = entries[i].rec
+	}
+	return recs
+}
+
+// mmsi returns the MMSI of rec, or an empty string when the Window has no MMSI
+// index.
+func (win *Window) mmsi(rec *Record) string {
+	if win.mmsiIndex < 0 || win.mmsiIndex >= len(*rec) {
+		return ""
+	}
+	return (*rec)[win.mmsiIndex]
+}
+
+// TimeBounds returns the earliest and latest BaseDateTime of the Records in the
+// Window, which lie between its Left and Right markers.  It returns false when
+// the Window holds no Record with a time that can be parsed.
+func (win *Window) TimeBounds() (first, last time.Time, ok bool) {
+	for _, rec := range win.Data {
+		t, err := win.parseTime(rec)
+		if err != nil {
+			continue
+		}
+		if !ok || t.Before(first) {
+			first = t
+		}
+		if !ok || t.After(last) {
+			last = t
+		}
+		ok = true
+	}
+	return first, last, ok
+}
+
+// Latest returns the Record of mmsi in the Window with the latest BaseDateTime,
+// the last known state of the vessel.  Of Records at the same time the one that
+// sorts last in Records is returned.  It returns false when the Window holds no
+// Record of mmsi or the Headers of the RecordSet have no MMSI.
+func (win *Window) Latest(mmsi string) (*Record, bool) {
+	if win.mmsiIndex < 0 {
+		return nil, false
+	}
+	var latest *Record
+	for _, rec := range win.Records() {
+		if win.mmsi(rec) == mmsi {
+			latest = rec
+		}
+	}
+	return latest, latest != nil
+}
+
+// LatestByMMSI returns the Record with the latest BaseDateTime of every MMSI in
+// the Window, as for Latest.  It is empty when the Headers of the RecordSet have
+// no MMSI.
+func (win *Window) LatestByMMSI() map[string]*Record {
+	latest := make(map[string]*Record)
+	if win.mmsiIndex < 0 {
+		return latest
+	}
+	for _, rec := range win.Records() {
+		latest[win.mmsi(rec)] = rec
+	}
+	return latest
+}
+
+// String implements the Stringer interface for Window.  Records are written in
+// the order of Records.
 func (win *Window) String() string {
 	var buf bytes.Buffer
-	for _, rec := range win.Data {
+	for _, rec := range win.Records() {
 		fmt.Fprintln(&buf, rec)
 	}
 	return buf.String()
@@ -2,7 +2,10 @@ package ais
 
 import (
 	"encoding/csv"
+	"errors"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -182,3 +185,120 @@ func TestWindow_AddRecord(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordSet_SlideWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		width, step time.Duration
+		want        []int
+	}{
+		// The last record in ten.csv is weeks after the others so it is alone in
+		// the final window.
+		{"overlapping", 3 * time.Second, 2 * time.Second, []int{3, 3, 3, 3, 1, 1}},
+		{"adjacent", 5 * time.Second, 5 * time.Second, []int{5, 4, 1}},
+		{"gaps between windows", 2 * time.Second, 5 * time.Second, []int{2, 2}}, // the last record falls in a gap
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, _ := OpenRecordSet("testdata/ten.csv")
+			defer rs.Close()
+			var got []int
+			err := rs.SlideWindow(tt.width, tt.step, func(win *Window) error {
+				got = append(got, win.Len())
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("RecordSet.SlideWindow() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecordSet.SlideWindow() window sizes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordSet_SlideWindow_Errors(t *testing.T) {
+	rs, _ := OpenRecordSet("testdata/ten.csv")
+	defer rs.Close()
+	if err := rs.SlideWindow(0, time.Second, nil); err == nil {
+		t.Error("RecordSet.SlideWindow() expected error for zero width")
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := rs.SlideWindow(time.Second, time.Second, func(win *Window) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("RecordSet.SlideWindow() = %v after %d calls, want %v after 1 call", err, calls, stop)
+	}
+
+	unsorted := NewRecordSet()
+	unsorted.SetHeaders(goodHeaders)
+	unsorted.Write(Record(track2))
+	unsorted.Write(Record(track1))
+	unsorted.Flush()
+	if err := unsorted.SlideWindow(time.Minute, time.Minute, func(*Window) error { return nil }); err == nil {
+		t.Error("RecordSet.SlideWindow() expected error for unsorted records")
+	}
+}
+
+func TestWindow_Records(t *testing.T) {
+	data := `MMSI,BaseDateTime,LAT,LON
+366000002,2017-12-01T00:00:02,36.0,-76.0
+366000001,2017-12-01T00:00:02,36.1,-76.1
+366000002,2017-12-01T00:00:00,36.2,-76.2
+366000001,2017-12-01T00:00:01,36.3,-76.3
+`
+	rs, err := NewRecordSetFromReader(strings.NewReader(data), Headers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	win, err := NewWindow(rs, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		rec, err := rs.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		win.AddRecord(*rec)
+	}
+
+	want := []string{"36.2", "36.3", "36.1", "36.0"}
+	for run := 0; run < 5; run++ {
+		var got []string
+		for _, rec := range win.Records() {
+			got = append(got, (*rec)[2])
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Records() LATs = %v, want %v", got, want)
+		}
+	}
+
+	first, last, ok := win.TimeBounds()
+	start := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	if !ok || !first.Equal(start) || !last.Equal(start.Add(2*time.Second)) {
+		t.Errorf("TimeBounds() = %v, %v, %v", first, last, ok)
+	}
+	if _, _, ok := new(Window).TimeBounds(); ok {
+		t.Error("TimeBounds() of an empty Window returned true")
+	}
+
+	rec, ok := win.Latest("366000001")
+	if !ok || (*rec)[2] != "36.1" {
+		t.Errorf("Latest(366000001) = %v, %v", rec, ok)
+	}
+	if _, ok := win.Latest("123456789"); ok {
+		t.Error("Latest of an MMSI not in the Window returned true")
+	}
+	latest := win.LatestByMMSI()
+	if len(latest) != 2 || (*latest["366000002"])[2] != "36.0" || (*latest["366000001"])[2] != "36.1" {
+		t.Errorf("LatestByMMSI() = %v", latest)
+	}
+}